@@ -4,6 +4,7 @@ import (
 	"coffee-shop/middleware"
 	"coffee-shop/models"
 	"coffee-shop/routes"
+	"log"
 	"net/http"
 	"sync"
 
@@ -20,6 +21,9 @@ func initApp() {
 		gin.SetMode(gin.ReleaseMode)
 
 		models.InitDB()
+		if err := models.PrepareSchema(); err != nil {
+			log.Fatal(err)
+		}
 		models.InitRedis()
 
 		router = gin.New()
@@ -0,0 +1,212 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store is the response cache used by the controllers. Implementations must
+// treat a missing or unreachable backend as a cache miss, never as an error.
+// Entries that are invalidated together belong in a Namespace.
+type Store interface {
+	Get(ctx context.Context, key string) (string, bool)
+	// Set stores value at key; a ttl of 0 keeps it until it is replaced.
+	Set(ctx context.Context, key, value string, ttl time.Duration)
+
+	// GetList returns the list at key, first entry first.
+	GetList(ctx context.Context, key string) ([]string, bool)
+	// SetList replaces the list at key.
+	SetList(ctx context.Context, key string, values []string, ttl time.Duration)
+	// PushList moves value to the front of the list at key and trims the
+	// list to max entries. It does nothing when key holds no list, so that a
+	// list is only ever cached whole, from SetList.
+	PushList(ctx context.Context, key, value string, max int, ttl time.Duration)
+}
+
+// NewRedis wraps client. A nil client (Redis unavailable at startup) gives a
+// store that never hits.
+func NewRedis(client *redis.Client) Store {
+	if client == nil {
+		return Noop{}
+	}
+	return &redisStore{client: client}
+}
+
+type redisStore struct {
+	client *redis.Client
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) (string, bool) {
+	value, err := s.client.Get(ctx, key).Result()
+	if err != nil || value == "" {
+		return "", false
+	}
+	return value, true
+}
+
+func (s *redisStore) Set(ctx context.Context, key, value string, ttl time.Duration) {
+	if err := s.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		log.Printf("Failed to set cache key %s: %v", key, err)
+	}
+}
+
+func (s *redisStore) GetList(ctx context.Context, key string) ([]string, bool) {
+	values, err := s.client.LRange(ctx, key, 0, -1).Result()
+	if err != nil || len(values) == 0 {
+		return nil, false
+	}
+	return values, true
+}
+
+func (s *redisStore) SetList(ctx context.Context, key string, values []string, ttl time.Duration) {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		if len(values) > 0 {
+			pipe.RPush(ctx, key, toInterfaces(values)...)
+			pipe.Expire(ctx, key, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to set cache list %s: %v", key, err)
+	}
+}
+
+func (s *redisStore) PushList(ctx context.Context, key, value string, max int, ttl time.Duration) {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, key, 0, value)
+		pipe.LPushX(ctx, key, value)
+		pipe.LTrim(ctx, key, 0, int64(max-1))
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to push to cache list %s: %v", key, err)
+	}
+}
+
+func toInterfaces(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+// Noop never stores anything.
+type Noop struct{}
+
+func (Noop) Get(context.Context, string) (string, bool)         { return "", false }
+func (Noop) Set(context.Context, string, string, time.Duration) {}
+
+func (Noop) GetList(context.Context, string) ([]string, bool)             { return nil, false }
+func (Noop) SetList(context.Context, string, []string, time.Duration)     {}
+func (Noop) PushList(context.Context, string, string, int, time.Duration) {}
+
+// Memory is an in-process Store, used by tests and single-instance setups.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value     string
+	list      []string
+	expiresAt time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: map[string]memoryEntry{}}
+}
+
+func (m *Memory) Get(_ context.Context, key string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.live(key)
+	if !ok || entry.list != nil {
+		return "", false
+	}
+	return entry.value, true
+}
+
+// live returns the unexpired entry at key. The caller holds m.mu.
+func (m *Memory) live(key string) (memoryEntry, bool) {
+	entry, ok := m.entries[key]
+	if !ok {
+		return memoryEntry{}, false
+	}
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		delete(m.entries, key)
+		return memoryEntry{}, false
+	}
+	return entry, true
+}
+
+func (m *Memory) Set(_ context.Context, key, value string, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	m.entries[key] = entry
+}
+
+func (m *Memory) GetList(_ context.Context, key string) ([]string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.live(key)
+	if !ok || len(entry.list) == 0 {
+		return nil, false
+	}
+	return slices.Clone(entry.list), true
+}
+
+func (m *Memory) SetList(_ context.Context, key string, values []string, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(values) == 0 {
+		delete(m.entries, key)
+		return
+	}
+	entry := memoryEntry{list: slices.Clone(values)}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	m.entries[key] = entry
+}
+
+func (m *Memory) PushList(_ context.Context, key, value string, max int, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.live(key)
+	if !ok || entry.list == nil {
+		return
+	}
+	list := append([]string{value}, slices.DeleteFunc(entry.list, func(v string) bool { return v == value })...)
+	if len(list) > max {
+		list = list[:max]
+	}
+	entry.list = list
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	m.entries[key] = entry
+}
+
+// Len reports the number of live entries.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
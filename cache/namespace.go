@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Namespace groups cached entries that are invalidated together, such as
+// every product list and detail. Its keys embed the namespace's current
+// version, so Invalidate only has to replace the version: entries under the
+// old one are never read again and expire on their own.
+type Namespace struct {
+	store Store
+	name  string
+	loads singleflight.Group
+}
+
+func NewNamespace(store Store, name string) *Namespace {
+	return &Namespace{store: store, name: name}
+}
+
+func (n *Namespace) versionKey() string {
+	return n.name + ":version"
+}
+
+// version returns the namespace's current version, starting one when there
+// is none yet (or it was evicted).
+func (n *Namespace) version(ctx context.Context) string {
+	version, ok := n.store.Get(ctx, n.versionKey())
+	if !ok {
+		version = newVersion()
+		n.store.Set(ctx, n.versionKey(), version, 0)
+	}
+	return version
+}
+
+func (n *Namespace) key(ctx context.Context, key string) string {
+	return n.name + ":" + n.version(ctx) + ":" + key
+}
+
+// Modified returns when the namespace was last invalidated, to the second.
+// Anything cached in it can only be as old as that; a namespace whose
+// version was lost counts as modified now.
+func (n *Namespace) Modified(ctx context.Context) time.Time {
+	started, _, _ := strings.Cut(n.version(ctx), ".")
+	nanos, err := strconv.ParseInt(started, 36, 64)
+	if err != nil {
+		return time.Now().Truncate(time.Second)
+	}
+	return time.Unix(0, nanos).Truncate(time.Second)
+}
+
+func (n *Namespace) Get(ctx context.Context, key string) (string, bool) {
+	return n.store.Get(ctx, n.key(ctx, key))
+}
+
+func (n *Namespace) Set(ctx context.Context, key, value string, ttl time.Duration) {
+	n.store.Set(ctx, n.key(ctx, key), value, ttl)
+}
+
+// Fetch returns the value cached at key. On a miss it calls load and caches
+// what it returns for the returned ttl; concurrent misses for the same key
+// in this process wait for that one load instead of each running their own.
+func (n *Namespace) Fetch(ctx context.Context, key string, load func() (string, time.Duration, error)) (string, error) {
+	key = n.key(ctx, key)
+	if value, ok := n.store.Get(ctx, key); ok {
+		return value, nil
+	}
+	value, err, _ := n.loads.Do(key, func() (interface{}, error) {
+		// A load that finished just before this one started has cached
+		// the value already.
+		if value, ok := n.store.Get(ctx, key); ok {
+			return value, nil
+		}
+		value, ttl, err := load()
+		if err != nil {
+			return "", err
+		}
+		n.store.Set(ctx, key, value, ttl)
+		return value, nil
+	})
+	return value.(string), err
+}
+
+// Invalidate drops every entry in the namespace in O(1), whatever their
+// number.
+func (n *Namespace) Invalidate(ctx context.Context) {
+	n.store.Set(ctx, n.versionKey(), newVersion(), 0)
+}
+
+// newVersion returns a version no earlier one repeats, even after the
+// stored version was lost: the time it was made, then a random part that
+// keeps two versions made in the same clock tick apart.
+func newVersion() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "." + strconv.FormatUint(uint64(rand.Uint32()), 36)
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNamespaceFetchCoalescesMisses(t *testing.T) {
+	ctx := context.Background()
+	ns := NewNamespace(NewMemory(), "products")
+
+	var loads int32
+	release := make(chan struct{})
+	load := func() (string, time.Duration, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return "latte", time.Minute, nil
+	}
+
+	var wg sync.WaitGroup
+	values := make([]string, 10)
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], _ = ns.Fetch(ctx, "list", load)
+		}(i)
+	}
+	// Let the goroutines pile up behind the first load.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Fatalf("loads = %d, want 1", n)
+	}
+	for _, v := range values {
+		if v != "latte" {
+			t.Fatalf("values = %v", values)
+		}
+	}
+	if v, ok := ns.Get(ctx, "list"); !ok || v != "latte" {
+		t.Fatalf("cached = %q, %v", v, ok)
+	}
+}
+
+func TestNamespaceInvalidate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	products := NewNamespace(store, "products")
+	categories := NewNamespace(store, "categories")
+	products.Set(ctx, "list", "latte", 0)
+	categories.Set(ctx, "list", "coffee", 0)
+
+	before := time.Now().Truncate(time.Second)
+	products.Invalidate(ctx)
+	if modified := products.Modified(ctx); modified.Before(before) || modified.After(time.Now()) {
+		t.Fatalf("modified = %v, want about %v", modified, before)
+	}
+	if _, ok := products.Get(ctx, "list"); ok {
+		t.Fatal("expected the products namespace to be emptied")
+	}
+	if v, ok := categories.Get(ctx, "list"); !ok || v != "coffee" {
+		t.Fatal("invalidating one namespace must not touch another")
+	}
+
+	// Another process sees the new version through the shared store.
+	products.Set(ctx, "list", "mocha", 0)
+	if v, ok := NewNamespace(store, "products").Get(ctx, "list"); !ok || v != "mocha" {
+		t.Fatalf("shared read = %q, %v", v, ok)
+	}
+}
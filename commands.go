@@ -0,0 +1,276 @@
+package main
+
+import (
+	"coffee-shop/cache"
+	"coffee-shop/database/migration"
+	"coffee-shop/database/seed"
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"coffee-shop/services"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+const usage = `Usage: coffee-shop <command> [arguments]
+
+Commands:
+  serve                          start the HTTP API (default)
+  migrate up                     apply all pending migrations
+  migrate down [N|-all]          roll back N migrations (default 1) or all of them
+  migrate status                 show the current, expected and available versions
+  migrate check                  fail unless the schema is at the version this binary expects
+  migrate force VERSION          mark VERSION as applied and clear the dirty flag
+  seed [-password P]             load the demo data into an empty database
+  create-admin -email E -password P [-name N] [-phone P]
+                                 create an admin account
+  reset-password -email E -password P
+                                 set a new password for a user
+  apply-prices                   apply the scheduled price changes that are due
+  refresh-recommendations        recount the products bought together and their popularity
+
+Environment:
+  AUTO_MIGRATE=true              apply pending migrations when serve starts
+  PRICE_SCHEDULER_INTERVAL=1m    how often serve applies scheduled prices; 0 turns it off
+  RECOMMENDATION_REFRESH_INTERVAL=1h
+                                 how often serve recounts the products bought together and
+                                 their popularity; 0 turns it off
+`
+
+// cliActor is recorded in the audit log for changes made from the command line.
+var cliActor = services.Actor{Email: "cli"}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return serve()
+	}
+
+	switch args[0] {
+	case "serve":
+		return serve()
+	case "migrate":
+		return migrateCommand(args[1:])
+	case "seed":
+		return seedCommand(args[1:])
+	case "create-admin":
+		return createAdminCommand(args[1:])
+	case "reset-password":
+		return resetPasswordCommand(args[1:])
+	case "apply-prices":
+		return applyPricesCommand()
+	case "refresh-recommendations":
+		return refreshRecommendationsCommand()
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func migrateCommand(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return errors.New("migrate needs a subcommand: up, down, status, check or force")
+	}
+
+	m, err := models.NewMigrator(models.DSN())
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch args[0] {
+	case "up":
+		err = m.Up()
+	case "down":
+		err = migrateDown(m, args[1:])
+	case "status":
+		return migrateStatus(m)
+	case "check":
+		if err := models.CheckSchema(m); err != nil {
+			return err
+		}
+		fmt.Println("Schema version matches this binary")
+		return nil
+	case "force":
+		if len(args) != 2 {
+			return errors.New("usage: migrate force VERSION")
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		err = m.Force(version)
+	default:
+		return fmt.Errorf("unknown migrate subcommand %q", args[0])
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("No migrations to apply")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return migrateStatus(m)
+}
+
+func migrateDown(m *migrate.Migrate, args []string) error {
+	if len(args) == 0 {
+		return m.Steps(-1)
+	}
+	if args[0] == "-all" {
+		return m.Down()
+	}
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps < 1 {
+		return fmt.Errorf("invalid step count %q", args[0])
+	}
+	return m.Steps(-steps)
+}
+
+func migrateStatus(m *migrate.Migrate) error {
+	applied := -1
+	current, dirty, err := m.Version()
+	switch {
+	case errors.Is(err, migrate.ErrNilVersion):
+		fmt.Println("Current version: none")
+	case err != nil:
+		return err
+	case dirty:
+		applied = int(current)
+		fmt.Printf("Current version: %d (dirty - fix the schema, then run \"migrate force %d\")\n", current, current)
+	default:
+		applied = int(current)
+		fmt.Printf("Current version: %d\n", current)
+	}
+
+	versions, err := migration.Versions()
+	if err != nil {
+		return err
+	}
+	if n := len(versions); n > 0 {
+		fmt.Printf("Expected version: %d\n", versions[n-1].Number)
+	}
+	for _, v := range versions {
+		state := "pending"
+		if v.Number <= applied {
+			state = "applied"
+		}
+		fmt.Printf("  %06d %-30s %s\n", v.Number, v.Name, state)
+	}
+	return nil
+}
+
+func seedCommand(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	password := flags.String("password", os.Getenv("SEED_PASSWORD"), "password for every seeded user (default $SEED_PASSWORD)")
+	flags.Parse(args)
+
+	if *password == "" {
+		return errors.New("seed needs -password or SEED_PASSWORD")
+	}
+
+	models.InitDB()
+	defer models.CloseDB()
+
+	users, err := seed.Load(context.Background(), models.DB, *password)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Seeded demo data with %d users\n", users)
+	return nil
+}
+
+func createAdminCommand(args []string) error {
+	flags := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	email := flags.String("email", "", "admin email")
+	password := flags.String("password", "", "admin password")
+	name := flags.String("name", "Administrator", "full name")
+	phone := flags.String("phone", "", "phone number")
+	flags.Parse(args)
+
+	models.InitDB()
+	defer models.CloseDB()
+
+	users := services.NewUserService(repositories.NewPostgresStore(models.DB), cache.NewMemory())
+	u, err := users.Create(context.Background(), cliActor, services.NewUserInput{
+		Email:    *email,
+		Password: *password,
+		Role:     "admin",
+		FullName: *name,
+		Phone:    *phone,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created admin %s (id %d)\n", u.Email, u.ID)
+	return nil
+}
+
+func resetPasswordCommand(args []string) error {
+	flags := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	email := flags.String("email", "", "user email")
+	password := flags.String("password", "", "new password")
+	flags.Parse(args)
+
+	models.InitDB()
+	defer models.CloseDB()
+
+	users := services.NewUserService(repositories.NewPostgresStore(models.DB), cache.NewMemory())
+	if err := users.ResetPassword(context.Background(), cliActor, *email, *password); err != nil {
+		return err
+	}
+	fmt.Printf("Password updated for %s\n", *email)
+	return nil
+}
+
+// applyPricesCommand applies the due price changes once, for deployments
+// without a long-running server, such as a cron job next to a serverless
+// function.
+func applyPricesCommand() error {
+	models.InitDB()
+	defer models.CloseDB()
+
+	models.InitRedis()
+	defer models.CloseRedis()
+
+	n, err := newProductService().ApplyScheduledPrices(context.Background(), time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Applied %d scheduled price changes\n", n)
+	return nil
+}
+
+// refreshRecommendationsCommand recounts the products bought together and
+// their popularity once, for deployments without a long-running server.
+func refreshRecommendationsCommand() error {
+	models.InitDB()
+	defer models.CloseDB()
+
+	models.InitRedis()
+	defer models.CloseRedis()
+
+	products := newProductService()
+	n, err := products.RefreshBoughtTogether(context.Background(), time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Found %d product pairs bought together\n", n)
+
+	pruned, err := products.RefreshPopularity(context.Background(), time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Refreshed product popularity, deleting %d old views\n", pruned)
+	return nil
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"coffee-shop/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController struct {
+	audit *services.AuditService
+}
+
+func NewAuditController(audit *services.AuditService) *AuditController {
+	return &AuditController{audit: audit}
+}
+
+// @Summary Get audit log
+// @Description List privileged changes, newest first (Admin)
+// @Tags Admin - Audit Log
+// @Security BearerAuth
+// @Produce json
+// @Param actor_id query int false "Filter by actor (admin user ID)"
+// @Param entity_type query string false "Filter by entity type (user, product, category, order)"
+// @Param entity_id query int false "Filter by entity ID"
+// @Param start_date query string false "From date (format: 2006-01-02)"
+// @Param end_date query string false "To date, inclusive (format: 2006-01-02)"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param cursor query string false "Continue after meta.next_cursor instead of using page"
+// @Success 200 {object} models.ListEnvelopeV2{data=[]models.AuditLogV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /v2/admin/audit-log [get]
+// @DeprecatedRouter /v1/admin/audit-log [get]
+// @DeprecatedRouter /admin/audit-log [get]
+func (ctrl *AuditController) GetAuditLog(c *gin.Context) {
+	page, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+
+	filter := repositories.AuditFilter{EntityType: c.Query("entity_type")}
+	if actorStr := strings.TrimSpace(c.Query("actor_id")); actorStr != "" {
+		actorID, err := strconv.Atoi(actorStr)
+		if err != nil || actorID <= 0 {
+			c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid actor_id")})
+			return
+		}
+		filter.ActorID = actorID
+	}
+
+	if entityStr := strings.TrimSpace(c.Query("entity_id")); entityStr != "" {
+		entityID, err := strconv.Atoi(entityStr)
+		if err != nil || entityID <= 0 {
+			c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid entity_id")})
+			return
+		}
+		filter.EntityID = entityID
+	}
+
+	if startDate := strings.TrimSpace(c.Query("start_date")); startDate != "" {
+		start, err := time.Parse("2006-01-02", startDate)
+		if err != nil {
+			c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid start_date, expected format 2006-01-02")})
+			return
+		}
+		filter.From = start
+	}
+
+	if endDate := strings.TrimSpace(c.Query("end_date")); endDate != "" {
+		end, err := time.Parse("2006-01-02", endDate)
+		if err != nil {
+			c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid end_date, expected format 2006-01-02")})
+			return
+		}
+		filter.Until = end.AddDate(0, 0, 1)
+	}
+
+	entries, total, err := ctrl.audit.List(c.Request.Context(), filter, page)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve audit log")
+		return
+	}
+
+	var next *pagination.Cursor
+	if n := len(entries); n > 0 {
+		next = pagination.Next(page, n, pagination.Cursor{CreatedAt: entries[n-1].CreatedAt, ID: int(entries[n-1].ID)})
+	}
+
+	response := pagination.Response(c, msg(c, "Audit log retrieved successfully"), entries, page, total, next)
+	if isV2(c) {
+		c.JSON(200, models.NewListEnvelopeV2(response, models.NewAuditLogListV2(entries)))
+		return
+	}
+	c.JSON(200, response)
+}
@@ -2,58 +2,25 @@ package controllers
 
 import (
 	"coffee-shop/models"
-	"context"
-	"crypto/rand"
+	"coffee-shop/services"
 	"errors"
 	"fmt"
 	"mime/multipart"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/redis/go-redis/v9"
-	"golang.org/x/crypto/bcrypt"
 )
 
-type AuthController struct{}
-
-func hashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(hash), err
-}
-
-func verifyPassword(hashed, plain string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(plain)) == nil
-}
-
-func generateOTP(length int) (string, error) {
-	const digits = "0123456789"
-	b := make([]byte, length)
-	if _, err := rand.Read(b); err != nil {
-		return "", err
-	}
-	for i := range b {
-		b[i] = digits[int(b[i])%len(digits)]
-	}
-	return string(b), nil
-}
-
-func isValidEmail(email string) bool {
-	re := regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
-	return re.MatchString(email)
+type AuthController struct {
+	auth *services.AuthService
 }
 
-func isValidPassword(password string) bool {
-	return len(password) >= 6
-}
-
-func isValidPhone(phone string) bool {
-	re := regexp.MustCompile(`^[0-9+\-]{8,20}$`)
-	return re.MatchString(phone)
+func NewAuthController(auth *services.AuthService) *AuthController {
+	return &AuthController{auth: auth}
 }
 
 func getJWTSecret() string {
@@ -64,7 +31,9 @@ func getJWTSecret() string {
 	return secret
 }
 
-func generateToken(userID int, email, role string, expiry time.Duration) (string, error) {
+// generateToken signs a JWT for the user. language is the saved locale
+// preference and is only added as a claim when set.
+func generateToken(userID int, email, role, language string, expiry time.Duration) (string, error) {
 	secret := getJWTSecret()
 
 	if expiry <= 0 {
@@ -78,6 +47,9 @@ func generateToken(userID int, email, role string, expiry time.Duration) (string
 		"exp":     time.Now().Add(expiry).Unix(),
 		"iat":     time.Now().Unix(),
 	}
+	if language != "" {
+		claims["language"] = language
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
@@ -116,135 +88,43 @@ func deleteFile(path string) {
 // @Accept json
 // @Produce json
 // @Param body body models.RegisterRequest true "Register payload"
-// @Success 201 {object} models.Response
+// @Success 201 {object} models.EnvelopeV2
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /auth/register [post]
+// @Router /v2/auth/register [post]
+// @DeprecatedRouter /v1/auth/register [post]
+// @DeprecatedRouter /auth/register [post]
 func (ctrl *AuthController) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBind(&req); err != nil {
 		c.JSON(400, models.ErrorResponse{
 			Success: false,
-			Message: "Invalid request payload",
+			Message: msg(c, "Invalid request payload"),
 			Error:   err.Error(),
 		})
 		return
 	}
 
-	email := strings.TrimSpace(req.Email)
-	password := req.Password
-	fullName := strings.TrimSpace(req.FullName)
-	phone := strings.TrimSpace(req.Phone)
-	role := strings.TrimSpace(req.Role)
-	if role == "" {
-		role = "customer"
-	}
-
-	if !isValidEmail(email) {
-		c.JSON(400, models.ErrorResponse{
-			Success: false,
-			Message: "Invalid email format",
-		})
-		return
-	}
-
-	if !isValidPassword(password) {
-		c.JSON(400, models.ErrorResponse{
-			Success: false,
-			Message: "Password must be at least 6 characters",
-		})
-		return
-	}
-
-	if len(fullName) < 3 {
-		c.JSON(400, models.ErrorResponse{
-			Success: false,
-			Message: "Full name must be at least 3 characters",
-		})
-		return
-	}
-
-	if phone != "" && !isValidPhone(phone) {
-		c.JSON(400, models.ErrorResponse{
-			Success: false,
-			Message: "Invalid phone number",
-		})
-		return
-	}
-
-	if role != "customer" && role != "admin" {
-		c.JSON(400, models.ErrorResponse{
-			Success: false,
-			Message: "Role must be 'customer' or 'admin'",
-		})
-		return
-	}
-
-	var exists int
-	if err := models.DB.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM users WHERE email=$1", email,
-	).Scan(&exists); err != nil {
-		c.JSON(500, models.ErrorResponse{
-			Success: false,
-			Message: "Failed to check existing user",
-		})
-		return
-	}
-	if exists > 0 {
-		c.JSON(400, models.ErrorResponse{
-			Success: false,
-			Message: "Email already exists",
-		})
-		return
-	}
-
-	hashed, _ := hashPassword(password)
-	now := time.Now()
-
-	var userID int
-	err := models.DB.QueryRow(
-		context.Background(),
-		`INSERT INTO users (email, password, role, created_at, updated_at) 
-		 VALUES ($1,$2,$3,$4,$5) RETURNING id`,
-		email, hashed, role, now, now,
-	).Scan(&userID)
-
-	if err != nil {
-		c.JSON(500, models.ErrorResponse{
-			Success: false,
-			Message: "Registration failed",
-			Error:   err.Error(),
-		})
-		return
-	}
-
-	_, err = models.DB.Exec(
-		context.Background(),
-		`INSERT INTO user_profiles (user_id, full_name, phone, created_at, updated_at) 
-		 VALUES ($1, $2, $3, $4, $5)`,
-		userID, fullName, phone, now, now,
-	)
-
+	u, err := ctrl.auth.Register(c.Request.Context(), services.RegisterInput{
+		Email:    req.Email,
+		Password: req.Password,
+		FullName: req.FullName,
+		Phone:    req.Phone,
+		Role:     req.Role,
+		Language: req.Language,
+	})
 	if err != nil {
-		c.JSON(201, models.Response{
-			Success: true,
-			Message: "User registered successfully (profile pending)",
-			Data: gin.H{
-				"id":    userID,
-				"email": email,
-				"role":  role,
-			},
-		})
+		respondServiceError(c, err, "Registration failed")
 		return
 	}
 
 	c.JSON(201, models.Response{
 		Success: true,
-		Message: "User registered successfully",
+		Message: msg(c, "User registered successfully"),
 		Data: gin.H{
-			"id":    userID,
-			"email": email,
-			"role":  role,
+			"id":    u.ID,
+			"email": u.Email,
+			"role":  u.Role,
 		},
 	})
 }
@@ -255,115 +135,70 @@ func (ctrl *AuthController) Register(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param body body models.LoginRequest true "Login payload"
-// @Success 200 {object} models.Response
+// @Success 200 {object} models.EnvelopeV2{data=models.LoginV2}
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /auth/login [post]
+// @Router /v2/auth/login [post]
+// @DeprecatedRouter /v1/auth/login [post]
+// @DeprecatedRouter /auth/login [post]
 func (ctrl *AuthController) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBind(&req); err != nil {
 		c.JSON(400, models.ErrorResponse{
 			Success: false,
-			Message: "Invalid request payload",
+			Message: msg(c, "Invalid request payload"),
 			Error:   err.Error(),
 		})
 		return
 	}
 
-	email := strings.TrimSpace(req.Email)
-	password := req.Password
-
-	if !isValidEmail(email) {
-		c.JSON(400, models.ErrorResponse{
-			Success: false,
-			Message: "Invalid email format",
-		})
-		return
-	}
-
-	if password == "" {
-		c.JSON(400, models.ErrorResponse{
-			Success: false,
-			Message: "Password is required",
-		})
-		return
-	}
-
-	var (
-		id   int
-		hash string
-		role string
-	)
-
-	err := models.DB.QueryRow(
-		context.Background(),
-		"SELECT id, password, role FROM users WHERE email=$1",
-		email,
-	).Scan(&id, &hash, &role)
-
+	u, err := ctrl.auth.Login(c.Request.Context(), req.Email, req.Password)
 	if err != nil {
-		c.JSON(401, models.ErrorResponse{
-			Success: false,
-			Message: "Invalid email or password",
-		})
+		respondServiceError(c, err, "Invalid email or password")
 		return
 	}
 
-	if !verifyPassword(hash, password) {
-		c.JSON(401, models.ErrorResponse{
+	token, err := generateToken(u.ID, u.Email, u.Role, u.Language, time.Hour)
+	if err != nil {
+		c.JSON(500, models.ErrorResponse{
 			Success: false,
-			Message: "Invalid email or password",
+			Message: msg(c, "Failed to generate token"),
 		})
 		return
 	}
 
-	var (
-		fullName string
-		phone    string
-		address  string
-		photoURL string
-	)
-
-	profileErr := models.DB.QueryRow(
-		context.Background(),
-		`SELECT COALESCE(up.full_name, ''), COALESCE(up.phone, ''), 
-		        COALESCE(up.address, ''), COALESCE(up.photo_url, '')
-		 FROM users u
-		 LEFT JOIN user_profiles up ON u.id = up.user_id
-		 WHERE u.id = $1`,
-		id,
-	).Scan(&fullName, &phone, &address, &photoURL)
-
-	if profileErr != nil {
-		fullName = ""
-		phone = ""
-		address = ""
-		photoURL = ""
-	}
-
-	token, err := generateToken(id, email, role, time.Hour)
-	if err != nil {
-		c.JSON(500, models.ErrorResponse{
-			Success: false,
-			Message: "Failed to generate token",
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Login successful"), models.LoginV2{
+			Token: token,
+			User: models.UserV2{
+				ID:       u.ID,
+				Email:    u.Email,
+				Role:     u.Role,
+				FullName: u.FullName,
+				Phone:    u.Phone,
+				Address:  u.Address,
+				PhotoURL: u.PhotoURL,
+				Language: u.Language,
+			},
 		})
 		return
 	}
 
 	c.JSON(200, models.Response{
 		Success: true,
-		Message: "Login successful",
+		Message: msg(c, "Login successful"),
 		Data: gin.H{
 			"token": token,
 			"user": gin.H{
-				"id":        id,
-				"email":     email,
-				"role":      role,
-				"full_name": fullName,
-				"phone":     phone,
-				"address":   address,
-				"photo_url": photoURL,
+				"id":        u.ID,
+				"email":     u.Email,
+				"role":      u.Role,
+				"full_name": u.FullName,
+				"phone":     u.Phone,
+				"address":   u.Address,
+				"photo_url": u.PhotoURL,
+				"language":  u.Language,
 			},
 		},
 	})
@@ -374,11 +209,13 @@ func (ctrl *AuthController) Login(c *gin.Context) {
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param email body struct{Email string `json:"email"`} true "Email"
-// @Success 200 {object} models.Response
+// @Param email body object true "Payload with the email"
+// @Success 200 {object} models.EnvelopeV2
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /auth/forgot-password [post]
+// @Router /v2/auth/forgot-password [post]
+// @DeprecatedRouter /v1/auth/forgot-password [post]
+// @DeprecatedRouter /auth/forgot-password [post]
 func (ctrl *AuthController) ForgotPassword(c *gin.Context) {
 	var payload struct {
 		Email string `json:"email" form:"email" binding:"required,email"`
@@ -386,65 +223,20 @@ func (ctrl *AuthController) ForgotPassword(c *gin.Context) {
 	if err := c.ShouldBind(&payload); err != nil {
 		c.JSON(400, models.ErrorResponse{
 			Success: false,
-			Message: "Invalid email",
+			Message: msg(c, "Invalid email"),
 			Error:   err.Error(),
 		})
 		return
 	}
 
-	email := strings.TrimSpace(payload.Email)
-
-	var userID int
-	err := models.DB.QueryRow(context.Background(),
-		"SELECT id FROM users WHERE email=$1",
-		email,
-	).Scan(&userID)
-	if err != nil {
-		c.JSON(200, models.Response{
-			Success: true,
-			Message: "If that email exists, an OTP has been sent",
-		})
-		return
-	}
-
-	otp, err := generateOTP(6)
-	if err != nil {
-		c.JSON(500, models.ErrorResponse{
-			Success: false,
-			Message: "Failed to generate OTP",
-		})
-		return
-	}
-
-	if models.RedisClient == nil {
-		c.JSON(500, models.ErrorResponse{
-			Success: false,
-			Message: "OTP service unavailable",
-		})
-		return
-	}
-
-	ctx := context.Background()
-	key := fmt.Sprintf("otp:%s", strings.ToLower(email))
-	if err := models.RedisClient.Set(ctx, key, otp, 5*time.Minute).Err(); err != nil {
-		c.JSON(500, models.ErrorResponse{
-			Success: false,
-			Message: "Failed to store OTP",
-		})
+	if err := ctrl.auth.SendPasswordReset(c.Request.Context(), payload.Email, requestLocale(c)); err != nil {
+		respondServiceError(c, err, "Failed to store OTP")
 		return
 	}
 
-	emailService, err := models.NewEmailService()
-	if err != nil {
-		fmt.Printf("[OTP Generated - SMTP Not Configured]\n")
-		fmt.Printf("Email: %s\nOTP: %s\nExpires: 5 minutes\n", email, otp)
-	} else {
-		_ = emailService.SendOTPEmail(email, otp)
-	}
-
 	c.JSON(200, models.Response{
 		Success: true,
-		Message: "If that email exists, an OTP has been sent",
+		Message: msg(c, "If that email exists, an OTP has been sent"),
 	})
 }
 
@@ -453,12 +245,13 @@ func (ctrl *AuthController) ForgotPassword(c *gin.Context) {
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param body body struct{
-// @Param body body struct{Email string `json:"email"`; OTP string `json:"otp"`; NewPassword string `json:"new_password"`} true "Verify payload"
-// @Success 200 {object} models.Response
+// @Param body body object true "Verify payload with email, otp and new_password"
+// @Success 200 {object} models.EnvelopeV2
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /auth/verify-otp [post]
+// @Router /v2/auth/verify-otp [post]
+// @DeprecatedRouter /v1/auth/verify-otp [post]
+// @DeprecatedRouter /auth/verify-otp [post]
 func (ctrl *AuthController) VerifyOTP(c *gin.Context) {
 	var payload struct {
 		Email       string `json:"email" form:"email" binding:"required,email"`
@@ -468,69 +261,19 @@ func (ctrl *AuthController) VerifyOTP(c *gin.Context) {
 	if err := c.ShouldBind(&payload); err != nil {
 		c.JSON(400, models.ErrorResponse{
 			Success: false,
-			Message: "Invalid request payload",
+			Message: msg(c, "Invalid request payload"),
 			Error:   err.Error(),
 		})
 		return
 	}
 
-	email := strings.TrimSpace(payload.Email)
-	otp := strings.TrimSpace(payload.OTP)
-
-	if models.RedisClient == nil {
-		c.JSON(500, models.ErrorResponse{
-			Success: false,
-			Message: "OTP service unavailable",
-		})
+	if err := ctrl.auth.ResetPassword(c.Request.Context(), payload.Email, payload.OTP, payload.NewPassword); err != nil {
+		respondServiceError(c, err, "Failed to reset password")
 		return
 	}
 
-	ctx := context.Background()
-	key := fmt.Sprintf("otp:%s", strings.ToLower(email))
-
-	stored, err := models.RedisClient.Get(ctx, key).Result()
-	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			c.JSON(400, models.ErrorResponse{
-				Success: false,
-				Message: "OTP is invalid or expired",
-			})
-			return
-		}
-		c.JSON(500, models.ErrorResponse{
-			Success: false,
-			Message: "Failed to verify OTP",
-		})
-		return
-	}
-
-	if stored != otp {
-		c.JSON(400, models.ErrorResponse{
-			Success: false,
-			Message: "OTP is invalid or expired",
-		})
-		return
-	}
-
-	hashed, _ := hashPassword(payload.NewPassword)
-
-	_, err = models.DB.Exec(
-		context.Background(),
-		"UPDATE users SET password=$1, updated_at=$2 WHERE email=$3",
-		hashed, time.Now(), email,
-	)
-	if err != nil {
-		c.JSON(500, models.ErrorResponse{
-			Success: false,
-			Message: "Failed to reset password",
-		})
-		return
-	}
-
-	_ = models.RedisClient.Del(ctx, key).Err()
-
 	c.JSON(200, models.Response{
 		Success: true,
-		Message: "Password reset successfully",
+		Message: msg(c, "Password reset successfully"),
 	})
 }
@@ -1,44 +1,64 @@
 package controllers
 
 import (
+	"coffee-shop/cache"
 	"coffee-shop/models"
-	"context"
-	"strings"
+	"coffee-shop/services"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-type CategoryController struct{}
+// categoryCacheTTL bounds how long categories are cached; every category
+// write invalidates them anyway.
+const categoryCacheTTL = time.Hour
+
+type CategoryController struct {
+	categories *services.CategoryService
+	cache      *cache.Namespace
+}
+
+func NewCategoryController(categories *services.CategoryService, responses cache.Store) *CategoryController {
+	return &CategoryController{
+		categories: categories,
+		cache:      cache.NewNamespace(responses, services.CategoryCacheNamespace),
+	}
+}
 
 // @Summary Get all categories
 // @Description Get list of all categories
 // @Tags Categories
 // @Produce json
-// @Success 200 {object} models.Response
-// @Router /categories [get]
+// @Param If-None-Match header string false "ETag of the copy the client has"
+// @Param If-Modified-Since header string false "Last-Modified of the copy the client has"
+// @Success 200 {object} models.EnvelopeV2{data=[]models.CategoryV2}
+// @Success 304 "Not modified"
+// @Router /v2/categories [get]
+// @DeprecatedRouter /v1/categories [get]
+// @DeprecatedRouter /categories [get]
 func (ctrl *CategoryController) GetCategories(c *gin.Context) {
-	rows, _ := models.DB.Query(context.Background(),
-		"SELECT id, name, created_at FROM categories ORDER BY id")
-	defer rows.Close()
-
-	categories := []gin.H{}
-	for rows.Next() {
-		var id int
-		var name string
-		var createdAt time.Time
-		rows.Scan(&id, &name, &createdAt)
-		categories = append(categories, gin.H{
-			"id":        id,
-			"name":      name,
-			"createdAt": createdAt,
-		})
-	}
-
-	c.JSON(200, gin.H{
-		"success": true,
-		"message": "Categories retrieved successfully",
-		"data":    categories,
+	serveCached(c, ctrl.cache, "list_"+requestLocale(c), "Failed to retrieve categories", func() (cacheable, error) {
+		categories, err := ctrl.categories.List(c.Request.Context(), requestLocale(c))
+		if err != nil {
+			return cacheable{}, err
+		}
+
+		var modified time.Time
+		data := make([]models.CategoryV2, 0, len(categories))
+		for _, category := range categories {
+			data = append(data, models.NewCategoryV2(category))
+			if category.UpdatedAt.After(modified) {
+				modified = category.UpdatedAt
+			}
+		}
+
+		return cacheable{body: gin.H{
+			"success": true,
+			"message": msg(c, "Categories retrieved successfully"),
+			"data":    data,
+		}, modified: modified, ttl: categoryCacheTTL}, nil
 	})
 }
 
@@ -47,35 +67,29 @@ func (ctrl *CategoryController) GetCategories(c *gin.Context) {
 // @Tags Categories
 // @Produce json
 // @Param id path int true "Category ID"
-// @Success 200 {object} models.Response
+// @Param If-None-Match header string false "ETag of the copy the client has"
+// @Param If-Modified-Since header string false "Last-Modified of the copy the client has"
+// @Success 200 {object} models.EnvelopeV2{data=models.CategoryV2}
+// @Success 304 "Not modified"
 // @Failure 404 {object} models.ErrorResponse
-// @Router /categories/{id} [get]
+// @Router /v2/categories/{id} [get]
+// @DeprecatedRouter /v1/categories/{id} [get]
+// @DeprecatedRouter /categories/{id} [get]
 func (ctrl *CategoryController) GetCategoryByID(c *gin.Context) {
-	id := c.Param("id")
-
-	var categoryID int
-	var name string
-	var createdAt time.Time
-	err := models.DB.QueryRow(context.Background(),
-		"SELECT id, name, created_at FROM categories WHERE id=$1",
-		id).Scan(&categoryID, &name, &createdAt)
-
-	if err != nil {
-		c.JSON(404, gin.H{
-			"success": false,
-			"message": "Category not found",
-		})
-		return
-	}
-
-	c.JSON(200, gin.H{
-		"success": true,
-		"message": "Category retrieved successfully",
-		"data": gin.H{
-			"id":        categoryID,
-			"name":      name,
-			"createdAt": createdAt,
-		},
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	key := fmt.Sprintf("category_%d_%s", id, requestLocale(c))
+	serveCached(c, ctrl.cache, key, "Category not found", func() (cacheable, error) {
+		category, err := ctrl.categories.Get(c.Request.Context(), id, requestLocale(c))
+		if err != nil {
+			return cacheable{}, err
+		}
+
+		return cacheable{body: gin.H{
+			"success": true,
+			"message": msg(c, "Category retrieved successfully"),
+			"data":    models.NewCategoryV2(category),
+		}, modified: category.UpdatedAt, ttl: categoryCacheTTL}, nil
 	})
 }
 
@@ -86,60 +100,21 @@ func (ctrl *CategoryController) GetCategoryByID(c *gin.Context) {
 // @Produce json
 // @Param name formData string true "Category name"
 // @Security BearerAuth
-// @Success 201 {object} models.Response
-// @Router /admin/categories [post]
+// @Success 201 {object} models.EnvelopeV2{data=models.CategoryV2}
+// @Router /v2/admin/categories [post]
+// @DeprecatedRouter /v1/admin/categories [post]
+// @DeprecatedRouter /admin/categories [post]
 func (ctrl *CategoryController) CreateCategory(c *gin.Context) {
-	name := strings.TrimSpace(c.PostForm("name"))
-
-	if name == "" {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "Name is required",
-		})
-		return
-	}
-
-	if len(name) < 3 {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "Category name must be at least 3 characters",
-		})
-		return
-	}
-
-	var exists int
-	models.DB.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM categories WHERE name=$1", name).Scan(&exists)
-	if exists > 0 {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "Category name already exists",
-		})
-		return
-	}
-
-	var categoryID int
-	var createdAt time.Time
-	err := models.DB.QueryRow(context.Background(),
-		"INSERT INTO categories (name, created_at) VALUES ($1, NOW()) RETURNING id, created_at",
-		name).Scan(&categoryID, &createdAt)
-
+	category, err := ctrl.categories.Create(c.Request.Context(), actorFrom(c), c.PostForm("name"))
 	if err != nil {
-		c.JSON(500, gin.H{
-			"success": false,
-			"message": "Failed to create category",
-		})
+		respondServiceError(c, err, "Failed to create category")
 		return
 	}
 
 	c.JSON(201, gin.H{
 		"success": true,
-		"message": "Category created successfully",
-		"data": gin.H{
-			"id":        categoryID,
-			"name":      name,
-			"createdAt": createdAt,
-		},
+		"message": msg(c, "Category created successfully"),
+		"data":    models.NewCategoryV2(category),
 	})
 }
 
@@ -151,64 +126,21 @@ func (ctrl *CategoryController) CreateCategory(c *gin.Context) {
 // @Param id path int true "Category ID"
 // @Param name formData string true "Category name"
 // @Security BearerAuth
-// @Success 200 {object} models.Response
-// @Router /admin/categories/{id} [patch]
+// @Success 200 {object} models.EnvelopeV2
+// @Router /v2/admin/categories/{id} [patch]
+// @DeprecatedRouter /v1/admin/categories/{id} [patch]
+// @DeprecatedRouter /admin/categories/{id} [patch]
 func (ctrl *CategoryController) UpdateCategory(c *gin.Context) {
-	id := c.Param("id")
-	name := strings.TrimSpace(c.PostForm("name"))
-
-	if name == "" {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "Name is required",
-		})
-		return
-	}
-
-	if len(name) < 3 {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "Category name must be at least 3 characters",
-		})
-		return
-	}
-
-	var exists int
-	models.DB.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM categories WHERE id=$1", id).Scan(&exists)
-	if exists == 0 {
-		c.JSON(404, gin.H{
-			"success": false,
-			"message": "Category not found",
-		})
-		return
-	}
+	id, _ := strconv.Atoi(c.Param("id"))
 
-	var nameExists int
-	models.DB.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM categories WHERE name=$1 AND id!=$2", name, id).Scan(&nameExists)
-	if nameExists > 0 {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "Category name already exists",
-		})
-		return
-	}
-
-	_, err := models.DB.Exec(context.Background(),
-		"UPDATE categories SET name=$1 WHERE id=$2", name, id)
-
-	if err != nil {
-		c.JSON(500, gin.H{
-			"success": false,
-			"message": "Failed to update category",
-		})
+	if err := ctrl.categories.Update(c.Request.Context(), actorFrom(c), id, c.PostForm("name")); err != nil {
+		respondServiceError(c, err, "Failed to update category")
 		return
 	}
 
 	c.JSON(200, gin.H{
 		"success": true,
-		"message": "Category updated successfully",
+		"message": msg(c, "Category updated successfully"),
 	})
 }
 
@@ -218,35 +150,20 @@ func (ctrl *CategoryController) UpdateCategory(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Category ID"
 // @Security BearerAuth
-// @Success 200 {object} models.Response
-// @Router /admin/categories/{id} [delete]
+// @Success 200 {object} models.EnvelopeV2
+// @Router /v2/admin/categories/{id} [delete]
+// @DeprecatedRouter /v1/admin/categories/{id} [delete]
+// @DeprecatedRouter /admin/categories/{id} [delete]
 func (ctrl *CategoryController) DeleteCategory(c *gin.Context) {
-	id := c.Param("id")
-
-	var exists int
-	models.DB.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM categories WHERE id=$1", id).Scan(&exists)
-	if exists == 0 {
-		c.JSON(404, gin.H{
-			"success": false,
-			"message": "Category not found",
-		})
-		return
-	}
+	id, _ := strconv.Atoi(c.Param("id"))
 
-	_, err := models.DB.Exec(context.Background(),
-		"DELETE FROM categories WHERE id=$1", id)
-
-	if err != nil {
-		c.JSON(500, gin.H{
-			"success": false,
-			"message": "Failed to delete category",
-		})
+	if err := ctrl.categories.Delete(c.Request.Context(), actorFrom(c), id); err != nil {
+		respondServiceError(c, err, "Failed to delete category")
 		return
 	}
 
 	c.JSON(200, gin.H{
 		"success": true,
-		"message": "Category deleted successfully",
+		"message": msg(c, "Category deleted successfully"),
 	})
 }
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FavoriteController lets signed-in customers keep a list of favourite
+// products.
+type FavoriteController struct {
+	products *services.ProductService
+}
+
+func NewFavoriteController(products *services.ProductService) *FavoriteController {
+	return &FavoriteController{products: products}
+}
+
+// @Summary Get favourite products
+// @Description The signed-in user's favourite products, latest favourite first
+// @Tags Profile
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param cursor query string false "Continue after meta.next_cursor instead of using page"
+// @Success 200 {object} models.ListEnvelopeV2{data=[]models.ProductV2}
+// @Router /v2/profile/favorites [get]
+// @DeprecatedRouter /v1/profile/favorites [get]
+// @DeprecatedRouter /profile/favorites [get]
+func (ctrl *FavoriteController) GetFavorites(c *gin.Context) {
+	page, ok := pageParams(c, 10)
+	if !ok {
+		return
+	}
+
+	products, total, err := ctrl.products.FavoriteProducts(c.Request.Context(), c.GetInt("user_id"), requestLocale(c), page)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve favorites")
+		return
+	}
+
+	var next *pagination.Cursor
+	if n := len(products); n > 0 {
+		next = pagination.Next(page, n, pagination.Cursor{CreatedAt: *products[n-1].FavoritedAt, ID: products[n-1].ID})
+	}
+
+	response := pagination.Response(c, msg(c, "Favorites retrieved successfully"), products, page, total, next)
+	if isV2(c) {
+		c.JSON(200, models.NewListEnvelopeV2(response, models.NewProductListV2(products)))
+		return
+	}
+	c.JSON(200, response)
+}
+
+// @Summary Add a favourite
+// @Description Add an active product to the signed-in user's favourites; adding it again changes nothing
+// @Tags Profile
+// @Security BearerAuth
+// @Produce json
+// @Param productId path int true "Product ID"
+// @Success 200 {object} models.EnvelopeV2
+// @Success 201 {object} models.EnvelopeV2
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/profile/favorites/{productId} [post]
+// @DeprecatedRouter /v1/profile/favorites/{productId} [post]
+// @DeprecatedRouter /profile/favorites/{productId} [post]
+func (ctrl *FavoriteController) AddFavorite(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Param("productId"))
+
+	added, err := ctrl.products.AddFavorite(c.Request.Context(), c.GetInt("user_id"), productID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update favorites")
+		return
+	}
+
+	status, message := 201, "Product added to favorites"
+	if !added {
+		status, message = 200, "Product is already in your favorites"
+	}
+	c.JSON(status, gin.H{
+		"success": true,
+		"message": msg(c, message),
+	})
+}
+
+// @Summary Remove a favourite
+// @Tags Profile
+// @Security BearerAuth
+// @Produce json
+// @Param productId path int true "Product ID"
+// @Success 200 {object} models.EnvelopeV2
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/profile/favorites/{productId} [delete]
+// @DeprecatedRouter /v1/profile/favorites/{productId} [delete]
+// @DeprecatedRouter /profile/favorites/{productId} [delete]
+func (ctrl *FavoriteController) RemoveFavorite(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Param("productId"))
+
+	if err := ctrl.products.RemoveFavorite(c.Request.Context(), c.GetInt("user_id"), productID); err != nil {
+		respondServiceError(c, err, "Failed to update favorites")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Product removed from favorites"),
+	})
+}
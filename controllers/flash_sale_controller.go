@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FlashSaleController struct {
+	flashSales *services.FlashSaleService
+}
+
+func NewFlashSaleController(flashSales *services.FlashSaleService) *FlashSaleController {
+	return &FlashSaleController{flashSales: flashSales}
+}
+
+// respondFlashSale answers with one flash sale and its products.
+func respondFlashSale(c *gin.Context, status int, message string, sale models.FlashSale) {
+	if isV2(c) {
+		respondV2(c, status, msg(c, message), models.NewFlashSaleV2(sale))
+		return
+	}
+	c.JSON(status, gin.H{
+		"success": true,
+		"message": msg(c, message),
+		"data":    sale,
+	})
+}
+
+// @Summary Get active flash sales
+// @Description List the flash sales running now with their products and sale prices, plus the server time and the seconds left for a countdown
+// @Tags Flash Sales
+// @Produce json
+// @Success 200 {object} models.EnvelopeV2
+// @Router /v2/flash-sales/active [get]
+// @DeprecatedRouter /v1/flash-sales/active [get]
+// @DeprecatedRouter /flash-sales/active [get]
+func (ctrl *FlashSaleController) GetActiveFlashSales(c *gin.Context) {
+	sales, now, err := ctrl.flashSales.Active(c.Request.Context(), requestLocale(c))
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve flash sales")
+		return
+	}
+
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Active flash sales retrieved"), gin.H{
+			"serverTime": now,
+			"flashSales": models.NewActiveFlashSaleListV2(sales),
+		})
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Active flash sales retrieved"),
+		"data": gin.H{
+			"server_time": now,
+			"flash_sales": sales,
+		},
+	})
+}
+
+// @Summary Get flash sales
+// @Description List every flash sale, latest start first (Admin)
+// @Tags Admin - Flash Sales
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.EnvelopeV2{data=[]models.FlashSaleV2}
+// @Router /v2/admin/flash-sales [get]
+// @DeprecatedRouter /v1/admin/flash-sales [get]
+// @DeprecatedRouter /admin/flash-sales [get]
+func (ctrl *FlashSaleController) GetFlashSales(c *gin.Context) {
+	sales, err := ctrl.flashSales.List(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve flash sales")
+		return
+	}
+
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Flash sales retrieved"), models.NewFlashSaleListV2(sales))
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Flash sales retrieved"),
+		"data":    sales,
+	})
+}
+
+// @Summary Get flash sale
+// @Tags Admin - Flash Sales
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Flash sale ID"
+// @Success 200 {object} models.EnvelopeV2{data=models.FlashSaleV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/flash-sales/{id} [get]
+// @DeprecatedRouter /v1/admin/flash-sales/{id} [get]
+// @DeprecatedRouter /admin/flash-sales/{id} [get]
+func (ctrl *FlashSaleController) GetFlashSale(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	sale, err := ctrl.flashSales.Get(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve flash sales")
+		return
+	}
+	respondFlashSale(c, 200, "Flash sale retrieved", sale)
+}
+
+// @Summary Create flash sale
+// @Description Put products on sale between two times, each at a sale price or a percentage off, optionally limited per customer (Admin)
+// @Tags Admin - Flash Sales
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.FlashSaleRequest true "Flash sale"
+// @Success 201 {object} models.EnvelopeV2{data=models.FlashSaleV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /v2/admin/flash-sales [post]
+// @DeprecatedRouter /v1/admin/flash-sales [post]
+// @DeprecatedRouter /admin/flash-sales [post]
+func (ctrl *FlashSaleController) CreateFlashSale(c *gin.Context) {
+	in, ok := bindFlashSale(c)
+	if !ok {
+		return
+	}
+
+	sale, err := ctrl.flashSales.Create(c.Request.Context(), actorFrom(c), in)
+	if err != nil {
+		respondServiceError(c, err, "Failed to create flash sale")
+		return
+	}
+	respondFlashSale(c, 201, "Flash sale created", sale)
+}
+
+// @Summary Update flash sale
+// @Description Replace a flash sale's times and products (Admin)
+// @Tags Admin - Flash Sales
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Flash sale ID"
+// @Param request body models.FlashSaleRequest true "Flash sale"
+// @Success 200 {object} models.EnvelopeV2{data=models.FlashSaleV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /v2/admin/flash-sales/{id} [put]
+// @DeprecatedRouter /v1/admin/flash-sales/{id} [put]
+// @DeprecatedRouter /admin/flash-sales/{id} [put]
+func (ctrl *FlashSaleController) UpdateFlashSale(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	in, ok := bindFlashSale(c)
+	if !ok {
+		return
+	}
+
+	sale, err := ctrl.flashSales.Update(c.Request.Context(), actorFrom(c), id, in)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update flash sale")
+		return
+	}
+	respondFlashSale(c, 200, "Flash sale updated", sale)
+}
+
+// @Summary Delete flash sale
+// @Description Remove a flash sale; orders placed during it keep their prices (Admin)
+// @Tags Admin - Flash Sales
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Flash sale ID"
+// @Success 200 {object} models.EnvelopeV2
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/flash-sales/{id} [delete]
+// @DeprecatedRouter /v1/admin/flash-sales/{id} [delete]
+// @DeprecatedRouter /admin/flash-sales/{id} [delete]
+func (ctrl *FlashSaleController) DeleteFlashSale(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	if err := ctrl.flashSales.Delete(c.Request.Context(), actorFrom(c), id); err != nil {
+		respondServiceError(c, err, "Failed to delete flash sale")
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Flash sale deleted"),
+	})
+}
+
+func bindFlashSale(c *gin.Context) (services.FlashSaleInput, bool) {
+	var req models.FlashSaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid request data: ") + err.Error()})
+		return services.FlashSaleInput{}, false
+	}
+
+	in := services.FlashSaleInput{
+		Name:     req.Name,
+		StartsAt: req.StartsAt.In(time.UTC),
+		EndsAt:   req.EndsAt.In(time.UTC),
+		IsActive: req.IsActive == nil || *req.IsActive,
+		Items:    make([]models.FlashSaleItem, 0, len(req.Items)),
+	}
+	for _, item := range req.Items {
+		in.Items = append(in.Items, models.FlashSaleItem{
+			ProductID:       item.ProductID,
+			SalePrice:       item.SalePrice,
+			DiscountPercent: item.DiscountPercent,
+			MaxPerCustomer:  item.MaxPerCustomer,
+		})
+	}
+	return in, true
+}
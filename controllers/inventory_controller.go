@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InventoryController records stock movements and reports low stock.
+type InventoryController struct {
+	products *services.ProductService
+}
+
+func NewInventoryController(products *services.ProductService) *InventoryController {
+	return &InventoryController{products: products}
+}
+
+// respondStockMovement answers with the ledger entry a stock change made.
+func respondStockMovement(c *gin.Context, status int, message string, m models.StockMovement) {
+	if isV2(c) {
+		respondV2(c, status, msg(c, message), models.StockMovementV2(m))
+		return
+	}
+	c.JSON(status, gin.H{
+		"success": true,
+		"message": msg(c, message),
+		"data":    m,
+	})
+}
+
+// @Summary Get stock movements
+// @Description List a product's stock ledger, newest first: sales, restocks, adjustments, cancellation returns and waste (Admin)
+// @Tags Admin - Inventory
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param cursor query string false "Continue after meta.next_cursor instead of using page"
+// @Success 200 {object} models.ListEnvelopeV2{data=[]models.StockMovementV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/stock-movements [get]
+// @DeprecatedRouter /v1/admin/products/{id}/stock-movements [get]
+// @DeprecatedRouter /admin/products/{id}/stock-movements [get]
+func (ctrl *InventoryController) GetStockMovements(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	page, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+
+	movements, total, err := ctrl.products.StockMovements(c.Request.Context(), id, page)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve stock movements")
+		return
+	}
+
+	var next *pagination.Cursor
+	if n := len(movements); n > 0 {
+		next = pagination.Next(page, n, pagination.Cursor{CreatedAt: movements[n-1].CreatedAt, ID: movements[n-1].ID})
+	}
+
+	response := pagination.Response(c, msg(c, "Stock movements retrieved successfully"), movements, page, total, next)
+	if isV2(c) {
+		c.JSON(200, models.NewListEnvelopeV2(response, models.NewStockMovementListV2(movements)))
+		return
+	}
+	c.JSON(200, response)
+}
+
+// @Summary Restock product
+// @Description Add a delivery to a product's stock (Admin)
+// @Tags Admin - Inventory
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param quantity formData int true "Units received"
+// @Param reason formData string false "Reason, such as the supplier or delivery note"
+// @Success 201 {object} models.EnvelopeV2{data=models.StockMovementV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/restock [post]
+// @DeprecatedRouter /v1/admin/products/{id}/restock [post]
+// @DeprecatedRouter /admin/products/{id}/restock [post]
+func (ctrl *InventoryController) RestockProduct(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	quantity, _ := strconv.Atoi(c.PostForm("quantity"))
+
+	m, err := ctrl.products.Restock(c.Request.Context(), actorFrom(c), id, quantity, c.PostForm("reason"))
+	if err != nil {
+		respondServiceError(c, err, "Failed to update stock")
+		return
+	}
+	respondStockMovement(c, 201, "Stock movement recorded", m)
+}
+
+// @Summary Record stocktake
+// @Description Set a product's stock to the counted level; the difference is recorded as an adjustment (Admin)
+// @Tags Admin - Inventory
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param counted formData int true "Units counted"
+// @Param reason formData string false "Reason, default Stocktake"
+// @Success 200 {object} models.EnvelopeV2{data=models.StockMovementV2}
+// @Success 201 {object} models.EnvelopeV2{data=models.StockMovementV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/stocktake [post]
+// @DeprecatedRouter /v1/admin/products/{id}/stocktake [post]
+// @DeprecatedRouter /admin/products/{id}/stocktake [post]
+func (ctrl *InventoryController) StocktakeProduct(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	counted := postFormInt(c, "counted")
+	if counted == nil {
+		c.JSON(400, models.ErrorResponse{Success: false, Message: msg(c, "Counted stock is required")})
+		return
+	}
+
+	m, err := ctrl.products.Stocktake(c.Request.Context(), actorFrom(c), id, *counted, c.PostForm("reason"))
+	if err != nil {
+		respondServiceError(c, err, "Failed to update stock")
+		return
+	}
+	if m.ID == 0 {
+		respondStockMovement(c, 200, "Stock count matches, nothing to adjust", m)
+		return
+	}
+	respondStockMovement(c, 201, "Stock movement recorded", m)
+}
+
+// @Summary Write off stock
+// @Description Remove spoiled or damaged units from a product's stock (Admin)
+// @Tags Admin - Inventory
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param quantity formData int true "Units written off"
+// @Param reason formData string true "Reason"
+// @Success 201 {object} models.EnvelopeV2{data=models.StockMovementV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/waste [post]
+// @DeprecatedRouter /v1/admin/products/{id}/waste [post]
+// @DeprecatedRouter /admin/products/{id}/waste [post]
+func (ctrl *InventoryController) WasteProduct(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	quantity, _ := strconv.Atoi(c.PostForm("quantity"))
+
+	m, err := ctrl.products.Waste(c.Request.Context(), actorFrom(c), id, quantity, c.PostForm("reason"))
+	if err != nil {
+		respondServiceError(c, err, "Failed to update stock")
+		return
+	}
+	respondStockMovement(c, 201, "Stock movement recorded", m)
+}
+
+// @Summary Get low stock products
+// @Description List the products below their low-stock threshold, lowest stock first (Admin)
+// @Tags Admin - Inventory
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.EnvelopeV2{data=[]models.ProductV2}
+// @Router /v2/admin/inventory/low-stock [get]
+// @DeprecatedRouter /v1/admin/inventory/low-stock [get]
+// @DeprecatedRouter /admin/inventory/low-stock [get]
+func (ctrl *InventoryController) GetLowStockProducts(c *gin.Context) {
+	products, err := ctrl.products.LowStock(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve low stock products")
+		return
+	}
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Low stock products retrieved successfully"), models.NewProductListV2(products))
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Low stock products retrieved successfully"),
+		"data":    products,
+	})
+}
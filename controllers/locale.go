@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"coffee-shop/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestLocale returns the locale chosen by LocaleMiddleware (or the user's
+// saved preference), falling back to the default when the middleware is absent.
+func requestLocale(c *gin.Context) string {
+	if locale := c.GetString("locale"); locale != "" {
+		return locale
+	}
+	return i18n.DefaultLocale()
+}
+
+// msg translates a user-facing message into the request locale.
+func msg(c *gin.Context, id string) string {
+	return i18n.T(requestLocale(c), id)
+}
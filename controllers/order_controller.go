@@ -2,106 +2,22 @@ package controllers
 
 import (
 	"coffee-shop/models"
-	"context"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"coffee-shop/services"
 	"fmt"
-	"net/url"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-type OrderController struct{}
-
-func (ctrl *OrderController) getPaginationParams(c *gin.Context, defaultLimit int) (page, limit, offset int) {
-	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = defaultLimit
-	}
-	if limit > 100 {
-		limit = 100
-	}
-
-	offset = (page - 1) * limit
-	return page, limit, offset
+type OrderController struct {
+	orders *services.OrderService
 }
 
-func (ctrl *OrderController) generateLinks(c *gin.Context, page, limit, totalPages int) models.PaginationLinks {
-	scheme := "https"
-	if c.Request.TLS == nil {
-		scheme = "http"
-	}
-
-	host := c.Request.Host
-	path := c.Request.URL.Path
-	queryParams := c.Request.URL.Query()
-
-	makeURL := func(pageNum int) string {
-		newParams := url.Values{}
-		for key, values := range queryParams {
-			if key != "page" {
-				for _, value := range values {
-					newParams.Add(key, value)
-				}
-			}
-		}
-		newParams.Set("page", strconv.Itoa(pageNum))
-		newParams.Set("limit", strconv.Itoa(limit))
-		return fmt.Sprintf("%s://%s%s?%s", scheme, host, path, newParams.Encode())
-	}
-
-	links := models.PaginationLinks{
-		Self: makeURL(page),
-	}
-
-	if page > 1 {
-		prevURL := makeURL(page - 1)
-		links.Prev = prevURL
-	}
-
-	if page < totalPages {
-		nextURL := makeURL(page + 1)
-		links.Next = nextURL
-	}
-
-	return links
-}
-
-func (ctrl *OrderController) buildResponse(c *gin.Context, message string, data interface{}, page, limit, totalItems int) models.HATEOASResponse {
-	totalPages := 0
-	if totalItems > 0 {
-		totalPages = (totalItems + limit - 1) / limit
-	}
-
-	if page > totalPages && totalPages > 0 {
-		page = totalPages
-	}
-	if page < 1 {
-		page = 1
-	}
-
-	meta := models.PaginationMeta{
-		Page:       page,
-		Limit:      limit,
-		TotalItems: totalItems,
-		TotalPages: totalPages,
-	}
-
-	links := ctrl.generateLinks(c, page, limit, totalPages)
-
-	return models.HATEOASResponse{
-		Success: true,
-		Message: message,
-		Data:    data,
-		Meta:    meta,
-		Links:   links,
-	}
+func NewOrderController(orders *services.OrderService) *OrderController {
+	return &OrderController{orders: orders}
 }
 
 // @Summary Get all orders
@@ -111,188 +27,136 @@ func (ctrl *OrderController) buildResponse(c *gin.Context, message string, data
 // @Produce json
 // @Param page query int false "Page number"
 // @Param limit query int false "Items per page"
+// @Param cursor query string false "Continue after meta.next_cursor instead of using page"
 // @Param status query string false "Filter by status"
 // @Param search query string false "Search by order number"
-// @Success 200 {object} models.HATEOASResponse
-// @Router /admin/orders [get]
+// @Success 200 {object} models.ListEnvelopeV2{data=[]models.OrderSummaryV2}
+// @Router /v2/admin/orders [get]
+// @DeprecatedRouter /v1/admin/orders [get]
+// @DeprecatedRouter /admin/orders [get]
 func (ctrl *OrderController) GetAllOrders(c *gin.Context) {
-	page, limit, offset := ctrl.getPaginationParams(c, 10)
-
-	status := c.Query("status")
-	search := c.Query("search")
-
-	var total int
-	countQuery := "SELECT COUNT(*) FROM orders"
-	countArgs := []interface{}{}
-
-	whereConditions := []string{}
-	if status != "" && status != "All" {
-		whereConditions = append(whereConditions, "status = $1")
-		countArgs = append(countArgs, status)
-	}
-
-	if len(whereConditions) > 0 {
-		countQuery += " WHERE " + strings.Join(whereConditions, " AND ")
-		err := models.DB.QueryRow(context.Background(), countQuery, countArgs...).Scan(&total)
-		if err != nil {
-			fmt.Println("Count error:", err)
-			c.JSON(500, gin.H{"success": false, "message": "Failed to count orders"})
-			return
-		}
-	} else {
-		err := models.DB.QueryRow(context.Background(), countQuery).Scan(&total)
-		if err != nil {
-			fmt.Println("Count error:", err)
-			c.JSON(500, gin.H{"success": false, "message": "Failed to count orders"})
-			return
-		}
-	}
-
-	fmt.Println("Total orders in DB:", total)
-
-	query := `
-		SELECT 
-			o.id,
-			o.user_id,
-			o.subtotal,
-			o.created_at
-		FROM orders o
-	`
-
-	queryArgs := []interface{}{}
-	argIndex := 1
-
-	whereConditions = []string{}
-
-	if status != "" && status != "All" {
-		whereConditions = append(whereConditions, fmt.Sprintf("o.status = $%d", argIndex))
-		queryArgs = append(queryArgs, status)
-		argIndex++
-	}
-
-	if search != "" {
-		whereConditions = append(whereConditions, fmt.Sprintf("CAST(o.id AS TEXT) LIKE $%d", argIndex))
-		queryArgs = append(queryArgs, "%"+search+"%")
-		argIndex++
-	}
-
-	if len(whereConditions) > 0 {
-		query += " WHERE " + strings.Join(whereConditions, " AND ")
+	page, ok := pageParams(c, 10)
+	if !ok {
+		return
 	}
 
-	query += fmt.Sprintf(" ORDER BY o.created_at DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
-	queryArgs = append(queryArgs, limit, offset)
-
-	fmt.Println("Executing query:", query)
-	fmt.Println("With args:", queryArgs)
-
-	rows, err := models.DB.Query(context.Background(), query, queryArgs...)
+	list, total, err := ctrl.orders.List(c.Request.Context(), repositories.OrderFilter{
+		Params: page,
+		Status: c.Query("status"),
+		Search: c.Query("search"),
+	})
 	if err != nil {
-		fmt.Println("Query execution error:", err)
-		c.JSON(500, gin.H{
-			"success": false,
-			"message": "Query error: " + err.Error(),
-		})
+		respondServiceError(c, err, "Failed to count orders")
 		return
 	}
-	defer rows.Close()
 
 	orders := []gin.H{}
-	rowCount := 0
-
-	for rows.Next() {
-		var id, userID, subtotal int
-		var createdAt time.Time
-
-		err := rows.Scan(&id, &userID, &subtotal, &createdAt)
-		if err != nil {
-			fmt.Println("Scan error on row", rowCount, ":", err)
-			continue
-		}
-
-		rowCount++
-		fmt.Println("Scanned order:", id, userID, subtotal)
+	ordersV2 := []models.OrderSummaryV2{}
+	for _, o := range list {
+		// v1 has always numbered orders after their ID; v2 sends the stored
+		// number, as history and detail do.
+		orderNumber := fmt.Sprintf("ORD-%d", o.ID)
+		customerName, deliveryAddress := legacyOrderContact(o)
 
 		orders = append(orders, gin.H{
-			"id":               id,
-			"order_id":         id,
-			"orderId":          id,
-			"order_number":     fmt.Sprintf("ORD-%d", id),
-			"orderNumber":      fmt.Sprintf("ORD-%d", id),
-			"user_id":          userID,
-			"userId":           userID,
-			"status":           "pending",
-			"subtotal":         subtotal,
-			"total":            subtotal,
-			"customer_name":    "Customer",
-			"customerName":     "Customer",
-			"delivery_address": "N/A",
-			"deliveryAddress":  "N/A",
-			"created_at":       createdAt,
-			"createdAt":        createdAt,
+			"id":               o.ID,
+			"order_id":         o.ID,
+			"orderId":          o.ID,
+			"order_number":     orderNumber,
+			"orderNumber":      orderNumber,
+			"user_id":          o.UserID,
+			"userId":           o.UserID,
+			"status":           o.Status,
+			"subtotal":         o.Subtotal,
+			"total":            o.Total,
+			"customer_name":    customerName,
+			"customerName":     customerName,
+			"delivery_address": deliveryAddress,
+			"deliveryAddress":  deliveryAddress,
+			"created_at":       o.CreatedAt,
+			"createdAt":        o.CreatedAt,
+		})
+		ordersV2 = append(ordersV2, models.OrderSummaryV2{
+			ID:              o.ID,
+			OrderNumber:     o.OrderNumber,
+			UserID:          o.UserID,
+			Status:          o.Status,
+			Subtotal:        o.Subtotal,
+			Total:           o.Total,
+			CustomerName:    o.CustomerName,
+			DeliveryAddress: o.DeliveryAddress,
+			CreatedAt:       o.CreatedAt,
 		})
 	}
 
-	fmt.Println("Total rows scanned:", rowCount)
-	fmt.Println("Orders array length:", len(orders))
+	var next *pagination.Cursor
+	if n := len(list); n > 0 {
+		next = pagination.Next(page, n, pagination.Cursor{CreatedAt: list[n-1].CreatedAt, ID: list[n-1].ID})
+	}
 
-	response := ctrl.buildResponse(c, "Orders retrieved successfully", orders, page, limit, total)
+	response := pagination.Response(c, msg(c, "Orders retrieved successfully"), orders, page, total, next)
+	if isV2(c) {
+		c.JSON(200, models.NewListEnvelopeV2(response, ordersV2))
+		return
+	}
 	c.JSON(200, response)
 }
 
+// legacyOrderContact returns the order's customer name and delivery
+// address for v1, which has always sent both: it keeps its old placeholders
+// for a missing value. v2 leaves missing values out.
+func legacyOrderContact(o models.OrderSummaryV2) (string, string) {
+	customerName, deliveryAddress := o.CustomerName, o.DeliveryAddress
+	if customerName == "" {
+		customerName = "Customer"
+	}
+	if deliveryAddress == "" {
+		deliveryAddress = "N/A"
+	}
+	return customerName, deliveryAddress
+}
+
 // @Summary Get order by ID
 // @Description Get order details (Admin)
 // @Tags Admin - Orders
 // @Security BearerAuth
 // @Produce json
 // @Param id path int true "Order ID"
-// @Success 200 {object} models.Response
+// @Success 200 {object} models.EnvelopeV2{data=models.OrderSummaryV2}
 // @Failure 404 {object} models.ErrorResponse
-// @Router /admin/orders/{id} [get]
+// @Router /v2/admin/orders/{id} [get]
+// @DeprecatedRouter /v1/admin/orders/{id} [get]
+// @DeprecatedRouter /admin/orders/{id} [get]
 func (ctrl *OrderController) GetOrderByID(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 
-	if id <= 0 {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "Invalid order ID",
-		})
+	o, err := ctrl.orders.Get(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err, "Order not found")
 		return
 	}
-
-	query := "SELECT id, user_id, subtotal, created_at FROM orders WHERE id = $1"
-
-	var orderID, userID, subtotal int
-	var createdAt time.Time
-
-	err := models.DB.QueryRow(context.Background(), query, id).Scan(
-		&orderID, &userID, &subtotal, &createdAt,
-	)
-
-	if err != nil {
-		c.JSON(404, gin.H{
-			"success": false,
-			"message": "Order not found",
-		})
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Order retrieved successfully"), o)
 		return
 	}
 
+	orderNumber := fmt.Sprintf("ORD-%d", o.ID)
 	c.JSON(200, gin.H{
 		"success": true,
-		"message": "Order retrieved successfully",
+		"message": msg(c, "Order retrieved successfully"),
 		"data": gin.H{
-			"id":           orderID,
-			"order_id":     orderID,
-			"orderId":      orderID,
-			"order_number": fmt.Sprintf("ORD-%d", orderID),
-			"orderNumber":  fmt.Sprintf("ORD-%d", orderID),
-			"user_id":      userID,
-			"userId":       userID,
-			"status":       "pending",
-			"subtotal":     subtotal,
-			"total":        subtotal,
-			"created_at":   createdAt,
-			"createdAt":    createdAt,
+			"id":           o.ID,
+			"order_id":     o.ID,
+			"orderId":      o.ID,
+			"order_number": orderNumber,
+			"orderNumber":  orderNumber,
+			"user_id":      o.UserID,
+			"userId":       o.UserID,
+			"status":       o.Status,
+			"subtotal":     o.Subtotal,
+			"total":        o.Total,
+			"created_at":   o.CreatedAt,
+			"createdAt":    o.CreatedAt,
 		},
 	})
 }
@@ -304,22 +168,32 @@ func (ctrl *OrderController) GetOrderByID(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param order body object true "Order data"
-// @Success 201 {object} models.Response
-// @Router /orders [post]
+// @Success 201 {object} models.EnvelopeV2{data=models.OrderRefV2}
+// @Router /v2/orders [post]
+// @DeprecatedRouter /v1/orders [post]
+// @DeprecatedRouter /orders [post]
 func (ctrl *OrderController) CreateOrder(c *gin.Context) {
 	userID := c.GetInt("user_id")
 
 	if userID == 0 {
-		c.JSON(401, gin.H{"success": false, "message": "Unauthorized"})
+		c.JSON(401, gin.H{"success": false, "message": msg(c, "Unauthorized")})
+		return
+	}
+
+	if isV2(c) {
+		respondV2(c, 201, msg(c, "Order created successfully"), models.OrderRefV2{
+			OrderID: 1,
+			Message: msg(c, "Please use /v2/transactions/checkout endpoint instead"),
+		})
 		return
 	}
 
 	c.JSON(201, gin.H{
 		"success": true,
-		"message": "Order created successfully",
+		"message": msg(c, "Order created successfully"),
 		"data": gin.H{
 			"order_id": 1,
-			"message":  "Please use /transactions/checkout endpoint instead",
+			"message":  msg(c, "Please use /transactions/checkout endpoint instead"),
 		},
 	})
 }
@@ -331,46 +205,24 @@ func (ctrl *OrderController) CreateOrder(c *gin.Context) {
 // @Accept multipart/form-data
 // @Produce json
 // @Param id path int true "Order ID"
-// @Param status formData string true "New status"
-// @Success 200 {object} models.Response
-// @Router /admin/orders/{id}/status [patch]
+// @Param status formData string true "New status, an order_status name such as on_progress or finish_order"
+// @Success 200 {object} models.EnvelopeV2{data=models.OrderStatusV2}
+// @Router /v2/admin/orders/{id}/status [patch]
+// @DeprecatedRouter /v1/admin/orders/{id}/status [patch]
+// @DeprecatedRouter /admin/orders/{id}/status [patch]
 func (ctrl *OrderController) UpdateOrderStatus(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	status := strings.TrimSpace(c.PostForm("status"))
-
-	if id <= 0 {
-		c.JSON(400, gin.H{"success": false, "message": "Invalid order ID"})
-		return
-	}
+	status := c.PostForm("status")
 
-	if status == "" {
-		c.JSON(400, gin.H{"success": false, "message": "Status is required"})
-		return
-	}
-
-	var exists int
-	err := models.DB.QueryRow(context.Background(), "SELECT COUNT(*) FROM orders WHERE id=$1", id).Scan(&exists)
-	if err != nil || exists == 0 {
-		c.JSON(404, gin.H{"success": false, "message": "Order not found"})
-		return
-	}
-
-	_, err = models.DB.Exec(context.Background(),
-		"UPDATE orders SET status=$1, updated_at=$2 WHERE id=$3",
-		status, time.Now(), id)
-
-	if err != nil {
-		c.JSON(500, gin.H{"success": false, "message": "Failed to update order status"})
+	if err := ctrl.orders.UpdateStatus(c.Request.Context(), actorFrom(c), id, status); err != nil {
+		respondServiceError(c, err, "Failed to update order status")
 		return
 	}
 
 	c.JSON(200, gin.H{
 		"success": true,
-		"message": "Order status updated successfully",
-		"data": gin.H{
-			"id":     id,
-			"status": status,
-		},
+		"message": msg(c, "Order status updated successfully"),
+		"data":    models.OrderStatusV2{ID: id, Status: strings.TrimSpace(status)},
 	})
 }
 
@@ -381,46 +233,19 @@ func (ctrl *OrderController) UpdateOrderStatus(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Order ID"
 // @Success 200 {object} models.Response
-// @Router /admin/orders/{id} [delete]
+//
+// DeleteOrder is not routed, so it is left out of the API docs.
 func (ctrl *OrderController) DeleteOrder(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 
-	if id <= 0 {
-		c.JSON(400, gin.H{"success": false, "message": "Invalid order ID"})
-		return
-	}
-
-	var exists int
-	err := models.DB.QueryRow(context.Background(), "SELECT COUNT(*) FROM orders WHERE id=$1", id).Scan(&exists)
-	if err != nil || exists == 0 {
-		c.JSON(404, gin.H{"success": false, "message": "Order not found"})
-		return
-	}
-
-	ctx := context.Background()
-	tx, err := models.DB.Begin(ctx)
-	if err != nil {
-		c.JSON(500, gin.H{"success": false, "message": "Failed to start transaction"})
-		return
-	}
-	defer tx.Rollback(ctx)
-
-	_, _ = tx.Exec(ctx, "DELETE FROM order_items WHERE order_id=$1", id)
-
-	_, err = tx.Exec(ctx, "DELETE FROM orders WHERE id=$1", id)
-	if err != nil {
-		c.JSON(500, gin.H{"success": false, "message": "Failed to delete order"})
-		return
-	}
-
-	if err = tx.Commit(ctx); err != nil {
-		c.JSON(500, gin.H{"success": false, "message": "Failed to commit transaction"})
+	if err := ctrl.orders.Delete(c.Request.Context(), id); err != nil {
+		respondServiceError(c, err, "Failed to delete order")
 		return
 	}
 
 	c.JSON(200, gin.H{
 		"success": true,
-		"message": "Order deleted successfully",
+		"message": msg(c, "Order deleted successfully"),
 		"data": gin.H{
 			"id": id,
 		},
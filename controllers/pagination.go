@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageParams reads the pagination query parameters. It answers 400 and
+// returns false when the cursor is malformed.
+func pageParams(c *gin.Context, defaultLimit int) (pagination.Params, bool) {
+	page, err := pagination.FromQuery(c, defaultLimit)
+	if err != nil {
+		c.JSON(400, models.ErrorResponse{
+			Success: false,
+			Message: msg(c, "Invalid cursor"),
+		})
+		return pagination.Params{}, false
+	}
+	return page, true
+}
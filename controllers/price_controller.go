@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PriceController shows price history and schedules price changes.
+type PriceController struct {
+	products *services.ProductService
+}
+
+func NewPriceController(products *services.ProductService) *PriceController {
+	return &PriceController{products: products}
+}
+
+// respondPrice answers with one price history entry or scheduled change.
+func respondPrice(c *gin.Context, status int, message string, p models.ProductPrice) {
+	if isV2(c) {
+		respondV2(c, status, msg(c, message), models.ProductPriceV2(p))
+		return
+	}
+	c.JSON(status, gin.H{
+		"success": true,
+		"message": msg(c, message),
+		"data":    p,
+	})
+}
+
+// respondPrices answers with a list of scheduled price changes.
+func respondPrices(c *gin.Context, message string, prices []models.ProductPrice) {
+	if isV2(c) {
+		respondV2(c, 200, msg(c, message), models.NewProductPriceListV2(prices))
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, message),
+		"data":    prices,
+	})
+}
+
+// @Summary Get price history
+// @Description List the prices a product has had, latest first, with the price each replaced and who set it (Admin)
+// @Tags Admin - Prices
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param cursor query string false "Continue after meta.next_cursor instead of using page"
+// @Success 200 {object} models.ListEnvelopeV2{data=[]models.ProductPriceV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/price-history [get]
+// @DeprecatedRouter /v1/admin/products/{id}/price-history [get]
+// @DeprecatedRouter /admin/products/{id}/price-history [get]
+func (ctrl *PriceController) GetPriceHistory(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	page, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+
+	prices, total, err := ctrl.products.PriceHistory(c.Request.Context(), id, page)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve price history")
+		return
+	}
+
+	var next *pagination.Cursor
+	if n := len(prices); n > 0 {
+		next = pagination.Next(page, n, pagination.Cursor{CreatedAt: *prices[n-1].AppliedAt, ID: prices[n-1].ID})
+	}
+
+	response := pagination.Response(c, msg(c, "Price history retrieved successfully"), prices, page, total, next)
+	if isV2(c) {
+		c.JSON(200, models.NewListEnvelopeV2(response, models.NewProductPriceListV2(prices)))
+		return
+	}
+	c.JSON(200, response)
+}
+
+// @Summary Get price at a time
+// @Description Return the price a product had at the given time (Admin)
+// @Tags Admin - Prices
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param at query string true "Time in RFC 3339, or a date (format: 2006-01-02) for the end of that day in UTC"
+// @Success 200 {object} models.EnvelopeV2{data=models.ProductPriceV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/price [get]
+// @DeprecatedRouter /v1/admin/products/{id}/price [get]
+// @DeprecatedRouter /admin/products/{id}/price [get]
+func (ctrl *PriceController) GetPriceAt(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	at, ok := parseAt(c.Query("at"))
+	if !ok {
+		c.JSON(400, models.ErrorResponse{Success: false, Message: msg(c, "Invalid at, expected RFC 3339 or format 2006-01-02")})
+		return
+	}
+
+	price, err := ctrl.products.PriceAt(c.Request.Context(), id, at)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve price history")
+		return
+	}
+	respondPrice(c, 200, "Price retrieved", price)
+}
+
+// @Summary Get scheduled prices
+// @Description List every pending price change, soonest first (Admin)
+// @Tags Admin - Prices
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.EnvelopeV2{data=[]models.ProductPriceV2}
+// @Router /v2/admin/scheduled-prices [get]
+// @DeprecatedRouter /v1/admin/scheduled-prices [get]
+// @DeprecatedRouter /admin/scheduled-prices [get]
+func (ctrl *PriceController) GetAllScheduledPrices(c *gin.Context) {
+	prices, err := ctrl.products.ScheduledPrices(c.Request.Context(), 0)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve scheduled prices")
+		return
+	}
+	respondPrices(c, "Scheduled prices retrieved", prices)
+}
+
+// @Summary Get a product's scheduled prices
+// @Description List a product's pending price changes, soonest first (Admin)
+// @Tags Admin - Prices
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} models.EnvelopeV2{data=[]models.ProductPriceV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/scheduled-prices [get]
+// @DeprecatedRouter /v1/admin/products/{id}/scheduled-prices [get]
+// @DeprecatedRouter /admin/products/{id}/scheduled-prices [get]
+func (ctrl *PriceController) GetScheduledPrices(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	prices, err := ctrl.products.ScheduledPrices(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve scheduled prices")
+		return
+	}
+	respondPrices(c, "Scheduled prices retrieved", prices)
+}
+
+// @Summary Schedule price change
+// @Description Plan a new price for a product from a future time; the scheduler applies it and clears the product caches (Admin)
+// @Tags Admin - Prices
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param request body models.PriceChangeRequest true "Price change"
+// @Success 201 {object} models.EnvelopeV2{data=models.ProductPriceV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/scheduled-prices [post]
+// @DeprecatedRouter /v1/admin/products/{id}/scheduled-prices [post]
+// @DeprecatedRouter /admin/products/{id}/scheduled-prices [post]
+func (ctrl *PriceController) SchedulePrice(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var req models.PriceChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid request data: ") + err.Error()})
+		return
+	}
+
+	change, err := ctrl.products.SchedulePrice(c.Request.Context(), actorFrom(c), id, req.Price, req.EffectiveAt.In(time.UTC), req.Reason)
+	if err != nil {
+		respondServiceError(c, err, "Failed to schedule price change")
+		return
+	}
+	respondPrice(c, 201, "Price change scheduled", change)
+}
+
+// @Summary Cancel scheduled price change
+// @Description Drop a price change that has not been applied yet (Admin)
+// @Tags Admin - Prices
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param changeId path int true "Scheduled price change ID"
+// @Success 200 {object} models.EnvelopeV2
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/scheduled-prices/{changeId} [delete]
+// @DeprecatedRouter /v1/admin/products/{id}/scheduled-prices/{changeId} [delete]
+// @DeprecatedRouter /admin/products/{id}/scheduled-prices/{changeId} [delete]
+func (ctrl *PriceController) CancelScheduledPrice(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	changeID, _ := strconv.Atoi(c.Param("changeId"))
+
+	if err := ctrl.products.CancelScheduledPrice(c.Request.Context(), actorFrom(c), id, changeID); err != nil {
+		respondServiceError(c, err, "Failed to cancel price change")
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Price change cancelled"),
+	})
+}
+
+// parseAt reads a time in RFC 3339, or a date standing for the end of that
+// day in UTC.
+func parseAt(value string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Nanosecond), true
+}
@@ -1,11 +1,16 @@
 package controllers
 
 import (
+	"bytes"
+	"coffee-shop/cache"
 	"coffee-shop/models"
-	"context"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"coffee-shop/search"
+	"coffee-shop/services"
+	"coffee-shop/spreadsheet"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/url"
 	"strconv"
 	"strings"
@@ -14,90 +19,25 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-type ProductController struct{}
-
-func (ctrl *ProductController) getPaginationParams(c *gin.Context, defaultLimit int) (page, limit, offset int) {
-	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ = strconv.Atoi(c.DefaultQuery("limit", fmt.Sprintf("%d", defaultLimit)))
-	if page <= 0 {
-		page = 1
-	}
-	if limit <= 0 {
-		limit = defaultLimit
-	}
-	if limit > 100 {
-		limit = 100
-	}
-	offset = (page - 1) * limit
-	return
+type ProductController struct {
+	products *services.ProductService
+	searches *services.SearchService
+	cache    *cache.Namespace
 }
 
-func (ctrl *ProductController) generateProductLinks(c *gin.Context, page, limit, total int) models.PaginationLinks {
-	scheme := "https"
-	if c.Request.TLS == nil {
-		scheme = "http"
-	}
-
-	host := c.Request.Host
-	path := c.Request.URL.Path
-	queryParams := c.Request.URL.Query()
-
-	makeURL := func(pageNum int) string {
-		newParams := url.Values{}
-		for key, values := range queryParams {
-			if key != "page" {
-				for _, value := range values {
-					newParams.Add(key, value)
-				}
-			}
-		}
-		newParams.Set("page", strconv.Itoa(pageNum))
-		newParams.Set("limit", strconv.Itoa(limit))
-		return fmt.Sprintf("%s://%s%s?%s", scheme, host, path, newParams.Encode())
-	}
-
-	totalPages := 0
-	if limit > 0 && total >= 0 {
-		totalPages = (total + limit - 1) / limit
-	}
-
-	links := models.PaginationLinks{
-		Self: makeURL(page),
-	}
-	if page > 1 {
-		links.Prev = makeURL(page - 1)
+func NewProductController(products *services.ProductService, searches *services.SearchService, responses cache.Store) *ProductController {
+	return &ProductController{
+		products: products,
+		searches: searches,
+		cache:    cache.NewNamespace(responses, services.ProductCacheNamespace),
 	}
-	if page < totalPages {
-		links.Next = makeURL(page + 1)
-	}
-	return links
 }
 
-func (ctrl *ProductController) buildProductResponse(c *gin.Context, message string, data interface{}, page, limit, total int) models.HATEOASResponse {
-	totalPages := 0
-	if limit > 0 && total >= 0 {
-		totalPages = (total + limit - 1) / limit
-	}
-
-	meta := models.PaginationMeta{
-		Page:       page,
-		Limit:      limit,
-		TotalItems: total,
-		TotalPages: totalPages,
-	}
-
-	links := ctrl.generateProductLinks(c, page, limit, total)
-
-	return models.HATEOASResponse{
-		Success: true,
-		Message: message,
-		Data:    data,
-		Meta:    meta,
-		Links:   links,
-	}
-}
+// suggestCacheTTL is short so new products and popular queries show up
+// quickly; the suggestions are also dropped with the product cache.
+const suggestCacheTTL = 30 * time.Second
 
-func getProductCacheKey(page, limit int, params url.Values) string {
+func getProductCacheKey(prefix string, page, limit int, params url.Values) string {
 	filtered := url.Values{}
 
 	for key, values := range params {
@@ -115,250 +55,212 @@ func getProductCacheKey(page, limit int, params url.Values) string {
 
 	encoded := filtered.Encode()
 	if encoded == "" {
-		return fmt.Sprintf("products_list_p%d_l%d", page, limit)
+		return fmt.Sprintf("%s_p%d_l%d", prefix, page, limit)
 	}
 
-	return fmt.Sprintf("products_list_p%d_l%d_%s", page, limit, encoded)
+	return fmt.Sprintf("%s_p%d_l%d_%s", prefix, page, limit, encoded)
 }
 
-func invalidateProductCache() {
-	if models.RedisClient == nil {
-		return
+// productResponse wraps a response about products for serveCached. It may be
+// cached for five minutes, or less when a flash sale starts or ends sooner.
+func (ctrl *ProductController) productResponse(c *gin.Context, body interface{}, products ...models.Product) cacheable {
+	return cacheable{
+		body:     body,
+		modified: productsModified(c.Request.Context(), ctrl.products, products),
+		ttl:      ctrl.products.CacheTTL(c.Request.Context(), 5*time.Minute),
 	}
+}
 
-	ctx := context.Background()
-	iter := models.RedisClient.Scan(ctx, 0, "products_*", 0).Iterator()
-	for iter.Next(ctx) {
-		if err := models.RedisClient.Del(ctx, iter.Val()).Err(); err != nil {
-			log.Printf("Failed to delete cache key %s: %v", iter.Val(), err)
-		}
-	}
-	if err := iter.Err(); err != nil {
-		log.Printf("Failed to scan cache keys: %v", err)
+// serveProductList lists the products matching filter, with their facets
+// when withFacets is set. Anonymous responses are cached; signed-in users
+// get their favourites marked.
+func (ctrl *ProductController) serveProductList(c *gin.Context, message string, filter repositories.ProductFilter, withFacets bool) {
+	page, ok := pageParams(c, 10)
+	if !ok {
+		return
 	}
-}
+	ctx := c.Request.Context()
+	query := filter.Search
 
-// @Summary Get all products
-// @Tags Products
-// @Produce json
-// @Param page query int false "Page number"
-// @Param limit query int false "Items per page"
-// @Success 200 {object} models.HATEOASResponse
-// @Router /products [get]
-func (ctrl *ProductController) GetAllProducts(c *gin.Context) {
-	page, limit, offset := ctrl.getPaginationParams(c, 10)
+	cacheKey := getProductCacheKey("list_"+responseVariant(c), page.Page, page.Limit, c.Request.URL.Query())
+	found, ok := serveCached(c, ctrl.cache, cacheKey, "Failed to retrieve products", func() (cacheable, error) {
+		filter.Locale = requestLocale(c)
+		filter.Params = page
+		products, total, err := ctrl.products.List(ctx, filter)
+		if err != nil {
+			return cacheable{}, err
+		}
+		if err := setFavorited(c, ctrl.products, products); err != nil {
+			return cacheable{}, err
+		}
 
-	cacheKey := getProductCacheKey(page, limit, c.Request.URL.Query())
-	ctx := context.Background()
+		// A (created_at, id) cursor can only continue the newest-first
+		// order; searches and other sorts paginate by page.
+		var next *pagination.Cursor
+		filter.Search = strings.TrimSpace(filter.Search)
+		if n := len(products); n > 0 && filter.NewestFirst() {
+			next = pagination.Next(page, n, pagination.Cursor{CreatedAt: products[n-1].CreatedAt, ID: products[n-1].ID})
+		}
 
-	if models.RedisClient != nil {
-		cached, err := models.RedisClient.Get(ctx, cacheKey).Result()
-		if err == nil && cached != "" {
-			log.Println("Serving products from cache")
-			c.Data(200, "application/json", []byte(cached))
-			return
+		list := pagination.Response(c, msg(c, message), products, page, total, next)
+		if withFacets {
+			facets, err := ctrl.products.Facets(ctx, filter)
+			if err != nil {
+				return cacheable{}, err
+			}
+			list.Facets = &facets
 		}
-	}
 
-	rows, err := models.DB.Query(ctx,
-		`SELECT id, name, description, category_id, price, stock, 
-		        COALESCE(image_url, ''), COALESCE(cloudinary_id, ''),
-		        COALESCE(is_flash_sale, false), COALESCE(is_favorite, false), 
-		        COALESCE(is_buy1get1, false), is_active, created_at, updated_at
-		 FROM products
-		 WHERE is_active = TRUE
-		 ORDER BY created_at DESC
-		 LIMIT $1 OFFSET $2`,
-		limit, offset,
-	)
-	if err != nil {
-		log.Printf("Error querying products: %v", err)
-		c.JSON(500, gin.H{"success": false, "message": "Failed to retrieve products"})
-		return
-	}
-	defer rows.Close()
-
-	products := []models.Product{}
-	for rows.Next() {
-		var p models.Product
-		if err := rows.Scan(
-			&p.ID, &p.Name, &p.Description, &p.CategoryID,
-			&p.Price, &p.Stock, &p.ImageURL, &p.CloudinaryID,
-			&p.IsFlashSale, &p.IsFavorite, &p.IsBuy1Get1,
-			&p.IsActive, &p.CreatedAt, &p.UpdatedAt,
-		); err != nil {
-			log.Printf("Error scanning product: %v", err)
-			continue
+		var body interface{} = list
+		if isV2(c) {
+			body = models.NewListEnvelopeV2(list, models.NewProductListV2(products))
 		}
-		products = append(products, p)
-	}
+		response := ctrl.productResponse(c, body, products...)
+		response.items = total
+		return response, nil
+	})
 
-	var total int
-	if err := models.DB.QueryRow(ctx,
-		`SELECT COUNT(*) FROM products WHERE is_active = TRUE`,
-	).Scan(&total); err != nil {
-		log.Printf("Error counting products: %v", err)
-		total = len(products)
+	// Count each search that found products once, on its first page, whether
+	// or not it was cached.
+	if ok && query != "" && page.Page == 1 {
+		ctrl.searches.RecordQuery(ctx, query, searcher(c), found)
 	}
+}
 
-	response := ctrl.buildProductResponse(c, "Products retrieved successfully", products, page, limit, total)
-
-	if models.RedisClient != nil {
-		jsonData, _ := json.Marshal(response)
-		models.RedisClient.Set(ctx, cacheKey, jsonData, 5*time.Minute)
+// searcher identifies who ran a search: the signed-in user, or else the
+// client's IP address.
+func searcher(c *gin.Context) string {
+	if userID := c.GetInt("user_id"); userID != 0 {
+		return fmt.Sprintf("user:%d", userID)
 	}
+	return "ip:" + c.ClientIP()
+}
 
-	c.JSON(200, response)
+// @Summary Get all products
+// @Tags Products
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param cursor query string false "Continue after meta.next_cursor instead of using page"
+// @Param If-None-Match header string false "ETag of the copy the client has"
+// @Param If-Modified-Since header string false "Last-Modified of the copy the client has"
+// @Success 200 {object} models.ListEnvelopeV2{data=[]models.ProductV2}
+// @Success 304 "Not modified"
+// @Router /v2/products [get]
+// @DeprecatedRouter /v1/products [get]
+// @DeprecatedRouter /products [get]
+func (ctrl *ProductController) GetAllProducts(c *gin.Context) {
+	ctrl.serveProductList(c, "Products retrieved successfully", repositories.ProductFilter{}, false)
 }
 
 // @Summary Filter products
 // @Tags Products
 // @Produce json
-// @Param search query string false "Search by name"
+// @Param search query string false "Full-text search over name, description and category; tolerates typos and orders by relevance"
 // @Param category_id query int false "Category ID"
 // @Param min_price query int false "Minimum price"
 // @Param max_price query int false "Maximum price"
 // @Param is_flash_sale query bool false "Flash sale only"
-// @Param is_favorite query bool false "Favorites only"
+// @Param is_featured query bool false "Featured only (is_favorite is still accepted)"
+// @Param is_buy1get1 query bool false "Buy 1 get 1 only"
+// @Param in_stock query bool false "In-stock products only"
+// @Param min_rating query number false "Minimum average review rating (0-5)"
+// @Param sort query string false "newest (default), price_asc, price_desc, best_selling, top_rated or popular; searches default to relevance"
 // @Param page query int false "Page"
 // @Param limit query int false "Limit"
-// @Success 200 {object} models.HATEOASResponse
-// @Router /products/filter [get]
+// @Param cursor query string false "Continue after meta.next_cursor instead of using page"
+// @Param If-None-Match header string false "ETag of the copy the client has"
+// @Param If-Modified-Since header string false "Last-Modified of the copy the client has"
+// @Success 200 {object} models.ListEnvelopeV2{data=[]models.ProductV2}
+// @Success 304 "Not modified"
+// @Router /v2/products/filter [get]
+// @DeprecatedRouter /v1/products/filter [get]
+// @DeprecatedRouter /products/filter [get]
 func (ctrl *ProductController) FilterProducts(c *gin.Context) {
-	page, limit, offset := ctrl.getPaginationParams(c, 10)
-	ctx := context.Background()
-
-	cacheKey := getProductCacheKey(page, limit, c.Request.URL.Query())
-
-	if models.RedisClient != nil {
-		cached, _ := models.RedisClient.Get(ctx, cacheKey).Result()
-		if cached != "" {
-			c.Data(200, "application/json", []byte(cached))
-			return
-		}
-	}
-
-	query := `SELECT id, name, description, category_id, price, stock, 
-	                 COALESCE(image_url, ''), COALESCE(cloudinary_id, ''),
-	                 COALESCE(is_flash_sale, false), COALESCE(is_favorite, false), 
-	                 COALESCE(is_buy1get1, false), is_active, created_at, updated_at
-	          FROM products WHERE is_active = TRUE`
-
-	args := []interface{}{}
-	argIdx := 1
-
-	if search := strings.TrimSpace(c.Query("search")); search != "" {
-		query += fmt.Sprintf(" AND LOWER(name) LIKE LOWER($%d)", argIdx)
-		args = append(args, "%"+search+"%")
-		argIdx++
-	}
-
-	if categoryID, err := strconv.Atoi(c.Query("category_id")); err == nil && categoryID > 0 {
-		query += fmt.Sprintf(" AND category_id = $%d", argIdx)
-		args = append(args, categoryID)
-		argIdx++
-	}
-
-	if minPrice, err := strconv.Atoi(c.Query("min_price")); err == nil && minPrice > 0 {
-		query += fmt.Sprintf(" AND price >= $%d", argIdx)
-		args = append(args, minPrice)
-		argIdx++
-	}
-	if maxPrice, err := strconv.Atoi(c.Query("max_price")); err == nil && maxPrice > 0 {
-		query += fmt.Sprintf(" AND price <= $%d", argIdx)
-		args = append(args, maxPrice)
-		argIdx++
-	}
-
-	if isFlashSale, err := strconv.ParseBool(c.Query("is_flash_sale")); err == nil && isFlashSale {
-		query += fmt.Sprintf(" AND is_flash_sale = $%d", argIdx)
-		args = append(args, true)
-		argIdx++
-	}
+	filter := repositories.ProductFilter{Search: c.Query("search")}
+	filter.CategoryID, _ = strconv.Atoi(c.Query("category_id"))
+	filter.MinPrice, _ = strconv.Atoi(c.Query("min_price"))
+	filter.MaxPrice, _ = strconv.Atoi(c.Query("max_price"))
+	filter.FlashSale, _ = strconv.ParseBool(c.Query("is_flash_sale"))
+	filter.Featured, _ = strconv.ParseBool(c.DefaultQuery("is_featured", c.Query("is_favorite")))
+	filter.Buy1Get1, _ = strconv.ParseBool(c.Query("is_buy1get1"))
+	filter.InStock, _ = strconv.ParseBool(c.Query("in_stock"))
+	filter.MinRating, _ = strconv.ParseFloat(c.Query("min_rating"), 64)
+	filter.Sort = c.Query("sort")
+
+	ctrl.serveProductList(c, "Products filtered successfully", filter, true)
+}
 
-	if isFavorite, err := strconv.ParseBool(c.Query("is_favorite")); err == nil && isFavorite {
-		query += fmt.Sprintf(" AND is_favorite = $%d", argIdx)
-		args = append(args, true)
-		argIdx++
+// @Summary Search suggestions
+// @Description Product names, categories and popular searches for the search box, by prefix or close typo
+// @Tags Products
+// @Produce json
+// @Param q query string true "What the user has typed so far"
+// @Param limit query int false "Suggestions per group (default 5, max 10)"
+// @Success 200 {object} models.EnvelopeV2{data=models.Suggestions}
+// @Router /v2/products/suggest [get]
+// @DeprecatedRouter /v1/products/suggest [get]
+// @DeprecatedRouter /products/suggest [get]
+func (ctrl *ProductController) SuggestProducts(c *gin.Context) {
+	ctx := c.Request.Context()
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	cacheKey := fmt.Sprintf("suggest_%s_l%d_%s", requestLocale(c), limit, search.Normalize(c.Query("q")))
+	if cached, ok := ctrl.cache.Get(ctx, cacheKey); ok {
+		c.Data(200, "application/json", []byte(cached))
+		return
 	}
 
-	query += " ORDER BY created_at DESC"
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
-	args = append(args, limit, offset)
-
-	rows, err := models.DB.Query(ctx, query, args...)
+	suggestions, err := ctrl.searches.Suggest(ctx, c.Query("q"), requestLocale(c), limit)
 	if err != nil {
-		log.Printf("Error filtering products: %v", err)
-		c.JSON(500, gin.H{"success": false, "message": "Failed to filter products"})
+		respondServiceError(c, err, "Failed to retrieve suggestions")
 		return
 	}
-	defer rows.Close()
-
-	products := []models.Product{}
-	for rows.Next() {
-		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.CategoryID,
-			&p.Price, &p.Stock, &p.ImageURL, &p.CloudinaryID,
-			&p.IsFlashSale, &p.IsFavorite, &p.IsBuy1Get1,
-			&p.IsActive, &p.CreatedAt, &p.UpdatedAt); err != nil {
-			continue
-		}
-		products = append(products, p)
-	}
-
-	countQuery := strings.Replace(query, "SELECT id, name, description, category_id, price, stock, COALESCE(image_url, ''), COALESCE(cloudinary_id, ''), COALESCE(is_flash_sale, false), COALESCE(is_favorite, false), COALESCE(is_buy1get1, false), is_active, created_at, updated_at", "SELECT COUNT(*)", 1)
-	countQuery = countQuery[:strings.Index(countQuery, "LIMIT")]
-
-	var total int
-	models.DB.QueryRow(ctx, countQuery, args[:len(args)-2]...).Scan(&total)
-
-	response := ctrl.buildProductResponse(c, "Products filtered successfully", products, page, limit, total)
 
-	if models.RedisClient != nil {
-		jsonData, _ := json.Marshal(response)
-		models.RedisClient.Set(ctx, cacheKey, jsonData, 5*time.Minute)
+	// The envelope is the same in v1 and v2, so both share the cache entry.
+	response := models.EnvelopeV2{Success: true, Message: msg(c, "Suggestions retrieved"), Data: suggestions}
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		c.JSON(200, response)
+		return
 	}
-
-	c.JSON(200, response)
+	ctrl.cache.Set(ctx, cacheKey, string(jsonData), suggestCacheTTL)
+	c.Data(200, "application/json", jsonData)
 }
 
-// @Summary Get favorite products
+// @Summary Get featured products
+// @Description The products the shop features; also served at /products/favorite
 // @Tags Products
 // @Produce json
-// @Success 200 {object} models.Response
-// @Router /products/favorite [get]
-func (ctrl *ProductController) GetFavoriteProducts(c *gin.Context) {
-	ctx := context.Background()
-
-	rows, err := models.DB.Query(ctx,
-		`SELECT id, name, description, category_id, price, stock, 
-		        COALESCE(image_url, ''), COALESCE(cloudinary_id, ''),
-		        COALESCE(is_flash_sale, false), COALESCE(is_favorite, false), 
-		        COALESCE(is_buy1get1, false), is_active, created_at, updated_at
-		 FROM products
-		 WHERE is_active = TRUE AND is_favorite = TRUE
-		 ORDER BY created_at DESC`,
-	)
-	if err != nil {
-		c.JSON(500, gin.H{"success": false, "message": "Failed to retrieve favorites"})
-		return
-	}
-	defer rows.Close()
-
-	products := []models.Product{}
-	for rows.Next() {
-		var p models.Product
-		rows.Scan(&p.ID, &p.Name, &p.Description, &p.CategoryID,
-			&p.Price, &p.Stock, &p.ImageURL, &p.CloudinaryID,
-			&p.IsFlashSale, &p.IsFavorite, &p.IsBuy1Get1,
-			&p.IsActive, &p.CreatedAt, &p.UpdatedAt)
-		products = append(products, p)
-	}
+// @Param If-None-Match header string false "ETag of the copy the client has"
+// @Param If-Modified-Since header string false "Last-Modified of the copy the client has"
+// @Success 200 {object} models.EnvelopeV2{data=[]models.ProductV2}
+// @Success 304 "Not modified"
+// @Router /v2/products/featured [get]
+// @DeprecatedRouter /v1/products/featured [get]
+// @DeprecatedRouter /products/featured [get]
+// @DeprecatedRouter /v2/products/favorite [get]
+// @DeprecatedRouter /v1/products/favorite [get]
+// @DeprecatedRouter /products/favorite [get]
+func (ctrl *ProductController) GetFeaturedProducts(c *gin.Context) {
+	serveCached(c, ctrl.cache, "featured_"+responseVariant(c), "Failed to retrieve featured products", func() (cacheable, error) {
+		products, err := ctrl.products.Featured(c.Request.Context(), requestLocale(c))
+		if err != nil {
+			return cacheable{}, err
+		}
+		if err := setFavorited(c, ctrl.products, products); err != nil {
+			return cacheable{}, err
+		}
 
-	c.JSON(200, gin.H{
-		"success": true,
-		"message": "Favorite products retrieved",
-		"data":    products,
+		if isV2(c) {
+			return ctrl.productResponse(c, models.EnvelopeV2{Success: true, Message: msg(c, "Featured products retrieved"), Data: models.NewProductListV2(products)},
+				products...), nil
+		}
+		return ctrl.productResponse(c, gin.H{
+			"success": true,
+			"message": msg(c, "Featured products retrieved"),
+			"data":    products,
+		}, products...), nil
 	})
 }
 
@@ -366,41 +268,76 @@ func (ctrl *ProductController) GetFavoriteProducts(c *gin.Context) {
 // @Tags Products
 // @Produce json
 // @Param id path int true "Product ID"
-// @Success 200 {object} models.Response
+// @Param If-None-Match header string false "ETag of the copy the client has"
+// @Param If-Modified-Since header string false "Last-Modified of the copy the client has"
+// @Success 200 {object} models.EnvelopeV2{data=models.ProductV2}
+// @Success 304 "Not modified"
 // @Failure 404 {object} models.ErrorResponse
-// @Router /products/{id} [get]
+// @Router /v2/products/{id} [get]
+// @DeprecatedRouter /v1/products/{id} [get]
+// @DeprecatedRouter /products/{id} [get]
 func (ctrl *ProductController) GetProductByID(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	ctx := context.Background()
 
-	if id <= 0 {
-		c.JSON(400, gin.H{"success": false, "message": "Invalid product ID"})
-		return
+	key := fmt.Sprintf("product_%d_%s", id, responseVariant(c))
+	serveCached(c, ctrl.cache, key, "Failed to retrieve products", func() (cacheable, error) {
+		p, err := ctrl.products.Get(c.Request.Context(), id, requestLocale(c))
+		if err != nil {
+			return cacheable{}, err
+		}
+		marked := []models.Product{p}
+		if err := setFavorited(c, ctrl.products, marked); err != nil {
+			return cacheable{}, err
+		}
+		p = marked[0]
+		recordView(c, ctrl.products, p.ID)
+
+		if isV2(c) {
+			return ctrl.productResponse(c, models.EnvelopeV2{Success: true, Message: msg(c, "Product retrieved"), Data: models.NewProductV2(p)}, p), nil
+		}
+		return ctrl.productResponse(c, gin.H{
+			"success": true,
+			"message": msg(c, "Product retrieved"),
+			"data":    p,
+		}, p), nil
+	})
+}
+
+// setFavorited sets IsFavorited on the products for a signed-in user.
+func setFavorited(c *gin.Context, service *services.ProductService, products []models.Product) error {
+	userID := c.GetInt("user_id")
+	if userID == 0 {
+		return nil
 	}
+	return service.MarkFavorited(c.Request.Context(), userID, products)
+}
 
-	var p models.Product
-	err := models.DB.QueryRow(ctx,
-		`SELECT id, name, description, category_id, price, stock, 
-		        COALESCE(image_url, ''), COALESCE(cloudinary_id, ''),
-		        COALESCE(is_flash_sale, false), COALESCE(is_favorite, false), 
-		        COALESCE(is_buy1get1, false), is_active, created_at, updated_at
-		 FROM products WHERE id = $1 AND is_active = TRUE`,
-		id,
-	).Scan(&p.ID, &p.Name, &p.Description, &p.CategoryID,
-		&p.Price, &p.Stock, &p.ImageURL, &p.CloudinaryID,
-		&p.IsFlashSale, &p.IsFavorite, &p.IsBuy1Get1,
-		&p.IsActive, &p.CreatedAt, &p.UpdatedAt)
+// markFavorited is setFavorited for handlers that answer directly: it
+// answers with an error and returns false when that fails.
+func markFavorited(c *gin.Context, service *services.ProductService, products []models.Product, message string) bool {
+	if err := setFavorited(c, service, products); err != nil {
+		respondServiceError(c, err, message)
+		return false
+	}
+	return true
+}
 
-	if err != nil {
-		c.JSON(404, gin.H{"success": false, "message": "Product not found"})
-		return
+// recordView stores a signed-in user's view of the product, for their
+// recently viewed list and the product's popularity.
+func recordView(c *gin.Context, service *services.ProductService, productID int) {
+	if userID := c.GetInt("user_id"); userID != 0 {
+		service.RecordView(c.Request.Context(), userID, productID)
 	}
+}
 
-	c.JSON(200, gin.H{
-		"success": true,
-		"message": "Product retrieved",
-		"data":    p,
-	})
+// productImageUpload returns the "image" form file, or nil when none was sent.
+// The caller must close the file.
+func productImageUpload(c *gin.Context) *services.Upload {
+	file, header, err := c.Request.FormFile("image")
+	if err != nil {
+		return nil
+	}
+	return &services.Upload{File: file, Header: header}
 }
 
 // @Summary Create product
@@ -409,109 +346,64 @@ func (ctrl *ProductController) GetProductByID(c *gin.Context) {
 // @Security BearerAuth
 // @Accept multipart/form-data
 // @Produce json
+// @Param sku formData string false "Stock keeping unit, unique ignoring case; the import matches products on it"
 // @Param name formData string true "Product name"
 // @Param description formData string false "Description"
 // @Param category_id formData int true "Category ID"
 // @Param price formData int true "Price"
 // @Param stock formData int true "Stock"
+// @Param low_stock_threshold formData int false "Report the product as low on stock below this level, 0 for never (default 10)"
 // @Param is_flash_sale formData bool false "Flash sale"
-// @Param is_favorite formData bool false "Favorite"
+// @Param is_featured formData bool false "Featured by the shop (is_favorite is still accepted)"
 // @Param is_buy1get1 formData bool false "Buy 1 Get 1"
 // @Param image formData file false "Product image"
-// @Success 201 {object} models.Response
-// @Router /admin/products [post]
+// @Success 201 {object} models.EnvelopeV2{data=models.ProductV2}
+// @Router /v2/admin/products [post]
+// @DeprecatedRouter /v1/admin/products [post]
+// @DeprecatedRouter /admin/products [post]
 func (ctrl *ProductController) CreateProduct(c *gin.Context) {
-	ctx := context.Background()
-
-	name := strings.TrimSpace(c.PostForm("name"))
-	description := strings.TrimSpace(c.PostForm("description"))
-	categoryID, _ := strconv.Atoi(c.PostForm("category_id"))
-	price, _ := strconv.Atoi(c.PostForm("price"))
-	stock, _ := strconv.Atoi(c.PostForm("stock"))
-	isFlashSale, _ := strconv.ParseBool(c.DefaultPostForm("is_flash_sale", "false"))
-	isFavorite, _ := strconv.ParseBool(c.DefaultPostForm("is_favorite", "false"))
-	isBuy1Get1, _ := strconv.ParseBool(c.DefaultPostForm("is_buy1get1", "false"))
-
-	if len(name) < 3 {
-		c.JSON(400, gin.H{"success": false, "message": "Product name must be at least 3 characters"})
-		return
-	}
-	if categoryID <= 0 {
-		c.JSON(400, gin.H{"success": false, "message": "Invalid category_id"})
-		return
+	in := services.ProductInput{
+		SKU:         c.PostForm("sku"),
+		Name:        c.PostForm("name"),
+		Description: c.PostForm("description"),
 	}
-	if price < 1000 {
-		c.JSON(400, gin.H{"success": false, "message": "Price must be at least 1000"})
-		return
+	in.CategoryID, _ = strconv.Atoi(c.PostForm("category_id"))
+	in.Price, _ = strconv.Atoi(c.PostForm("price"))
+	in.Stock, _ = strconv.Atoi(c.PostForm("stock"))
+	in.LowStockThreshold = services.DefaultLowStockThreshold
+	if threshold := postFormInt(c, "low_stock_threshold"); threshold != nil {
+		in.LowStockThreshold = *threshold
 	}
-	if stock < 0 {
-		c.JSON(400, gin.H{"success": false, "message": "Invalid stock"})
-		return
-	}
-
-	var imageURL, cloudinaryID string
-	uploadedFile, fileHeader, fileErr := c.Request.FormFile("image")
+	in.IsFlashSale, _ = strconv.ParseBool(c.DefaultPostForm("is_flash_sale", "false"))
+	in.IsFeatured, _ = strconv.ParseBool(c.DefaultPostForm("is_featured", c.DefaultPostForm("is_favorite", "false")))
+	in.IsBuy1Get1, _ = strconv.ParseBool(c.DefaultPostForm("is_buy1get1", "false"))
 
-	if fileErr == nil {
-		defer uploadedFile.Close()
-
-		cloudinaryService, err := models.NewCloudinaryService()
-		if err != nil {
-			log.Printf("Cloudinary not configured: %v", err)
-			c.JSON(500, gin.H{"success": false, "message": "Image upload service not available"})
-			return
-		}
-
-		if err := cloudinaryService.ValidateImageFile(fileHeader); err != nil {
-			c.JSON(400, gin.H{"success": false, "message": err.Error()})
-			return
-		}
-
-		imageURL, cloudinaryID, err = cloudinaryService.UploadImage(ctx, uploadedFile, fileHeader.Filename, "products")
-		if err != nil {
-			log.Printf("Upload failed: %v", err)
-			c.JSON(500, gin.H{"success": false, "message": "Failed to upload image"})
-			return
-		}
+	image := productImageUpload(c)
+	if image != nil {
+		defer image.File.Close()
 	}
 
-	now := time.Now()
-	var productID int
-
-	err := models.DB.QueryRow(ctx,
-		`INSERT INTO products 
-		 (name, description, category_id, price, stock, image_url, cloudinary_id, 
-		  is_flash_sale, is_favorite, is_buy1get1, is_active, created_at, updated_at) 
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, TRUE, $11, $12) 
-		 RETURNING id`,
-		name, description, categoryID, price, stock, imageURL, cloudinaryID,
-		isFlashSale, isFavorite, isBuy1Get1, now, now,
-	).Scan(&productID)
-
+	p, err := ctrl.products.Create(c.Request.Context(), actorFrom(c), in, image)
 	if err != nil {
-		if cloudinaryID != "" {
-			cloudinaryService, _ := models.NewCloudinaryService()
-			if cloudinaryService != nil {
-				cloudinaryService.DeleteImage(ctx, cloudinaryID)
-			}
-		}
-		log.Printf("Insert failed: %v", err)
-		c.JSON(500, gin.H{"success": false, "message": "Failed to create product"})
+		respondServiceError(c, err, "Failed to create product")
 		return
 	}
 
-	invalidateProductCache()
+	if isV2(c) {
+		respondV2(c, 201, msg(c, "Product created successfully"), models.NewProductV2(p))
+		return
+	}
 
 	c.JSON(201, gin.H{
 		"success": true,
-		"message": "Product created successfully",
+		"message": msg(c, "Product created successfully"),
 		"data": gin.H{
-			"id":            productID,
-			"name":          name,
-			"price":         price,
-			"stock":         stock,
-			"image_url":     imageURL,
-			"cloudinary_id": cloudinaryID,
+			"id":            p.ID,
+			"name":          p.Name,
+			"price":         p.Price,
+			"stock":         p.Stock,
+			"image_url":     p.ImageURL,
+			"cloudinary_id": p.CloudinaryID,
 		},
 	})
 }
@@ -523,180 +415,354 @@ func (ctrl *ProductController) CreateProduct(c *gin.Context) {
 // @Accept multipart/form-data
 // @Produce json
 // @Param id path int true "Product ID"
+// @Param sku formData string false "Stock keeping unit, empty to clear"
 // @Param name formData string false "Product name"
 // @Param description formData string false "Description"
 // @Param category_id formData int false "Category ID"
 // @Param price formData int false "Price"
-// @Param stock formData int false "Stock"
+// @Param stock formData int false "Stock; a change is recorded in the stock ledger as an adjustment"
+// @Param low_stock_threshold formData int false "Report the product as low on stock below this level, 0 for never"
 // @Param is_flash_sale formData bool false "Flash sale"
-// @Param is_favorite formData bool false "Favorite"
+// @Param is_featured formData bool false "Featured by the shop (is_favorite is still accepted)"
 // @Param is_buy1get1 formData bool false "Buy 1 Get 1"
 // @Param is_active formData bool false "Active status"
 // @Param image formData file false "Product image"
-// @Success 200 {object} models.Response
-// @Router /admin/products/{id} [patch]
+// @Success 200 {object} models.EnvelopeV2{data=models.ProductV2}
+// @Router /v2/admin/products/{id} [patch]
+// @DeprecatedRouter /v1/admin/products/{id} [patch]
+// @DeprecatedRouter /admin/products/{id} [patch]
 func (ctrl *ProductController) UpdateProduct(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	ctx := context.Background()
+	featured := postFormBool(c, "is_featured")
+	if featured == nil {
+		featured = postFormBool(c, "is_favorite")
+	}
 
-	if id <= 0 {
-		c.JSON(400, gin.H{"success": false, "message": "Invalid product ID"})
-		return
+	patch := services.ProductPatch{
+		SKU:               postFormString(c, "sku"),
+		Name:              postFormString(c, "name"),
+		Description:       postFormString(c, "description"),
+		CategoryID:        postFormInt(c, "category_id"),
+		Price:             postFormInt(c, "price"),
+		Stock:             postFormInt(c, "stock"),
+		LowStockThreshold: postFormInt(c, "low_stock_threshold"),
+		IsFlashSale:       postFormBool(c, "is_flash_sale"),
+		IsFeatured:        featured,
+		IsBuy1Get1:        postFormBool(c, "is_buy1get1"),
+		IsActive:          postFormBool(c, "is_active"),
 	}
 
-	var existing models.Product
-	err := models.DB.QueryRow(ctx,
-		`SELECT name, description, category_id, price, stock, 
-		        COALESCE(image_url, ''), COALESCE(cloudinary_id, ''),
-		        COALESCE(is_flash_sale, false), COALESCE(is_favorite, false), 
-		        COALESCE(is_buy1get1, false), is_active
-		 FROM products WHERE id = $1`,
-		id,
-	).Scan(&existing.Name, &existing.Description, &existing.CategoryID,
-		&existing.Price, &existing.Stock, &existing.ImageURL, &existing.CloudinaryID,
-		&existing.IsFlashSale, &existing.IsFavorite, &existing.IsBuy1Get1, &existing.IsActive)
+	image := productImageUpload(c)
+	if image != nil {
+		defer image.File.Close()
+	}
 
+	p, err := ctrl.products.Update(c.Request.Context(), actorFrom(c), id, patch, image)
 	if err != nil {
-		c.JSON(404, gin.H{"success": false, "message": "Product not found"})
+		respondServiceError(c, err, "Failed to update product")
 		return
 	}
 
-	name := strings.TrimSpace(c.DefaultPostForm("name", existing.Name))
-	description := strings.TrimSpace(c.DefaultPostForm("description", existing.Description))
-	categoryID, _ := strconv.Atoi(c.DefaultPostForm("category_id", strconv.Itoa(existing.CategoryID)))
-	price, _ := strconv.Atoi(c.DefaultPostForm("price", strconv.Itoa(existing.Price)))
-	stock, _ := strconv.Atoi(c.DefaultPostForm("stock", strconv.Itoa(existing.Stock)))
-	isFlashSale, _ := strconv.ParseBool(c.DefaultPostForm("is_flash_sale", strconv.FormatBool(existing.IsFlashSale)))
-	isFavorite, _ := strconv.ParseBool(c.DefaultPostForm("is_favorite", strconv.FormatBool(existing.IsFavorite)))
-	isBuy1Get1, _ := strconv.ParseBool(c.DefaultPostForm("is_buy1get1", strconv.FormatBool(existing.IsBuy1Get1)))
-	isActive, _ := strconv.ParseBool(c.DefaultPostForm("is_active", strconv.FormatBool(existing.IsActive)))
-
-	if len(name) < 3 {
-		c.JSON(400, gin.H{"success": false, "message": "Product name must be at least 3 characters"})
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Product updated successfully"), models.NewProductV2(p))
 		return
 	}
-	if categoryID <= 0 {
-		c.JSON(400, gin.H{"success": false, "message": "Invalid category_id"})
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Product updated successfully"),
+		"data": gin.H{
+			"id":            p.ID,
+			"name":          p.Name,
+			"price":         p.Price,
+			"stock":         p.Stock,
+			"image_url":     p.ImageURL,
+			"cloudinary_id": p.CloudinaryID,
+		},
+	})
+}
+
+// @Summary Archive product
+// @Description Archive a product: it is hidden from the storefront and carts but kept in order history and can be restored (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} models.EnvelopeV2
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id} [delete]
+// @DeprecatedRouter /v1/admin/products/{id} [delete]
+// @DeprecatedRouter /admin/products/{id} [delete]
+func (ctrl *ProductController) DeleteProduct(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	if err := ctrl.products.Delete(c.Request.Context(), actorFrom(c), id); err != nil {
+		respondServiceError(c, err, "Failed to archive product")
 		return
 	}
-	if price < 1000 {
-		c.JSON(400, gin.H{"success": false, "message": "Price must be at least 1000"})
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Product archived"),
+	})
+}
+
+// @Summary Get archived products
+// @Description List archived products, most recently archived first (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param cursor query string false "Continue after meta.next_cursor instead of using page"
+// @Success 200 {object} models.ListEnvelopeV2{data=[]models.ProductV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /v2/admin/products/archived [get]
+// @DeprecatedRouter /v1/admin/products/archived [get]
+// @DeprecatedRouter /admin/products/archived [get]
+func (ctrl *ProductController) GetArchivedProducts(c *gin.Context) {
+	page, ok := pageParams(c, 10)
+	if !ok {
 		return
 	}
-	if stock < 0 {
-		c.JSON(400, gin.H{"success": false, "message": "Invalid stock"})
+
+	products, total, err := ctrl.products.Archived(c.Request.Context(), page)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve archived products")
 		return
 	}
 
-	imageURL := existing.ImageURL
-	cloudinaryID := existing.CloudinaryID
-
-	uploadedFile, fileHeader, fileErr := c.Request.FormFile("image")
-	if fileErr == nil {
-		defer uploadedFile.Close()
-
-		cloudinaryService, err := models.NewCloudinaryService()
-		if err != nil {
-			c.JSON(500, gin.H{"success": false, "message": "Image upload service not available"})
-			return
-		}
-
-		if err := cloudinaryService.ValidateImageFile(fileHeader); err != nil {
-			c.JSON(400, gin.H{"success": false, "message": err.Error()})
-			return
-		}
-
-		newImageURL, newCloudinaryID, err := cloudinaryService.UploadImage(ctx, uploadedFile, fileHeader.Filename, "products")
-		if err != nil {
-			c.JSON(500, gin.H{"success": false, "message": "Failed to upload image"})
-			return
-		}
-
-		if existing.CloudinaryID != "" {
-			cloudinaryService.DeleteImage(ctx, existing.CloudinaryID)
-		}
+	var next *pagination.Cursor
+	if n := len(products); n > 0 {
+		next = pagination.Next(page, n, pagination.Cursor{CreatedAt: *products[n-1].DeletedAt, ID: products[n-1].ID})
+	}
 
-		imageURL = newImageURL
-		cloudinaryID = newCloudinaryID
+	response := pagination.Response(c, msg(c, "Archived products retrieved successfully"), products, page, total, next)
+	if isV2(c) {
+		c.JSON(200, models.NewListEnvelopeV2(response, models.NewProductListV2(products)))
+		return
 	}
+	c.JSON(200, response)
+}
 
-	now := time.Now()
-	_, err = models.DB.Exec(ctx,
-		`UPDATE products 
-		 SET name=$1, description=$2, category_id=$3, price=$4, stock=$5, 
-		     image_url=$6, cloudinary_id=$7, is_flash_sale=$8, is_favorite=$9, 
-		     is_buy1get1=$10, is_active=$11, updated_at=$12 
-		 WHERE id=$13`,
-		name, description, categoryID, price, stock, imageURL, cloudinaryID,
-		isFlashSale, isFavorite, isBuy1Get1, isActive, now, id,
-	)
+// @Summary Restore product
+// @Description Put an archived product back on the storefront (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} models.EnvelopeV2{data=models.ProductV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/restore [post]
+// @DeprecatedRouter /v1/admin/products/{id}/restore [post]
+// @DeprecatedRouter /admin/products/{id}/restore [post]
+func (ctrl *ProductController) RestoreProduct(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
 
+	p, err := ctrl.products.Restore(c.Request.Context(), actorFrom(c), id)
 	if err != nil {
-		log.Printf("Update failed: %v", err)
-		c.JSON(500, gin.H{"success": false, "message": "Failed to update product"})
+		respondServiceError(c, err, "Failed to restore product")
 		return
 	}
 
-	invalidateProductCache()
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Product restored"), models.NewProductV2(p))
+		return
+	}
 
 	c.JSON(200, gin.H{
 		"success": true,
-		"message": "Product updated successfully",
-		"data": gin.H{
-			"id":            id,
-			"name":          name,
-			"price":         price,
-			"stock":         stock,
-			"image_url":     imageURL,
-			"cloudinary_id": cloudinaryID,
-		},
+		"message": msg(c, "Product restored"),
+		"data":    p,
 	})
 }
 
-// @Summary Delete product
-// @Description Delete product permanently (Admin)
+// @Summary Delete product permanently
+// @Description Permanently delete an archived product and its image. Products that appear on any order cannot be deleted (Admin)
 // @Tags Admin - Products
 // @Security BearerAuth
 // @Produce json
 // @Param id path int true "Product ID"
-// @Success 200 {object} models.Response
-// @Router /admin/products/{id} [delete]
-func (ctrl *ProductController) DeleteProduct(c *gin.Context) {
+// @Success 200 {object} models.EnvelopeV2
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/purge [delete]
+// @DeprecatedRouter /v1/admin/products/{id}/purge [delete]
+// @DeprecatedRouter /admin/products/{id}/purge [delete]
+func (ctrl *ProductController) PurgeProduct(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	ctx := context.Background()
 
-	if id <= 0 {
-		c.JSON(400, gin.H{"success": false, "message": "Invalid product ID"})
+	if err := ctrl.products.Purge(c.Request.Context(), actorFrom(c), id); err != nil {
+		respondServiceError(c, err, "Failed to delete product")
 		return
 	}
 
-	var cloudinaryID string
-	err := models.DB.QueryRow(ctx,
-		"SELECT COALESCE(cloudinary_id, '') FROM products WHERE id=$1", id,
-	).Scan(&cloudinaryID)
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Product deleted permanently"),
+	})
+}
+
+// maxImportFileSize caps the sheet a product import accepts.
+const maxImportFileSize = 10 << 20
+
+// @Summary Export products
+// @Description Download the unarchived products as a sheet with their SKU, category name, price, stock, flags and image URL; the file can be edited and imported again (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "csv (default) or xlsx"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Router /v2/admin/products/export [get]
+// @DeprecatedRouter /v1/admin/products/export [get]
+// @DeprecatedRouter /admin/products/export [get]
+func (ctrl *ProductController) ExportProducts(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", spreadsheet.CSV))
+	if _, ok := spreadsheet.ContentTypes[format]; !ok {
+		c.JSON(400, models.ErrorResponse{Success: false, Message: msg(c, "Format must be csv or xlsx")})
+		return
+	}
 
+	rows, err := ctrl.products.Export(c.Request.Context())
 	if err != nil {
-		c.JSON(404, gin.H{"success": false, "message": "Product not found"})
+		respondServiceError(c, err, "Failed to export products")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := spreadsheet.Write(&buf, format, rows); err != nil {
+		respondServiceError(c, err, "Failed to export products")
 		return
 	}
+	filename := fmt.Sprintf("products-%s.%s", time.Now().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(200, spreadsheet.ContentTypes[format], buf.Bytes())
+}
 
-	_, err = models.DB.Exec(ctx, "DELETE FROM products WHERE id=$1", id)
+// @Summary Import products
+// @Description Create and update products from a CSV or XLSX sheet in the export's layout. Rows match products by SKU, then by name; empty cells keep the stored value. Without apply the import is a dry run that returns a row-by-row diff; with apply=true every row is written in one transaction, and nothing is written while any row has errors (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Sheet (.csv or .xlsx)"
+// @Param apply formData bool false "Write the changes instead of only previewing them"
+// @Success 200 {object} models.EnvelopeV2{data=models.ProductImportReportV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /v2/admin/products/import [post]
+// @DeprecatedRouter /v1/admin/products/import [post]
+// @DeprecatedRouter /admin/products/import [post]
+func (ctrl *ProductController) ImportProducts(c *gin.Context) {
+	header, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(500, gin.H{"success": false, "message": "Failed to delete product"})
+		c.JSON(400, models.ErrorResponse{Success: false, Message: msg(c, "File is required")})
+		return
+	}
+	format := spreadsheet.FormatOf(header.Filename)
+	if format == "" {
+		c.JSON(400, models.ErrorResponse{Success: false, Message: msg(c, "File must be a .csv or .xlsx sheet")})
 		return
 	}
+	if header.Size > maxImportFileSize {
+		c.JSON(400, models.ErrorResponse{Success: false, Message: msg(c, "File is too large")})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		respondServiceError(c, err, "Failed to read the file")
+		return
+	}
+	defer file.Close()
 
-	if cloudinaryID != "" {
-		cloudinaryService, _ := models.NewCloudinaryService()
-		if cloudinaryService != nil {
-			cloudinaryService.DeleteImage(ctx, cloudinaryID)
-		}
+	rows, err := spreadsheet.Read(file, header.Size, format)
+	if err != nil {
+		c.JSON(400, models.ErrorResponse{Success: false, Message: msg(c, "The file could not be read as a sheet")})
+		return
 	}
 
-	invalidateProductCache()
+	apply, _ := strconv.ParseBool(c.DefaultPostForm("apply", "false"))
+	report, err := ctrl.products.Import(c.Request.Context(), actorFrom(c), rows, apply)
+	if err != nil {
+		respondServiceError(c, err, "Failed to import products")
+		return
+	}
 
-	c.JSON(200, gin.H{
-		"success": true,
-		"message": "Product deleted permanently",
+	status, message := 200, "Import preview, nothing was changed"
+	switch {
+	case report.Applied:
+		message = "Products imported successfully"
+	case apply:
+		status, message = 400, "Fix the rows with errors, nothing was imported"
+	}
+	data := newProductImportReport(c, report)
+	if isV2(c) {
+		c.JSON(status, models.EnvelopeV2{Success: status == 200, Message: msg(c, message), Data: models.NewProductImportReportV2(data)})
+		return
+	}
+	c.JSON(status, gin.H{
+		"success": status == 200,
+		"message": msg(c, message),
+		"data":    data,
 	})
 }
+
+// newProductImportReport localizes the report's row errors and counts the
+// rows by action.
+func newProductImportReport(c *gin.Context, report services.ImportReport) models.ProductImportReport {
+	out := models.ProductImportReport{Applied: report.Applied, Rows: make([]models.ProductImportRow, 0, len(report.Rows))}
+	for _, row := range report.Rows {
+		var errs []string
+		for _, e := range row.Errors {
+			message := msg(c, e.Message)
+			if len(e.Args) > 0 {
+				message = fmt.Sprintf(message, e.Args...)
+			}
+			errs = append(errs, message)
+		}
+		out.Rows = append(out.Rows, models.ProductImportRow{Row: row.Row, Action: row.Action, ProductID: row.ProductID,
+			SKU: row.SKU, Name: row.Name, Changes: row.Changes, Errors: errs})
+
+		switch row.Action {
+		case models.ImportActionCreate:
+			out.Summary.Create++
+		case models.ImportActionUpdate:
+			out.Summary.Update++
+		case models.ImportActionUnchanged:
+			out.Summary.Unchanged++
+		case models.ImportActionError:
+			out.Summary.Error++
+		}
+	}
+	return out
+}
+
+// postFormString returns the form value, or nil when the field was not sent.
+func postFormString(c *gin.Context, key string) *string {
+	value, ok := c.GetPostForm(key)
+	if !ok {
+		return nil
+	}
+	return &value
+}
+
+func postFormInt(c *gin.Context, key string) *int {
+	value, ok := c.GetPostForm(key)
+	if !ok {
+		return nil
+	}
+	n, _ := strconv.Atoi(value)
+	return &n
+}
+
+func postFormBool(c *gin.Context, key string) *bool {
+	value, ok := c.GetPostForm(key)
+	if !ok {
+		return nil
+	}
+	b, _ := strconv.ParseBool(value)
+	return &b
+}
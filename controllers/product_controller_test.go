@@ -0,0 +1,479 @@
+package controllers
+
+import (
+	"coffee-shop/cache"
+	"coffee-shop/middleware"
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"coffee-shop/repositories/memory"
+	"coffee-shop/services"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type noImages struct{}
+
+func (noImages) Validate(*multipart.FileHeader) error { return nil }
+func (noImages) Upload(context.Context, multipart.File, string, string) (string, string, error) {
+	return "", "", nil
+}
+func (noImages) UploadMany(context.Context, []*multipart.FileHeader, string) ([]services.UploadedImage, error) {
+	return nil, nil
+}
+func (noImages) Delete(context.Context, string) error { return nil }
+
+func newProductRouter(t *testing.T) (*gin.Engine, *memory.Store, *cache.Memory) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore()
+	responses := cache.NewMemory()
+	products := services.NewProductService(store, noImages{}, responses)
+	ctrl := NewProductController(products, services.NewSearchService(store, responses), responses)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("locale", c.Query("lang")) })
+	signedIn := func(c *gin.Context) {
+		c.Set("user_id", 1)
+		c.Set("user_email", "admin@example.com")
+	}
+	router.GET("/products", middleware.CacheControl("PRODUCTS", "public, no-cache"), ctrl.GetAllProducts)
+	router.GET("/products/filter", ctrl.FilterProducts)
+	router.GET("/products/suggest", ctrl.SuggestProducts)
+	router.GET("/products/:id", ctrl.GetProductByID)
+	router.GET("/v2/products", func(c *gin.Context) { c.Set("api_version", 2) }, ctrl.GetAllProducts)
+	router.GET("/signed-in/products", signedIn, ctrl.GetAllProducts)
+	router.POST("/admin/products", signedIn, ctrl.CreateProduct)
+	router.PATCH("/admin/products/:id", signedIn, ctrl.UpdateProduct)
+	return router, store, responses
+}
+
+func TestGetAllProductsServesFromCache(t *testing.T) {
+	router, store, responses := newProductRouter(t)
+	p := models.Product{Name: "Latte", CategoryID: 1, Price: 25000, IsActive: true}
+	if err := store.Products().Create(context.Background(), &p); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products", nil))
+	if w.Code != 200 {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	var body models.HATEOASResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	// One cached response plus the namespace version.
+	if body.Meta.TotalItems != 1 || responses.Len() != 2 {
+		t.Fatalf("total=%d cached=%d", body.Meta.TotalItems, responses.Len())
+	}
+
+	if err := store.Products().Delete(context.Background(), p.ID); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products", nil))
+	if !strings.Contains(w.Body.String(), "Latte") {
+		t.Fatal("expected the second request to be served from cache")
+	}
+}
+
+func TestGetProductByIDCachedUntilProductChanges(t *testing.T) {
+	router, store, _ := newProductRouter(t)
+	p := models.Product{Name: "Latte", CategoryID: 1, Price: 25000, IsActive: true}
+	if err := store.Products().Create(context.Background(), &p); err != nil {
+		t.Fatal(err)
+	}
+	path := "/products/" + strconv.Itoa(p.ID)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"name":"Latte"`) {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	// Written behind the service's back: the cached response still wins.
+	p.Name = "Mocha"
+	if err := store.Products().Update(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if !strings.Contains(w.Body.String(), `"name":"Latte"`) {
+		t.Fatalf("expected the cached product, got %s", w.Body)
+	}
+
+	form := url.Values{"name": {"Flat White"}}
+	req := httptest.NewRequest(http.MethodPatch, "/admin"+path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("update status = %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if !strings.Contains(w.Body.String(), `"name":"Flat White"`) {
+		t.Fatalf("expected the update to invalidate the cache, got %s", w.Body)
+	}
+}
+
+func TestGetAllProductsConditionalRequests(t *testing.T) {
+	router, store, _ := newProductRouter(t)
+	p := models.Product{Name: "Latte", CategoryID: 1, Price: 25000, IsActive: true}
+	if err := store.Products().Create(context.Background(), &p); err != nil {
+		t.Fatal(err)
+	}
+	get := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("", "")
+	etag, modified := w.Header().Get("ETag"), w.Header().Get("Last-Modified")
+	if w.Code != 200 || !strings.HasPrefix(etag, `"`) || modified == "" || w.Header().Get("Cache-Control") != "public, no-cache" {
+		t.Fatalf("status = %d, headers = %v", w.Code, w.Header())
+	}
+
+	w = get("If-None-Match", etag)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+		t.Fatalf("If-None-Match: status = %d, body = %q", w.Code, w.Body)
+	}
+	if w = get("If-None-Match", `"stale"`); w.Code != 200 {
+		t.Fatalf("stale If-None-Match: status = %d", w.Code)
+	}
+	if w = get("If-Modified-Since", modified); w.Code != http.StatusNotModified {
+		t.Fatalf("If-Modified-Since: status = %d", w.Code)
+	}
+	// If-None-Match wins over If-Modified-Since.
+	req := httptest.NewRequest(http.MethodGet, "/products", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	req.Header.Set("If-Modified-Since", modified)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("both validators: status = %d", w.Code)
+	}
+
+	form := url.Values{"price": {"27000"}}
+	req = httptest.NewRequest(http.MethodPatch, "/admin/products/"+strconv.Itoa(p.ID), strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("update status = %d: %s", w.Code, w.Body)
+	}
+	if w = get("If-None-Match", etag); w.Code != 200 || w.Header().Get("ETag") == etag {
+		t.Fatalf("after update: status = %d, etag = %s", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestGetAllProductsModifiedWhenFlashSaleStarts(t *testing.T) {
+	router, store, _ := newProductRouter(t)
+	ctx := context.Background()
+	p := models.Product{Name: "Latte", CategoryID: 1, Price: 25000, IsActive: true}
+	if err := store.Products().Create(ctx, &p); err != nil {
+		t.Fatal(err)
+	}
+	salePrice := 20000
+	startsAt := time.Now().Add(1100 * time.Millisecond)
+	sale := models.FlashSale{Name: "Flash", StartsAt: startsAt, EndsAt: startsAt.Add(time.Hour), IsActive: true,
+		Items: []models.FlashSaleItem{{ProductID: p.ID, SalePrice: &salePrice}}}
+	if err := store.FlashSales().Create(ctx, &sale); err != nil {
+		t.Fatal(err)
+	}
+	get := func(since string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		if since != "" {
+			req.Header.Set("If-Modified-Since", since)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	modified := w.Header().Get("Last-Modified")
+	if w = get(modified); w.Code != http.StatusNotModified {
+		t.Fatalf("before the sale: status = %d", w.Code)
+	}
+
+	time.Sleep(time.Until(startsAt) + 100*time.Millisecond)
+	w = get(modified)
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"price":20000`) {
+		t.Fatalf("after the sale started: status = %d, body = %s", w.Code, w.Body)
+	}
+	if after, _ := http.ParseTime(w.Header().Get("Last-Modified")); after.Before(startsAt.Truncate(time.Second)) {
+		t.Fatalf("Last-Modified = %s, sale started %s", after, startsAt)
+	}
+}
+
+func TestCachedProductsFollowOrderStock(t *testing.T) {
+	router, store, responses := newProductRouter(t)
+	ctx := context.Background()
+	p := models.Product{Name: "Latte", CategoryID: 1, Price: 25000, Stock: 5, IsActive: true}
+	if err := store.Products().Create(ctx, &p); err != nil {
+		t.Fatal(err)
+	}
+	expectStock := func(stock int) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products", nil))
+		if want := `"stock":` + strconv.Itoa(stock); w.Code != 200 || !strings.Contains(w.Body.String(), want) {
+			t.Fatalf("want %s, got %d: %s", want, w.Code, w.Body)
+		}
+	}
+	expectStock(5)
+
+	if _, err := store.Carts().AddItem(ctx, repositories.CartItemKey{UserID: 2, ProductID: p.ID}, 2); err != nil {
+		t.Fatal(err)
+	}
+	orders := services.NewOrderService(store, nil, responses)
+	result, err := orders.Checkout(ctx, 2, services.CheckoutInput{
+		Email: "b@example.com", FullName: "Budi", Address: "Jl. Kopi 1", DeliveryMethod: "pick_up",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStock(3)
+
+	actor := services.Actor{ID: 1, Email: "admin@example.com"}
+	if err := orders.UpdateStatus(ctx, actor, result.ID, "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	expectStock(5)
+}
+
+func TestSignedInProductsValidatedByETagOnly(t *testing.T) {
+	router, store, _ := newProductRouter(t)
+	p := models.Product{Name: "Latte", CategoryID: 1, Price: 25000, IsActive: true}
+	if err := store.Products().Create(context.Background(), &p); err != nil {
+		t.Fatal(err)
+	}
+	get := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/signed-in/products", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != 200 || etag == "" || w.Header().Get("Last-Modified") != "" {
+		t.Fatalf("status = %d, headers = %v", w.Code, w.Header())
+	}
+	if w = get("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); w.Code != 200 {
+		t.Fatalf("If-Modified-Since: status = %d", w.Code)
+	}
+	if w = get("If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Fatalf("If-None-Match: status = %d", w.Code)
+	}
+}
+
+func TestGetAllProductsV2UsesCamelCase(t *testing.T) {
+	router, store, _ := newProductRouter(t)
+	p := models.Product{Name: "Latte", CategoryID: 1, Price: 25000, IsActive: true}
+	if err := store.Products().Create(context.Background(), &p); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/products", nil))
+	if !strings.Contains(w.Body.String(), `"categoryId":1`) {
+		t.Fatalf("unexpected v2 body %s", w.Body)
+	}
+}
+
+func TestCreateProductLocalizesValidationErrors(t *testing.T) {
+	router, _, _ := newProductRouter(t)
+
+	form := url.Values{"name": {"Latte"}, "category_id": {"1"}, "price": {"10"}}
+	req := httptest.NewRequest(http.MethodPost, "/admin/products?lang=id", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != 400 {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["message"] == "Price must be at least 1000" {
+		t.Fatalf("message was not translated: %v", body["message"])
+	}
+}
+
+func TestCreateProductRecordsAudit(t *testing.T) {
+	router, store, _ := newProductRouter(t)
+
+	form := url.Values{"name": {"Latte"}, "category_id": {"1"}, "price": {"25000"}, "stock": {"4"}}
+	req := httptest.NewRequest(http.MethodPost, "/admin/products", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != 201 {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	entries := store.AuditEntries()
+	if len(entries) != 1 || entries[0].ActorEmail != "admin@example.com" {
+		t.Fatalf("unexpected audit entries %+v", entries)
+	}
+}
+
+func TestGetAllProductsCursorPagination(t *testing.T) {
+	router, store, _ := newProductRouter(t)
+	for _, name := range []string{"Latte", "Mocha", "Espresso"} {
+		p := models.Product{Name: name, CategoryID: 1, Price: 25000, IsActive: true}
+		if err := store.Products().Create(context.Background(), &p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var first models.HATEOASResponse
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products?limit=2", nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.Meta.NextCursor == "" || len(first.Data.([]interface{})) != 2 {
+		t.Fatalf("first page = %s", w.Body)
+	}
+
+	var second models.HATEOASResponse
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products?limit=2&cursor="+first.Meta.NextCursor, nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &second); err != nil {
+		t.Fatal(err)
+	}
+	items := second.Data.([]interface{})
+	if len(items) != 1 || items[0].(map[string]interface{})["name"] != "Latte" || second.Links.Next != "" {
+		t.Fatalf("second page = %s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products?cursor=bogus", nil))
+	if w.Code != 400 {
+		t.Fatalf("bogus cursor status = %d", w.Code)
+	}
+}
+
+func TestSuggestProducts(t *testing.T) {
+	router, store, responses := newProductRouter(t)
+	ctx := context.Background()
+	category := models.Category{Name: "Latte Art"}
+	if err := store.Categories().Create(ctx, &category); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Iced Latte", "Espresso", "Lavender Tea"} {
+		p := models.Product{Name: name, CategoryID: category.ID, Price: 25000, IsActive: true}
+		if err := store.Products().Create(ctx, &p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Three shoppers searching makes "latte" popular enough to suggest; a
+	// shopper repeating it, later pages and searches that found nothing are
+	// not counted.
+	searches := []struct{ path, ip string }{
+		{"/products/filter?search=Latte", "192.0.2.1"},
+		{"/products/filter?search=latte%20", "192.0.2.2"},
+		{"/products/filter?search=LATTE", "192.0.2.3"},
+		{"/products/filter?search=latte", "192.0.2.1"},
+		{"/products/filter?search=latte&page=2", "192.0.2.4"},
+		{"/products/filter?search=mocha", "192.0.2.1"},
+	}
+	for _, s := range searches {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, s.path, nil)
+		req.RemoteAddr = s.ip + ":1234"
+		router.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("%s: status = %d", s.path, w.Code)
+		}
+	}
+	if hits := store.SearchHits("latte"); hits != 3 {
+		t.Fatalf("hits = %d, want 3", hits)
+	}
+	if hits := store.SearchHits("mocha"); hits != 0 {
+		t.Fatalf("mocha hits = %d, want 0", hits)
+	}
+
+	suggested := func(q string) models.Suggestions {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products/suggest?q="+q, nil))
+		if w.Code != 200 {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		var body struct {
+			Data models.Suggestions `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Data
+	}
+	if s := suggested("La"); len(s.Queries) != 0 {
+		t.Fatalf("unmoderated queries suggested: %+v", s.Queries)
+	}
+
+	searchService := services.NewSearchService(store, responses)
+	pending, _, err := searchService.Queries(ctx, models.SearchQueryPending, pagination.Params{Limit: 10})
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("pending = %+v, %v", pending, err)
+	}
+	if _, err := searchService.ModerateQuery(ctx, services.Actor{ID: 1}, pending[0].ID, models.SearchQueryAllowed); err != nil {
+		t.Fatal(err)
+	}
+
+	s := suggested("La")
+	if len(s.Products) != 2 || s.Products[0].Name != "Lavender Tea" || s.Products[1].Name != "Iced Latte" {
+		t.Fatalf("products = %+v", s.Products)
+	}
+	if len(s.Categories) != 1 || s.Categories[0].Name != "Latte Art" {
+		t.Fatalf("categories = %+v", s.Categories)
+	}
+	if len(s.Queries) != 1 || s.Queries[0] != "latte" {
+		t.Fatalf("queries = %+v", s.Queries)
+	}
+
+	cached := responses.Len()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products/suggest?q=la", nil))
+	if w.Code != 200 || responses.Len() != cached {
+		t.Fatalf("expected the normalized query to hit the cache (status %d)", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products/suggest?q=esspreso", nil))
+	if !strings.Contains(w.Body.String(), `"name":"Espresso"`) {
+		t.Fatalf("typo suggestion missing: %s", w.Body)
+	}
+}
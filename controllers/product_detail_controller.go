@@ -1,131 +1,353 @@
 package controllers
 
 import (
+	"coffee-shop/cache"
 	"coffee-shop/models"
-	"context"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"coffee-shop/services"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-type ProductDetailController struct{}
+type ProductDetailController struct {
+	products *services.ProductService
+	carts    *services.CartService
+	cache    *cache.Namespace
+}
+
+func NewProductDetailController(products *services.ProductService, carts *services.CartService,
+	responses cache.Store) *ProductDetailController {
+	return &ProductDetailController{
+		products: products,
+		carts:    carts,
+		cache:    cache.NewNamespace(responses, services.ProductCacheNamespace),
+	}
+}
 
 // @Summary Get product detail with variants
-// @Description Get complete product information including sizes, temperatures, and recommendations
+// @Description Get complete product information including the option combinations it is sold in, and recommendations
 // @Tags Products
 // @Produce json
 // @Param id path int true "Product ID"
-// @Success 200 {object} models.Response
-// @Router /products/{id}/detail [get]
+// @Param If-None-Match header string false "ETag of the copy the client has"
+// @Param If-Modified-Since header string false "Last-Modified of the copy the client has"
+// @Success 200 {object} models.EnvelopeV2{data=models.ProductDetailV2}
+// @Success 304 "Not modified"
+// @Router /v2/products/{id}/detail [get]
+// @DeprecatedRouter /v1/products/{id}/detail [get]
+// @DeprecatedRouter /products/{id}/detail [get]
 func (ctrl *ProductDetailController) GetProductDetail(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 
-	var p models.Product
-	err := models.DB.QueryRow(context.Background(),
-		`SELECT id, name, description, category_id, price, stock, COALESCE(image_url, ''), 
-		COALESCE(is_flash_sale, false), COALESCE(is_favorite, false), COALESCE(is_buy1get1, false), 
-		is_active, created_at, updated_at FROM products WHERE id=$1 AND is_active=true`, id).
-		Scan(&p.ID, &p.Name, &p.Description, &p.CategoryID, &p.Price, &p.Stock, &p.ImageURL,
-			&p.IsFlashSale, &p.IsFavorite, &p.IsBuy1Get1, &p.IsActive, &p.CreatedAt, &p.UpdatedAt)
+	key := fmt.Sprintf("detail_%d_%s", id, responseVariant(c))
+	serveCached(c, ctrl.cache, key, "Product not found", func() (cacheable, error) {
+		d, err := ctrl.products.Detail(c.Request.Context(), id, requestLocale(c))
+		if err != nil {
+			return cacheable{}, err
+		}
+		marked := []models.Product{d.Product}
+		if err := setFavorited(c, ctrl.products, marked); err != nil {
+			return cacheable{}, err
+		}
+		d.Product = marked[0]
+		recordView(c, ctrl.products, d.Product.ID)
+		ttl := ctrl.products.CacheTTL(c.Request.Context(), 5*time.Minute)
+		modified := productsModified(c.Request.Context(), ctrl.products, marked)
+
+		if isV2(c) {
+			return cacheable{body: models.EnvelopeV2{Success: true, Message: msg(c, "Product detail retrieved"), Data: models.ProductDetailV2{
+				Product:         models.NewProductV2(d.Product),
+				Images:          d.Images,
+				Sizes:           d.Sizes,
+				Temperatures:    d.Temperatures,
+				Options:         d.Options,
+				TotalReviews:    d.TotalReviews,
+				AverageRating:   d.AverageRating,
+				Reviews:         d.Reviews,
+				Recommendations: d.Recommendations,
+			}}, modified: modified, ttl: ttl}, nil
+		}
+		return cacheable{body: gin.H{
+			"success": true,
+			"message": msg(c, "Product detail retrieved"),
+			"data": gin.H{
+				"product":         d.Product,
+				"images":          d.Images,
+				"sizes":           d.Sizes,
+				"temperatures":    d.Temperatures,
+				"options":         d.Options,
+				"totalReviews":    d.TotalReviews,
+				"averageRating":   d.AverageRating,
+				"reviews":         d.Reviews,
+				"recommendations": d.Recommendations,
+			},
+		}, modified: modified, ttl: ttl}, nil
+	})
+}
 
+// respondRecommendations answers with recommended products, each carrying
+// its recommendation_type.
+func (ctrl *ProductDetailController) respondRecommendations(c *gin.Context, products []models.Product) {
+	if !markFavorited(c, ctrl.products, products, "Failed to retrieve recommendations") {
+		return
+	}
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Recommendations retrieved"), models.NewProductListV2(products))
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Recommendations retrieved"),
+		"data":    products,
+	})
+}
+
+// @Summary Get product recommendations
+// @Description Products to suggest with a product: the shop's upsells, cross-sells and pairings first, then products often ordered with it, then others from its category and the best sellers. recommendation_type says which
+// @Tags Products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param limit query int false "Number of products (default 6, max 20)"
+// @Success 200 {object} models.EnvelopeV2{data=[]models.ProductV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/products/{id}/recommendations [get]
+// @DeprecatedRouter /v1/products/{id}/recommendations [get]
+// @DeprecatedRouter /products/{id}/recommendations [get]
+func (ctrl *ProductDetailController) GetProductRecommendations(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	products, err := ctrl.products.Recommendations(c.Request.Context(), id, limit, requestLocale(c))
 	if err != nil {
-		c.JSON(404, gin.H{"success": false, "message": "Product not found"})
+		respondServiceError(c, err, "Failed to retrieve recommendations")
 		return
 	}
+	ctrl.respondRecommendations(c, products)
+}
 
-	images := []gin.H{}
-	imgRows, _ := models.DB.Query(context.Background(),
-		"SELECT image_url, display_order FROM product_images WHERE product_id=$1 ORDER BY display_order", id)
-	defer imgRows.Close()
-	for imgRows.Next() {
-		var url string
-		var order int
-		imgRows.Scan(&url, &order)
-		images = append(images, gin.H{"url": url, "order": order})
+// @Summary Get product reviews
+// @Description Get a product's approved reviews, newest first, with the average rating and the number of reviews per rating
+// @Tags Products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param cursor query string false "Continue after meta.next_cursor instead of using page"
+// @Success 200 {object} models.ListEnvelopeV2{data=[]models.ProductReviewV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/products/{id}/reviews [get]
+// @DeprecatedRouter /v1/products/{id}/reviews [get]
+// @DeprecatedRouter /products/{id}/reviews [get]
+func (ctrl *ProductDetailController) GetProductReviews(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	page, ok := pageParams(c, 10)
+	if !ok {
+		return
 	}
 
-	sizes := []gin.H{}
-	sizeRows, _ := models.DB.Query(context.Background(),
-		"SELECT id, name, price_adjustment FROM product_sizes WHERE is_active=true ORDER BY price_adjustment")
-	defer sizeRows.Close()
-	for sizeRows.Next() {
-		var sid int
-		var name string
-		var adj int
-		sizeRows.Scan(&sid, &name, &adj)
-		sizes = append(sizes, gin.H{"id": sid, "name": name, "priceAdjustment": adj})
+	reviews, total, ratings, err := ctrl.products.Reviews(c.Request.Context(), id, page)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve reviews")
+		return
 	}
 
-	temps := []gin.H{}
-	tempRows, _ := models.DB.Query(context.Background(),
-		"SELECT id, name FROM product_temperatures WHERE is_active=true")
-	defer tempRows.Close()
-	for tempRows.Next() {
-		var tid int
-		var name string
-		tempRows.Scan(&tid, &name)
-		temps = append(temps, gin.H{"id": tid, "name": name})
+	var next *pagination.Cursor
+	if n := len(reviews); n > 0 {
+		next = pagination.Next(page, n, pagination.Cursor{CreatedAt: reviews[n-1].CreatedAt, ID: reviews[n-1].ID})
 	}
 
-	var totalReviews int
-	var avgRating float64
-	models.DB.QueryRow(context.Background(),
-		"SELECT COUNT(*), COALESCE(AVG(rating), 0) FROM product_reviews WHERE product_id=$1", id).
-		Scan(&totalReviews, &avgRating)
-
-	reviews := []gin.H{}
-	revRows, _ := models.DB.Query(context.Background(),
-		`SELECT pr.rating, pr.review_text, pr.created_at, up.full_name 
-		FROM product_reviews pr 
-		LEFT JOIN user_profiles up ON pr.user_id=up.user_id 
-		WHERE pr.product_id=$1 ORDER BY pr.created_at DESC LIMIT 5`, id)
-	defer revRows.Close()
-	for revRows.Next() {
-		var rating int
-		var text, name string
-		var created interface{}
-		revRows.Scan(&rating, &text, &created, &name)
-		reviews = append(reviews, gin.H{
-			"rating": rating, "text": text, "user": name, "createdAt": created,
-		})
+	// Both versions show the storefront review body.
+	items := models.NewProductReviewListV2(reviews)
+	response := pagination.Response(c, msg(c, "Reviews retrieved"), items, page, total, next)
+	response.Ratings = &ratings
+	if isV2(c) {
+		c.JSON(200, models.NewListEnvelopeV2(response, items))
+		return
 	}
+	c.JSON(200, response)
+}
 
-	recs := []gin.H{}
-	recRows, _ := models.DB.Query(context.Background(),
-		`SELECT id, name, price, COALESCE(image_url, ''), COALESCE(is_flash_sale, false) 
-		FROM products WHERE category_id=$1 AND id!=$2 AND is_active=true LIMIT 3`,
-		p.CategoryID, id)
-	defer recRows.Close()
-	for recRows.Next() {
-		var rid, rprice int
-		var rname, rimg string
-		var rflash bool
-		recRows.Scan(&rid, &rname, &rprice, &rimg, &rflash)
-		recs = append(recs, gin.H{
-			"id": rid, "name": rname, "price": rprice, "imageUrl": rimg, "isFlashSale": rflash,
-		})
+func respondReview(c *gin.Context, status int, message string, review models.ProductReview) {
+	if isV2(c) {
+		respondV2(c, status, msg(c, message), models.NewReviewV2(review))
+		return
+	}
+	c.JSON(status, gin.H{
+		"success": true,
+		"message": msg(c, message),
+		"data":    review,
+	})
+}
+
+// @Summary Write a review
+// @Description Review a product received in a completed order, once per product. The review is shown after an admin approves it
+// @Tags Products
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param review body models.ReviewRequest true "Rating from 1 to 5 and optional text"
+// @Success 201 {object} models.EnvelopeV2{data=models.ReviewV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /v2/products/{id}/reviews [post]
+// @DeprecatedRouter /v1/products/{id}/reviews [post]
+// @DeprecatedRouter /products/{id}/reviews [post]
+func (ctrl *ProductDetailController) CreateReview(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var req models.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid request data: ") + err.Error()})
+		return
+	}
+
+	review, err := ctrl.products.CreateReview(c.Request.Context(), c.GetInt("user_id"), id, req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to create review")
+		return
+	}
+	respondReview(c, 201, "Review submitted for moderation", review)
+}
+
+// @Summary Edit a review
+// @Description Change the rating or text of your own review; it is moderated again
+// @Tags Products
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param reviewId path int true "Review ID"
+// @Param review body models.ReviewRequest true "Fields to change"
+// @Success 200 {object} models.EnvelopeV2{data=models.ReviewV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/products/{id}/reviews/{reviewId} [patch]
+// @DeprecatedRouter /v1/products/{id}/reviews/{reviewId} [patch]
+// @DeprecatedRouter /products/{id}/reviews/{reviewId} [patch]
+func (ctrl *ProductDetailController) UpdateReview(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	reviewID, _ := strconv.Atoi(c.Param("reviewId"))
+	var req models.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid request data: ") + err.Error()})
+		return
+	}
+
+	review, err := ctrl.products.UpdateReview(c.Request.Context(), c.GetInt("user_id"), id, reviewID, req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update review")
+		return
+	}
+	respondReview(c, 200, "Review updated and submitted for moderation", review)
+}
+
+// @Summary Delete a review
+// @Description Delete your own review
+// @Tags Products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param reviewId path int true "Review ID"
+// @Success 200 {object} models.EnvelopeV2
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/products/{id}/reviews/{reviewId} [delete]
+// @DeprecatedRouter /v1/products/{id}/reviews/{reviewId} [delete]
+// @DeprecatedRouter /products/{id}/reviews/{reviewId} [delete]
+func (ctrl *ProductDetailController) DeleteReview(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	reviewID, _ := strconv.Atoi(c.Param("reviewId"))
+
+	if err := ctrl.products.DeleteReview(c.Request.Context(), c.GetInt("user_id"), id, reviewID); err != nil {
+		respondServiceError(c, err, "Failed to delete review")
+		return
 	}
 
 	c.JSON(200, gin.H{
 		"success": true,
-		"message": "Product detail retrieved",
-		"data": gin.H{
-			"product":         p,
-			"images":          images,
-			"sizes":           sizes,
-			"temperatures":    temps,
-			"totalReviews":    totalReviews,
-			"averageRating":   avgRating,
-			"reviews":         reviews,
-			"recommendations": recs,
-		},
+		"message": msg(c, "Review deleted"),
 	})
 }
 
+// @Summary Get the review moderation queue
+// @Description List reviews in a status, newest first (Admin)
+// @Tags Admin - Reviews
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "pending (default), approved, hidden or all"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param cursor query string false "Continue after meta.next_cursor instead of using page"
+// @Success 200 {object} models.ListEnvelopeV2{data=[]models.ReviewV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /v2/admin/reviews [get]
+// @DeprecatedRouter /v1/admin/reviews [get]
+// @DeprecatedRouter /admin/reviews [get]
+func (ctrl *ProductDetailController) GetReviewQueue(c *gin.Context) {
+	page, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+	status := c.DefaultQuery("status", models.ReviewPending)
+	if status == "all" {
+		status = ""
+	}
+
+	reviews, total, err := ctrl.products.ReviewQueue(c.Request.Context(), status, page)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve reviews")
+		return
+	}
+
+	var next *pagination.Cursor
+	if n := len(reviews); n > 0 {
+		next = pagination.Next(page, n, pagination.Cursor{CreatedAt: reviews[n-1].CreatedAt, ID: reviews[n-1].ID})
+	}
+
+	response := pagination.Response(c, msg(c, "Reviews retrieved"), reviews, page, total, next)
+	if isV2(c) {
+		c.JSON(200, models.NewListEnvelopeV2(response, models.NewReviewListV2(reviews)))
+		return
+	}
+	c.JSON(200, response)
+}
+
+// @Summary Moderate a review
+// @Description Approve or hide a review, and set or remove the shop's reply; an empty reply removes it (Admin)
+// @Tags Admin - Reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param moderation body models.ReviewModerationRequest true "status (approved or hidden) and/or reply"
+// @Success 200 {object} models.EnvelopeV2{data=models.ReviewV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/reviews/{id} [patch]
+// @DeprecatedRouter /v1/admin/reviews/{id} [patch]
+// @DeprecatedRouter /admin/reviews/{id} [patch]
+func (ctrl *ProductDetailController) ModerateReview(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var req models.ReviewModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid request data: ") + err.Error()})
+		return
+	}
+
+	review, err := ctrl.products.ModerateReview(c.Request.Context(), actorFrom(c), id, req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to moderate review")
+		return
+	}
+	respondReview(c, 200, "Review moderated", review)
+}
+
 // Create cart
 // @Summary Add to cart
-// @Description Add product to cart
+// @Description Add product to cart. The size, temperature and variant must be one of the product's options; products without options are added without any
 // @Tags Cart
 // @Security BearerAuth
 // @Accept multipart/form-data
@@ -135,221 +357,111 @@ func (ctrl *ProductDetailController) GetProductDetail(c *gin.Context) {
 // @Param size_id formData int false "Size ID"
 // @Param temperature_id formData int false "Temperature ID"
 // @Param variant_id formData int false "Variant ID"
-// @Success 201 {object} models.Response
-// @Router /cart [post]
+// @Success 201 {object} models.EnvelopeV2{data=models.CartItemRefV2}
+// @Router /v2/cart [post]
+// @DeprecatedRouter /v1/cart [post]
+// @DeprecatedRouter /cart [post]
 func (ctrl *ProductDetailController) AddToCart(c *gin.Context) {
-	ctx := context.Background()
-	userID := c.GetInt("user_id")
-
 	productIDStr := c.PostForm("product_id")
 	quantityStr := c.PostForm("quantity")
-	sizeIDStr := c.PostForm("size_id")
-	tempIDStr := c.PostForm("temperature_id")
-	variantIDStr := c.PostForm("variant_id")
 
 	if productIDStr == "" || quantityStr == "" {
-		c.JSON(400, gin.H{"success": false, "message": "Product ID and quantity are required"})
+		c.JSON(400, gin.H{"success": false, "message": msg(c, "Product ID and quantity are required")})
 		return
 	}
 
 	productID, err := strconv.Atoi(productIDStr)
 	if err != nil || productID <= 0 {
-		c.JSON(400, gin.H{"success": false, "message": "Invalid product ID"})
+		c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid product ID")})
 		return
 	}
 
 	quantity, err := strconv.Atoi(quantityStr)
 	if err != nil || quantity <= 0 {
-		c.JSON(400, gin.H{"success": false, "message": "Invalid quantity"})
+		c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid quantity")})
 		return
 	}
 
-	var sizeID, tempID, variantID *int
-
-	if sizeIDStr != "" {
-		if val, err := strconv.Atoi(sizeIDStr); err == nil && val > 0 {
-			sizeID = &val
-		}
+	key := repositories.CartItemKey{
+		UserID:        c.GetInt("user_id"),
+		ProductID:     productID,
+		SizeID:        optionalFormID(c, "size_id"),
+		TemperatureID: optionalFormID(c, "temperature_id"),
+		VariantID:     optionalFormID(c, "variant_id"),
 	}
 
-	if tempIDStr != "" {
-		if val, err := strconv.Atoi(tempIDStr); err == nil && val > 0 {
-			tempID = &val
-		}
-	}
-
-	if variantIDStr != "" {
-		if val, err := strconv.Atoi(variantIDStr); err == nil && val > 0 {
-			variantID = &val
-		}
-	}
-
-	var productExists bool
-	var stock int
-	err = models.DB.QueryRow(ctx,
-		"SELECT EXISTS(SELECT 1 FROM products WHERE id=$1 AND is_active=true), COALESCE((SELECT stock FROM products WHERE id=$1), 0)",
-		productID).Scan(&productExists, &stock)
-
-	if err != nil || !productExists {
-		c.JSON(400, gin.H{"success": false, "message": "Product not found or inactive"})
-		return
-	}
-
-	if stock < quantity {
-		c.JSON(400, gin.H{"success": false, "message": fmt.Sprintf("Insufficient stock. Available: %d", stock)})
+	ref, created, err := ctrl.carts.Add(c.Request.Context(), key, quantity)
+	if err != nil {
+		respondServiceError(c, err, "Failed to add to cart")
 		return
 	}
 
-	var existingID, existingQty int
-	checkQuery := `
-		SELECT id, quantity FROM cart_items 
-		WHERE user_id=$1 
-		AND product_id=$2 
-		AND ($3::int IS NULL AND size_id IS NULL OR size_id = $3)
-		AND ($4::int IS NULL AND temperature_id IS NULL OR temperature_id = $4)
-		AND ($5::int IS NULL AND variant_id IS NULL OR variant_id = $5)
-	`
-
-	err = models.DB.QueryRow(ctx, checkQuery,
-		userID, productID, sizeID, tempID, variantID).Scan(&existingID, &existingQty)
-
-	if err == nil {
-		newQty := existingQty + quantity
-
-		if stock < newQty {
-			c.JSON(400, gin.H{"success": false, "message": fmt.Sprintf("Insufficient stock. Available: %d, Current cart: %d", stock, existingQty)})
-			return
-		}
-
-		_, err = models.DB.Exec(ctx,
-			"UPDATE cart_items SET quantity=$1, updated_at=NOW() WHERE id=$2",
-			newQty, existingID)
-
-		if err != nil {
-			c.JSON(500, gin.H{"success": false, "message": "Failed to update cart: " + err.Error()})
-			return
-		}
-
+	if !created {
 		c.JSON(200, gin.H{
 			"success": true,
-			"message": "Cart updated successfully",
-			"data": gin.H{
-				"cartItemId": existingID,
-				"quantity":   newQty,
-			},
+			"message": msg(c, "Cart updated successfully"),
+			"data":    ref,
 		})
 		return
 	}
 
-	insertQuery := `
-		INSERT INTO cart_items (user_id, product_id, quantity, size_id, temperature_id, variant_id, created_at, updated_at) 
-		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
-		RETURNING id
-	`
-
-	var newCartID int
-	err = models.DB.QueryRow(ctx, insertQuery,
-		userID, productID, quantity, sizeID, tempID, variantID).Scan(&newCartID)
-
-	if err != nil {
-		c.JSON(500, gin.H{"success": false, "message": "Failed to add to cart: " + err.Error()})
-		return
-	}
-
 	c.JSON(201, gin.H{
 		"success": true,
-		"message": "Added to cart successfully",
-		"data": gin.H{
-			"cartItemId": newCartID,
-			"quantity":   quantity,
-		},
+		"message": msg(c, "Added to cart successfully"),
+		"data":    ref,
 	})
 }
 
+// optionalFormID returns the positive ID in the form field, or nil when it
+// is absent or invalid.
+func optionalFormID(c *gin.Context, key string) *int {
+	if val, err := strconv.Atoi(c.PostForm(key)); err == nil && val > 0 {
+		return &val
+	}
+	return nil
+}
+
 // Get user cart
 // @Summary Get user cart
 // @Description Get all cart items for current user
 // @Tags Cart
 // @Security BearerAuth
 // @Produce json
-// @Success 200 {object} models.Response
-// @Router /cart [get]
+// @Success 200 {object} models.EnvelopeV2{data=models.CartV2}
+// @Router /v2/cart [get]
+// @DeprecatedRouter /v1/cart [get]
+// @DeprecatedRouter /cart [get]
 func (ctrl *ProductDetailController) GetCart(c *gin.Context) {
-	ctx := context.Background()
-	userID := c.GetInt("user_id")
-
-	rows, err := models.DB.Query(ctx,
-		`SELECT 
-			ci.id, 
-			ci.product_id, 
-			p.name, 
-			p.price, 
-			ci.quantity, 
-			COALESCE(ps.name,'') as size_name, 
-			COALESCE(ps.price_adjustment,0) as size_adj,
-			COALESCE(pt.name,'') as temp_name, 
-			COALESCE(pt.price,0) as temp_price,
-			COALESCE(pv.name,'') as variant_name, 
-			COALESCE(pv.price,0) as variant_price,
-			COALESCE(p.image_url,'') as image_url,
-			p.stock
-		FROM cart_items ci
-		JOIN products p ON ci.product_id=p.id
-		LEFT JOIN product_sizes ps ON ci.size_id=ps.id
-		LEFT JOIN product_temperatures pt ON ci.temperature_id=pt.id
-		LEFT JOIN product_variants pv ON ci.variant_id=pv.id
-		WHERE ci.user_id=$1 
-		AND p.is_active=true
-		ORDER BY ci.created_at DESC`, userID)
-
+	cart, err := ctrl.carts.Get(c.Request.Context(), c.GetInt("user_id"), requestLocale(c))
 	if err != nil {
-		c.JSON(500, gin.H{"success": false, "message": "Failed to retrieve cart: " + err.Error()})
+		respondServiceError(c, err, "Failed to retrieve cart")
 		return
 	}
-	defer rows.Close()
-
-	items := []gin.H{}
-	subtotal := 0
-
-	for rows.Next() {
-		var id, pid, basePrice, qty, sizeAdj, tempPrice, variantPrice, stock int
-		var pname, sizeName, tempName, variantName, img string
-
-		err = rows.Scan(&id, &pid, &pname, &basePrice, &qty,
-			&sizeName, &sizeAdj, &tempName, &tempPrice,
-			&variantName, &variantPrice, &img, &stock)
-
-		if err != nil {
-			continue
-		}
-
-		itemPrice := (basePrice + sizeAdj + tempPrice + variantPrice) * qty
-		subtotal += itemPrice
-
-		items = append(items, gin.H{
-			"id":               id,
-			"productId":        pid,
-			"name":             pname,
-			"basePrice":        basePrice,
-			"quantity":         qty,
-			"size":             sizeName,
-			"sizeAdjustment":   sizeAdj,
-			"temperature":      tempName,
-			"temperaturePrice": tempPrice,
-			"variant":          variantName,
-			"variantPrice":     variantPrice,
-			"subtotal":         itemPrice,
-			"imageUrl":         img,
-			"stock":            stock,
-		})
-	}
 
 	c.JSON(200, gin.H{
 		"success": true,
-		"message": "Cart retrieved",
-		"data": gin.H{
-			"items":    items,
-			"subtotal": subtotal,
-		},
+		"message": msg(c, "Cart retrieved"),
+		"data":    cart,
 	})
 }
+
+// @Summary Get cart recommendations
+// @Description Products to add to the cart: the shop's cross-sells and pairings for what is in it, then products often ordered with it, then others from the same categories and the best sellers. An empty cart gets the best sellers
+// @Tags Cart
+// @Security BearerAuth
+// @Produce json
+// @Param limit query int false "Number of products (default 6, max 20)"
+// @Success 200 {object} models.EnvelopeV2{data=[]models.ProductV2}
+// @Router /v2/cart/recommendations [get]
+// @DeprecatedRouter /v1/cart/recommendations [get]
+// @DeprecatedRouter /cart/recommendations [get]
+func (ctrl *ProductDetailController) GetCartRecommendations(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	products, err := ctrl.products.CartRecommendations(c.Request.Context(), c.GetInt("user_id"), limit, requestLocale(c))
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve recommendations")
+		return
+	}
+	ctrl.respondRecommendations(c, products)
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/services"
+	"mime/multipart"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProductGalleryController manages the images admins upload for a product.
+type ProductGalleryController struct {
+	products *services.ProductService
+}
+
+func NewProductGalleryController(products *services.ProductService) *ProductGalleryController {
+	return &ProductGalleryController{products: products}
+}
+
+// respondGallery answers with a product gallery in display order.
+func respondGallery(c *gin.Context, status int, message string, images []models.ProductImage) {
+	if isV2(c) {
+		respondV2(c, status, msg(c, message), models.NewProductImageListV2(images))
+		return
+	}
+	c.JSON(status, gin.H{
+		"success": true,
+		"message": msg(c, message),
+		"data":    images,
+	})
+}
+
+// @Summary Get product images
+// @Description List the image gallery of a product in display order (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} models.EnvelopeV2{data=[]models.ProductImageV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/images [get]
+// @DeprecatedRouter /v1/admin/products/{id}/images [get]
+// @DeprecatedRouter /admin/products/{id}/images [get]
+func (ctrl *ProductGalleryController) GetProductImages(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	images, err := ctrl.products.Images(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve product images")
+		return
+	}
+	respondGallery(c, 200, "Product images retrieved", images)
+}
+
+// @Summary Upload product images
+// @Description Add up to four images to the end of a product gallery. The first image of an empty gallery becomes the primary image (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param images formData file true "Images (repeat the field for several files)"
+// @Success 201 {object} models.EnvelopeV2{data=[]models.ProductImageV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/images [post]
+// @DeprecatedRouter /v1/admin/products/{id}/images [post]
+// @DeprecatedRouter /admin/products/{id}/images [post]
+func (ctrl *ProductGalleryController) UploadProductImages(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var files []*multipart.FileHeader
+	if form, err := c.MultipartForm(); err == nil {
+		files = form.File["images"]
+	}
+
+	images, err := ctrl.products.AddImages(c.Request.Context(), actorFrom(c), id, files)
+	if err != nil {
+		respondServiceError(c, err, "Failed to upload product images")
+		return
+	}
+	respondGallery(c, 201, "Product images uploaded", images)
+}
+
+// @Summary Reorder product images
+// @Description Set the display order of a product gallery; image_ids must list every image once (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param image_ids formData string true "Image IDs in the new order, comma separated"
+// @Success 200 {object} models.EnvelopeV2{data=[]models.ProductImageV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/images/order [put]
+// @DeprecatedRouter /v1/admin/products/{id}/images/order [put]
+// @DeprecatedRouter /admin/products/{id}/images/order [put]
+func (ctrl *ProductGalleryController) ReorderProductImages(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	imageIDs := []int{}
+	for _, value := range c.PostFormArray("image_ids") {
+		for _, part := range strings.Split(value, ",") {
+			imageID, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				c.JSON(400, models.ErrorResponse{
+					Success: false,
+					Message: msg(c, "image_ids must list every image of the product exactly once"),
+				})
+				return
+			}
+			imageIDs = append(imageIDs, imageID)
+		}
+	}
+
+	images, err := ctrl.products.ReorderImages(c.Request.Context(), actorFrom(c), id, imageIDs)
+	if err != nil {
+		respondServiceError(c, err, "Failed to reorder product images")
+		return
+	}
+	respondGallery(c, 200, "Product images reordered", images)
+}
+
+// @Summary Set primary product image
+// @Description Make an image the primary image; the product image_url follows it (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param imageId path int true "Image ID"
+// @Success 200 {object} models.EnvelopeV2{data=[]models.ProductImageV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/images/{imageId}/primary [patch]
+// @DeprecatedRouter /v1/admin/products/{id}/images/{imageId}/primary [patch]
+// @DeprecatedRouter /admin/products/{id}/images/{imageId}/primary [patch]
+func (ctrl *ProductGalleryController) SetPrimaryProductImage(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	imageID, _ := strconv.Atoi(c.Param("imageId"))
+
+	images, err := ctrl.products.SetPrimaryImage(c.Request.Context(), actorFrom(c), id, imageID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update product images")
+		return
+	}
+	respondGallery(c, 200, "Primary image updated", images)
+}
+
+// @Summary Delete product image
+// @Description Remove an image from a product gallery and from Cloudinary. The next image becomes primary when the primary image is deleted (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param imageId path int true "Image ID"
+// @Success 200 {object} models.EnvelopeV2{data=[]models.ProductImageV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/images/{imageId} [delete]
+// @DeprecatedRouter /v1/admin/products/{id}/images/{imageId} [delete]
+// @DeprecatedRouter /admin/products/{id}/images/{imageId} [delete]
+func (ctrl *ProductGalleryController) DeleteProductImage(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	imageID, _ := strconv.Atoi(c.Param("imageId"))
+
+	images, err := ctrl.products.DeleteImage(c.Request.Context(), actorFrom(c), id, imageID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to delete product image")
+		return
+	}
+	respondGallery(c, 200, "Product image deleted", images)
+}
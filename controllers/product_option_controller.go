@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProductOptionController manages the options, such as size or milk, a
+// product can be ordered with.
+type ProductOptionController struct {
+	products *services.ProductService
+}
+
+func NewProductOptionController(products *services.ProductService) *ProductOptionController {
+	return &ProductOptionController{products: products}
+}
+
+// respondOption answers with one entry of a product's option matrix.
+func respondOption(c *gin.Context, status int, message string, option models.ProductOption) {
+	if isV2(c) {
+		respondV2(c, status, msg(c, message), models.NewProductOptionV2(option))
+		return
+	}
+	c.JSON(status, gin.H{
+		"success": true,
+		"message": msg(c, message),
+		"data":    option,
+	})
+}
+
+// @Summary Get product options
+// @Description List the size, temperature and variant combinations a product is sold in, inactive ones included (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} models.EnvelopeV2{data=[]models.ProductOptionV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/options [get]
+// @DeprecatedRouter /v1/admin/products/{id}/options [get]
+// @DeprecatedRouter /admin/products/{id}/options [get]
+func (ctrl *ProductOptionController) GetProductOptions(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	options, err := ctrl.products.Options(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve product options")
+		return
+	}
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Product options retrieved"), models.NewProductOptionListV2(options))
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Product options retrieved"),
+		"data":    options,
+	})
+}
+
+// @Summary Create product option
+// @Description Offer a product in a size, temperature and variant combination for a surcharge; leave out the choices that do not apply (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param size_id formData int false "Size ID"
+// @Param temperature_id formData int false "Temperature ID"
+// @Param variant_id formData int false "Variant ID"
+// @Param price_adjustment formData int false "Surcharge on the product price"
+// @Param is_active formData bool false "Whether customers can order it, default true"
+// @Success 201 {object} models.EnvelopeV2{data=models.ProductOptionV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/options [post]
+// @DeprecatedRouter /v1/admin/products/{id}/options [post]
+// @DeprecatedRouter /admin/products/{id}/options [post]
+func (ctrl *ProductOptionController) CreateProductOption(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	in := services.OptionInput{
+		SizeID:        postFormInt(c, "size_id"),
+		TemperatureID: postFormInt(c, "temperature_id"),
+		VariantID:     postFormInt(c, "variant_id"),
+		IsActive:      postFormBool(c, "is_active"),
+	}
+	if price := postFormInt(c, "price_adjustment"); price != nil {
+		in.PriceAdjustment = *price
+	}
+
+	option, err := ctrl.products.CreateOption(c.Request.Context(), actorFrom(c), id, in)
+	if err != nil {
+		respondServiceError(c, err, "Failed to create product option")
+		return
+	}
+	respondOption(c, 201, "Product option created", option)
+}
+
+// @Summary Update product option
+// @Description Change the surcharge of an option or switch it on or off (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param optionId path int true "Option ID"
+// @Param price_adjustment formData int false "Surcharge on the product price"
+// @Param is_active formData bool false "Whether customers can order it"
+// @Success 200 {object} models.EnvelopeV2{data=models.ProductOptionV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/options/{optionId} [patch]
+// @DeprecatedRouter /v1/admin/products/{id}/options/{optionId} [patch]
+// @DeprecatedRouter /admin/products/{id}/options/{optionId} [patch]
+func (ctrl *ProductOptionController) UpdateProductOption(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	optionID, _ := strconv.Atoi(c.Param("optionId"))
+
+	option, err := ctrl.products.UpdateOption(c.Request.Context(), actorFrom(c), id, optionID, services.OptionUpdate{
+		PriceAdjustment: postFormInt(c, "price_adjustment"),
+		IsActive:        postFormBool(c, "is_active"),
+	})
+	if err != nil {
+		respondServiceError(c, err, "Failed to update product option")
+		return
+	}
+	respondOption(c, 200, "Product option updated", option)
+}
+
+// @Summary Delete product option
+// @Description Stop offering a product in a combination. Cart lines holding it can no longer check out (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param optionId path int true "Option ID"
+// @Success 200 {object} models.EnvelopeV2
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/options/{optionId} [delete]
+// @DeprecatedRouter /v1/admin/products/{id}/options/{optionId} [delete]
+// @DeprecatedRouter /admin/products/{id}/options/{optionId} [delete]
+func (ctrl *ProductOptionController) DeleteProductOption(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	optionID, _ := strconv.Atoi(c.Param("optionId"))
+
+	if err := ctrl.products.DeleteOption(c.Request.Context(), actorFrom(c), id, optionID); err != nil {
+		respondServiceError(c, err, "Failed to delete product option")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Product option deleted"),
+	})
+}
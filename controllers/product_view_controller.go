@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProductViewController shows signed-in customers the products they looked at
+// last.
+type ProductViewController struct {
+	products *services.ProductService
+}
+
+func NewProductViewController(products *services.ProductService) *ProductViewController {
+	return &ProductViewController{products: products}
+}
+
+// @Summary Get recently viewed products
+// @Description The products the signed-in user opened most recently, latest first, up to 20
+// @Tags Profile
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.EnvelopeV2{data=[]models.ProductV2}
+// @Router /v2/profile/recently-viewed [get]
+// @DeprecatedRouter /v1/profile/recently-viewed [get]
+// @DeprecatedRouter /profile/recently-viewed [get]
+func (ctrl *ProductViewController) GetRecentlyViewed(c *gin.Context) {
+	products, err := ctrl.products.RecentlyViewed(c.Request.Context(), c.GetInt("user_id"), requestLocale(c))
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve recently viewed products")
+		return
+	}
+	if !markFavorited(c, ctrl.products, products, "Failed to retrieve recently viewed products") {
+		return
+	}
+
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Recently viewed products retrieved"), models.NewProductListV2(products))
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Recently viewed products retrieved"),
+		"data":    products,
+	})
+}
@@ -2,8 +2,8 @@ package controllers
 
 import (
 	"coffee-shop/libs"
-	"coffee-shop/models"
-	"context"
+	"coffee-shop/repositories"
+	"coffee-shop/services"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,19 +11,20 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type ProfileController struct {
 	uploadFolder string
+	users        *services.UserService
 }
 
-func NewProfileController() *ProfileController {
+func NewProfileController(users *services.UserService) *ProfileController {
 	uploadFolder := "./uploads"
 	os.MkdirAll(uploadFolder, os.ModePerm)
 
 	return &ProfileController{
 		uploadFolder: uploadFolder,
+		users:        users,
 	}
 }
 
@@ -35,6 +36,7 @@ type ProfileResponse struct {
 	Phone     string    `json:"phone"`
 	Address   string    `json:"address"`
 	PhotoURL  string    `json:"photoUrl"`
+	Language  string    `json:"language"`
 	CreatedAt time.Time `json:"createdAt"`
 }
 
@@ -42,6 +44,7 @@ type UpdateProfileRequest struct {
 	FullName        string `form:"full_name" binding:"max=100"`
 	Phone           string `form:"phone" binding:"max=20"`
 	Address         string `form:"address" binding:"max=500"`
+	Language        string `form:"language" binding:"max=10"`
 	OldPassword     string `form:"old_password" binding:"max=100"`
 	NewPassword     string `form:"new_password" binding:"max=100"`
 	ConfirmPassword string `form:"confirm_password" binding:"max=100"`
@@ -58,56 +61,43 @@ type UpdateProfileResponse struct {
 // @Tags Profile
 // @Security BearerAuth
 // @Produce json
-// @Success 200 {object} models.Response
-// @Router /profile [get]
+// @Success 200 {object} models.EnvelopeV2
+// @Router /v2/profile [get]
+// @DeprecatedRouter /v1/profile [get]
+// @DeprecatedRouter /profile [get]
+// @Router /v2/admin/profile [get]
+// @DeprecatedRouter /v1/admin/profile [get]
+// @DeprecatedRouter /admin/profile [get]
 func (ctrl *ProfileController) GetProfile(c *gin.Context) {
 	userID := c.GetInt("user_id")
 	if userID == 0 {
 		c.JSON(401, gin.H{
 			"success": false,
-			"message": "Unauthorized",
+			"message": msg(c, "Unauthorized"),
 		})
 		return
 	}
 
-	var profile ProfileResponse
-
-	err := models.DB.QueryRow(context.Background(),
-		`SELECT 
-			u.id,
-			u.email,
-			u.role,
-			u.created_at,
-			COALESCE(p.full_name, '') as full_name,
-			COALESCE(p.phone, '') as phone,
-			COALESCE(p.address, '') as address,
-			COALESCE(p.photo_url, '') as photo_url
-		FROM users u 
-		LEFT JOIN user_profiles p ON u.id = p.user_id 
-		WHERE u.id = $1 AND u.deleted_at IS NULL`,
-		userID).Scan(
-		&profile.ID,
-		&profile.Email,
-		&profile.Role,
-		&profile.CreatedAt,
-		&profile.FullName,
-		&profile.Phone,
-		&profile.Address,
-		&profile.PhotoURL,
-	)
-
+	p, err := ctrl.users.Profile(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(404, gin.H{
-			"success": false,
-			"message": "Profile not found",
-		})
+		respondServiceError(c, err, "Profile not found")
 		return
 	}
 
 	c.JSON(200, gin.H{
 		"success": true,
-		"message": "Profile retrieved successfully",
-		"data":    profile,
+		"message": msg(c, "Profile retrieved successfully"),
+		"data": ProfileResponse{
+			ID:        p.ID,
+			Email:     p.Email,
+			Role:      p.Role,
+			FullName:  p.FullName,
+			Phone:     p.Phone,
+			Address:   p.Address,
+			PhotoURL:  p.PhotoURL,
+			Language:  p.Language,
+			CreatedAt: p.CreatedAt,
+		},
 	})
 }
 
@@ -120,18 +110,24 @@ func (ctrl *ProfileController) GetProfile(c *gin.Context) {
 // @Param full_name formData string false "Full Name"
 // @Param phone formData string false "Phone"
 // @Param address formData string false "Address"
+// @Param language formData string false "Preferred language (en, id)"
 // @Param photo formData file false "Profile photo"
 // @Param old_password formData string false "Old Password"
 // @Param new_password formData string false "New Password"
 // @Param confirm_password formData string false "Confirm New Password"
-// @Success 200 {object} models.Response
-// @Router /profile [patch]
+// @Success 200 {object} models.EnvelopeV2
+// @Router /v2/profile [patch]
+// @DeprecatedRouter /v1/profile [patch]
+// @DeprecatedRouter /profile [patch]
+// @Router /v2/admin/profile [patch]
+// @DeprecatedRouter /v1/admin/profile [patch]
+// @DeprecatedRouter /admin/profile [patch]
 func (ctrl *ProfileController) UpdateProfile(c *gin.Context) {
 	userID := c.GetInt("user_id")
 	if userID == 0 {
 		c.JSON(401, gin.H{
 			"success": false,
-			"message": "Unauthorized",
+			"message": msg(c, "Unauthorized"),
 		})
 		return
 	}
@@ -140,45 +136,50 @@ func (ctrl *ProfileController) UpdateProfile(c *gin.Context) {
 	if err := c.ShouldBind(&req); err != nil {
 		c.JSON(400, gin.H{
 			"success": false,
-			"message": "Invalid request data: " + err.Error(),
+			"message": msg(c, "Invalid request data: ") + err.Error(),
 		})
 		return
 	}
 
-	if req.FullName != "" && len(req.FullName) < 3 {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "Full name must be at least 3 characters",
-		})
-		return
-	}
-
-	if req.Phone != "" && !isValidPhone(req.Phone) {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "Invalid phone number format",
-		})
+	update, err := services.NormalizeProfile(repositories.ProfileUpdate{
+		FullName: req.FullName,
+		Phone:    req.Phone,
+		Address:  req.Address,
+		Language: req.Language,
+	})
+	if err != nil {
+		respondServiceError(c, err, "Failed to update profile")
 		return
 	}
 
 	if req.OldPassword != "" || req.NewPassword != "" || req.ConfirmPassword != "" {
-		if err := ctrl.handlePasswordChange(c, userID, req); err != nil {
+		if err := ctrl.users.ChangePassword(c.Request.Context(), userID, req.OldPassword, req.NewPassword, req.ConfirmPassword); err != nil {
+			respondServiceError(c, err, "Failed to update password")
 			return
 		}
 	}
 
-	photoURL, cloudinaryPublicID, shouldUpdatePhoto, err := ctrl.handlePhotoUpload(c, userID)
+	photoURL, cloudinaryPublicID, err := ctrl.handlePhotoUpload(c, userID)
 	if err != nil {
 		return
 	}
+	update.PhotoURL, update.PhotoID = photoURL, cloudinaryPublicID
 
-	if err := ctrl.updateProfileInDB(c, userID, req, photoURL, cloudinaryPublicID, shouldUpdatePhoto); err != nil {
+	if err := ctrl.users.UpdateProfile(c.Request.Context(), userID, update); err != nil {
+		respondServiceError(c, err, "Failed to update profile")
 		return
 	}
 
+	if update.Language != "" {
+		// Later requests use the saved preference even with the old token;
+		// the reply already does too.
+		c.Set("locale", update.Language)
+		c.Header("Content-Language", update.Language)
+	}
+
 	response := UpdateProfileResponse{
 		Success: true,
-		Message: "Profile updated successfully",
+		Message: msg(c, "Profile updated successfully"),
 	}
 
 	if photoURL != "" {
@@ -187,89 +188,27 @@ func (ctrl *ProfileController) UpdateProfile(c *gin.Context) {
 		}
 	}
 
-	c.JSON(200, response)
-}
-
-func (ctrl *ProfileController) handlePasswordChange(c *gin.Context, userID int, req UpdateProfileRequest) error {
-	if req.OldPassword == "" || req.NewPassword == "" || req.ConfirmPassword == "" {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "All password fields are required for password change",
-		})
-		return fmt.Errorf("missing password fields")
-	}
-
-	if !isValidPassword(req.NewPassword) {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "New password must be at least 6 characters",
-		})
-		return fmt.Errorf("invalid password length")
-	}
-
-	if req.NewPassword != req.ConfirmPassword {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "New password and confirm password do not match",
-		})
-		return fmt.Errorf("password mismatch")
-	}
-
-	if req.OldPassword == req.NewPassword {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "New password must be different from old password",
-		})
-		return fmt.Errorf("same old and new password")
-	}
-
-	var currentHash string
-	err := models.DB.QueryRow(context.Background(),
-		"SELECT password FROM users WHERE id=$1 AND deleted_at IS NULL", userID).Scan(&currentHash)
-	if err != nil {
-		c.JSON(500, gin.H{
-			"success": false,
-			"message": "Failed to verify password",
-		})
-		return err
-	}
-
-	if !verifyPassword(currentHash, req.OldPassword) {
-		c.JSON(400, gin.H{
-			"success": false,
-			"message": "Invalid old password",
-		})
-		return fmt.Errorf("invalid old password")
-	}
-
-	newHash, err := hashPassword(req.NewPassword)
-	if err != nil {
-		c.JSON(500, gin.H{
-			"success": false,
-			"message": "Failed to hash password",
-		})
-		return err
-	}
-
-	_, err = models.DB.Exec(context.Background(),
-		"UPDATE users SET password=$1, updated_at=$2 WHERE id=$3",
-		newHash, time.Now(), userID)
-	if err != nil {
-		c.JSON(500, gin.H{
-			"success": false,
-			"message": "Failed to update password",
-		})
-		return err
+	// Hand back a token that carries the new preference too, for clients
+	// that read it from the token.
+	if update.Language != "" {
+		token, err := generateToken(userID, c.GetString("user_email"), c.GetString("user_role"), update.Language, time.Hour)
+		if err == nil {
+			if response.Data == nil {
+				response.Data = map[string]interface{}{}
+			}
+			response.Data["language"] = update.Language
+			response.Data["token"] = token
+		}
 	}
 
-	return nil
+	c.JSON(200, response)
 }
 
-func (ctrl *ProfileController) handlePhotoUpload(c *gin.Context, userID int) (string, string, bool, error) {
+func (ctrl *ProfileController) handlePhotoUpload(c *gin.Context, userID int) (string, string, error) {
 	file, err := c.FormFile("photo")
 	if err != nil || file == nil {
 		fmt.Printf("[Controller] No photo file in request (err: %v)\n", err)
-		return "", "", false, nil
+		return "", "", nil
 	}
 
 	fmt.Printf("[Controller] Photo upload - user_id=%d, file=%s, size=%d\n",
@@ -279,9 +218,9 @@ func (ctrl *ProfileController) handlePhotoUpload(c *gin.Context, userID int) (st
 		fmt.Printf("[Controller] File too large: %d bytes\n", file.Size)
 		c.JSON(400, gin.H{
 			"success": false,
-			"message": "File terlalu besar (max 5MB)",
+			"message": msg(c, "File terlalu besar (max 5MB)"),
 		})
-		return "", "", false, fmt.Errorf("file too large")
+		return "", "", fmt.Errorf("file too large")
 	}
 
 	ext := strings.ToLower(filepath.Ext(file.Filename))
@@ -298,20 +237,13 @@ func (ctrl *ProfileController) handlePhotoUpload(c *gin.Context, userID int) (st
 		fmt.Printf("[Controller] Invalid file format: %s\n", ext)
 		c.JSON(400, gin.H{
 			"success": false,
-			"message": "Format image salah. Hanya " + strings.Join(allowedExts, ", "),
+			"message": msg(c, "Format image salah. Hanya ") + strings.Join(allowedExts, ", "),
 		})
-		return "", "", false, fmt.Errorf("invalid image format")
+		return "", "", fmt.Errorf("invalid image format")
 	}
 
-	var oldCloudinaryPublicID string
-	err = models.DB.QueryRow(context.Background(),
-		"SELECT cloudinary_public_id FROM user_profiles WHERE user_id=$1",
-		userID).Scan(&oldCloudinaryPublicID)
-
-	if err != nil {
-		fmt.Printf("[Controller] No existing profile found or error: %v\n", err)
-		oldCloudinaryPublicID = ""
-	} else {
+	oldCloudinaryPublicID := ctrl.users.PhotoID(c.Request.Context(), userID)
+	if oldCloudinaryPublicID != "" {
 		fmt.Printf("[Controller] Found old Cloudinary Public ID: %s\n", oldCloudinaryPublicID)
 	}
 
@@ -321,9 +253,9 @@ func (ctrl *ProfileController) handlePhotoUpload(c *gin.Context, userID int) (st
 		fmt.Printf("[Controller] Failed to save file locally: %v\n", err)
 		c.JSON(500, gin.H{
 			"success": false,
-			"message": "Failed to save uploaded file: " + err.Error(),
+			"message": msg(c, "Failed to save uploaded file: ") + err.Error(),
 		})
-		return "", "", false, err
+		return "", "", err
 	}
 
 	fmt.Printf("[Controller] File saved locally: %s\n", localPath)
@@ -332,9 +264,9 @@ func (ctrl *ProfileController) handlePhotoUpload(c *gin.Context, userID int) (st
 		fmt.Printf("[Controller] Local file doesn't exist after save!\n")
 		c.JSON(500, gin.H{
 			"success": false,
-			"message": "File was not saved correctly",
+			"message": msg(c, "File was not saved correctly"),
 		})
-		return "", "", false, fmt.Errorf("file not saved")
+		return "", "", fmt.Errorf("file not saved")
 	}
 
 	fmt.Printf("[Controller] Calling libs.UploadToCloudinary...\n")
@@ -349,18 +281,18 @@ func (ctrl *ProfileController) handlePhotoUpload(c *gin.Context, userID int) (st
 
 		c.JSON(500, gin.H{
 			"success": false,
-			"message": "Failed to upload photo to Cloudinary: " + err.Error(),
+			"message": msg(c, "Failed to upload photo to Cloudinary: ") + err.Error(),
 		})
-		return "", "", false, err
+		return "", "", err
 	}
 
 	if cloudinaryURL == "" {
 		fmt.Printf("[Controller] Cloudinary returned empty URL!\n")
 		c.JSON(500, gin.H{
 			"success": false,
-			"message": "Cloudinary returned empty URL",
+			"message": msg(c, "Cloudinary returned empty URL"),
 		})
-		return "", "", false, fmt.Errorf("cloudinary returned empty url")
+		return "", "", fmt.Errorf("cloudinary returned empty url")
 	}
 
 	fmt.Printf("[Controller] Cloudinary URL received: %s\n", cloudinaryURL)
@@ -380,7 +312,7 @@ func (ctrl *ProfileController) handlePhotoUpload(c *gin.Context, userID int) (st
 		}(oldCloudinaryPublicID)
 	}
 
-	return cloudinaryURL, cloudinaryPublicID, true, nil
+	return cloudinaryURL, cloudinaryPublicID, nil
 }
 
 func extractPublicIDFromURL(url string) string {
@@ -422,125 +354,3 @@ func extractPublicIDFromURL(url string) string {
 	fmt.Printf("[extractPublicID] Result: %s\n", publicIDWithExt)
 	return publicIDWithExt
 }
-
-func (ctrl *ProfileController) updateProfileInDB(c *gin.Context, userID int, req UpdateProfileRequest,
-	photoURL, cloudinaryPublicID string, shouldUpdatePhoto bool) error {
-
-	ctx := context.Background()
-	now := time.Now()
-
-	tx, err := models.DB.Begin(ctx)
-	if err != nil {
-		c.JSON(500, gin.H{
-			"success": false,
-			"message": "Failed to start transaction",
-		})
-		return err
-	}
-	defer tx.Rollback(ctx)
-
-	var profileExists bool
-	err = tx.QueryRow(ctx,
-		"SELECT EXISTS(SELECT 1 FROM user_profiles WHERE user_id=$1)", userID).
-		Scan(&profileExists)
-
-	if err != nil {
-		c.JSON(500, gin.H{
-			"success": false,
-			"message": "Failed to check profile existence",
-		})
-		return err
-	}
-
-	fmt.Printf("Profile exists: %v, shouldUpdatePhoto: %v\n", profileExists, shouldUpdatePhoto)
-	fmt.Printf("Photo URL to save: %s\n", photoURL)
-	fmt.Printf("Cloudinary Public ID to save: %s\n", cloudinaryPublicID)
-
-	var result pgconn.CommandTag
-
-	if profileExists {
-		if shouldUpdatePhoto && photoURL != "" {
-			fmt.Printf("Updating profile WITH photo for user_id=%d\n", userID)
-			query := `UPDATE user_profiles SET 
-				full_name = COALESCE(NULLIF($1, ''), full_name),
-				phone = COALESCE(NULLIF($2, ''), phone),
-				address = COALESCE(NULLIF($3, ''), address),
-				photo_url = $4,
-				cloudinary_public_id = $5,
-				updated_at = $6
-				WHERE user_id = $7`
-
-			result, err = tx.Exec(ctx, query,
-				req.FullName,
-				req.Phone,
-				req.Address,
-				photoURL,
-				cloudinaryPublicID,
-				now,
-				userID,
-			)
-		} else {
-			fmt.Printf("Updating profile WITHOUT photo for user_id=%d\n", userID)
-			query := `UPDATE user_profiles SET 
-				full_name = COALESCE(NULLIF($1, ''), full_name),
-				phone = COALESCE(NULLIF($2, ''), phone),
-				address = COALESCE(NULLIF($3, ''), address),
-				updated_at = $4
-				WHERE user_id = $5`
-
-			result, err = tx.Exec(ctx, query,
-				req.FullName,
-				req.Phone,
-				req.Address,
-				now,
-				userID,
-			)
-		}
-	} else {
-		fmt.Printf("Inserting NEW profile for user_id=%d\n", userID)
-		query := `INSERT INTO user_profiles (
-			user_id, full_name, phone, address, 
-			photo_url, cloudinary_public_id, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
-
-		result, err = tx.Exec(ctx, query,
-			userID,
-			req.FullName,
-			req.Phone,
-			req.Address,
-			photoURL,
-			cloudinaryPublicID,
-			now,
-			now,
-		)
-	}
-
-	if err != nil {
-		fmt.Printf("Database error: %v\n", err)
-		c.JSON(500, gin.H{
-			"success": false,
-			"message": "Failed to update profile: " + err.Error(),
-		})
-		return err
-	}
-
-	fmt.Printf("Database update successful: %v rows affected\n", result.RowsAffected())
-
-	if err := tx.Commit(ctx); err != nil {
-		fmt.Printf("Failed to commit transaction: %v\n", err)
-		c.JSON(500, gin.H{
-			"success": false,
-			"message": "Failed to commit profile update",
-		})
-		return err
-	}
-
-	var savedPhotoURL string
-	_ = models.DB.QueryRow(context.Background(),
-		"SELECT COALESCE(photo_url, '') FROM user_profiles WHERE user_id=$1",
-		userID).Scan(&savedPhotoURL)
-
-	fmt.Printf("Verified photo_url in database: %s\n", savedPhotoURL)
-
-	return nil
-}
@@ -2,47 +2,41 @@ package controllers
 
 import (
 	"coffee-shop/models"
-	"context"
+	"coffee-shop/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-type PromoController struct{}
+type PromoController struct {
+	promos *services.PromoService
+}
+
+func NewPromoController(promos *services.PromoService) *PromoController {
+	return &PromoController{promos: promos}
+}
 
+// @Summary Get promos
+// @Description List the active promo banners with their codes and colours, newest first
+// @Tags Promos
+// @Produce json
+// @Success 200 {object} models.EnvelopeV2{data=[]models.PromoV2}
+// @Router /v2/promos [get]
+// @DeprecatedRouter /v1/promos [get]
+// @DeprecatedRouter /promos [get]
 func (ctrl *PromoController) GetAllPromos(c *gin.Context) {
-	rows, err := models.DB.Query(context.Background(),
-		"SELECT id, title, description, code, bg_color, text_color FROM promos WHERE is_active=true ORDER BY created_at DESC")
+	promos, err := ctrl.promos.Active(c.Request.Context())
 	if err != nil {
-		c.JSON(500, gin.H{
-			"success": false,
-			"message": "Failed to get promos",
-		})
+		respondServiceError(c, err, "Failed to get promos")
 		return
 	}
-	defer rows.Close()
-
-	promos := []gin.H{}
-	for rows.Next() {
-		var id int
-		var title, description, code, bgColor, textColor string
 
-		if err := rows.Scan(&id, &title, &description, &code, &bgColor, &textColor); err != nil {
-			continue
-		}
-
-		promos = append(promos, gin.H{
-			"id":          id,
-			"title":       title,
-			"description": description,
-			"code":        code,
-			"bgColor":     bgColor,
-			"textColor":   textColor,
-		})
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Promos retrieved"), models.NewPromoListV2(promos))
+		return
 	}
-
 	c.JSON(200, gin.H{
 		"success": true,
-		"message": "Promos retrieved",
+		"message": msg(c, "Promos retrieved"),
 		"data":    promos,
 	})
 }
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromotionRuleController struct {
+	promotions *services.PromotionService
+}
+
+func NewPromotionRuleController(promotions *services.PromotionService) *PromotionRuleController {
+	return &PromotionRuleController{promotions: promotions}
+}
+
+// respondPromotionRule answers with one promotion rule.
+func respondPromotionRule(c *gin.Context, status int, message string, rule models.PromotionRule) {
+	if isV2(c) {
+		respondV2(c, status, msg(c, message), models.NewPromotionRuleV2(rule))
+		return
+	}
+	c.JSON(status, gin.H{
+		"success": true,
+		"message": msg(c, message),
+		"data":    rule,
+	})
+}
+
+// @Summary Get promotion rules
+// @Description List every promotion rule, newest first (Admin)
+// @Tags Admin - Promotions
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.EnvelopeV2{data=[]models.PromotionRuleV2}
+// @Router /v2/admin/promotion-rules [get]
+// @DeprecatedRouter /v1/admin/promotion-rules [get]
+// @DeprecatedRouter /admin/promotion-rules [get]
+func (ctrl *PromotionRuleController) GetPromotionRules(c *gin.Context) {
+	rules, err := ctrl.promotions.List(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve promotion rules")
+		return
+	}
+
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Promotion rules retrieved"), models.NewPromotionRuleListV2(rules))
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Promotion rules retrieved"),
+		"data":    rules,
+	})
+}
+
+// @Summary Get promotion rule
+// @Tags Admin - Promotions
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Promotion rule ID"
+// @Success 200 {object} models.EnvelopeV2{data=models.PromotionRuleV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/promotion-rules/{id} [get]
+// @DeprecatedRouter /v1/admin/promotion-rules/{id} [get]
+// @DeprecatedRouter /admin/promotion-rules/{id} [get]
+func (ctrl *PromotionRuleController) GetPromotionRule(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	rule, err := ctrl.promotions.Get(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve promotion rules")
+		return
+	}
+	respondPromotionRule(c, 200, "Promotion rule retrieved", rule)
+}
+
+// @Summary Create promotion rule
+// @Description Discount get_quantity units for every buy_quantity bought, of one product (buy_x_get_y) or across a category (mix_and_match). discount_percent defaults to 100, a free item (Admin)
+// @Tags Admin - Promotions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.PromotionRuleRequest true "Promotion rule"
+// @Success 201 {object} models.EnvelopeV2{data=models.PromotionRuleV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /v2/admin/promotion-rules [post]
+// @DeprecatedRouter /v1/admin/promotion-rules [post]
+// @DeprecatedRouter /admin/promotion-rules [post]
+func (ctrl *PromotionRuleController) CreatePromotionRule(c *gin.Context) {
+	in, ok := bindPromotionRule(c)
+	if !ok {
+		return
+	}
+
+	rule, err := ctrl.promotions.Create(c.Request.Context(), actorFrom(c), in)
+	if err != nil {
+		respondServiceError(c, err, "Failed to create promotion rule")
+		return
+	}
+	respondPromotionRule(c, 201, "Promotion rule created", rule)
+}
+
+// @Summary Update promotion rule
+// @Description Replace a promotion rule (Admin)
+// @Tags Admin - Promotions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Promotion rule ID"
+// @Param request body models.PromotionRuleRequest true "Promotion rule"
+// @Success 200 {object} models.EnvelopeV2{data=models.PromotionRuleV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/promotion-rules/{id} [put]
+// @DeprecatedRouter /v1/admin/promotion-rules/{id} [put]
+// @DeprecatedRouter /admin/promotion-rules/{id} [put]
+func (ctrl *PromotionRuleController) UpdatePromotionRule(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	in, ok := bindPromotionRule(c)
+	if !ok {
+		return
+	}
+
+	rule, err := ctrl.promotions.Update(c.Request.Context(), actorFrom(c), id, in)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update promotion rule")
+		return
+	}
+	respondPromotionRule(c, 200, "Promotion rule updated", rule)
+}
+
+// @Summary Delete promotion rule
+// @Description Remove a promotion rule; orders it discounted keep their discount (Admin)
+// @Tags Admin - Promotions
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Promotion rule ID"
+// @Success 200 {object} models.EnvelopeV2
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/promotion-rules/{id} [delete]
+// @DeprecatedRouter /v1/admin/promotion-rules/{id} [delete]
+// @DeprecatedRouter /admin/promotion-rules/{id} [delete]
+func (ctrl *PromotionRuleController) DeletePromotionRule(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	if err := ctrl.promotions.Delete(c.Request.Context(), actorFrom(c), id); err != nil {
+		respondServiceError(c, err, "Failed to delete promotion rule")
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Promotion rule deleted"),
+	})
+}
+
+func bindPromotionRule(c *gin.Context) (services.PromotionInput, bool) {
+	var req models.PromotionRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid request data: ") + err.Error()})
+		return services.PromotionInput{}, false
+	}
+
+	in := services.PromotionInput{
+		Name:            req.Name,
+		Type:            req.Type,
+		ProductID:       req.ProductID,
+		CategoryID:      req.CategoryID,
+		BuyQuantity:     req.BuyQuantity,
+		GetQuantity:     req.GetQuantity,
+		DiscountPercent: 100,
+		StartsAt:        req.StartsAt,
+		EndsAt:          req.EndsAt,
+		IsActive:        req.IsActive == nil || *req.IsActive,
+	}
+	if req.DiscountPercent != nil {
+		in.DiscountPercent = *req.DiscountPercent
+	}
+	return in, true
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecommendationController manages the recommendations admins curate for a
+// product.
+type RecommendationController struct {
+	products *services.ProductService
+}
+
+func NewRecommendationController(products *services.ProductService) *RecommendationController {
+	return &RecommendationController{products: products}
+}
+
+// respondRecommendation answers with one curated recommendation.
+func respondRecommendation(c *gin.Context, status int, message string, rec models.ProductRecommendation) {
+	if isV2(c) {
+		respondV2(c, status, msg(c, message), models.ProductRecommendationV2(rec))
+		return
+	}
+	c.JSON(status, gin.H{
+		"success": true,
+		"message": msg(c, message),
+		"data":    rec,
+	})
+}
+
+// @Summary Get curated recommendations
+// @Description List the products the shop recommends with a product, inactive ones included, highest priority first (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} models.EnvelopeV2{data=[]models.ProductRecommendationV2}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/recommendations [get]
+// @DeprecatedRouter /v1/admin/products/{id}/recommendations [get]
+// @DeprecatedRouter /admin/products/{id}/recommendations [get]
+func (ctrl *RecommendationController) GetCuratedRecommendations(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	recommendations, err := ctrl.products.ProductRecommendations(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve recommendations")
+		return
+	}
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Recommendations retrieved"), models.NewProductRecommendationListV2(recommendations))
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Recommendations retrieved"),
+		"data":    recommendations,
+	})
+}
+
+// @Summary Create recommendation
+// @Description Recommend another product with a product as an upsell, cross-sell or pairing. Higher priorities are shown first (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param recommended_product_id formData int true "Product to recommend"
+// @Param recommendation_type formData string true "upsell, cross_sell or pairing"
+// @Param priority formData int false "Higher is shown first, default 0"
+// @Param is_active formData bool false "Whether it is shown, default true"
+// @Success 201 {object} models.EnvelopeV2{data=models.ProductRecommendationV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/recommendations [post]
+// @DeprecatedRouter /v1/admin/products/{id}/recommendations [post]
+// @DeprecatedRouter /admin/products/{id}/recommendations [post]
+func (ctrl *RecommendationController) CreateRecommendation(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	recommendedID, _ := strconv.Atoi(c.PostForm("recommended_product_id"))
+	priority, _ := strconv.Atoi(c.PostForm("priority"))
+
+	rec, err := ctrl.products.CreateRecommendation(c.Request.Context(), actorFrom(c), id, services.RecommendationInput{
+		RecommendedProductID: recommendedID,
+		Type:                 c.PostForm("recommendation_type"),
+		Priority:             priority,
+		IsActive:             postFormBool(c, "is_active"),
+	})
+	if err != nil {
+		respondServiceError(c, err, "Failed to create recommendation")
+		return
+	}
+	respondRecommendation(c, 201, "Recommendation created", rec)
+}
+
+// @Summary Update recommendation
+// @Description Change the type or priority of a recommendation, or switch it on or off (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param recommendationId path int true "Recommendation ID"
+// @Param recommendation_type formData string false "upsell, cross_sell or pairing"
+// @Param priority formData int false "Higher is shown first"
+// @Param is_active formData bool false "Whether it is shown"
+// @Success 200 {object} models.EnvelopeV2{data=models.ProductRecommendationV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/recommendations/{recommendationId} [patch]
+// @DeprecatedRouter /v1/admin/products/{id}/recommendations/{recommendationId} [patch]
+// @DeprecatedRouter /admin/products/{id}/recommendations/{recommendationId} [patch]
+func (ctrl *RecommendationController) UpdateRecommendation(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	recommendationID, _ := strconv.Atoi(c.Param("recommendationId"))
+
+	in := services.RecommendationUpdate{
+		Priority: postFormInt(c, "priority"),
+		IsActive: postFormBool(c, "is_active"),
+	}
+	if recommendationType, ok := c.GetPostForm("recommendation_type"); ok {
+		in.Type = &recommendationType
+	}
+
+	rec, err := ctrl.products.UpdateRecommendation(c.Request.Context(), actorFrom(c), id, recommendationID, in)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update recommendation")
+		return
+	}
+	respondRecommendation(c, 200, "Recommendation updated", rec)
+}
+
+// @Summary Delete recommendation
+// @Description Stop recommending a product with a product (Admin)
+// @Tags Admin - Products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param recommendationId path int true "Recommendation ID"
+// @Success 200 {object} models.EnvelopeV2
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/products/{id}/recommendations/{recommendationId} [delete]
+// @DeprecatedRouter /v1/admin/products/{id}/recommendations/{recommendationId} [delete]
+// @DeprecatedRouter /admin/products/{id}/recommendations/{recommendationId} [delete]
+func (ctrl *RecommendationController) DeleteRecommendation(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	recommendationID, _ := strconv.Atoi(c.Param("recommendationId"))
+
+	if err := ctrl.products.DeleteRecommendation(c.Request.Context(), actorFrom(c), id, recommendationID); err != nil {
+		respondServiceError(c, err, "Failed to delete recommendation")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Recommendation deleted"),
+	})
+}
@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"coffee-shop/cache"
+	"coffee-shop/models"
+	"coffee-shop/services"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cacheable is a response serveCached can cache and validate.
+type cacheable struct {
+	body interface{}
+	// modified is when the newest resource in body last changed.
+	modified time.Time
+	// ttl is how long body may be cached.
+	ttl time.Duration
+	// items is how many items a list in body found in all, for handlers
+	// that act on empty results.
+	items int
+}
+
+// cachedResponse is a response with its validators, as serveCached caches
+// it.
+type cachedResponse struct {
+	ETag         string          `json:"etag"`
+	LastModified time.Time       `json:"last_modified"`
+	Body         json.RawMessage `json:"body"`
+	Items        int             `json:"items,omitempty"`
+}
+
+// serveCached answers with the response build makes, as JSON with an ETag,
+// Last-Modified and the route's Cache-Control policy, or 304 Not Modified
+// when the client's copy is still current. Anonymous requests share the
+// response cached at key in ns, and concurrent misses for the same key wait
+// for one build. Signed-in users get responses personalised for them, so
+// theirs are always built and validated by ETag alone: favouriting a product
+// changes their copy without moving its Last-Modified. On failure the error
+// is answered with fallback as for respondServiceError and ok is false;
+// otherwise items is the count build gave the response.
+func serveCached(c *gin.Context, ns *cache.Namespace, key, fallback string, build func() (cacheable, error)) (items int, ok bool) {
+	ctx := c.Request.Context()
+
+	var response cachedResponse
+	var err error
+	personal := c.GetInt("user_id") != 0
+	if personal {
+		response, err = buildResponse(ctx, ns, build)
+	} else {
+		response, err = fetchResponse(ctx, ns, key, build)
+	}
+	if err != nil {
+		respondServiceError(c, err, fallback)
+		return 0, false
+	}
+
+	if policy := c.GetString("cache_control"); policy != "" {
+		c.Header("Cache-Control", policy)
+	}
+	c.Header("ETag", response.ETag)
+	if personal {
+		response.LastModified = time.Time{}
+	} else {
+		c.Header("Last-Modified", response.LastModified.Format(http.TimeFormat))
+	}
+	if notModified(c.Request, response) {
+		c.Status(http.StatusNotModified)
+		return response.Items, true
+	}
+	c.Data(200, "application/json", response.Body)
+	return response.Items, true
+}
+
+func fetchResponse(ctx context.Context, ns *cache.Namespace, key string, build func() (cacheable, error)) (cachedResponse, error) {
+	cached, err := ns.Fetch(ctx, key, func() (string, time.Duration, error) {
+		fresh, err := build()
+		if err != nil {
+			return "", 0, err
+		}
+		response, err := newCachedResponse(ctx, ns, fresh)
+		if err != nil {
+			return "", 0, err
+		}
+		data, err := json.Marshal(response)
+		if err != nil {
+			return "", 0, err
+		}
+		return string(data), fresh.ttl, nil
+	})
+	if err != nil {
+		return cachedResponse{}, err
+	}
+
+	var response cachedResponse
+	if err := json.Unmarshal([]byte(cached), &response); err != nil || response.ETag == "" {
+		// Cached in an older format; it expires on its own.
+		log.Printf("Ignoring unreadable cached response %s: %v", key, err)
+		return buildResponse(ctx, ns, build)
+	}
+	return response, nil
+}
+
+func buildResponse(ctx context.Context, ns *cache.Namespace, build func() (cacheable, error)) (cachedResponse, error) {
+	fresh, err := build()
+	if err != nil {
+		return cachedResponse{}, err
+	}
+	return newCachedResponse(ctx, ns, fresh)
+}
+
+// newCachedResponse encodes fresh with a strong ETag hashed from the body.
+// It is last modified when its newest resource was, or when something in ns
+// last changed if that is later: deleting a product changes a list without
+// changing any product still in it.
+func newCachedResponse(ctx context.Context, ns *cache.Namespace, fresh cacheable) (cachedResponse, error) {
+	body, err := json.Marshal(fresh.body)
+	if err != nil {
+		return cachedResponse{}, err
+	}
+	sum := sha256.Sum256(body)
+
+	modified := ns.Modified(ctx)
+	if fresh.modified.After(modified) {
+		modified = fresh.modified.Truncate(time.Second)
+	}
+	return cachedResponse{
+		ETag:         `"` + hex.EncodeToString(sum[:16]) + `"`,
+		LastModified: modified.UTC(),
+		Body:         body,
+		Items:        fresh.items,
+	}, nil
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when there is
+// no If-None-Match, as RFC 9110 orders them. A response without a
+// LastModified is only matched by its ETag.
+func notModified(r *http.Request, response cachedResponse) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == response.ETag {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !response.LastModified.IsZero() && !response.LastModified.After(since)
+}
+
+// productsModified returns when products last changed: the newest update
+// of one, or a flash sale starting or ending since, which reprices them
+// without updating them.
+func productsModified(ctx context.Context, service *services.ProductService, products []models.Product) time.Time {
+	modified := latestUpdate(products)
+	if changed := service.PricesChangedAt(ctx); changed.After(modified) {
+		modified = changed
+	}
+	return modified
+}
+
+// latestUpdate returns when the most recently updated product changed.
+func latestUpdate(products []models.Product) time.Time {
+	var latest time.Time
+	for _, p := range products {
+		if p.UpdatedAt.After(latest) {
+			latest = p.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// responseVariant keeps the cached v1 and v2 payloads, and each locale's,
+// apart.
+func responseVariant(c *gin.Context) string {
+	if isV2(c) {
+		return "v2_" + requestLocale(c)
+	}
+	return "v1_" + requestLocale(c)
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchQueryController lets admins moderate the past searches suggested to
+// shoppers.
+type SearchQueryController struct {
+	searches *services.SearchService
+}
+
+func NewSearchQueryController(searches *services.SearchService) *SearchQueryController {
+	return &SearchQueryController{searches: searches}
+}
+
+// @Summary Get recorded searches
+// @Description List the storefront searches that found products, most searched first; only allowed ones are suggested (Admin)
+// @Tags Admin - Search
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "pending (default), allowed, blocked or all"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} models.ListEnvelopeV2{data=[]models.SearchQueryV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /v2/admin/search-queries [get]
+// @DeprecatedRouter /v1/admin/search-queries [get]
+// @DeprecatedRouter /admin/search-queries [get]
+func (ctrl *SearchQueryController) GetSearchQueries(c *gin.Context) {
+	page, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+	status := c.DefaultQuery("status", models.SearchQueryPending)
+	if status == "all" {
+		status = ""
+	}
+
+	queries, total, err := ctrl.searches.Queries(c.Request.Context(), status, page)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve search queries")
+		return
+	}
+
+	response := pagination.Response(c, msg(c, "Search queries retrieved"), queries, page, total, nil)
+	if isV2(c) {
+		c.JSON(200, models.NewListEnvelopeV2(response, models.NewSearchQueryListV2(queries)))
+		return
+	}
+	c.JSON(200, response)
+}
+
+// @Summary Moderate a recorded search
+// @Description Allow a search to be suggested to shoppers once it is popular, or block it (Admin)
+// @Tags Admin - Search
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Search query ID"
+// @Param moderation body models.SearchQueryModerationRequest true "status: allowed or blocked"
+// @Success 200 {object} models.EnvelopeV2{data=models.SearchQueryV2}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /v2/admin/search-queries/{id} [patch]
+// @DeprecatedRouter /v1/admin/search-queries/{id} [patch]
+// @DeprecatedRouter /admin/search-queries/{id} [patch]
+func (ctrl *SearchQueryController) ModerateSearchQuery(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var req models.SearchQueryModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid request data: ") + err.Error()})
+		return
+	}
+
+	query, err := ctrl.searches.ModerateQuery(c.Request.Context(), actorFrom(c), id, req.Status)
+	if err != nil {
+		respondServiceError(c, err, "Failed to moderate search query")
+		return
+	}
+	if isV2(c) {
+		respondV2(c, 200, msg(c, "Search query moderated"), models.SearchQueryV2(query))
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Search query moderated"),
+		"data":    query,
+	})
+}
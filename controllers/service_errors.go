@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"coffee-shop/services"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondServiceError writes err as a JSON error response. A services.Error
+// carries its own status and message; anything else is logged and reported
+// as a 500 with the fallback message.
+func respondServiceError(c *gin.Context, err error, fallback string) {
+	var serviceErr *services.Error
+	if !errors.As(err, &serviceErr) {
+		log.Printf("%s: %v", fallback, err)
+		c.JSON(500, gin.H{"success": false, "message": msg(c, fallback)})
+		return
+	}
+
+	if serviceErr.Err != nil {
+		log.Printf("%s: %v", serviceErr.Message, serviceErr.Err)
+	}
+
+	message := msg(c, serviceErr.Message)
+	if len(serviceErr.Args) > 0 {
+		message = fmt.Sprintf(message, serviceErr.Args...)
+	}
+	c.JSON(serviceErr.Status, gin.H{"success": false, "message": message})
+}
+
+// actorFrom identifies the authenticated user for the audit log.
+func actorFrom(c *gin.Context) services.Actor {
+	actorEmail, _ := c.Get("user_email")
+	email, _ := actorEmail.(string)
+
+	return services.Actor{ID: c.GetInt("user_id"), Email: email, IP: c.ClientIP()}
+}
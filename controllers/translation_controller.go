@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"coffee-shop/i18n"
+	"coffee-shop/models"
+	"coffee-shop/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TranslationController struct {
+	translations *services.TranslationService
+}
+
+func NewTranslationController(translations *services.TranslationService) *TranslationController {
+	return &TranslationController{translations: translations}
+}
+
+// translationTarget validates the :id and :locale path params shared by the
+// translation endpoints.
+func translationTarget(c *gin.Context) (int, string, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid ID")})
+		return 0, "", false
+	}
+
+	locale := c.Param("locale")
+	if locale != "" {
+		if locale = i18n.Normalize(locale); locale == "" {
+			c.JSON(400, gin.H{"success": false, "message": msg(c, "Unsupported language")})
+			return 0, "", false
+		}
+	}
+	return id, locale, true
+}
+
+// @Summary List product translations
+// @Tags Admin - Translations
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} models.EnvelopeV2
+// @Router /v2/admin/products/{id}/translations [get]
+// @DeprecatedRouter /v1/admin/products/{id}/translations [get]
+// @DeprecatedRouter /admin/products/{id}/translations [get]
+func (ctrl *TranslationController) GetProductTranslations(c *gin.Context) {
+	id, _, ok := translationTarget(c)
+	if !ok {
+		return
+	}
+
+	translations, err := ctrl.translations.ProductTranslations(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve translations")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Translations retrieved successfully"),
+		"data":    translations,
+	})
+}
+
+// @Summary Create or replace a product translation
+// @Description Empty fields fall back to the product's base name/description
+// @Tags Admin - Translations
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param locale path string true "Locale (en, id)"
+// @Param name formData string false "Translated name"
+// @Param description formData string false "Translated description"
+// @Success 200 {object} models.EnvelopeV2
+// @Router /v2/admin/products/{id}/translations/{locale} [put]
+// @DeprecatedRouter /v1/admin/products/{id}/translations/{locale} [put]
+// @DeprecatedRouter /admin/products/{id}/translations/{locale} [put]
+func (ctrl *TranslationController) UpsertProductTranslation(c *gin.Context) {
+	id, locale, ok := translationTarget(c)
+	if !ok {
+		return
+	}
+
+	var req models.TranslationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid request data: ") + err.Error()})
+		return
+	}
+	t := models.ProductTranslation{
+		ProductID:   id,
+		Locale:      locale,
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+	}
+
+	if err := ctrl.translations.SaveProductTranslation(c.Request.Context(), actorFrom(c), t); err != nil {
+		respondServiceError(c, err, "Failed to save translation")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Translation saved successfully"),
+		"data": gin.H{
+			"productId":   id,
+			"locale":      locale,
+			"name":        t.Name,
+			"description": t.Description,
+		},
+	})
+}
+
+// @Summary Delete a product translation
+// @Tags Admin - Translations
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param locale path string true "Locale (en, id)"
+// @Success 200 {object} models.EnvelopeV2
+// @Router /v2/admin/products/{id}/translations/{locale} [delete]
+// @DeprecatedRouter /v1/admin/products/{id}/translations/{locale} [delete]
+// @DeprecatedRouter /admin/products/{id}/translations/{locale} [delete]
+func (ctrl *TranslationController) DeleteProductTranslation(c *gin.Context) {
+	id, locale, ok := translationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.translations.DeleteProductTranslation(c.Request.Context(), actorFrom(c), id, locale); err != nil {
+		respondServiceError(c, err, "Failed to delete translation")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Translation deleted successfully"),
+	})
+}
+
+// @Summary List category translations
+// @Tags Admin - Translations
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Category ID"
+// @Success 200 {object} models.EnvelopeV2
+// @Router /v2/admin/categories/{id}/translations [get]
+// @DeprecatedRouter /v1/admin/categories/{id}/translations [get]
+// @DeprecatedRouter /admin/categories/{id}/translations [get]
+func (ctrl *TranslationController) GetCategoryTranslations(c *gin.Context) {
+	id, _, ok := translationTarget(c)
+	if !ok {
+		return
+	}
+
+	translations, err := ctrl.translations.CategoryTranslations(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err, "Failed to retrieve translations")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Translations retrieved successfully"),
+		"data":    translations,
+	})
+}
+
+// @Summary Create or replace a category translation
+// @Tags Admin - Translations
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param locale path string true "Locale (en, id)"
+// @Param name formData string true "Translated name"
+// @Success 200 {object} models.EnvelopeV2
+// @Router /v2/admin/categories/{id}/translations/{locale} [put]
+// @DeprecatedRouter /v1/admin/categories/{id}/translations/{locale} [put]
+// @DeprecatedRouter /admin/categories/{id}/translations/{locale} [put]
+func (ctrl *TranslationController) UpsertCategoryTranslation(c *gin.Context) {
+	id, locale, ok := translationTarget(c)
+	if !ok {
+		return
+	}
+
+	var req models.TranslationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "message": msg(c, "Invalid request data: ") + err.Error()})
+		return
+	}
+	t := models.CategoryTranslation{CategoryID: id, Locale: locale, Name: strings.TrimSpace(req.Name)}
+
+	if err := ctrl.translations.SaveCategoryTranslation(c.Request.Context(), actorFrom(c), t); err != nil {
+		respondServiceError(c, err, "Failed to save translation")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Translation saved successfully"),
+		"data": gin.H{
+			"categoryId": id,
+			"locale":     locale,
+			"name":       t.Name,
+		},
+	})
+}
+
+// @Summary Delete a category translation
+// @Tags Admin - Translations
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param locale path string true "Locale (en, id)"
+// @Success 200 {object} models.EnvelopeV2
+// @Router /v2/admin/categories/{id}/translations/{locale} [delete]
+// @DeprecatedRouter /v1/admin/categories/{id}/translations/{locale} [delete]
+// @DeprecatedRouter /admin/categories/{id}/translations/{locale} [delete]
+func (ctrl *TranslationController) DeleteCategoryTranslation(c *gin.Context) {
+	id, locale, ok := translationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.translations.DeleteCategoryTranslation(c.Request.Context(), actorFrom(c), id, locale); err != nil {
+		respondServiceError(c, err, "Failed to delete translation")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": msg(c, "Translation deleted successfully"),
+	})
+}
@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"coffee-shop/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// isV2 reports whether the request came in through the /v2 route group.
+// Unversioned and /v1 routes keep the legacy response shapes.
+func isV2(c *gin.Context) bool {
+	return c.GetInt("api_version") >= 2
+}
+
+func respondV2(c *gin.Context, status int, message string, data interface{}) {
+	c.JSON(status, models.EnvelopeV2{
+		Success: true,
+		Message: message,
+		Data:    data,
+	})
+}
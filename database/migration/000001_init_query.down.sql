@@ -0,0 +1,21 @@
+-- Reverses 000001_init_query: drop every table in reverse dependency order.
+DROP TABLE IF EXISTS product_recommendations;
+DROP TABLE IF EXISTS product_details;
+DROP TABLE IF EXISTS cart_items;
+DROP TABLE IF EXISTS product_variants;
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS order_status;
+DROP TABLE IF EXISTS product_temperatures;
+DROP TABLE IF EXISTS product_sizes;
+DROP TABLE IF EXISTS tax_rates;
+DROP TABLE IF EXISTS payment_methods;
+DROP TABLE IF EXISTS delivery_methods;
+DROP TABLE IF EXISTS promo_products;
+DROP TABLE IF EXISTS promos;
+DROP TABLE IF EXISTS product_reviews;
+DROP TABLE IF EXISTS product_images;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS user_profiles;
+DROP TABLE IF EXISTS users;
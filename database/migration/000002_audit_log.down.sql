@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trg_audit_log_append_only ON audit_log;
+
+DROP FUNCTION IF EXISTS audit_log_prevent_mutation();
+
+DROP TABLE IF EXISTS audit_log;
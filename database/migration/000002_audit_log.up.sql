@@ -0,0 +1,30 @@
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id INT,
+    actor_email VARCHAR(255),
+    action VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id INT NOT NULL,
+    before_data JSONB,
+    after_data JSONB,
+    changes JSONB,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_log_actor ON audit_log(actor_id);
+CREATE INDEX idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+
+-- Audit entries are append-only: reject any attempt to rewrite history
+CREATE OR REPLACE FUNCTION audit_log_prevent_mutation() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_audit_log_append_only
+BEFORE UPDATE OR DELETE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION audit_log_prevent_mutation();
+
+COMMENT ON TABLE audit_log IS 'Append-only trail of privileged (admin) data changes';
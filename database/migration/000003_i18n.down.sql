@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS category_translations;
+
+DROP TABLE IF EXISTS product_translations;
+
+ALTER TABLE user_profiles DROP COLUMN IF EXISTS language;
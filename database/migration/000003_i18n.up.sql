@@ -0,0 +1,24 @@
+-- Preferred language for API messages and emails; NULL means "use the request locale"
+ALTER TABLE user_profiles ADD COLUMN language VARCHAR(10);
+
+CREATE TABLE product_translations (
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    locale VARCHAR(10) NOT NULL,
+    name VARCHAR(255),
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (product_id, locale)
+);
+
+CREATE TABLE category_translations (
+    category_id INT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    locale VARCHAR(10) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (category_id, locale)
+);
+
+CREATE INDEX idx_product_translations_locale ON product_translations(locale);
+CREATE INDEX idx_category_translations_locale ON category_translations(locale);
@@ -0,0 +1,6 @@
+ALTER TABLE promos DROP COLUMN IF EXISTS text_color;
+ALTER TABLE promos DROP COLUMN IF EXISTS bg_color;
+
+DROP INDEX IF EXISTS idx_users_active;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
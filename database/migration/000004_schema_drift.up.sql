@@ -0,0 +1,10 @@
+-- Columns the application already reads but 000001 never created.
+
+-- Soft-deleted accounts are hidden from profile lookups and password changes.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_users_active ON users(id) WHERE deleted_at IS NULL;
+
+-- Banner colours for the promo list; the default is the brand orange used in emails.
+ALTER TABLE promos ADD COLUMN bg_color VARCHAR(20) NOT NULL DEFAULT '#f97316';
+ALTER TABLE promos ADD COLUMN text_color VARCHAR(20) NOT NULL DEFAULT '#ffffff';
@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_products_name_trgm;
+DROP INDEX IF EXISTS idx_products_search_vector;
+
+DROP TRIGGER IF EXISTS trg_category_translations_search ON category_translations;
+DROP TRIGGER IF EXISTS trg_categories_search ON categories;
+DROP TRIGGER IF EXISTS trg_product_translations_search ON product_translations;
+DROP TRIGGER IF EXISTS trg_products_search ON products;
+
+DROP FUNCTION IF EXISTS categories_refresh_search();
+DROP FUNCTION IF EXISTS product_translations_refresh_search();
+DROP FUNCTION IF EXISTS products_refresh_search();
+DROP FUNCTION IF EXISTS product_search_document(INT);
+
+ALTER TABLE products DROP COLUMN IF EXISTS search_vector;
+
+-- pg_trgm is left installed: other objects may have come to depend on it.
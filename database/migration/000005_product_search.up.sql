@@ -0,0 +1,80 @@
+-- Full-text search over products with typo tolerance on the name.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE products ADD COLUMN search_vector tsvector;
+
+-- The search document weights the name (and its translations) highest, then
+-- the category, then the description. The 'simple' configuration is used
+-- because the catalogue mixes English and Indonesian, so no stemming applies.
+CREATE FUNCTION product_search_document(pid INT) RETURNS tsvector AS $$
+    SELECT
+        setweight(to_tsvector('simple', COALESCE(p.name, '') || ' ' || COALESCE(
+            (SELECT string_agg(t.name, ' ') FROM product_translations t WHERE t.product_id = p.id), '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(c.name, '') || ' ' || COALESCE(
+            (SELECT string_agg(ct.name, ' ') FROM category_translations ct WHERE ct.category_id = p.category_id), '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(p.description, '') || ' ' || COALESCE(
+            (SELECT string_agg(t.description, ' ') FROM product_translations t WHERE t.product_id = p.id), '')), 'C')
+    FROM products p
+    LEFT JOIN categories c ON c.id = p.category_id
+    WHERE p.id = pid
+$$ LANGUAGE sql STABLE;
+
+-- The refresh only sets search_vector, which is not in any trigger's column
+-- list, so it does not fire the triggers again.
+CREATE FUNCTION products_refresh_search() RETURNS trigger AS $$
+BEGIN
+    UPDATE products SET search_vector = product_search_document(NEW.id) WHERE id = NEW.id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_products_search
+AFTER INSERT OR UPDATE OF name, description, category_id ON products
+FOR EACH ROW EXECUTE FUNCTION products_refresh_search();
+
+CREATE FUNCTION product_translations_refresh_search() RETURNS trigger AS $$
+DECLARE
+    pid INT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        pid := OLD.product_id;
+    ELSE
+        pid := NEW.product_id;
+    END IF;
+    UPDATE products SET search_vector = product_search_document(id) WHERE id = pid;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_product_translations_search
+AFTER INSERT OR UPDATE OR DELETE ON product_translations
+FOR EACH ROW EXECUTE FUNCTION product_translations_refresh_search();
+
+CREATE FUNCTION categories_refresh_search() RETURNS trigger AS $$
+DECLARE
+    cid INT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        cid := OLD.category_id;
+    ELSIF TG_TABLE_NAME = 'categories' THEN
+        cid := NEW.id;
+    ELSE
+        cid := NEW.category_id;
+    END IF;
+    UPDATE products SET search_vector = product_search_document(id) WHERE category_id = cid;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_categories_search
+AFTER UPDATE OF name ON categories
+FOR EACH ROW EXECUTE FUNCTION categories_refresh_search();
+
+CREATE TRIGGER trg_category_translations_search
+AFTER INSERT OR UPDATE OR DELETE ON category_translations
+FOR EACH ROW EXECUTE FUNCTION categories_refresh_search();
+
+UPDATE products SET search_vector = product_search_document(id);
+
+CREATE INDEX idx_products_search_vector ON products USING GIN (search_vector);
+CREATE INDEX idx_products_name_trgm ON products USING GIN (name gin_trgm_ops);
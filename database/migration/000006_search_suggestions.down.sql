@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_category_translations_name_trgm;
+DROP INDEX IF EXISTS idx_product_translations_name_trgm;
+DROP INDEX IF EXISTS idx_categories_name_trgm;
+
+DROP TABLE IF EXISTS search_queries;
@@ -0,0 +1,13 @@
+-- Normalized storefront searches, counted so suggestions can offer popular queries.
+CREATE TABLE search_queries (
+    query VARCHAR(100) PRIMARY KEY,
+    hits INT NOT NULL DEFAULT 1,
+    last_searched_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Trigram indexes serve both the prefix (ILIKE 'es%') and the typo (<%)
+-- matches behind GET /products/suggest.
+CREATE INDEX idx_search_queries_query_trgm ON search_queries USING GIN (query gin_trgm_ops);
+CREATE INDEX idx_categories_name_trgm ON categories USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_product_translations_name_trgm ON product_translations USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_category_translations_name_trgm ON category_translations USING GIN (name gin_trgm_ops);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_products_archived;
+
+ALTER TABLE products DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,5 @@
+-- Deleting a product archives it: the row stays so order history can still
+-- join it, but the storefront only lists products that were never archived.
+ALTER TABLE products ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_products_archived ON products(deleted_at DESC, id DESC) WHERE deleted_at IS NOT NULL;
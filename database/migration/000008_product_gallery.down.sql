@@ -0,0 +1,14 @@
+ALTER TABLE product_images DROP CONSTRAINT IF EXISTS uq_product_images_order;
+
+ALTER TABLE product_images
+ADD CONSTRAINT unique_product_image_position UNIQUE (product_id, display_order);
+-- Galleries grown past four images since are left as they are.
+ALTER TABLE product_images
+ADD CONSTRAINT check_image_position CHECK (display_order BETWEEN 1 AND 4) NOT VALID;
+
+DROP INDEX IF EXISTS idx_product_images_primary;
+
+ALTER TABLE product_images ALTER COLUMN is_primary DROP NOT NULL;
+ALTER TABLE product_images ALTER COLUMN is_primary DROP DEFAULT;
+
+ALTER TABLE product_images DROP COLUMN IF EXISTS cloudinary_id;
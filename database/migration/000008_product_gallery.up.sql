@@ -0,0 +1,60 @@
+-- Gallery images are uploaded to Cloudinary like the main product image, so
+-- they need the public ID to be deleted again.
+ALTER TABLE product_images ADD COLUMN cloudinary_id VARCHAR(255);
+
+UPDATE product_images SET is_primary = FALSE WHERE is_primary IS NULL;
+ALTER TABLE product_images ALTER COLUMN is_primary SET DEFAULT FALSE;
+ALTER TABLE product_images ALTER COLUMN is_primary SET NOT NULL;
+
+-- The single image stored on products becomes the first gallery image.
+INSERT INTO product_images (product_id, image_url, cloudinary_id, is_primary, display_order)
+SELECT p.id, p.image_url, p.cloudinary_id, TRUE, 1
+FROM products p
+WHERE COALESCE(p.image_url, '') <> ''
+  AND NOT EXISTS (SELECT 1 FROM product_images i WHERE i.product_id = p.id);
+
+-- The original position constraints are checked row by row, so a reorder
+-- could not swap two positions; the deferred constraint below replaces the
+-- unique one. The gallery size is left to the service, which must be able
+-- to reorder and trim galleries that already hold more than four images.
+ALTER TABLE product_images DROP CONSTRAINT unique_product_image_position;
+ALTER TABLE product_images DROP CONSTRAINT check_image_position;
+
+-- Existing galleries may skip positions, leave them empty and mark several
+-- primary images, which the indexes below reject. Number each gallery 1, 2,
+-- 3... in its current order...
+UPDATE product_images i SET display_order = ordered.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY product_id ORDER BY display_order NULLS LAST, id) AS position
+    FROM product_images
+) ordered
+WHERE ordered.id = i.id AND i.display_order IS DISTINCT FROM ordered.position;
+
+-- ...keep only the first of its primary images...
+UPDATE product_images i SET is_primary = FALSE
+WHERE i.is_primary
+  AND EXISTS (
+    SELECT 1 FROM product_images earlier
+    WHERE earlier.product_id = i.product_id AND earlier.is_primary
+      AND earlier.display_order < i.display_order);
+
+-- ...or mark the first image when none was.
+UPDATE product_images i SET is_primary = TRUE
+WHERE i.display_order = 1
+  AND NOT EXISTS (SELECT 1 FROM product_images p WHERE p.product_id = i.product_id AND p.is_primary);
+
+-- Gallery images that are the product's image share its public ID.
+UPDATE product_images i SET cloudinary_id = p.cloudinary_id
+FROM products p
+WHERE p.id = i.product_id AND p.image_url = i.image_url AND i.cloudinary_id IS NULL;
+
+-- products.image_url mirrors the primary image.
+UPDATE products p SET image_url = i.image_url, cloudinary_id = i.cloudinary_id
+FROM product_images i
+WHERE i.product_id = p.id AND i.is_primary AND p.image_url IS DISTINCT FROM i.image_url;
+
+CREATE UNIQUE INDEX idx_product_images_primary ON product_images(product_id) WHERE is_primary;
+
+-- Deferred so a reorder can swap positions inside one transaction.
+ALTER TABLE product_images
+ADD CONSTRAINT uq_product_images_order UNIQUE (product_id, display_order) DEFERRABLE INITIALLY DEFERRED;
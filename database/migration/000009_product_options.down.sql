@@ -0,0 +1,5 @@
+-- Matrix rows are left in place; before 000009 nothing read them.
+ALTER TABLE product_details ALTER COLUMN is_active DROP NOT NULL;
+ALTER TABLE product_details ALTER COLUMN price_adjustment DROP NOT NULL;
+
+DROP INDEX IF EXISTS idx_product_details_option;
@@ -0,0 +1,54 @@
+-- product_details becomes the option matrix: the size, temperature and
+-- variant combinations each product is sold in, with the surcharge of each.
+
+-- The original UNIQUE constraint never fires when an option is NULL, so
+-- duplicates are dropped before a unique index that treats NULLs as equal.
+DELETE FROM product_recommendations r
+USING product_details d, product_details keep
+WHERE d.id IN (r.product_detail_id, r.recommended_product_detail_id)
+  AND keep.product_id = d.product_id
+  AND keep.id < d.id
+  AND keep.size_id IS NOT DISTINCT FROM d.size_id
+  AND keep.temperature_id IS NOT DISTINCT FROM d.temperature_id
+  AND keep.variant_id IS NOT DISTINCT FROM d.variant_id;
+
+DELETE FROM product_details d
+USING product_details keep
+WHERE keep.product_id = d.product_id
+  AND keep.id < d.id
+  AND keep.size_id IS NOT DISTINCT FROM d.size_id
+  AND keep.temperature_id IS NOT DISTINCT FROM d.temperature_id
+  AND keep.variant_id IS NOT DISTINCT FROM d.variant_id;
+
+CREATE UNIQUE INDEX idx_product_details_option ON product_details
+    (product_id, COALESCE(size_id, 0), COALESCE(temperature_id, 0), COALESCE(variant_id, 0));
+
+UPDATE product_details SET price_adjustment = 0 WHERE price_adjustment IS NULL;
+UPDATE product_details SET is_active = TRUE WHERE is_active IS NULL;
+ALTER TABLE product_details ALTER COLUMN price_adjustment SET NOT NULL;
+ALTER TABLE product_details ALTER COLUMN is_active SET NOT NULL;
+
+-- Until now every product was sold plain or in any active size and
+-- temperature at the global surcharges. Products without a matrix keep
+-- those combinations, so admins only have to remove what does not apply.
+INSERT INTO product_details (product_id, size_id, temperature_id, price_adjustment, is_active)
+SELECT p.id, NULL, NULL, 0, TRUE
+FROM products p
+WHERE NOT EXISTS (SELECT 1 FROM product_details d WHERE d.product_id = p.id)
+UNION ALL
+SELECT p.id, s.id, t.id, COALESCE(s.price_adjustment, 0) + COALESCE(t.price, 0), TRUE
+FROM products p
+CROSS JOIN product_sizes s
+CROSS JOIN product_temperatures t
+WHERE s.is_active AND t.is_active
+  AND NOT EXISTS (SELECT 1 FROM product_details d WHERE d.product_id = p.id);
+
+-- Combinations already sitting in carts stay purchasable at today's price.
+INSERT INTO product_details (product_id, size_id, temperature_id, variant_id, price_adjustment, is_active)
+SELECT DISTINCT ci.product_id, ci.size_id, ci.temperature_id, ci.variant_id,
+    COALESCE(s.price_adjustment, 0) + COALESCE(t.price, 0) + COALESCE(v.price, 0), TRUE
+FROM cart_items ci
+LEFT JOIN product_sizes s ON s.id = ci.size_id
+LEFT JOIN product_temperatures t ON t.id = ci.temperature_id
+LEFT JOIN product_variants v ON v.id = ci.variant_id
+ON CONFLICT DO NOTHING;
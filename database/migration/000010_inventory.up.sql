@@ -0,0 +1,28 @@
+-- Every change to products.stock is recorded in a ledger with its reason
+-- and the user who made it. quantity is the signed change and stock_after
+-- the level it left, so the ledger can be read without replaying it.
+CREATE TABLE stock_movements (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    movement_type VARCHAR(20) NOT NULL
+        CHECK (movement_type IN ('sale', 'restock', 'adjustment', 'cancellation', 'waste')),
+    quantity INT NOT NULL CHECK (quantity <> 0),
+    stock_after INT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    order_id INT REFERENCES orders(id) ON DELETE SET NULL,
+    actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_stock_movements_product ON stock_movements(product_id, created_at DESC, id DESC);
+
+-- A product is low on stock once it drops below its threshold; 0 turns the
+-- alert off.
+ALTER TABLE products ADD COLUMN low_stock_threshold INT NOT NULL DEFAULT 10
+    CHECK (low_stock_threshold >= 0);
+
+-- Existing stock opens the ledger.
+INSERT INTO stock_movements (product_id, movement_type, quantity, stock_after, reason)
+SELECT id, 'adjustment', stock, stock, 'Opening balance'
+FROM products
+WHERE stock <> 0;
@@ -0,0 +1,6 @@
+-- An optional stock keeping unit identifies a product across imports, so a
+-- product can be renamed without creating a duplicate. Matching ignores
+-- case, like the import does.
+ALTER TABLE products ADD COLUMN sku VARCHAR(64);
+
+CREATE UNIQUE INDEX idx_products_sku ON products (LOWER(sku)) WHERE sku IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_order_items_flash_sale;
+ALTER TABLE order_items DROP COLUMN IF EXISTS flash_sale_id;
+DROP TABLE IF EXISTS flash_sale_items;
+DROP TABLE IF EXISTS flash_sales;
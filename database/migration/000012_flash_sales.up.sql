@@ -0,0 +1,34 @@
+-- A flash sale sells its products below their regular price between
+-- starts_at and ends_at. The schedule is stored with its time zone so the
+-- storefront countdown does not depend on the server's.
+CREATE TABLE flash_sales (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX idx_flash_sales_ends_at ON flash_sales(ends_at) WHERE is_active;
+
+-- Each product of a sale has either a fixed sale price or a percentage off,
+-- and optionally a cap on the units one customer may buy at that price.
+CREATE TABLE flash_sale_items (
+    flash_sale_id INT NOT NULL REFERENCES flash_sales(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sale_price INT CHECK (sale_price > 0),
+    discount_percent INT CHECK (discount_percent BETWEEN 1 AND 99),
+    max_per_customer INT CHECK (max_per_customer > 0),
+    PRIMARY KEY (flash_sale_id, product_id),
+    CHECK ((sale_price IS NULL) <> (discount_percent IS NULL))
+);
+
+CREATE INDEX idx_flash_sale_items_product ON flash_sale_items(product_id);
+
+-- Order lines remember the sale that priced them, to enforce the cap.
+ALTER TABLE order_items ADD COLUMN flash_sale_id INT REFERENCES flash_sales(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_order_items_flash_sale ON order_items(flash_sale_id, product_id) WHERE flash_sale_id IS NOT NULL;
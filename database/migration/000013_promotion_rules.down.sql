@@ -0,0 +1,5 @@
+ALTER TABLE order_items
+    DROP COLUMN IF EXISTS promotion_name,
+    DROP COLUMN IF EXISTS promotion_rule_id,
+    DROP COLUMN IF EXISTS discount_amount;
+DROP TABLE IF EXISTS promotion_rules;
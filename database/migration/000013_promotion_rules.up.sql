@@ -0,0 +1,30 @@
+-- A promotion rule gives get_quantity units free (or discount_percent off)
+-- for every buy_quantity units bought. buy_x_get_y counts the units of one
+-- product, mix_and_match counts any products of one category together.
+CREATE TABLE promotion_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('buy_x_get_y', 'mix_and_match')),
+    product_id INT REFERENCES products(id) ON DELETE CASCADE,
+    category_id INT REFERENCES categories(id) ON DELETE CASCADE,
+    buy_quantity INT NOT NULL CHECK (buy_quantity > 0),
+    get_quantity INT NOT NULL CHECK (get_quantity > 0),
+    discount_percent INT NOT NULL DEFAULT 100 CHECK (discount_percent BETWEEN 1 AND 100),
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((type = 'buy_x_get_y' AND product_id IS NOT NULL AND category_id IS NULL)
+        OR (type = 'mix_and_match' AND category_id IS NOT NULL AND product_id IS NULL)),
+    CHECK (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX idx_promotion_rules_active ON promotion_rules(id) WHERE is_active;
+
+-- Order lines keep the promotion discount they were given, so the order
+-- total can be explained after the rule changes or is deleted.
+ALTER TABLE order_items
+    ADD COLUMN discount_amount INT NOT NULL DEFAULT 0,
+    ADD COLUMN promotion_rule_id INT REFERENCES promotion_rules(id) ON DELETE SET NULL,
+    ADD COLUMN promotion_name VARCHAR(100);
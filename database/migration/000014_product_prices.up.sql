@@ -0,0 +1,25 @@
+-- Every price a product has had, and the changes scheduled for it. A change
+-- is pending until applied_at is set: the scheduler applies it once
+-- effective_at has passed, while a change made directly is applied at once.
+-- previous_price is the price it replaced, NULL for a product's first price.
+-- Every time is a TIMESTAMPTZ so schedules compare across time zones.
+CREATE TABLE product_prices (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    price INT NOT NULL CHECK (price > 0),
+    previous_price INT,
+    effective_at TIMESTAMPTZ NOT NULL,
+    applied_at TIMESTAMPTZ,
+    reason TEXT NOT NULL DEFAULT '',
+    actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_product_prices_history ON product_prices(product_id, applied_at DESC, id DESC) WHERE applied_at IS NOT NULL;
+CREATE INDEX idx_product_prices_pending ON product_prices(effective_at) WHERE applied_at IS NULL;
+CREATE UNIQUE INDEX idx_product_prices_pending_unique ON product_prices(product_id, effective_at) WHERE applied_at IS NULL;
+
+-- Current prices open the history.
+INSERT INTO product_prices (product_id, price, effective_at, applied_at, reason)
+SELECT id, price, NOW(), NOW(), 'Opening price'
+FROM products;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_favorites;
+
+ALTER TABLE products RENAME COLUMN is_featured TO is_favorite;
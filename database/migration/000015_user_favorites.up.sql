@@ -0,0 +1,13 @@
+-- Favourites belong to customers now; the shop's own pick becomes the
+-- featured flag.
+ALTER TABLE products RENAME COLUMN is_favorite TO is_featured;
+
+CREATE TABLE user_favorites (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, product_id)
+);
+
+CREATE INDEX idx_user_favorites_user ON user_favorites(user_id, created_at DESC, product_id DESC);
+CREATE INDEX idx_user_favorites_product ON user_favorites(product_id);
@@ -0,0 +1,17 @@
+ALTER TABLE products
+    DROP COLUMN IF EXISTS review_count,
+    DROP COLUMN IF EXISTS average_rating;
+
+DROP INDEX IF EXISTS idx_product_reviews_status;
+DROP INDEX IF EXISTS idx_product_reviews_approved;
+DROP INDEX IF EXISTS idx_product_reviews_user_product;
+
+ALTER TABLE product_reviews
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS replied_at,
+    DROP COLUMN IF EXISTS reply,
+    DROP COLUMN IF EXISTS status;
+
+-- The superseded duplicates become reviews again.
+INSERT INTO product_reviews SELECT * FROM product_reviews_superseded;
+DROP TABLE IF EXISTS product_reviews_superseded;
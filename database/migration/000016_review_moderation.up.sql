@@ -0,0 +1,46 @@
+-- Customers write their own reviews now, one per product, and an admin
+-- approves or hides each before the storefront shows it. Reviews written
+-- before moderation are approved; of several by the same customer for the
+-- same product only the latest stays a review. The older ones are moved to
+-- product_reviews_superseded rather than lost, and the down migration puts
+-- them back.
+CREATE TABLE product_reviews_superseded AS
+SELECT pr.* FROM product_reviews pr
+WHERE EXISTS (
+    SELECT 1 FROM product_reviews newer
+    WHERE newer.product_id = pr.product_id AND newer.user_id = pr.user_id AND newer.id > pr.id
+);
+
+ALTER TABLE product_reviews_superseded
+    ADD PRIMARY KEY (id),
+    ADD FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE;
+
+DELETE FROM product_reviews pr
+USING product_reviews_superseded superseded
+WHERE superseded.id = pr.id;
+
+ALTER TABLE product_reviews
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'hidden')),
+    ADD COLUMN reply TEXT,
+    ADD COLUMN replied_at TIMESTAMP,
+    ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE product_reviews SET status = 'approved', updated_at = created_at;
+
+CREATE UNIQUE INDEX idx_product_reviews_user_product ON product_reviews(product_id, user_id);
+CREATE INDEX idx_product_reviews_approved ON product_reviews(product_id, created_at DESC, id DESC) WHERE status = 'approved';
+CREATE INDEX idx_product_reviews_status ON product_reviews(status, created_at DESC, id DESC);
+
+-- The rating of the approved reviews, kept on the product so listings can
+-- sort and filter by it without aggregating the reviews.
+ALTER TABLE products
+    ADD COLUMN average_rating NUMERIC(3, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN review_count INT NOT NULL DEFAULT 0;
+
+UPDATE products
+SET average_rating = ratings.average, review_count = ratings.count
+FROM (
+    SELECT product_id, ROUND(AVG(rating), 2) AS average, COUNT(*) AS count
+    FROM product_reviews WHERE status = 'approved' GROUP BY product_id
+) ratings
+WHERE ratings.product_id = products.id;
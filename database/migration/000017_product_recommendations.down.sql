@@ -0,0 +1,29 @@
+DROP TABLE IF EXISTS product_co_purchases;
+
+DROP INDEX IF EXISTS idx_product_recommendations_recommended;
+
+ALTER TABLE product_recommendations
+    DROP CONSTRAINT product_recommendations_pair_key,
+    DROP CONSTRAINT product_recommendations_not_self,
+    DROP CONSTRAINT product_recommendations_type_check,
+    DROP COLUMN updated_at,
+    ALTER COLUMN priority DROP NOT NULL,
+    ALTER COLUMN is_active DROP NOT NULL,
+    ADD COLUMN product_detail_id INT,
+    ADD COLUMN recommended_product_detail_id INT REFERENCES product_details(id);
+
+-- Links go back to each product's first option combination; products
+-- without one lose their recommendations.
+UPDATE product_recommendations r SET product_detail_id =
+    (SELECT MIN(d.id) FROM product_details d WHERE d.product_id = r.product_id);
+UPDATE product_recommendations r SET recommended_product_detail_id =
+    (SELECT MIN(d.id) FROM product_details d WHERE d.product_id = r.recommended_product_id);
+DELETE FROM product_recommendations
+WHERE product_detail_id IS NULL OR recommended_product_detail_id IS NULL;
+
+ALTER TABLE product_recommendations
+    DROP COLUMN product_id,
+    DROP COLUMN recommended_product_id,
+    ALTER COLUMN product_detail_id SET NOT NULL,
+    ALTER COLUMN recommended_product_detail_id SET NOT NULL,
+    ADD UNIQUE (product_detail_id, recommended_product_detail_id);
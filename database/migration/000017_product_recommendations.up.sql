@@ -0,0 +1,56 @@
+-- Recommendations link products rather than option combinations: a latte
+-- recommends a croissant whatever size either is ordered in.
+ALTER TABLE product_recommendations
+    ADD COLUMN product_id INT REFERENCES products(id) ON DELETE CASCADE,
+    ADD COLUMN recommended_product_id INT REFERENCES products(id) ON DELETE CASCADE;
+
+UPDATE product_recommendations r SET product_id = d.product_id
+FROM product_details d WHERE d.id = r.product_detail_id;
+UPDATE product_recommendations r SET recommended_product_id = d.product_id
+FROM product_details d WHERE d.id = r.recommended_product_detail_id;
+
+DELETE FROM product_recommendations
+WHERE product_id IS NULL OR recommended_product_id IS NULL OR product_id = recommended_product_id;
+
+-- Several combinations of the same two products collapse into one link,
+-- keeping the one with the highest priority.
+DELETE FROM product_recommendations r
+USING product_recommendations keep
+WHERE keep.product_id = r.product_id
+  AND keep.recommended_product_id = r.recommended_product_id
+  AND (COALESCE(keep.priority, 0), -keep.id) > (COALESCE(r.priority, 0), -r.id);
+
+-- The column was free text; anything that is not one of the three kinds the
+-- storefront knows is treated as a cross-sell.
+UPDATE product_recommendations SET recommendation_type = 'cross_sell'
+WHERE recommendation_type NOT IN ('upsell', 'cross_sell', 'pairing');
+UPDATE product_recommendations SET priority = 0 WHERE priority IS NULL;
+UPDATE product_recommendations SET is_active = TRUE WHERE is_active IS NULL;
+
+ALTER TABLE product_recommendations
+    DROP COLUMN product_detail_id,
+    DROP COLUMN recommended_product_detail_id,
+    ALTER COLUMN product_id SET NOT NULL,
+    ALTER COLUMN recommended_product_id SET NOT NULL,
+    ALTER COLUMN priority SET NOT NULL,
+    ALTER COLUMN is_active SET NOT NULL,
+    ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD CONSTRAINT product_recommendations_type_check
+        CHECK (recommendation_type IN ('upsell', 'cross_sell', 'pairing')),
+    ADD CONSTRAINT product_recommendations_not_self CHECK (product_id <> recommended_product_id),
+    ADD CONSTRAINT product_recommendations_pair_key UNIQUE (product_id, recommended_product_id);
+
+CREATE INDEX idx_product_recommendations_recommended ON product_recommendations(recommended_product_id);
+
+-- How many orders contained both products, recomputed from order_items by
+-- the recommendation refresh. Each pair is stored in both directions.
+CREATE TABLE product_co_purchases (
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    related_product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    order_count INT NOT NULL,
+    refreshed_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (product_id, related_product_id)
+);
+
+CREATE INDEX idx_product_co_purchases_rank ON product_co_purchases(product_id, order_count DESC);
+CREATE INDEX idx_product_co_purchases_related ON product_co_purchases(related_product_id);
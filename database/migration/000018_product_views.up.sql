@@ -0,0 +1,11 @@
+-- Every product page a signed-in user opens, for their recently viewed list,
+-- the popularity sort and analytics.
+CREATE TABLE product_views (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    viewed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_product_views_user ON product_views(user_id, viewed_at DESC);
+CREATE INDEX idx_product_views_viewed_at ON product_views(viewed_at, product_id);
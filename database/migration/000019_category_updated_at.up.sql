@@ -0,0 +1,5 @@
+-- When a category last changed, for the Last-Modified header on category
+-- responses. Existing categories count as unchanged since they were created.
+ALTER TABLE categories ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE categories SET updated_at = COALESCE(created_at, updated_at);
@@ -0,0 +1,8 @@
+-- The name the customer gave at checkout, kept with the order like its
+-- delivery address. Earlier orders take the name on the customer's profile.
+ALTER TABLE orders ADD COLUMN customer_name VARCHAR(255) NOT NULL DEFAULT '';
+
+UPDATE orders o
+SET customer_name = COALESCE(up.full_name, '')
+FROM user_profiles up
+WHERE up.user_id = o.user_id;
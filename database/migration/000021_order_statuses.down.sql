@@ -0,0 +1,4 @@
+-- Statuses still referenced by orders are kept.
+DELETE FROM order_status s
+WHERE s.name IN ('pending', 'on_progress', 'sending_goods', 'finish_order', 'cancelled')
+  AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.status_id = s.id);
@@ -0,0 +1,9 @@
+-- The statuses the application moves orders through. Checkout needs
+-- "pending"; databases that already have a status keep it as it is.
+INSERT INTO order_status (name, display_name, description, display_order) VALUES
+('pending', 'Pending', 'Order is waiting for confirmation', 1),
+('on_progress', 'On Progress', 'Order is being prepared', 2),
+('sending_goods', 'Sending Goods', 'Order is being delivered', 3),
+('finish_order', 'Finish Order', 'Order has been completed', 4),
+('cancelled', 'Cancelled', 'Order has been cancelled', 5)
+ON CONFLICT (name) DO NOTHING;
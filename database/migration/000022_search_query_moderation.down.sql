@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_search_queries_status_hits;
+
+ALTER TABLE search_queries
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS id;
@@ -0,0 +1,9 @@
+-- Past searches are only suggested to shoppers once an admin allows them.
+-- Searches recorded before moderation existed wait in the queue too.
+ALTER TABLE search_queries
+    ADD COLUMN id SERIAL UNIQUE,
+    ADD COLUMN status VARCHAR(10) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'allowed', 'blocked'));
+
+-- Serves the moderation queue, most searched first within a status.
+CREATE INDEX idx_search_queries_status_hits ON search_queries (status, hits DESC);
@@ -0,0 +1,16 @@
+-- How many users viewed each product each day. The recommendation refresher
+-- counts them from product_views, and the popularity sort reads them instead
+-- of the raw views, which are only kept for a while.
+CREATE TABLE product_view_daily (
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    viewers INT NOT NULL,
+    PRIMARY KEY (product_id, day)
+);
+
+CREATE INDEX idx_product_view_daily_day ON product_view_daily(day);
+
+INSERT INTO product_view_daily (product_id, day, viewers)
+SELECT product_id, viewed_at::date, COUNT(DISTINCT user_id)
+FROM product_views
+GROUP BY product_id, viewed_at::date;
@@ -0,0 +1,59 @@
+// Package migration embeds the SQL migrations so the binary can apply them
+// without the source tree being present at runtime.
+package migration
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Files holds every up and down migration in this directory.
+//
+//go:embed *.sql
+var Files embed.FS
+
+// Version is an up migration compiled into the binary.
+type Version struct {
+	Number int
+	Name   string
+}
+
+// Versions lists the embedded up migrations in ascending order.
+func Versions() ([]Version, error) {
+	entries, err := fs.Glob(Files, "*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []Version{}
+	for _, entry := range entries {
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(entry, ".up.sql"), "_")
+		if !ok {
+			continue
+		}
+		number, err := strconv.Atoi(prefix)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, Version{Number: number, Name: name})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Number < versions[j].Number })
+	return versions, nil
+}
+
+// Latest returns the highest embedded version, which is the schema version
+// this binary expects the database to be at.
+func Latest() (int, error) {
+	versions, err := Versions()
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, errors.New("no migrations embedded")
+	}
+	return versions[len(versions)-1].Number, nil
+}
@@ -0,0 +1,39 @@
+package migration
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestEveryUpHasDown(t *testing.T) {
+	ups, err := fs.Glob(Files, "*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, up := range ups {
+		down := strings.TrimSuffix(up, ".up.sql") + ".down.sql"
+		if _, err := fs.Stat(Files, down); err != nil {
+			t.Errorf("%s has no %s", up, down)
+		}
+	}
+}
+
+func TestLatestIsHighestVersion(t *testing.T) {
+	versions, err := Versions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range versions {
+		if i > 0 && v.Number <= versions[i-1].Number {
+			t.Fatalf("versions out of order: %+v", versions)
+		}
+	}
+	if latest != versions[len(versions)-1].Number {
+		t.Fatalf("Latest() = %d, want %d", latest, versions[len(versions)-1].Number)
+	}
+}
@@ -1,9 +1,12 @@
+-- Demo data loaded by `go run . seed`. User passwords are left NULL here and
+-- set to a bcrypt hash of the seed password by the command.
+
 INSERT INTO users (email, password, role, created_at, updated_at) VALUES
-('admin@harlanholden.com', '$2b$10$abcdefghijklmnopqrstuv', 'admin', '2025-10-15 08:00:00', '2025-10-15 08:00:00'),
-('anggi@email.com', '$2b$10$abcdefghijklmnopqrstuv', 'customer', '2025-10-01 10:30:00', '2025-10-01 10:30:00'),
-('prayoga@email.com', '$2b$10$abcdefghijklmnopqrstuv', 'customer', '2025-10-15 14:20:00', '2025-10-15 14:20:00'),
-('raka@email.com', '$2b$10$abcdefghijklmnopqrstuv', 'customer', '2025-10-01 09:15:00', '2025-10-01 09:15:00'),
-('rangga@email.com', '$2b$10$abcdefghijklmnopqrstuv', 'customer', '2025-10-10 16:45:00', '2025-10-10 16:45:00');
+('admin@harlanholden.com', NULL, 'admin', '2025-10-15 08:00:00', '2025-10-15 08:00:00'),
+('anggi@email.com', NULL, 'customer', '2025-10-01 10:30:00', '2025-10-01 10:30:00'),
+('prayoga@email.com', NULL, 'customer', '2025-10-15 14:20:00', '2025-10-15 14:20:00'),
+('raka@email.com', NULL, 'customer', '2025-10-01 09:15:00', '2025-10-01 09:15:00'),
+('rangga@email.com', NULL, 'customer', '2025-10-10 16:45:00', '2025-10-10 16:45:00');
 
 INSERT INTO user_profiles (user_id, full_name, phone, address, photo_url, created_at, updated_at) VALUES
 (1, 'Admin Harlan Holden', '081234567890', 'Jl. Sudirman, Jakarta Pusat', 'https://id.pinterest.com/pin/625226360811543537/', '2025-10-15 08:00:00', '2025-10-15 08:00:00'),
@@ -23,11 +26,14 @@ INSERT INTO product_sizes (name, price_adjustment, is_active, created_at) VALUES
 ('Medium', 5000, TRUE, '2025-10-10 08:00:00'),
 ('Large', 10000, TRUE, '2025-10-10 08:00:00');
 
-INSERT INTO product_temperatures (name, is_active, created_at) VALUES
-('Hot', TRUE, '2025-10-10 08:00:00'),
-('Ice', TRUE, '2025-10-10 08:00:00');
+INSERT INTO product_temperatures (name, price, is_active) VALUES
+('Hot', 0, TRUE),
+('Iced', 2000, TRUE),
+('Warm', 0, TRUE),
+('Extra Hot', 1000, TRUE),
+('Blended', 3000, TRUE);
 
-INSERT INTO products (name, description, category_id, price, is_flash_sale, is_favorite, is_buy1get1, is_active, stock, created_at, updated_at) VALUES
+INSERT INTO products (name, description, category_id, price, is_flash_sale, is_featured, is_buy1get1, is_active, stock, created_at, updated_at) VALUES
 ('Sea Salt Latte', 'Latte topped with Harlan sea salt cream. Items may arrive less cold due to delivery time. Product look may vary due to delivery.', 1, 67000, FALSE, TRUE, FALSE, TRUE, 100, '2025-10-12 09:00:00', '2025-10-12 09:00:00'),
 ('Butterscotch Latte', 'Caramel latte with sea salt, framed with cookie crumble. Items may arrive less hot/cold due to delivery time. Iced drink as seen on photo.', 1, 62000, FALSE, TRUE, FALSE, TRUE, 95, '2025-10-12 09:00:00', '2025-10-12 09:00:00'),
 ('Peanut Butter Latte', 'A flat white with sweet and salty ground peanuts, topped with cookie crumble. Items may arrive less hot/cold due to delivery time. Product look may vary due to delivery.', 1, 62000, FALSE, TRUE, FALSE, TRUE, 90, '2025-10-12 09:00:00', '2025-10-12 09:00:00'),
@@ -86,12 +92,20 @@ INSERT INTO product_images (product_id, image_url, is_primary, display_order, cr
 (4, 'https://food-cms.grab.com/compressed_webp/items/PHITE2025-10304191964125/detail/menueditor_item_556ec05ae99744afa555fc23e1913597_1705983521589490906.webp', TRUE, 1, '2025-10-12 09:30:00'),
 (5, 'https://food-cms.grab.com/compressed_webp/items/PHITE2022091202161897825/detail/menueditor_item_29bcc8780b074c6689f176a334bc3ab6_1701164526305614041.webp', TRUE, 1, '2025-10-12 09:30:00');
 
-INSERT INTO product_reviews (product_id, user_id, rating, review_text, created_at) VALUES
-(1, 2, 5, 'Sea salt cream on top is amazing! Perfect balance.', '2025-10-05 10:30:00'),
-(2, 3, 5, 'Butterscotch latte is so good! Love the cookie crumble.', '2025-10-20 14:15:00'),
-(14, 4, 5, 'Pandan latte is the best! Unique and refreshing.', '2025-10-05 11:20:00'),
-(8, 2, 5, 'Perfect americano for morning boost!', '2025-10-15 16:30:00'),
-(3, 5, 5, 'Matcha latte is authentic and delicious.', '2025-10-20 13:45:00');
+INSERT INTO product_reviews (product_id, user_id, rating, review_text, status, created_at, updated_at) VALUES
+(1, 2, 5, 'Sea salt cream on top is amazing! Perfect balance.', 'approved', '2025-10-05 10:30:00', '2025-10-05 10:30:00'),
+(2, 3, 5, 'Butterscotch latte is so good! Love the cookie crumble.', 'approved', '2025-10-20 14:15:00', '2025-10-20 14:15:00'),
+(14, 4, 5, 'Pandan latte is the best! Unique and refreshing.', 'approved', '2025-10-05 11:20:00', '2025-10-05 11:20:00'),
+(8, 2, 5, 'Perfect americano for morning boost!', 'approved', '2025-10-15 16:30:00', '2025-10-15 16:30:00'),
+(3, 5, 5, 'Matcha latte is authentic and delicious.', 'approved', '2025-10-20 13:45:00', '2025-10-20 13:45:00');
+
+UPDATE products
+SET average_rating = ratings.average, review_count = ratings.count
+FROM (
+    SELECT product_id, ROUND(AVG(rating), 2) AS average, COUNT(*) AS count
+    FROM product_reviews WHERE status = 'approved' GROUP BY product_id
+) ratings
+WHERE ratings.product_id = products.id;
 
 INSERT INTO promos (code, title, description, discount_percentage, start_date, end_date, is_active, created_at) VALUES
 ('MOTHERSDAY', 'HAPPY MOTHERS DAY!', 'Get one of our favorite menu for free!', 100, '2025-10-01', '2025-10-12', TRUE, '2025-10-01 00:00:00'),
@@ -125,29 +139,6 @@ INSERT INTO tax_rates (name, rate_percentage, is_active, created_at) VALUES
 ('PPN 10%', 10.00, TRUE, '2025-10-10 08:00:00'),
 ('Service Charge 5%', 5.00, TRUE, '2025-10-10 08:00:00');
 
-INSERT INTO orders (order_number, user_id, status, delivery_address, delivery_method_id, subtotal, delivery_fee, tax_amount, tax_rate_id, total, promo_id, payment_method_id, order_date, created_at, updated_at) VALUES
-('HH-2025-1001', 2, 'delivered', 'Jl. Thamrin No. 123, Jakarta Pusat', 1, 140000, 15000, 15400, 1, 170400, 1, 2, '2025-10-10 10:30:00', '2025-10-10 10:30:00', '2025-10-10 11:45:00'),
-('HH-2025-1002', 3, 'delivered', 'Jl. Gatot Subroto No. 45, Jakarta Selatan', 2, 188000, 25000, 20680, 1, 233680, NULL, 4, '2025-10-25 14:15:00', '2025-10-25 14:15:00', '2025-10-25 15:10:00'),
-('HH-2025-1003', 4, 'processing', 'Jl. HR Rasuna Said No. 78, Jakarta Selatan', 1, 195000, 15000, 21450, 1, 231450, 2, 5, '2025-10-10 11:20:00', '2025-10-10 11:20:00', '2025-10-10 11:20:00'),
-('HH-2025-1004', 5, 'pending', 'Jl. Kuningan No. 90, Jakarta Selatan', 4, 220000, 0, 24200, 1, 244200, NULL, 7, '2025-10-28 16:45:00', '2025-10-28 16:45:00', '2025-10-28 16:45:00'),
-('HH-2025-1005', 2, 'delivered', 'Jl. Thamrin No. 123, Jakarta Pusat', 4, 178000, 0, 19580, 1, 197580, 3, 3, '2025-10-05 09:30:00', '2025-10-05 09:30:00', '2025-10-05 10:15:00');
-
-INSERT INTO product_sizes (name, price_adjustment, is_active, created_at) VALUES
-('Regular', 0, TRUE, '2025-10-10 08:00:00'),
-('Medium', 5000, TRUE, '2025-10-10 08:00:00'),
-('Large', 10000, TRUE, '2025-10-10 08:00:00');
-
-INSERT INTO product_temperatures (name, is_active, created_at) VALUES
-('Hot', TRUE, '2025-10-10 08:00:00'),
-('Ice', TRUE, '2025-10-10 08:00:00');
-
-INSERT INTO order_items (order_id, product_id, quantity, size_id, temperature_id, unit_price, is_flash_sale, created_at) VALUES
-(1, 1, 2, 1, 2, 50000, TRUE, '2025-10-10 10:30:00'),
-(1, 8, 1, 1, 1, 43000, FALSE, '2025-10-10 10:30:00'),
-(2, 14, 2, 1, 1, 50000, FALSE, '2025-10-25 14:15:00'),
-(2, 28, 2, NULL, NULL, 40000, FALSE, '2025-10-25 14:15:00'),
-(3, 11, 2, 1, 1, 45000, FALSE, '2025-10-10 11:20:00');
-
 INSERT INTO cart_items (user_id, product_id, quantity, size_id, temperature_id, created_at, updated_at) VALUES
 (2, 1, 1, 1, 2, '2025-10-10 10:00:00', '2025-10-10 10:00:00'),
 (2, 30, 1, NULL, NULL, '2025-10-10 10:02:00', '2025-10-10 10:02:00'),
@@ -167,6 +158,43 @@ INSERT INTO product_variants (name, description, price, is_active) VALUES
 ('Caramel Drizzle', 'Dengan caramel drizzle', 4000, TRUE),
 ('Chocolate Chip', 'Dengan chocolate chip', 5000, TRUE);
 
+-- Drinks come in every size, hot or iced; iced-only drinks skip the hot
+-- options and bottled water has none. Food and bundles are sold plain.
+INSERT INTO product_details (product_id, size_id, temperature_id, price_adjustment)
+SELECT p.id, s.id, t.id, s.price_adjustment + t.price
+FROM products p
+CROSS JOIN product_sizes s
+CROSS JOIN product_temperatures t
+WHERE p.category_id IN (1, 2)
+  AND p.name NOT LIKE '%Water%'
+  AND t.name IN ('Hot', 'Iced')
+  AND (t.name = 'Iced' OR (p.description NOT LIKE '%Iced only%'
+                           AND p.name NOT LIKE '%Iced Tea%'
+                           AND p.name NOT LIKE '%Cold Brew%'))
+ORDER BY p.id, s.id, t.id;
+
+-- The shop's own picks: a snack to go with a drink, the bundle that
+-- includes it, or a dessert.
+INSERT INTO product_recommendations (product_id, recommended_product_id, recommendation_type, priority) VALUES
+(1, 40, 'pairing', 10),
+(1, 31, 'upsell', 5),
+(5, 37, 'pairing', 10),
+(5, 29, 'upsell', 5),
+(13, 39, 'pairing', 10),
+(13, 30, 'upsell', 5),
+(6, 43, 'cross_sell', 0),
+(10, 44, 'cross_sell', 0);
+
+-- The seeded stock opens each product's ledger.
+INSERT INTO stock_movements (product_id, movement_type, quantity, stock_after, reason, created_at)
+SELECT id, 'adjustment', stock, stock, 'Opening balance', created_at
+FROM products
+WHERE stock <> 0;
+
+-- The seeded prices open each product's price history.
+INSERT INTO product_prices (product_id, price, effective_at, applied_at, reason, created_at)
+SELECT id, price, created_at, created_at, 'Opening price', created_at
+FROM products;
 
 INSERT INTO product_images (product_id, image_url, is_primary, display_order) VALUES
 (6, 'https://food-cms.grab.com/compressed_webp/items/PHITE2022111608533096371/detail/menueditor_item_77c30fb249fb491bac3eb05522beb91a_1701165051471861885.webp', true, 1),
@@ -174,22 +202,11 @@ INSERT INTO product_images (product_id, image_url, is_primary, display_order) VA
 (6, 'https://i.pinimg.com/736x/d5/2e/4e/d52e4e807352c3421ed89d95ddee75a2.jpg', false, 3),
 (6, 'https://i.pinimg.com/1200x/32/ba/52/32ba52056d30b5bed11e6aeb2ad30874.jpg', false, 4);
 
+UPDATE products p SET image_url = i.image_url
+FROM product_images i
+WHERE i.product_id = p.id AND i.is_primary;
 
-INSERT INTO product_temperatures (name, price, is_active) VALUES
-('Hot', 0, TRUE),
-('Iced', 2000, TRUE),
-('Warm', 0, TRUE),
-('Extra Hot', 1000, TRUE),
-('Blended', 3000, TRUE);
-
-
-INSERT INTO order_status (name, display_name, description, display_order) VALUES
-('pending', 'Pending', 'Order is waiting for confirmation', 1),
-('on_progress', 'On Progress', 'Order is being prepared', 2),
-('sending_goods', 'Sending Goods', 'Order is being delivered', 3),
-('finish_order', 'Finish Order', 'Order has been completed', 4),
-('cancelled', 'Cancelled', 'Order has been cancelled', 5);
-
+-- order_status rows come from migration 000021, in ID order.
 INSERT INTO orders (order_number, user_id, status_id, delivery_address, delivery_method_id, subtotal, delivery_fee, tax_amount, total, payment_method_id, order_date, created_at, updated_at) VALUES
 ('ORD-2023-001', 2, 2, 'Jl. Thamrin No. 123, Jakarta Pusat', 1, 460000, 0, 0, 460000, 1, '2023-01-23 10:30:00', '2023-01-23 10:30:00', '2023-01-23 10:30:00'),
 ('ORD-2023-002', 2, 2, 'Jl. Thamrin No. 123, Jakarta Pusat', 1, 230000, 0, 0, 230000, 2, '2023-01-24 14:20:00', '2023-01-24 14:20:00', '2023-01-24 14:20:00'),
@@ -0,0 +1,54 @@
+// Package seed loads the demo data used for local development.
+package seed
+
+import (
+	"coffee-shop/libs"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed fixtures.sql
+var fixtures string
+
+// ErrNotEmpty is returned when the database already has users.
+var ErrNotEmpty = errors.New("database already contains users; seed only runs on an empty database")
+
+// Load inserts the fixtures in one transaction and gives every seeded user
+// password. It returns the number of users created.
+func Load(ctx context.Context, db *pgxpool.Pool, password string) (int, error) {
+	if len(password) < 6 {
+		return 0, errors.New("seed password must be at least 6 characters")
+	}
+	hash, err := libs.HashPassword(password)
+	if err != nil {
+		return 0, err
+	}
+
+	var users int
+	err = pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		var existing int
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&existing); err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrNotEmpty
+		}
+
+		if _, err := tx.Exec(ctx, fixtures); err != nil {
+			return fmt.Errorf("load fixtures: %w", err)
+		}
+
+		tag, err := tx.Exec(ctx, "UPDATE users SET password=$1 WHERE password IS NULL", hash)
+		if err != nil {
+			return err
+		}
+		users = int(tag.RowsAffected())
+		return nil
+	})
+	return users, err
+}
@@ -0,0 +1,1133 @@
+package e2e
+
+import (
+	"coffee-shop/cache"
+	"coffee-shop/repositories"
+	"coffee-shop/services"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	h := newHarness(t)
+	h.expect(h.Get("/health", ""), 200)
+}
+
+func TestLoginRejectsWrongPassword(t *testing.T) {
+	h := newHarness(t)
+	u := h.CreateUser("customer@example.com", "customer")
+	u.Password = "wrong-password"
+
+	r := h.JSON("POST", "/auth/login", "", map[string]string{"email": u.Email, "password": u.Password})
+	h.expect(r, 401)
+}
+
+func TestAdminRoutesRequireAdminToken(t *testing.T) {
+	h := newHarness(t)
+	_, customer := h.CustomerToken()
+	_, admin := h.AdminToken()
+
+	h.expect(h.Get("/admin/users", ""), 401)
+	h.expect(h.Get("/admin/users", customer), 403)
+	h.expect(h.Get("/admin/users", admin), 200)
+}
+
+func TestProductListing(t *testing.T) {
+	h := newHarness(t)
+	category := h.CreateCategory("Coffee")
+	h.CreateProduct(productFixture{Name: "Latte", CategoryID: category, Price: 25000, Stock: 10, IsFeatured: true})
+	h.CreateProduct(productFixture{Name: "Mocha", CategoryID: category, Price: 28000, Stock: 10})
+
+	r := h.Get("/products?limit=1", "")
+	h.expect(r, 200)
+	meta, _ := r.Body["meta"].(map[string]interface{})
+	if meta["total_items"] != float64(2) || meta["total_pages"] != float64(2) {
+		t.Fatalf("unexpected meta %v", meta)
+	}
+	cursor, _ := meta["next_cursor"].(string)
+	h.expect(h.Get("/products?limit=1&cursor="+cursor, ""), 200)
+	h.expect(h.Get("/products?limit=1&sort=price_asc&cursor="+cursor, ""), 400)
+	h.expect(h.Get("/products/filter?limit=1&search=latte&cursor="+cursor, ""), 400)
+
+	// LIKE wildcards in a search are matched literally.
+	r = h.Get("/products/filter?search=%25", "")
+	h.expect(r, 200)
+	if items, _ := r.Body["data"].([]interface{}); len(items) != 0 {
+		t.Fatalf("search for %%: %s", r.Raw)
+	}
+
+	r = h.Get("/v2/products/featured", "")
+	h.expect(r, 200)
+	items, _ := r.Body["data"].([]interface{})
+	if len(items) != 1 {
+		t.Fatalf("featured = %s", r.Raw)
+	}
+	if _, ok := items[0].(map[string]interface{})["categoryId"]; !ok {
+		t.Fatalf("v2 product is not camelCase: %s", r.Raw)
+	}
+}
+
+func TestAdminCreatesProduct(t *testing.T) {
+	h := newHarness(t)
+	_, admin := h.AdminToken()
+	category := h.CreateCategory("Coffee")
+
+	r := h.Form("POST", "/admin/products", admin, map[string]string{
+		"name":        "Cold Brew",
+		"category_id": itoa(category),
+		"price":       "30000",
+		"stock":       "7",
+	})
+	h.expect(r, 201)
+
+	if n := h.queryInt(`SELECT COUNT(*) FROM products WHERE name = 'Cold Brew'`); n != 1 {
+		t.Fatalf("products stored = %d", n)
+	}
+	if n := h.queryInt(`SELECT COUNT(*) FROM audit_log WHERE entity_type = 'product' AND action = 'create'`); n != 1 {
+		t.Fatalf("audit entries = %d", n)
+	}
+}
+
+func TestCartAndCheckout(t *testing.T) {
+	h := newHarness(t)
+	customer, token := h.CustomerToken()
+	product := h.CreateProduct(productFixture{Name: "Latte", Price: 25000, Stock: 5})
+	h.AddOption(product, sizeLarge, temperatureIce, 7000)
+
+	r := h.Form("POST", "/cart", token, map[string]string{
+		"product_id":     itoa(product),
+		"quantity":       "2",
+		"size_id":        itoa(sizeLarge),
+		"temperature_id": itoa(temperatureIce),
+	})
+	h.expect(r, 201)
+
+	r = h.Get("/cart", token)
+	h.expect(r, 200)
+
+	r = h.Form("POST", "/transactions/checkout", token, map[string]string{
+		"delivery_method":   "door_delivery",
+		"payment_method_id": itoa(paymentCash),
+	})
+	h.expect(r, 201)
+
+	if stock := h.queryInt(`SELECT stock FROM products WHERE id = $1`, product); stock != 3 {
+		t.Fatalf("stock = %d, want 3", stock)
+	}
+	if n := h.queryInt(`SELECT COUNT(*) FROM cart_items WHERE user_id = $1`, customer.ID); n != 0 {
+		t.Fatalf("cart still has %d lines", n)
+	}
+	// (25000 + 7000 large iced) * 2 + 10000 delivery
+	if total := h.queryInt(`SELECT total FROM orders WHERE user_id = $1`, customer.ID); total != 74000 {
+		t.Fatalf("total = %d, want 74000", total)
+	}
+
+	r = h.Get("/history", token)
+	h.expect(r, 200)
+	orders, _ := r.Body["data"].([]interface{})
+	if len(orders) != 1 {
+		t.Fatalf("history = %s", r.Raw)
+	}
+}
+
+func TestCheckoutRejectsEmptyCart(t *testing.T) {
+	h := newHarness(t)
+	_, token := h.CustomerToken()
+
+	r := h.Form("POST", "/transactions/checkout", token, map[string]string{"delivery_method": "dine_in"})
+	h.expect(r, 400)
+}
+
+func TestCheckoutRejectsInsufficientStock(t *testing.T) {
+	h := newHarness(t)
+	customer, token := h.CustomerToken()
+	product := h.CreateProduct(productFixture{Name: "Latte", Price: 25000, Stock: 1})
+	h.AddToCart(customer.ID, product, 3)
+
+	r := h.Form("POST", "/transactions/checkout", token, map[string]string{"delivery_method": "dine_in"})
+	h.expect(r, 400)
+
+	if n := h.queryInt(`SELECT COUNT(*) FROM orders`); n != 0 {
+		t.Fatalf("orders = %d, want none after a rejected checkout", n)
+	}
+}
+
+func TestCheckoutFailsWithoutPendingStatus(t *testing.T) {
+	h := newHarness(t)
+	customer, token := h.CustomerToken()
+	product := h.CreateProduct(productFixture{Name: "Latte", Price: 25000, Stock: 5})
+	h.AddToCart(customer.ID, product, 1)
+	h.exec(`DELETE FROM order_status WHERE name = 'pending'`)
+
+	r := h.Form("POST", "/transactions/checkout", token, map[string]string{"delivery_method": "dine_in"})
+	h.expect(r, 500)
+
+	if n := h.queryInt(`SELECT COUNT(*) FROM orders`); n != 0 {
+		t.Fatalf("orders = %d, want none", n)
+	}
+	if stock := h.queryInt(`SELECT stock FROM products WHERE id = $1`, product); stock != 5 {
+		t.Fatalf("stock = %d, want 5", stock)
+	}
+}
+
+func TestProductReviewsCursorPagination(t *testing.T) {
+	h := newHarness(t)
+	product := h.CreateProduct(productFixture{Name: "Latte", Price: 25000, Stock: 5})
+	for rating := 1; rating <= 3; rating++ {
+		customer := h.CreateUser("customer"+itoa(rating)+"@example.com", "customer")
+		h.exec(`INSERT INTO product_reviews (product_id, user_id, rating, review_text, status, created_at)
+			VALUES ($1, $2, $3, 'ok', 'approved', NOW() - make_interval(mins => $3))`, product, customer.ID, rating)
+	}
+
+	r := h.Get("/products/"+itoa(product)+"/reviews?limit=2", "")
+	h.expect(r, 200)
+	meta, _ := r.Body["meta"].(map[string]interface{})
+	cursor, _ := meta["next_cursor"].(string)
+	if meta["total_items"] != float64(3) || cursor == "" {
+		t.Fatalf("first page = %s", r.Raw)
+	}
+
+	r = h.Get("/products/"+itoa(product)+"/reviews?limit=2&cursor="+cursor, "")
+	h.expect(r, 200)
+	reviews, _ := r.Body["data"].([]interface{})
+	if len(reviews) != 1 || reviews[0].(map[string]interface{})["rating"] != float64(3) {
+		t.Fatalf("second page = %s", r.Raw)
+	}
+}
+
+func TestProductSearch(t *testing.T) {
+	h := newHarness(t)
+	coffee := h.CreateCategory("Coffee")
+	tea := h.CreateCategory("Tea")
+	espresso := h.CreateProduct(productFixture{Name: "Espresso", CategoryID: coffee, Price: 18000, Stock: 5})
+	latte := h.CreateProduct(productFixture{Name: "Iced Latte", CategoryID: coffee, Price: 25000, Stock: 5})
+	matcha := h.CreateProduct(productFixture{Name: "Matcha", CategoryID: tea, Price: 27000, Stock: 5})
+	h.exec(`UPDATE products SET description = 'A bold single shot' WHERE id = $1`, espresso)
+	h.exec(`UPDATE products SET description = 'Espresso with cold milk' WHERE id = $1`, latte)
+	h.exec(`UPDATE products SET description = 'Stone-ground green tea' WHERE id = $1`, matcha)
+
+	ids := func(path string) []int {
+		t.Helper()
+		r := h.Get(path, "")
+		h.expect(r, 200)
+		items, _ := r.Body["data"].([]interface{})
+		out := []int{}
+		for _, item := range items {
+			out = append(out, int(item.(map[string]interface{})["id"].(float64)))
+		}
+		return out
+	}
+
+	// The name match ranks above the description match.
+	if got := ids("/products/filter?search=espresso"); len(got) != 2 || got[0] != espresso || got[1] != latte {
+		t.Fatalf("espresso = %v, want [%d %d]", got, espresso, latte)
+	}
+	if got := ids("/products/filter?search=esspreso"); len(got) != 1 || got[0] != espresso {
+		t.Fatalf("esspreso = %v, want [%d]", got, espresso)
+	}
+	if got := ids("/products/filter?search=bold"); len(got) != 1 || got[0] != espresso {
+		t.Fatalf("bold = %v, want [%d]", got, espresso)
+	}
+	if got := ids("/products/filter?search=tea"); len(got) != 1 || got[0] != matcha {
+		t.Fatalf("tea = %v, want [%d]", got, matcha)
+	}
+
+	r := h.Get("/v2/products/filter?search=cold%20milk", "")
+	h.expect(r, 200)
+	items, _ := r.Body["data"].([]interface{})
+	if len(items) != 1 {
+		t.Fatalf("cold milk = %s", r.Raw)
+	}
+	highlight, _ := items[0].(map[string]interface{})["highlight"].(map[string]interface{})
+	if highlight["description"] != "Espresso with <mark>cold</mark> <mark>milk</mark>" {
+		t.Fatalf("highlight = %v", highlight)
+	}
+	if meta, _ := r.Body["meta"].(map[string]interface{}); meta["nextCursor"] != nil {
+		t.Fatalf("search results must not offer a cursor: %v", meta)
+	}
+}
+
+func TestProductSuggestions(t *testing.T) {
+	h := newHarness(t)
+	coffee := h.CreateCategory("Coffee")
+	h.CreateProduct(productFixture{Name: "Espresso", CategoryID: coffee, Price: 18000, Stock: 5})
+	h.CreateProduct(productFixture{Name: "Iced Latte", CategoryID: coffee, Price: 25000, Stock: 5})
+	h.exec(`INSERT INTO search_queries (query, hits, status) VALUES
+		('espresso tonic', 12, 'allowed'), ('esp typo', 1, 'allowed'), ('espresso scam', 40, 'blocked')`)
+
+	r := h.Get("/v2/products/suggest?q=es", "")
+	h.expect(r, 200)
+	data := r.Data()
+	products, _ := data["products"].([]interface{})
+	if len(products) != 1 || products[0].(map[string]interface{})["name"] != "Espresso" {
+		t.Fatalf("products = %s", r.Raw)
+	}
+	queries, _ := data["queries"].([]interface{})
+	if len(queries) != 1 || queries[0] != "espresso tonic" {
+		t.Fatalf("queries = %s", r.Raw)
+	}
+
+	r = h.Get("/products/suggest?q=esspreso", "")
+	h.expect(r, 200)
+	products, _ = r.Data()["products"].([]interface{})
+	if len(products) == 0 || products[0].(map[string]interface{})["name"] != "Espresso" {
+		t.Fatalf("typo suggestions = %s", r.Raw)
+	}
+
+	// The same client searching again is not counted twice, and searches
+	// that found nothing are not recorded.
+	h.expect(h.Get("/products/filter?search=Iced%20Latte", ""), 200)
+	h.expect(h.Get("/products/filter?search=iced%20latte", ""), 200)
+	h.expect(h.Get("/products/filter?search=flat%20white", ""), 200)
+	if hits := h.queryInt(`SELECT hits FROM search_queries WHERE query = 'iced latte'`); hits != 1 {
+		t.Fatalf("hits = %d, want 1", hits)
+	}
+	if n := h.queryInt(`SELECT COUNT(*) FROM search_queries WHERE query = 'flat white'`); n != 0 {
+		t.Fatalf("a search without results was recorded")
+	}
+
+	_, adminToken := h.AdminToken()
+	r = h.Get("/admin/search-queries", adminToken)
+	h.expect(r, 200)
+	pending, _ := r.Body["data"].([]interface{})
+	if len(pending) != 1 || pending[0].(map[string]interface{})["query"] != "iced latte" {
+		t.Fatalf("pending = %s", r.Raw)
+	}
+	id := h.queryInt(`SELECT id FROM search_queries WHERE query = 'iced latte'`)
+	h.expect(h.JSON("PATCH", "/admin/search-queries/"+itoa(id), adminToken, map[string]interface{}{"status": "pending"}), 400)
+	h.expect(h.JSON("PATCH", "/admin/search-queries/"+itoa(id), adminToken, map[string]interface{}{"status": "blocked"}), 200)
+	if status := h.queryString(`SELECT status FROM search_queries WHERE id = $1`, id); status != "blocked" {
+		t.Fatalf("status = %s", status)
+	}
+}
+
+func TestProductSortingAndFacets(t *testing.T) {
+	h := newHarness(t)
+	customer, token := h.CustomerToken()
+	coffee := h.CreateCategory("Coffee")
+	tea := h.CreateCategory("Tea")
+	latte := h.CreateProduct(productFixture{Name: "Latte", CategoryID: coffee, Price: 25000, Stock: 10, IsFeatured: true})
+	mocha := h.CreateProduct(productFixture{Name: "Mocha", CategoryID: coffee, Price: 28000, Stock: 0})
+	matcha := h.CreateProduct(productFixture{Name: "Matcha", CategoryID: tea, Price: 12000, Stock: 10, IsFlashSale: true})
+	h.exec(`UPDATE products SET is_buy1get1 = TRUE WHERE id = $1`, mocha)
+	h.AddReview(mocha, customer.ID, 5)
+	h.AddReview(latte, customer.ID, 3)
+
+	// Matcha sells three, Latte one.
+	h.AddToCart(customer.ID, matcha, 3)
+	h.AddToCart(customer.ID, latte, 1)
+	h.expect(h.Form("POST", "/transactions/checkout", token, map[string]string{
+		"delivery_method":   "pick_up",
+		"payment_method_id": itoa(paymentCash),
+	}), 201)
+
+	ids := func(query string) []int {
+		t.Helper()
+		r := h.Get("/products/filter?"+query, "")
+		h.expect(r, 200)
+		items, _ := r.Body["data"].([]interface{})
+		out := []int{}
+		for _, item := range items {
+			out = append(out, int(item.(map[string]interface{})["id"].(float64)))
+		}
+		return out
+	}
+	cases := map[string][]int{
+		"sort=price_asc":                                  {matcha, latte, mocha},
+		"sort=price_desc":                                 {mocha, latte, matcha},
+		"sort=best_selling":                               {matcha, latte, mocha},
+		"sort=top_rated":                                  {mocha, latte, matcha},
+		"sort=top_rated&min_rating=4":                     {mocha},
+		"in_stock=true&sort=price_asc":                    {matcha, latte},
+		"is_buy1get1=true":                                {mocha},
+		"category_id=" + itoa(coffee) + "&sort=price_asc": {latte, mocha},
+	}
+	for query, want := range cases {
+		if got := ids(query); !slices.Equal(got, want) {
+			t.Errorf("%s = %v, want %v", query, got, want)
+		}
+	}
+
+	h.expect(h.Get("/products/filter?sort=cheapest", ""), 400)
+
+	r := h.Get("/v2/products/filter?in_stock=true", "")
+	h.expect(r, 200)
+	facets, _ := r.Body["facets"].(map[string]interface{})
+	categories, _ := facets["categories"].([]interface{})
+	if len(categories) != 2 || categories[0].(map[string]interface{})["count"] != float64(1) {
+		t.Fatalf("category facets = %s", r.Raw)
+	}
+	ranges, _ := facets["priceRanges"].([]interface{})
+	if len(ranges) != 4 || ranges[0].(map[string]interface{})["count"] != float64(1) || ranges[2].(map[string]interface{})["count"] != float64(1) {
+		t.Fatalf("price facets = %s", r.Raw)
+	}
+	if facets["flashSale"] != float64(1) || facets["featured"] != float64(1) || facets["buy1Get1"] != float64(0) || facets["inStock"] != float64(2) {
+		t.Fatalf("flag facets = %s", r.Raw)
+	}
+}
+
+func TestAdminArchivesRestoresAndPurgesProducts(t *testing.T) {
+	h := newHarness(t)
+	customer, token := h.CustomerToken()
+	_, adminToken := h.AdminToken()
+	latte := h.CreateProduct(productFixture{Name: "Latte", Price: 25000, Stock: 5})
+	mocha := h.CreateProduct(productFixture{Name: "Mocha", Price: 30000, Stock: 5})
+
+	h.AddToCart(customer.ID, latte, 1)
+	r := h.Form("POST", "/transactions/checkout", token, map[string]string{"delivery_method": "dine_in"})
+	h.expect(r, 201)
+
+	h.expect(h.JSON("DELETE", "/admin/products/"+itoa(latte), adminToken, nil), 200)
+	h.expect(h.Get("/products/"+itoa(latte), ""), 404)
+	h.expect(h.JSON("DELETE", "/admin/products/"+itoa(latte), adminToken, nil), 409)
+
+	r = h.Get("/products", "")
+	h.expect(r, 200)
+	if products, _ := r.Body["data"].([]interface{}); len(products) != 1 {
+		t.Fatalf("storefront lists archived products: %s", r.Raw)
+	}
+
+	r = h.Get("/history", token)
+	h.expect(r, 200)
+	if orders, _ := r.Body["data"].([]interface{}); len(orders) != 1 {
+		t.Fatalf("history lost the archived product's order: %s", r.Raw)
+	}
+
+	h.expect(h.JSON("DELETE", "/admin/products/"+itoa(latte)+"/purge", adminToken, nil), 409)
+
+	r = h.Get("/admin/products/archived", adminToken)
+	h.expect(r, 200)
+	if archived, _ := r.Body["data"].([]interface{}); len(archived) != 1 {
+		t.Fatalf("archived = %s", r.Raw)
+	}
+
+	h.expect(h.JSON("POST", "/admin/products/"+itoa(latte)+"/restore", adminToken, nil), 200)
+	h.expect(h.Get("/products/"+itoa(latte), ""), 200)
+
+	h.expect(h.JSON("DELETE", "/admin/products/"+itoa(mocha)+"/purge", adminToken, nil), 409)
+	h.AddToCart(customer.ID, mocha, 1)
+	h.expect(h.JSON("DELETE", "/admin/products/"+itoa(mocha), adminToken, nil), 200)
+	h.expect(h.JSON("DELETE", "/admin/products/"+itoa(mocha)+"/purge", adminToken, nil), 200)
+	if n := h.queryInt(`SELECT COUNT(*) FROM products WHERE id = $1`, mocha); n != 0 {
+		t.Fatalf("purged product still stored")
+	}
+	if n := h.queryInt(`SELECT COUNT(*) FROM cart_items WHERE product_id = $1`, mocha); n != 0 {
+		t.Fatalf("purge left %d cart lines", n)
+	}
+}
+
+func TestAdminManagesProductGallery(t *testing.T) {
+	h := newHarness(t)
+	_, adminToken := h.AdminToken()
+	product := h.CreateProduct(productFixture{Name: "Latte", Price: 25000, Stock: 5})
+	images := "/admin/products/" + itoa(product) + "/images"
+	imageURL := func() string {
+		return h.queryString(`SELECT COALESCE(image_url, '') FROM products WHERE id = $1`, product)
+	}
+
+	r := h.Files("POST", images, adminToken, "images", "front.png", "side.png")
+	h.expect(r, 201)
+	gallery, _ := r.Body["data"].([]interface{})
+	if len(gallery) != 2 {
+		t.Fatalf("gallery = %s", r.Raw)
+	}
+	front := int(gallery[0].(map[string]interface{})["id"].(float64))
+	side := int(gallery[1].(map[string]interface{})["id"].(float64))
+	if url := imageURL(); url != "https://images.example.com/products/front.png" {
+		t.Fatalf("image_url = %q, want the first upload", url)
+	}
+
+	h.expect(h.Files("POST", images, adminToken, "images", "a.png", "b.png", "c.png"), 400)
+
+	r = h.Form("PUT", images+"/order", adminToken, map[string]string{"image_ids": itoa(side) + "," + itoa(front)})
+	h.expect(r, 200)
+	if n := h.queryInt(`SELECT display_order FROM product_images WHERE id = $1`, side); n != 1 {
+		t.Fatalf("side image position = %d, want 1", n)
+	}
+
+	h.expect(h.JSON("PATCH", images+"/"+itoa(side)+"/primary", adminToken, nil), 200)
+	if url := imageURL(); url != "https://images.example.com/products/side.png" {
+		t.Fatalf("image_url = %q after changing the primary image", url)
+	}
+
+	h.expect(h.JSON("DELETE", images+"/"+itoa(side), adminToken, nil), 200)
+	if url := imageURL(); url != "https://images.example.com/products/front.png" {
+		t.Fatalf("image_url = %q after deleting the primary image", url)
+	}
+	if n := h.queryInt(`SELECT COUNT(*) FROM product_images WHERE product_id = $1 AND is_primary AND display_order = 1`, product); n != 1 {
+		t.Fatalf("remaining gallery has %d primary images in first position", n)
+	}
+
+	r = h.Get("/products/"+itoa(product)+"/detail", "")
+	h.expect(r, 200)
+	if detail := r.Data(); len(detail["images"].([]interface{})) != 1 {
+		t.Fatalf("detail images = %s", r.Raw)
+	}
+}
+
+func TestAdminManagesProductOptions(t *testing.T) {
+	h := newHarness(t)
+	_, token := h.CustomerToken()
+	_, adminToken := h.AdminToken()
+	latte := h.CreateProduct(productFixture{Name: "Latte", Price: 25000, Stock: 5})
+	cookie := h.CreateProduct(productFixture{Name: "Cookie", Price: 15000, Stock: 5})
+	options := "/admin/products/" + itoa(latte) + "/options"
+
+	r := h.Form("POST", options, adminToken, map[string]string{
+		"size_id":          itoa(sizeRegular),
+		"temperature_id":   itoa(temperatureHot),
+		"price_adjustment": "0",
+	})
+	h.expect(r, 201)
+	r = h.Form("POST", options, adminToken, map[string]string{
+		"size_id":          itoa(sizeLarge),
+		"temperature_id":   itoa(temperatureIce),
+		"price_adjustment": "6000",
+	})
+	h.expect(r, 201)
+	icedLarge := int(r.Data()["id"].(float64))
+	h.expect(h.Form("POST", options, adminToken, map[string]string{
+		"size_id":        itoa(sizeLarge),
+		"temperature_id": itoa(temperatureIce),
+	}), 409)
+
+	r = h.Get("/products/"+itoa(latte)+"/detail", "")
+	h.expect(r, 200)
+	detail := r.Data()
+	if len(detail["options"].([]interface{})) != 2 || len(detail["sizes"].([]interface{})) != 2 {
+		t.Fatalf("detail = %s", r.Raw)
+	}
+	r = h.Get("/products/"+itoa(cookie)+"/detail", "")
+	h.expect(r, 200)
+	if sizes := r.Data()["sizes"].([]interface{}); len(sizes) != 0 {
+		t.Fatalf("cookie offers sizes: %s", r.Raw)
+	}
+
+	addToCart := func(product int, fields map[string]string) *response {
+		fields["product_id"], fields["quantity"] = itoa(product), "1"
+		return h.Form("POST", "/cart", token, fields)
+	}
+	h.expect(addToCart(latte, map[string]string{"size_id": itoa(sizeLarge), "temperature_id": itoa(temperatureHot)}), 400)
+	h.expect(addToCart(cookie, map[string]string{"size_id": itoa(sizeLarge)}), 400)
+	h.expect(addToCart(cookie, map[string]string{}), 201)
+	h.expect(addToCart(latte, map[string]string{"size_id": itoa(sizeLarge), "temperature_id": itoa(temperatureIce)}), 201)
+
+	h.expect(h.Form("PATCH", options+"/"+itoa(icedLarge), adminToken, map[string]string{"is_active": "false"}), 200)
+	r = h.Get("/cart", token)
+	h.expect(r, 200)
+	if subtotal := r.Data()["subtotal"]; subtotal != float64(15000) {
+		t.Fatalf("cart subtotal = %v with an unavailable line: %s", subtotal, r.Raw)
+	}
+	h.expect(h.Form("POST", "/transactions/checkout", token, map[string]string{"delivery_method": "dine_in"}), 400)
+
+	h.expect(h.Form("PATCH", options+"/"+itoa(icedLarge), adminToken, map[string]string{
+		"is_active":        "true",
+		"price_adjustment": "8000",
+	}), 200)
+	h.expect(h.Form("POST", "/transactions/checkout", token, map[string]string{"delivery_method": "dine_in"}), 201)
+	if total := h.queryInt(`SELECT total FROM orders`); total != 25000+8000+15000 {
+		t.Fatalf("total = %d", total)
+	}
+
+	h.expect(h.JSON("DELETE", options+"/"+itoa(icedLarge), adminToken, nil), 200)
+	h.expect(h.JSON("DELETE", options+"/"+itoa(icedLarge), adminToken, nil), 404)
+	if n := h.queryInt(`SELECT COUNT(*) FROM audit_log WHERE entity_type = 'product_option'`); n != 5 {
+		t.Fatalf("audit entries = %d", n)
+	}
+}
+
+func TestAdminTracksInventory(t *testing.T) {
+	h := newHarness(t)
+	customer, token := h.CustomerToken()
+	_, adminToken := h.AdminToken()
+	mocha := h.CreateProduct(productFixture{Name: "Mocha", Price: 30000, Stock: 5})
+	h.CreateProduct(productFixture{Name: "Scone", Price: 20000, Stock: 40})
+	product := "/admin/products/" + itoa(mocha)
+
+	h.expect(h.Form("POST", product+"/restock", adminToken, map[string]string{"quantity": "0"}), 400)
+	r := h.Form("POST", product+"/restock", adminToken, map[string]string{"quantity": "10", "reason": "Weekly delivery"})
+	h.expect(r, 201)
+	if after := r.Data()["stock_after"]; after != float64(15) {
+		t.Fatalf("restock = %s", r.Raw)
+	}
+	h.expect(h.Form("POST", product+"/waste", adminToken, map[string]string{"quantity": "1"}), 400)
+	h.expect(h.Form("POST", product+"/waste", adminToken, map[string]string{"quantity": "2", "reason": "Spilled"}), 201)
+	h.expect(h.Form("POST", product+"/stocktake", adminToken, map[string]string{"counted": "13"}), 200)
+	h.expect(h.Form("POST", product+"/stocktake", adminToken, map[string]string{"counted": "12"}), 201)
+	h.expect(h.Form("PATCH", product, adminToken, map[string]string{"low_stock_threshold": "11"}), 200)
+
+	h.AddToCart(customer.ID, mocha, 2)
+	h.expect(h.Form("POST", "/transactions/checkout", token, map[string]string{
+		"delivery_method":   "pick_up",
+		"payment_method_id": itoa(paymentCash),
+	}), 201)
+	orderID := h.queryInt(`SELECT id FROM orders`)
+
+	r = h.Get("/admin/inventory/low-stock", adminToken)
+	h.expect(r, 200)
+	low, _ := r.Body["data"].([]interface{})
+	if len(low) != 1 || low[0].(map[string]interface{})["id"] != float64(mocha) {
+		t.Fatalf("low stock = %s", r.Raw)
+	}
+
+	status := "/admin/orders/" + itoa(orderID) + "/status"
+	h.expect(h.Form("PATCH", status, adminToken, map[string]string{"status": "cancelled"}), 200)
+	h.expect(h.Form("PATCH", status, adminToken, map[string]string{"status": "pending"}), 409)
+	if stock := h.queryInt(`SELECT stock FROM products WHERE id = $1`, mocha); stock != 12 {
+		t.Fatalf("stock = %d, want 12", stock)
+	}
+
+	r = h.Get("/v2"+product+"/stock-movements?limit=3", adminToken)
+	h.expect(r, 200)
+	movements, _ := r.Body["data"].([]interface{})
+	if len(movements) != 3 {
+		t.Fatalf("movements = %s", r.Raw)
+	}
+	for i, want := range []string{"cancellation", "sale", "adjustment"} {
+		if got := movements[i].(map[string]interface{})["type"]; got != want {
+			t.Fatalf("movement %d = %v, want %s: %s", i, got, want, r.Raw)
+		}
+	}
+	if n := h.queryInt(`SELECT COUNT(*) FROM stock_movements WHERE product_id = $1`, mocha); n != 5 {
+		t.Fatalf("ledger has %d entries, want 5", n)
+	}
+	if n := h.queryInt(`SELECT COUNT(*) FROM stock_movements WHERE order_id = $1`, orderID); n != 2 {
+		t.Fatalf("order has %d stock movements, want 2", n)
+	}
+}
+
+func TestAdminSchedulesPriceChanges(t *testing.T) {
+	h := newHarness(t)
+	_, adminToken := h.AdminToken()
+	latte := h.CreateProduct(productFixture{Name: "Latte", Price: 25000, Stock: 10})
+	product := "/admin/products/" + itoa(latte)
+
+	h.expect(h.Form("PATCH", product, adminToken, map[string]string{"price": "27000"}), 200)
+	beforeIncrease := time.Now()
+
+	change := map[string]interface{}{"price": 30000, "effective_at": time.Now().Add(-time.Minute), "reason": "Supplier increase"}
+	h.expect(h.JSON("POST", product+"/scheduled-prices", adminToken, change), 400)
+	change["effective_at"] = time.Now().Add(time.Hour)
+	r := h.JSON("POST", product+"/scheduled-prices", adminToken, change)
+	h.expect(r, 201)
+	changeID := int(r.Data()["id"].(float64))
+	h.expect(h.JSON("POST", product+"/scheduled-prices", adminToken, change), 409)
+	change["effective_at"] = time.Now().Add(2 * time.Hour)
+	r = h.JSON("POST", product+"/scheduled-prices", adminToken, change)
+	h.expect(r, 201)
+	h.expect(h.JSON("DELETE", product+"/scheduled-prices/"+itoa(int(r.Data()["id"].(float64))), adminToken, nil), 200)
+
+	r = h.Get("/admin/scheduled-prices", adminToken)
+	h.expect(r, 200)
+	if scheduled, _ := r.Body["data"].([]interface{}); len(scheduled) != 1 {
+		t.Fatalf("scheduled = %s", r.Raw)
+	}
+
+	h.exec(`UPDATE product_prices SET effective_at = NOW() - INTERVAL '1 minute' WHERE id = $1`, changeID)
+	products := services.NewProductService(repositories.NewPostgresStore(h.db), &fakeImages{}, cache.Noop{})
+	if n, err := products.ApplyScheduledPrices(context.Background(), time.Now()); err != nil || n != 1 {
+		t.Fatalf("apply = %d, %v", n, err)
+	}
+	if price := h.queryInt(`SELECT price FROM products WHERE id = $1`, latte); price != 30000 {
+		t.Fatalf("price = %d, want 30000", price)
+	}
+
+	r = h.Get("/v2"+product+"/price-history", adminToken)
+	h.expect(r, 200)
+	history, _ := r.Body["data"].([]interface{})
+	if len(history) != 2 {
+		t.Fatalf("history = %s", r.Raw)
+	}
+	if latest := history[0].(map[string]interface{}); latest["price"] != float64(30000) || latest["previousPrice"] != float64(27000) {
+		t.Fatalf("latest price = %s", r.Raw)
+	}
+
+	r = h.Get(product+"/price?at="+beforeIncrease.UTC().Format(time.RFC3339Nano), adminToken)
+	h.expect(r, 200)
+	if r.Data()["price"] != float64(27000) {
+		t.Fatalf("price at = %s", r.Raw)
+	}
+	h.expect(h.Get(product+"/price?at=2020-01-01", adminToken), 404)
+	h.expect(h.Get(product+"/price?at=yesterday", adminToken), 400)
+}
+
+func TestAdminImportsAndExportsProducts(t *testing.T) {
+	h := newHarness(t)
+	_, adminToken := h.AdminToken()
+	coffee := h.CreateCategory("Coffee")
+	latte := h.CreateProduct(productFixture{Name: "Latte", CategoryID: coffee, Price: 28000, Stock: 5})
+
+	r := h.Get("/admin/products/export?format=pdf", adminToken)
+	h.expect(r, 400)
+	r = h.Get("/admin/products/export", adminToken)
+	h.expect(r, 200)
+	if !strings.HasPrefix(string(r.Raw), "sku,name,description,category,price,stock") ||
+		!strings.Contains(string(r.Raw), ",Latte,Latte description,Coffee,28000,5,") {
+		t.Fatalf("export = %s", r.Raw)
+	}
+	h.expect(h.Get("/admin/products/export?format=xlsx", adminToken), 200)
+
+	valid := "sku,name,category,price,stock\n" +
+		"LAT-1,Latte,,32000,8\n" +
+		"CB-1,Cold Brew,Coffee,25000,12\n"
+	sheet := []byte(valid + "TEA-1,Tea,Tea,25000,3\n")
+	r = h.Upload("POST", "/admin/products/import", adminToken, "file", "products.csv", sheet, nil)
+	h.expect(r, 200)
+	summary, _ := r.Data()["summary"].(map[string]interface{})
+	if summary["update"] != float64(1) || summary["create"] != float64(1) || summary["error"] != float64(1) {
+		t.Fatalf("dry run = %s", r.Raw)
+	}
+	h.expect(h.Upload("POST", "/admin/products/import", adminToken, "file", "products.csv", sheet,
+		map[string]string{"apply": "true"}), 400)
+	if price := h.queryInt(`SELECT price FROM products WHERE id = $1`, latte); price != 28000 {
+		t.Fatalf("rejected import wrote price %d", price)
+	}
+
+	r = h.Upload("POST", "/v2/admin/products/import", adminToken, "file", "products.csv", []byte(valid),
+		map[string]string{"apply": "true"})
+	h.expect(r, 200)
+	if r.Data()["applied"] != true {
+		t.Fatalf("apply = %s", r.Raw)
+	}
+	if sku := h.queryString(`SELECT sku FROM products WHERE id = $1`, latte); sku != "LAT-1" {
+		t.Fatalf("sku = %q", sku)
+	}
+	if stock := h.queryInt(`SELECT stock FROM products WHERE id = $1`, latte); stock != 8 {
+		t.Fatalf("stock = %d, want 8", stock)
+	}
+	if n := h.queryInt(`SELECT COUNT(*) FROM stock_movements WHERE reason = 'Product import'`); n != 1 {
+		t.Fatalf("import recorded %d stock movements, want 1", n)
+	}
+	if n := h.queryInt(`SELECT COUNT(*) FROM products WHERE sku = 'CB-1' AND stock = 12`); n != 1 {
+		t.Fatalf("cold brew created %d times", n)
+	}
+}
+
+func TestFlashSalePricesProductsAndCheckout(t *testing.T) {
+	h := newHarness(t)
+	_, adminToken := h.AdminToken()
+	customer, token := h.CustomerToken()
+	latte := h.CreateProduct(productFixture{Name: "Latte", Price: 20000, Stock: 10})
+
+	now := time.Now()
+	sale := map[string]interface{}{
+		"name":      "Payday",
+		"starts_at": now.Add(-time.Hour),
+		"ends_at":   now.Add(time.Hour),
+		"items":     []map[string]interface{}{{"product_id": latte, "sale_price": 20000}},
+	}
+	h.expect(h.JSON("POST", "/admin/flash-sales", adminToken, sale), 400)
+	sale["items"] = []map[string]interface{}{{"product_id": latte, "discount_percent": 25, "max_per_customer": 2}}
+	r := h.JSON("POST", "/admin/flash-sales", adminToken, sale)
+	h.expect(r, 201)
+	saleID := int(r.Data()["id"].(float64))
+	h.expect(h.JSON("POST", "/admin/flash-sales", adminToken, sale), 409)
+
+	r = h.Get("/v2/products/"+itoa(latte), "")
+	h.expect(r, 200)
+	flash, _ := r.Data()["flashSale"].(map[string]interface{})
+	if r.Data()["price"] != float64(20000) || flash["price"] != float64(15000) || flash["flashSaleId"] != float64(saleID) {
+		t.Fatalf("product = %s", r.Raw)
+	}
+
+	r = h.Get("/v2/flash-sales/active", "")
+	h.expect(r, 200)
+	active, _ := r.Data()["flashSales"].([]interface{})
+	if len(active) != 1 || active[0].(map[string]interface{})["endsInSeconds"].(float64) <= 0 {
+		t.Fatalf("active = %s", r.Raw)
+	}
+
+	h.expect(h.Form("POST", "/cart", token, map[string]string{"product_id": itoa(latte), "quantity": "3"}), 400)
+	h.expect(h.Form("POST", "/cart", token, map[string]string{"product_id": itoa(latte), "quantity": "2"}), 201)
+	h.expect(h.Form("POST", "/transactions/checkout", token, map[string]string{"payment_method_id": itoa(paymentCash)}), 201)
+	if total := h.queryInt(`SELECT total FROM orders WHERE user_id = $1`, customer.ID); total != 30000 {
+		t.Fatalf("total = %d, want 30000", total)
+	}
+	if n := h.queryInt(`SELECT COUNT(*) FROM order_items WHERE flash_sale_id = $1 AND is_flash_sale`, saleID); n != 1 {
+		t.Fatalf("%d order items linked to the flash sale, want 1", n)
+	}
+	h.expect(h.Form("POST", "/cart", token, map[string]string{"product_id": itoa(latte), "quantity": "1"}), 400)
+
+	h.expect(h.JSON("DELETE", "/admin/flash-sales/"+itoa(saleID), adminToken, nil), 200)
+	r = h.Get("/v2/products/"+itoa(latte), "")
+	h.expect(r, 200)
+	if _, ok := r.Data()["flashSale"]; ok {
+		t.Fatalf("deleted flash sale still prices the product: %s", r.Raw)
+	}
+}
+
+func TestPromotionRulesDiscountCheckout(t *testing.T) {
+	h := newHarness(t)
+	_, adminToken := h.AdminToken()
+	customer, token := h.CustomerToken()
+	pastry := h.CreateCategory("Pastry")
+	croissant := h.CreateProduct(productFixture{Name: "Croissant", CategoryID: pastry, Price: 18000, Stock: 10})
+	muffin := h.CreateProduct(productFixture{Name: "Muffin", CategoryID: pastry, Price: 15000, Stock: 10})
+	cookie := h.CreateProduct(productFixture{Name: "Cookie", Price: 8000, Stock: 10, IsBuy1Get1: true})
+
+	rule := map[string]interface{}{
+		"name": "Pastry 2+1", "type": "mix_and_match", "product_id": croissant, "category_id": pastry,
+		"buy_quantity": 2, "get_quantity": 1,
+	}
+	h.expect(h.JSON("POST", "/admin/promotion-rules", adminToken, rule), 400)
+	delete(rule, "product_id")
+	r := h.JSON("POST", "/admin/promotion-rules", adminToken, rule)
+	h.expect(r, 201)
+	ruleID := int(r.Data()["id"].(float64))
+
+	h.AddToCart(customer.ID, croissant, 2)
+	h.AddToCart(customer.ID, muffin, 1)
+	h.AddToCart(customer.ID, cookie, 2)
+
+	r = h.Get("/cart", token)
+	h.expect(r, 200)
+	// The cheapest pastry and the second cookie are free.
+	if r.Data()["subtotal"] != float64(67000) || r.Data()["discount"] != float64(23000) || r.Data()["total"] != float64(44000) {
+		t.Fatalf("cart = %s", r.Raw)
+	}
+	adjustments, _ := r.Data()["adjustments"].([]interface{})
+	if len(adjustments) != 2 {
+		t.Fatalf("adjustments = %s", r.Raw)
+	}
+
+	h.expect(h.Form("POST", "/transactions/checkout", token, map[string]string{"payment_method_id": itoa(paymentCash)}), 201)
+	if total := h.queryInt(`SELECT total FROM orders WHERE user_id = $1`, customer.ID); total != 44000 {
+		t.Fatalf("total = %d, want 44000", total)
+	}
+	if n := h.queryInt(`SELECT discount_amount FROM order_items WHERE product_id = $1 AND promotion_rule_id = $2`,
+		muffin, ruleID); n != 15000 {
+		t.Fatalf("muffin discount = %d, want 15000", n)
+	}
+	if name := h.queryString(`SELECT promotion_name FROM order_items WHERE product_id = $1`, cookie); name != "Buy 1 Get 1" {
+		t.Fatalf("cookie promotion = %q", name)
+	}
+}
+
+func TestCustomerFavouritesProducts(t *testing.T) {
+	h := newHarness(t)
+	_, token := h.CustomerToken()
+	category := h.CreateCategory("Coffee")
+	latte := h.CreateProduct(productFixture{Name: "Latte", CategoryID: category, Price: 25000, Stock: 10, IsFeatured: true})
+	mocha := h.CreateProduct(productFixture{Name: "Mocha", CategoryID: category, Price: 28000, Stock: 10})
+
+	h.expect(h.JSON("POST", "/profile/favorites/"+itoa(latte), "", nil), 401)
+	h.expect(h.JSON("POST", "/profile/favorites/999", token, nil), 404)
+	h.expect(h.JSON("POST", "/profile/favorites/"+itoa(mocha), token, nil), 201)
+	h.expect(h.JSON("POST", "/profile/favorites/"+itoa(mocha), token, nil), 200)
+
+	r := h.Get("/v2/profile/favorites", token)
+	h.expect(r, 200)
+	items, _ := r.Body["data"].([]interface{})
+	if len(items) != 1 || items[0].(map[string]interface{})["isFavorited"] != true {
+		t.Fatalf("favorites = %s", r.Raw)
+	}
+
+	// Anonymous lists carry no flag; signed-in ones mark the favourites.
+	r = h.Get("/products", "")
+	h.expect(r, 200)
+	for _, item := range r.Body["data"].([]interface{}) {
+		if _, ok := item.(map[string]interface{})["is_favorited"]; ok {
+			t.Fatalf("anonymous list = %s", r.Raw)
+		}
+	}
+	r = h.Get("/products", token)
+	h.expect(r, 200)
+	for _, item := range r.Body["data"].([]interface{}) {
+		p := item.(map[string]interface{})
+		if p["is_favorited"] != (p["id"] == float64(mocha)) {
+			t.Fatalf("signed-in list = %s", r.Raw)
+		}
+	}
+
+	r = h.Get("/products/"+itoa(latte), token)
+	h.expect(r, 200)
+	if r.Data()["is_favorited"] != false || r.Data()["is_favorite"] != true {
+		t.Fatalf("latte = %s", r.Raw)
+	}
+
+	h.expect(h.JSON("DELETE", "/profile/favorites/"+itoa(mocha), token, nil), 200)
+	h.expect(h.JSON("DELETE", "/profile/favorites/"+itoa(mocha), token, nil), 404)
+	r = h.Get("/profile/favorites", token)
+	h.expect(r, 200)
+	if meta, _ := r.Body["meta"].(map[string]interface{}); meta["total_items"] != float64(0) {
+		t.Fatalf("favorites after removal = %s", r.Raw)
+	}
+}
+
+func TestCustomerReviewsAndModeration(t *testing.T) {
+	h := newHarness(t)
+	_, adminToken := h.AdminToken()
+	customer, token := h.CustomerToken()
+	latte := h.CreateProduct(productFixture{Name: "Latte", Price: 25000, Stock: 10})
+	reviews := "/products/" + itoa(latte) + "/reviews"
+
+	h.expect(h.JSON("POST", reviews, "", map[string]interface{}{"rating": 4}), 401)
+	h.expect(h.JSON("POST", reviews, token, map[string]interface{}{"rating": 4}), 403)
+
+	h.AddToCart(customer.ID, latte, 1)
+	h.expect(h.Form("POST", "/transactions/checkout", token, map[string]string{"payment_method_id": itoa(paymentCash)}), 201)
+	orderID := h.queryInt(`SELECT id FROM orders WHERE user_id = $1`, customer.ID)
+	h.expect(h.JSON("POST", reviews, token, map[string]interface{}{"rating": 4}), 403)
+	h.expect(h.Form("PATCH", "/admin/orders/"+itoa(orderID)+"/status", adminToken, map[string]string{"status": "completed"}), 200)
+
+	h.expect(h.JSON("POST", reviews, token, map[string]interface{}{"rating": 6}), 400)
+	r := h.JSON("POST", reviews, token, map[string]interface{}{"rating": 4, "text": "Smooth"})
+	h.expect(r, 201)
+	if r.Data()["status"] != "pending" {
+		t.Fatalf("new review = %s", r.Raw)
+	}
+	reviewID := int(r.Data()["id"].(float64))
+	h.expect(h.JSON("POST", reviews, token, map[string]interface{}{"rating": 5}), 409)
+
+	r = h.Get(reviews, "")
+	h.expect(r, 200)
+	if meta, _ := r.Body["meta"].(map[string]interface{}); meta["total_items"] != float64(0) {
+		t.Fatalf("pending review is public: %s", r.Raw)
+	}
+
+	r = h.Get("/admin/reviews", adminToken)
+	h.expect(r, 200)
+	if queue, _ := r.Body["data"].([]interface{}); len(queue) != 1 {
+		t.Fatalf("queue = %s", r.Raw)
+	}
+	r = h.JSON("PATCH", "/admin/reviews/"+itoa(reviewID), adminToken, map[string]interface{}{"status": "approved", "reply": "Thank you!"})
+	h.expect(r, 200)
+
+	r = h.Get("/v2"+reviews, "")
+	h.expect(r, 200)
+	items, _ := r.Body["data"].([]interface{})
+	ratings, _ := r.Body["ratings"].(map[string]interface{})
+	if len(items) != 1 || items[0].(map[string]interface{})["reply"] != "Thank you!" || ratings["average"] != float64(4) || ratings["count"] != float64(1) {
+		t.Fatalf("approved reviews = %s", r.Raw)
+	}
+	r = h.Get("/products/"+itoa(latte), "")
+	h.expect(r, 200)
+	if r.Data()["average_rating"] != float64(4) || r.Data()["review_count"] != float64(1) {
+		t.Fatalf("product rating = %s", r.Raw)
+	}
+
+	// An edit goes back to the queue and out of the rating.
+	r = h.JSON("PATCH", reviews+"/"+itoa(reviewID), token, map[string]interface{}{"rating": 2})
+	h.expect(r, 200)
+	if r.Data()["status"] != "pending" || r.Data()["rating"] != float64(2) {
+		t.Fatalf("edited review = %s", r.Raw)
+	}
+	if n := h.queryInt(`SELECT review_count FROM products WHERE id = $1`, latte); n != 0 {
+		t.Fatalf("review_count = %d after edit, want 0", n)
+	}
+
+	h.expect(h.JSON("DELETE", reviews+"/"+itoa(reviewID), adminToken, nil), 404)
+	h.expect(h.JSON("DELETE", reviews+"/"+itoa(reviewID), token, nil), 200)
+	if n := h.queryInt(`SELECT COUNT(*) FROM product_reviews`); n != 0 {
+		t.Fatalf("reviews = %d after delete", n)
+	}
+}
+
+func TestProductRecommendations(t *testing.T) {
+	h := newHarness(t)
+	_, adminToken := h.AdminToken()
+	customer, token := h.CustomerToken()
+	coffee := h.CreateCategory("Coffee")
+	pastry := h.CreateCategory("Pastry")
+	latte := h.CreateProduct(productFixture{Name: "Latte", CategoryID: coffee, Price: 25000, Stock: 10})
+	mocha := h.CreateProduct(productFixture{Name: "Mocha", CategoryID: coffee, Price: 30000, Stock: 10})
+	h.CreateProduct(productFixture{Name: "Americano", CategoryID: coffee, Price: 20000, Stock: 10})
+	cookie := h.CreateProduct(productFixture{Name: "Cookie", CategoryID: pastry, Price: 12000, Stock: 10})
+	pie := h.CreateProduct(productFixture{Name: "Hand Pie", CategoryID: pastry, Price: 18000, Stock: 10})
+	curated := "/admin/products/" + itoa(latte) + "/recommendations"
+
+	h.expect(h.Form("POST", curated, token, map[string]string{"recommended_product_id": itoa(mocha)}), 403)
+	h.expect(h.Form("POST", curated, adminToken, map[string]string{"recommended_product_id": itoa(latte), "recommendation_type": "upsell"}), 400)
+	h.expect(h.Form("POST", curated, adminToken, map[string]string{"recommended_product_id": itoa(mocha), "recommendation_type": "bundle"}), 400)
+	h.expect(h.Form("POST", curated, adminToken, map[string]string{"recommended_product_id": itoa(mocha), "recommendation_type": "upsell", "priority": "5"}), 201)
+	r := h.Form("POST", curated, adminToken, map[string]string{"recommended_product_id": itoa(cookie), "recommendation_type": "pairing", "priority": "10"})
+	h.expect(r, 201)
+	link := curated + "/" + itoa(int(r.Data()["id"].(float64)))
+	h.expect(h.Form("POST", curated, adminToken, map[string]string{"recommended_product_id": itoa(mocha), "recommendation_type": "pairing"}), 409)
+
+	r = h.Get(curated, adminToken)
+	h.expect(r, 200)
+	if links, _ := r.Body["data"].([]interface{}); len(links) != 2 || links[0].(map[string]interface{})["recommended_product_id"] != float64(cookie) {
+		t.Fatalf("curated = %s", r.Raw)
+	}
+
+	// Two orders with the latte and the hand pie make them a pair.
+	for range 2 {
+		h.AddToCart(customer.ID, latte, 1)
+		h.AddToCart(customer.ID, pie, 1)
+		h.expect(h.Form("POST", "/transactions/checkout", token, map[string]string{"payment_method_id": itoa(paymentCash)}), 201)
+	}
+	products := services.NewProductService(repositories.NewPostgresStore(h.db), &fakeImages{}, cache.Noop{})
+	if n, err := products.RefreshBoughtTogether(context.Background(), time.Now()); err != nil || n != 1 {
+		t.Fatalf("refresh = %d, %v", n, err)
+	}
+
+	r = h.Get("/products/"+itoa(latte)+"/recommendations", "")
+	h.expect(r, 200)
+	if got := recommendationNames(r); got != "Cookie:pairing,Mocha:upsell,Hand Pie:bought_together,Americano:same_category" {
+		t.Fatalf("recommendations = %s", got)
+	}
+	r = h.Get("/v2/products/"+itoa(latte)+"/recommendations?limit=1", "")
+	h.expect(r, 200)
+	if items, _ := r.Body["data"].([]interface{}); len(items) != 1 || items[0].(map[string]interface{})["recommendationType"] != "pairing" {
+		t.Fatalf("v2 recommendations = %s", r.Raw)
+	}
+
+	// The cart leaves upsells out.
+	h.expect(h.Get("/cart/recommendations", ""), 401)
+	h.AddToCart(customer.ID, latte, 1)
+	r = h.Get("/cart/recommendations", token)
+	h.expect(r, 200)
+	if got := recommendationNames(r); !strings.HasPrefix(got, "Cookie:pairing,Hand Pie:bought_together,") || strings.Contains(got, "upsell") {
+		t.Fatalf("cart recommendations = %s", got)
+	}
+
+	h.expect(h.Form("PATCH", link, adminToken, map[string]string{"is_active": "false"}), 200)
+	r = h.Get("/products/"+itoa(latte)+"/recommendations", "")
+	h.expect(r, 200)
+	if got := recommendationNames(r); strings.Contains(got, "pairing") {
+		t.Fatalf("inactive link shown: %s", got)
+	}
+	h.expect(h.JSON("DELETE", link, adminToken, nil), 200)
+	h.expect(h.JSON("DELETE", link, adminToken, nil), 404)
+}
+
+// recommendationNames joins the names and recommendation types of a product
+// list, in order.
+func recommendationNames(r *response) string {
+	items, _ := r.Body["data"].([]interface{})
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		p := item.(map[string]interface{})
+		names = append(names, fmt.Sprintf("%v:%v", p["name"], p["recommendation_type"]))
+	}
+	return strings.Join(names, ",")
+}
+
+func TestRecentlyViewedProducts(t *testing.T) {
+	h := newHarness(t)
+	_, adminToken := h.AdminToken()
+	_, token := h.CustomerToken()
+	latte := h.CreateProduct(productFixture{Name: "Latte", Price: 25000, Stock: 10})
+	mocha := h.CreateProduct(productFixture{Name: "Mocha", Price: 30000, Stock: 10})
+	matcha := h.CreateProduct(productFixture{Name: "Matcha", Price: 20000, Stock: 10})
+
+	h.expect(h.Get("/profile/recently-viewed", ""), 401)
+	h.expect(h.Get("/products/"+itoa(matcha), ""), 200)
+	h.expect(h.Get("/products/"+itoa(latte), token), 200)
+	h.expect(h.Get("/products/"+itoa(mocha)+"/detail", token), 200)
+	h.expect(h.Get("/products/"+itoa(latte), token), 200)
+	h.expect(h.Get("/products/999999", token), 404)
+	if n := h.queryInt(`SELECT COUNT(*) FROM product_views`); n != 3 {
+		t.Fatalf("views = %d, want 3", n)
+	}
+
+	r := h.Get("/profile/recently-viewed", token)
+	h.expect(r, 200)
+	if items, _ := r.Body["data"].([]interface{}); len(items) != 2 || items[0].(map[string]interface{})["name"] != "Latte" ||
+		items[1].(map[string]interface{})["name"] != "Mocha" {
+		t.Fatalf("recently viewed = %s", r.Raw)
+	}
+	h.expect(h.JSON("DELETE", "/admin/products/"+itoa(latte), adminToken, nil), 200)
+	r = h.Get("/v2/profile/recently-viewed", token)
+	h.expect(r, 200)
+	if items, _ := r.Body["data"].([]interface{}); len(items) != 1 || items[0].(map[string]interface{})["name"] != "Mocha" {
+		t.Fatalf("recently viewed after archiving latte = %s", r.Raw)
+	}
+
+	// Mocha has two viewers, matcha only an anonymous one.
+	h.expect(h.Get("/products/"+itoa(mocha), adminToken), 200)
+	products := services.NewProductService(repositories.NewPostgresStore(h.db), &fakeImages{}, cache.Noop{})
+	if _, err := products.RefreshPopularity(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if n := h.queryInt(`SELECT viewers FROM product_view_daily WHERE product_id = $1`, mocha); n != 2 {
+		t.Fatalf("daily viewers of mocha = %d, want 2", n)
+	}
+	r = h.Get("/products/filter?sort=popular", "")
+	h.expect(r, 200)
+	if items, _ := r.Body["data"].([]interface{}); len(items) != 2 || items[0].(map[string]interface{})["id"] != float64(mocha) {
+		t.Fatalf("popular = %s", r.Raw)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	h := newHarness(t)
+	_, adminToken := h.AdminToken()
+	_, customerToken := h.CustomerToken()
+	coffee := h.CreateCategory("Coffee")
+	latte := h.CreateProduct(productFixture{Name: "Latte", CategoryID: coffee, Price: 25000, Stock: 5})
+
+	r := h.Get("/categories", "")
+	h.expect(r, 200)
+	etag, modified := r.Header.Get("ETag"), r.Header.Get("Last-Modified")
+	if etag == "" || modified == "" || r.Header.Get("Cache-Control") != "public, max-age=300" {
+		t.Fatalf("category headers = %v", r.Header)
+	}
+	r = h.GetWithHeader("/categories", "If-None-Match", etag)
+	h.expect(r, 304)
+	if len(r.Raw) != 0 || r.Header.Get("ETag") != etag {
+		t.Fatalf("304 = %v %q", r.Header, r.Raw)
+	}
+	h.expect(h.GetWithHeader("/categories", "If-Modified-Since", modified), 304)
+	r = h.GetWithHeader("/v2/categories", "If-Modified-Since", modified)
+	h.expect(r, 304)
+	if r.Header.Get("ETag") == "" || r.Header.Get("Cache-Control") != "public, max-age=300" {
+		t.Fatalf("v2 category headers = %v", r.Header)
+	}
+
+	h.expect(h.Form("PATCH", "/admin/categories/"+itoa(coffee), adminToken, map[string]string{"name": "Kopi"}), 200)
+	r = h.GetWithHeader("/categories", "If-None-Match", etag)
+	h.expect(r, 200)
+	if r.Header.Get("ETag") == etag || len(r.Body["data"].([]interface{})) != 1 {
+		t.Fatalf("after rename = %v %s", r.Header, r.Raw)
+	}
+
+	path := "/products/" + itoa(latte) + "/detail"
+	r = h.Get(path, "")
+	h.expect(r, 200)
+	etag = r.Header.Get("ETag")
+	if r.Header.Get("Cache-Control") != "public, no-cache" {
+		t.Fatalf("detail headers = %v", r.Header)
+	}
+	h.expect(h.GetWithHeader(path, "If-None-Match", `W/"other", `+etag), 304)
+
+	h.expect(h.Form("PATCH", "/admin/products/"+itoa(latte), adminToken, map[string]string{"price": "27000"}), 200)
+	h.expect(h.GetWithHeader(path, "If-None-Match", etag), 200)
+
+	// A signed-in customer's copy is personalised.
+	r = h.Get(path, customerToken)
+	h.expect(r, 200)
+	if r.Header.Get("Cache-Control") != "private, no-cache" || r.Header.Get("Last-Modified") != "" {
+		t.Fatalf("signed-in detail headers = %v", r.Header)
+	}
+	h.expect(h.Get("/products/999999/detail", ""), 404)
+}
+
+func TestLanguagePreferenceAppliesToExistingToken(t *testing.T) {
+	h := newHarness(t)
+	_, token := h.CustomerToken()
+
+	r := h.Get("/profile", token)
+	h.expect(r, 200)
+	if got := r.Header.Get("Content-Language"); got != "en" {
+		t.Fatalf("Content-Language before = %q", got)
+	}
+
+	h.expect(h.Form("PATCH", "/profile", token, map[string]string{"language": "id"}), 200)
+
+	// The token issued before the change still carries the old language.
+	r = h.Get("/profile", token)
+	h.expect(r, 200)
+	if got := r.Header.Get("Content-Language"); got != "id" {
+		t.Fatalf("Content-Language after = %q", got)
+	}
+	r = h.Get("/profile?lang=en", token)
+	if got := r.Header.Get("Content-Language"); got != "en" {
+		t.Fatalf("explicit ?lang= = %q", got)
+	}
+}
@@ -0,0 +1,36 @@
+package e2e
+
+import (
+	"coffee-shop/database/migration"
+	"coffee-shop/database/seed"
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestSeedLoadsFixturesWithUsablePasswords(t *testing.T) {
+	h := newHarness(t)
+	h.truncate()
+	// The orders in the fixtures need the statuses a migration inserts.
+	statuses, err := fs.ReadFile(migration.Files, "000021_order_statuses.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.exec(string(statuses))
+
+	users, err := seed.Load(context.Background(), h.db, "demo-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users == 0 {
+		t.Fatal("no users seeded")
+	}
+
+	h.Login(userFixture{Email: "admin@harlanholden.com", Password: "demo-password"})
+	h.expect(h.Get("/products", ""), 200)
+
+	if _, err := seed.Load(context.Background(), h.db, "demo-password"); !errors.Is(err, seed.ErrNotEmpty) {
+		t.Fatalf("second load error = %v, want ErrNotEmpty", err)
+	}
+}
@@ -0,0 +1,202 @@
+package e2e
+
+import (
+	"coffee-shop/libs"
+	"coffee-shop/services"
+	"context"
+	"mime/multipart"
+	"sync"
+)
+
+// fixturePassword is the password of every user created by the fixtures.
+const fixturePassword = "Secret123!"
+
+// Reference data seeded after every reset. IDs are stable because the
+// tables are truncated with RESTART IDENTITY.
+const (
+	statusPending   = 1
+	statusCompleted = 2
+	paymentCash     = 1
+	sizeRegular     = 1
+	sizeLarge       = 2
+	temperatureHot  = 1
+	temperatureIce  = 2
+)
+
+func (h *harness) seedReferenceData() {
+	h.t.Helper()
+	h.exec(`INSERT INTO order_status (name, display_name, display_order) VALUES
+		('pending', 'Pending', 1),
+		('completed', 'Completed', 2),
+		('cancelled', 'Cancelled', 3)`)
+	h.exec(`INSERT INTO delivery_methods (name, base_fee) VALUES
+		('Dine In', 0), ('Door Delivery', 10000), ('Pick Up', 0)`)
+	h.exec(`INSERT INTO payment_methods (name) VALUES ('Cash'), ('Bank Transfer')`)
+	h.exec(`INSERT INTO product_sizes (name, price_adjustment) VALUES ('Regular', 0), ('Large', 5000)`)
+	h.exec(`INSERT INTO product_temperatures (name, price) VALUES ('Hot', 0), ('Ice', 2000)`)
+}
+
+func (h *harness) exec(sql string, args ...interface{}) {
+	h.t.Helper()
+	if _, err := h.db.Exec(context.Background(), sql, args...); err != nil {
+		h.t.Fatalf("%v\n%s", err, sql)
+	}
+}
+
+func (h *harness) queryInt(sql string, args ...interface{}) int {
+	h.t.Helper()
+	var n int
+	if err := h.db.QueryRow(context.Background(), sql, args...).Scan(&n); err != nil {
+		h.t.Fatalf("%v\n%s", err, sql)
+	}
+	return n
+}
+
+func (h *harness) queryString(sql string, args ...interface{}) string {
+	h.t.Helper()
+	var s string
+	if err := h.db.QueryRow(context.Background(), sql, args...).Scan(&s); err != nil {
+		h.t.Fatalf("%v\n%s", err, sql)
+	}
+	return s
+}
+
+type userFixture struct {
+	ID       int
+	Email    string
+	Password string
+	Role     string
+}
+
+// CreateUser inserts a user with a profile directly into the database.
+func (h *harness) CreateUser(email, role string) userFixture {
+	h.t.Helper()
+	hash, err := libs.HashPassword(fixturePassword)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	u := userFixture{Email: email, Password: fixturePassword, Role: role}
+	u.ID = h.queryInt(
+		`INSERT INTO users (email, password, role, created_at) VALUES ($1, $2, $3, NOW()) RETURNING id`,
+		email, hash, role)
+	h.exec(
+		`INSERT INTO user_profiles (user_id, full_name, phone, address) VALUES ($1, $2, '081234567890', 'Jl. Kopi No. 1')`,
+		u.ID, "Test "+role)
+	return u
+}
+
+// Login signs u in through the API and returns the bearer token.
+func (h *harness) Login(u userFixture) string {
+	h.t.Helper()
+	r := h.JSON("POST", "/auth/login", "", map[string]string{"email": u.Email, "password": u.Password})
+	h.expect(r, 200)
+
+	token, _ := r.Data()["token"].(string)
+	if token == "" {
+		h.t.Fatalf("login returned no token: %s", r.Raw)
+	}
+	return token
+}
+
+// CustomerToken creates a customer and returns it with a token.
+func (h *harness) CustomerToken() (userFixture, string) {
+	h.t.Helper()
+	u := h.CreateUser("customer@example.com", "customer")
+	return u, h.Login(u)
+}
+
+// AdminToken creates an admin and returns it with a token.
+func (h *harness) AdminToken() (userFixture, string) {
+	h.t.Helper()
+	u := h.CreateUser("admin@example.com", "admin")
+	return u, h.Login(u)
+}
+
+// CreateCategory inserts an active category and returns its ID.
+func (h *harness) CreateCategory(name string) int {
+	h.t.Helper()
+	return h.queryInt(`INSERT INTO categories (name) VALUES ($1) RETURNING id`, name)
+}
+
+type productFixture struct {
+	Name        string
+	CategoryID  int
+	Price       int
+	Stock       int
+	IsFlashSale bool
+	IsFeatured  bool
+	IsBuy1Get1  bool
+}
+
+// CreateProduct inserts an active product and returns its ID. A category is
+// created when p.CategoryID is zero.
+func (h *harness) CreateProduct(p productFixture) int {
+	h.t.Helper()
+	if p.CategoryID == 0 {
+		p.CategoryID = h.CreateCategory("Category for " + p.Name)
+	}
+	return h.queryInt(
+		`INSERT INTO products (name, description, category_id, price, stock, is_flash_sale, is_featured, is_buy1get1, is_active)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, true) RETURNING id`,
+		p.Name, p.Name+" description", p.CategoryID, p.Price, p.Stock, p.IsFlashSale, p.IsFeatured, p.IsBuy1Get1)
+}
+
+// AddToCart puts a line without options straight into the user's cart.
+func (h *harness) AddToCart(userID, productID, quantity int) {
+	h.t.Helper()
+	h.exec(
+		`INSERT INTO cart_items (user_id, product_id, quantity) VALUES ($1, $2, $3)`,
+		userID, productID, quantity)
+}
+
+// AddReview records an approved review and refreshes the product's rating.
+func (h *harness) AddReview(productID, userID, rating int) {
+	h.t.Helper()
+	h.exec(
+		`INSERT INTO product_reviews (product_id, user_id, rating, status) VALUES ($1, $2, $3, 'approved')`,
+		productID, userID, rating)
+	h.exec(
+		`UPDATE products SET average_rating = r.average, review_count = r.count
+		 FROM (SELECT AVG(rating) AS average, COUNT(*) AS count FROM product_reviews
+		       WHERE product_id = $1 AND status = 'approved') r
+		 WHERE id = $1`, productID)
+}
+
+// AddOption offers the product in a size and temperature for a surcharge
+// and returns the option ID.
+func (h *harness) AddOption(productID, sizeID, temperatureID, priceAdjustment int) int {
+	h.t.Helper()
+	return h.queryInt(
+		`INSERT INTO product_details (product_id, size_id, temperature_id, price_adjustment)
+		 VALUES ($1, $2, $3, $4) RETURNING id`,
+		productID, sizeID, temperatureID, priceAdjustment)
+}
+
+// fakeImages accepts every upload and remembers what it stored.
+type fakeImages struct {
+	mu      sync.Mutex
+	deleted []string
+}
+
+func (f *fakeImages) Validate(*multipart.FileHeader) error { return nil }
+
+func (f *fakeImages) Upload(_ context.Context, _ multipart.File, filename, folder string) (string, string, error) {
+	return "https://images.example.com/" + folder + "/" + filename, folder + "/" + filename, nil
+}
+
+func (f *fakeImages) UploadMany(ctx context.Context, headers []*multipart.FileHeader, folder string) ([]services.UploadedImage, error) {
+	uploaded := []services.UploadedImage{}
+	for _, h := range headers {
+		url, id, _ := f.Upload(ctx, nil, h.Filename, folder)
+		uploaded = append(uploaded, services.UploadedImage{URL: url, ID: id})
+	}
+	return uploaded, nil
+}
+
+func (f *fakeImages) Delete(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, id)
+	return nil
+}
@@ -0,0 +1,369 @@
+package e2e
+
+import (
+	"bytes"
+	"coffee-shop/cache"
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"coffee-shop/routes"
+	"coffee-shop/services"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// env is shared by every test in the package. It is nil when
+// TEST_DATABASE_URL is not set.
+var env *environment
+
+type environment struct {
+	adminDSN string
+	dbName   string
+	db       *pgxpool.Pool
+	redis    *redis.Client
+	server   *httptest.Server
+}
+
+func TestMain(m *testing.M) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		log.Println("TEST_DATABASE_URL not set; skipping end-to-end tests")
+		os.Exit(m.Run())
+	}
+
+	var err error
+	env, err = setup(dsn, os.Getenv("TEST_REDIS_ADDR"))
+	if err != nil {
+		log.Fatalf("e2e setup failed: %v", err)
+	}
+
+	code := m.Run()
+	env.teardown()
+	os.Exit(code)
+}
+
+// setup creates a uniquely named database next to the one in dsn, migrates
+// it and starts the router against it.
+func setup(dsn, redisAddr string) (*environment, error) {
+	ctx := context.Background()
+	e := &environment{
+		adminDSN: dsn,
+		dbName:   fmt.Sprintf("coffee_shop_e2e_%d", time.Now().UnixNano()),
+	}
+
+	admin, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close()
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+e.dbName); err != nil {
+		return nil, fmt.Errorf("create database: %w", err)
+	}
+
+	testDSN, err := withDatabase(dsn, e.dbName)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.Migrate(testDSN); err != nil {
+		e.teardown()
+		return nil, err
+	}
+
+	e.db, err = pgxpool.New(ctx, testDSN)
+	if err != nil {
+		e.teardown()
+		return nil, err
+	}
+	models.DB = e.db
+
+	responses := cache.Store(cache.NewMemory())
+	if redisAddr != "" {
+		e.redis = redis.NewClient(&redis.Options{Addr: redisAddr, DB: 15})
+		if err := e.redis.Ping(ctx).Err(); err != nil {
+			e.teardown()
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+		models.RedisClient = e.redis
+		responses = cache.NewRedis(e.redis)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.Recovery())
+	routes.RegisterRoutes(router, routes.Dependencies{
+		Store:  repositories.NewPostgresStore(e.db),
+		Cache:  responses,
+		Images: &fakeImages{},
+		OTPs:   services.NewRedisOTPs(e.redis),
+	})
+	e.server = httptest.NewServer(router)
+	return e, nil
+}
+
+func (e *environment) teardown() {
+	if e.server != nil {
+		e.server.Close()
+	}
+	if e.redis != nil {
+		_ = e.redis.FlushDB(context.Background()).Err()
+		_ = e.redis.Close()
+		models.RedisClient = nil
+	}
+	if e.db != nil {
+		e.db.Close()
+		models.DB = nil
+	}
+
+	ctx := context.Background()
+	admin, err := pgxpool.New(ctx, e.adminDSN)
+	if err != nil {
+		log.Printf("e2e teardown: %v", err)
+		return
+	}
+	defer admin.Close()
+	if _, err := admin.Exec(ctx, "DROP DATABASE IF EXISTS "+e.dbName+" WITH (FORCE)"); err != nil {
+		log.Printf("e2e teardown: %v", err)
+	}
+}
+
+func withDatabase(dsn, name string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("parse TEST_DATABASE_URL: %w", err)
+	}
+	u.Path = "/" + name
+	return u.String(), nil
+}
+
+// harness is the per-test view of the environment. Every test starts from
+// freshly truncated tables holding only the reference data.
+type harness struct {
+	t  *testing.T
+	db *pgxpool.Pool
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	if env == nil {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	h := &harness{t: t, db: env.db}
+	h.reset()
+	return h
+}
+
+func (h *harness) reset() {
+	h.t.Helper()
+	h.truncate()
+	h.seedReferenceData()
+}
+
+// truncate empties every table, including the reference data.
+func (h *harness) truncate() {
+	h.t.Helper()
+	ctx := context.Background()
+
+	rows, err := h.db.Query(ctx,
+		`SELECT tablename FROM pg_tables
+		 WHERE schemaname = 'public' AND tablename <> 'schema_migrations'`)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			h.t.Fatal(err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+
+	if len(tables) > 0 {
+		if _, err := h.db.Exec(ctx, "TRUNCATE "+strings.Join(tables, ", ")+" RESTART IDENTITY CASCADE"); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+	if env.redis != nil {
+		if err := env.redis.FlushDB(ctx).Err(); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+}
+
+// response is a decoded API reply.
+type response struct {
+	Status int
+	Header http.Header
+	Body   map[string]interface{}
+	Raw    []byte
+}
+
+// Data returns the "data" member of the envelope as an object.
+func (r *response) Data() map[string]interface{} {
+	data, _ := r.Body["data"].(map[string]interface{})
+	return data
+}
+
+// Message returns the "message" member of the envelope.
+func (r *response) Message() string {
+	message, _ := r.Body["message"].(string)
+	return message
+}
+
+// JSON sends body encoded as JSON. A nil body sends no content.
+func (h *harness) JSON(method, path, token string, body interface{}) *response {
+	h.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	return h.do(method, path, token, "application/json", reader)
+}
+
+// Form sends fields as a multipart form, which every handler accepts.
+func (h *harness) Form(method, path, token string, fields map[string]string) *response {
+	h.t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		h.t.Fatal(err)
+	}
+	return h.do(method, path, token, w.FormDataContentType(), &buf)
+}
+
+// Files sends a multipart form with one small file per name under field.
+func (h *harness) Files(method, path, token, field string, names ...string) *response {
+	h.t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, name := range names {
+		part, err := w.CreateFormFile(field, name)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		if _, err := part.Write([]byte("\x89PNG\r\n\x1a\n")); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		h.t.Fatal(err)
+	}
+	return h.do(method, path, token, w.FormDataContentType(), &buf)
+}
+
+// Upload sends a multipart form with fields and one file named filename
+// holding content under field.
+func (h *harness) Upload(method, path, token, field, filename string, content []byte, fields map[string]string) *response {
+	h.t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+	part, err := w.CreateFormFile(field, filename)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	if _, err := part.Write(content); err != nil {
+		h.t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		h.t.Fatal(err)
+	}
+	return h.do(method, path, token, w.FormDataContentType(), &buf)
+}
+
+// Get is shorthand for a body-less GET.
+func (h *harness) Get(path, token string) *response {
+	h.t.Helper()
+	return h.do(http.MethodGet, path, token, "", nil)
+}
+
+// GetWithHeader is a body-less, anonymous GET carrying one extra header,
+// such as If-None-Match.
+func (h *harness) GetWithHeader(path, name, value string) *response {
+	h.t.Helper()
+	req, err := http.NewRequest(http.MethodGet, env.server.URL+path, nil)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	req.Header.Set(name, value)
+	return h.send(req)
+}
+
+func (h *harness) do(method, path, token, contentType string, body io.Reader) *response {
+	h.t.Helper()
+	req, err := http.NewRequest(method, env.server.URL+path, body)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return h.send(req)
+}
+
+func (h *harness) send(req *http.Request) *response {
+	h.t.Helper()
+	method, path := req.Method, req.URL.Path
+	res, err := env.server.Client().Do(req)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	r := &response{Status: res.StatusCode, Header: res.Header, Raw: raw}
+	if len(raw) > 0 && strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(raw, &r.Body); err != nil {
+			h.t.Fatalf("%s %s: decode body: %v\n%s", method, path, err, raw)
+		}
+	}
+	return r
+}
+
+// expect fails the test unless r has the given status.
+func (h *harness) expect(r *response, status int) {
+	h.t.Helper()
+	if r.Status != status {
+		h.t.Fatalf("status = %d, want %d\n%s", r.Status, status, r.Raw)
+	}
+}
+
+func itoa(n int) string {
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,284 @@
+package e2e
+
+import (
+	"coffee-shop/models"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TestMigrationsRoundTrip applies every migration to a scratch database,
+// rolls them all back and applies them again, so each up has a working down.
+func TestMigrationsRoundTrip(t *testing.T) {
+	newHarness(t)
+	ctx := context.Background()
+	dsn, m := scratchDatabase(t)
+
+	if err := models.CheckSchema(m); err == nil {
+		t.Fatal("empty database passed the schema check")
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if err := models.CheckSchema(m); err != nil {
+		t.Fatalf("after up: %v", err)
+	}
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var pending int
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM order_status WHERE name = 'pending'`).Scan(&pending); err != nil || pending != 1 {
+		t.Fatalf("pending statuses after up = %d, %v", pending, err)
+	}
+
+	if err := m.Steps(-1); err != nil {
+		t.Fatalf("down 1: %v", err)
+	}
+	if err := models.CheckSchema(m); err == nil {
+		t.Fatal("schema one version behind passed the check")
+	}
+	if err := m.Down(); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+
+	var tables int
+	if err := db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM information_schema.tables
+		 WHERE table_schema = 'public' AND table_name <> 'schema_migrations'`).Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Fatalf("%d tables left after migrating down", tables)
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("up again: %v", err)
+	}
+	if err := models.CheckSchema(m); err != nil {
+		t.Fatalf("after second up: %v", err)
+	}
+}
+
+// scratchDatabase creates an empty database, dropped when the test ends, and
+// a migrator for it.
+func scratchDatabase(t *testing.T) (string, *migrate.Migrate) {
+	t.Helper()
+	ctx := context.Background()
+
+	admin, err := pgxpool.New(ctx, env.adminDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("coffee_shop_schema_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+name); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(ctx, "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)")
+		admin.Close()
+	})
+
+	dsn, err := withDatabase(env.adminDSN, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := models.NewMigrator(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return dsn, m
+}
+
+// TestGalleryMigrationNormalizesImages runs migration 8 over galleries with
+// several primary images, gaps and unplaced images, as older data may have,
+// and back down again.
+func TestGalleryMigrationNormalizesImages(t *testing.T) {
+	newHarness(t)
+	ctx := context.Background()
+	dsn, m := scratchDatabase(t)
+	if err := m.Migrate(7); err != nil {
+		t.Fatalf("up to 7: %v", err)
+	}
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, sql := range []string{
+		`INSERT INTO categories (name) VALUES ('Coffee')`,
+		`INSERT INTO products (id, name, category_id, price, image_url, cloudinary_id) VALUES
+			(1, 'Latte', 1, 25000, 'old.jpg', 'old'), (2, 'Mocha', 1, 28000, 'b.jpg', 'mocha-b'),
+			(3, 'Matcha', 1, 27000, NULL, NULL)`,
+		// What the original constraints allowed: gaps, empty positions and
+		// several primary images, and any number of unplaced images.
+		`INSERT INTO product_images (product_id, image_url, is_primary, display_order) VALUES
+			(1, 'a.jpg', TRUE, 3), (1, 'b.jpg', TRUE, 4), (1, 'c.jpg', NULL, NULL),
+			(2, 'a.jpg', FALSE, 2), (2, 'b.jpg', FALSE, 4),
+			(3, 'm1.jpg', FALSE, 1), (3, 'm2.jpg', FALSE, 2), (3, 'm3.jpg', FALSE, 3), (3, 'm4.jpg', FALSE, 4),
+			(3, 'm5.jpg', FALSE, NULL)`,
+	} {
+		if _, err := db.Exec(ctx, sql); err != nil {
+			t.Fatalf("%v\n%s", err, sql)
+		}
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	rows, err := db.Query(ctx, `SELECT product_id, image_url, is_primary, display_order FROM product_images ORDER BY product_id, display_order`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for rows.Next() {
+		var productID, order int
+		var url string
+		var primary bool
+		if err := rows.Scan(&productID, &url, &primary, &order); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d %s %v %d", productID, url, primary, order))
+	}
+	rows.Close()
+	want := []string{"1 a.jpg true 1", "1 b.jpg false 2", "1 c.jpg false 3", "2 a.jpg true 1", "2 b.jpg false 2",
+		"3 m1.jpg true 1", "3 m2.jpg false 2", "3 m3.jpg false 3", "3 m4.jpg false 4", "3 m5.jpg false 5"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("gallery = %v, want %v", got, want)
+	}
+
+	var latte, mocha, mochaID string
+	if err := db.QueryRow(ctx, `SELECT
+			(SELECT image_url FROM products WHERE id = 1),
+			(SELECT image_url FROM products WHERE id = 2),
+			(SELECT COALESCE(cloudinary_id, '') FROM product_images WHERE product_id = 2 AND image_url = 'b.jpg')`,
+	).Scan(&latte, &mocha, &mochaID); err != nil {
+		t.Fatal(err)
+	}
+	if latte != "a.jpg" || mocha != "a.jpg" || mochaID != "mocha-b" {
+		t.Fatalf("image_url = %s, %s; gallery public ID = %q", latte, mocha, mochaID)
+	}
+
+	if err := m.Migrate(7); err != nil {
+		t.Fatalf("down to 7: %v", err)
+	}
+	if _, err := db.Exec(ctx, `INSERT INTO product_images (product_id, image_url, display_order) VALUES (2, 'c.jpg', 2)`); err == nil {
+		t.Fatal("the position constraint was not restored")
+	}
+}
+
+func TestReviewMigrationKeepsDuplicates(t *testing.T) {
+	newHarness(t)
+	ctx := context.Background()
+	dsn, m := scratchDatabase(t)
+	if err := m.Migrate(15); err != nil {
+		t.Fatalf("up to 15: %v", err)
+	}
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, sql := range []string{
+		`INSERT INTO categories (name) VALUES ('Coffee')`,
+		`INSERT INTO products (id, name, category_id, price) VALUES (1, 'Latte', 1, 25000)`,
+		`INSERT INTO product_reviews (id, product_id, user_id, rating, review_text) VALUES
+			(1, 1, 7, 2, 'first'), (2, 1, 7, 4, 'second'), (3, 1, 8, 5, 'other')`,
+	} {
+		if _, err := db.Exec(ctx, sql); err != nil {
+			t.Fatalf("%v\n%s", err, sql)
+		}
+	}
+
+	if err := m.Migrate(16); err != nil {
+		t.Fatalf("up to 16: %v", err)
+	}
+	var reviews, superseded, count int
+	if err := db.QueryRow(ctx, `SELECT
+			(SELECT COUNT(*) FROM product_reviews),
+			(SELECT COUNT(*) FROM product_reviews_superseded WHERE id = 1),
+			(SELECT review_count FROM products WHERE id = 1)`,
+	).Scan(&reviews, &superseded, &count); err != nil {
+		t.Fatal(err)
+	}
+	if reviews != 2 || superseded != 1 || count != 2 {
+		t.Fatalf("reviews = %d, superseded = %d, review_count = %d", reviews, superseded, count)
+	}
+
+	if err := m.Migrate(15); err != nil {
+		t.Fatalf("down to 15: %v", err)
+	}
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM product_reviews`).Scan(&reviews); err != nil {
+		t.Fatal(err)
+	}
+	if reviews != 3 {
+		t.Fatalf("reviews after down = %d, want 3", reviews)
+	}
+}
+
+func TestAdminUpdatesOrderStatus(t *testing.T) {
+	h := newHarness(t)
+	_, token := h.CustomerToken()
+	_, adminToken := h.AdminToken()
+	product := h.CreateProduct(productFixture{Name: "Mocha", Price: 30000, Stock: 5})
+
+	h.expect(h.Form("POST", "/cart", token, map[string]string{"product_id": itoa(product), "quantity": "1"}), 201)
+	h.expect(h.Form("POST", "/transactions/checkout", token, map[string]string{
+		"delivery_method":   "pick_up",
+		"payment_method_id": itoa(paymentCash),
+	}), 201)
+	orderID := h.queryInt(`SELECT id FROM orders ORDER BY id DESC LIMIT 1`)
+
+	r := h.Form("PATCH", "/admin/orders/"+itoa(orderID)+"/status", adminToken, map[string]string{"status": "shipped"})
+	h.expect(r, 400)
+
+	r = h.Form("PATCH", "/admin/orders/"+itoa(orderID)+"/status", adminToken, map[string]string{"status": "completed"})
+	h.expect(r, 200)
+
+	r = h.Get("/admin/orders?status=completed", adminToken)
+	h.expect(r, 200)
+	orders, _ := r.Body["data"].([]interface{})
+	if len(orders) != 1 {
+		t.Fatalf("completed orders = %s", r.Raw)
+	}
+	if status := orders[0].(map[string]interface{})["status"]; status != "completed" {
+		t.Fatalf("status = %v, want completed", status)
+	}
+
+	orderNumber := h.queryString(`SELECT order_number FROM orders WHERE id = $1`, orderID)
+	r = h.Get("/v2/admin/orders/"+itoa(orderID), adminToken)
+	h.expect(r, 200)
+	if order := r.Data(); order["customerName"] != "Test customer" || order["deliveryAddress"] != "Jl. Kopi No. 1" ||
+		order["orderNumber"] != orderNumber {
+		t.Fatalf("v2 order = %s, want order number %s", r.Raw, orderNumber)
+	}
+
+	r = h.Get("/v2/admin/orders", adminToken)
+	h.expect(r, 200)
+	orders, _ = r.Body["data"].([]interface{})
+	if len(orders) != 1 || orders[0].(map[string]interface{})["orderNumber"] != orderNumber {
+		t.Fatalf("v2 orders = %s, want order number %s", r.Raw, orderNumber)
+	}
+}
+
+func TestProfileHidesDeletedUsers(t *testing.T) {
+	h := newHarness(t)
+	customer, token := h.CustomerToken()
+
+	h.expect(h.Get("/profile", token), 200)
+
+	h.exec(`UPDATE users SET deleted_at = NOW() WHERE id = $1`, customer.ID)
+	h.expect(h.Get("/profile", token), 404)
+}
@@ -0,0 +1,121 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	LocaleEN = "en"
+	LocaleID = "id"
+)
+
+// Supported lists every locale that has a message catalog.
+var Supported = []string{LocaleEN, LocaleID}
+
+//go:embed locales/*.json
+var catalogFiles embed.FS
+
+// catalogs maps locale -> message ID -> translated text. Message IDs are the
+// source strings used in the code, so a missing entry simply falls back to
+// the ID itself.
+var catalogs = map[string]map[string]string{}
+
+func init() {
+	for _, locale := range Supported {
+		raw, err := catalogFiles.ReadFile("locales/" + locale + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("i18n: missing catalog for %q: %v", locale, err))
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(raw, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog for %q: %v", locale, err))
+		}
+		catalogs[locale] = messages
+	}
+}
+
+// DefaultLocale is the locale used when a request does not ask for a
+// supported one. It can be changed with DEFAULT_LOCALE and defaults to English.
+func DefaultLocale() string {
+	if locale := Normalize(os.Getenv("DEFAULT_LOCALE")); locale != "" {
+		return locale
+	}
+	return LocaleEN
+}
+
+// Normalize maps a language tag such as "id-ID" or "EN_us" to a supported
+// locale, or returns "" when the language is not supported.
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	for _, locale := range Supported {
+		if tag == locale {
+			return locale
+		}
+	}
+	return ""
+}
+
+// FromAcceptLanguage picks the supported locale with the highest q-value from
+// an Accept-Language header, or "" when none of them are supported.
+func FromAcceptLanguage(header string) string {
+	type candidate struct {
+		locale string
+		q      float64
+		pos    int
+	}
+
+	candidates := []candidate{}
+	for pos, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		locale := Normalize(fields[0])
+		if locale == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{locale: locale, q: q, pos: pos})
+	}
+
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].q != candidates[j].q {
+			return candidates[i].q > candidates[j].q
+		}
+		return candidates[i].pos < candidates[j].pos
+	})
+	return candidates[0].locale
+}
+
+// T returns the translation of id for locale. Unknown locales use the
+// default locale and untranslated messages are returned unchanged.
+func T(locale, id string) string {
+	if Normalize(locale) == "" {
+		locale = DefaultLocale()
+	}
+	if text, ok := catalogs[Normalize(locale)][id]; ok && text != "" {
+		return text
+	}
+	return id
+}
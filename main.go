@@ -1,16 +1,11 @@
 package main
 
 import (
-	"coffee-shop/docs"
-	"coffee-shop/middleware"
-	"coffee-shop/models"
-	"coffee-shop/routes"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
@@ -39,8 +34,12 @@ func init() {
 
 // @title Coffee Shop
 // @version 1.0
+// @termsOfService http://swagger.io/terms/
 // @description Coffee Shop Management System API Documentation
+// @description Operations are documented under /v2, which returns camelCase fields. The unversioned and /v1 paths are deprecated aliases of the same operations that return the v1 shape: snake_case fields in models.Response, or models.HATEOASResponse for lists.
+// @host harlan-holden-coffee-backend.vercel.app
 // @BasePath /
+// @schemes https
 
 // @securityDefinitions.apikey BearerAuth
 // @in header
@@ -52,43 +51,7 @@ func main() {
 		_ = godotenv.Load()
 	}
 
-	models.InitDB()
-	defer models.CloseDB()
-
-	models.InitRedis()
-	defer models.CloseRedis()
-
-	if os.Getenv("GIN_MODE") == "release" {
-		gin.SetMode(gin.ReleaseMode)
-	}
-
-	router := gin.Default()
-	router.Use(gin.Recovery())
-	router.Use(middleware.CORSMiddleware())
-
-	docs.SwaggerInfo.Title = "Coffee Shop API"
-	docs.SwaggerInfo.Description = "Coffee Shop Management System API"
-	docs.SwaggerInfo.Version = "1.0"
-
-	swaggerHost := os.Getenv("SWAGGER_HOST")
-	if swaggerHost == "" {
-		swaggerHost = "localhost:8083"
-	}
-	docs.SwaggerInfo.Host = swaggerHost
-	docs.SwaggerInfo.BasePath = "/"
-	docs.SwaggerInfo.Schemes = []string{"http", "https"}
-
-	routes.SetupRoutes(router)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8083"
-	}
-
-	log.Printf("Server starting on port %s", port)
-	log.Printf("Swagger documentation available at: http://localhost:%s/swagger/index.html", port)
-
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatal(err)
 	}
 }
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"coffee-shop/i18n"
 	"coffee-shop/models"
+	"context"
 	"os"
 	"strings"
 
@@ -9,42 +11,87 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// Languages looks up the language a user saved in their profile, returning
+// "" when there is none.
+type Languages interface {
+	Language(ctx context.Context, userID int) string
+}
+
+// AuthMiddleware rejects requests without a valid token. The signed-in user
+// is answered in the language saved in their profile, read from languages,
+// so a changed preference applies before the token is renewed; the token's
+// language claim is used when languages has none.
+func AuthMiddleware(languages Languages) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(401, gin.H{"success": false, "message": "Authorization required"})
+		claims, problem := bearerClaims(c)
+		if problem != "" {
+			c.JSON(401, gin.H{"success": false, "message": i18n.T(c.GetString("locale"), problem)})
 			c.Abort()
 			return
 		}
+		signIn(c, claims, languages)
+		c.Next()
+	}
+}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(401, gin.H{"success": false, "message": "Invalid authorization format"})
-			c.Abort()
-			return
+// OptionalAuthMiddleware signs the user in like AuthMiddleware when the
+// request carries a valid token, and lets it through anonymously otherwise,
+// for public routes that personalise their response.
+func OptionalAuthMiddleware(languages Languages) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, problem := bearerClaims(c); problem == "" {
+			signIn(c, claims, languages)
 		}
+		c.Next()
+	}
+}
 
-		secret := os.Getenv("JWT_SECRET")
-		if secret == "" {
-			secret = "secret"
-		}
+// bearerClaims parses the request's bearer token. On failure it returns the
+// message to answer with instead.
+func bearerClaims(c *gin.Context) (jwt.MapClaims, string) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		return nil, "Authorization required"
+	}
 
-		token, err := jwt.Parse(parts[1], func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, "Invalid authorization format"
+	}
 
-		if err != nil || !token.Valid {
-			c.JSON(401, gin.H{"success": false, "message": "Invalid token"})
-			c.Abort()
-			return
-		}
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "secret"
+	}
 
-		claims := token.Claims.(jwt.MapClaims)
-		c.Set("user_id", int(claims["user_id"].(float64)))
-		c.Set("user_email", claims["email"])
-		c.Set("user_role", claims["role"])
-		c.Next()
+	token, err := jwt.Parse(parts[1], func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+
+	if err != nil || !token.Valid {
+		return nil, "Invalid token"
+	}
+	return token.Claims.(jwt.MapClaims), ""
+}
+
+func signIn(c *gin.Context, claims jwt.MapClaims, languages Languages) {
+	userID := int(claims["user_id"].(float64))
+	c.Set("user_id", userID)
+	c.Set("user_email", claims["email"])
+	c.Set("user_role", claims["role"])
+
+	if c.GetBool("locale_explicit") {
+		return
+	}
+	var language string
+	if languages != nil {
+		language = languages.Language(c.Request.Context(), userID)
+	}
+	if language == "" {
+		language, _ = claims["language"].(string)
+	}
+	if locale := i18n.Normalize(language); locale != "" {
+		setLocale(c, locale)
 	}
 }
 
@@ -52,7 +99,7 @@ func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("user_role")
 		if !exists || role != "admin" {
-			c.JSON(403, models.ErrorResponse{Success: false, Message: "Admin access required"})
+			c.JSON(403, models.ErrorResponse{Success: false, Message: i18n.T(c.GetString("locale"), "Admin access required")})
 			c.Abort()
 			return
 		}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheControl picks the Cache-Control policy for a route from the
+// CACHE_CONTROL_<route> environment variable, or fallback when it is unset.
+// Handlers send it with successful responses only, so errors are not cached.
+// Responses to requests with a token are personalised, so a public policy
+// becomes private for them, and Vary tells shared caches to key on the token.
+func CacheControl(route, fallback string) gin.HandlerFunc {
+	policy := strings.TrimSpace(os.Getenv("CACHE_CONTROL_" + route))
+	if policy == "" {
+		policy = fallback
+	}
+	private := privatePolicy(policy)
+
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Authorization")
+		if c.GetHeader("Authorization") != "" {
+			c.Set("cache_control", private)
+		} else {
+			c.Set("cache_control", policy)
+		}
+		c.Next()
+	}
+}
+
+// privatePolicy swaps a public directive for private, keeping the rest.
+func privatePolicy(policy string) string {
+	directives := strings.Split(policy, ",")
+	for i, directive := range directives {
+		if strings.EqualFold(strings.TrimSpace(directive), "public") {
+			directives[i] = strings.Replace(directive, strings.TrimSpace(directive), "private", 1)
+		}
+	}
+	return strings.Join(directives, ",")
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"coffee-shop/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LocaleMiddleware resolves the response language from the ?lang= query
+// parameter, then Accept-Language, then DEFAULT_LOCALE. AuthMiddleware may
+// later replace it with the user's saved preference unless ?lang= was given.
+func LocaleMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := i18n.Normalize(c.Query("lang"))
+		if locale != "" {
+			c.Set("locale_explicit", true)
+		} else if locale = i18n.FromAcceptLanguage(c.GetHeader("Accept-Language")); locale == "" {
+			locale = i18n.DefaultLocale()
+		}
+
+		setLocale(c, locale)
+		c.Header("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+func setLocale(c *gin.Context, locale string) {
+	c.Set("locale", locale)
+	c.Header("Content-Language", locale)
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersion tags the request with the API version of the route group it
+// was mounted on, so handlers can pick the matching response shape.
+func APIVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("api_version", version)
+		c.Next()
+	}
+}
+
+// DeprecationMiddleware advertises that a route group is superseded using the
+// Deprecation (RFC 9745), Sunset (RFC 8594) and successor-version Link headers.
+// The dates come from API_V1_DEPRECATED_AT and API_V1_SUNSET (format 2006-01-02).
+// stripPrefix is removed from the request path before successorPrefix is added.
+func DeprecationMiddleware(stripPrefix, successorPrefix string) gin.HandlerFunc {
+	deprecation := "true"
+	if deprecatedAt, ok := parseHeaderDate("API_V1_DEPRECATED_AT"); ok {
+		deprecation = fmt.Sprintf("@%d", deprecatedAt.Unix())
+	}
+
+	sunset := ""
+	if sunsetAt, ok := parseHeaderDate("API_V1_SUNSET"); ok {
+		sunset = sunsetAt.Format(http.TimeFormat)
+	}
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if sunset != "" {
+			c.Header("Sunset", sunset)
+		}
+
+		successor := successorPrefix + strings.TrimPrefix(c.Request.URL.Path, stripPrefix)
+		c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		c.Next()
+	}
+}
+
+func parseHeaderDate(key string) (time.Time, bool) {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		log.Printf("Ignoring invalid %s %q: %v", key, value, err)
+		return time.Time{}, false
+	}
+	return t.UTC(), true
+}
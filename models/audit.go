@@ -0,0 +1,149 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	AuditActionCreate        = "create"
+	AuditActionUpdate        = "update"
+	AuditActionDelete        = "delete"
+	AuditActionArchive       = "archive"
+	AuditActionRestore       = "restore"
+	AuditActionUpdateStatus  = "update_status"
+	AuditActionResetPassword = "reset_password"
+)
+
+const (
+	AuditEntityUser     = "user"
+	AuditEntityProduct  = "product"
+	AuditEntityCategory = "category"
+	AuditEntityOrder    = "order"
+
+	AuditEntityProductTranslation    = "product_translation"
+	AuditEntityProductGallery        = "product_gallery"
+	AuditEntityProductOption         = "product_option"
+	AuditEntityProductPrice          = "product_price"
+	AuditEntityCategoryTranslation   = "category_translation"
+	AuditEntityFlashSale             = "flash_sale"
+	AuditEntityPromotionRule         = "promotion_rule"
+	AuditEntityProductReview         = "product_review"
+	AuditEntityProductRecommendation = "product_recommendation"
+	AuditEntitySearchQuery           = "search_query"
+)
+
+type AuditLog struct {
+	ID         int64           `json:"id"`
+	ActorID    *int            `json:"actor_id"`
+	ActorEmail string          `json:"actor_email"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   int             `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	Changes    json.RawMessage `json:"changes,omitempty"`
+	IPAddress  string          `json:"ip_address"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditEntry describes a single privileged change. Before and After can be
+// any JSON-serialisable value; nil means the entity did not exist on that side.
+type AuditEntry struct {
+	ActorID    int
+	ActorEmail string
+	Action     string
+	EntityType string
+	EntityID   int
+	Before     interface{}
+	After      interface{}
+	IPAddress  string
+}
+
+// auditExecer is satisfied by pgx.Tx (and the pool, for callers that manage
+// their own transaction).
+type auditExecer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// RecordAudit writes the entry inside tx so the trail is committed (or rolled
+// back) together with the change it describes.
+func RecordAudit(ctx context.Context, tx auditExecer, entry AuditEntry) error {
+	before, err := toAuditMap(entry.Before)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit before state: %w", err)
+	}
+	after, err := toAuditMap(entry.After)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit after state: %w", err)
+	}
+
+	var actorID interface{}
+	if entry.ActorID > 0 {
+		actorID = entry.ActorID
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO audit_log
+		 (actor_id, actor_email, action, entity_type, entity_id, before_data, after_data, changes, ip_address, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		actorID, entry.ActorEmail, entry.Action, entry.EntityType, entry.EntityID,
+		nullableJSON(before), nullableJSON(after), nullableJSON(auditDiff(before, after)),
+		entry.IPAddress, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+func toAuditMap(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// auditDiff returns {field: {"from": old, "to": new}} for every field whose
+// value differs between the two snapshots.
+func auditDiff(before, after map[string]interface{}) map[string]interface{} {
+	changes := map[string]interface{}{}
+
+	for key, oldVal := range before {
+		newVal, ok := after[key]
+		if !ok || !reflect.DeepEqual(oldVal, newVal) {
+			changes[key] = map[string]interface{}{"from": oldVal, "to": newVal}
+		}
+	}
+	for key, newVal := range after {
+		if _, ok := before[key]; !ok {
+			changes[key] = map[string]interface{}{"from": nil, "to": newVal}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+func nullableJSON(m map[string]interface{}) interface{} {
+	if m == nil {
+		return nil
+	}
+	raw, _ := json.Marshal(m)
+	return string(raw)
+}
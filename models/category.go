@@ -1,9 +1,15 @@
 package models
 
+import "time"
+
 type Category struct {
-	ID        int    `json:"id"`
-	Name      string `json:"name"`
-	CreatedAt string `json:"created_at"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt is when the category or the translation its name came
+	// from last changed.
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type CategoryRequest struct {
@@ -1,17 +1,19 @@
 package models
 
 import (
+	"coffee-shop/database/migration"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/joho/godotenv"
@@ -24,7 +26,7 @@ func InitDB() {
 		_ = godotenv.Load()
 	}
 
-	dsn := buildDSN()
+	dsn := DSN()
 
 	config, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
@@ -55,13 +57,34 @@ func InitDB() {
 	}
 
 	log.Println("Database connected successfully")
+}
 
-	if err := runMigrations(dsn); err != nil {
-		log.Fatalf("Failed to run database migrations: %v", err)
+// PrepareSchema readies the database for serving: it applies pending
+// migrations when AutoMigrate is on, then fails unless the schema is at the
+// version this binary expects. Commands that only run a task skip it.
+func PrepareSchema() error {
+	dsn := DSN()
+	if AutoMigrate() {
+		if err := Migrate(dsn); err != nil {
+			return fmt.Errorf("failed to run database migrations: %w", err)
+		}
+	}
+	if err := VerifySchema(dsn); err != nil {
+		return fmt.Errorf("database schema check failed: %w", err)
 	}
+	return nil
 }
 
-func buildDSN() string {
+// AutoMigrate reports whether PrepareSchema should apply pending migrations,
+// which is only done when AUTO_MIGRATE is set to a true value. Otherwise run
+// "migrate up" as a deploy step.
+func AutoMigrate() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("AUTO_MIGRATE"))
+	return enabled
+}
+
+// DSN returns DATABASE_URL, or a connection string built from the DB_* variables.
+func DSN() string {
 	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
 		log.Println("Using DATABASE_URL for connection")
 		return databaseURL
@@ -83,33 +106,44 @@ func buildDSN() string {
 	return dsn
 }
 
-func runMigrations(dsn string) error {
+// NewMigrator returns a migrator for the database at dsn using the migrations
+// embedded in the binary. The caller must Close it.
+func NewMigrator(dsn string) (*migrate.Migrate, error) {
 	sqlDB, err := sql.Open("pgx", dsn)
 	if err != nil {
-		return fmt.Errorf("failed to open DB for migrations: %w", err)
+		return nil, fmt.Errorf("failed to open DB for migrations: %w", err)
 	}
-	defer sqlDB.Close()
 
 	driver, err := postgres.WithInstance(sqlDB, &postgres.Config{})
 	if err != nil {
-		return fmt.Errorf("failed to create migration driver: %w", err)
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to create migration driver: %w", err)
 	}
 
-	migrationPath, err := filepath.Abs("database/migration")
+	source, err := iofs.New(migration.Files, ".")
 	if err != nil {
-		return fmt.Errorf("failed to resolve migration path: %w", err)
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to load embedded migrations: %w", err)
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://"+migrationPath,
-		"postgres",
-		driver,
-	)
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
 	if err != nil {
-		return fmt.Errorf("failed to initialize migrator: %w", err)
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to initialize migrator: %w", err)
 	}
+	return m, nil
+}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+// Migrate applies every pending migration embedded in the binary to the
+// database at dsn.
+func Migrate(dsn string) error {
+	m, err := NewMigrator(dsn)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
 		return fmt.Errorf("failed to apply migrations: %w", err)
 	}
 
@@ -117,6 +151,42 @@ func runMigrations(dsn string) error {
 	return nil
 }
 
+// VerifySchema runs CheckSchema against the database at dsn.
+func VerifySchema(dsn string) error {
+	m, err := NewMigrator(dsn)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	return CheckSchema(m)
+}
+
+// CheckSchema compares the version recorded by the migrator with the newest
+// migration embedded in the binary. A database that is behind or dirty is an
+// error; one that is ahead, as when rolling back to an older binary, is only
+// logged.
+func CheckSchema(m *migrate.Migrate) error {
+	expected, err := migration.Latest()
+	if err != nil {
+		return err
+	}
+
+	current, dirty, err := m.Version()
+	switch {
+	case errors.Is(err, migrate.ErrNilVersion):
+		return fmt.Errorf("database has no schema version, expected %d: run \"migrate up\"", expected)
+	case err != nil:
+		return fmt.Errorf("failed to read schema version: %w", err)
+	case dirty:
+		return fmt.Errorf("schema version %d is dirty: fix the schema, then run \"migrate force %d\"", current, current)
+	case int(current) < expected:
+		return fmt.Errorf("schema version %d is older than %d expected by this binary: run \"migrate up\"", current, expected)
+	case int(current) > expected:
+		log.Printf("Warning: schema version %d is newer than %d expected by this binary", current, expected)
+	}
+	return nil
+}
+
 func CloseDB() {
 	if DB != nil {
 		DB.Close()
@@ -6,6 +6,7 @@ type RegisterRequest struct {
 	FullName string `json:"full_name" form:"full_name" binding:"required,min=3"`
 	Phone    string `json:"phone" form:"phone" binding:"omitempty"`
 	Role     string `json:"role" form:"role" binding:"omitempty,oneof=customer admin"`
+	Language string `json:"language" form:"language" binding:"omitempty"`
 }
 
 type LoginRequest struct {
@@ -96,11 +97,14 @@ type PaginationResponse struct {
 	Meta    MetaData    `json:"meta"`
 }
 
+// PaginationMeta describes a page of a list. Page is 0 when the page was
+// requested by cursor; NextCursor continues after the last item.
 type PaginationMeta struct {
-	Page       int `json:"page"`
-	Limit      int `json:"limit"`
-	TotalItems int `json:"total_items"`
-	TotalPages int `json:"total_pages"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	TotalItems int    `json:"total_items"`
+	TotalPages int    `json:"total_pages"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type PaginationLinks struct {
@@ -115,4 +119,6 @@ type HATEOASResponse struct {
 	Data    interface{}     `json:"data"`
 	Meta    PaginationMeta  `json:"meta"`
 	Links   PaginationLinks `json:"links"`
+	Facets  *ProductFacets  `json:"facets,omitempty"`
+	Ratings *RatingSummary  `json:"ratings,omitempty"`
 }
@@ -0,0 +1,735 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// API v2 response DTOs. Every v2 payload is camelCase and wrapped in either
+// EnvelopeV2 (single resource / action result) or ListEnvelopeV2 (collections).
+
+type EnvelopeV2 struct {
+	Success bool        `json:"success"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+type PageMetaV2 struct {
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	TotalItems int    `json:"totalItems"`
+	TotalPages int    `json:"totalPages"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type PageLinksV2 struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+type ListEnvelopeV2 struct {
+	Success bool             `json:"success"`
+	Message string           `json:"message"`
+	Data    interface{}      `json:"data"`
+	Meta    PageMetaV2       `json:"meta"`
+	Links   *PageLinksV2     `json:"links,omitempty"`
+	Facets  *ProductFacetsV2 `json:"facets,omitempty"`
+	Ratings *RatingSummary   `json:"ratings,omitempty"`
+}
+
+// NewListEnvelopeV2 converts a v1 HATEOAS response into the v2 list envelope,
+// replacing its data with the already converted v2 items.
+func NewListEnvelopeV2(resp HATEOASResponse, data interface{}) ListEnvelopeV2 {
+	return ListEnvelopeV2{
+		Success: resp.Success,
+		Message: resp.Message,
+		Data:    data,
+		Meta: PageMetaV2{
+			Page:       resp.Meta.Page,
+			Limit:      resp.Meta.Limit,
+			TotalItems: resp.Meta.TotalItems,
+			TotalPages: resp.Meta.TotalPages,
+			NextCursor: resp.Meta.NextCursor,
+		},
+		Links: &PageLinksV2{
+			Self: resp.Links.Self,
+			Next: resp.Links.Next,
+			Prev: resp.Links.Prev,
+		},
+		Facets:  NewProductFacetsV2(resp.Facets),
+		Ratings: resp.Ratings,
+	}
+}
+
+type ProductFacetsV2 struct {
+	Categories  []CategoryFacet `json:"categories"`
+	PriceRanges []PriceFacet    `json:"priceRanges"`
+	FlashSale   int             `json:"flashSale"`
+	Featured    int             `json:"featured"`
+	Buy1Get1    int             `json:"buy1Get1"`
+	InStock     int             `json:"inStock"`
+}
+
+// NewProductFacetsV2 returns nil when f is nil, so lists without facets
+// leave the field out.
+func NewProductFacetsV2(f *ProductFacets) *ProductFacetsV2 {
+	if f == nil {
+		return nil
+	}
+	return &ProductFacetsV2{
+		Categories:  f.Categories,
+		PriceRanges: f.PriceRanges,
+		FlashSale:   f.FlashSale,
+		Featured:    f.Featured,
+		Buy1Get1:    f.Buy1Get1,
+		InStock:     f.InStock,
+	}
+}
+
+type ProductV2 struct {
+	ID                 int               `json:"id"`
+	SKU                string            `json:"sku,omitempty"`
+	Name               string            `json:"name"`
+	Description        string            `json:"description"`
+	CategoryID         int               `json:"categoryId"`
+	Price              int               `json:"price"`
+	Stock              int               `json:"stock"`
+	LowStockThreshold  int               `json:"lowStockThreshold"`
+	ImageURL           string            `json:"imageUrl"`
+	CloudinaryID       string            `json:"cloudinaryId,omitempty"`
+	IsFlashSale        bool              `json:"isFlashSale"`
+	IsFeatured         bool              `json:"isFeatured"`
+	IsBuy1Get1         bool              `json:"isBuy1Get1"`
+	IsActive           bool              `json:"isActive"`
+	AverageRating      float64           `json:"averageRating"`
+	ReviewCount        int               `json:"reviewCount"`
+	CreatedAt          time.Time         `json:"createdAt"`
+	UpdatedAt          time.Time         `json:"updatedAt"`
+	DeletedAt          *time.Time        `json:"deletedAt,omitempty"`
+	FlashSale          *FlashSalePriceV2 `json:"flashSale,omitempty"`
+	Relevance          float64           `json:"relevance,omitempty"`
+	Highlight          *SearchHighlight  `json:"highlight,omitempty"`
+	IsFavorited        *bool             `json:"isFavorited,omitempty"`
+	FavoritedAt        *time.Time        `json:"favoritedAt,omitempty"`
+	RecommendationType string            `json:"recommendationType,omitempty"`
+}
+
+func NewProductV2(p Product) ProductV2 {
+	return ProductV2{
+		ID:                 p.ID,
+		SKU:                p.SKU,
+		Name:               p.Name,
+		Description:        p.Description,
+		CategoryID:         p.CategoryID,
+		Price:              p.Price,
+		Stock:              p.Stock,
+		LowStockThreshold:  p.LowStockThreshold,
+		ImageURL:           p.ImageURL,
+		CloudinaryID:       p.CloudinaryID,
+		IsFlashSale:        p.IsFlashSale,
+		IsFeatured:         p.IsFeatured,
+		IsBuy1Get1:         p.IsBuy1Get1,
+		IsActive:           p.IsActive,
+		AverageRating:      p.AverageRating,
+		ReviewCount:        p.ReviewCount,
+		CreatedAt:          p.CreatedAt,
+		UpdatedAt:          p.UpdatedAt,
+		DeletedAt:          p.DeletedAt,
+		FlashSale:          NewFlashSalePriceV2(p.FlashSale),
+		Relevance:          p.Relevance,
+		Highlight:          p.Highlight,
+		IsFavorited:        p.IsFavorited,
+		FavoritedAt:        p.FavoritedAt,
+		RecommendationType: p.RecommendationType,
+	}
+}
+
+func NewProductListV2(products []Product) []ProductV2 {
+	out := make([]ProductV2, 0, len(products))
+	for _, p := range products {
+		out = append(out, NewProductV2(p))
+	}
+	return out
+}
+
+type StockMovementV2 struct {
+	ID         int       `json:"id"`
+	ProductID  int       `json:"productId"`
+	Type       string    `json:"type"`
+	Quantity   int       `json:"quantity"`
+	StockAfter int       `json:"stockAfter"`
+	Reason     string    `json:"reason"`
+	OrderID    *int      `json:"orderId"`
+	ActorID    *int      `json:"actorId"`
+	ActorEmail string    `json:"actorEmail,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func NewStockMovementListV2(movements []StockMovement) []StockMovementV2 {
+	out := make([]StockMovementV2, 0, len(movements))
+	for _, m := range movements {
+		out = append(out, StockMovementV2(m))
+	}
+	return out
+}
+
+type ProductPriceV2 struct {
+	ID            int        `json:"id"`
+	ProductID     int        `json:"productId"`
+	Price         int        `json:"price"`
+	PreviousPrice *int       `json:"previousPrice"`
+	EffectiveAt   time.Time  `json:"effectiveAt"`
+	AppliedAt     *time.Time `json:"appliedAt"`
+	Reason        string     `json:"reason"`
+	ActorID       *int       `json:"actorId"`
+	ActorEmail    string     `json:"actorEmail,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+func NewProductPriceListV2(prices []ProductPrice) []ProductPriceV2 {
+	out := make([]ProductPriceV2, 0, len(prices))
+	for _, p := range prices {
+		out = append(out, ProductPriceV2(p))
+	}
+	return out
+}
+
+type FlashSalePriceV2 struct {
+	FlashSaleID     int       `json:"flashSaleId"`
+	Name            string    `json:"name"`
+	RegularPrice    int       `json:"regularPrice"`
+	Price           int       `json:"price"`
+	DiscountPercent int       `json:"discountPercent"`
+	MaxPerCustomer  *int      `json:"maxPerCustomer"`
+	EndsAt          time.Time `json:"endsAt"`
+}
+
+// NewFlashSalePriceV2 returns nil for a product that is not on sale.
+func NewFlashSalePriceV2(p *FlashSalePrice) *FlashSalePriceV2 {
+	if p == nil {
+		return nil
+	}
+	v2 := FlashSalePriceV2(*p)
+	return &v2
+}
+
+type FlashSaleItemV2 struct {
+	ProductID       int    `json:"productId"`
+	Name            string `json:"name"`
+	ImageURL        string `json:"imageUrl"`
+	RegularPrice    int    `json:"regularPrice"`
+	Price           int    `json:"price"`
+	SalePrice       *int   `json:"salePrice"`
+	DiscountPercent *int   `json:"discountPercent"`
+	MaxPerCustomer  *int   `json:"maxPerCustomer"`
+}
+
+type FlashSaleV2 struct {
+	ID        int               `json:"id"`
+	Name      string            `json:"name"`
+	StartsAt  time.Time         `json:"startsAt"`
+	EndsAt    time.Time         `json:"endsAt"`
+	IsActive  bool              `json:"isActive"`
+	Items     []FlashSaleItemV2 `json:"items"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+func NewFlashSaleV2(s FlashSale) FlashSaleV2 {
+	out := FlashSaleV2{ID: s.ID, Name: s.Name, StartsAt: s.StartsAt, EndsAt: s.EndsAt, IsActive: s.IsActive,
+		Items: make([]FlashSaleItemV2, 0, len(s.Items)), CreatedAt: s.CreatedAt, UpdatedAt: s.UpdatedAt}
+	for _, item := range s.Items {
+		out.Items = append(out.Items, FlashSaleItemV2(item))
+	}
+	return out
+}
+
+func NewFlashSaleListV2(sales []FlashSale) []FlashSaleV2 {
+	out := make([]FlashSaleV2, 0, len(sales))
+	for _, s := range sales {
+		out = append(out, NewFlashSaleV2(s))
+	}
+	return out
+}
+
+type ActiveFlashSaleV2 struct {
+	FlashSaleV2
+	EndsInSeconds int64 `json:"endsInSeconds"`
+}
+
+func NewActiveFlashSaleListV2(sales []ActiveFlashSale) []ActiveFlashSaleV2 {
+	out := make([]ActiveFlashSaleV2, 0, len(sales))
+	for _, s := range sales {
+		out = append(out, ActiveFlashSaleV2{FlashSaleV2: NewFlashSaleV2(s.FlashSale), EndsInSeconds: s.EndsInSeconds})
+	}
+	return out
+}
+
+type ProductImportRowV2 struct {
+	Row       int                   `json:"row"`
+	Action    string                `json:"action"`
+	ProductID int                   `json:"productId,omitempty"`
+	SKU       string                `json:"sku,omitempty"`
+	Name      string                `json:"name"`
+	Changes   []ProductImportChange `json:"changes,omitempty"`
+	Errors    []string              `json:"errors,omitempty"`
+}
+
+type ProductImportReportV2 struct {
+	Applied bool                 `json:"applied"`
+	Summary ProductImportSummary `json:"summary"`
+	Rows    []ProductImportRowV2 `json:"rows"`
+}
+
+func NewProductImportReportV2(r ProductImportReport) ProductImportReportV2 {
+	out := ProductImportReportV2{Applied: r.Applied, Summary: r.Summary, Rows: make([]ProductImportRowV2, 0, len(r.Rows))}
+	for _, row := range r.Rows {
+		out.Rows = append(out.Rows, ProductImportRowV2(row))
+	}
+	return out
+}
+
+type ProductImageV2 struct {
+	ID        int    `json:"id"`
+	URL       string `json:"url"`
+	IsPrimary bool   `json:"isPrimary"`
+	Order     int    `json:"order"`
+}
+
+func NewProductImageListV2(images []ProductImage) []ProductImageV2 {
+	out := make([]ProductImageV2, 0, len(images))
+	for _, img := range images {
+		out = append(out, ProductImageV2{ID: img.ID, URL: img.URL, IsPrimary: img.IsPrimary, Order: img.Order})
+	}
+	return out
+}
+
+type ProductSizeV2 struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	PriceAdjustment int    `json:"priceAdjustment"`
+}
+
+type ProductTemperatureV2 struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type ProductVariantV2 struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type ProductOptionV2 struct {
+	ID              int    `json:"id"`
+	SizeID          *int   `json:"sizeId"`
+	Size            string `json:"size"`
+	TemperatureID   *int   `json:"temperatureId"`
+	Temperature     string `json:"temperature"`
+	VariantID       *int   `json:"variantId"`
+	Variant         string `json:"variant"`
+	PriceAdjustment int    `json:"priceAdjustment"`
+	IsActive        bool   `json:"isActive"`
+}
+
+func NewProductOptionV2(o ProductOption) ProductOptionV2 {
+	return ProductOptionV2{
+		ID:              o.ID,
+		SizeID:          o.SizeID,
+		Size:            o.Size,
+		TemperatureID:   o.TemperatureID,
+		Temperature:     o.Temperature,
+		VariantID:       o.VariantID,
+		Variant:         o.Variant,
+		PriceAdjustment: o.PriceAdjustment,
+		IsActive:        o.IsActive,
+	}
+}
+
+func NewProductOptionListV2(options []ProductOption) []ProductOptionV2 {
+	out := make([]ProductOptionV2, 0, len(options))
+	for _, o := range options {
+		out = append(out, NewProductOptionV2(o))
+	}
+	return out
+}
+
+type ProductRecommendationV2 struct {
+	ID                     int       `json:"id"`
+	ProductID              int       `json:"productId"`
+	RecommendedProductID   int       `json:"recommendedProductId"`
+	RecommendedProductName string    `json:"recommendedProductName,omitempty"`
+	Type                   string    `json:"recommendationType"`
+	Priority               int       `json:"priority"`
+	IsActive               bool      `json:"isActive"`
+	CreatedAt              time.Time `json:"createdAt"`
+	UpdatedAt              time.Time `json:"updatedAt"`
+}
+
+func NewProductRecommendationListV2(recommendations []ProductRecommendation) []ProductRecommendationV2 {
+	out := make([]ProductRecommendationV2, 0, len(recommendations))
+	for _, r := range recommendations {
+		out = append(out, ProductRecommendationV2(r))
+	}
+	return out
+}
+
+// ProductReviewV2 is an approved review as the storefront shows it, in v1
+// as well.
+type ProductReviewV2 struct {
+	ID        int        `json:"id"`
+	Rating    int        `json:"rating"`
+	Text      string     `json:"text"`
+	User      string     `json:"user"`
+	Reply     string     `json:"reply,omitempty"`
+	RepliedAt *time.Time `json:"repliedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func NewProductReviewV2(r ProductReview) ProductReviewV2 {
+	return ProductReviewV2{
+		ID:        r.ID,
+		Rating:    r.Rating,
+		Text:      r.Text,
+		User:      r.User,
+		Reply:     r.Reply,
+		RepliedAt: r.RepliedAt,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func NewProductReviewListV2(reviews []ProductReview) []ProductReviewV2 {
+	out := make([]ProductReviewV2, 0, len(reviews))
+	for _, r := range reviews {
+		out = append(out, NewProductReviewV2(r))
+	}
+	return out
+}
+
+// ReviewV2 is a review with its moderation status, as its author and the
+// admins see it.
+type ReviewV2 struct {
+	ID          int        `json:"id"`
+	ProductID   int        `json:"productId"`
+	ProductName string     `json:"productName,omitempty"`
+	UserID      int        `json:"userId"`
+	User        string     `json:"user"`
+	Rating      int        `json:"rating"`
+	Text        string     `json:"text"`
+	Status      string     `json:"status"`
+	Reply       string     `json:"reply,omitempty"`
+	RepliedAt   *time.Time `json:"repliedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+func NewReviewV2(r ProductReview) ReviewV2 {
+	return ReviewV2(r)
+}
+
+func NewReviewListV2(reviews []ProductReview) []ReviewV2 {
+	out := make([]ReviewV2, 0, len(reviews))
+	for _, r := range reviews {
+		out = append(out, NewReviewV2(r))
+	}
+	return out
+}
+
+// SearchQueryV2 is a past search with its moderation status, as admins see
+// it.
+type SearchQueryV2 struct {
+	ID             int       `json:"id"`
+	Query          string    `json:"query"`
+	Hits           int       `json:"hits"`
+	Status         string    `json:"status"`
+	LastSearchedAt time.Time `json:"lastSearchedAt"`
+}
+
+func NewSearchQueryListV2(queries []SearchQuery) []SearchQueryV2 {
+	out := make([]SearchQueryV2, 0, len(queries))
+	for _, q := range queries {
+		out = append(out, SearchQueryV2(q))
+	}
+	return out
+}
+
+type ProductSummaryV2 struct {
+	ID                 int    `json:"id"`
+	Name               string `json:"name"`
+	Price              int    `json:"price"`
+	ImageURL           string `json:"imageUrl"`
+	IsFlashSale        bool   `json:"isFlashSale"`
+	RecommendationType string `json:"recommendationType,omitempty"`
+}
+
+type ProductDetailV2 struct {
+	Product         ProductV2              `json:"product"`
+	Images          []ProductImageV2       `json:"images"`
+	Sizes           []ProductSizeV2        `json:"sizes"`
+	Temperatures    []ProductTemperatureV2 `json:"temperatures"`
+	Options         []ProductOptionV2      `json:"options"`
+	TotalReviews    int                    `json:"totalReviews"`
+	AverageRating   float64                `json:"averageRating"`
+	Reviews         []ProductReviewV2      `json:"reviews"`
+	Recommendations []ProductSummaryV2     `json:"recommendations"`
+}
+
+type CategoryV2 struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func NewCategoryV2(c Category) CategoryV2 {
+	return CategoryV2{ID: c.ID, Name: c.Name, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
+}
+
+type CartItemV2 struct {
+	ID            int    `json:"id"`
+	ProductID     int    `json:"productId"`
+	CategoryID    int    `json:"categoryId"`
+	Name          string `json:"name"`
+	BasePrice     int    `json:"basePrice"`
+	IsBuy1Get1    bool   `json:"isBuy1Get1"`
+	Quantity      int    `json:"quantity"`
+	SizeID        *int   `json:"sizeId"`
+	Size          string `json:"size"`
+	TemperatureID *int   `json:"temperatureId"`
+	Temperature   string `json:"temperature"`
+	VariantID     *int   `json:"variantId"`
+	Variant       string `json:"variant"`
+	// BasePrice is the flash sale price while the product is on sale, and
+	// RegularPrice the product's own price.
+	RegularPrice int               `json:"regularPrice"`
+	FlashSale    *FlashSalePriceV2 `json:"flashSale,omitempty"`
+	// OptionPrice is the product's surcharge for the chosen combination.
+	OptionPrice int `json:"optionPrice"`
+	// Available is false when the product is no longer sold with the chosen
+	// options; such lines are left out of the subtotal and block checkout.
+	Available bool   `json:"available"`
+	Subtotal  int    `json:"subtotal"`
+	ImageURL  string `json:"imageUrl"`
+	Stock     int    `json:"stock"`
+}
+
+// CartAdjustmentV2 is a promotion discount on one cart line.
+type CartAdjustmentV2 struct {
+	CartItemID      int    `json:"cartItemId"`
+	PromotionRuleID *int   `json:"promotionRuleId"`
+	Name            string `json:"name"`
+	Quantity        int    `json:"quantity"`
+	Amount          int    `json:"amount"`
+}
+
+// CartV2 is the customer's cart. Subtotal is before promotions, Discount
+// the sum of Adjustments, and Total what checkout charges before delivery.
+type CartV2 struct {
+	Items       []CartItemV2       `json:"items"`
+	Adjustments []CartAdjustmentV2 `json:"adjustments"`
+	Subtotal    int                `json:"subtotal"`
+	Discount    int                `json:"discount"`
+	Total       int                `json:"total"`
+}
+
+type PromotionRuleV2 struct {
+	ID              int        `json:"id"`
+	Name            string     `json:"name"`
+	Type            string     `json:"type"`
+	ProductID       *int       `json:"productId"`
+	CategoryID      *int       `json:"categoryId"`
+	BuyQuantity     int        `json:"buyQuantity"`
+	GetQuantity     int        `json:"getQuantity"`
+	DiscountPercent int        `json:"discountPercent"`
+	StartsAt        *time.Time `json:"startsAt"`
+	EndsAt          *time.Time `json:"endsAt"`
+	IsActive        bool       `json:"isActive"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+func NewPromotionRuleV2(r PromotionRule) PromotionRuleV2 {
+	return PromotionRuleV2(r)
+}
+
+func NewPromotionRuleListV2(rules []PromotionRule) []PromotionRuleV2 {
+	out := make([]PromotionRuleV2, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, NewPromotionRuleV2(r))
+	}
+	return out
+}
+
+type PromoV2 struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Code        string `json:"code"`
+	BgColor     string `json:"bgColor"`
+	TextColor   string `json:"textColor"`
+}
+
+func NewPromoListV2(promos []Promo) []PromoV2 {
+	out := make([]PromoV2, 0, len(promos))
+	for _, p := range promos {
+		out = append(out, PromoV2(p))
+	}
+	return out
+}
+
+type CartItemRefV2 struct {
+	CartItemID int `json:"cartItemId"`
+	Quantity   int `json:"quantity"`
+}
+
+type UserV2 struct {
+	ID        int        `json:"id"`
+	Email     string     `json:"email"`
+	Role      string     `json:"role"`
+	FullName  string     `json:"fullName"`
+	Phone     string     `json:"phone"`
+	Address   string     `json:"address"`
+	PhotoURL  string     `json:"photoUrl"`
+	Language  string     `json:"language,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+func NewUserV2(u UserWithProfile) UserV2 {
+	createdAt := u.CreatedAt
+	return UserV2{
+		ID:        u.ID,
+		Email:     u.Email,
+		Role:      u.Role,
+		FullName:  u.FullName,
+		Phone:     u.Phone,
+		Address:   u.Address,
+		PhotoURL:  u.PhotoURL,
+		CreatedAt: &createdAt,
+	}
+}
+
+type LoginV2 struct {
+	Token string `json:"token"`
+	User  UserV2 `json:"user"`
+}
+
+type OrderSummaryV2 struct {
+	ID              int       `json:"id"`
+	OrderNumber     string    `json:"orderNumber"`
+	UserID          int       `json:"userId"`
+	Status          string    `json:"status"`
+	Subtotal        int       `json:"subtotal"`
+	Total           int       `json:"total"`
+	CustomerName    string    `json:"customerName,omitempty"`
+	DeliveryAddress string    `json:"deliveryAddress,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+type OrderStatusV2 struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
+
+type OrderRefV2 struct {
+	OrderID int    `json:"orderId"`
+	Message string `json:"message"`
+}
+
+type OrderItemV2 struct {
+	ProductID   int    `json:"productId"`
+	Name        string `json:"name"`
+	Quantity    int    `json:"quantity"`
+	Size        string `json:"size"`
+	Temperature string `json:"temperature"`
+	UnitPrice   int    `json:"unitPrice"`
+	// Discount is the promotion discount on the line, already taken off
+	// TotalPrice, and Promotion the name of the promotion that gave it.
+	Discount       int    `json:"discount"`
+	Promotion      string `json:"promotion,omitempty"`
+	TotalPrice     int    `json:"totalPrice"`
+	ImageURL       string `json:"imageUrl"`
+	IsFlashSale    bool   `json:"isFlashSale"`
+	DeliveryMethod string `json:"deliveryMethod"`
+}
+
+type OrderDetailV2 struct {
+	OrderNumber    string        `json:"orderNumber"`
+	OrderDate      string        `json:"orderDate"`
+	FullName       string        `json:"fullName"`
+	Phone          string        `json:"phone"`
+	Address        string        `json:"address"`
+	DeliveryMethod string        `json:"deliveryMethod"`
+	PaymentMethod  string        `json:"paymentMethod"`
+	Status         string        `json:"status"`
+	StatusDisplay  string        `json:"statusDisplay"`
+	Subtotal       int           `json:"subtotal"`
+	Discount       int           `json:"discount"`
+	DeliveryFee    int           `json:"deliveryFee"`
+	TaxAmount      int           `json:"taxAmount"`
+	Total          int           `json:"total"`
+	Items          []OrderItemV2 `json:"items"`
+}
+
+type CheckoutResultV2 struct {
+	ID             int    `json:"id"`
+	OrderNumber    string `json:"orderNumber"`
+	Status         string `json:"status"`
+	Subtotal       int    `json:"subtotal"`
+	Discount       int    `json:"discount"`
+	DeliveryFee    int    `json:"deliveryFee"`
+	Total          int    `json:"total"`
+	Email          string `json:"email"`
+	FullName       string `json:"fullName"`
+	Address        string `json:"address"`
+	DeliveryMethod string `json:"deliveryMethod"`
+	PaymentMethod  string `json:"paymentMethod"`
+}
+
+type HistoryOrderV2 struct {
+	ID            int      `json:"id"`
+	Invoice       string   `json:"invoice"`
+	Date          string   `json:"date"`
+	Status        string   `json:"status"`
+	StatusDisplay string   `json:"statusDisplay"`
+	Total         int      `json:"total"`
+	ProductImages []string `json:"productImages"`
+	TotalItems    int      `json:"totalItems"`
+	// OrderDate is the sort key behind Date, used for cursors.
+	OrderDate time.Time `json:"-"`
+}
+
+type AuditLogV2 struct {
+	ID         int64           `json:"id"`
+	ActorID    *int            `json:"actorId"`
+	ActorEmail string          `json:"actorEmail"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entityType"`
+	EntityID   int             `json:"entityId"`
+	Before     json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After      json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	Changes    json.RawMessage `json:"changes,omitempty" swaggertype:"object"`
+	IPAddress  string          `json:"ipAddress"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+func NewAuditLogListV2(entries []AuditLog) []AuditLogV2 {
+	out := make([]AuditLogV2, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, AuditLogV2{
+			ID:         e.ID,
+			ActorID:    e.ActorID,
+			ActorEmail: e.ActorEmail,
+			Action:     e.Action,
+			EntityType: e.EntityType,
+			EntityID:   e.EntityID,
+			Before:     e.Before,
+			After:      e.After,
+			Changes:    e.Changes,
+			IPAddress:  e.IPAddress,
+			CreatedAt:  e.CreatedAt,
+		})
+	}
+	return out
+}
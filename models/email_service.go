@@ -1,7 +1,9 @@
 package models
 
 import (
+	"coffee-shop/i18n"
 	"fmt"
+	"html"
 	"os"
 	"strconv"
 
@@ -32,11 +34,84 @@ func NewEmailService() (*EmailService, error) {
 	return &EmailService{dialer: dialer}, nil
 }
 
-func (s *EmailService) SendOTPEmail(toEmail, otp string) error {
+type otpEmailCopy struct {
+	Subject, Heading, Greeting, Intro, CodeLabel, Expiry, Ignore, Regards, Automated, Rights string
+}
+
+type orderEmailCopy struct {
+	Subject, Heading, Thanks, OrderNumber, TotalAmount, Processing, Closing, Rights string
+}
+
+// Email text per locale. Unsupported locales fall back to the default locale
+// and then to English.
+var otpEmailCopies = map[string]otpEmailCopy{
+	i18n.LocaleEN: {
+		Subject:   "Password Reset OTP - Harlan Holden Coffee",
+		Heading:   "Password Reset Request",
+		Greeting:  "Hello,",
+		Intro:     "You have requested to reset your password. Please use the following One-Time Password (OTP) to proceed:",
+		CodeLabel: "Your OTP Code",
+		Expiry:    "This code will expire in 5 minutes.",
+		Ignore:    "If you did not request a password reset, please ignore this email or contact support if you have concerns.",
+		Regards:   "Best regards,<br>Harlan Holden Coffee Team",
+		Automated: "This is an automated email. Please do not reply.",
+		Rights:    "All rights reserved.",
+	},
+	i18n.LocaleID: {
+		Subject:   "OTP Reset Kata Sandi - Harlan Holden Coffee",
+		Heading:   "Permintaan Reset Kata Sandi",
+		Greeting:  "Halo,",
+		Intro:     "Kamu meminta untuk mereset kata sandi. Gunakan kode One-Time Password (OTP) berikut untuk melanjutkan:",
+		CodeLabel: "Kode OTP Kamu",
+		Expiry:    "Kode ini akan kedaluwarsa dalam 5 menit.",
+		Ignore:    "Jika kamu tidak meminta reset kata sandi, abaikan email ini atau hubungi tim support kami.",
+		Regards:   "Salam hangat,<br>Tim Harlan Holden Coffee",
+		Automated: "Email ini dikirim otomatis. Mohon tidak membalas email ini.",
+		Rights:    "Hak cipta dilindungi.",
+	},
+}
+
+var orderEmailCopies = map[string]orderEmailCopy{
+	i18n.LocaleEN: {
+		Subject:     "Order Confirmation #%s - Harlan Holden Coffee",
+		Heading:     "Order Confirmation",
+		Thanks:      "Thank you for your order!",
+		OrderNumber: "Order Number",
+		TotalAmount: "Total Amount",
+		Processing:  "Your order has been received and is being processed. We'll notify you when your order is ready.",
+		Closing:     "Thank you for choosing us!<br>Harlan Holden Coffee Team",
+		Rights:      "All rights reserved.",
+	},
+	i18n.LocaleID: {
+		Subject:     "Konfirmasi Pesanan #%s - Harlan Holden Coffee",
+		Heading:     "Konfirmasi Pesanan",
+		Thanks:      "Terima kasih atas pesananmu!",
+		OrderNumber: "Nomor Pesanan",
+		TotalAmount: "Total Pembayaran",
+		Processing:  "Pesananmu sudah kami terima dan sedang diproses. Kami akan mengabarimu saat pesanan siap.",
+		Closing:     "Terima kasih telah memilih kami!<br>Tim Harlan Holden Coffee",
+		Rights:      "Hak cipta dilindungi.",
+	},
+}
+
+func emailLocale(locale string) string {
+	if locale = i18n.Normalize(locale); locale != "" {
+		return locale
+	}
+	return i18n.DefaultLocale()
+}
+
+func (s *EmailService) SendOTPEmail(toEmail, otp, locale string) error {
+	text, ok := otpEmailCopies[emailLocale(locale)]
+	if !ok {
+		text = otpEmailCopies[i18n.LocaleEN]
+	}
+
 	m := gomail.NewMessage()
 	m.SetHeader("From", os.Getenv("SMTP_FROM"))
 	m.SetHeader("To", toEmail)
-	m.SetHeader("Subject", "Password Reset OTP - Harlan Holden Coffee")
+	m.SetHeader("Content-Language", emailLocale(locale))
+	m.SetHeader("Subject", text.Subject)
 
 	body := fmt.Sprintf(`
 <!DOCTYPE html>
@@ -57,30 +132,31 @@ func (s *EmailService) SendOTPEmail(toEmail, otp string) error {
         <div class="header">
             <div class="logo">Harlan Holden Coffee</div>
         </div>
-        <h2 style="color: #333;">Password Reset Request</h2>
-        <p>Hello,</p>
-        <p>You have requested to reset your password. Please use the following One-Time Password (OTP) to proceed:</p>
+        <h2 style="color: #333;">%s</h2>
+        <p>%s</p>
+        <p>%s</p>
         
         <div class="otp-box">
-            <div style="color: #666; font-size: 14px; margin-bottom: 10px;">Your OTP Code</div>
+            <div style="color: #666; font-size: 14px; margin-bottom: 10px;">%s</div>
             <div class="otp-code">%s</div>
         </div>
         
-        <p><strong>This code will expire in 5 minutes.</strong></p>
-        <p>If you did not request a password reset, please ignore this email or contact support if you have concerns.</p>
+        <p><strong>%s</strong></p>
+        <p>%s</p>
         
         <div style="margin-top: 30px; padding-top: 20px; border-top: 1px solid #eee;">
-            <p style="color: #666; font-size: 14px;">Best regards,<br>Harlan Holden Coffee Team</p>
+            <p style="color: #666; font-size: 14px;">%s</p>
         </div>
         
         <div class="footer">
-            <p>This is an automated email. Please do not reply.</p>
-            <p>&copy; 2024 Harlan Holden Coffee. All rights reserved.</p>
+            <p>%s</p>
+            <p>&copy; 2024 Harlan Holden Coffee. %s</p>
         </div>
     </div>
 </body>
 </html>
-	`, otp)
+	`, text.Heading, text.Greeting, text.Intro, text.CodeLabel, otp,
+		text.Expiry, text.Ignore, text.Regards, text.Automated, text.Rights)
 
 	m.SetBody("text/html", body)
 
@@ -91,11 +167,17 @@ func (s *EmailService) SendOTPEmail(toEmail, otp string) error {
 	return nil
 }
 
-func (s *EmailService) SendOrderConfirmationEmail(toEmail, orderNumber string, total int) error {
+func (s *EmailService) SendOrderConfirmationEmail(toEmail, orderNumber string, total int, locale string) error {
+	text, ok := orderEmailCopies[emailLocale(locale)]
+	if !ok {
+		text = orderEmailCopies[i18n.LocaleEN]
+	}
+
 	m := gomail.NewMessage()
 	m.SetHeader("From", os.Getenv("SMTP_FROM"))
 	m.SetHeader("To", toEmail)
-	m.SetHeader("Subject", fmt.Sprintf("Order Confirmation #%s - Harlan Holden Coffee", orderNumber))
+	m.SetHeader("Content-Language", emailLocale(locale))
+	m.SetHeader("Subject", fmt.Sprintf(text.Subject, orderNumber))
 
 	body := fmt.Sprintf(`
 <!DOCTYPE html>
@@ -115,27 +197,80 @@ func (s *EmailService) SendOrderConfirmationEmail(toEmail, orderNumber string, t
         <div class="header">
             <div class="logo">Harlan Holden Coffee</div>
         </div>
-        <h2 style="color: #333;">Order Confirmation</h2>
-        <p>Thank you for your order!</p>
+        <h2 style="color: #333;">%s</h2>
+        <p>%s</p>
         
         <div class="order-box">
-            <p><strong>Order Number:</strong> %s</p>
-            <p><strong>Total Amount:</strong> IDR %s</p>
+            <p><strong>%s:</strong> %s</p>
+            <p><strong>%s:</strong> IDR %s</p>
         </div>
         
-        <p>Your order has been received and is being processed. We'll notify you when your order is ready.</p>
+        <p>%s</p>
         
         <div style="margin-top: 30px; padding-top: 20px; border-top: 1px solid #eee;">
-            <p style="color: #666; font-size: 14px;">Thank you for choosing us!<br>Harlan Holden Coffee Team</p>
+            <p style="color: #666; font-size: 14px;">%s</p>
         </div>
         
         <div class="footer">
-            <p>&copy; 2024 Harlan Holden Coffee. All rights reserved.</p>
+            <p>&copy; 2024 Harlan Holden Coffee. %s</p>
         </div>
     </div>
 </body>
 </html>
-	`, orderNumber, formatRupiah(total))
+	`, text.Heading, text.Thanks, text.OrderNumber, orderNumber,
+		text.TotalAmount, formatRupiah(total), text.Processing, text.Closing, text.Rights)
+
+	m.SetBody("text/html", body)
+
+	if err := s.dialer.DialAndSend(m); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// SendLowStockEmail tells the shop which products have dropped below their
+// low-stock threshold. It goes to staff, so it is always in English.
+func (s *EmailService) SendLowStockEmail(toEmail string, products []Product) error {
+	m := gomail.NewMessage()
+	m.SetHeader("From", os.Getenv("SMTP_FROM"))
+	m.SetHeader("To", toEmail)
+	m.SetHeader("Subject", fmt.Sprintf("Low stock: %d product(s) - Harlan Holden Coffee", len(products)))
+
+	rows := ""
+	for _, p := range products {
+		rows += fmt.Sprintf(`
+            <tr><td style="padding: 8px; border-bottom: 1px solid #eee;">%s</td>
+                <td style="padding: 8px; border-bottom: 1px solid #eee; text-align: right;">%d</td>
+                <td style="padding: 8px; border-bottom: 1px solid #eee; text-align: right;">%d</td></tr>`,
+			html.EscapeString(p.Name), p.Stock, p.LowStockThreshold)
+	}
+
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; background-color: #f4f4f4; padding: 20px; }
+        .container { max-width: 600px; margin: 0 auto; background-color: white; padding: 30px; border-radius: 10px; box-shadow: 0 2px 10px rgba(0,0,0,0.1); }
+        .logo { font-size: 24px; font-weight: bold; color: #f97316; text-align: center; margin-bottom: 30px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="logo">Harlan Holden Coffee</div>
+        <h2 style="color: #333;">Low Stock Alert</h2>
+        <p>A recent order took these products below their low-stock threshold:</p>
+        <table style="width: 100%%; border-collapse: collapse;">
+            <tr><th style="text-align: left; padding: 8px;">Product</th>
+                <th style="text-align: right; padding: 8px;">In stock</th>
+                <th style="text-align: right; padding: 8px;">Threshold</th></tr>%s
+        </table>
+        <p style="color: #666; font-size: 14px; margin-top: 30px;">Record deliveries with a restock in the admin panel.</p>
+    </div>
+</body>
+</html>
+	`, rows)
 
 	m.SetBody("text/html", body)
 
@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// FlashSale is a campaign that sells its products below their regular price
+// from StartsAt until EndsAt. An inactive sale is kept but never runs.
+type FlashSale struct {
+	ID        int             `json:"id"`
+	Name      string          `json:"name"`
+	StartsAt  time.Time       `json:"starts_at"`
+	EndsAt    time.Time       `json:"ends_at"`
+	IsActive  bool            `json:"is_active"`
+	Items     []FlashSaleItem `json:"items"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// RunningAt reports whether the sale prices its products at t.
+func (s FlashSale) RunningAt(t time.Time) bool {
+	return s.IsActive && !t.Before(s.StartsAt) && t.Before(s.EndsAt)
+}
+
+// FlashSaleItem is a product of a flash sale. Exactly one of SalePrice and
+// DiscountPercent is set; MaxPerCustomer caps the units one customer may buy
+// at the sale price. Name, ImageURL and RegularPrice come from the product,
+// and Price is what it sells for during the sale.
+type FlashSaleItem struct {
+	ProductID       int    `json:"product_id"`
+	Name            string `json:"name"`
+	ImageURL        string `json:"image_url"`
+	RegularPrice    int    `json:"regular_price"`
+	Price           int    `json:"price"`
+	SalePrice       *int   `json:"sale_price"`
+	DiscountPercent *int   `json:"discount_percent"`
+	MaxPerCustomer  *int   `json:"max_per_customer"`
+}
+
+// PriceFor is the item's sale price for a product whose regular price is
+// regular, rounded to the rupiah.
+func (i FlashSaleItem) PriceFor(regular int) int {
+	if i.SalePrice != nil {
+		return *i.SalePrice
+	}
+	if i.DiscountPercent != nil {
+		return (regular*(100-*i.DiscountPercent) + 50) / 100
+	}
+	return regular
+}
+
+// FlashSalePrice is the price a product sells at in the flash sale running
+// now. DiscountPercent is the saving on the regular price, rounded down.
+type FlashSalePrice struct {
+	FlashSaleID     int       `json:"flash_sale_id"`
+	Name            string    `json:"name"`
+	RegularPrice    int       `json:"regular_price"`
+	Price           int       `json:"price"`
+	DiscountPercent int       `json:"discount_percent"`
+	MaxPerCustomer  *int      `json:"max_per_customer"`
+	EndsAt          time.Time `json:"ends_at"`
+}
+
+// NewFlashSalePrice is the price of item in sale.
+func NewFlashSalePrice(sale FlashSale, item FlashSaleItem) FlashSalePrice {
+	price := item.PriceFor(item.RegularPrice)
+	p := FlashSalePrice{
+		FlashSaleID:    sale.ID,
+		Name:           sale.Name,
+		RegularPrice:   item.RegularPrice,
+		Price:          price,
+		MaxPerCustomer: item.MaxPerCustomer,
+		EndsAt:         sale.EndsAt,
+	}
+	if item.RegularPrice > 0 {
+		p.DiscountPercent = (item.RegularPrice - price) * 100 / item.RegularPrice
+	}
+	return p
+}
+
+// ActiveFlashSale is a running flash sale as the storefront shows it, with
+// the seconds left on its countdown.
+type ActiveFlashSale struct {
+	FlashSale
+	EndsInSeconds int64 `json:"ends_in_seconds"`
+}
+
+// FlashSaleRequest is the JSON body that creates or replaces a flash sale.
+// IsActive defaults to true.
+type FlashSaleRequest struct {
+	Name     string                 `json:"name" binding:"required"`
+	StartsAt time.Time              `json:"starts_at" binding:"required"`
+	EndsAt   time.Time              `json:"ends_at" binding:"required"`
+	IsActive *bool                  `json:"is_active"`
+	Items    []FlashSaleItemRequest `json:"items" binding:"required,dive"`
+}
+
+type FlashSaleItemRequest struct {
+	ProductID       int  `json:"product_id" binding:"required"`
+	SalePrice       *int `json:"sale_price"`
+	DiscountPercent *int `json:"discount_percent"`
+	MaxPerCustomer  *int `json:"max_per_customer"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Stock movement types, stored in stock_movements.movement_type.
+const (
+	StockMovementSale         = "sale"
+	StockMovementRestock      = "restock"
+	StockMovementAdjustment   = "adjustment"
+	StockMovementCancellation = "cancellation"
+	StockMovementWaste        = "waste"
+)
+
+// StockMovement is one entry of a product's inventory ledger. Quantity is the
+// signed change to the stock and StockAfter the level it left. OrderID is set
+// for sales and cancellations, ActorID for movements made by a signed-in user.
+type StockMovement struct {
+	ID         int       `json:"id"`
+	ProductID  int       `json:"product_id"`
+	Type       string    `json:"type"`
+	Quantity   int       `json:"quantity"`
+	StockAfter int       `json:"stock_after"`
+	Reason     string    `json:"reason"`
+	OrderID    *int      `json:"order_id"`
+	ActorID    *int      `json:"actor_id"`
+	ActorEmail string    `json:"actor_email,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ProductPrice is one entry of a product's price history, or a change
+// scheduled for it while AppliedAt is nil. PreviousPrice is the price it
+// replaced and is nil for the product's first price. ActorID is the user who
+// made or scheduled the change.
+type ProductPrice struct {
+	ID            int        `json:"id"`
+	ProductID     int        `json:"product_id"`
+	Price         int        `json:"price"`
+	PreviousPrice *int       `json:"previous_price"`
+	EffectiveAt   time.Time  `json:"effective_at"`
+	AppliedAt     *time.Time `json:"applied_at"`
+	Reason        string     `json:"reason"`
+	ActorID       *int       `json:"actor_id"`
+	ActorEmail    string     `json:"actor_email,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// PriceChangeRequest is the JSON body that schedules a price change.
+type PriceChangeRequest struct {
+	Price       int       `json:"price" binding:"required"`
+	EffectiveAt time.Time `json:"effective_at" binding:"required"`
+	Reason      string    `json:"reason"`
+}
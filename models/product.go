@@ -2,19 +2,103 @@ package models
 
 import "time"
 
+// Product is also the v1 response body, which keeps calling IsFeatured, the
+// shop's own pick, is_favorite: its name from before customers had
+// favourites of their own.
 type Product struct {
+	ID                int       `json:"id"`
+	SKU               string    `json:"sku,omitempty"`
+	Name              string    `json:"name"`
+	Description       string    `json:"description"`
+	CategoryID        int       `json:"category_id"`
+	Price             int       `json:"price"`
+	Stock             int       `json:"stock"`
+	LowStockThreshold int       `json:"low_stock_threshold"`
+	ImageURL          string    `json:"image_url"`
+	CloudinaryID      string    `json:"cloudinary_id,omitempty"`
+	IsFlashSale       bool      `json:"is_flash_sale"`
+	IsFeatured        bool      `json:"is_favorite"`
+	IsBuy1Get1        bool      `json:"is_buy1get1"`
+	IsActive          bool      `json:"is_active"`
+	AverageRating     float64   `json:"average_rating"`
+	ReviewCount       int       `json:"review_count"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	// DeletedAt is set while the product is archived.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// FlashSale is set while the product is in a running flash sale.
+	FlashSale *FlashSalePrice `json:"flash_sale,omitempty"`
+	// Relevance and Highlight are only set on search results.
+	Relevance float64          `json:"relevance,omitempty"`
+	Highlight *SearchHighlight `json:"highlight,omitempty"`
+	// IsFavorited is set for signed-in customers: whether the product is in
+	// their favourites. FavoritedAt is set on their favourites list.
+	IsFavorited *bool      `json:"is_favorited,omitempty"`
+	FavoritedAt *time.Time `json:"favorited_at,omitempty"`
+	// RecommendationType is only set on recommendations: why the product
+	// is suggested.
+	RecommendationType string `json:"recommendation_type,omitempty"`
+}
+
+// ProductImage is one picture in a product's gallery. The primary image is
+// also stored on the product as image_url.
+type ProductImage struct {
 	ID           int       `json:"id"`
-	Name         string    `json:"name"`
-	Description  string    `json:"description"`
-	CategoryID   int       `json:"category_id"`
-	Price        int       `json:"price"`
-	Stock        int       `json:"stock"`
-	ImageURL     string    `json:"image_url"`
+	ProductID    int       `json:"product_id"`
+	URL          string    `json:"image_url"`
 	CloudinaryID string    `json:"cloudinary_id,omitempty"`
-	IsFlashSale  bool      `json:"is_flash_sale"`
-	IsFavorite   bool      `json:"is_favorite"`
-	IsBuy1Get1   bool      `json:"is_buy1get1"`
-	IsActive     bool      `json:"is_active"`
+	IsPrimary    bool      `json:"is_primary"`
+	Order        int       `json:"display_order"`
 	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ProductOption is one size, temperature and variant combination a product
+// is sold in, from product_details, with its surcharge on the product price.
+// A nil ID means the combination has no choice of that kind. A product
+// without options is only sold plain.
+type ProductOption struct {
+	ID              int       `json:"id"`
+	ProductID       int       `json:"product_id"`
+	SizeID          *int      `json:"size_id"`
+	Size            string    `json:"size,omitempty"`
+	TemperatureID   *int      `json:"temperature_id"`
+	Temperature     string    `json:"temperature,omitempty"`
+	VariantID       *int      `json:"variant_id"`
+	Variant         string    `json:"variant,omitempty"`
+	PriceAdjustment int       `json:"price_adjustment"`
+	IsActive        bool      `json:"is_active"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// SearchHighlight is the HTML-escaped name and description of a search
+// result with matched words wrapped in <mark> tags.
+type SearchHighlight struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ProductFacets count the products matching a listing's filters, so the
+// storefront can show how many results each refinement would leave.
+type ProductFacets struct {
+	Categories  []CategoryFacet `json:"categories"`
+	PriceRanges []PriceFacet    `json:"price_ranges"`
+	FlashSale   int             `json:"flash_sale"`
+	Featured    int             `json:"favorite"`
+	Buy1Get1    int             `json:"buy1get1"`
+	InStock     int             `json:"in_stock"`
+}
+
+type CategoryFacet struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// PriceFacet counts products priced from Min up to, but not including, Max.
+// Max is 0 for the open-ended top range.
+type PriceFacet struct {
+	Min   int `json:"min"`
+	Max   int `json:"max,omitempty"`
+	Count int `json:"count"`
 }
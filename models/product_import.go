@@ -0,0 +1,45 @@
+package models
+
+// Product import row actions.
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionError     = "error"
+)
+
+// ProductImportChange is one field a row of a product import changes, with
+// the stored and imported values as they appear in the sheet.
+type ProductImportChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// ProductImportRow is what a product import does with one row of the sheet.
+// Row is the sheet's row number, so the header is row 1.
+type ProductImportRow struct {
+	Row       int                   `json:"row"`
+	Action    string                `json:"action"`
+	ProductID int                   `json:"product_id,omitempty"`
+	SKU       string                `json:"sku,omitempty"`
+	Name      string                `json:"name"`
+	Changes   []ProductImportChange `json:"changes,omitempty"`
+	Errors    []string              `json:"errors,omitempty"`
+}
+
+// ProductImportSummary counts the rows of an import by action.
+type ProductImportSummary struct {
+	Create    int `json:"create"`
+	Update    int `json:"update"`
+	Unchanged int `json:"unchanged"`
+	Error     int `json:"error"`
+}
+
+// ProductImportReport is the row-by-row diff of a product import. Applied is
+// false for a dry run and for an import rejected because of row errors.
+type ProductImportReport struct {
+	Applied bool                 `json:"applied"`
+	Summary ProductImportSummary `json:"summary"`
+	Rows    []ProductImportRow   `json:"rows"`
+}
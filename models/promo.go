@@ -0,0 +1,12 @@
+package models
+
+// Promo is a discount code shown as a banner in the storefront, in the
+// colours it is drawn with.
+type Promo struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Code        string `json:"code"`
+	BgColor     string `json:"bgColor"`
+	TextColor   string `json:"textColor"`
+}
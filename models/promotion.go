@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+const (
+	// PromotionBuyXGetY discounts GetQuantity units of a product for every
+	// BuyQuantity units of it bought; buy 1 get 1 is the common case.
+	PromotionBuyXGetY = "buy_x_get_y"
+	// PromotionMixAndMatch does the same across every product of a category.
+	PromotionMixAndMatch = "mix_and_match"
+)
+
+// PromotionTypes lists the valid PromotionRule types.
+var PromotionTypes = []string{PromotionBuyXGetY, PromotionMixAndMatch}
+
+// PromotionRule discounts GetQuantity units by DiscountPercent (100 makes
+// them free) for every BuyQuantity units bought. A buy_x_get_y rule has a
+// ProductID and a mix_and_match rule a CategoryID. A nil StartsAt or EndsAt
+// leaves that end of the schedule open.
+type PromotionRule struct {
+	ID              int        `json:"id"`
+	Name            string     `json:"name"`
+	Type            string     `json:"type"`
+	ProductID       *int       `json:"product_id"`
+	CategoryID      *int       `json:"category_id"`
+	BuyQuantity     int        `json:"buy_quantity"`
+	GetQuantity     int        `json:"get_quantity"`
+	DiscountPercent int        `json:"discount_percent"`
+	StartsAt        *time.Time `json:"starts_at"`
+	EndsAt          *time.Time `json:"ends_at"`
+	IsActive        bool       `json:"is_active"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// RunningAt reports whether the rule applies at t.
+func (r PromotionRule) RunningAt(t time.Time) bool {
+	return r.IsActive && (r.StartsAt == nil || !t.Before(*r.StartsAt)) && (r.EndsAt == nil || t.Before(*r.EndsAt))
+}
+
+// PromotionAdjustment is the discount a promotion gives one cart or order
+// line: Quantity of its units discounted by Amount in total. A nil
+// PromotionRuleID is the product's own buy 1 get 1 flag.
+type PromotionAdjustment struct {
+	PromotionRuleID *int   `json:"promotion_rule_id"`
+	Name            string `json:"name"`
+	Quantity        int    `json:"quantity"`
+	Amount          int    `json:"amount"`
+}
+
+// PromotionRuleRequest is the JSON body that creates or replaces a
+// promotion rule. DiscountPercent defaults to 100 and IsActive to true.
+type PromotionRuleRequest struct {
+	Name            string     `json:"name" binding:"required"`
+	Type            string     `json:"type" binding:"required"`
+	ProductID       *int       `json:"product_id"`
+	CategoryID      *int       `json:"category_id"`
+	BuyQuantity     int        `json:"buy_quantity" binding:"required"`
+	GetQuantity     int        `json:"get_quantity" binding:"required"`
+	DiscountPercent *int       `json:"discount_percent"`
+	StartsAt        *time.Time `json:"starts_at"`
+	EndsAt          *time.Time `json:"ends_at"`
+	IsActive        *bool      `json:"is_active"`
+}
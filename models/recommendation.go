@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// The shop curates three kinds of recommendation: an upsell is a pricier
+// alternative to the product, a cross-sell something else the customer may
+// want and a pairing something that goes with it.
+const (
+	RecommendationUpsell    = "upsell"
+	RecommendationCrossSell = "cross_sell"
+	RecommendationPairing   = "pairing"
+)
+
+// The other recommendation types say where a suggestion came from when the
+// curated ones run out: products often ordered together with it, products
+// from the same category and the best sellers.
+const (
+	RecommendationBoughtTogether = "bought_together"
+	RecommendationSameCategory   = "same_category"
+	RecommendationBestSelling    = "best_selling"
+)
+
+// RecommendationTypes lists the types a ProductRecommendation can have.
+var RecommendationTypes = []string{RecommendationUpsell, RecommendationCrossSell, RecommendationPairing}
+
+// ProductRecommendation is a product the shop recommends alongside another.
+// Higher priorities are shown first.
+type ProductRecommendation struct {
+	ID                     int       `json:"id"`
+	ProductID              int       `json:"product_id"`
+	RecommendedProductID   int       `json:"recommended_product_id"`
+	RecommendedProductName string    `json:"recommended_product_name,omitempty"`
+	Type                   string    `json:"recommendation_type"`
+	Priority               int       `json:"priority"`
+	IsActive               bool      `json:"is_active"`
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// A review waits for moderation until an admin approves or hides it; only
+// approved reviews are shown and counted in the product's rating.
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewHidden   = "hidden"
+)
+
+// ReviewStatuses lists the valid ProductReview statuses.
+var ReviewStatuses = []string{ReviewPending, ReviewApproved, ReviewHidden}
+
+// ProductReview is a customer's review of a product, with the shop's reply.
+// User is the reviewer's full name.
+type ProductReview struct {
+	ID          int        `json:"id"`
+	ProductID   int        `json:"product_id"`
+	ProductName string     `json:"product_name,omitempty"`
+	UserID      int        `json:"user_id"`
+	User        string     `json:"user"`
+	Rating      int        `json:"rating"`
+	Text        string     `json:"text"`
+	Status      string     `json:"status"`
+	Reply       string     `json:"reply,omitempty"`
+	RepliedAt   *time.Time `json:"replied_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ReviewRequest is the JSON body that writes or edits a review. Rating is
+// required when writing one; fields left out of an edit keep their value.
+type ReviewRequest struct {
+	Rating *int    `json:"rating"`
+	Text   *string `json:"text"`
+}
+
+// ReviewModerationRequest is the JSON body an admin moderates a review with:
+// a new status, a reply, or both. An empty reply removes the reply.
+type ReviewModerationRequest struct {
+	Status *string `json:"status"`
+	Reply  *string `json:"reply"`
+}
+
+// RatingSummary describes a product's approved reviews. Distribution counts
+// them per rating, from 5 stars down to 1.
+type RatingSummary struct {
+	Average      float64       `json:"average"`
+	Count        int           `json:"count"`
+	Distribution []RatingCount `json:"distribution"`
+}
+
+type RatingCount struct {
+	Rating int `json:"rating"`
+	Count  int `json:"count"`
+}
+
+// NewRatingSummary builds the summary from the number of reviews per rating.
+func NewRatingSummary(counts map[int]int) RatingSummary {
+	summary := RatingSummary{Distribution: make([]RatingCount, 0, 5)}
+	sum := 0
+	for rating := 5; rating >= 1; rating-- {
+		summary.Distribution = append(summary.Distribution, RatingCount{Rating: rating, Count: counts[rating]})
+		summary.Count += counts[rating]
+		sum += rating * counts[rating]
+	}
+	if summary.Count > 0 {
+		summary.Average = float64(sum) / float64(summary.Count)
+	}
+	return summary
+}
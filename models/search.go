@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Suggestions answer the storefront search box while the user types.
+type Suggestions struct {
+	Products   []SuggestedProduct  `json:"products"`
+	Categories []SuggestedCategory `json:"categories"`
+	Queries    []string            `json:"queries"`
+}
+
+type SuggestedProduct struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type SuggestedCategory struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// A past search waits for moderation until an admin allows or blocks it;
+// only allowed searches are suggested to other shoppers.
+const (
+	SearchQueryPending = "pending"
+	SearchQueryAllowed = "allowed"
+	SearchQueryBlocked = "blocked"
+)
+
+// SearchQueryStatuses lists the valid SearchQuery statuses.
+var SearchQueryStatuses = []string{SearchQueryPending, SearchQueryAllowed, SearchQueryBlocked}
+
+// SearchQuery is a normalized storefront search that found products. Hits
+// counts the shoppers who ran it, each at most once a day.
+type SearchQuery struct {
+	ID             int       `json:"id"`
+	Query          string    `json:"query"`
+	Hits           int       `json:"hits"`
+	Status         string    `json:"status"`
+	LastSearchedAt time.Time `json:"last_searched_at"`
+}
+
+// SearchQueryModerationRequest is the JSON body an admin allows or blocks a
+// past search with.
+type SearchQueryModerationRequest struct {
+	Status string `json:"status" binding:"required"`
+}
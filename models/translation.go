@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Translations override the base name/description columns, which hold the
+// content in the shop's default language. Lookups fall back field by field:
+// a translation for the requested locale wins when it is non-empty, otherwise
+// the base column is used.
+
+type ProductTranslation struct {
+	ProductID   int       `json:"product_id"`
+	Locale      string    `json:"locale"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CategoryTranslation struct {
+	CategoryID int       `json:"category_id"`
+	Locale     string    `json:"locale"`
+	Name       string    `json:"name"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type TranslationRequest struct {
+	Name        string `json:"name" form:"name"`
+	Description string `json:"description" form:"description"`
+}
+
+// Apply overlays the non-empty translated fields onto name and description.
+func (t ProductTranslation) Apply(name, description *string) {
+	if t.Name != "" {
+		*name = t.Name
+	}
+	if t.Description != "" && description != nil {
+		*description = t.Description
+	}
+}
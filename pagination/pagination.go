@@ -0,0 +1,196 @@
+// Package pagination reads page/limit and cursor query parameters and builds
+// the paginated response envelope shared by the list endpoints.
+//
+// Two modes are supported. Page mode (?page=2&limit=10) is the original
+// OFFSET pagination. Cursor mode (?cursor=...&limit=10) continues after the
+// last item of the previous page using its (created_at, id) key, so it stays
+// fast on deep pages and does not skip or repeat rows when new ones arrive.
+// Every response carries a next_cursor, so a client can switch to cursor mode
+// after fetching the first page normally.
+package pagination
+
+import (
+	"coffee-shop/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxLimit caps the page size a client may ask for.
+const MaxLimit = 100
+
+// ErrInvalidCursor is returned for a cursor that was not produced by Encode.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the sort key of the last item on a page. Lists are ordered by
+// (created_at, id) descending, so the next page holds the items strictly
+// before it.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+type cursorJSON struct {
+	T time.Time `json:"t"`
+	I int       `json:"i"`
+}
+
+// Encode returns the opaque form of c used in URLs.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(cursorJSON{T: c.CreatedAt.UTC(), I: c.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a cursor produced by Encode.
+func Decode(s string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var raw cursorJSON
+	if err := json.Unmarshal(data, &raw); err != nil || raw.I <= 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{CreatedAt: raw.T, ID: raw.I}, nil
+}
+
+// Params is the requested window. When After is set the list continues after
+// that cursor and Offset is zero; otherwise Offset is derived from Page.
+type Params struct {
+	Page   int
+	Limit  int
+	Offset int
+	After  *Cursor
+}
+
+// Keyset reports whether the request is in cursor mode.
+func (p Params) Keyset() bool {
+	return p.After != nil
+}
+
+// FromQuery reads page, limit and cursor from the request. Missing or
+// non-positive values fall back to page 1 and defaultLimit, and limit is
+// capped at MaxLimit.
+func FromQuery(c *gin.Context, defaultLimit int) (Params, error) {
+	p := Params{}
+	p.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	p.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	if p.Limit < 1 {
+		p.Limit = defaultLimit
+	}
+	if p.Limit > MaxLimit {
+		p.Limit = MaxLimit
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := Decode(raw)
+		if err != nil {
+			return Params{}, err
+		}
+		p.After = &cursor
+		p.Page = 0
+		return p, nil
+	}
+
+	p.Offset = (p.Page - 1) * p.Limit
+	return p, nil
+}
+
+// Next returns the cursor after last when the page was full, or nil when
+// there can be nothing after it.
+func Next(p Params, count int, last Cursor) *Cursor {
+	if count < p.Limit || count == 0 {
+		return nil
+	}
+	return &last
+}
+
+// TotalPages is the number of pages of size limit needed for total items.
+func TotalPages(total, limit int) int {
+	if total <= 0 || limit <= 0 {
+		return 0
+	}
+	return (total + limit - 1) / limit
+}
+
+// Response builds the HATEOAS envelope for one page of data. next is the
+// cursor of the following page, if any.
+func Response(c *gin.Context, message string, data interface{}, p Params, total int, next *Cursor) models.HATEOASResponse {
+	totalPages := TotalPages(total, p.Limit)
+
+	meta := models.PaginationMeta{
+		Page:       p.Page,
+		Limit:      p.Limit,
+		TotalItems: total,
+		TotalPages: totalPages,
+	}
+	if next != nil {
+		meta.NextCursor = next.Encode()
+	}
+
+	return models.HATEOASResponse{
+		Success: true,
+		Message: message,
+		Data:    data,
+		Meta:    meta,
+		Links:   Links(c, p, totalPages, next),
+	}
+}
+
+// Links builds the self, next and prev URLs for the page. In page mode they
+// step the page number; in cursor mode next follows the cursor and there is
+// no prev link.
+func Links(c *gin.Context, p Params, totalPages int, next *Cursor) models.PaginationLinks {
+	scheme := "https"
+	if c.Request.TLS == nil {
+		scheme = "http"
+	}
+
+	makeURL := func(set func(url.Values)) string {
+		params := url.Values{}
+		for key, values := range c.Request.URL.Query() {
+			if key == "page" || key == "cursor" || key == "limit" {
+				continue
+			}
+			for _, value := range values {
+				params.Add(key, value)
+			}
+		}
+		set(params)
+		params.Set("limit", strconv.Itoa(p.Limit))
+		return fmt.Sprintf("%s://%s%s?%s", scheme, c.Request.Host, c.Request.URL.Path, params.Encode())
+	}
+	pageURL := func(page int) string {
+		return makeURL(func(v url.Values) { v.Set("page", strconv.Itoa(page)) })
+	}
+	cursorURL := func(cursor Cursor) string {
+		return makeURL(func(v url.Values) { v.Set("cursor", cursor.Encode()) })
+	}
+
+	if p.Keyset() {
+		links := models.PaginationLinks{Self: cursorURL(*p.After)}
+		if next != nil {
+			links.Next = cursorURL(*next)
+		}
+		return links
+	}
+
+	links := models.PaginationLinks{Self: pageURL(p.Page)}
+	if p.Page > 1 {
+		links.Prev = pageURL(p.Page - 1)
+	}
+	if p.Page < totalPages {
+		links.Next = pageURL(p.Page + 1)
+	}
+	return links
+}
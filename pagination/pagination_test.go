@@ -0,0 +1,101 @@
+package pagination
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newContext(target string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", target, nil)
+	return c
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := Cursor{CreatedAt: time.Date(2025, 3, 1, 10, 30, 0, 123, time.UTC), ID: 42}
+
+	got, err := Decode(want.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{"nope", "e30", Cursor{}.Encode()} {
+		if _, err := Decode(bad); err != ErrInvalidCursor {
+			t.Errorf("Decode(%q) error = %v", bad, err)
+		}
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	p, err := FromQuery(newContext("/items?page=3&limit=500"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Page != 3 || p.Limit != MaxLimit || p.Offset != 2*MaxLimit || p.Keyset() {
+		t.Fatalf("page mode params = %+v", p)
+	}
+
+	cursor := Cursor{CreatedAt: time.Now(), ID: 7}
+	p, err = FromQuery(newContext("/items?page=3&limit=0&cursor="+cursor.Encode()), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Keyset() || p.After.ID != 7 || p.Offset != 0 || p.Limit != 10 {
+		t.Fatalf("cursor mode params = %+v", p)
+	}
+
+	if _, err := FromQuery(newContext("/items?cursor=garbage"), 10); err != ErrInvalidCursor {
+		t.Fatalf("error = %v", err)
+	}
+}
+
+func TestResponseLinks(t *testing.T) {
+	c := newContext("/products?page=2&limit=5&search=latte")
+	p, _ := FromQuery(c, 10)
+	next := &Cursor{CreatedAt: time.Now(), ID: 3}
+
+	resp := Response(c, "ok", nil, p, 12, next)
+	if resp.Meta.TotalPages != 3 || resp.Meta.NextCursor != next.Encode() {
+		t.Fatalf("meta = %+v", resp.Meta)
+	}
+	assertQuery(t, resp.Links.Prev, "page", "1")
+	assertQuery(t, resp.Links.Next, "page", "3")
+	assertQuery(t, resp.Links.Next, "search", "latte")
+
+	c = newContext("/products?limit=5&cursor=" + next.Encode())
+	p, _ = FromQuery(c, 10)
+	resp = Response(c, "ok", nil, p, 12, nil)
+	if resp.Links.Next != "" || resp.Links.Prev != "" {
+		t.Fatalf("last cursor page links = %+v", resp.Links)
+	}
+	assertQuery(t, resp.Links.Self, "cursor", next.Encode())
+}
+
+func TestNext(t *testing.T) {
+	last := Cursor{ID: 1}
+	if Next(Params{Limit: 5}, 4, last) != nil {
+		t.Error("short page should have no next cursor")
+	}
+	if Next(Params{Limit: 5}, 5, last) == nil {
+		t.Error("full page should have a next cursor")
+	}
+}
+
+func assertQuery(t *testing.T, link, key, want string) {
+	t.Helper()
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Query().Get(key); got != want {
+		t.Errorf("%s: %s = %q, want %q", link, key, got, want)
+	}
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuditFilter narrows the audit log. Zero fields match every entry; Until is
+// exclusive.
+type AuditFilter struct {
+	ActorID    int
+	EntityType string
+	EntityID   int
+	From       time.Time
+	Until      time.Time
+}
+
+type AuditRepository interface {
+	Record(ctx context.Context, entry models.AuditEntry) error
+	// List returns one page of the entries matching filter, newest first,
+	// and the number of matching entries.
+	List(ctx context.Context, filter AuditFilter, page pagination.Params) ([]models.AuditLog, int, error)
+}
+
+type pgAuditRepository struct {
+	db DBTX
+}
+
+func (r *pgAuditRepository) Record(ctx context.Context, entry models.AuditEntry) error {
+	return models.RecordAudit(ctx, r.db, entry)
+}
+
+func (r *pgAuditRepository) List(ctx context.Context, filter AuditFilter, page pagination.Params) ([]models.AuditLog, int, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ActorID > 0 {
+		add("actor_id = $%d", filter.ActorID)
+	}
+	if filter.EntityType != "" {
+		add("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityID > 0 {
+		add("entity_id = $%d", filter.EntityID)
+	}
+	if !filter.From.IsZero() {
+		add("created_at >= $%d", filter.From)
+	}
+	if !filter.Until.IsZero() {
+		add("created_at < $%d", filter.Until)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM audit_log"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	if page.After != nil {
+		conditions = append(conditions, keyset("created_at", "id", len(args)+1))
+		args = append(args, page.After.CreatedAt, page.After.ID)
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, page.Limit, page.Offset)
+
+	rows, err := r.db.Query(ctx, fmt.Sprintf(
+		`SELECT id, actor_id, COALESCE(actor_email, ''), action, entity_type, entity_id,
+		        before_data, after_data, changes, COALESCE(ip_address, ''), created_at
+		 FROM audit_log%s
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditLog{}
+	for rows.Next() {
+		var e models.AuditLog
+		var before, after, changes []byte
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorEmail, &e.Action, &e.EntityType, &e.EntityID,
+			&before, &after, &changes, &e.IPAddress, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		e.Before = json.RawMessage(before)
+		e.After = json.RawMessage(after)
+		e.Changes = json.RawMessage(changes)
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"context"
+)
+
+// CartItemKey identifies a cart line: the same product with different
+// options is a separate line.
+type CartItemKey struct {
+	UserID        int
+	ProductID     int
+	SizeID        *int
+	TemperatureID *int
+	VariantID     *int
+}
+
+// CartLine is a cart item joined with its product, as needed by checkout.
+type CartLine struct {
+	CartID            int
+	ProductID         int
+	CategoryID        int
+	Name              string
+	Price             int
+	IsBuy1Get1        bool
+	Quantity          int
+	Stock             int
+	LowStockThreshold int
+	SizeID            *int
+	TemperatureID     *int
+	VariantID         *int
+}
+
+type CartRepository interface {
+	// Items returns the user's cart with base prices, option names and the
+	// locale's product names. Option prices are left to the caller.
+	Items(ctx context.Context, userID int, locale string) ([]models.CartItemV2, error)
+	// FindItem returns the ID and quantity of the matching line, or ErrNotFound.
+	FindItem(ctx context.Context, key CartItemKey) (id, quantity int, err error)
+	AddItem(ctx context.Context, key CartItemKey, quantity int) (int, error)
+	SetQuantity(ctx context.Context, id, quantity int) error
+	// LockForCheckout returns the user's cart lines, locking them (and their
+	// products) until the surrounding transaction ends. Lines for archived
+	// products are left out.
+	LockForCheckout(ctx context.Context, userID int) ([]CartLine, error)
+	Clear(ctx context.Context, userID int) error
+}
+
+type pgCartRepository struct {
+	db DBTX
+}
+
+func (r *pgCartRepository) Items(ctx context.Context, userID int, locale string) ([]models.CartItemV2, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT
+			ci.id,
+			ci.product_id,
+			COALESCE(p.category_id, 0),
+			COALESCE(NULLIF(tr.name, ''), p.name),
+			p.price,
+			COALESCE(p.is_buy1get1, false),
+			ci.quantity,
+			ci.size_id,
+			COALESCE(ps.name,'') as size_name,
+			ci.temperature_id,
+			COALESCE(pt.name,'') as temp_name,
+			ci.variant_id,
+			COALESCE(pv.name,'') as variant_name,
+			COALESCE(p.image_url,'') as image_url,
+			p.stock
+		FROM cart_items ci
+		JOIN products p ON ci.product_id=p.id
+		LEFT JOIN product_translations tr ON tr.product_id=p.id AND tr.locale=$2
+		LEFT JOIN product_sizes ps ON ci.size_id=ps.id
+		LEFT JOIN product_temperatures pt ON ci.temperature_id=pt.id
+		LEFT JOIN product_variants pv ON ci.variant_id=pv.id
+		WHERE ci.user_id=$1
+		AND p.is_active=true
+		AND p.deleted_at IS NULL
+		ORDER BY ci.created_at DESC`, userID, locale)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.CartItemV2{}
+	for rows.Next() {
+		var item models.CartItemV2
+		err := rows.Scan(&item.ID, &item.ProductID, &item.CategoryID, &item.Name, &item.BasePrice, &item.IsBuy1Get1,
+			&item.Quantity,
+			&item.SizeID, &item.Size, &item.TemperatureID, &item.Temperature,
+			&item.VariantID, &item.Variant, &item.ImageURL, &item.Stock)
+		if err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *pgCartRepository) FindItem(ctx context.Context, key CartItemKey) (int, int, error) {
+	var id, quantity int
+	err := r.db.QueryRow(ctx,
+		`SELECT id, quantity FROM cart_items
+		 WHERE user_id=$1
+		 AND product_id=$2
+		 AND ($3::int IS NULL AND size_id IS NULL OR size_id = $3)
+		 AND ($4::int IS NULL AND temperature_id IS NULL OR temperature_id = $4)
+		 AND ($5::int IS NULL AND variant_id IS NULL OR variant_id = $5)`,
+		key.UserID, key.ProductID, key.SizeID, key.TemperatureID, key.VariantID,
+	).Scan(&id, &quantity)
+	if err != nil {
+		return 0, 0, notFound(err)
+	}
+	return id, quantity, nil
+}
+
+func (r *pgCartRepository) AddItem(ctx context.Context, key CartItemKey, quantity int) (int, error) {
+	var id int
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO cart_items (user_id, product_id, quantity, size_id, temperature_id, variant_id, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		 RETURNING id`,
+		key.UserID, key.ProductID, quantity, key.SizeID, key.TemperatureID, key.VariantID,
+	).Scan(&id)
+	return id, err
+}
+
+func (r *pgCartRepository) SetQuantity(ctx context.Context, id, quantity int) error {
+	_, err := r.db.Exec(ctx, "UPDATE cart_items SET quantity=$1, updated_at=NOW() WHERE id=$2", quantity, id)
+	return err
+}
+
+func (r *pgCartRepository) LockForCheckout(ctx context.Context, userID int) ([]CartLine, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT
+			ci.id,
+			ci.product_id,
+			COALESCE(p.category_id, 0),
+			p.name,
+			p.price,
+			COALESCE(p.is_buy1get1, false),
+			ci.quantity,
+			p.stock,
+			p.low_stock_threshold,
+			ci.size_id,
+			ci.temperature_id,
+			ci.variant_id
+		FROM cart_items ci
+		JOIN products p ON ci.product_id = p.id
+		WHERE ci.user_id = $1
+		AND p.deleted_at IS NULL
+		FOR UPDATE`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []CartLine{}
+	for rows.Next() {
+		var l CartLine
+		if err := rows.Scan(&l.CartID, &l.ProductID, &l.CategoryID, &l.Name, &l.Price, &l.IsBuy1Get1, &l.Quantity,
+			&l.Stock, &l.LowStockThreshold,
+			&l.SizeID, &l.TemperatureID, &l.VariantID); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+func (r *pgCartRepository) Clear(ctx context.Context, userID int) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM cart_items WHERE user_id=$1", userID)
+	return err
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"context"
+)
+
+type CategoryRepository interface {
+	// List returns every category with the locale's translated name applied.
+	List(ctx context.Context, locale string) ([]models.Category, error)
+	Get(ctx context.Context, id int, locale string) (models.Category, error)
+	// NameExists reports whether another category (other than excludeID) uses name.
+	NameExists(ctx context.Context, name string, excludeID int) (bool, error)
+	// Create inserts c and fills in its ID and CreatedAt.
+	Create(ctx context.Context, c *models.Category) error
+	UpdateName(ctx context.Context, id int, name string) error
+	Delete(ctx context.Context, id int) error
+}
+
+type pgCategoryRepository struct {
+	db DBTX
+}
+
+func (r *pgCategoryRepository) List(ctx context.Context, locale string) ([]models.Category, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT c.id, COALESCE(NULLIF(ct.name, ''), c.name), COALESCE(c.is_active, true), c.created_at,
+		        GREATEST(c.updated_at, ct.updated_at)
+		 FROM categories c
+		 LEFT JOIN category_translations ct ON ct.category_id = c.id AND ct.locale = $1
+		 ORDER BY c.id`, locale)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var category models.Category
+		if err := rows.Scan(&category.ID, &category.Name, &category.IsActive, &category.CreatedAt, &category.UpdatedAt); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (r *pgCategoryRepository) Get(ctx context.Context, id int, locale string) (models.Category, error) {
+	var category models.Category
+	err := r.db.QueryRow(ctx,
+		`SELECT c.id, COALESCE(NULLIF(ct.name, ''), c.name), COALESCE(c.is_active, true), c.created_at,
+		        GREATEST(c.updated_at, ct.updated_at)
+		 FROM categories c
+		 LEFT JOIN category_translations ct ON ct.category_id = c.id AND ct.locale = $2
+		 WHERE c.id=$1`,
+		id, locale).Scan(&category.ID, &category.Name, &category.IsActive, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		return models.Category{}, notFound(err)
+	}
+	return category, nil
+}
+
+func (r *pgCategoryRepository) NameExists(ctx context.Context, name string, excludeID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM categories WHERE name=$1 AND id!=$2)", name, excludeID).Scan(&exists)
+	return exists, err
+}
+
+func (r *pgCategoryRepository) Create(ctx context.Context, c *models.Category) error {
+	return r.db.QueryRow(ctx,
+		"INSERT INTO categories (name, created_at, updated_at) VALUES ($1, NOW(), NOW()) RETURNING id, COALESCE(is_active, true), created_at, updated_at",
+		c.Name).Scan(&c.ID, &c.IsActive, &c.CreatedAt, &c.UpdatedAt)
+}
+
+func (r *pgCategoryRepository) UpdateName(ctx context.Context, id int, name string) error {
+	tag, err := r.db.Exec(ctx, "UPDATE categories SET name=$1, updated_at=NOW() WHERE id=$2", name, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgCategoryRepository) Delete(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM categories WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
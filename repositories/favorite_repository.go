@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"context"
+	"fmt"
+	"time"
+)
+
+// FavoriteFilter selects one page of a user's favourites, latest first.
+type FavoriteFilter struct {
+	pagination.Params
+	UserID int
+	Locale string
+}
+
+type FavoriteRepository interface {
+	// Add puts the product in the user's favourites and reports whether it
+	// was not there yet; adding it again keeps the original time.
+	Add(ctx context.Context, userID, productID int) (bool, error)
+	// Remove returns ErrNotFound when the product is not a favourite.
+	Remove(ctx context.Context, userID, productID int) error
+	// List returns one page of the user's favourite active, unarchived
+	// products with FavoritedAt set, and their total number.
+	List(ctx context.Context, filter FavoriteFilter) ([]models.Product, int, error)
+	// Favorited returns which of the products the user has favourited.
+	Favorited(ctx context.Context, userID int, productIDs []int) (map[int]bool, error)
+}
+
+type pgFavoriteRepository struct {
+	db DBTX
+}
+
+func (r *pgFavoriteRepository) Add(ctx context.Context, userID, productID int) (bool, error) {
+	tag, err := r.db.Exec(ctx,
+		`INSERT INTO user_favorites (user_id, product_id, created_at) VALUES ($1, $2, NOW())
+		 ON CONFLICT (user_id, product_id) DO NOTHING`, userID, productID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *pgFavoriteRepository) Remove(ctx context.Context, userID, productID int) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM user_favorites WHERE user_id=$1 AND product_id=$2", userID, productID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgFavoriteRepository) List(ctx context.Context, filter FavoriteFilter) ([]models.Product, int, error) {
+	const from = ` FROM user_favorites f
+		 JOIN products ON products.id = f.product_id
+		 WHERE f.user_id = $1 AND products.is_active = TRUE AND products.deleted_at IS NULL`
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*)"+from, filter.UserID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	where := ""
+	args := []any{filter.UserID}
+	if filter.After != nil {
+		where = " AND " + keyset("f.created_at", "products.id", 2)
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(ctx, "SELECT "+qualifiedProductColumns+", f.created_at"+from+where+
+		fmt.Sprintf(" ORDER BY f.created_at DESC, products.id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		var favoritedAt time.Time
+		p, err := scanProduct(rows, &favoritedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		p.FavoritedAt = &favoritedAt
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	translator := &pgProductRepository{db: r.db}
+	if err := translator.translate(ctx, filter.Locale, products); err != nil {
+		return nil, 0, err
+	}
+	return products, total, nil
+}
+
+func (r *pgFavoriteRepository) Favorited(ctx context.Context, userID int, productIDs []int) (map[int]bool, error) {
+	favorited := map[int]bool{}
+	if len(productIDs) == 0 {
+		return favorited, nil
+	}
+	rows, err := r.db.Query(ctx,
+		"SELECT product_id FROM user_favorites WHERE user_id=$1 AND product_id = ANY($2)", userID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		favorited[id] = true
+	}
+	return favorited, rows.Err()
+}
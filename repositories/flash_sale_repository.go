@@ -0,0 +1,200 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"context"
+	"time"
+)
+
+type FlashSaleRepository interface {
+	// List returns every flash sale with its items, latest start first.
+	List(ctx context.Context) ([]models.FlashSale, error)
+	Get(ctx context.Context, id int) (models.FlashSale, error)
+	// Current returns the active flash sales that have not ended at now,
+	// running or scheduled, soonest start first. Items of inactive or
+	// archived products are left out and names are translated to locale.
+	Current(ctx context.Context, now time.Time, locale string) ([]models.FlashSale, error)
+	// LastBoundary returns when an active flash sale last started or ended
+	// at or before now, or the zero time if none has.
+	LastBoundary(ctx context.Context, now time.Time) (time.Time, error)
+	// Create inserts s with its items and fills in its ID and timestamps.
+	Create(ctx context.Context, s *models.FlashSale) error
+	// Update replaces the stored sale and its items with s.
+	Update(ctx context.Context, s models.FlashSale) error
+	Delete(ctx context.Context, id int) error
+	// Purchased returns how many units of the product the user has ordered
+	// at the flash sale's price, leaving out cancelled orders.
+	Purchased(ctx context.Context, flashSaleID, productID, userID int) (int, error)
+}
+
+type pgFlashSaleRepository struct {
+	db DBTX
+}
+
+const flashSaleColumns = "id, name, starts_at, ends_at, is_active, created_at, updated_at"
+
+func (r *pgFlashSaleRepository) List(ctx context.Context) ([]models.FlashSale, error) {
+	sales, err := r.query(ctx, "SELECT "+flashSaleColumns+" FROM flash_sales ORDER BY starts_at DESC, id DESC")
+	if err != nil {
+		return nil, err
+	}
+	return sales, r.loadItems(ctx, sales, "", false)
+}
+
+func (r *pgFlashSaleRepository) Get(ctx context.Context, id int) (models.FlashSale, error) {
+	sales, err := r.query(ctx, "SELECT "+flashSaleColumns+" FROM flash_sales WHERE id = $1", id)
+	if err != nil {
+		return models.FlashSale{}, err
+	}
+	if len(sales) == 0 {
+		return models.FlashSale{}, ErrNotFound
+	}
+	if err := r.loadItems(ctx, sales, "", false); err != nil {
+		return models.FlashSale{}, err
+	}
+	return sales[0], nil
+}
+
+func (r *pgFlashSaleRepository) Current(ctx context.Context, now time.Time, locale string) ([]models.FlashSale, error) {
+	sales, err := r.query(ctx,
+		"SELECT "+flashSaleColumns+" FROM flash_sales WHERE is_active AND ends_at > $1 ORDER BY starts_at, id", now)
+	if err != nil {
+		return nil, err
+	}
+	return sales, r.loadItems(ctx, sales, locale, true)
+}
+
+func (r *pgFlashSaleRepository) LastBoundary(ctx context.Context, now time.Time) (time.Time, error) {
+	var last *time.Time
+	err := r.db.QueryRow(ctx,
+		`SELECT MAX(GREATEST(CASE WHEN starts_at <= $1 THEN starts_at END, CASE WHEN ends_at <= $1 THEN ends_at END))
+		 FROM flash_sales WHERE is_active`, now).Scan(&last)
+	if err != nil || last == nil {
+		return time.Time{}, err
+	}
+	return *last, nil
+}
+
+func (r *pgFlashSaleRepository) Create(ctx context.Context, s *models.FlashSale) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO flash_sales (name, starts_at, ends_at, is_active, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, NOW(), NOW())
+		 RETURNING id, created_at, updated_at`,
+		s.Name, s.StartsAt, s.EndsAt, s.IsActive).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return r.insertItems(ctx, s.ID, s.Items)
+}
+
+func (r *pgFlashSaleRepository) Update(ctx context.Context, s models.FlashSale) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE flash_sales SET name=$1, starts_at=$2, ends_at=$3, is_active=$4, updated_at=$5 WHERE id=$6`,
+		s.Name, s.StartsAt, s.EndsAt, s.IsActive, s.UpdatedAt, s.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if _, err := r.db.Exec(ctx, "DELETE FROM flash_sale_items WHERE flash_sale_id=$1", s.ID); err != nil {
+		return err
+	}
+	return r.insertItems(ctx, s.ID, s.Items)
+}
+
+func (r *pgFlashSaleRepository) Delete(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM flash_sales WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgFlashSaleRepository) Purchased(ctx context.Context, flashSaleID, productID, userID int) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx,
+		`SELECT COALESCE(SUM(oi.quantity), 0)
+		 FROM order_items oi
+		 JOIN orders o ON o.id = oi.order_id
+		 LEFT JOIN order_status os ON os.id = o.status_id
+		 WHERE oi.flash_sale_id = $1 AND oi.product_id = $2 AND o.user_id = $3
+		 AND COALESCE(os.name, '') <> 'cancelled'`,
+		flashSaleID, productID, userID).Scan(&n)
+	return n, err
+}
+
+func (r *pgFlashSaleRepository) query(ctx context.Context, sql string, args ...any) ([]models.FlashSale, error) {
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sales := []models.FlashSale{}
+	for rows.Next() {
+		s := models.FlashSale{Items: []models.FlashSaleItem{}}
+		if err := rows.Scan(&s.ID, &s.Name, &s.StartsAt, &s.EndsAt, &s.IsActive, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		sales = append(sales, s)
+	}
+	return sales, rows.Err()
+}
+
+// loadItems fills in the items of sales, with their product's name, image
+// and regular price. listedOnly leaves out inactive and archived products.
+func (r *pgFlashSaleRepository) loadItems(ctx context.Context, sales []models.FlashSale, locale string, listedOnly bool) error {
+	if len(sales) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(sales))
+	index := make(map[int]int, len(sales))
+	for i, s := range sales {
+		ids = append(ids, s.ID)
+		index[s.ID] = i
+	}
+
+	query := `SELECT fsi.flash_sale_id, fsi.product_id, COALESCE(NULLIF(tr.name, ''), p.name), COALESCE(p.image_url, ''),
+			p.price, fsi.sale_price, fsi.discount_percent, fsi.max_per_customer
+		FROM flash_sale_items fsi
+		JOIN products p ON p.id = fsi.product_id
+		LEFT JOIN product_translations tr ON tr.product_id = p.id AND tr.locale = $2
+		WHERE fsi.flash_sale_id = ANY($1)`
+	if listedOnly {
+		query += " AND p.is_active = TRUE AND p.deleted_at IS NULL"
+	}
+	rows, err := r.db.Query(ctx, query+" ORDER BY fsi.flash_sale_id, fsi.product_id", ids, locale)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var saleID int
+		var item models.FlashSaleItem
+		if err := rows.Scan(&saleID, &item.ProductID, &item.Name, &item.ImageURL, &item.RegularPrice,
+			&item.SalePrice, &item.DiscountPercent, &item.MaxPerCustomer); err != nil {
+			return err
+		}
+		item.Price = item.PriceFor(item.RegularPrice)
+		s := &sales[index[saleID]]
+		s.Items = append(s.Items, item)
+	}
+	return rows.Err()
+}
+
+func (r *pgFlashSaleRepository) insertItems(ctx context.Context, flashSaleID int, items []models.FlashSaleItem) error {
+	for _, item := range items {
+		if _, err := r.db.Exec(ctx,
+			`INSERT INTO flash_sale_items (flash_sale_id, product_id, sale_price, discount_percent, max_per_customer)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			flashSaleID, item.ProductID, item.SalePrice, item.DiscountPercent, item.MaxPerCustomer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"context"
+	"fmt"
+)
+
+// MovementFilter selects one page of a product's stock ledger, newest first.
+type MovementFilter struct {
+	pagination.Params
+	ProductID int
+}
+
+type InventoryRepository interface {
+	// Record appends m to the ledger and fills in its ID and CreatedAt.
+	Record(ctx context.Context, m *models.StockMovement) error
+	// Movements returns one page of a product's ledger and the total number
+	// of entries.
+	Movements(ctx context.Context, filter MovementFilter) ([]models.StockMovement, int, error)
+	// LowStock returns the unarchived products whose stock is below their
+	// threshold, lowest stock first.
+	LowStock(ctx context.Context) ([]models.Product, error)
+}
+
+type pgInventoryRepository struct {
+	db DBTX
+}
+
+func (r *pgInventoryRepository) Record(ctx context.Context, m *models.StockMovement) error {
+	return r.db.QueryRow(ctx,
+		`INSERT INTO stock_movements (product_id, movement_type, quantity, stock_after, reason, order_id, actor_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		 RETURNING id, created_at`,
+		m.ProductID, m.Type, m.Quantity, m.StockAfter, m.Reason, m.OrderID, m.ActorID,
+	).Scan(&m.ID, &m.CreatedAt)
+}
+
+func (r *pgInventoryRepository) Movements(ctx context.Context, filter MovementFilter) ([]models.StockMovement, int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM stock_movements WHERE product_id=$1", filter.ProductID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	where := "sm.product_id = $1"
+	args := []any{filter.ProductID}
+	if filter.After != nil {
+		where += " AND " + keyset("sm.created_at", "sm.id", 2)
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(ctx,
+		`SELECT sm.id, sm.product_id, sm.movement_type, sm.quantity, sm.stock_after, sm.reason,
+			sm.order_id, sm.actor_id, COALESCE(u.email, ''), sm.created_at
+		 FROM stock_movements sm
+		 LEFT JOIN users u ON sm.actor_id = u.id
+		 WHERE `+where+
+			fmt.Sprintf(" ORDER BY sm.created_at DESC, sm.id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	movements := []models.StockMovement{}
+	for rows.Next() {
+		var m models.StockMovement
+		if err := rows.Scan(&m.ID, &m.ProductID, &m.Type, &m.Quantity, &m.StockAfter, &m.Reason,
+			&m.OrderID, &m.ActorID, &m.ActorEmail, &m.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		movements = append(movements, m)
+	}
+	return movements, total, rows.Err()
+}
+
+func (r *pgInventoryRepository) LowStock(ctx context.Context) ([]models.Product, error) {
+	products := &pgProductRepository{db: r.db}
+	return products.queryProducts(ctx, "SELECT "+productColumns+
+		` FROM products
+		 WHERE deleted_at IS NULL AND stock < low_stock_threshold
+		 ORDER BY stock, id`)
+}
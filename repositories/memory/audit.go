@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type auditRow struct {
+	models.AuditEntry
+	CreatedAt time.Time
+}
+
+type auditRepository struct{ s *Store }
+
+func (r *auditRepository) Record(_ context.Context, entry models.AuditEntry) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.state.audit = append(r.s.state.audit, auditRow{AuditEntry: entry, CreatedAt: time.Now()})
+	return nil
+}
+
+func (r *auditRepository) List(_ context.Context, f repositories.AuditFilter, p pagination.Params) ([]models.AuditLog, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	entries := []models.AuditLog{}
+	for i := len(r.s.state.audit) - 1; i >= 0; i-- {
+		row := r.s.state.audit[i]
+		switch {
+		case f.ActorID > 0 && row.ActorID != f.ActorID,
+			f.EntityType != "" && row.EntityType != f.EntityType,
+			f.EntityID > 0 && row.EntityID != f.EntityID,
+			!f.From.IsZero() && row.CreatedAt.Before(f.From),
+			!f.Until.IsZero() && !row.CreatedAt.Before(f.Until):
+			continue
+		}
+
+		entry := models.AuditLog{
+			ID:         int64(i + 1),
+			ActorEmail: row.ActorEmail,
+			Action:     row.Action,
+			EntityType: row.EntityType,
+			EntityID:   row.EntityID,
+			IPAddress:  row.IPAddress,
+			CreatedAt:  row.CreatedAt,
+		}
+		if row.ActorID > 0 {
+			actorID := row.ActorID
+			entry.ActorID = &actorID
+		}
+		if row.Before != nil {
+			entry.Before, _ = json.Marshal(row.Before)
+		}
+		if row.After != nil {
+			entry.After, _ = json.Marshal(row.After)
+		}
+		entries = append(entries, entry)
+	}
+	return window(entries, p, func(e models.AuditLog) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: e.CreatedAt, ID: int(e.ID)}
+	}), len(entries), nil
+}
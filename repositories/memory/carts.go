@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"sort"
+)
+
+type cartRow struct {
+	repositories.CartItemKey
+	ID       int
+	Quantity int
+}
+
+type cartRepository struct{ s *Store }
+
+func (r *cartRepository) Items(_ context.Context, userID int, locale string) ([]models.CartItemV2, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	products := &productRepository{r.s}
+	items := []models.CartItemV2{}
+	for _, row := range r.sorted(userID) {
+		p, ok := r.s.state.products[row.ProductID]
+		if !ok || !listed(p) {
+			continue
+		}
+		p = products.translate(p, locale)
+		items = append(items, models.CartItemV2{
+			ID:            row.ID,
+			ProductID:     p.ID,
+			CategoryID:    p.CategoryID,
+			Name:          p.Name,
+			BasePrice:     p.Price,
+			IsBuy1Get1:    p.IsBuy1Get1,
+			Quantity:      row.Quantity,
+			SizeID:        row.SizeID,
+			Size:          r.s.state.sizes[deref(row.SizeID)].Name,
+			TemperatureID: row.TemperatureID,
+			Temperature:   r.s.state.temperatures[deref(row.TemperatureID)].Name,
+			VariantID:     row.VariantID,
+			Variant:       r.s.state.variants[deref(row.VariantID)].Name,
+			ImageURL:      p.ImageURL,
+			Stock:         p.Stock,
+		})
+	}
+	return items, nil
+}
+
+func (r *cartRepository) FindItem(_ context.Context, key repositories.CartItemKey) (int, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, row := range r.s.state.cart {
+		if row.UserID == key.UserID && row.ProductID == key.ProductID &&
+			sameOption(row.SizeID, key.SizeID) &&
+			sameOption(row.TemperatureID, key.TemperatureID) &&
+			sameOption(row.VariantID, key.VariantID) {
+			return row.ID, row.Quantity, nil
+		}
+	}
+	return 0, 0, repositories.ErrNotFound
+}
+
+func (r *cartRepository) AddItem(_ context.Context, key repositories.CartItemKey, quantity int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	id := r.s.id()
+	r.s.state.cart[id] = cartRow{CartItemKey: key, ID: id, Quantity: quantity}
+	return id, nil
+}
+
+func (r *cartRepository) SetQuantity(_ context.Context, id, quantity int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	row, ok := r.s.state.cart[id]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	row.Quantity = quantity
+	r.s.state.cart[id] = row
+	return nil
+}
+
+func (r *cartRepository) LockForCheckout(_ context.Context, userID int) ([]repositories.CartLine, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	lines := []repositories.CartLine{}
+	for _, row := range r.sorted(userID) {
+		p, ok := r.s.state.products[row.ProductID]
+		if !ok || p.DeletedAt != nil {
+			continue
+		}
+		lines = append(lines, repositories.CartLine{
+			CartID:            row.ID,
+			ProductID:         p.ID,
+			CategoryID:        p.CategoryID,
+			Name:              p.Name,
+			Price:             p.Price,
+			IsBuy1Get1:        p.IsBuy1Get1,
+			Quantity:          row.Quantity,
+			Stock:             p.Stock,
+			LowStockThreshold: p.LowStockThreshold,
+			SizeID:            row.SizeID,
+			TemperatureID:     row.TemperatureID,
+			VariantID:         row.VariantID,
+		})
+	}
+	return lines, nil
+}
+
+func (r *cartRepository) Clear(_ context.Context, userID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, row := range r.s.state.cart {
+		if row.UserID == userID {
+			delete(r.s.state.cart, id)
+		}
+	}
+	return nil
+}
+
+func (r *cartRepository) sorted(userID int) []cartRow {
+	rows := []cartRow{}
+	for _, row := range r.s.state.cart {
+		if row.UserID == userID {
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ID > rows[j].ID })
+	return rows
+}
+
+// deref returns the ID, or 0 for no option.
+func deref(id *int) int {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+func sameOption(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
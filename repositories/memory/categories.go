@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"sort"
+	"time"
+)
+
+type categoryRepository struct{ s *Store }
+
+func (r *categoryRepository) List(_ context.Context, locale string) ([]models.Category, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	categories := []models.Category{}
+	for _, c := range r.s.state.categories {
+		categories = append(categories, r.translate(c, locale))
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+	return categories, nil
+}
+
+func (r *categoryRepository) Get(_ context.Context, id int, locale string) (models.Category, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c, ok := r.s.state.categories[id]
+	if !ok {
+		return models.Category{}, repositories.ErrNotFound
+	}
+	return r.translate(c, locale), nil
+}
+
+func (r *categoryRepository) NameExists(_ context.Context, name string, excludeID int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, c := range r.s.state.categories {
+		if c.Name == name && c.ID != excludeID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *categoryRepository) Create(_ context.Context, c *models.Category) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c.ID = r.s.id()
+	c.IsActive = true
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	r.s.state.categories[c.ID] = *c
+	return nil
+}
+
+func (r *categoryRepository) UpdateName(_ context.Context, id int, name string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c, ok := r.s.state.categories[id]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	c.Name = name
+	c.UpdatedAt = time.Now()
+	r.s.state.categories[id] = c
+	return nil
+}
+
+func (r *categoryRepository) Delete(_ context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.categories[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.categories, id)
+	delete(r.s.state.categoryTranslations, id)
+	for ruleID, rule := range r.s.state.promotions {
+		if rule.CategoryID != nil && *rule.CategoryID == id {
+			delete(r.s.state.promotions, ruleID)
+		}
+	}
+	return nil
+}
+
+func (r *categoryRepository) translate(c models.Category, locale string) models.Category {
+	if t, ok := r.s.state.categoryTranslations[c.ID][locale]; ok {
+		c.Name = t.Name
+		if t.UpdatedAt.After(c.UpdatedAt) {
+			c.UpdatedAt = t.UpdatedAt
+		}
+	}
+	return c
+}
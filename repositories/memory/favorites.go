@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"sort"
+	"time"
+)
+
+type favoriteRepository struct{ s *Store }
+
+func (r *favoriteRepository) Add(_ context.Context, userID, productID int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.favorites[userID][productID]; ok {
+		return false, nil
+	}
+	if r.s.state.favorites[userID] == nil {
+		r.s.state.favorites[userID] = map[int]time.Time{}
+	}
+	r.s.state.favorites[userID][productID] = time.Now()
+	return true, nil
+}
+
+func (r *favoriteRepository) Remove(_ context.Context, userID, productID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.favorites[userID][productID]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.favorites[userID], productID)
+	return nil
+}
+
+func (r *favoriteRepository) List(_ context.Context, filter repositories.FavoriteFilter) ([]models.Product, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	products := &productRepository{r.s}
+	favorites := []models.Product{}
+	for productID, at := range r.s.state.favorites[filter.UserID] {
+		p, ok := r.s.state.products[productID]
+		if !ok || !p.IsActive || p.DeletedAt != nil {
+			continue
+		}
+		p = products.translate(p, filter.Locale)
+		favoritedAt := at
+		p.FavoritedAt = &favoritedAt
+		favorites = append(favorites, p)
+	}
+	sort.Slice(favorites, func(i, j int) bool {
+		a, b := favorites[i], favorites[j]
+		if !a.FavoritedAt.Equal(*b.FavoritedAt) {
+			return a.FavoritedAt.After(*b.FavoritedAt)
+		}
+		return a.ID > b.ID
+	})
+	return window(favorites, filter.Params, func(p models.Product) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: *p.FavoritedAt, ID: p.ID}
+	}), len(favorites), nil
+}
+
+func (r *favoriteRepository) Favorited(_ context.Context, userID int, productIDs []int) (map[int]bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	favorited := map[int]bool{}
+	for _, id := range productIDs {
+		if _, ok := r.s.state.favorites[userID][id]; ok {
+			favorited[id] = true
+		}
+	}
+	return favorited, nil
+}
@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"sort"
+	"time"
+)
+
+type flashSaleRepository struct{ s *Store }
+
+func (r *flashSaleRepository) List(context.Context) ([]models.FlashSale, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	sales := []models.FlashSale{}
+	for _, sale := range r.s.state.flashSales {
+		sales = append(sales, r.withItems(sale, "", false))
+	}
+	sort.Slice(sales, func(i, j int) bool {
+		if !sales[i].StartsAt.Equal(sales[j].StartsAt) {
+			return sales[i].StartsAt.After(sales[j].StartsAt)
+		}
+		return sales[i].ID > sales[j].ID
+	})
+	return sales, nil
+}
+
+func (r *flashSaleRepository) Get(_ context.Context, id int) (models.FlashSale, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	sale, ok := r.s.state.flashSales[id]
+	if !ok {
+		return models.FlashSale{}, repositories.ErrNotFound
+	}
+	return r.withItems(sale, "", false), nil
+}
+
+func (r *flashSaleRepository) Current(_ context.Context, now time.Time, locale string) ([]models.FlashSale, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	sales := []models.FlashSale{}
+	for _, sale := range r.s.state.flashSales {
+		if sale.IsActive && sale.EndsAt.After(now) {
+			sales = append(sales, r.withItems(sale, locale, true))
+		}
+	}
+	sort.Slice(sales, func(i, j int) bool {
+		if !sales[i].StartsAt.Equal(sales[j].StartsAt) {
+			return sales[i].StartsAt.Before(sales[j].StartsAt)
+		}
+		return sales[i].ID < sales[j].ID
+	})
+	return sales, nil
+}
+
+func (r *flashSaleRepository) LastBoundary(_ context.Context, now time.Time) (time.Time, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var last time.Time
+	for _, sale := range r.s.state.flashSales {
+		if !sale.IsActive {
+			continue
+		}
+		for _, t := range []time.Time{sale.StartsAt, sale.EndsAt} {
+			if !t.After(now) && t.After(last) {
+				last = t
+			}
+		}
+	}
+	return last, nil
+}
+
+func (r *flashSaleRepository) Create(_ context.Context, sale *models.FlashSale) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	sale.ID = r.s.id()
+	sale.CreatedAt = time.Now()
+	sale.UpdatedAt = sale.CreatedAt
+	r.s.state.flashSales[sale.ID] = stripFlashSale(*sale)
+	return nil
+}
+
+func (r *flashSaleRepository) Update(_ context.Context, sale models.FlashSale) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	existing, ok := r.s.state.flashSales[sale.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	sale.CreatedAt = existing.CreatedAt
+	r.s.state.flashSales[sale.ID] = stripFlashSale(sale)
+	return nil
+}
+
+func (r *flashSaleRepository) Delete(_ context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.flashSales[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.flashSales, id)
+	for orderID, items := range r.s.state.orderItems {
+		for i := range items {
+			if items[i].FlashSaleID != nil && *items[i].FlashSaleID == id {
+				items[i].FlashSaleID = nil
+			}
+		}
+		r.s.state.orderItems[orderID] = items
+	}
+	return nil
+}
+
+func (r *flashSaleRepository) Purchased(_ context.Context, flashSaleID, productID, userID int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	n := 0
+	for orderID, items := range r.s.state.orderItems {
+		o := r.s.state.orders[orderID]
+		if o.UserID != userID || r.s.state.statuses[o.StatusID] == "cancelled" {
+			continue
+		}
+		for _, item := range items {
+			if item.ProductID == productID && item.FlashSaleID != nil && *item.FlashSaleID == flashSaleID {
+				n += item.Quantity
+			}
+		}
+	}
+	return n, nil
+}
+
+// withItems fills in the product name, image and regular price of the sale's
+// items. listedOnly leaves out inactive and archived products. The caller
+// must hold the lock.
+func (r *flashSaleRepository) withItems(sale models.FlashSale, locale string, listedOnly bool) models.FlashSale {
+	products := &productRepository{r.s}
+	items := []models.FlashSaleItem{}
+	for _, item := range sale.Items {
+		p, ok := r.s.state.products[item.ProductID]
+		if !ok || listedOnly && !listed(p) {
+			continue
+		}
+		p = products.translate(p, locale)
+		item.Name, item.ImageURL, item.RegularPrice = p.Name, p.ImageURL, p.Price
+		item.Price = item.PriceFor(p.Price)
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ProductID < items[j].ProductID })
+	sale.Items = items
+	return sale
+}
+
+// stripFlashSale keeps only the columns flash_sale_items stores.
+func stripFlashSale(sale models.FlashSale) models.FlashSale {
+	items := make([]models.FlashSaleItem, 0, len(sale.Items))
+	for _, item := range sale.Items {
+		items = append(items, models.FlashSaleItem{ProductID: item.ProductID, SalePrice: item.SalePrice,
+			DiscountPercent: item.DiscountPercent, MaxPerCustomer: item.MaxPerCustomer})
+	}
+	sale.Items = items
+	return sale
+}
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"sort"
+	"time"
+)
+
+type inventoryRepository struct{ s *Store }
+
+func (r *inventoryRepository) Record(_ context.Context, m *models.StockMovement) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	m.ID = r.s.id()
+	m.CreatedAt = time.Now()
+	r.s.state.stockMovements = append(r.s.state.stockMovements, *m)
+	return nil
+}
+
+func (r *inventoryRepository) Movements(_ context.Context, filter repositories.MovementFilter) ([]models.StockMovement, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	movements := []models.StockMovement{}
+	for _, m := range r.s.state.stockMovements {
+		if m.ProductID != filter.ProductID {
+			continue
+		}
+		if m.ActorID != nil {
+			m.ActorEmail = r.s.state.users[*m.ActorID].Email
+		}
+		movements = append(movements, m)
+	}
+	sortByIDDesc(movements, func(m models.StockMovement) int { return m.ID })
+	return window(movements, filter.Params, func(m models.StockMovement) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: m.CreatedAt, ID: m.ID}
+	}), len(movements), nil
+}
+
+func (r *inventoryRepository) LowStock(context.Context) ([]models.Product, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	products := []models.Product{}
+	for _, p := range r.s.state.products {
+		if p.DeletedAt == nil && p.Stock < p.LowStockThreshold {
+			products = append(products, p)
+		}
+	}
+	sort.Slice(products, func(i, j int) bool {
+		if products[i].Stock != products[j].Stock {
+			return products[i].Stock < products[j].Stock
+		}
+		return products[i].ID < products[j].ID
+	})
+	return products, nil
+}
@@ -0,0 +1,257 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type orderRow struct {
+	repositories.NewOrder
+	ID        int
+	CreatedAt time.Time
+}
+
+type orderRepository struct{ s *Store }
+
+func (r *orderRepository) List(_ context.Context, filter repositories.OrderFilter) ([]models.OrderSummaryV2, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	orders := []models.OrderSummaryV2{}
+	for _, o := range r.s.state.orders {
+		if filter.Status != "" && filter.Status != "All" && r.s.state.statuses[o.StatusID] != filter.Status {
+			continue
+		}
+		if filter.Search != "" && !strings.Contains(strconv.Itoa(o.ID), filter.Search) {
+			continue
+		}
+		orders = append(orders, r.s.summary(o))
+	}
+	sortByIDDesc(orders, func(o models.OrderSummaryV2) int { return o.ID })
+	return window(orders, filter.Params, func(o models.OrderSummaryV2) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: o.CreatedAt, ID: o.ID}
+	}), len(orders), nil
+}
+
+func (r *orderRepository) Get(_ context.Context, id int) (models.OrderSummaryV2, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	o, ok := r.s.state.orders[id]
+	if !ok {
+		return models.OrderSummaryV2{}, repositories.ErrNotFound
+	}
+	return r.s.summary(o), nil
+}
+
+func (r *orderRepository) StatusName(_ context.Context, id int) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	o, ok := r.s.state.orders[id]
+	if !ok {
+		return "", repositories.ErrNotFound
+	}
+	return r.s.state.statuses[o.StatusID], nil
+}
+
+func (r *orderRepository) UpdateStatus(_ context.Context, id int, statusID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	o, ok := r.s.state.orders[id]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	o.StatusID = statusID
+	r.s.state.orders[id] = o
+	return nil
+}
+
+func (r *orderRepository) Delete(_ context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.orders[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.orders, id)
+	delete(r.s.state.orderItems, id)
+	return nil
+}
+
+func (r *orderRepository) History(_ context.Context, filter repositories.HistoryFilter) ([]models.HistoryOrderV2, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rows := []orderRow{}
+	for _, o := range r.s.state.orders {
+		status := r.s.state.statuses[o.StatusID]
+		day := o.OrderDate.Format("2006-01-02")
+		switch {
+		case o.UserID != filter.UserID,
+			filter.Status != "" && status != filter.Status,
+			filter.From != "" && day < filter.From,
+			filter.To != "" && day > filter.To,
+			!filter.Month.IsZero() && (o.OrderDate.Year() != filter.Month.Year() || o.OrderDate.Month() != filter.Month.Month()):
+			continue
+		}
+		rows = append(rows, o)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].OrderDate.Equal(rows[j].OrderDate) {
+			return rows[i].OrderDate.After(rows[j].OrderDate)
+		}
+		return rows[i].ID > rows[j].ID
+	})
+
+	orders := []models.HistoryOrderV2{}
+	for _, o := range window(rows, filter.Params, func(o orderRow) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: o.OrderDate, ID: o.ID}
+	}) {
+		status := r.s.state.statuses[o.StatusID]
+		h := models.HistoryOrderV2{
+			ID:            o.ID,
+			Invoice:       o.OrderNumber,
+			Date:          o.OrderDate.Format("02 January 2006"),
+			Status:        status,
+			StatusDisplay: status,
+			Total:         o.Total,
+			TotalItems:    len(r.s.state.orderItems[o.ID]),
+			OrderDate:     o.OrderDate,
+		}
+		for _, item := range r.s.state.orderItems[o.ID] {
+			if img := r.s.state.products[item.ProductID].ImageURL; img != "" && len(h.ProductImages) < 4 {
+				h.ProductImages = append(h.ProductImages, img)
+			}
+		}
+		orders = append(orders, h)
+	}
+	return orders, len(rows), nil
+}
+
+func (r *orderRepository) Detail(_ context.Context, orderID, userID int) (models.OrderDetailV2, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	o, ok := r.s.state.orders[orderID]
+	if !ok || o.UserID != userID {
+		return models.OrderDetailV2{}, repositories.ErrNotFound
+	}
+
+	user := r.s.state.users[userID]
+	status := r.s.state.statuses[o.StatusID]
+	d := models.OrderDetailV2{
+		OrderNumber:    o.OrderNumber,
+		OrderDate:      o.OrderDate.Format("02 January 2006 at 03:04 PM"),
+		FullName:       user.FullName,
+		Phone:          user.Phone,
+		Address:        o.DeliveryAddress,
+		DeliveryMethod: "Dine In",
+		PaymentMethod:  "Cash",
+		Status:         status,
+		StatusDisplay:  status,
+		Subtotal:       o.Subtotal,
+		DeliveryFee:    o.DeliveryFee,
+		Total:          o.Total,
+		Items:          []models.OrderItemV2{},
+	}
+	for _, item := range r.s.state.orderItems[orderID] {
+		p := r.s.state.products[item.ProductID]
+		d.Items = append(d.Items, models.OrderItemV2{
+			ProductID:      item.ProductID,
+			Name:           p.Name,
+			Quantity:       item.Quantity,
+			UnitPrice:      item.UnitPrice,
+			Discount:       item.DiscountAmount,
+			Promotion:      item.PromotionName,
+			TotalPrice:     item.UnitPrice*item.Quantity - item.DiscountAmount,
+			ImageURL:       p.ImageURL,
+			IsFlashSale:    item.IsFlashSale,
+			DeliveryMethod: d.DeliveryMethod,
+		})
+		d.Discount += item.DiscountAmount
+	}
+	return d, nil
+}
+
+func (r *orderRepository) StatusID(_ context.Context, status string) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, name := range r.s.state.statuses {
+		if name == status {
+			return id, nil
+		}
+	}
+	return 0, repositories.ErrNotFound
+}
+
+func (r *orderRepository) Create(_ context.Context, o repositories.NewOrder) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	id := r.s.id()
+	r.s.state.orders[id] = orderRow{NewOrder: o, ID: id, CreatedAt: time.Now()}
+	return id, nil
+}
+
+func (r *orderRepository) AddItem(_ context.Context, orderID int, item repositories.NewOrderItem) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.orders[orderID]; !ok {
+		return repositories.ErrNotFound
+	}
+	r.s.state.orderItems[orderID] = append(r.s.state.orderItems[orderID], item)
+	return nil
+}
+
+func (r *orderRepository) Items(_ context.Context, orderID int) ([]repositories.NewOrderItem, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return append([]repositories.NewOrderItem{}, r.s.state.orderItems[orderID]...), nil
+}
+
+func (s *Store) summary(o orderRow) models.OrderSummaryV2 {
+	return models.OrderSummaryV2{
+		ID:              o.ID,
+		OrderNumber:     o.OrderNumber,
+		UserID:          o.UserID,
+		Status:          s.state.statuses[o.StatusID],
+		Subtotal:        o.Subtotal,
+		Total:           o.Total,
+		CustomerName:    o.CustomerName,
+		DeliveryAddress: o.DeliveryAddress,
+		CreatedAt:       o.CreatedAt,
+	}
+}
+
+func sortByIDDesc[T any](items []T, id func(T) int) {
+	sort.Slice(items, func(i, j int) bool { return id(items[i]) > id(items[j]) })
+}
+
+func (r *orderRepository) Purchased(_ context.Context, userID, productID int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for orderID, o := range r.s.state.orders {
+		if o.UserID != userID || !slices.Contains(repositories.CompletedOrderStatuses, r.s.state.statuses[o.StatusID]) {
+			continue
+		}
+		for _, item := range r.s.state.orderItems[orderID] {
+			if item.ProductID == productID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
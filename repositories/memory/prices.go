@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"sort"
+	"time"
+)
+
+type priceRepository struct{ s *Store }
+
+func (r *priceRepository) Record(_ context.Context, p *models.ProductPrice) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p.ID = r.s.id()
+	p.CreatedAt = time.Now()
+	r.s.state.prices[p.ID] = *p
+	return nil
+}
+
+func (r *priceRepository) History(_ context.Context, filter repositories.PriceFilter) ([]models.ProductPrice, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	prices := r.applied(filter.ProductID, nil)
+	return window(prices, filter.Params, func(p models.ProductPrice) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: *p.AppliedAt, ID: p.ID}
+	}), len(prices), nil
+}
+
+func (r *priceRepository) At(_ context.Context, productID int, t time.Time) (models.ProductPrice, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	prices := r.applied(productID, &t)
+	if len(prices) == 0 {
+		return models.ProductPrice{}, repositories.ErrNotFound
+	}
+	return prices[0], nil
+}
+
+func (r *priceRepository) Scheduled(_ context.Context, productID int) ([]models.ProductPrice, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.pending(func(p models.ProductPrice) bool { return productID == 0 || p.ProductID == productID }), nil
+}
+
+func (r *priceRepository) Get(_ context.Context, id int) (models.ProductPrice, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, ok := r.s.state.prices[id]
+	if !ok {
+		return models.ProductPrice{}, repositories.ErrNotFound
+	}
+	return r.withActor(p), nil
+}
+
+func (r *priceRepository) Delete(_ context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if p, ok := r.s.state.prices[id]; !ok || p.AppliedAt != nil {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.prices, id)
+	return nil
+}
+
+func (r *priceRepository) Due(_ context.Context, now time.Time) ([]models.ProductPrice, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.pending(func(p models.ProductPrice) bool {
+		product, ok := r.s.state.products[p.ProductID]
+		return ok && product.DeletedAt == nil && !p.EffectiveAt.After(now)
+	}), nil
+}
+
+func (r *priceRepository) MarkApplied(_ context.Context, p models.ProductPrice) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.state.prices[p.ID]
+	if !ok || stored.AppliedAt != nil {
+		return repositories.ErrNotFound
+	}
+	stored.PreviousPrice = p.PreviousPrice
+	stored.AppliedAt = p.AppliedAt
+	r.s.state.prices[p.ID] = stored
+	return nil
+}
+
+// applied returns the product's applied prices, latest first, leaving out
+// those applied after before when it is set. The caller must hold the lock.
+func (r *priceRepository) applied(productID int, before *time.Time) []models.ProductPrice {
+	prices := []models.ProductPrice{}
+	for _, p := range r.s.state.prices {
+		if p.ProductID != productID || p.AppliedAt == nil || before != nil && p.AppliedAt.After(*before) {
+			continue
+		}
+		prices = append(prices, r.withActor(p))
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		if !prices[i].AppliedAt.Equal(*prices[j].AppliedAt) {
+			return prices[i].AppliedAt.After(*prices[j].AppliedAt)
+		}
+		return prices[i].ID > prices[j].ID
+	})
+	return prices
+}
+
+// pending returns the pending changes keep accepts, soonest first. The
+// caller must hold the lock.
+func (r *priceRepository) pending(keep func(models.ProductPrice) bool) []models.ProductPrice {
+	prices := []models.ProductPrice{}
+	for _, p := range r.s.state.prices {
+		if p.AppliedAt == nil && keep(p) {
+			prices = append(prices, r.withActor(p))
+		}
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		if !prices[i].EffectiveAt.Equal(prices[j].EffectiveAt) {
+			return prices[i].EffectiveAt.Before(prices[j].EffectiveAt)
+		}
+		return prices[i].ID < prices[j].ID
+	})
+	return prices
+}
+
+// withActor fills in the email of the price's actor. The caller must hold
+// the lock.
+func (r *priceRepository) withActor(p models.ProductPrice) models.ProductPrice {
+	if p.ActorID != nil {
+		p.ActorEmail = r.s.state.users[*p.ActorID].Email
+	}
+	return p
+}
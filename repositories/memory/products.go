@@ -0,0 +1,689 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"coffee-shop/search"
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+type productRepository struct{ s *Store }
+
+func (r *productRepository) List(_ context.Context, filter repositories.ProductFilter) ([]models.Product, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	matches := r.matching(filter)
+	sortNewestFirst(matches)
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Relevance > matches[j].Relevance })
+	switch filter.Sort {
+	case repositories.SortPriceAsc:
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].Price < matches[j].Price })
+	case repositories.SortPriceDesc:
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].Price > matches[j].Price })
+	case repositories.SortBestSelling:
+		sold := r.sold()
+		sort.SliceStable(matches, func(i, j int) bool { return sold[matches[i].ID] > sold[matches[j].ID] })
+	case repositories.SortPopular:
+		popularity := r.popularity(time.Now())
+		sort.SliceStable(matches, func(i, j int) bool { return popularity[matches[i].ID] > popularity[matches[j].ID] })
+	case repositories.SortTopRated:
+		sort.SliceStable(matches, func(i, j int) bool {
+			a, b := matches[i], matches[j]
+			if a.AverageRating != b.AverageRating {
+				return a.AverageRating > b.AverageRating
+			}
+			return a.ReviewCount > b.ReviewCount
+		})
+	}
+
+	total := len(matches)
+	if filter.Limit > 0 {
+		params := filter.Params
+		if !filter.NewestFirst() {
+			params.After = nil
+		}
+		matches = window(matches, params, func(p models.Product) pagination.Cursor {
+			return pagination.Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+		})
+	}
+	return matches, total, nil
+}
+
+func (r *productRepository) Facets(_ context.Context, filter repositories.ProductFilter) (models.ProductFacets, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	facets := models.ProductFacets{Categories: []models.CategoryFacet{}}
+	perCategory := map[int]int{}
+	prices := make([]int, len(repositories.PriceBuckets)+1)
+	for _, p := range r.matching(filter) {
+		perCategory[p.CategoryID]++
+		bucket := sort.Search(len(repositories.PriceBuckets), func(i int) bool { return p.Price < repositories.PriceBuckets[i] })
+		prices[bucket]++
+		if r.onFlashSale(p) {
+			facets.FlashSale++
+		}
+		if p.IsFeatured {
+			facets.Featured++
+		}
+		if p.IsBuy1Get1 {
+			facets.Buy1Get1++
+		}
+		if p.Stock > 0 {
+			facets.InStock++
+		}
+	}
+
+	for id, n := range perCategory {
+		c, ok := r.s.state.categories[id]
+		if !ok {
+			continue
+		}
+		c = (&categoryRepository{r.s}).translate(c, filter.Locale)
+		facets.Categories = append(facets.Categories, models.CategoryFacet{ID: id, Name: c.Name, Count: n})
+	}
+	sort.Slice(facets.Categories, func(i, j int) bool { return facets.Categories[i].Name < facets.Categories[j].Name })
+
+	lower := 0
+	for i, n := range prices {
+		f := models.PriceFacet{Min: lower, Count: n}
+		if i < len(repositories.PriceBuckets) {
+			f.Max = repositories.PriceBuckets[i]
+			lower = f.Max
+		}
+		facets.PriceRanges = append(facets.PriceRanges, f)
+	}
+	return facets, nil
+}
+
+// matching returns the translated listed products that pass filter, with
+// Relevance set when searching. The caller must hold the lock.
+func (r *productRepository) matching(filter repositories.ProductFilter) []models.Product {
+	matches := []models.Product{}
+	for _, p := range r.s.state.products {
+		if !listed(p) {
+			continue
+		}
+		translated := r.translate(p, filter.Locale)
+		if filter.Search != "" {
+			relevance := r.relevance(p, translated, filter.Search)
+			if relevance == 0 {
+				continue
+			}
+			translated.Relevance = relevance
+		}
+		if filter.CategoryID > 0 && p.CategoryID != filter.CategoryID {
+			continue
+		}
+		if filter.MinPrice > 0 && p.Price < filter.MinPrice {
+			continue
+		}
+		if filter.MaxPrice > 0 && p.Price > filter.MaxPrice {
+			continue
+		}
+		if filter.FlashSale && !r.onFlashSale(p) {
+			continue
+		}
+		if filter.Featured && !p.IsFeatured {
+			continue
+		}
+		if filter.Buy1Get1 && !p.IsBuy1Get1 {
+			continue
+		}
+		if filter.InStock && p.Stock <= 0 {
+			continue
+		}
+		if filter.MinRating > 0 && p.AverageRating < filter.MinRating {
+			continue
+		}
+		matches = append(matches, translated)
+	}
+	return matches
+}
+
+// sold totals the quantity ordered per product, leaving out cancelled orders.
+func (r *productRepository) sold() map[int]int {
+	sold := map[int]int{}
+	for orderID, items := range r.s.state.orderItems {
+		if r.s.state.statuses[r.s.state.orders[orderID].StatusID] == "cancelled" {
+			continue
+		}
+		for _, item := range items {
+			sold[item.ProductID] += item.Quantity
+		}
+	}
+	return sold
+}
+
+// popularity scores products like the SQL popularity join: each product's
+// daily viewers within the window, as last counted, halving in weight every
+// half-life.
+func (r *productRepository) popularity(now time.Time) map[int]float64 {
+	score := map[int]float64{}
+	for key, viewers := range r.s.state.viewDaily {
+		if !key.day.After(now.Add(-repositories.PopularityWindow)) {
+			continue
+		}
+		score[key.productID] += float64(viewers) * math.Pow(0.5, float64(now.Sub(key.day))/float64(repositories.PopularityHalfLife))
+	}
+	return score
+}
+
+func (r *productRepository) GetActive(_ context.Context, id int, locale string) (models.Product, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, ok := r.s.state.products[id]
+	if !ok || !listed(p) {
+		return models.Product{}, repositories.ErrNotFound
+	}
+	return r.translate(p, locale), nil
+}
+
+func (r *productRepository) Get(_ context.Context, id int) (models.Product, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, ok := r.s.state.products[id]
+	if !ok {
+		return models.Product{}, repositories.ErrNotFound
+	}
+	return p, nil
+}
+
+func (r *productRepository) All(context.Context) ([]models.Product, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	products := []models.Product{}
+	for _, p := range r.s.state.products {
+		products = append(products, p)
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+	return products, nil
+}
+
+func (r *productRepository) SKUExists(_ context.Context, sku string, excludeID int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, p := range r.s.state.products {
+		if p.ID != excludeID && p.SKU != "" && strings.EqualFold(p.SKU, sku) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *productRepository) Related(_ context.Context, categoryID, excludeID, limit int, locale string) ([]models.Product, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	related := []models.Product{}
+	for _, p := range r.s.state.products {
+		if p.CategoryID == categoryID && p.ID != excludeID && listed(p) {
+			related = append(related, r.translate(p, locale))
+		}
+	}
+	sort.Slice(related, func(i, j int) bool { return related[i].ID < related[j].ID })
+	if len(related) > limit {
+		related = related[:limit]
+	}
+	return related, nil
+}
+
+func (r *productRepository) Images(_ context.Context, productID int) ([]models.ProductImage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	images := []models.ProductImage{}
+	for _, img := range r.s.state.productImages {
+		if img.ProductID == productID {
+			images = append(images, img)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Order != images[j].Order {
+			return images[i].Order < images[j].Order
+		}
+		return images[i].ID < images[j].ID
+	})
+	return images, nil
+}
+
+func (r *productRepository) Sizes(context.Context) ([]models.ProductSizeV2, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	sizes := []models.ProductSizeV2{}
+	for _, size := range r.s.state.sizes {
+		sizes = append(sizes, size)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].PriceAdjustment != sizes[j].PriceAdjustment {
+			return sizes[i].PriceAdjustment < sizes[j].PriceAdjustment
+		}
+		return sizes[i].ID < sizes[j].ID
+	})
+	return sizes, nil
+}
+
+func (r *productRepository) Temperatures(context.Context) ([]models.ProductTemperatureV2, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	temperatures := []models.ProductTemperatureV2{}
+	for _, t := range r.s.state.temperatures {
+		temperatures = append(temperatures, t)
+	}
+	sort.Slice(temperatures, func(i, j int) bool { return temperatures[i].ID < temperatures[j].ID })
+	return temperatures, nil
+}
+
+func (r *productRepository) Variants(context.Context) ([]models.ProductVariantV2, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	variants := []models.ProductVariantV2{}
+	for _, v := range r.s.state.variants {
+		variants = append(variants, v)
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].ID < variants[j].ID })
+	return variants, nil
+}
+
+func (r *productRepository) Options(_ context.Context, productIDs ...int) ([]models.ProductOption, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	wanted := map[int]bool{}
+	for _, id := range productIDs {
+		wanted[id] = true
+	}
+	options := []models.ProductOption{}
+	for _, o := range r.s.state.productOptions {
+		if !wanted[o.ProductID] {
+			continue
+		}
+		if o.SizeID != nil {
+			o.Size = r.s.state.sizes[*o.SizeID].Name
+		}
+		if o.TemperatureID != nil {
+			o.Temperature = r.s.state.temperatures[*o.TemperatureID].Name
+		}
+		if o.VariantID != nil {
+			o.Variant = r.s.state.variants[*o.VariantID].Name
+		}
+		options = append(options, o)
+	}
+	sort.Slice(options, func(i, j int) bool {
+		if options[i].PriceAdjustment != options[j].PriceAdjustment {
+			return options[i].PriceAdjustment < options[j].PriceAdjustment
+		}
+		return options[i].ID < options[j].ID
+	})
+	return options, nil
+}
+
+func (r *productRepository) Create(_ context.Context, p *models.Product) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	p.ID = r.s.id()
+	p.CreatedAt, p.UpdatedAt = now, now
+	r.s.state.products[p.ID] = *p
+	return nil
+}
+
+func (r *productRepository) Update(_ context.Context, p models.Product) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.state.products[p.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	p.CreatedAt, p.DeletedAt, p.Stock = stored.CreatedAt, stored.DeletedAt, stored.Stock
+	p.AverageRating, p.ReviewCount = stored.AverageRating, stored.ReviewCount
+	r.s.state.products[p.ID] = p
+	return nil
+}
+
+func (r *productRepository) Archive(_ context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, ok := r.s.state.products[id]
+	if !ok || p.DeletedAt != nil {
+		return repositories.ErrNotFound
+	}
+	now := time.Now()
+	p.DeletedAt, p.UpdatedAt = &now, now
+	r.s.state.products[id] = p
+	return nil
+}
+
+func (r *productRepository) Restore(_ context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, ok := r.s.state.products[id]
+	if !ok || p.DeletedAt == nil {
+		return repositories.ErrNotFound
+	}
+	p.DeletedAt, p.UpdatedAt = nil, time.Now()
+	r.s.state.products[id] = p
+	return nil
+}
+
+func (r *productRepository) Archived(_ context.Context, page pagination.Params) ([]models.Product, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	archived := []models.Product{}
+	for _, p := range r.s.state.products {
+		if p.DeletedAt != nil {
+			archived = append(archived, p)
+		}
+	}
+	sort.Slice(archived, func(i, j int) bool {
+		if !archived[i].DeletedAt.Equal(*archived[j].DeletedAt) {
+			return archived[i].DeletedAt.After(*archived[j].DeletedAt)
+		}
+		return archived[i].ID > archived[j].ID
+	})
+	return window(archived, page, func(p models.Product) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: *p.DeletedAt, ID: p.ID}
+	}), len(archived), nil
+}
+
+func (r *productRepository) OrderCount(_ context.Context, id int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	n := 0
+	for _, items := range r.s.state.orderItems {
+		for _, item := range items {
+			if item.ProductID == id {
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+func (r *productRepository) Delete(_ context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.products[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.products, id)
+	delete(r.s.state.productTranslations, id)
+	for reviewID, review := range r.s.state.reviews {
+		if review.ProductID == id {
+			delete(r.s.state.reviews, reviewID)
+		}
+	}
+	for imageID, img := range r.s.state.productImages {
+		if img.ProductID == id {
+			delete(r.s.state.productImages, imageID)
+		}
+	}
+	for optionID, o := range r.s.state.productOptions {
+		if o.ProductID == id {
+			delete(r.s.state.productOptions, optionID)
+		}
+	}
+	for cartID, row := range r.s.state.cart {
+		if row.ProductID == id {
+			delete(r.s.state.cart, cartID)
+		}
+	}
+	movements := r.s.state.stockMovements[:0]
+	for _, m := range r.s.state.stockMovements {
+		if m.ProductID != id {
+			movements = append(movements, m)
+		}
+	}
+	r.s.state.stockMovements = movements
+	for priceID, p := range r.s.state.prices {
+		if p.ProductID == id {
+			delete(r.s.state.prices, priceID)
+		}
+	}
+	for _, favorites := range r.s.state.favorites {
+		delete(favorites, id)
+	}
+	for recID, rec := range r.s.state.recommendations {
+		if rec.ProductID == id || rec.RecommendedProductID == id {
+			delete(r.s.state.recommendations, recID)
+		}
+	}
+	delete(r.s.state.coPurchases, id)
+	for _, related := range r.s.state.coPurchases {
+		delete(related, id)
+	}
+	r.s.state.views = slices.DeleteFunc(r.s.state.views, func(v productView) bool { return v.productID == id })
+	for saleID, sale := range r.s.state.flashSales {
+		items := []models.FlashSaleItem{}
+		for _, item := range sale.Items {
+			if item.ProductID != id {
+				items = append(items, item)
+			}
+		}
+		sale.Items = items
+		r.s.state.flashSales[saleID] = sale
+	}
+	for ruleID, rule := range r.s.state.promotions {
+		if rule.ProductID != nil && *rule.ProductID == id {
+			delete(r.s.state.promotions, ruleID)
+		}
+	}
+	return nil
+}
+
+func (r *productRepository) LockStock(_ context.Context, id int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, ok := r.s.state.products[id]
+	if !ok {
+		return 0, repositories.ErrNotFound
+	}
+	return p.Stock, nil
+}
+
+func (r *productRepository) AdjustStock(_ context.Context, id, delta int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, ok := r.s.state.products[id]
+	if !ok {
+		return 0, repositories.ErrNotFound
+	}
+	if p.Stock+delta < 0 {
+		return 0, repositories.ErrInsufficientStock
+	}
+	p.Stock += delta
+	p.UpdatedAt = time.Now()
+	r.s.state.products[id] = p
+	return p.Stock, nil
+}
+
+func (r *productRepository) SetPrice(_ context.Context, id, price int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, ok := r.s.state.products[id]
+	if !ok {
+		return 0, repositories.ErrNotFound
+	}
+	previous := p.Price
+	p.Price = price
+	p.UpdatedAt = time.Now()
+	r.s.state.products[id] = p
+	return previous, nil
+}
+
+func (r *productRepository) AddImage(_ context.Context, img *models.ProductImage) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	img.ID = r.s.id()
+	img.CreatedAt = time.Now()
+	r.s.state.productImages[img.ID] = *img
+	return nil
+}
+
+func (r *productRepository) UpdateImage(_ context.Context, img models.ProductImage) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.state.productImages[img.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	img.ProductID, img.CreatedAt = stored.ProductID, stored.CreatedAt
+	r.s.state.productImages[img.ID] = img
+	return nil
+}
+
+func (r *productRepository) DeleteImage(_ context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.productImages[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.productImages, id)
+	return nil
+}
+
+func (r *productRepository) CreateOption(_ context.Context, o *models.ProductOption) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	o.ID = r.s.id()
+	o.CreatedAt = time.Now()
+	o.UpdatedAt = o.CreatedAt
+	r.s.state.productOptions[o.ID] = *o
+	return nil
+}
+
+func (r *productRepository) UpdateOption(_ context.Context, o models.ProductOption) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.state.productOptions[o.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	stored.PriceAdjustment, stored.IsActive, stored.UpdatedAt = o.PriceAdjustment, o.IsActive, time.Now()
+	r.s.state.productOptions[o.ID] = stored
+	return nil
+}
+
+func (r *productRepository) DeleteOption(_ context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.productOptions[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.productOptions, id)
+	return nil
+}
+
+// onFlashSale reports whether p is flagged as flash sale or in a flash sale
+// running now. The caller must hold the lock.
+func (r *productRepository) onFlashSale(p models.Product) bool {
+	if p.IsFlashSale {
+		return true
+	}
+	now := time.Now()
+	for _, sale := range r.s.state.flashSales {
+		if !sale.RunningAt(now) {
+			continue
+		}
+		for _, item := range sale.Items {
+			if item.ProductID == p.ID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// listed reports whether shoppers can see p: it is active and not archived.
+func listed(p models.Product) bool {
+	return p.IsActive && p.DeletedAt == nil
+}
+
+func (r *productRepository) translate(p models.Product, locale string) models.Product {
+	if t, ok := r.s.state.productTranslations[p.ID][locale]; ok {
+		t.Apply(&p.Name, &p.Description)
+	}
+	return p
+}
+
+// relevance approximates the Postgres ranking: a substring of the name, then
+// each query term matching a word of the name, category or description, with
+// the same weights as product_search_document. It is 0 when nothing matched.
+func (r *productRepository) relevance(p, translated models.Product, q string) float64 {
+	terms := search.Terms(q)
+	score := 0.0
+	if strings.Contains(strings.ToLower(p.Name), strings.ToLower(q)) {
+		score += 1
+	}
+	score += float64(search.Score(p.Name+" "+translated.Name, terms))
+	score += 0.4 * float64(search.Score(r.s.state.categories[p.CategoryID].Name, terms))
+	score += 0.2 * float64(search.Score(p.Description+" "+translated.Description, terms))
+	return score
+}
+
+func sortNewestFirst(products []models.Product) {
+	sort.Slice(products, func(i, j int) bool {
+		if !products[i].CreatedAt.Equal(products[j].CreatedAt) {
+			return products[i].CreatedAt.After(products[j].CreatedAt)
+		}
+		return products[i].ID > products[j].ID
+	})
+}
+
+// window applies p to items sorted newest first: it skips past the cursor
+// when one is set, then takes one page.
+func window[T any](items []T, p pagination.Params, key func(T) pagination.Cursor) []T {
+	if p.After != nil {
+		start := len(items)
+		for i, item := range items {
+			k := key(item)
+			if k.CreatedAt.Before(p.After.CreatedAt) || k.CreatedAt.Equal(p.After.CreatedAt) && k.ID < p.After.ID {
+				start = i
+				break
+			}
+		}
+		items = items[start:]
+	}
+	return page(items, p.Limit, p.Offset)
+}
+
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return items[:0]
+	}
+	end := offset + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"context"
+	"sort"
+)
+
+type promoRepository struct{ s *Store }
+
+func (r *promoRepository) Active(context.Context) ([]models.Promo, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	promos := []models.Promo{}
+	for _, p := range r.s.state.promos {
+		promos = append(promos, p)
+	}
+	sort.Slice(promos, func(i, j int) bool { return promos[i].ID > promos[j].ID })
+	return promos, nil
+}
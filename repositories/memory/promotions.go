@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"sort"
+	"time"
+)
+
+type promotionRepository struct{ s *Store }
+
+func (r *promotionRepository) List(context.Context) ([]models.PromotionRule, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rules := []models.PromotionRule{}
+	for _, rule := range r.s.state.promotions {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID > rules[j].ID })
+	return rules, nil
+}
+
+func (r *promotionRepository) Get(_ context.Context, id int) (models.PromotionRule, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rule, ok := r.s.state.promotions[id]
+	if !ok {
+		return models.PromotionRule{}, repositories.ErrNotFound
+	}
+	return rule, nil
+}
+
+func (r *promotionRepository) Running(_ context.Context, now time.Time) ([]models.PromotionRule, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rules := []models.PromotionRule{}
+	for _, rule := range r.s.state.promotions {
+		if rule.RunningAt(now) {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules, nil
+}
+
+func (r *promotionRepository) Create(_ context.Context, rule *models.PromotionRule) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rule.ID = r.s.id()
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = rule.CreatedAt
+	r.s.state.promotions[rule.ID] = *rule
+	return nil
+}
+
+func (r *promotionRepository) Update(_ context.Context, rule models.PromotionRule) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	existing, ok := r.s.state.promotions[rule.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	rule.CreatedAt = existing.CreatedAt
+	r.s.state.promotions[rule.ID] = rule
+	return nil
+}
+
+func (r *promotionRepository) Delete(_ context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.promotions[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.promotions, id)
+	for orderID, items := range r.s.state.orderItems {
+		for i := range items {
+			if items[i].PromotionRuleID != nil && *items[i].PromotionRuleID == id {
+				items[i].PromotionRuleID = nil
+			}
+		}
+		r.s.state.orderItems[orderID] = items
+	}
+	return nil
+}
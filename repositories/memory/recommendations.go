@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"slices"
+	"sort"
+	"time"
+)
+
+type recommendationRepository struct{ s *Store }
+
+func (r *recommendationRepository) List(_ context.Context, productID int) ([]models.ProductRecommendation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	recommendations := []models.ProductRecommendation{}
+	for _, rec := range r.s.state.recommendations {
+		if rec.ProductID == productID {
+			rec.RecommendedProductName = r.s.state.products[rec.RecommendedProductID].Name
+			recommendations = append(recommendations, rec)
+		}
+	}
+	sortRecommendations(recommendations)
+	return recommendations, nil
+}
+
+func (r *recommendationRepository) Create(_ context.Context, rec *models.ProductRecommendation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rec.ID = r.s.id()
+	rec.CreatedAt = time.Now()
+	rec.UpdatedAt = rec.CreatedAt
+	stored := *rec
+	stored.RecommendedProductName = ""
+	r.s.state.recommendations[rec.ID] = stored
+	return nil
+}
+
+func (r *recommendationRepository) Update(_ context.Context, rec models.ProductRecommendation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.state.recommendations[rec.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	stored.Type = rec.Type
+	stored.Priority = rec.Priority
+	stored.IsActive = rec.IsActive
+	stored.UpdatedAt = time.Now()
+	r.s.state.recommendations[rec.ID] = stored
+	return nil
+}
+
+func (r *recommendationRepository) Delete(_ context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.recommendations[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.recommendations, id)
+	return nil
+}
+
+func (r *recommendationRepository) Curated(_ context.Context, productIDs []int, types []string, locale string) ([]models.Product, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	best := map[int]models.ProductRecommendation{}
+	for _, rec := range r.s.state.recommendations {
+		if !rec.IsActive || !slices.Contains(productIDs, rec.ProductID) || slices.Contains(productIDs, rec.RecommendedProductID) ||
+			!slices.Contains(types, rec.Type) || !listed(r.s.state.products[rec.RecommendedProductID]) {
+			continue
+		}
+		if current, ok := best[rec.RecommendedProductID]; ok && !ranksBefore(rec, current) {
+			continue
+		}
+		best[rec.RecommendedProductID] = rec
+	}
+	picks := make([]models.ProductRecommendation, 0, len(best))
+	for _, rec := range best {
+		picks = append(picks, rec)
+	}
+	sortRecommendations(picks)
+
+	products := &productRepository{r.s}
+	curated := make([]models.Product, 0, len(picks))
+	for _, rec := range picks {
+		p := products.translate(r.s.state.products[rec.RecommendedProductID], locale)
+		p.RecommendationType = rec.Type
+		curated = append(curated, p)
+	}
+	return curated, nil
+}
+
+func (r *recommendationRepository) BoughtTogether(_ context.Context, productIDs []int, limit int, locale string) ([]models.Product, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	orders := map[int]int{}
+	for _, id := range productIDs {
+		for related, n := range r.s.state.coPurchases[id] {
+			if !slices.Contains(productIDs, related) && listed(r.s.state.products[related]) {
+				orders[related] += n
+			}
+		}
+	}
+	ids := make([]int, 0, len(orders))
+	for id := range orders {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if orders[ids[i]] != orders[ids[j]] {
+			return orders[ids[i]] > orders[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	products := &productRepository{r.s}
+	together := make([]models.Product, 0, len(ids))
+	for _, id := range ids {
+		p := products.translate(r.s.state.products[id], locale)
+		p.RecommendationType = models.RecommendationBoughtTogether
+		together = append(together, p)
+	}
+	return together, nil
+}
+
+func (r *recommendationRepository) RefreshBoughtTogether(_ context.Context, minOrders int, _ time.Time) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	counts := map[int]map[int]int{}
+	for orderID, items := range r.s.state.orderItems {
+		if r.s.state.statuses[r.s.state.orders[orderID].StatusID] == "cancelled" {
+			continue
+		}
+		inOrder := map[int]bool{}
+		for _, item := range items {
+			inOrder[item.ProductID] = true
+		}
+		for a := range inOrder {
+			for b := range inOrder {
+				if a == b {
+					continue
+				}
+				if counts[a] == nil {
+					counts[a] = map[int]int{}
+				}
+				counts[a][b]++
+			}
+		}
+	}
+
+	pairs := 0
+	r.s.state.coPurchases = map[int]map[int]int{}
+	for a, related := range counts {
+		for b, n := range related {
+			if n < minOrders {
+				continue
+			}
+			if r.s.state.coPurchases[a] == nil {
+				r.s.state.coPurchases[a] = map[int]int{}
+			}
+			r.s.state.coPurchases[a][b] = n
+			pairs++
+		}
+	}
+	return pairs / 2, nil
+}
+
+// ranksBefore reports whether a is shown before b: higher priority first,
+// then the older link.
+func ranksBefore(a, b models.ProductRecommendation) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.ID < b.ID
+}
+
+func sortRecommendations(recommendations []models.ProductRecommendation) {
+	sort.Slice(recommendations, func(i, j int) bool { return ranksBefore(recommendations[i], recommendations[j]) })
+}
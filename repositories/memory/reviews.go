@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+type reviewRepository struct{ s *Store }
+
+func (r *reviewRepository) List(_ context.Context, filter repositories.ReviewFilter) ([]models.ProductReview, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	reviews := []models.ProductReview{}
+	for _, review := range r.s.state.reviews {
+		if filter.ProductID > 0 && review.ProductID != filter.ProductID {
+			continue
+		}
+		if filter.Status != "" && review.Status != filter.Status {
+			continue
+		}
+		reviews = append(reviews, r.withNames(review))
+	}
+	sort.Slice(reviews, func(i, j int) bool {
+		if !reviews[i].CreatedAt.Equal(reviews[j].CreatedAt) {
+			return reviews[i].CreatedAt.After(reviews[j].CreatedAt)
+		}
+		return reviews[i].ID > reviews[j].ID
+	})
+	return window(reviews, filter.Params, func(review models.ProductReview) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: review.CreatedAt, ID: review.ID}
+	}), len(reviews), nil
+}
+
+func (r *reviewRepository) Get(_ context.Context, id int) (models.ProductReview, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	review, ok := r.s.state.reviews[id]
+	if !ok {
+		return models.ProductReview{}, repositories.ErrNotFound
+	}
+	return r.withNames(review), nil
+}
+
+func (r *reviewRepository) Find(_ context.Context, productID, userID int) (models.ProductReview, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, review := range r.s.state.reviews {
+		if review.ProductID == productID && review.UserID == userID {
+			return r.withNames(review), nil
+		}
+	}
+	return models.ProductReview{}, repositories.ErrNotFound
+}
+
+func (r *reviewRepository) Create(_ context.Context, review *models.ProductReview) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	review.ID = r.s.id()
+	review.CreatedAt, review.UpdatedAt = now, now
+	r.s.state.reviews[review.ID] = *review
+	return nil
+}
+
+func (r *reviewRepository) Update(_ context.Context, review models.ProductReview) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.state.reviews[review.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	stored.Rating, stored.Text, stored.Status = review.Rating, review.Text, review.Status
+	stored.Reply, stored.RepliedAt, stored.UpdatedAt = review.Reply, review.RepliedAt, review.UpdatedAt
+	r.s.state.reviews[review.ID] = stored
+	return nil
+}
+
+func (r *reviewRepository) Delete(_ context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.reviews[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.reviews, id)
+	return nil
+}
+
+func (r *reviewRepository) Ratings(_ context.Context, productID int) (map[int]int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	counts := map[int]int{}
+	for _, review := range r.s.state.reviews {
+		if review.ProductID == productID && review.Status == models.ReviewApproved {
+			counts[review.Rating]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *reviewRepository) Recompute(_ context.Context, productID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.recompute(productID)
+	return nil
+}
+
+// recompute does the work of Recompute; the caller holds the lock.
+func (r *reviewRepository) recompute(productID int) {
+	p, ok := r.s.state.products[productID]
+	if !ok {
+		return
+	}
+	sum, count := 0, 0
+	for _, review := range r.s.state.reviews {
+		if review.ProductID == productID && review.Status == models.ReviewApproved {
+			sum += review.Rating
+			count++
+		}
+	}
+	p.AverageRating, p.ReviewCount = 0, count
+	if count > 0 {
+		p.AverageRating = math.Round(float64(sum)/float64(count)*100) / 100
+	}
+	r.s.state.products[productID] = p
+}
+
+// withNames fills in the product and reviewer names; the caller holds the
+// lock.
+func (r *reviewRepository) withNames(review models.ProductReview) models.ProductReview {
+	review.ProductName = r.s.state.products[review.ProductID].Name
+	review.User = r.s.state.users[review.UserID].FullName
+	return review
+}
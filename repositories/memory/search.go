@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"coffee-shop/search"
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+type searchRepository struct{ s *Store }
+
+// suggestion is a candidate label; prefix marks labels that start with the
+// query, which are listed before word and typo matches.
+type suggestion struct {
+	id     int
+	label  string
+	prefix bool
+	hits   int
+}
+
+func (r *searchRepository) Suggest(_ context.Context, filter repositories.SuggestFilter) (models.Suggestions, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	products := []suggestion{}
+	for _, p := range r.s.state.products {
+		if !listed(p) {
+			continue
+		}
+		label := (&productRepository{r.s}).translate(p, filter.Locale).Name
+		if sg, ok := suggest(p.ID, filter.Query, label, p.Name); ok {
+			products = append(products, sg)
+		}
+	}
+	categories := []suggestion{}
+	for _, c := range r.s.state.categories {
+		label := (&categoryRepository{r.s}).translate(c, filter.Locale).Name
+		if sg, ok := suggest(c.ID, filter.Query, label, c.Name); ok {
+			categories = append(categories, sg)
+		}
+	}
+	queries := []suggestion{}
+	for _, q := range r.s.state.searchQueries {
+		if q.Status != models.SearchQueryAllowed || q.Hits < filter.MinHits {
+			continue
+		}
+		if sg, ok := suggest(0, filter.Query, q.Query); ok {
+			sg.hits = q.Hits
+			queries = append(queries, sg)
+		}
+	}
+
+	s := models.Suggestions{
+		Products:   []models.SuggestedProduct{},
+		Categories: []models.SuggestedCategory{},
+		Queries:    []string{},
+	}
+	for _, sg := range top(products, filter.Limit) {
+		s.Products = append(s.Products, models.SuggestedProduct{ID: sg.id, Name: sg.label})
+	}
+	for _, sg := range top(categories, filter.Limit) {
+		s.Categories = append(s.Categories, models.SuggestedCategory{ID: sg.id, Name: sg.label})
+	}
+	for _, sg := range top(queries, filter.Limit) {
+		s.Queries = append(s.Queries, sg.label)
+	}
+	return s, nil
+}
+
+func (r *searchRepository) RecordQuery(_ context.Context, query string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	q, ok := r.s.state.searchQueries[query]
+	if !ok {
+		q = models.SearchQuery{ID: r.s.id(), Query: query, Status: models.SearchQueryPending}
+	}
+	q.Hits++
+	q.LastSearchedAt = time.Now()
+	r.s.state.searchQueries[query] = q
+	return nil
+}
+
+func (r *searchRepository) Queries(_ context.Context, filter repositories.SearchQueryFilter) ([]models.SearchQuery, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	queries := []models.SearchQuery{}
+	for _, q := range r.s.state.searchQueries {
+		if filter.Status == "" || q.Status == filter.Status {
+			queries = append(queries, q)
+		}
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		if queries[i].Hits != queries[j].Hits {
+			return queries[i].Hits > queries[j].Hits
+		}
+		return queries[i].Query < queries[j].Query
+	})
+	return page(queries, filter.Limit, filter.Offset), len(queries), nil
+}
+
+func (r *searchRepository) Query(_ context.Context, id int) (models.SearchQuery, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, q := range r.s.state.searchQueries {
+		if q.ID == id {
+			return q, nil
+		}
+	}
+	return models.SearchQuery{}, repositories.ErrNotFound
+}
+
+func (r *searchRepository) SetQueryStatus(_ context.Context, id int, status string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for query, q := range r.s.state.searchQueries {
+		if q.ID == id {
+			q.Status = status
+			r.s.state.searchQueries[query] = q
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+// SearchHits returns how many times query was recorded.
+func (s *Store) SearchHits(query string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.searchQueries[query].Hits
+}
+
+// suggest matches q against the label and any other names of the same row
+// (such as the untranslated name) by prefix, word prefix or typo.
+func suggest(id int, q, label string, names ...string) (suggestion, bool) {
+	sg := suggestion{id: id, label: label}
+	matched := false
+	for _, name := range append([]string{label}, names...) {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, q) {
+			if name == label {
+				sg.prefix = true
+			}
+			matched = true
+		}
+		if strings.Contains(lower, " "+q) {
+			matched = true
+		}
+		for _, word := range search.Terms(name) {
+			if search.Matches(word, q) {
+				matched = true
+			}
+		}
+	}
+	return sg, matched
+}
+
+func top(items []suggestion, limit int) []suggestion {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].prefix != items[j].prefix {
+			return items[i].prefix
+		}
+		if items[i].hits != items[j].hits {
+			return items[i].hits > items[j].hits
+		}
+		return items[i].label < items[j].label
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
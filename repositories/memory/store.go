@@ -0,0 +1,263 @@
+// Package memory provides in-memory implementations of the repositories,
+// used by the service and handler tests in place of PostgreSQL.
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps every table in maps guarded by a single mutex. WithTx snapshots
+// the state and restores it when fn fails, which is enough to exercise the
+// rollback paths of the services.
+type Store struct {
+	mu    sync.Mutex
+	state *state
+}
+
+type state struct {
+	nextID int
+
+	products             map[int]models.Product
+	productTranslations  map[int]map[string]models.ProductTranslation
+	productImages        map[int]models.ProductImage
+	productOptions       map[int]models.ProductOption
+	sizes                map[int]models.ProductSizeV2
+	temperatures         map[int]models.ProductTemperatureV2
+	variants             map[int]models.ProductVariantV2
+	categories           map[int]models.Category
+	categoryTranslations map[int]map[string]models.CategoryTranslation
+	users                map[int]userRow
+	orders               map[int]orderRow
+	orderItems           map[int][]repositories.NewOrderItem
+	statuses             map[int]string
+	cart                 map[int]cartRow
+	stockMovements       []models.StockMovement
+	prices               map[int]models.ProductPrice
+	flashSales           map[int]models.FlashSale
+	promotions           map[int]models.PromotionRule
+	promos               map[int]models.Promo
+	favorites            map[int]map[int]time.Time
+	audit                []auditRow
+	searchQueries        map[string]models.SearchQuery
+	reviews              map[int]models.ProductReview
+	recommendations      map[int]models.ProductRecommendation
+	// coPurchases counts the orders holding both products, by product and
+	// then related product, as of the last refresh.
+	coPurchases map[int]map[int]int
+	views       []productView
+	// viewDaily counts each product's viewers each day, as of the last
+	// CountDaily.
+	viewDaily map[viewDay]int
+}
+
+func NewStore() *Store {
+	return &Store{state: &state{
+		nextID:               1,
+		products:             map[int]models.Product{},
+		productTranslations:  map[int]map[string]models.ProductTranslation{},
+		productImages:        map[int]models.ProductImage{},
+		productOptions:       map[int]models.ProductOption{},
+		sizes:                map[int]models.ProductSizeV2{},
+		temperatures:         map[int]models.ProductTemperatureV2{},
+		variants:             map[int]models.ProductVariantV2{},
+		categories:           map[int]models.Category{},
+		categoryTranslations: map[int]map[string]models.CategoryTranslation{},
+		users:                map[int]userRow{},
+		orders:               map[int]orderRow{},
+		orderItems:           map[int][]repositories.NewOrderItem{},
+		statuses:             map[int]string{1: "pending", 2: "completed", 3: "cancelled"},
+		cart:                 map[int]cartRow{},
+		prices:               map[int]models.ProductPrice{},
+		flashSales:           map[int]models.FlashSale{},
+		promotions:           map[int]models.PromotionRule{},
+		promos:               map[int]models.Promo{},
+		favorites:            map[int]map[int]time.Time{},
+		searchQueries:        map[string]models.SearchQuery{},
+		reviews:              map[int]models.ProductReview{},
+		recommendations:      map[int]models.ProductRecommendation{},
+		coPurchases:          map[int]map[int]int{},
+		viewDaily:            map[viewDay]int{},
+	}}
+}
+
+func (s *Store) Products() repositories.ProductRepository     { return &productRepository{s} }
+func (s *Store) Categories() repositories.CategoryRepository  { return &categoryRepository{s} }
+func (s *Store) Users() repositories.UserRepository           { return &userRepository{s} }
+func (s *Store) Orders() repositories.OrderRepository         { return &orderRepository{s} }
+func (s *Store) Carts() repositories.CartRepository           { return &cartRepository{s} }
+func (s *Store) Inventory() repositories.InventoryRepository  { return &inventoryRepository{s} }
+func (s *Store) Prices() repositories.PriceRepository         { return &priceRepository{s} }
+func (s *Store) FlashSales() repositories.FlashSaleRepository { return &flashSaleRepository{s} }
+func (s *Store) Promotions() repositories.PromotionRepository { return &promotionRepository{s} }
+func (s *Store) Promos() repositories.PromoRepository         { return &promoRepository{s} }
+func (s *Store) Reviews() repositories.ReviewRepository       { return &reviewRepository{s} }
+func (s *Store) Favorites() repositories.FavoriteRepository   { return &favoriteRepository{s} }
+func (s *Store) Audit() repositories.AuditRepository          { return &auditRepository{s} }
+func (s *Store) Search() repositories.SearchRepository        { return &searchRepository{s} }
+func (s *Store) Views() repositories.ViewRepository           { return &viewRepository{s} }
+
+func (s *Store) Recommendations() repositories.RecommendationRepository {
+	return &recommendationRepository{s}
+}
+
+func (s *Store) Translations() repositories.TranslationRepository {
+	return &translationRepository{s}
+}
+
+func (s *Store) WithTx(ctx context.Context, fn func(tx repositories.Store) error) error {
+	s.mu.Lock()
+	snapshot := s.state.clone()
+	s.mu.Unlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.state = snapshot
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// AuditEntries returns the audit entries recorded so far.
+func (s *Store) AuditEntries() []models.AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]models.AuditEntry, len(s.state.audit))
+	for i, row := range s.state.audit {
+		entries[i] = row.AuditEntry
+	}
+	return entries
+}
+
+// SetProductTranslation stores t, replacing any translation for the same
+// product and locale.
+func (s *Store) SetProductTranslation(t models.ProductTranslation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state.productTranslations[t.ProductID] == nil {
+		s.state.productTranslations[t.ProductID] = map[string]models.ProductTranslation{}
+	}
+	s.state.productTranslations[t.ProductID][t.Locale] = t
+}
+
+// AddSize, AddTemperature and AddVariant store an active choice that
+// product options can use.
+func (s *Store) AddSize(size models.ProductSizeV2) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.sizes[size.ID] = size
+}
+
+func (s *Store) AddTemperature(temperature models.ProductTemperatureV2) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.temperatures[temperature.ID] = temperature
+}
+
+func (s *Store) AddVariant(variant models.ProductVariantV2) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.variants[variant.ID] = variant
+}
+
+// AddPromo stores an active promo and fills in its ID.
+func (s *Store) AddPromo(p *models.Promo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p.ID = s.id()
+	s.state.promos[p.ID] = *p
+}
+
+// AddReview records an approved review of the product with the rating, by a
+// customer of its own.
+func (s *Store) AddReview(productID, rating int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, now := s.id(), time.Now()
+	s.state.reviews[id] = models.ProductReview{ID: id, ProductID: productID, UserID: s.id(), Rating: rating,
+		Status: models.ReviewApproved, CreatedAt: now, UpdatedAt: now}
+	(&reviewRepository{s}).recompute(productID)
+}
+
+// PasswordHash returns the stored password hash of the user.
+func (s *Store) PasswordHash(userID int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.users[userID].PasswordHash
+}
+
+// SetLanguage saves the user's preferred language, as a profile update does.
+func (s *Store) SetLanguage(userID int, language string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row := s.state.users[userID]
+	row.Language = language
+	s.state.users[userID] = row
+}
+
+func (s *Store) id() int {
+	id := s.state.nextID
+	s.state.nextID++
+	return id
+}
+
+func (st *state) clone() *state {
+	c := *st
+	c.products = cloneMap(st.products)
+	c.productTranslations = map[int]map[string]models.ProductTranslation{}
+	for id, m := range st.productTranslations {
+		c.productTranslations[id] = cloneMap(m)
+	}
+	c.productImages = cloneMap(st.productImages)
+	c.productOptions = cloneMap(st.productOptions)
+	c.sizes = cloneMap(st.sizes)
+	c.temperatures = cloneMap(st.temperatures)
+	c.variants = cloneMap(st.variants)
+	c.categories = cloneMap(st.categories)
+	c.categoryTranslations = map[int]map[string]models.CategoryTranslation{}
+	for id, m := range st.categoryTranslations {
+		c.categoryTranslations[id] = cloneMap(m)
+	}
+	c.users = cloneMap(st.users)
+	c.orders = cloneMap(st.orders)
+	c.orderItems = map[int][]repositories.NewOrderItem{}
+	for id, items := range st.orderItems {
+		c.orderItems[id] = append([]repositories.NewOrderItem(nil), items...)
+	}
+	c.statuses = cloneMap(st.statuses)
+	c.cart = cloneMap(st.cart)
+	c.stockMovements = append([]models.StockMovement(nil), st.stockMovements...)
+	c.prices = cloneMap(st.prices)
+	c.flashSales = cloneMap(st.flashSales)
+	c.promotions = cloneMap(st.promotions)
+	c.promos = cloneMap(st.promos)
+	c.favorites = map[int]map[int]time.Time{}
+	for userID, m := range st.favorites {
+		c.favorites[userID] = cloneMap(m)
+	}
+	c.audit = append([]auditRow(nil), st.audit...)
+	c.searchQueries = cloneMap(st.searchQueries)
+	c.reviews = cloneMap(st.reviews)
+	c.recommendations = cloneMap(st.recommendations)
+	c.coPurchases = map[int]map[int]int{}
+	for productID, m := range st.coPurchases {
+		c.coPurchases[productID] = cloneMap(m)
+	}
+	c.views = append([]productView(nil), st.views...)
+	c.viewDaily = cloneMap(st.viewDaily)
+	return &c
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	c := make(map[K]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+var _ repositories.Store = (*Store)(nil)
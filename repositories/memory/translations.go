@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"sort"
+	"time"
+)
+
+type translationRepository struct{ s *Store }
+
+func (r *translationRepository) ProductTranslations(_ context.Context, productID int) ([]models.ProductTranslation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	translations := []models.ProductTranslation{}
+	for _, t := range r.s.state.productTranslations[productID] {
+		translations = append(translations, t)
+	}
+	sort.Slice(translations, func(i, j int) bool { return translations[i].Locale < translations[j].Locale })
+	return translations, nil
+}
+
+func (r *translationRepository) ProductTranslation(_ context.Context, productID int, locale string) (models.ProductTranslation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.state.productTranslations[productID][locale]
+	if !ok {
+		return models.ProductTranslation{}, repositories.ErrNotFound
+	}
+	return t, nil
+}
+
+func (r *translationRepository) SaveProductTranslation(_ context.Context, t models.ProductTranslation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.s.state.productTranslations[t.ProductID] == nil {
+		r.s.state.productTranslations[t.ProductID] = map[string]models.ProductTranslation{}
+	}
+	t.UpdatedAt = time.Now()
+	r.s.state.productTranslations[t.ProductID][t.Locale] = t
+	return nil
+}
+
+func (r *translationRepository) DeleteProductTranslation(_ context.Context, productID int, locale string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.productTranslations[productID][locale]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.productTranslations[productID], locale)
+	return nil
+}
+
+func (r *translationRepository) CategoryTranslations(_ context.Context, categoryID int) ([]models.CategoryTranslation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	translations := []models.CategoryTranslation{}
+	for _, t := range r.s.state.categoryTranslations[categoryID] {
+		translations = append(translations, t)
+	}
+	sort.Slice(translations, func(i, j int) bool { return translations[i].Locale < translations[j].Locale })
+	return translations, nil
+}
+
+func (r *translationRepository) CategoryTranslation(_ context.Context, categoryID int, locale string) (models.CategoryTranslation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.state.categoryTranslations[categoryID][locale]
+	if !ok {
+		return models.CategoryTranslation{}, repositories.ErrNotFound
+	}
+	return t, nil
+}
+
+func (r *translationRepository) SaveCategoryTranslation(_ context.Context, t models.CategoryTranslation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.s.state.categoryTranslations[t.CategoryID] == nil {
+		r.s.state.categoryTranslations[t.CategoryID] = map[string]models.CategoryTranslation{}
+	}
+	t.UpdatedAt = time.Now()
+	r.s.state.categoryTranslations[t.CategoryID][t.Locale] = t
+	return nil
+}
+
+func (r *translationRepository) DeleteCategoryTranslation(_ context.Context, categoryID int, locale string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.categoryTranslations[categoryID][locale]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.categoryTranslations[categoryID], locale)
+	return nil
+}
@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"time"
+)
+
+type userRow struct {
+	models.UserWithProfile
+	PasswordHash string
+	Language     string
+	PhotoID      string
+}
+
+type userRepository struct{ s *Store }
+
+func (r *userRepository) List(_ context.Context, p pagination.Params) ([]models.UserWithProfile, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	users := []models.UserWithProfile{}
+	for _, u := range r.s.state.users {
+		users = append(users, u.UserWithProfile)
+	}
+	sortByIDDesc(users, func(u models.UserWithProfile) int { return u.ID })
+	return window(users, p, func(u models.UserWithProfile) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: u.CreatedAt, ID: u.ID}
+	}), len(users), nil
+}
+
+func (r *userRepository) Get(_ context.Context, id int) (models.UserWithProfile, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.state.users[id]
+	if !ok {
+		return models.UserWithProfile{}, repositories.ErrNotFound
+	}
+	return u.UserWithProfile, nil
+}
+
+func (r *userRepository) GetByEmail(_ context.Context, email string) (models.UserWithProfile, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, u := range r.s.state.users {
+		if u.Email == email {
+			return u.UserWithProfile, nil
+		}
+	}
+	return models.UserWithProfile{}, repositories.ErrNotFound
+}
+
+func (r *userRepository) Language(_ context.Context, id int) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.state.users[id]
+	if !ok {
+		return "", repositories.ErrNotFound
+	}
+	return u.Language, nil
+}
+
+func (r *userRepository) EmailExists(_ context.Context, email string, excludeID int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, u := range r.s.state.users {
+		if u.Email == email && u.ID != excludeID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *userRepository) Create(_ context.Context, u repositories.NewUser) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	id := r.s.id()
+	r.s.state.users[id] = userRow{
+		UserWithProfile: models.UserWithProfile{
+			ID:        id,
+			Email:     u.Email,
+			Role:      u.Role,
+			FullName:  u.FullName,
+			Phone:     u.Phone,
+			CreatedAt: time.Now(),
+		},
+		PasswordHash: u.PasswordHash,
+		Language:     u.Language,
+	}
+	return id, nil
+}
+
+func (r *userRepository) Update(_ context.Context, id int, u repositories.UserUpdate) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	row, ok := r.s.state.users[id]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	if u.Email != "" {
+		row.Email = u.Email
+	}
+	if u.Role != "" {
+		row.Role = u.Role
+	}
+	row.FullName, row.Phone, row.Address = u.FullName, u.Phone, u.Address
+	r.s.state.users[id] = row
+	return nil
+}
+
+func (r *userRepository) PasswordHash(_ context.Context, id int) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.state.users[id]
+	if !ok {
+		return "", repositories.ErrNotFound
+	}
+	return u.PasswordHash, nil
+}
+
+func (r *userRepository) UpdateProfile(_ context.Context, id int, u repositories.ProfileUpdate) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	row, ok := r.s.state.users[id]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	set := func(field *string, value string) {
+		if value != "" {
+			*field = value
+		}
+	}
+	set(&row.FullName, u.FullName)
+	set(&row.Phone, u.Phone)
+	set(&row.Address, u.Address)
+	set(&row.Language, u.Language)
+	if u.PhotoURL != "" {
+		row.PhotoURL, row.PhotoID = u.PhotoURL, u.PhotoID
+	}
+	r.s.state.users[id] = row
+	return nil
+}
+
+func (r *userRepository) PhotoID(_ context.Context, id int) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.state.users[id]
+	if !ok {
+		return "", repositories.ErrNotFound
+	}
+	return u.PhotoID, nil
+}
+
+func (r *userRepository) SetPassword(_ context.Context, id int, hash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	row, ok := r.s.state.users[id]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	row.PasswordHash = hash
+	r.s.state.users[id] = row
+	return nil
+}
+
+func (r *userRepository) Delete(_ context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.state.users[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.s.state.users, id)
+	delete(r.s.state.favorites, id)
+	return nil
+}
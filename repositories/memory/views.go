@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"coffee-shop/models"
+	"context"
+	"slices"
+	"time"
+)
+
+type productView struct {
+	id, userID, productID int
+	at                    time.Time
+}
+
+// viewDay is a product's day in the daily view counts.
+type viewDay struct {
+	productID int
+	day       time.Time
+}
+
+type viewRepository struct{ s *Store }
+
+func (r *viewRepository) Record(_ context.Context, userID, productID int, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.state.views = append(r.s.state.views, productView{id: r.s.id(), userID: userID, productID: productID, at: at})
+	return nil
+}
+
+func (r *viewRepository) Recent(_ context.Context, userID, limit int) ([]int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	latest := map[int]productView{}
+	for _, v := range r.s.state.views {
+		if v.userID != userID {
+			continue
+		}
+		if seen, ok := latest[v.productID]; !ok || v.at.After(seen.at) || (v.at.Equal(seen.at) && v.id > seen.id) {
+			latest[v.productID] = v
+		}
+	}
+	views := make([]productView, 0, len(latest))
+	for _, v := range latest {
+		views = append(views, v)
+	}
+	slices.SortFunc(views, func(a, b productView) int {
+		if c := b.at.Compare(a.at); c != 0 {
+			return c
+		}
+		return b.id - a.id
+	})
+
+	ids := []int{}
+	for _, v := range views {
+		if len(ids) == limit {
+			break
+		}
+		ids = append(ids, v.productID)
+	}
+	return ids, nil
+}
+
+func (r *viewRepository) Products(_ context.Context, ids []int, locale string) ([]models.Product, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	products := &productRepository{r.s}
+	viewed := []models.Product{}
+	for _, id := range ids {
+		if p, ok := r.s.state.products[id]; ok && listed(p) {
+			viewed = append(viewed, products.translate(p, locale))
+		}
+	}
+	return viewed, nil
+}
+
+func (r *viewRepository) CountDaily(_ context.Context, since time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	from := since.Truncate(24 * time.Hour)
+	if len(r.s.state.viewDaily) > 0 {
+		from = time.Time{}
+		for key := range r.s.state.viewDaily {
+			if key.day.After(from) {
+				from = key.day
+			}
+		}
+	}
+
+	viewers := map[viewDay]map[int]bool{}
+	for _, v := range r.s.state.views {
+		if v.at.Before(from) {
+			continue
+		}
+		key := viewDay{v.productID, v.at.Truncate(24 * time.Hour)}
+		if viewers[key] == nil {
+			viewers[key] = map[int]bool{}
+		}
+		viewers[key][v.userID] = true
+	}
+	for key, users := range viewers {
+		r.s.state.viewDaily[key] = len(users)
+	}
+	return nil
+}
+
+func (r *viewRepository) Prune(_ context.Context, before time.Time) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for key := range r.s.state.viewDaily {
+		if key.day.Before(before.Truncate(24 * time.Hour)) {
+			delete(r.s.state.viewDaily, key)
+		}
+	}
+	kept := r.s.state.views[:0]
+	for _, v := range r.s.state.views {
+		if !v.at.Before(before) {
+			kept = append(kept, v)
+		}
+	}
+	pruned := len(r.s.state.views) - len(kept)
+	r.s.state.views = kept
+	return pruned, nil
+}
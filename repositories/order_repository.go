@@ -0,0 +1,478 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OrderFilter narrows the admin order list. An empty Status or "All" matches
+// every order; Search matches the order ID.
+type OrderFilter struct {
+	pagination.Params
+	Status string
+	Search string
+}
+
+// HistoryFilter narrows a customer's order history. From and To are dates in
+// 2006-01-02 form; a non-zero Month restricts the result to that month.
+type HistoryFilter struct {
+	pagination.Params
+	UserID int
+	Status string
+	From   string
+	To     string
+	Month  time.Time
+}
+
+// NewOrder is the header row written by checkout.
+type NewOrder struct {
+	OrderNumber     string
+	UserID          int
+	StatusID        int
+	CustomerName    string
+	DeliveryAddress string
+	Subtotal        int
+	DeliveryFee     int
+	Total           int
+	PaymentMethodID int
+	OrderDate       time.Time
+}
+
+// NewOrderItem is a line of a NewOrder. Zero or nil option IDs are stored as
+// NULL. FlashSaleID is the flash sale that priced the line, if any, and
+// DiscountAmount the promotion discount taken off UnitPrice × Quantity.
+type NewOrderItem struct {
+	ProductID       int
+	Quantity        int
+	SizeID          *int
+	TemperatureID   *int
+	UnitPrice       int
+	IsFlashSale     bool
+	FlashSaleID     *int
+	DiscountAmount  int
+	PromotionRuleID *int
+	PromotionName   string
+}
+
+type OrderRepository interface {
+	// List returns one page of orders, newest first, and the total number of matches.
+	List(ctx context.Context, filter OrderFilter) ([]models.OrderSummaryV2, int, error)
+	Get(ctx context.Context, id int) (models.OrderSummaryV2, error)
+	// StatusName returns the name of the order's current status. Inside a
+	// transaction the order stays locked until it ends.
+	StatusName(ctx context.Context, id int) (string, error)
+	UpdateStatus(ctx context.Context, id int, statusID int) error
+	// Delete removes the order and its items.
+	Delete(ctx context.Context, id int) error
+
+	History(ctx context.Context, filter HistoryFilter) ([]models.HistoryOrderV2, int, error)
+	// Detail returns the order with its items, or ErrNotFound when it does not
+	// belong to userID.
+	Detail(ctx context.Context, orderID, userID int) (models.OrderDetailV2, error)
+	// Items returns the lines of an order.
+	Items(ctx context.Context, orderID int) ([]NewOrderItem, error)
+
+	// StatusID resolves an order_status name, returning ErrNotFound for
+	// names that are not in the table.
+	StatusID(ctx context.Context, name string) (int, error)
+	Create(ctx context.Context, o NewOrder) (int, error)
+	AddItem(ctx context.Context, orderID int, item NewOrderItem) error
+	// Purchased reports whether the user has a completed order containing
+	// the product.
+	Purchased(ctx context.Context, userID, productID int) (bool, error)
+}
+
+// CompletedOrderStatuses are the order_status names of orders that reached
+// the customer.
+var CompletedOrderStatuses = []string{"completed", "finish_order"}
+
+// orderStatusJoin resolves orders.status_id to its order_status row as "os".
+const orderStatusJoin = " LEFT JOIN order_status os ON o.status_id = os.id"
+
+// orderSummaryColumns are the columns scanOrderSummary reads.
+const orderSummaryColumns = `o.id, o.order_number, o.user_id, COALESCE(os.name, ''), o.subtotal, o.total,
+	o.customer_name, o.delivery_address, o.created_at`
+
+func scanOrderSummary(row interface{ Scan(...any) error }) (models.OrderSummaryV2, error) {
+	var o models.OrderSummaryV2
+	err := row.Scan(&o.ID, &o.OrderNumber, &o.UserID, &o.Status, &o.Subtotal, &o.Total, &o.CustomerName, &o.DeliveryAddress, &o.CreatedAt)
+	return o, err
+}
+
+type pgOrderRepository struct {
+	db DBTX
+}
+
+func (r *pgOrderRepository) List(ctx context.Context, filter OrderFilter) ([]models.OrderSummaryV2, int, error) {
+	where := []string{}
+	args := []any{}
+	argIdx := 1
+
+	if filter.Status != "" && filter.Status != "All" {
+		where = append(where, fmt.Sprintf("os.name = $%d", argIdx))
+		args = append(args, filter.Status)
+		argIdx++
+	}
+	if filter.Search != "" {
+		where = append(where, fmt.Sprintf("CAST(o.id AS TEXT) LIKE $%d", argIdx))
+		args = append(args, "%"+filter.Search+"%")
+		argIdx++
+	}
+
+	whereClause := ""
+	if len(where) > 0 {
+		whereClause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM orders o"+orderStatusJoin+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	if filter.After != nil {
+		if whereClause == "" {
+			whereClause = " WHERE "
+		} else {
+			whereClause += " AND "
+		}
+		whereClause += keyset("o.created_at", "o.id", argIdx)
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		argIdx += 2
+	}
+
+	query := "SELECT " + orderSummaryColumns + " FROM orders o" +
+		orderStatusJoin + whereClause +
+		fmt.Sprintf(" ORDER BY o.created_at DESC, o.id DESC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	orders := []models.OrderSummaryV2{}
+	for rows.Next() {
+		o, err := scanOrderSummary(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, total, rows.Err()
+}
+
+func (r *pgOrderRepository) Get(ctx context.Context, id int) (models.OrderSummaryV2, error) {
+	o, err := scanOrderSummary(r.db.QueryRow(ctx,
+		"SELECT "+orderSummaryColumns+" FROM orders o"+orderStatusJoin+" WHERE o.id = $1", id))
+	if err != nil {
+		return models.OrderSummaryV2{}, notFound(err)
+	}
+	return o, nil
+}
+
+func (r *pgOrderRepository) StatusName(ctx context.Context, id int) (string, error) {
+	var status string
+	err := r.db.QueryRow(ctx,
+		`SELECT COALESCE(os.name, '') FROM orders o
+		 LEFT JOIN order_status os ON o.status_id = os.id
+		 WHERE o.id=$1
+		 FOR UPDATE OF o`, id).Scan(&status)
+	if err != nil {
+		return "", notFound(err)
+	}
+	return status, nil
+}
+
+func (r *pgOrderRepository) UpdateStatus(ctx context.Context, id int, statusID int) error {
+	tag, err := r.db.Exec(ctx, "UPDATE orders SET status_id=$1, updated_at=$2 WHERE id=$3", statusID, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgOrderRepository) Delete(ctx context.Context, id int) error {
+	if _, err := r.db.Exec(ctx, "DELETE FROM order_items WHERE order_id=$1", id); err != nil {
+		return err
+	}
+	tag, err := r.db.Exec(ctx, "DELETE FROM orders WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgOrderRepository) History(ctx context.Context, filter HistoryFilter) ([]models.HistoryOrderV2, int, error) {
+	where := []string{"o.user_id = $1"}
+	args := []any{filter.UserID}
+	argIdx := 2
+
+	if filter.Status != "" {
+		where = append(where, fmt.Sprintf("os.name = $%d", argIdx))
+		args = append(args, filter.Status)
+		argIdx++
+	}
+	if filter.From != "" {
+		where = append(where, fmt.Sprintf("DATE(o.order_date) >= $%d", argIdx))
+		args = append(args, filter.From)
+		argIdx++
+	}
+	if filter.To != "" {
+		where = append(where, fmt.Sprintf("DATE(o.order_date) <= $%d", argIdx))
+		args = append(args, filter.To)
+		argIdx++
+	}
+	if !filter.Month.IsZero() {
+		year, month, _ := filter.Month.Date()
+		firstDay := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		lastDay := firstDay.AddDate(0, 1, -1)
+
+		where = append(where,
+			fmt.Sprintf("DATE(o.order_date) >= $%d AND DATE(o.order_date) <= $%d", argIdx, argIdx+1))
+		args = append(args, firstDay.Format("2006-01-02"), lastDay.Format("2006-01-02"))
+		argIdx += 2
+	}
+
+	whereClause := strings.Join(where, " AND ")
+
+	var total int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT o.id)
+		FROM orders o
+		JOIN order_status os ON o.status_id = os.id
+		WHERE `+whereClause, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if filter.After != nil {
+		whereClause += " AND " + keyset("o.order_date", "o.id", argIdx)
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		argIdx += 2
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			o.id,
+			o.order_number,
+			o.order_date,
+			o.total,
+			os.name as status,
+			os.display_name as status_display,
+			COALESCE(
+				(
+					SELECT json_agg(p.image_url ORDER BY oi.id)
+					FROM order_items oi
+					JOIN products p ON oi.product_id = p.id
+					WHERE oi.order_id = o.id
+					LIMIT 4
+				),
+				'[]'::json
+			) as product_images,
+			(
+				SELECT COUNT(*)
+				FROM order_items oi
+				WHERE oi.order_id = o.id
+			) as total_items
+		FROM orders o
+		JOIN order_status os ON o.status_id = os.id
+		WHERE %s
+		ORDER BY o.order_date DESC, o.id DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argIdx, argIdx+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	orders := []models.HistoryOrderV2{}
+	for rows.Next() {
+		var (
+			o             models.HistoryOrderV2
+			orderDate     time.Time
+			productImages []byte
+		)
+		err := rows.Scan(&o.ID, &o.Invoice, &orderDate, &o.Total, &o.Status, &o.StatusDisplay,
+			&productImages, &o.TotalItems)
+		if err != nil {
+			continue
+		}
+
+		var images []*string
+		_ = json.Unmarshal(productImages, &images)
+		for _, img := range images {
+			if img != nil && *img != "" {
+				o.ProductImages = append(o.ProductImages, *img)
+			}
+		}
+
+		o.Date = orderDate.Format("02 January 2006")
+		o.OrderDate = orderDate
+		orders = append(orders, o)
+	}
+	return orders, total, rows.Err()
+}
+
+func (r *pgOrderRepository) Detail(ctx context.Context, orderID, userID int) (models.OrderDetailV2, error) {
+	var d models.OrderDetailV2
+	var orderDate time.Time
+
+	err := r.db.QueryRow(ctx,
+		`SELECT
+			o.order_number,
+			o.order_date,
+			o.delivery_address,
+			COALESCE(dm.name, 'Dine In') as delivery_method,
+			COALESCE(pm.name, 'Cash') as payment_method,
+			o.subtotal,
+			o.delivery_fee,
+			o.tax_amount,
+			o.total,
+			os.name as status_name,
+			os.display_name as status_display,
+			COALESCE(up.phone, '') as phone,
+			COALESCE(up.full_name, u.email) as full_name
+		FROM orders o
+		JOIN users u ON o.user_id = u.id
+		LEFT JOIN user_profiles up ON u.id = up.user_id
+		LEFT JOIN delivery_methods dm ON o.delivery_method_id = dm.id
+		LEFT JOIN payment_methods pm ON o.payment_method_id = pm.id
+		JOIN order_status os ON o.status_id = os.id
+		WHERE o.id = $1 AND o.user_id = $2`,
+		orderID, userID).Scan(
+		&d.OrderNumber, &orderDate, &d.Address, &d.DeliveryMethod,
+		&d.PaymentMethod, &d.Subtotal, &d.DeliveryFee, &d.TaxAmount, &d.Total,
+		&d.Status, &d.StatusDisplay, &d.Phone, &d.FullName)
+	if err != nil {
+		return models.OrderDetailV2{}, notFound(err)
+	}
+	d.OrderDate = orderDate.Format("02 January 2006 at 03:04 PM")
+
+	rows, err := r.db.Query(ctx,
+		`SELECT
+			oi.product_id,
+			p.name,
+			oi.quantity,
+			COALESCE(ps.name,'') as size_name,
+			COALESCE(pt.name,'') as temperature_name,
+			oi.unit_price,
+			oi.discount_amount,
+			COALESCE(oi.promotion_name, ''),
+			COALESCE(p.image_url, '') as image_url,
+			COALESCE(oi.is_flash_sale, false) as is_flash_sale,
+			COALESCE(dm.name, 'Dine In') as delivery_method
+		FROM order_items oi
+		JOIN products p ON oi.product_id = p.id
+		LEFT JOIN product_sizes ps ON oi.size_id = ps.id
+		LEFT JOIN product_temperatures pt ON oi.temperature_id = pt.id
+		LEFT JOIN orders o ON oi.order_id = o.id
+		LEFT JOIN delivery_methods dm ON o.delivery_method_id = dm.id
+		WHERE oi.order_id = $1`, orderID)
+	if err != nil {
+		return models.OrderDetailV2{}, err
+	}
+	defer rows.Close()
+
+	d.Items = []models.OrderItemV2{}
+	for rows.Next() {
+		var item models.OrderItemV2
+		err := rows.Scan(&item.ProductID, &item.Name, &item.Quantity, &item.Size, &item.Temperature,
+			&item.UnitPrice, &item.Discount, &item.Promotion, &item.ImageURL, &item.IsFlashSale, &item.DeliveryMethod)
+		if err != nil {
+			return models.OrderDetailV2{}, err
+		}
+		item.TotalPrice = item.UnitPrice*item.Quantity - item.Discount
+		d.Discount += item.Discount
+		d.Items = append(d.Items, item)
+	}
+	return d, rows.Err()
+}
+
+func (r *pgOrderRepository) Items(ctx context.Context, orderID int) ([]NewOrderItem, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT product_id, quantity, size_id, temperature_id, unit_price, COALESCE(is_flash_sale, false), flash_sale_id,
+			discount_amount, promotion_rule_id, COALESCE(promotion_name, '')
+		 FROM order_items WHERE order_id=$1 ORDER BY id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []NewOrderItem{}
+	for rows.Next() {
+		var item NewOrderItem
+		if err := rows.Scan(&item.ProductID, &item.Quantity, &item.SizeID, &item.TemperatureID,
+			&item.UnitPrice, &item.IsFlashSale, &item.FlashSaleID, &item.DiscountAmount, &item.PromotionRuleID,
+			&item.PromotionName); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *pgOrderRepository) StatusID(ctx context.Context, name string) (int, error) {
+	var id int
+	err := r.db.QueryRow(ctx, "SELECT id FROM order_status WHERE name=$1 LIMIT 1", name).Scan(&id)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	return id, nil
+}
+
+func (r *pgOrderRepository) Create(ctx context.Context, o NewOrder) (int, error) {
+	now := time.Now()
+
+	var id int
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO orders (order_number, user_id, status_id, customer_name, delivery_address, delivery_method_id,
+			subtotal, delivery_fee, tax_amount, total, payment_method_id, order_date, created_at, updated_at)
+		 VALUES ($1,$2,$3,$4,$5,1,$6,$7,0,$8,$9,$10,$11,$12) RETURNING id`,
+		o.OrderNumber, o.UserID, o.StatusID, o.CustomerName, o.DeliveryAddress, o.Subtotal, o.DeliveryFee, o.Total,
+		o.PaymentMethodID, o.OrderDate, now, now).Scan(&id)
+	return id, err
+}
+
+func (r *pgOrderRepository) AddItem(ctx context.Context, orderID int, item NewOrderItem) error {
+	var sizeID, temperatureID any
+	if item.SizeID != nil && *item.SizeID > 0 {
+		sizeID = *item.SizeID
+	}
+	if item.TemperatureID != nil && *item.TemperatureID > 0 {
+		temperatureID = *item.TemperatureID
+	}
+
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO order_items (order_id, product_id, quantity, size_id, temperature_id, unit_price, is_flash_sale,
+			flash_sale_id, discount_amount, promotion_rule_id, promotion_name, created_at)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,NULLIF($11,''),$12)`,
+		orderID, item.ProductID, item.Quantity, sizeID, temperatureID, item.UnitPrice, item.IsFlashSale,
+		item.FlashSaleID, item.DiscountAmount, item.PromotionRuleID, item.PromotionName, time.Now())
+	return err
+}
+
+func (r *pgOrderRepository) Purchased(ctx context.Context, userID, productID int) (bool, error) {
+	var purchased bool
+	err := r.db.QueryRow(ctx,
+		`SELECT EXISTS (
+		   SELECT 1 FROM orders o
+		   JOIN order_items oi ON oi.order_id = o.id`+orderStatusJoin+`
+		   WHERE o.user_id = $1 AND oi.product_id = $2 AND os.name = ANY($3))`,
+		userID, productID, CompletedOrderStatuses).Scan(&purchased)
+	return purchased, err
+}
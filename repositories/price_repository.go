@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"context"
+	"fmt"
+	"time"
+)
+
+// PriceFilter selects one page of a product's price history, newest first.
+type PriceFilter struct {
+	pagination.Params
+	ProductID int
+}
+
+type PriceRepository interface {
+	// Record inserts p, applied or scheduled, and fills in its ID and
+	// CreatedAt.
+	Record(ctx context.Context, p *models.ProductPrice) error
+	// History returns one page of a product's applied prices, latest first,
+	// and the total number applied.
+	History(ctx context.Context, filter PriceFilter) ([]models.ProductPrice, int, error)
+	// At returns the price the product had at t: the last one applied at or
+	// before it. It returns ErrNotFound when the history starts after t.
+	At(ctx context.Context, productID int, t time.Time) (models.ProductPrice, error)
+	// Scheduled returns the pending changes of a product, or of every
+	// product when productID is 0, soonest first.
+	Scheduled(ctx context.Context, productID int) ([]models.ProductPrice, error)
+	Get(ctx context.Context, id int) (models.ProductPrice, error)
+	// Delete cancels a pending change; it returns ErrNotFound when the
+	// change does not exist or has been applied.
+	Delete(ctx context.Context, id int) error
+	// Due returns the pending changes whose effective time is not after now,
+	// soonest first, and locks them until the surrounding transaction ends.
+	// Changes another transaction has locked are skipped, and so are those of
+	// archived products: they stay pending until the product is restored.
+	Due(ctx context.Context, now time.Time) ([]models.ProductPrice, error)
+	// MarkApplied saves the PreviousPrice and AppliedAt of a pending change.
+	MarkApplied(ctx context.Context, p models.ProductPrice) error
+}
+
+const priceColumns = `pp.id, pp.product_id, pp.price, pp.previous_price, pp.effective_at, pp.applied_at, pp.reason,
+	pp.actor_id, COALESCE(u.email, ''), pp.created_at`
+
+type pgPriceRepository struct {
+	db DBTX
+}
+
+func (r *pgPriceRepository) Record(ctx context.Context, p *models.ProductPrice) error {
+	return r.db.QueryRow(ctx,
+		`INSERT INTO product_prices (product_id, price, previous_price, effective_at, applied_at, reason, actor_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		 RETURNING id, created_at`,
+		p.ProductID, p.Price, p.PreviousPrice, p.EffectiveAt, p.AppliedAt, p.Reason, p.ActorID,
+	).Scan(&p.ID, &p.CreatedAt)
+}
+
+func (r *pgPriceRepository) History(ctx context.Context, filter PriceFilter) ([]models.ProductPrice, int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx,
+		"SELECT COUNT(*) FROM product_prices WHERE product_id=$1 AND applied_at IS NOT NULL", filter.ProductID,
+	).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	where := "pp.product_id = $1 AND pp.applied_at IS NOT NULL"
+	args := []any{filter.ProductID}
+	if filter.After != nil {
+		where += " AND " + keyset("pp.applied_at", "pp.id", 2)
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	prices, err := r.query(ctx, "WHERE "+where+
+		fmt.Sprintf(" ORDER BY pp.applied_at DESC, pp.id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args)),
+		args...)
+	return prices, total, err
+}
+
+func (r *pgPriceRepository) At(ctx context.Context, productID int, t time.Time) (models.ProductPrice, error) {
+	prices, err := r.query(ctx,
+		`WHERE pp.product_id = $1 AND pp.applied_at <= $2
+		 ORDER BY pp.applied_at DESC, pp.id DESC LIMIT 1`, productID, t)
+	if err != nil {
+		return models.ProductPrice{}, err
+	}
+	if len(prices) == 0 {
+		return models.ProductPrice{}, ErrNotFound
+	}
+	return prices[0], nil
+}
+
+func (r *pgPriceRepository) Scheduled(ctx context.Context, productID int) ([]models.ProductPrice, error) {
+	return r.query(ctx,
+		`WHERE pp.applied_at IS NULL AND ($1 = 0 OR pp.product_id = $1)
+		 ORDER BY pp.effective_at, pp.id`, productID)
+}
+
+func (r *pgPriceRepository) Get(ctx context.Context, id int) (models.ProductPrice, error) {
+	prices, err := r.query(ctx, "WHERE pp.id = $1", id)
+	if err != nil {
+		return models.ProductPrice{}, err
+	}
+	if len(prices) == 0 {
+		return models.ProductPrice{}, ErrNotFound
+	}
+	return prices[0], nil
+}
+
+func (r *pgPriceRepository) Delete(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM product_prices WHERE id=$1 AND applied_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgPriceRepository) Due(ctx context.Context, now time.Time) ([]models.ProductPrice, error) {
+	return r.query(ctx,
+		`WHERE pp.applied_at IS NULL AND pp.effective_at <= $1
+		   AND EXISTS (SELECT 1 FROM products p WHERE p.id = pp.product_id AND p.deleted_at IS NULL)
+		 ORDER BY pp.effective_at, pp.id
+		 FOR UPDATE OF pp SKIP LOCKED`, now)
+}
+
+func (r *pgPriceRepository) MarkApplied(ctx context.Context, p models.ProductPrice) error {
+	tag, err := r.db.Exec(ctx,
+		"UPDATE product_prices SET previous_price=$1, applied_at=$2 WHERE id=$3 AND applied_at IS NULL",
+		p.PreviousPrice, p.AppliedAt, p.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// query selects the prices matching the rest of the statement, which starts
+// with its WHERE clause.
+func (r *pgPriceRepository) query(ctx context.Context, rest string, args ...any) ([]models.ProductPrice, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+priceColumns+`
+		 FROM product_prices pp
+		 LEFT JOIN users u ON pp.actor_id = u.id
+		 `+rest, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []models.ProductPrice{}
+	for rows.Next() {
+		var p models.ProductPrice
+		if err := rows.Scan(&p.ID, &p.ProductID, &p.Price, &p.PreviousPrice, &p.EffectiveAt, &p.AppliedAt, &p.Reason,
+			&p.ActorID, &p.ActorEmail, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
@@ -0,0 +1,843 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/search"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ProductFilter narrows List to active, unarchived products matching every set field.
+// Search matches name, description and category (including translations) by
+// word prefix, and the name by substring or with typos; results are then
+// ordered by relevance. Locale selects the translation overlaid on name and
+// description; Limit 0 returns every match.
+type ProductFilter struct {
+	pagination.Params
+	Search     string
+	CategoryID int
+	MinPrice   int
+	MaxPrice   int
+	FlashSale  bool
+	Featured   bool
+	Buy1Get1   bool
+	InStock    bool
+	MinRating  float64
+	// Sort is one of ProductSorts. Empty means relevance when searching and
+	// newest first otherwise.
+	Sort   string
+	Locale string
+}
+
+// NewestFirst reports whether the filter lists products newest first, the
+// only order a (created_at, id) cursor can continue.
+func (f ProductFilter) NewestFirst() bool {
+	return f.Search == "" && (f.Sort == "" || f.Sort == SortNewest)
+}
+
+type ProductRepository interface {
+	// List returns one page of active products in the filter's order and the
+	// total number of matches. filter.After is ignored unless the filter is
+	// NewestFirst.
+	List(ctx context.Context, filter ProductFilter) ([]models.Product, int, error)
+	// Facets counts the products matching filter per category, price range
+	// and flag.
+	Facets(ctx context.Context, filter ProductFilter) (models.ProductFacets, error)
+	// GetActive returns an active, unarchived product with its translation
+	// applied.
+	GetActive(ctx context.Context, id int, locale string) (models.Product, error)
+	// Get returns the stored product regardless of state, archived or not,
+	// untranslated.
+	Get(ctx context.Context, id int) (models.Product, error)
+	// All returns every stored product, archived ones included, untranslated
+	// and in ID order.
+	All(ctx context.Context) ([]models.Product, error)
+	// SKUExists reports whether a product other than excludeID uses sku,
+	// ignoring case.
+	SKUExists(ctx context.Context, sku string, excludeID int) (bool, error)
+	Related(ctx context.Context, categoryID, excludeID, limit int, locale string) ([]models.Product, error)
+	// Images returns the product's gallery in display order.
+	Images(ctx context.Context, productID int) ([]models.ProductImage, error)
+	// Sizes, Temperatures and Variants return the active choices options
+	// can be built from.
+	Sizes(ctx context.Context) ([]models.ProductSizeV2, error)
+	Temperatures(ctx context.Context) ([]models.ProductTemperatureV2, error)
+	Variants(ctx context.Context) ([]models.ProductVariantV2, error)
+	// Options returns the option matrix of the given products, active or
+	// not, cheapest first.
+	Options(ctx context.Context, productIDs ...int) ([]models.ProductOption, error)
+
+	// Create inserts p and fills in its ID and timestamps.
+	Create(ctx context.Context, p *models.Product) error
+	// Update saves every writable field of p except the stock, which only
+	// moves through AdjustStock so that each change has a ledger entry.
+	Update(ctx context.Context, p models.Product) error
+	// Archive hides a product from the storefront by setting deleted_at; it
+	// returns ErrNotFound when the product does not exist or is already
+	// archived. Restore clears deleted_at again.
+	Archive(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) error
+	// Archived returns one page of archived products, most recently archived
+	// first, and the total number archived.
+	Archived(ctx context.Context, page pagination.Params) ([]models.Product, int, error)
+	// OrderCount returns how many order lines reference the product.
+	OrderCount(ctx context.Context, id int) (int, error)
+	// Delete removes the product row together with its images, reviews,
+	// details, promo links, cart lines, stock ledger and price history.
+	// Callers must check OrderCount first: order lines are history and are
+	// never deleted.
+	Delete(ctx context.Context, id int) error
+	// LockStock returns the product's stock and locks the row until the
+	// surrounding transaction ends.
+	LockStock(ctx context.Context, id int) (int, error)
+	// AdjustStock adds delta, which may be negative, to the product's stock
+	// and returns the new level. It returns ErrInsufficientStock, changing
+	// nothing, when the stock would drop below zero.
+	AdjustStock(ctx context.Context, id, delta int) (int, error)
+	// SetPrice sets the product's price and returns the price it replaced.
+	SetPrice(ctx context.Context, id, price int) (int, error)
+
+	// AddImage inserts a gallery image and fills in its ID and CreatedAt.
+	AddImage(ctx context.Context, img *models.ProductImage) error
+	// UpdateImage saves the URL, Cloudinary ID, primary flag and position of
+	// a gallery image. Positions are only checked for uniqueness at commit,
+	// so a transaction can swap them.
+	UpdateImage(ctx context.Context, img models.ProductImage) error
+	DeleteImage(ctx context.Context, id int) error
+
+	// CreateOption inserts o and fills in its ID and timestamps.
+	CreateOption(ctx context.Context, o *models.ProductOption) error
+	// UpdateOption saves the price adjustment and active flag of o.
+	UpdateOption(ctx context.Context, o models.ProductOption) error
+	DeleteOption(ctx context.Context, id int) error
+}
+
+const productColumns = `id, COALESCE(sku, ''), name, COALESCE(description, ''), category_id, price, stock, low_stock_threshold,
+	COALESCE(image_url, ''), COALESCE(cloudinary_id, ''),
+	COALESCE(is_flash_sale, false), COALESCE(is_featured, false),
+	COALESCE(is_buy1get1, false), is_active, average_rating::float8, review_count, created_at, updated_at, deleted_at`
+
+// qualifiedProductColumns is productColumns for queries that join other
+// tables.
+const qualifiedProductColumns = `products.id, COALESCE(products.sku, ''), products.name, COALESCE(products.description, ''),
+	products.category_id, products.price, products.stock, products.low_stock_threshold,
+	COALESCE(products.image_url, ''), COALESCE(products.cloudinary_id, ''),
+	COALESCE(products.is_flash_sale, false), COALESCE(products.is_featured, false),
+	COALESCE(products.is_buy1get1, false), products.is_active, products.average_rating::float8, products.review_count,
+	products.created_at, products.updated_at, products.deleted_at`
+
+type pgProductRepository struct {
+	db DBTX
+}
+
+// scanProduct scans productColumns followed by any extra columns into extra.
+func scanProduct(row interface{ Scan(...any) error }, extra ...any) (models.Product, error) {
+	var p models.Product
+	dest := append([]any{&p.ID, &p.SKU, &p.Name, &p.Description, &p.CategoryID,
+		&p.Price, &p.Stock, &p.LowStockThreshold, &p.ImageURL, &p.CloudinaryID,
+		&p.IsFlashSale, &p.IsFeatured, &p.IsBuy1Get1,
+		&p.IsActive, &p.AverageRating, &p.ReviewCount, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt}, extra...)
+	err := row.Scan(dest...)
+	return p, err
+}
+
+func (r *pgProductRepository) queryProducts(ctx context.Context, query string, args ...any) ([]models.Product, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// Product list orders accepted in ProductFilter.Sort.
+const (
+	SortNewest      = "newest"
+	SortPriceAsc    = "price_asc"
+	SortPriceDesc   = "price_desc"
+	SortBestSelling = "best_selling"
+	SortTopRated    = "top_rated"
+	SortPopular     = "popular"
+)
+
+// ProductSorts lists the valid ProductFilter.Sort values.
+var ProductSorts = []string{SortNewest, SortPriceAsc, SortPriceDesc, SortBestSelling, SortTopRated, SortPopular}
+
+// PriceBuckets are the upper bounds (exclusive) of the price facet ranges;
+// a final range holds everything from the last bound up.
+var PriceBuckets = []int{15000, 25000, 35000}
+
+// productSalesJoin adds sales.sold, the quantity on orders not cancelled.
+const productSalesJoin = ` LEFT JOIN (
+	SELECT oi.product_id, SUM(oi.quantity) AS sold
+	FROM order_items oi
+	JOIN orders o ON o.id = oi.order_id
+	LEFT JOIN order_status os ON os.id = o.status_id
+	WHERE os.name IS DISTINCT FROM 'cancelled'
+	GROUP BY oi.product_id
+) sales ON sales.product_id = products.id`
+
+// PopularityWindow is how far back product views count towards popularity,
+// and PopularityHalfLife how long a view takes to lose half its weight.
+const (
+	PopularityWindow   = 30 * 24 * time.Hour
+	PopularityHalfLife = 7 * 24 * time.Hour
+)
+
+// productPopularityJoin adds popularity.score, the product's daily viewers
+// over the PopularityWindow, as last counted by ViewRepository.CountDaily,
+// weighted by age.
+var productPopularityJoin = fmt.Sprintf(` LEFT JOIN (
+	SELECT product_id, SUM(viewers * POWER(0.5, EXTRACT(EPOCH FROM NOW() - day::timestamp) / %d)) AS score
+	FROM product_view_daily
+	WHERE day > NOW() - INTERVAL '%d seconds'
+	GROUP BY product_id
+) popularity ON popularity.product_id = products.id`, int(PopularityHalfLife.Seconds()), int(PopularityWindow.Seconds()))
+
+var productSortOrders = map[string]string{
+	SortPriceAsc:    "products.price ASC",
+	SortPriceDesc:   "products.price DESC",
+	SortBestSelling: "COALESCE(sales.sold, 0) DESC",
+	SortTopRated:    "products.average_rating DESC, products.review_count DESC",
+	SortPopular:     "COALESCE(popularity.score, 0) DESC",
+}
+
+// productQuery is the FROM and WHERE part shared by List and Facets.
+type productQuery struct {
+	joins  string
+	where  string
+	args   []any
+	argIdx int
+	// rank orders search results; it is "0" when not searching.
+	rank string
+}
+
+func (q *productQuery) arg(v any) string {
+	q.args = append(q.args, v)
+	q.argIdx++
+	return fmt.Sprintf("$%d", q.argIdx-1)
+}
+
+func buildProductQuery(filter ProductFilter) *productQuery {
+	q := &productQuery{argIdx: 1, rank: "0"}
+	where := []string{"products.is_active = TRUE", "products.deleted_at IS NULL"}
+
+	if filter.Search != "" {
+		// Substring and trigram word-similarity matches on the name are
+		// served by idx_products_name_trgm, prefix matches on the whole
+		// document by idx_products_search_vector.
+		like, raw := q.arg("%"+likeEscaper.Replace(filter.Search)+"%"), q.arg(filter.Search)
+		conditions := []string{"products.name ILIKE " + like, raw + " <% products.name"}
+		q.rank = "word_similarity(" + raw + ", products.name)"
+
+		if tsquery := search.PrefixQuery(search.Terms(filter.Search)); tsquery != "" {
+			ts := "to_tsquery('simple', " + q.arg(tsquery) + ")"
+			conditions = append(conditions, "products.search_vector @@ "+ts)
+			q.rank = "ts_rank_cd(products.search_vector, " + ts + ") + " + q.rank
+		}
+		where = append(where, "("+strings.Join(conditions, " OR ")+")")
+	}
+	if filter.CategoryID > 0 {
+		where = append(where, "products.category_id = "+q.arg(filter.CategoryID))
+	}
+	if filter.MinPrice > 0 {
+		where = append(where, "products.price >= "+q.arg(filter.MinPrice))
+	}
+	if filter.MaxPrice > 0 {
+		where = append(where, "products.price <= "+q.arg(filter.MaxPrice))
+	}
+	if filter.FlashSale {
+		where = append(where, productOnFlashSale)
+	}
+	if filter.Featured {
+		where = append(where, "products.is_featured = TRUE")
+	}
+	if filter.Buy1Get1 {
+		where = append(where, "products.is_buy1get1 = TRUE")
+	}
+	if filter.InStock {
+		where = append(where, "products.stock > 0")
+	}
+	if filter.MinRating > 0 {
+		where = append(where, "products.average_rating >= "+q.arg(filter.MinRating))
+	}
+
+	q.where = strings.Join(where, " AND ")
+	return q
+}
+
+// productOnFlashSale matches the products flagged as flash sale and those in a
+// flash sale running now.
+const productOnFlashSale = `(products.is_flash_sale = TRUE OR EXISTS (
+	SELECT 1 FROM flash_sale_items fsi
+	JOIN flash_sales fs ON fs.id = fsi.flash_sale_id
+	WHERE fsi.product_id = products.id AND fs.is_active AND fs.starts_at <= NOW() AND fs.ends_at > NOW()))`
+
+func (r *pgProductRepository) List(ctx context.Context, filter ProductFilter) ([]models.Product, int, error) {
+	q := buildProductQuery(filter)
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM products"+q.joins+" WHERE "+q.where, q.args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	order := []string{}
+	switch filter.Sort {
+	case SortBestSelling:
+		q.joins += productSalesJoin
+	case SortPopular:
+		q.joins += productPopularityJoin
+	}
+	if o, ok := productSortOrders[filter.Sort]; ok {
+		order = append(order, o)
+	}
+	order = append(order, "relevance DESC", "products.created_at DESC", "products.id DESC")
+
+	// A (created_at, id) cursor only continues the newest-first order; the
+	// service rejects it for any other.
+	where := q.where
+	if filter.After != nil && filter.NewestFirst() {
+		where += " AND " + keyset("products.created_at", "products.id", q.argIdx)
+		q.args = append(q.args, filter.After.CreatedAt, filter.After.ID)
+		q.argIdx += 2
+	}
+
+	query := "SELECT " + qualifiedProductColumns + ", (" + q.rank + ")::float8 AS relevance FROM products" + q.joins +
+		" WHERE " + where + " ORDER BY " + strings.Join(order, ", ")
+	if filter.Limit > 0 {
+		query += " LIMIT " + q.arg(filter.Limit) + " OFFSET " + q.arg(filter.Offset)
+	}
+
+	rows, err := r.db.Query(ctx, query, q.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		var relevance float64
+		p, err := scanProduct(rows, &relevance)
+		if err != nil {
+			return nil, 0, err
+		}
+		p.Relevance = relevance
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if err := r.translate(ctx, filter.Locale, products); err != nil {
+		return nil, 0, err
+	}
+	return products, total, nil
+}
+
+func (r *pgProductRepository) Facets(ctx context.Context, filter ProductFilter) (models.ProductFacets, error) {
+	q := buildProductQuery(filter)
+	facets := models.ProductFacets{Categories: []models.CategoryFacet{}, PriceRanges: []models.PriceFacet{}}
+
+	locale := fmt.Sprintf("$%d", q.argIdx)
+	rows, err := r.db.Query(ctx,
+		`SELECT c.id, COALESCE(NULLIF(ct.name, ''), c.name) AS label, COUNT(*)
+		 FROM products
+		 JOIN categories c ON c.id = products.category_id
+		 LEFT JOIN category_translations ct ON ct.category_id = c.id AND ct.locale = `+locale+
+			q.joins+` WHERE `+q.where+`
+		 GROUP BY c.id, label
+		 ORDER BY label`,
+		append(q.args, filter.Locale)...)
+	if err != nil {
+		return facets, err
+	}
+	for rows.Next() {
+		var f models.CategoryFacet
+		if err := rows.Scan(&f.ID, &f.Name, &f.Count); err != nil {
+			rows.Close()
+			return facets, err
+		}
+		facets.Categories = append(facets.Categories, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return facets, err
+	}
+
+	bucket := "CASE"
+	for i, bound := range PriceBuckets {
+		bucket += fmt.Sprintf(" WHEN products.price < %d THEN %d", bound, i)
+	}
+	bucket += fmt.Sprintf(" ELSE %d END", len(PriceBuckets))
+
+	counts := make([]int, len(PriceBuckets)+1)
+	rows, err = r.db.Query(ctx,
+		"SELECT "+bucket+" AS bucket, COUNT(*) FROM products"+q.joins+" WHERE "+q.where+" GROUP BY bucket",
+		q.args...)
+	if err != nil {
+		return facets, err
+	}
+	for rows.Next() {
+		var i, n int
+		if err := rows.Scan(&i, &n); err != nil {
+			rows.Close()
+			return facets, err
+		}
+		counts[i] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return facets, err
+	}
+	facets.PriceRanges = priceFacets(counts)
+
+	err = r.db.QueryRow(ctx,
+		`SELECT COUNT(*) FILTER (WHERE `+productOnFlashSale+`),
+		        COUNT(*) FILTER (WHERE products.is_featured),
+		        COUNT(*) FILTER (WHERE products.is_buy1get1),
+		        COUNT(*) FILTER (WHERE products.stock > 0)
+		 FROM products`+q.joins+` WHERE `+q.where,
+		q.args...).Scan(&facets.FlashSale, &facets.Featured, &facets.Buy1Get1, &facets.InStock)
+	return facets, err
+}
+
+// priceFacets pairs the per-bucket counts with the PriceBuckets bounds.
+func priceFacets(counts []int) []models.PriceFacet {
+	ranges := make([]models.PriceFacet, 0, len(counts))
+	lower := 0
+	for i, n := range counts {
+		f := models.PriceFacet{Min: lower, Count: n}
+		if i < len(PriceBuckets) {
+			f.Max = PriceBuckets[i]
+			lower = PriceBuckets[i]
+		}
+		ranges = append(ranges, f)
+	}
+	return ranges
+}
+
+func (r *pgProductRepository) GetActive(ctx context.Context, id int, locale string) (models.Product, error) {
+	p, err := scanProduct(r.db.QueryRow(ctx,
+		"SELECT "+productColumns+" FROM products WHERE id = $1 AND is_active = TRUE AND deleted_at IS NULL", id))
+	if err != nil {
+		return models.Product{}, notFound(err)
+	}
+
+	single := []models.Product{p}
+	if err := r.translate(ctx, locale, single); err != nil {
+		return models.Product{}, err
+	}
+	return single[0], nil
+}
+
+func (r *pgProductRepository) Get(ctx context.Context, id int) (models.Product, error) {
+	p, err := scanProduct(r.db.QueryRow(ctx, "SELECT "+productColumns+" FROM products WHERE id = $1", id))
+	if err != nil {
+		return models.Product{}, notFound(err)
+	}
+	return p, nil
+}
+
+func (r *pgProductRepository) All(ctx context.Context) ([]models.Product, error) {
+	return r.queryProducts(ctx, "SELECT "+productColumns+" FROM products ORDER BY id")
+}
+
+func (r *pgProductRepository) SKUExists(ctx context.Context, sku string, excludeID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM products WHERE LOWER(sku)=LOWER($1) AND id!=$2)", sku, excludeID).Scan(&exists)
+	return exists, err
+}
+
+func (r *pgProductRepository) Related(ctx context.Context, categoryID, excludeID, limit int, locale string) ([]models.Product, error) {
+	products, err := r.queryProducts(ctx,
+		"SELECT "+productColumns+` FROM products
+		 WHERE category_id = $1 AND id != $2 AND is_active = TRUE AND deleted_at IS NULL
+		 LIMIT $3`,
+		categoryID, excludeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return products, r.translate(ctx, locale, products)
+}
+
+func (r *pgProductRepository) Images(ctx context.Context, productID int) ([]models.ProductImage, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, product_id, image_url, COALESCE(cloudinary_id, ''), is_primary, display_order, created_at
+		 FROM product_images WHERE product_id=$1 ORDER BY display_order, id`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []models.ProductImage{}
+	for rows.Next() {
+		var img models.ProductImage
+		if err := rows.Scan(&img.ID, &img.ProductID, &img.URL, &img.CloudinaryID, &img.IsPrimary, &img.Order, &img.CreatedAt); err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+func (r *pgProductRepository) Sizes(ctx context.Context) ([]models.ProductSizeV2, error) {
+	rows, err := r.db.Query(ctx,
+		"SELECT id, name, price_adjustment FROM product_sizes WHERE is_active=true ORDER BY price_adjustment")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sizes := []models.ProductSizeV2{}
+	for rows.Next() {
+		var size models.ProductSizeV2
+		if err := rows.Scan(&size.ID, &size.Name, &size.PriceAdjustment); err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, rows.Err()
+}
+
+func (r *pgProductRepository) Temperatures(ctx context.Context) ([]models.ProductTemperatureV2, error) {
+	rows, err := r.db.Query(ctx, "SELECT id, name FROM product_temperatures WHERE is_active=true")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	temps := []models.ProductTemperatureV2{}
+	for rows.Next() {
+		var temp models.ProductTemperatureV2
+		if err := rows.Scan(&temp.ID, &temp.Name); err != nil {
+			return nil, err
+		}
+		temps = append(temps, temp)
+	}
+	return temps, rows.Err()
+}
+
+func (r *pgProductRepository) Variants(ctx context.Context) ([]models.ProductVariantV2, error) {
+	rows, err := r.db.Query(ctx,
+		"SELECT id, name, COALESCE(description, '') FROM product_variants WHERE is_active=true ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []models.ProductVariantV2{}
+	for rows.Next() {
+		var v models.ProductVariantV2
+		if err := rows.Scan(&v.ID, &v.Name, &v.Description); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+func (r *pgProductRepository) Options(ctx context.Context, productIDs ...int) ([]models.ProductOption, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT pd.id, pd.product_id,
+			pd.size_id, COALESCE(ps.name, ''),
+			pd.temperature_id, COALESCE(pt.name, ''),
+			pd.variant_id, COALESCE(pv.name, ''),
+			pd.price_adjustment, pd.is_active, pd.created_at, pd.updated_at
+		 FROM product_details pd
+		 LEFT JOIN product_sizes ps ON ps.id = pd.size_id
+		 LEFT JOIN product_temperatures pt ON pt.id = pd.temperature_id
+		 LEFT JOIN product_variants pv ON pv.id = pd.variant_id
+		 WHERE pd.product_id = ANY($1)
+		 ORDER BY pd.price_adjustment, pd.id`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := []models.ProductOption{}
+	for rows.Next() {
+		var o models.ProductOption
+		if err := rows.Scan(&o.ID, &o.ProductID, &o.SizeID, &o.Size, &o.TemperatureID, &o.Temperature,
+			&o.VariantID, &o.Variant, &o.PriceAdjustment, &o.IsActive, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		options = append(options, o)
+	}
+	return options, rows.Err()
+}
+
+func (r *pgProductRepository) Create(ctx context.Context, p *models.Product) error {
+	return r.db.QueryRow(ctx,
+		`INSERT INTO products
+		 (name, description, category_id, price, stock, low_stock_threshold, image_url, cloudinary_id,
+		  is_flash_sale, is_featured, is_buy1get1, is_active, sku, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), NOW(), NOW())
+		 RETURNING id, created_at, updated_at`,
+		p.Name, p.Description, p.CategoryID, p.Price, p.Stock, p.LowStockThreshold, p.ImageURL, p.CloudinaryID,
+		p.IsFlashSale, p.IsFeatured, p.IsBuy1Get1, p.IsActive, p.SKU,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+}
+
+func (r *pgProductRepository) Update(ctx context.Context, p models.Product) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE products
+		 SET name=$1, description=$2, category_id=$3, price=$4, low_stock_threshold=$5,
+		     image_url=$6, cloudinary_id=$7, is_flash_sale=$8, is_featured=$9,
+		     is_buy1get1=$10, is_active=$11, updated_at=$12, sku=NULLIF($13, '')
+		 WHERE id=$14`,
+		p.Name, p.Description, p.CategoryID, p.Price, p.LowStockThreshold, p.ImageURL, p.CloudinaryID,
+		p.IsFlashSale, p.IsFeatured, p.IsBuy1Get1, p.IsActive, p.UpdatedAt, p.SKU, p.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgProductRepository) Archive(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx,
+		"UPDATE products SET deleted_at=NOW(), updated_at=NOW() WHERE id=$1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgProductRepository) Restore(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx,
+		"UPDATE products SET deleted_at=NULL, updated_at=NOW() WHERE id=$1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgProductRepository) Archived(ctx context.Context, page pagination.Params) ([]models.Product, int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM products WHERE deleted_at IS NOT NULL").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	where := "deleted_at IS NOT NULL"
+	args := []any{}
+	if page.After != nil {
+		where += " AND " + keyset("deleted_at", "id", 1)
+		args = append(args, page.After.CreatedAt, page.After.ID)
+	}
+	args = append(args, page.Limit, page.Offset)
+
+	products, err := r.queryProducts(ctx, "SELECT "+productColumns+" FROM products WHERE "+where+
+		fmt.Sprintf(" ORDER BY deleted_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return products, total, nil
+}
+
+func (r *pgProductRepository) OrderCount(ctx context.Context, id int) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM order_items WHERE product_id=$1", id).Scan(&n)
+	return n, err
+}
+
+// productDependents are deleted before the product row itself, in order;
+// translations, stock movements, prices, recommendations, bought-together
+// pairs and views go with it through ON DELETE CASCADE.
+var productDependents = []string{
+	"DELETE FROM product_details WHERE product_id=$1",
+	"DELETE FROM product_images WHERE product_id=$1",
+	"DELETE FROM product_reviews WHERE product_id=$1",
+	"DELETE FROM promo_products WHERE product_id=$1",
+	"DELETE FROM cart_items WHERE product_id=$1",
+}
+
+func (r *pgProductRepository) Delete(ctx context.Context, id int) error {
+	for _, query := range productDependents {
+		if _, err := r.db.Exec(ctx, query, id); err != nil {
+			return err
+		}
+	}
+	tag, err := r.db.Exec(ctx, "DELETE FROM products WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgProductRepository) LockStock(ctx context.Context, id int) (int, error) {
+	var stock int
+	err := r.db.QueryRow(ctx, "SELECT stock FROM products WHERE id=$1 FOR UPDATE", id).Scan(&stock)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	return stock, nil
+}
+
+func (r *pgProductRepository) AdjustStock(ctx context.Context, id, delta int) (int, error) {
+	var stock int
+	err := r.db.QueryRow(ctx,
+		"UPDATE products SET stock=stock+$1, updated_at=NOW() WHERE id=$2 AND stock+$1 >= 0 RETURNING stock",
+		delta, id).Scan(&stock)
+	if err == nil {
+		return stock, nil
+	}
+	if err = notFound(err); err != ErrNotFound {
+		return 0, err
+	}
+	// No row matched: either the product is gone or the guard refused.
+	var exists bool
+	if err := r.db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM products WHERE id=$1)", id).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, ErrNotFound
+	}
+	return 0, ErrInsufficientStock
+}
+
+func (r *pgProductRepository) SetPrice(ctx context.Context, id, price int) (int, error) {
+	var previous int
+	err := r.db.QueryRow(ctx,
+		`UPDATE products p SET price=$1, updated_at=NOW()
+		 FROM (SELECT price FROM products WHERE id=$2 FOR UPDATE) old
+		 WHERE p.id=$2
+		 RETURNING old.price`, price, id).Scan(&previous)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	return previous, nil
+}
+
+func (r *pgProductRepository) AddImage(ctx context.Context, img *models.ProductImage) error {
+	return r.db.QueryRow(ctx,
+		`INSERT INTO product_images (product_id, image_url, cloudinary_id, is_primary, display_order, created_at)
+		 VALUES ($1, $2, NULLIF($3, ''), $4, $5, NOW())
+		 RETURNING id, created_at`,
+		img.ProductID, img.URL, img.CloudinaryID, img.IsPrimary, img.Order,
+	).Scan(&img.ID, &img.CreatedAt)
+}
+
+func (r *pgProductRepository) UpdateImage(ctx context.Context, img models.ProductImage) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE product_images
+		 SET image_url=$1, cloudinary_id=NULLIF($2, ''), is_primary=$3, display_order=$4
+		 WHERE id=$5`,
+		img.URL, img.CloudinaryID, img.IsPrimary, img.Order, img.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgProductRepository) DeleteImage(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM product_images WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgProductRepository) CreateOption(ctx context.Context, o *models.ProductOption) error {
+	return r.db.QueryRow(ctx,
+		`INSERT INTO product_details (product_id, size_id, temperature_id, variant_id, price_adjustment, is_active, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		 RETURNING id, created_at, updated_at`,
+		o.ProductID, o.SizeID, o.TemperatureID, o.VariantID, o.PriceAdjustment, o.IsActive,
+	).Scan(&o.ID, &o.CreatedAt, &o.UpdatedAt)
+}
+
+func (r *pgProductRepository) UpdateOption(ctx context.Context, o models.ProductOption) error {
+	tag, err := r.db.Exec(ctx,
+		"UPDATE product_details SET price_adjustment=$1, is_active=$2, updated_at=NOW() WHERE id=$3",
+		o.PriceAdjustment, o.IsActive, o.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgProductRepository) DeleteOption(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM product_details WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// translate overlays the locale's translations onto the products in place.
+func (r *pgProductRepository) translate(ctx context.Context, locale string, products []models.Product) error {
+	if locale == "" || len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	rows, err := r.db.Query(ctx,
+		`SELECT product_id, locale, COALESCE(name, ''), COALESCE(description, ''), updated_at
+		 FROM product_translations
+		 WHERE locale = $1 AND product_id = ANY($2)`,
+		locale, ids,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	translations := map[int]models.ProductTranslation{}
+	for rows.Next() {
+		var t models.ProductTranslation
+		if err := rows.Scan(&t.ProductID, &t.Locale, &t.Name, &t.Description, &t.UpdatedAt); err != nil {
+			return err
+		}
+		translations[t.ProductID] = t
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range products {
+		if t, ok := translations[products[i].ID]; ok {
+			t.Apply(&products[i].Name, &products[i].Description)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"context"
+)
+
+type PromoRepository interface {
+	// Active returns the active promos, newest first.
+	Active(ctx context.Context) ([]models.Promo, error)
+}
+
+type pgPromoRepository struct {
+	db DBTX
+}
+
+func (r *pgPromoRepository) Active(ctx context.Context) ([]models.Promo, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, title, COALESCE(description, ''), code, bg_color, text_color
+		 FROM promos WHERE is_active = TRUE
+		 ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promos := []models.Promo{}
+	for rows.Next() {
+		var p models.Promo
+		if err := rows.Scan(&p.ID, &p.Title, &p.Description, &p.Code, &p.BgColor, &p.TextColor); err != nil {
+			return nil, err
+		}
+		promos = append(promos, p)
+	}
+	return promos, rows.Err()
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"context"
+	"time"
+)
+
+type PromotionRepository interface {
+	// List returns every promotion rule, newest first.
+	List(ctx context.Context) ([]models.PromotionRule, error)
+	Get(ctx context.Context, id int) (models.PromotionRule, error)
+	// Running returns the rules that apply at now, oldest first.
+	Running(ctx context.Context, now time.Time) ([]models.PromotionRule, error)
+	// Create inserts r and fills in its ID and timestamps.
+	Create(ctx context.Context, r *models.PromotionRule) error
+	Update(ctx context.Context, r models.PromotionRule) error
+	Delete(ctx context.Context, id int) error
+}
+
+type pgPromotionRepository struct {
+	db DBTX
+}
+
+const promotionColumns = `id, name, type, product_id, category_id, buy_quantity, get_quantity, discount_percent,
+	starts_at, ends_at, is_active, created_at, updated_at`
+
+func (r *pgPromotionRepository) List(ctx context.Context) ([]models.PromotionRule, error) {
+	return r.query(ctx, "SELECT "+promotionColumns+" FROM promotion_rules ORDER BY id DESC")
+}
+
+func (r *pgPromotionRepository) Get(ctx context.Context, id int) (models.PromotionRule, error) {
+	rules, err := r.query(ctx, "SELECT "+promotionColumns+" FROM promotion_rules WHERE id = $1", id)
+	if err != nil {
+		return models.PromotionRule{}, err
+	}
+	if len(rules) == 0 {
+		return models.PromotionRule{}, ErrNotFound
+	}
+	return rules[0], nil
+}
+
+func (r *pgPromotionRepository) Running(ctx context.Context, now time.Time) ([]models.PromotionRule, error) {
+	return r.query(ctx,
+		`SELECT `+promotionColumns+` FROM promotion_rules
+		 WHERE is_active AND (starts_at IS NULL OR starts_at <= $1) AND (ends_at IS NULL OR ends_at > $1)
+		 ORDER BY id`, now)
+}
+
+func (r *pgPromotionRepository) Create(ctx context.Context, rule *models.PromotionRule) error {
+	return r.db.QueryRow(ctx,
+		`INSERT INTO promotion_rules (name, type, product_id, category_id, buy_quantity, get_quantity, discount_percent,
+			starts_at, ends_at, is_active, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		 RETURNING id, created_at, updated_at`,
+		rule.Name, rule.Type, rule.ProductID, rule.CategoryID, rule.BuyQuantity, rule.GetQuantity, rule.DiscountPercent,
+		rule.StartsAt, rule.EndsAt, rule.IsActive).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+}
+
+func (r *pgPromotionRepository) Update(ctx context.Context, rule models.PromotionRule) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE promotion_rules SET name=$1, type=$2, product_id=$3, category_id=$4, buy_quantity=$5, get_quantity=$6,
+			discount_percent=$7, starts_at=$8, ends_at=$9, is_active=$10, updated_at=$11
+		 WHERE id=$12`,
+		rule.Name, rule.Type, rule.ProductID, rule.CategoryID, rule.BuyQuantity, rule.GetQuantity, rule.DiscountPercent,
+		rule.StartsAt, rule.EndsAt, rule.IsActive, rule.UpdatedAt, rule.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgPromotionRepository) Delete(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM promotion_rules WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgPromotionRepository) query(ctx context.Context, sql string, args ...any) ([]models.PromotionRule, error) {
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.PromotionRule{}
+	for rows.Next() {
+		var rule models.PromotionRule
+		if err := rows.Scan(&rule.ID, &rule.Name, &rule.Type, &rule.ProductID, &rule.CategoryID, &rule.BuyQuantity,
+			&rule.GetQuantity, &rule.DiscountPercent, &rule.StartsAt, &rule.EndsAt, &rule.IsActive,
+			&rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"context"
+	"time"
+)
+
+type RecommendationRepository interface {
+	// List returns the product's curated recommendations, inactive ones
+	// included, highest priority first.
+	List(ctx context.Context, productID int) ([]models.ProductRecommendation, error)
+	// Create inserts r and fills in its ID and timestamps.
+	Create(ctx context.Context, r *models.ProductRecommendation) error
+	Update(ctx context.Context, r models.ProductRecommendation) error
+	Delete(ctx context.Context, id int) error
+
+	// Curated returns the active, unarchived products recommended for any
+	// of productIDs with one of the types, highest priority first, with
+	// RecommendationType set. The products themselves are left out.
+	Curated(ctx context.Context, productIDs []int, types []string, locale string) ([]models.Product, error)
+	// BoughtTogether returns up to limit active, unarchived products that
+	// were most often ordered together with productIDs as of the last
+	// RefreshBoughtTogether. The products themselves are left out.
+	BoughtTogether(ctx context.Context, productIDs []int, limit int, locale string) ([]models.Product, error)
+	// RefreshBoughtTogether recounts, from order_items, how many orders that
+	// were not cancelled contain each pair of products, keeping the pairs
+	// found in at least minOrders orders. It returns the number of pairs
+	// kept; each is stored in both directions.
+	RefreshBoughtTogether(ctx context.Context, minOrders int, now time.Time) (int, error)
+}
+
+type pgRecommendationRepository struct {
+	db DBTX
+}
+
+func (r *pgRecommendationRepository) List(ctx context.Context, productID int) ([]models.ProductRecommendation, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT r.id, r.product_id, r.recommended_product_id, p.name, r.recommendation_type, r.priority, r.is_active,
+			r.created_at, r.updated_at
+		 FROM product_recommendations r
+		 JOIN products p ON p.id = r.recommended_product_id
+		 WHERE r.product_id = $1
+		 ORDER BY r.priority DESC, r.id`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recommendations := []models.ProductRecommendation{}
+	for rows.Next() {
+		var rec models.ProductRecommendation
+		if err := rows.Scan(&rec.ID, &rec.ProductID, &rec.RecommendedProductID, &rec.RecommendedProductName, &rec.Type,
+			&rec.Priority, &rec.IsActive, &rec.CreatedAt, &rec.UpdatedAt); err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, rec)
+	}
+	return recommendations, rows.Err()
+}
+
+func (r *pgRecommendationRepository) Create(ctx context.Context, rec *models.ProductRecommendation) error {
+	return r.db.QueryRow(ctx,
+		`INSERT INTO product_recommendations (product_id, recommended_product_id, recommendation_type, priority, is_active,
+			created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		 RETURNING id, created_at, updated_at`,
+		rec.ProductID, rec.RecommendedProductID, rec.Type, rec.Priority, rec.IsActive).
+		Scan(&rec.ID, &rec.CreatedAt, &rec.UpdatedAt)
+}
+
+func (r *pgRecommendationRepository) Update(ctx context.Context, rec models.ProductRecommendation) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE product_recommendations SET recommendation_type=$1, priority=$2, is_active=$3, updated_at=NOW()
+		 WHERE id=$4`,
+		rec.Type, rec.Priority, rec.IsActive, rec.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgRecommendationRepository) Delete(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM product_recommendations WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgRecommendationRepository) Curated(ctx context.Context, productIDs []int, types []string, locale string) ([]models.Product, error) {
+	// A product recommended for several of productIDs is listed once, at
+	// its highest priority.
+	return r.query(ctx,
+		`SELECT `+qualifiedProductColumns+`, picks.recommendation_type FROM (
+			SELECT DISTINCT ON (recommended_product_id) recommended_product_id, recommendation_type, priority, id
+			FROM product_recommendations
+			WHERE product_id = ANY($1) AND NOT recommended_product_id = ANY($1)
+			  AND recommendation_type = ANY($2) AND is_active
+			ORDER BY recommended_product_id, priority DESC, id
+		 ) picks
+		 JOIN products ON products.id = picks.recommended_product_id
+		 WHERE products.is_active = TRUE AND products.deleted_at IS NULL
+		 ORDER BY picks.priority DESC, picks.id`,
+		locale, productIDs, types)
+}
+
+func (r *pgRecommendationRepository) BoughtTogether(ctx context.Context, productIDs []int, limit int, locale string) ([]models.Product, error) {
+	return r.query(ctx,
+		`SELECT `+qualifiedProductColumns+`, $3::text FROM (
+			SELECT related_product_id, SUM(order_count) AS orders
+			FROM product_co_purchases
+			WHERE product_id = ANY($1) AND NOT related_product_id = ANY($1)
+			GROUP BY related_product_id
+		 ) pairs
+		 JOIN products ON products.id = pairs.related_product_id
+		 WHERE products.is_active = TRUE AND products.deleted_at IS NULL
+		 ORDER BY pairs.orders DESC, products.id
+		 LIMIT $2`,
+		locale, productIDs, limit, models.RecommendationBoughtTogether)
+}
+
+func (r *pgRecommendationRepository) RefreshBoughtTogether(ctx context.Context, minOrders int, now time.Time) (int, error) {
+	if _, err := r.db.Exec(ctx, "DELETE FROM product_co_purchases"); err != nil {
+		return 0, err
+	}
+	tag, err := r.db.Exec(ctx,
+		`INSERT INTO product_co_purchases (product_id, related_product_id, order_count, refreshed_at)
+		 SELECT a.product_id, b.product_id, COUNT(DISTINCT a.order_id), $2
+		 FROM order_items a
+		 JOIN order_items b ON b.order_id = a.order_id AND b.product_id <> a.product_id
+		 JOIN orders o ON o.id = a.order_id
+		 LEFT JOIN order_status os ON os.id = o.status_id
+		 WHERE os.name IS DISTINCT FROM 'cancelled'
+		 GROUP BY a.product_id, b.product_id
+		 HAVING COUNT(DISTINCT a.order_id) >= $1`,
+		minOrders, now)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()) / 2, nil
+}
+
+// query runs a product query whose rows end with the recommendation type and
+// translates the products into locale.
+func (r *pgRecommendationRepository) query(ctx context.Context, sql, locale string, args ...any) ([]models.Product, error) {
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		var recommendationType string
+		p, err := scanProduct(rows, &recommendationType)
+		if err != nil {
+			return nil, err
+		}
+		p.RecommendationType = recommendationType
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	translator := &pgProductRepository{db: r.db}
+	return products, translator.translate(ctx, locale, products)
+}
@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ReviewFilter selects one page of reviews, newest first: of one product
+// when ProductID is set, and in one status when Status is set.
+type ReviewFilter struct {
+	pagination.Params
+	ProductID int
+	Status    string
+}
+
+type ReviewRepository interface {
+	// List returns one page of reviews and the total number of matches.
+	List(ctx context.Context, filter ReviewFilter) ([]models.ProductReview, int, error)
+	Get(ctx context.Context, id int) (models.ProductReview, error)
+	// Find returns the user's review of the product.
+	Find(ctx context.Context, productID, userID int) (models.ProductReview, error)
+	// Create inserts r and fills in its ID and timestamps.
+	Create(ctx context.Context, r *models.ProductReview) error
+	// Update saves the rating, text, status and reply of r.
+	Update(ctx context.Context, r models.ProductReview) error
+	Delete(ctx context.Context, id int) error
+	// Ratings counts the approved reviews of the product per rating.
+	Ratings(ctx context.Context, productID int) (map[int]int, error)
+	// Recompute stores the average rating and the number of the product's
+	// approved reviews on the product.
+	Recompute(ctx context.Context, productID int) error
+}
+
+type pgReviewRepository struct {
+	db DBTX
+}
+
+const reviewSelect = `SELECT pr.id, pr.product_id, p.name, pr.user_id, COALESCE(up.full_name, ''), pr.rating,
+	COALESCE(pr.review_text, ''), pr.status, COALESCE(pr.reply, ''), pr.replied_at,
+	pr.created_at, COALESCE(pr.updated_at, pr.created_at)
+	FROM product_reviews pr
+	JOIN products p ON p.id = pr.product_id
+	LEFT JOIN user_profiles up ON up.user_id = pr.user_id`
+
+func scanReview(row interface{ Scan(...any) error }) (models.ProductReview, error) {
+	var r models.ProductReview
+	err := row.Scan(&r.ID, &r.ProductID, &r.ProductName, &r.UserID, &r.User, &r.Rating,
+		&r.Text, &r.Status, &r.Reply, &r.RepliedAt, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+func (r *pgReviewRepository) List(ctx context.Context, filter ReviewFilter) ([]models.ProductReview, int, error) {
+	where := []string{"TRUE"}
+	args := []any{}
+	if filter.ProductID > 0 {
+		args = append(args, filter.ProductID)
+		where = append(where, fmt.Sprintf("pr.product_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where = append(where, fmt.Sprintf("pr.status = $%d", len(args)))
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx,
+		"SELECT COUNT(*) FROM product_reviews pr WHERE "+strings.Join(where, " AND "), args...,
+	).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	if filter.After != nil {
+		where = append(where, keyset("pr.created_at", "pr.id", len(args)+1))
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(ctx, fmt.Sprintf(reviewSelect+
+		" WHERE %s ORDER BY pr.created_at DESC, pr.id DESC LIMIT $%d OFFSET $%d",
+		strings.Join(where, " AND "), len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	reviews := []models.ProductReview{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, total, rows.Err()
+}
+
+func (r *pgReviewRepository) Get(ctx context.Context, id int) (models.ProductReview, error) {
+	review, err := scanReview(r.db.QueryRow(ctx, reviewSelect+" WHERE pr.id = $1", id))
+	return review, notFound(err)
+}
+
+func (r *pgReviewRepository) Find(ctx context.Context, productID, userID int) (models.ProductReview, error) {
+	review, err := scanReview(r.db.QueryRow(ctx,
+		reviewSelect+" WHERE pr.product_id = $1 AND pr.user_id = $2", productID, userID))
+	return review, notFound(err)
+}
+
+func (r *pgReviewRepository) Create(ctx context.Context, review *models.ProductReview) error {
+	return r.db.QueryRow(ctx,
+		`INSERT INTO product_reviews (product_id, user_id, rating, review_text, status, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		 RETURNING id, created_at, updated_at`,
+		review.ProductID, review.UserID, review.Rating, review.Text, review.Status,
+	).Scan(&review.ID, &review.CreatedAt, &review.UpdatedAt)
+}
+
+func (r *pgReviewRepository) Update(ctx context.Context, review models.ProductReview) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE product_reviews
+		 SET rating=$2, review_text=$3, status=$4, reply=NULLIF($5, ''), replied_at=$6, updated_at=$7
+		 WHERE id=$1`,
+		review.ID, review.Rating, review.Text, review.Status, review.Reply, review.RepliedAt, review.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgReviewRepository) Delete(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM product_reviews WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgReviewRepository) Ratings(ctx context.Context, productID int) (map[int]int, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT rating, COUNT(*) FROM product_reviews
+		 WHERE product_id=$1 AND status=$2 GROUP BY rating`, productID, models.ReviewApproved)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int]int{}
+	for rows.Next() {
+		var rating, count int
+		if err := rows.Scan(&rating, &count); err != nil {
+			return nil, err
+		}
+		counts[rating] = count
+	}
+	return counts, rows.Err()
+}
+
+func (r *pgReviewRepository) Recompute(ctx context.Context, productID int) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE products SET
+		   average_rating = COALESCE((SELECT ROUND(AVG(rating), 2) FROM product_reviews WHERE product_id=$1 AND status=$2), 0),
+		   review_count = (SELECT COUNT(*) FROM product_reviews WHERE product_id=$1 AND status=$2)
+		 WHERE id=$1`, productID, models.ReviewApproved)
+	return err
+}
@@ -0,0 +1,218 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SuggestFilter selects suggestions for Query, an already normalized search.
+// Only allowed queries searched at least MinHits times are offered.
+type SuggestFilter struct {
+	Query   string
+	Locale  string
+	Limit   int
+	MinHits int
+}
+
+// SearchQueryFilter selects past searches for moderation, most searched
+// first. An empty Status lists them all. Only page pagination applies.
+type SearchQueryFilter struct {
+	pagination.Params
+	Status string
+}
+
+// SearchRepository backs the storefront search box: suggestions while the
+// user types and the record of past searches they draw on.
+type SearchRepository interface {
+	// Suggest returns up to Limit product names, category names and past
+	// queries that start with Query, or contain a word that does, followed
+	// by close typo matches.
+	Suggest(ctx context.Context, filter SuggestFilter) (models.Suggestions, error)
+	// RecordQuery counts one more search for query.
+	RecordQuery(ctx context.Context, query string) error
+	// Queries returns one page of the recorded searches and how many match
+	// the filter.
+	Queries(ctx context.Context, filter SearchQueryFilter) ([]models.SearchQuery, int, error)
+	// Query returns a recorded search, or ErrNotFound.
+	Query(ctx context.Context, id int) (models.SearchQuery, error)
+	// SetQueryStatus moderates a recorded search; it returns ErrNotFound
+	// when there is no search id.
+	SetQueryStatus(ctx context.Context, id int, status string) error
+}
+
+type pgSearchRepository struct {
+	db DBTX
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// suggestPatterns returns the ILIKE patterns for names that start with q and
+// for names with a later word that does.
+func suggestPatterns(q string) (prefix, wordPrefix string) {
+	escaped := likeEscaper.Replace(q)
+	return escaped + "%", "% " + escaped + "%"
+}
+
+func (r *pgSearchRepository) Suggest(ctx context.Context, filter SuggestFilter) (models.Suggestions, error) {
+	prefix, wordPrefix := suggestPatterns(filter.Query)
+	s := models.Suggestions{
+		Products:   []models.SuggestedProduct{},
+		Categories: []models.SuggestedCategory{},
+		Queries:    []string{},
+	}
+
+	// Each branch of the UNIONs matches one column and is served by its
+	// trigram index; the few candidates are then joined back for the label.
+	rows, err := r.db.Query(ctx,
+		`WITH candidates AS (
+		     SELECT id FROM products
+		     WHERE name ILIKE $1 OR name ILIKE $2 OR $3 <% name
+		     UNION
+		     SELECT product_id FROM product_translations
+		     WHERE locale = $4 AND (name ILIKE $1 OR name ILIKE $2 OR $3 <% name)
+		 )
+		 SELECT p.id, COALESCE(NULLIF(t.name, ''), p.name) AS label
+		 FROM candidates m
+		 JOIN products p ON p.id = m.id
+		 LEFT JOIN product_translations t ON t.product_id = p.id AND t.locale = $4
+		 WHERE p.is_active = TRUE AND p.deleted_at IS NULL
+		 ORDER BY (COALESCE(NULLIF(t.name, ''), p.name) ILIKE $1) DESC,
+		          GREATEST(word_similarity($3, p.name), COALESCE(word_similarity($3, t.name), 0)) DESC,
+		          label
+		 LIMIT $5`,
+		prefix, wordPrefix, filter.Query, filter.Locale, filter.Limit)
+	if err != nil {
+		return s, err
+	}
+	for rows.Next() {
+		var p models.SuggestedProduct
+		if err := rows.Scan(&p.ID, &p.Name); err != nil {
+			rows.Close()
+			return s, err
+		}
+		s.Products = append(s.Products, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return s, err
+	}
+
+	rows, err = r.db.Query(ctx,
+		`WITH candidates AS (
+		     SELECT id FROM categories
+		     WHERE name ILIKE $1 OR name ILIKE $2 OR $3 <% name
+		     UNION
+		     SELECT category_id FROM category_translations
+		     WHERE locale = $4 AND (name ILIKE $1 OR name ILIKE $2 OR $3 <% name)
+		 )
+		 SELECT c.id, COALESCE(NULLIF(t.name, ''), c.name) AS label
+		 FROM candidates m
+		 JOIN categories c ON c.id = m.id
+		 LEFT JOIN category_translations t ON t.category_id = c.id AND t.locale = $4
+		 WHERE COALESCE(c.is_active, TRUE)
+		 ORDER BY (COALESCE(NULLIF(t.name, ''), c.name) ILIKE $1) DESC,
+		          GREATEST(word_similarity($3, c.name), COALESCE(word_similarity($3, t.name), 0)) DESC,
+		          label
+		 LIMIT $5`,
+		prefix, wordPrefix, filter.Query, filter.Locale, filter.Limit)
+	if err != nil {
+		return s, err
+	}
+	for rows.Next() {
+		var c models.SuggestedCategory
+		if err := rows.Scan(&c.ID, &c.Name); err != nil {
+			rows.Close()
+			return s, err
+		}
+		s.Categories = append(s.Categories, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return s, err
+	}
+
+	rows, err = r.db.Query(ctx,
+		`SELECT query FROM search_queries
+		 WHERE status = 'allowed' AND hits >= $4 AND (query LIKE $1 OR query LIKE $2 OR $3 <% query)
+		 ORDER BY (query LIKE $1) DESC, hits DESC, query
+		 LIMIT $5`,
+		prefix, wordPrefix, filter.Query, filter.MinHits, filter.Limit)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var q string
+		if err := rows.Scan(&q); err != nil {
+			return s, err
+		}
+		s.Queries = append(s.Queries, q)
+	}
+	return s, rows.Err()
+}
+
+func (r *pgSearchRepository) RecordQuery(ctx context.Context, query string) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO search_queries (query) VALUES ($1)
+		 ON CONFLICT (query) DO UPDATE
+		 SET hits = search_queries.hits + 1, last_searched_at = CURRENT_TIMESTAMP`,
+		query)
+	return err
+}
+
+const searchQuerySelect = `SELECT id, query, hits, status, last_searched_at FROM search_queries`
+
+func scanSearchQuery(row interface{ Scan(...any) error }) (models.SearchQuery, error) {
+	var q models.SearchQuery
+	err := row.Scan(&q.ID, &q.Query, &q.Hits, &q.Status, &q.LastSearchedAt)
+	return q, err
+}
+
+func (r *pgSearchRepository) Queries(ctx context.Context, filter SearchQueryFilter) ([]models.SearchQuery, int, error) {
+	where, args := "TRUE", []any{}
+	if filter.Status != "" {
+		where, args = "status = $1", append(args, filter.Status)
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM search_queries WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := r.db.Query(ctx, fmt.Sprintf(searchQuerySelect+
+		" WHERE %s ORDER BY hits DESC, query LIMIT $%d OFFSET $%d", where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	queries := []models.SearchQuery{}
+	for rows.Next() {
+		q, err := scanSearchQuery(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		queries = append(queries, q)
+	}
+	return queries, total, rows.Err()
+}
+
+func (r *pgSearchRepository) Query(ctx context.Context, id int) (models.SearchQuery, error) {
+	q, err := scanSearchQuery(r.db.QueryRow(ctx, searchQuerySelect+" WHERE id = $1", id))
+	return q, notFound(err)
+}
+
+func (r *pgSearchRepository) SetQueryStatus(ctx context.Context, id int, status string) error {
+	tag, err := r.db.Exec(ctx, `UPDATE search_queries SET status = $2 WHERE id = $1`, id, status)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
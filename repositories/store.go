@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrNotFound is returned when the requested row does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrInsufficientStock is returned when a stock change would leave a
+// product with less than nothing in stock.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// Store groups the repositories so a service can run several of them inside
+// one database transaction with WithTx.
+type Store interface {
+	Products() ProductRepository
+	Categories() CategoryRepository
+	Users() UserRepository
+	Orders() OrderRepository
+	Carts() CartRepository
+	Inventory() InventoryRepository
+	Prices() PriceRepository
+	FlashSales() FlashSaleRepository
+	Promotions() PromotionRepository
+	Promos() PromoRepository
+	Reviews() ReviewRepository
+	Favorites() FavoriteRepository
+	Recommendations() RecommendationRepository
+	Views() ViewRepository
+	Audit() AuditRepository
+	Search() SearchRepository
+	Translations() TranslationRepository
+
+	// WithTx runs fn against a Store bound to a single transaction. The
+	// transaction is committed when fn returns nil and rolled back otherwise.
+	WithTx(ctx context.Context, fn func(tx Store) error) error
+}
+
+// DBTX is satisfied by both *pgxpool.Pool and pgx.Tx.
+type DBTX interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type pgStore struct {
+	pool *pgxpool.Pool
+	db   DBTX
+}
+
+// NewPostgresStore returns a Store backed by the given connection pool.
+func NewPostgresStore(pool *pgxpool.Pool) Store {
+	return &pgStore{pool: pool, db: pool}
+}
+
+func (s *pgStore) Products() ProductRepository     { return &pgProductRepository{db: s.db} }
+func (s *pgStore) Categories() CategoryRepository  { return &pgCategoryRepository{db: s.db} }
+func (s *pgStore) Users() UserRepository           { return &pgUserRepository{db: s.db} }
+func (s *pgStore) Orders() OrderRepository         { return &pgOrderRepository{db: s.db} }
+func (s *pgStore) Carts() CartRepository           { return &pgCartRepository{db: s.db} }
+func (s *pgStore) Inventory() InventoryRepository  { return &pgInventoryRepository{db: s.db} }
+func (s *pgStore) Prices() PriceRepository         { return &pgPriceRepository{db: s.db} }
+func (s *pgStore) FlashSales() FlashSaleRepository { return &pgFlashSaleRepository{db: s.db} }
+func (s *pgStore) Promotions() PromotionRepository { return &pgPromotionRepository{db: s.db} }
+func (s *pgStore) Promos() PromoRepository         { return &pgPromoRepository{db: s.db} }
+func (s *pgStore) Reviews() ReviewRepository       { return &pgReviewRepository{db: s.db} }
+func (s *pgStore) Favorites() FavoriteRepository   { return &pgFavoriteRepository{db: s.db} }
+func (s *pgStore) Audit() AuditRepository          { return &pgAuditRepository{db: s.db} }
+func (s *pgStore) Search() SearchRepository        { return &pgSearchRepository{db: s.db} }
+func (s *pgStore) Views() ViewRepository           { return &pgViewRepository{db: s.db} }
+
+func (s *pgStore) Translations() TranslationRepository {
+	return &pgTranslationRepository{db: s.db}
+}
+
+func (s *pgStore) Recommendations() RecommendationRepository {
+	return &pgRecommendationRepository{db: s.db}
+}
+
+func (s *pgStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	// Already inside a transaction: join it instead of nesting.
+	if _, ok := s.db.(pgx.Tx); ok {
+		return fn(s)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&pgStore{pool: s.pool, db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// notFound maps pgx.ErrNoRows to ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// keyset is the WHERE condition selecting rows after a pagination cursor for
+// a list ordered by (timeCol, idCol) descending. The cursor's time and ID are
+// bound to $argIdx and $argIdx+1.
+func keyset(timeCol, idCol string, argIdx int) string {
+	return fmt.Sprintf("(%s, %s) < ($%d, $%d)", timeCol, idCol, argIdx, argIdx+1)
+}
@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"context"
+)
+
+// TranslationRepository stores the per-locale names and descriptions that
+// replace a product's or category's own when responding in that locale.
+type TranslationRepository interface {
+	// ProductTranslations returns every translation of the product, by locale.
+	ProductTranslations(ctx context.Context, productID int) ([]models.ProductTranslation, error)
+	ProductTranslation(ctx context.Context, productID int, locale string) (models.ProductTranslation, error)
+	// SaveProductTranslation inserts or replaces the translation for t's
+	// product and locale. Empty fields fall back to the product's own.
+	SaveProductTranslation(ctx context.Context, t models.ProductTranslation) error
+	DeleteProductTranslation(ctx context.Context, productID int, locale string) error
+
+	// CategoryTranslations returns every translation of the category, by
+	// locale.
+	CategoryTranslations(ctx context.Context, categoryID int) ([]models.CategoryTranslation, error)
+	CategoryTranslation(ctx context.Context, categoryID int, locale string) (models.CategoryTranslation, error)
+	// SaveCategoryTranslation inserts or replaces the translation for t's
+	// category and locale.
+	SaveCategoryTranslation(ctx context.Context, t models.CategoryTranslation) error
+	DeleteCategoryTranslation(ctx context.Context, categoryID int, locale string) error
+}
+
+type pgTranslationRepository struct {
+	db DBTX
+}
+
+const productTranslationColumns = "product_id, locale, COALESCE(name, ''), COALESCE(description, ''), updated_at"
+
+func scanProductTranslation(row interface{ Scan(...any) error }) (models.ProductTranslation, error) {
+	var t models.ProductTranslation
+	err := row.Scan(&t.ProductID, &t.Locale, &t.Name, &t.Description, &t.UpdatedAt)
+	return t, err
+}
+
+func (r *pgTranslationRepository) ProductTranslations(ctx context.Context, productID int) ([]models.ProductTranslation, error) {
+	rows, err := r.db.Query(ctx,
+		"SELECT "+productTranslationColumns+" FROM product_translations WHERE product_id = $1 ORDER BY locale", productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := []models.ProductTranslation{}
+	for rows.Next() {
+		t, err := scanProductTranslation(rows)
+		if err != nil {
+			return nil, err
+		}
+		translations = append(translations, t)
+	}
+	return translations, rows.Err()
+}
+
+func (r *pgTranslationRepository) ProductTranslation(ctx context.Context, productID int, locale string) (models.ProductTranslation, error) {
+	t, err := scanProductTranslation(r.db.QueryRow(ctx,
+		"SELECT "+productTranslationColumns+" FROM product_translations WHERE product_id = $1 AND locale = $2",
+		productID, locale))
+	if err != nil {
+		return models.ProductTranslation{}, notFound(err)
+	}
+	return t, nil
+}
+
+func (r *pgTranslationRepository) SaveProductTranslation(ctx context.Context, t models.ProductTranslation) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO product_translations (product_id, locale, name, description, created_at, updated_at)
+		 VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NOW(), NOW())
+		 ON CONFLICT (product_id, locale)
+		 DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description, updated_at = NOW()`,
+		t.ProductID, t.Locale, t.Name, t.Description)
+	return err
+}
+
+func (r *pgTranslationRepository) DeleteProductTranslation(ctx context.Context, productID int, locale string) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM product_translations WHERE product_id = $1 AND locale = $2", productID, locale)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+const categoryTranslationColumns = "category_id, locale, name, updated_at"
+
+func scanCategoryTranslation(row interface{ Scan(...any) error }) (models.CategoryTranslation, error) {
+	var t models.CategoryTranslation
+	err := row.Scan(&t.CategoryID, &t.Locale, &t.Name, &t.UpdatedAt)
+	return t, err
+}
+
+func (r *pgTranslationRepository) CategoryTranslations(ctx context.Context, categoryID int) ([]models.CategoryTranslation, error) {
+	rows, err := r.db.Query(ctx,
+		"SELECT "+categoryTranslationColumns+" FROM category_translations WHERE category_id = $1 ORDER BY locale", categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := []models.CategoryTranslation{}
+	for rows.Next() {
+		t, err := scanCategoryTranslation(rows)
+		if err != nil {
+			return nil, err
+		}
+		translations = append(translations, t)
+	}
+	return translations, rows.Err()
+}
+
+func (r *pgTranslationRepository) CategoryTranslation(ctx context.Context, categoryID int, locale string) (models.CategoryTranslation, error) {
+	t, err := scanCategoryTranslation(r.db.QueryRow(ctx,
+		"SELECT "+categoryTranslationColumns+" FROM category_translations WHERE category_id = $1 AND locale = $2",
+		categoryID, locale))
+	if err != nil {
+		return models.CategoryTranslation{}, notFound(err)
+	}
+	return t, nil
+}
+
+func (r *pgTranslationRepository) SaveCategoryTranslation(ctx context.Context, t models.CategoryTranslation) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO category_translations (category_id, locale, name, created_at, updated_at)
+		 VALUES ($1, $2, $3, NOW(), NOW())
+		 ON CONFLICT (category_id, locale)
+		 DO UPDATE SET name = EXCLUDED.name, updated_at = NOW()`,
+		t.CategoryID, t.Locale, t.Name)
+	return err
+}
+
+func (r *pgTranslationRepository) DeleteCategoryTranslation(ctx context.Context, categoryID int, locale string) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM category_translations WHERE category_id = $1 AND locale = $2", categoryID, locale)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"context"
+	"fmt"
+	"time"
+)
+
+type NewUser struct {
+	Email        string
+	PasswordHash string
+	Role         string
+	FullName     string
+	Phone        string
+	// Language is the preferred locale; empty leaves it unset.
+	Language string
+}
+
+// UserUpdate holds the admin edit of a user. Empty Email or Role keep the
+// stored value; the profile fields are always written.
+type UserUpdate struct {
+	Email    string
+	Role     string
+	FullName string
+	Phone    string
+	Address  string
+}
+
+// ProfileUpdate is a user's edit of their own profile. Empty fields keep the
+// stored value, and the photo is only replaced when PhotoURL is set.
+type ProfileUpdate struct {
+	FullName string
+	Phone    string
+	Address  string
+	Language string
+	PhotoURL string
+	// PhotoID identifies the photo at the image host, for deleting it.
+	PhotoID string
+}
+
+type UserRepository interface {
+	// List returns one page of users with their profile, newest first, and
+	// the total number of users.
+	List(ctx context.Context, page pagination.Params) ([]models.UserWithProfile, int, error)
+	Get(ctx context.Context, id int) (models.UserWithProfile, error)
+	GetByEmail(ctx context.Context, email string) (models.UserWithProfile, error)
+	// Language returns the language saved in the user's profile, or "" when
+	// they have not chosen one.
+	Language(ctx context.Context, id int) (string, error)
+	// EmailExists reports whether a user other than excludeID uses email.
+	EmailExists(ctx context.Context, email string, excludeID int) (bool, error)
+	// Create inserts the user and its profile and returns the new user ID.
+	Create(ctx context.Context, u NewUser) (int, error)
+	Update(ctx context.Context, id int, u UserUpdate) error
+	// PasswordHash returns the user's stored password hash.
+	PasswordHash(ctx context.Context, id int) (string, error)
+	SetPassword(ctx context.Context, id int, hash string) error
+	// UpdateProfile applies u to the user's profile, creating the profile
+	// when the user has none yet.
+	UpdateProfile(ctx context.Context, id int, u ProfileUpdate) error
+	// PhotoID returns the image host's ID of the user's profile photo, or ""
+	// when they have none.
+	PhotoID(ctx context.Context, id int) (string, error)
+	// Delete removes the user and its profile.
+	Delete(ctx context.Context, id int) error
+}
+
+type pgUserRepository struct {
+	db DBTX
+}
+
+const userWithProfileSelect = `SELECT u.id, u.email, u.role, u.created_at, COALESCE(p.full_name,''), COALESCE(p.phone,''),
+	COALESCE(p.address,''), COALESCE(p.photo_url,'')
+	FROM users u LEFT JOIN user_profiles p ON u.id=p.user_id`
+
+func scanUser(row interface{ Scan(...any) error }) (models.UserWithProfile, error) {
+	var u models.UserWithProfile
+	err := row.Scan(&u.ID, &u.Email, &u.Role, &u.CreatedAt, &u.FullName, &u.Phone, &u.Address, &u.PhotoURL)
+	return u, err
+}
+
+func (r *pgUserRepository) List(ctx context.Context, page pagination.Params) ([]models.UserWithProfile, int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	where := ""
+	args := []any{}
+	if page.After != nil {
+		where = " WHERE " + keyset("u.created_at", "u.id", 1)
+		args = append(args, page.After.CreatedAt, page.After.ID)
+	}
+	args = append(args, page.Limit, page.Offset)
+
+	rows, err := r.db.Query(ctx, userWithProfileSelect+where+
+		fmt.Sprintf(" ORDER BY u.created_at DESC, u.id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []models.UserWithProfile{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	return users, total, rows.Err()
+}
+
+func (r *pgUserRepository) Get(ctx context.Context, id int) (models.UserWithProfile, error) {
+	u, err := scanUser(r.db.QueryRow(ctx, userWithProfileSelect+" WHERE u.id=$1", id))
+	if err != nil {
+		return models.UserWithProfile{}, notFound(err)
+	}
+	return u, nil
+}
+
+func (r *pgUserRepository) GetByEmail(ctx context.Context, email string) (models.UserWithProfile, error) {
+	u, err := scanUser(r.db.QueryRow(ctx, userWithProfileSelect+" WHERE u.email=$1", email))
+	if err != nil {
+		return models.UserWithProfile{}, notFound(err)
+	}
+	return u, nil
+}
+
+func (r *pgUserRepository) Language(ctx context.Context, id int) (string, error) {
+	var language string
+	err := r.db.QueryRow(ctx,
+		"SELECT COALESCE(p.language,'') FROM users u LEFT JOIN user_profiles p ON u.id=p.user_id WHERE u.id=$1", id).
+		Scan(&language)
+	if err != nil {
+		return "", notFound(err)
+	}
+	return language, nil
+}
+
+func (r *pgUserRepository) EmailExists(ctx context.Context, email string, excludeID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM users WHERE email=$1 AND id!=$2)", email, excludeID).Scan(&exists)
+	return exists, err
+}
+
+func (r *pgUserRepository) Create(ctx context.Context, u NewUser) (int, error) {
+	now := time.Now()
+
+	var userID int
+	err := r.db.QueryRow(ctx,
+		"INSERT INTO users (email, password, role, created_at, updated_at) VALUES ($1,$2,$3,$4,$5) RETURNING id",
+		u.Email, u.PasswordHash, u.Role, now, now).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = r.db.Exec(ctx,
+		"INSERT INTO user_profiles (user_id, full_name, phone, language, created_at, updated_at) VALUES ($1,$2,$3,NULLIF($4,''),$5,$6)",
+		userID, u.FullName, u.Phone, u.Language, now, now)
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+func (r *pgUserRepository) Update(ctx context.Context, id int, u UserUpdate) error {
+	now := time.Now()
+
+	if u.Email != "" {
+		if _, err := r.db.Exec(ctx, "UPDATE users SET email=$1, updated_at=$2 WHERE id=$3", u.Email, now, id); err != nil {
+			return err
+		}
+	}
+	if u.Role != "" {
+		if _, err := r.db.Exec(ctx, "UPDATE users SET role=$1, updated_at=$2 WHERE id=$3", u.Role, now, id); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.db.Exec(ctx,
+		"UPDATE user_profiles SET full_name=$1, phone=$2, address=$3, updated_at=$4 WHERE user_id=$5",
+		u.FullName, u.Phone, u.Address, now, id)
+	return err
+}
+
+func (r *pgUserRepository) PasswordHash(ctx context.Context, id int) (string, error) {
+	var hash string
+	if err := r.db.QueryRow(ctx, "SELECT password FROM users WHERE id=$1", id).Scan(&hash); err != nil {
+		return "", notFound(err)
+	}
+	return hash, nil
+}
+
+func (r *pgUserRepository) UpdateProfile(ctx context.Context, id int, u ProfileUpdate) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO user_profiles (user_id, full_name, phone, address, photo_url, cloudinary_public_id, language, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, $8)
+		 ON CONFLICT (user_id) DO UPDATE SET
+			full_name = COALESCE(NULLIF(EXCLUDED.full_name, ''), user_profiles.full_name),
+			phone = COALESCE(NULLIF(EXCLUDED.phone, ''), user_profiles.phone),
+			address = COALESCE(NULLIF(EXCLUDED.address, ''), user_profiles.address),
+			photo_url = COALESCE(EXCLUDED.photo_url, user_profiles.photo_url),
+			cloudinary_public_id = CASE WHEN EXCLUDED.photo_url IS NULL
+				THEN user_profiles.cloudinary_public_id ELSE EXCLUDED.cloudinary_public_id END,
+			language = COALESCE(EXCLUDED.language, user_profiles.language),
+			updated_at = EXCLUDED.updated_at`,
+		id, u.FullName, u.Phone, u.Address, u.PhotoURL, u.PhotoID, u.Language, time.Now())
+	return err
+}
+
+func (r *pgUserRepository) PhotoID(ctx context.Context, id int) (string, error) {
+	var photoID string
+	err := r.db.QueryRow(ctx,
+		"SELECT COALESCE(cloudinary_public_id, '') FROM user_profiles WHERE user_id=$1", id).Scan(&photoID)
+	if err != nil {
+		return "", notFound(err)
+	}
+	return photoID, nil
+}
+
+func (r *pgUserRepository) SetPassword(ctx context.Context, id int, hash string) error {
+	tag, err := r.db.Exec(ctx, "UPDATE users SET password=$1, updated_at=$2 WHERE id=$3", hash, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgUserRepository) Delete(ctx context.Context, id int) error {
+	if _, err := r.db.Exec(ctx, "DELETE FROM user_profiles WHERE user_id=$1", id); err != nil {
+		return err
+	}
+	tag, err := r.db.Exec(ctx, "DELETE FROM users WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"coffee-shop/models"
+	"context"
+	"time"
+)
+
+type ViewRepository interface {
+	// Record stores that the user opened the product's page.
+	Record(ctx context.Context, userID, productID int, at time.Time) error
+	// Recent returns the IDs of up to limit products the user viewed, the
+	// latest view first, each product once.
+	Recent(ctx context.Context, userID, limit int) ([]int, error)
+	// Products returns the active, unarchived products among ids, translated
+	// into locale and in the order of ids.
+	Products(ctx context.Context, ids []int, locale string) ([]models.Product, error)
+	// CountDaily recounts how many users viewed each product each day, from
+	// the latest day already counted on, or from since when none is. The
+	// popularity sort reads these counts.
+	CountDaily(ctx context.Context, since time.Time) error
+	// Prune deletes the views and daily counts from before before and
+	// returns how many views it deleted.
+	Prune(ctx context.Context, before time.Time) (int, error)
+}
+
+type pgViewRepository struct {
+	db DBTX
+}
+
+func (r *pgViewRepository) Record(ctx context.Context, userID, productID int, at time.Time) error {
+	_, err := r.db.Exec(ctx,
+		"INSERT INTO product_views (user_id, product_id, viewed_at) VALUES ($1, $2, $3)", userID, productID, at)
+	return err
+}
+
+func (r *pgViewRepository) Recent(ctx context.Context, userID, limit int) ([]int, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT product_id FROM product_views
+		 WHERE user_id = $1
+		 GROUP BY product_id
+		 ORDER BY MAX(viewed_at) DESC, MAX(id) DESC
+		 LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *pgViewRepository) Products(ctx context.Context, ids []int, locale string) ([]models.Product, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+qualifiedProductColumns+` FROM products
+		 JOIN unnest($1::int[]) WITH ORDINALITY AS viewed(id, position) ON viewed.id = products.id
+		 WHERE products.is_active = TRUE AND products.deleted_at IS NULL
+		 ORDER BY viewed.position`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	translator := &pgProductRepository{db: r.db}
+	return products, translator.translate(ctx, locale, products)
+}
+
+func (r *pgViewRepository) CountDaily(ctx context.Context, since time.Time) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO product_view_daily (product_id, day, viewers)
+		 SELECT product_id, viewed_at::date, COUNT(DISTINCT user_id)
+		 FROM product_views
+		 WHERE viewed_at >= COALESCE((SELECT MAX(day) FROM product_view_daily), $1::date)
+		 GROUP BY product_id, viewed_at::date
+		 ON CONFLICT (product_id, day) DO UPDATE SET viewers = EXCLUDED.viewers`, since)
+	return err
+}
+
+func (r *pgViewRepository) Prune(ctx context.Context, before time.Time) (int, error) {
+	if _, err := r.db.Exec(ctx, "DELETE FROM product_view_daily WHERE day < $1::date", before); err != nil {
+		return 0, err
+	}
+	tag, err := r.db.Exec(ctx, "DELETE FROM product_views WHERE viewed_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
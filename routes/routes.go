@@ -1,101 +1,272 @@
 package routes
 
 import (
+	"coffee-shop/cache"
 	"coffee-shop/controllers"
 	_ "coffee-shop/docs"
 	"coffee-shop/middleware"
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"coffee-shop/services"
+	"os"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+type controllerSet struct {
+	auth           *controllers.AuthController
+	profile        *controllers.ProfileController
+	user           *controllers.UserController
+	product        *controllers.ProductController
+	favorite       *controllers.FavoriteController
+	productView    *controllers.ProductViewController
+	gallery        *controllers.ProductGalleryController
+	option         *controllers.ProductOptionController
+	recommendation *controllers.RecommendationController
+	inventory      *controllers.InventoryController
+	price          *controllers.PriceController
+	order          *controllers.OrderController
+	category       *controllers.CategoryController
+	productDetail  *controllers.ProductDetailController
+	transaction    *controllers.TransactionController
+	history        *controllers.HistoryController
+	orderDetail    *controllers.OrderDetailController
+	audit          *controllers.AuditController
+	translation    *controllers.TranslationController
+	flashSale      *controllers.FlashSaleController
+	promotion      *controllers.PromotionRuleController
+	promo          *controllers.PromoController
+	searchQuery    *controllers.SearchQueryController
+}
+
+// Dependencies are the backends the handlers run against.
+type Dependencies struct {
+	Store  repositories.Store
+	Cache  cache.Store
+	Images services.ImageStore
+	// OTPs keeps the password reset codes sent to users.
+	OTPs services.OTPStore
+	// StockAlerts is told when a checkout takes a product below its
+	// low-stock threshold; nil sends no alerts.
+	StockAlerts services.StockAlerter
+}
+
+// SetupRoutes registers the API against the PostgreSQL pool, Redis client and
+// Cloudinary account configured at startup.
 func SetupRoutes(router *gin.Engine) {
-	authCtrl := &controllers.AuthController{}
-	profileCtrl := controllers.NewProfileController()
-	userCtrl := &controllers.UserController{}
-	productCtrl := &controllers.ProductController{}
-	orderCtrl := &controllers.OrderController{}
-	categoryCtrl := &controllers.CategoryController{}
-	productDetailCtrl := &controllers.ProductDetailController{}
-	transactionCtrl := &controllers.TransactionController{}
-	historyCtrl := &controllers.HistoryController{}
-	orderDetailCtrl := &controllers.OrderDetailController{}
+	RegisterRoutes(router, Dependencies{
+		Store:       repositories.NewPostgresStore(models.DB),
+		Cache:       cache.NewRedis(models.RedisClient),
+		Images:      services.NewCloudinaryImages(),
+		OTPs:        services.NewRedisOTPs(models.RedisClient),
+		StockAlerts: services.NewEmailStockAlerter(os.Getenv("STOCK_ALERT_EMAIL")),
+	})
+}
+
+// RegisterRoutes registers the API against deps, so tests can substitute
+// in-memory backends.
+func RegisterRoutes(router *gin.Engine, deps Dependencies) {
+	productService := services.NewProductService(deps.Store, deps.Images, deps.Cache)
+	orderService := services.NewOrderService(deps.Store, deps.StockAlerts, deps.Cache)
+	userService := services.NewUserService(deps.Store, deps.Cache)
+	searchService := services.NewSearchService(deps.Store, deps.Cache)
+
+	ctrls := &controllerSet{
+		auth:           controllers.NewAuthController(services.NewAuthService(deps.Store, deps.OTPs)),
+		profile:        controllers.NewProfileController(userService),
+		user:           controllers.NewUserController(userService),
+		product:        controllers.NewProductController(productService, searchService, deps.Cache),
+		favorite:       controllers.NewFavoriteController(productService),
+		productView:    controllers.NewProductViewController(productService),
+		gallery:        controllers.NewProductGalleryController(productService),
+		option:         controllers.NewProductOptionController(productService),
+		recommendation: controllers.NewRecommendationController(productService),
+		inventory:      controllers.NewInventoryController(productService),
+		price:          controllers.NewPriceController(productService),
+		order:          controllers.NewOrderController(orderService),
+		category:       controllers.NewCategoryController(services.NewCategoryService(deps.Store, deps.Cache), deps.Cache),
+		productDetail:  controllers.NewProductDetailController(productService, services.NewCartService(deps.Store), deps.Cache),
+		transaction:    controllers.NewTransactionController(orderService),
+		history:        controllers.NewHistoryController(orderService),
+		orderDetail:    controllers.NewOrderDetailController(orderService),
+		audit:          controllers.NewAuditController(services.NewAuditService(deps.Store)),
+		translation:    controllers.NewTranslationController(services.NewTranslationService(deps.Store, deps.Cache)),
+		flashSale:      controllers.NewFlashSaleController(services.NewFlashSaleService(deps.Store, deps.Cache)),
+		promotion:      controllers.NewPromotionRuleController(services.NewPromotionService(deps.Store)),
+		promo:          controllers.NewPromoController(services.NewPromoService(deps.Store)),
+		searchQuery:    controllers.NewSearchQueryController(searchService),
+	}
+
+	router.Use(middleware.LocaleMiddleware())
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	router.GET("/health", func(c *gin.Context) { c.JSON(200, gin.H{"status": "ok"}) })
 
-	router.POST("/auth/register", authCtrl.Register)
-	router.POST("/auth/login", authCtrl.Login)
-	router.POST("/auth/forgot-password", authCtrl.ForgotPassword)
-	router.POST("/auth/verify-otp", authCtrl.VerifyOTP)
+	// Unversioned routes are the original API and behave exactly like /v1.
+	registerAPIRoutes(router.Group("", middleware.APIVersion(1), middleware.DeprecationMiddleware("", "/v2")), ctrls, userService)
+	registerAPIRoutes(router.Group("/v1", middleware.APIVersion(1), middleware.DeprecationMiddleware("/v1", "/v2")), ctrls, userService)
+	registerAPIRoutes(router.Group("/v2", middleware.APIVersion(2)), ctrls, userService)
+
+	router.Static("/uploads", "./uploads")
+}
+
+// registerAPIRoutes registers the API under api. languages gives the auth
+// middleware each signed-in user's saved language.
+func registerAPIRoutes(api *gin.RouterGroup, ctrls *controllerSet, languages middleware.Languages) {
+	api.POST("/auth/register", ctrls.auth.Register)
+	api.POST("/auth/login", ctrls.auth.Login)
+	api.POST("/auth/forgot-password", ctrls.auth.ForgotPassword)
+	api.POST("/auth/verify-otp", ctrls.auth.VerifyOTP)
 
-	router.GET("/categories", categoryCtrl.GetCategories)
-	router.GET("/categories/:id", categoryCtrl.GetCategoryByID)
+	// Clients may keep these responses as the Cache-Control policies allow
+	// and revalidate them with If-None-Match or If-Modified-Since; the
+	// policies can be overridden per route with CACHE_CONTROL_<route>.
+	categoryCache := middleware.CacheControl("CATEGORIES", "public, max-age=300")
+	productListCache := middleware.CacheControl("PRODUCTS", "public, no-cache")
+	productCache := middleware.CacheControl("PRODUCT_DETAIL", "public, no-cache")
 
-	router.GET("/products", productCtrl.GetAllProducts)
-	router.GET("/products/filter", productCtrl.FilterProducts)
-	router.GET("/products/favorite", productCtrl.GetFavoriteProducts)
-	router.GET("/products/:id", productCtrl.GetProductByID)
-	router.GET("/products/:id/detail", productDetailCtrl.GetProductDetail)
+	api.GET("/categories", categoryCache, ctrls.category.GetCategories)
+	api.GET("/categories/:id", categoryCache, ctrls.category.GetCategoryByID)
 
-	profileRoutes := router.Group("/profile")
-	profileRoutes.Use(middleware.AuthMiddleware())
+	// Signed-in customers see which products they favourited, and their views
+	// are recorded.
+	productRoutes := api.Group("/products")
+	productRoutes.Use(middleware.OptionalAuthMiddleware(languages))
 	{
-		profileRoutes.GET("", profileCtrl.GetProfile)
-		profileRoutes.PATCH("", profileCtrl.UpdateProfile)
+		productRoutes.GET("", productListCache, ctrls.product.GetAllProducts)
+		productRoutes.GET("/filter", productListCache, ctrls.product.FilterProducts)
+		productRoutes.GET("/suggest", ctrls.product.SuggestProducts)
+		productRoutes.GET("/featured", productListCache, ctrls.product.GetFeaturedProducts)
+		// The featured list's path from before customers had favourites.
+		productRoutes.GET("/favorite", productListCache, ctrls.product.GetFeaturedProducts)
+		productRoutes.GET("/:id", productCache, ctrls.product.GetProductByID)
+		productRoutes.GET("/:id/detail", productCache, ctrls.productDetail.GetProductDetail)
+		productRoutes.GET("/:id/recommendations", ctrls.productDetail.GetProductRecommendations)
+		productRoutes.GET("/:id/reviews", ctrls.productDetail.GetProductReviews)
+		productRoutes.POST("/:id/reviews", middleware.AuthMiddleware(languages), ctrls.productDetail.CreateReview)
+		productRoutes.PATCH("/:id/reviews/:reviewId", middleware.AuthMiddleware(languages), ctrls.productDetail.UpdateReview)
+		productRoutes.DELETE("/:id/reviews/:reviewId", middleware.AuthMiddleware(languages), ctrls.productDetail.DeleteReview)
 	}
 
-	cartRoutes := router.Group("/cart")
-	cartRoutes.Use(middleware.AuthMiddleware())
+	api.GET("/flash-sales/active", ctrls.flashSale.GetActiveFlashSales)
+	api.GET("/promos", ctrls.promo.GetAllPromos)
+
+	profileRoutes := api.Group("/profile")
+	profileRoutes.Use(middleware.AuthMiddleware(languages))
 	{
-		cartRoutes.POST("", productDetailCtrl.AddToCart)
-		cartRoutes.GET("", productDetailCtrl.GetCart)
+		profileRoutes.GET("", ctrls.profile.GetProfile)
+		profileRoutes.PATCH("", ctrls.profile.UpdateProfile)
+		profileRoutes.GET("/favorites", ctrls.favorite.GetFavorites)
+		profileRoutes.POST("/favorites/:productId", ctrls.favorite.AddFavorite)
+		profileRoutes.DELETE("/favorites/:productId", ctrls.favorite.RemoveFavorite)
+		profileRoutes.GET("/recently-viewed", ctrls.productView.GetRecentlyViewed)
 	}
 
-	orderRoutes := router.Group("/orders")
-	orderRoutes.Use(middleware.AuthMiddleware())
+	cartRoutes := api.Group("/cart")
+	cartRoutes.Use(middleware.AuthMiddleware(languages))
 	{
-		orderRoutes.POST("", orderCtrl.CreateOrder)
-		orderRoutes.GET("/:id/detail", orderDetailCtrl.GetOrderDetail)
+		cartRoutes.POST("", ctrls.productDetail.AddToCart)
+		cartRoutes.GET("", ctrls.productDetail.GetCart)
+		cartRoutes.GET("/recommendations", ctrls.productDetail.GetCartRecommendations)
 	}
 
-	transactionRoutes := router.Group("/transactions")
-	transactionRoutes.Use(middleware.AuthMiddleware())
+	orderRoutes := api.Group("/orders")
+	orderRoutes.Use(middleware.AuthMiddleware(languages))
 	{
-		transactionRoutes.POST("/checkout", transactionCtrl.Checkout)
+		orderRoutes.POST("", ctrls.order.CreateOrder)
+		orderRoutes.GET("/:id/detail", ctrls.orderDetail.GetOrderDetail)
 	}
 
-	historyRoutes := router.Group("/history")
-	historyRoutes.Use(middleware.AuthMiddleware())
+	transactionRoutes := api.Group("/transactions")
+	transactionRoutes.Use(middleware.AuthMiddleware(languages))
 	{
-		historyRoutes.GET("", historyCtrl.GetHistory)
+		transactionRoutes.POST("/checkout", ctrls.transaction.Checkout)
 	}
 
-	admin := router.Group("/admin")
-	admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	historyRoutes := api.Group("/history")
+	historyRoutes.Use(middleware.AuthMiddleware(languages))
 	{
-		admin.GET("/profile", profileCtrl.GetProfile)
-		admin.PATCH("/profile", profileCtrl.UpdateProfile)
-
-		admin.GET("/users", userCtrl.GetAllUsers)
-		admin.GET("/users/:id", userCtrl.GetUserByID)
-		admin.POST("/users", userCtrl.CreateUser)
-		admin.PATCH("/users/:id", userCtrl.UpdateUser)
-		admin.DELETE("/users/:id", userCtrl.DeleteUser)
-
-		admin.POST("/categories", categoryCtrl.CreateCategory)
-		admin.PATCH("/categories/:id", categoryCtrl.UpdateCategory)
-		admin.DELETE("/categories/:id", categoryCtrl.DeleteCategory)
-
-		admin.POST("/products", productCtrl.CreateProduct)
-		admin.PATCH("/products/:id", productCtrl.UpdateProduct)
-		admin.DELETE("/products/:id", productCtrl.DeleteProduct)
-
-		admin.GET("/orders", orderCtrl.GetAllOrders)
-		admin.GET("/orders/:id", orderCtrl.GetOrderByID)
-		admin.PATCH("/orders/:id/status", orderCtrl.UpdateOrderStatus)
+		historyRoutes.GET("", ctrls.history.GetHistory)
 	}
 
-	router.Static("/uploads", "./uploads")
+	admin := api.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(languages), middleware.AdminMiddleware())
+	{
+		admin.GET("/profile", ctrls.profile.GetProfile)
+		admin.PATCH("/profile", ctrls.profile.UpdateProfile)
+
+		admin.GET("/users", ctrls.user.GetAllUsers)
+		admin.GET("/users/:id", ctrls.user.GetUserByID)
+		admin.POST("/users", ctrls.user.CreateUser)
+		admin.PATCH("/users/:id", ctrls.user.UpdateUser)
+		admin.DELETE("/users/:id", ctrls.user.DeleteUser)
+
+		admin.POST("/categories", ctrls.category.CreateCategory)
+		admin.PATCH("/categories/:id", ctrls.category.UpdateCategory)
+		admin.DELETE("/categories/:id", ctrls.category.DeleteCategory)
+		admin.GET("/categories/:id/translations", ctrls.translation.GetCategoryTranslations)
+		admin.PUT("/categories/:id/translations/:locale", ctrls.translation.UpsertCategoryTranslation)
+		admin.DELETE("/categories/:id/translations/:locale", ctrls.translation.DeleteCategoryTranslation)
+
+		admin.POST("/products", ctrls.product.CreateProduct)
+		admin.PATCH("/products/:id", ctrls.product.UpdateProduct)
+		admin.DELETE("/products/:id", ctrls.product.DeleteProduct)
+		admin.GET("/products/archived", ctrls.product.GetArchivedProducts)
+		admin.GET("/products/export", ctrls.product.ExportProducts)
+		admin.POST("/products/import", ctrls.product.ImportProducts)
+		admin.POST("/products/:id/restore", ctrls.product.RestoreProduct)
+		admin.DELETE("/products/:id/purge", ctrls.product.PurgeProduct)
+		admin.GET("/products/:id/images", ctrls.gallery.GetProductImages)
+		admin.POST("/products/:id/images", ctrls.gallery.UploadProductImages)
+		admin.PUT("/products/:id/images/order", ctrls.gallery.ReorderProductImages)
+		admin.PATCH("/products/:id/images/:imageId/primary", ctrls.gallery.SetPrimaryProductImage)
+		admin.DELETE("/products/:id/images/:imageId", ctrls.gallery.DeleteProductImage)
+		admin.GET("/products/:id/options", ctrls.option.GetProductOptions)
+		admin.POST("/products/:id/options", ctrls.option.CreateProductOption)
+		admin.PATCH("/products/:id/options/:optionId", ctrls.option.UpdateProductOption)
+		admin.DELETE("/products/:id/options/:optionId", ctrls.option.DeleteProductOption)
+		admin.GET("/products/:id/recommendations", ctrls.recommendation.GetCuratedRecommendations)
+		admin.POST("/products/:id/recommendations", ctrls.recommendation.CreateRecommendation)
+		admin.PATCH("/products/:id/recommendations/:recommendationId", ctrls.recommendation.UpdateRecommendation)
+		admin.DELETE("/products/:id/recommendations/:recommendationId", ctrls.recommendation.DeleteRecommendation)
+		admin.GET("/products/:id/stock-movements", ctrls.inventory.GetStockMovements)
+		admin.POST("/products/:id/restock", ctrls.inventory.RestockProduct)
+		admin.POST("/products/:id/stocktake", ctrls.inventory.StocktakeProduct)
+		admin.POST("/products/:id/waste", ctrls.inventory.WasteProduct)
+		admin.GET("/inventory/low-stock", ctrls.inventory.GetLowStockProducts)
+		admin.GET("/products/:id/price-history", ctrls.price.GetPriceHistory)
+		admin.GET("/products/:id/price", ctrls.price.GetPriceAt)
+		admin.GET("/products/:id/scheduled-prices", ctrls.price.GetScheduledPrices)
+		admin.POST("/products/:id/scheduled-prices", ctrls.price.SchedulePrice)
+		admin.DELETE("/products/:id/scheduled-prices/:changeId", ctrls.price.CancelScheduledPrice)
+		admin.GET("/scheduled-prices", ctrls.price.GetAllScheduledPrices)
+		admin.GET("/products/:id/translations", ctrls.translation.GetProductTranslations)
+		admin.PUT("/products/:id/translations/:locale", ctrls.translation.UpsertProductTranslation)
+		admin.DELETE("/products/:id/translations/:locale", ctrls.translation.DeleteProductTranslation)
+
+		admin.GET("/flash-sales", ctrls.flashSale.GetFlashSales)
+		admin.POST("/flash-sales", ctrls.flashSale.CreateFlashSale)
+		admin.GET("/flash-sales/:id", ctrls.flashSale.GetFlashSale)
+		admin.PUT("/flash-sales/:id", ctrls.flashSale.UpdateFlashSale)
+		admin.DELETE("/flash-sales/:id", ctrls.flashSale.DeleteFlashSale)
+
+		admin.GET("/promotion-rules", ctrls.promotion.GetPromotionRules)
+		admin.POST("/promotion-rules", ctrls.promotion.CreatePromotionRule)
+		admin.GET("/promotion-rules/:id", ctrls.promotion.GetPromotionRule)
+		admin.PUT("/promotion-rules/:id", ctrls.promotion.UpdatePromotionRule)
+		admin.DELETE("/promotion-rules/:id", ctrls.promotion.DeletePromotionRule)
+
+		admin.GET("/reviews", ctrls.productDetail.GetReviewQueue)
+		admin.PATCH("/reviews/:id", ctrls.productDetail.ModerateReview)
+		admin.GET("/search-queries", ctrls.searchQuery.GetSearchQueries)
+		admin.PATCH("/search-queries/:id", ctrls.searchQuery.ModerateSearchQuery)
+
+		admin.GET("/orders", ctrls.order.GetAllOrders)
+		admin.GET("/orders/:id", ctrls.order.GetOrderByID)
+		admin.PATCH("/orders/:id/status", ctrls.order.UpdateOrderStatus)
+
+		admin.GET("/audit-log", ctrls.audit.GetAuditLog)
+	}
 }
@@ -0,0 +1,148 @@
+// Package search holds the query parsing and highlighting shared by the
+// Postgres product search and the in-memory store.
+//
+// Postgres does the matching: a weighted tsvector over name, category and
+// description answers prefix queries built by PrefixQuery, and pg_trgm
+// word similarity on the name catches typos such as "esspreso". Highlight
+// then marks the words of a result that match the query, using the same
+// prefix-or-typo rule in Go so it also works on translated text.
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MarkOpen and MarkClose surround matched words in highlighted text.
+	MarkOpen  = "<mark>"
+	MarkClose = "</mark>"
+)
+
+// MaxQueryLength caps a normalized query, in runes.
+const MaxQueryLength = 100
+
+// Normalize lower-cases q, collapses runs of whitespace and trims it to
+// MaxQueryLength, so equal searches are recorded and cached under one key.
+func Normalize(q string) string {
+	q = strings.Join(strings.Fields(strings.ToLower(q)), " ")
+	if runes := []rune(q); len(runes) > MaxQueryLength {
+		q = strings.TrimSpace(string(runes[:MaxQueryLength]))
+	}
+	return q
+}
+
+// Terms splits q into lower-case words of letters and digits.
+func Terms(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), isSeparator)
+}
+
+// PrefixQuery builds a to_tsquery expression matching every term as a
+// prefix, e.g. "esp:* & lat:*". It returns "" when terms is empty.
+func PrefixQuery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		parts = append(parts, t+":*")
+	}
+	return strings.Join(parts, " & ")
+}
+
+// Matches reports whether word matches term, either as a prefix or within
+// a small edit distance that grows with the length of the term.
+func Matches(word, term string) bool {
+	word = strings.ToLower(word)
+	if term == "" {
+		return false
+	}
+	if strings.HasPrefix(word, term) {
+		return true
+	}
+	return distance(word, term) <= tolerance(term)
+}
+
+// Score counts the terms that match a word of text.
+func Score(text string, terms []string) int {
+	words := strings.FieldsFunc(text, isSeparator)
+	score := 0
+	for _, term := range terms {
+		for _, w := range words {
+			if Matches(w, term) {
+				score++
+				break
+			}
+		}
+	}
+	return score
+}
+
+// Highlight HTML-escapes text and wraps every word matching one of terms in
+// MarkOpen and MarkClose. ok is false when no word matched.
+func Highlight(text string, terms []string) (highlighted string, ok bool) {
+	var b strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && isSeparator(runes[j]) == isSeparator(runes[i]) {
+			j++
+		}
+		chunk := string(runes[i:j])
+		if !isSeparator(runes[i]) && matchesAny(chunk, terms) {
+			b.WriteString(MarkOpen + html.EscapeString(chunk) + MarkClose)
+			ok = true
+		} else {
+			b.WriteString(html.EscapeString(chunk))
+		}
+		i = j
+	}
+	return b.String(), ok
+}
+
+func matchesAny(word string, terms []string) bool {
+	for _, t := range terms {
+		if Matches(word, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// tolerance is the number of edits allowed for a term: none for short
+// words, where a single edit changes the meaning, one from four letters and
+// two from seven.
+func tolerance(term string) int {
+	switch n := len([]rune(term)); {
+	case n >= 7:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// distance is the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize("  Iced\tLATTE   oat "); got != "iced latte oat" {
+		t.Fatalf("Normalize = %q", got)
+	}
+	if got := Normalize(strings.Repeat("a", MaxQueryLength+20)); len(got) != MaxQueryLength {
+		t.Fatalf("Normalize kept %d runes, want %d", len(got), MaxQueryLength)
+	}
+}
+
+func TestTermsAndPrefixQuery(t *testing.T) {
+	terms := Terms("  Caffè-Latte, ice!  ")
+	if len(terms) != 3 || terms[0] != "caffè" || terms[1] != "latte" || terms[2] != "ice" {
+		t.Fatalf("Terms = %q", terms)
+	}
+	if q := PrefixQuery(terms); q != "caffè:* & latte:* & ice:*" {
+		t.Fatalf("PrefixQuery = %q", q)
+	}
+	if q := PrefixQuery(Terms("'&|!")); q != "" {
+		t.Fatalf("PrefixQuery of punctuation = %q", q)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	cases := []struct {
+		word, term string
+		want       bool
+	}{
+		{"Espresso", "esp", true},
+		{"Espresso", "esspreso", true},
+		{"Latte", "latte", true},
+		{"Latte", "late", true},
+		{"Tea", "tee", false},
+		{"Mocha", "matcha", false},
+	}
+	for _, tc := range cases {
+		if got := Matches(tc.word, tc.term); got != tc.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tc.word, tc.term, got, tc.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got, ok := Highlight("Iced <b>Espresso</b> & milk", Terms("esspreso milk"))
+	want := "Iced &lt;b&gt;<mark>Espresso</mark>&lt;/b&gt; &amp; <mark>milk</mark>"
+	if !ok || got != want {
+		t.Fatalf("Highlight = %q, %v\nwant %q", got, ok, want)
+	}
+
+	if _, ok := Highlight("Green tea", Terms("coffee")); ok {
+		t.Fatal("unexpected match")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"coffee-shop/cache"
+	"coffee-shop/docs"
+	"coffee-shop/middleware"
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"coffee-shop/routes"
+	"coffee-shop/services"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultPriceSchedulerInterval is how often serve applies scheduled price
+// changes unless PRICE_SCHEDULER_INTERVAL says otherwise.
+const defaultPriceSchedulerInterval = time.Minute
+
+// defaultRecommendationRefreshInterval is how often serve recounts the
+// products bought together and their popularity unless
+// RECOMMENDATION_REFRESH_INTERVAL says otherwise.
+const defaultRecommendationRefreshInterval = time.Hour
+
+// serve starts the HTTP API. Pending migrations are applied first only when
+// AUTO_MIGRATE is enabled.
+func serve() error {
+	interval, err := intervalFromEnv("PRICE_SCHEDULER_INTERVAL", defaultPriceSchedulerInterval)
+	if err != nil {
+		return err
+	}
+	refreshInterval, err := intervalFromEnv("RECOMMENDATION_REFRESH_INTERVAL", defaultRecommendationRefreshInterval)
+	if err != nil {
+		return err
+	}
+
+	models.InitDB()
+	defer models.CloseDB()
+	if err := models.PrepareSchema(); err != nil {
+		return err
+	}
+
+	models.InitRedis()
+	defer models.CloseRedis()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	if interval > 0 {
+		go newProductService().RunPriceScheduler(ctx, interval)
+		log.Printf("Applying scheduled prices every %s", interval)
+	}
+	if refreshInterval > 0 {
+		go newProductService().RunRecommendationRefresher(ctx, refreshInterval)
+		log.Printf("Refreshing recommendations and popularity every %s", refreshInterval)
+	}
+
+	if os.Getenv("GIN_MODE") == "release" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.Default()
+	router.Use(gin.Recovery())
+	router.Use(middleware.CORSMiddleware())
+
+	docs.SwaggerInfo.Title = "Coffee Shop API"
+	docs.SwaggerInfo.Description = "Coffee Shop Management System API"
+	docs.SwaggerInfo.Version = "1.0"
+
+	swaggerHost := os.Getenv("SWAGGER_HOST")
+	if swaggerHost == "" {
+		swaggerHost = "localhost:8083"
+	}
+	docs.SwaggerInfo.Host = swaggerHost
+	docs.SwaggerInfo.BasePath = "/"
+	docs.SwaggerInfo.Schemes = []string{"http", "https"}
+
+	routes.SetupRoutes(router)
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8083"
+	}
+
+	log.Printf("Server starting on port %s", port)
+	log.Printf("Swagger documentation available at: http://localhost:%s/swagger/index.html", port)
+
+	if err := router.Run(":" + port); err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+	return nil
+}
+
+// intervalFromEnv reads a background job's interval from the environment
+// variable, a duration such as 30s; 0 turns the job off and an unset
+// variable means fallback.
+func intervalFromEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return interval, nil
+}
+
+// newProductService returns a ProductService on the PostgreSQL pool and Redis
+// client configured at startup.
+func newProductService() *services.ProductService {
+	return services.NewProductService(repositories.NewPostgresStore(models.DB), services.NewCloudinaryImages(),
+		cache.NewRedis(models.RedisClient))
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"strings"
+)
+
+// AuditService reads the audit log the other services write.
+type AuditService struct {
+	store repositories.Store
+}
+
+func NewAuditService(store repositories.Store) *AuditService {
+	return &AuditService{store: store}
+}
+
+// List returns one page of the audit entries matching filter, newest first,
+// and the number of matching entries.
+func (s *AuditService) List(ctx context.Context, filter repositories.AuditFilter, page pagination.Params) ([]models.AuditLog, int, error) {
+	filter.EntityType = strings.ToLower(strings.TrimSpace(filter.EntityType))
+	entries, total, err := s.store.Audit().List(ctx, filter, page)
+	if err != nil {
+		return nil, 0, fail("Failed to retrieve audit log", err)
+	}
+	return entries, total, nil
+}
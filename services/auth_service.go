@@ -0,0 +1,266 @@
+package services
+
+import (
+	"coffee-shop/i18n"
+	"coffee-shop/libs"
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"crypto/rand"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// otpTTL is how long a password reset OTP stays valid.
+const otpTTL = 5 * time.Minute
+
+// ErrOTPUnavailable is returned by an OTPStore without a backend.
+var ErrOTPUnavailable = errors.New("OTP store not available")
+
+// OTPStore keeps the one-time passwords sent for password resets, by email.
+// Unlike the response cache it reports backend failures: an OTP that is
+// silently lost locks the user out.
+type OTPStore interface {
+	Save(ctx context.Context, email, otp string, ttl time.Duration) error
+	// Get returns the OTP pending for email, or "" when there is none.
+	Get(ctx context.Context, email string) (string, error)
+	Delete(ctx context.Context, email string) error
+}
+
+// NewRedisOTPs returns an OTPStore backed by client. A nil client, as left
+// by a failed Redis connection, makes every call fail with ErrOTPUnavailable.
+func NewRedisOTPs(client *redis.Client) OTPStore {
+	return redisOTPs{client: client}
+}
+
+type redisOTPs struct {
+	client *redis.Client
+}
+
+func otpKey(email string) string {
+	return "otp:" + strings.ToLower(email)
+}
+
+func (s redisOTPs) Save(ctx context.Context, email, otp string, ttl time.Duration) error {
+	if s.client == nil {
+		return ErrOTPUnavailable
+	}
+	return s.client.Set(ctx, otpKey(email), otp, ttl).Err()
+}
+
+func (s redisOTPs) Get(ctx context.Context, email string) (string, error) {
+	if s.client == nil {
+		return "", ErrOTPUnavailable
+	}
+	otp, err := s.client.Get(ctx, otpKey(email)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return otp, err
+}
+
+func (s redisOTPs) Delete(ctx context.Context, email string) error {
+	if s.client == nil {
+		return ErrOTPUnavailable
+	}
+	return s.client.Del(ctx, otpKey(email)).Err()
+}
+
+// AuthService registers users, signs them in and resets forgotten passwords.
+type AuthService struct {
+	store repositories.Store
+	otps  OTPStore
+}
+
+func NewAuthService(store repositories.Store, otps OTPStore) *AuthService {
+	return &AuthService{store: store, otps: otps}
+}
+
+// RegisterInput is a self-service sign-up.
+type RegisterInput struct {
+	Email    string
+	Password string
+	FullName string
+	Phone    string
+	// Role defaults to customer.
+	Role     string
+	Language string
+}
+
+func (s *AuthService) Register(ctx context.Context, in RegisterInput) (models.UserWithProfile, error) {
+	in.Email = strings.TrimSpace(in.Email)
+	in.FullName = strings.TrimSpace(in.FullName)
+	in.Phone = strings.TrimSpace(in.Phone)
+	in.Role = strings.TrimSpace(in.Role)
+	if in.Role == "" {
+		in.Role = "customer"
+	}
+	language := i18n.Normalize(in.Language)
+
+	switch {
+	case !emailPattern.MatchString(in.Email):
+		return models.UserWithProfile{}, invalid("Invalid email format")
+	case len(in.Password) < 6:
+		return models.UserWithProfile{}, invalid("Password must be at least 6 characters")
+	case len(in.FullName) < 3:
+		return models.UserWithProfile{}, invalid("Full name must be at least 3 characters")
+	case in.Phone != "" && !profilePhonePattern.MatchString(in.Phone):
+		return models.UserWithProfile{}, invalid("Invalid phone number")
+	case in.Role != "customer" && in.Role != "admin":
+		return models.UserWithProfile{}, invalid("Role must be 'customer' or 'admin'")
+	case in.Language != "" && language == "":
+		return models.UserWithProfile{}, invalid("Unsupported language")
+	}
+
+	exists, err := s.store.Users().EmailExists(ctx, in.Email, 0)
+	if err != nil {
+		return models.UserWithProfile{}, fail("Failed to check existing user", err)
+	}
+	if exists {
+		return models.UserWithProfile{}, invalid("Email already exists")
+	}
+
+	hash, err := libs.HashPassword(in.Password)
+	if err != nil {
+		return models.UserWithProfile{}, fail("Registration failed", err)
+	}
+
+	var created models.UserWithProfile
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		id, err := tx.Users().Create(ctx, repositories.NewUser{
+			Email:        in.Email,
+			PasswordHash: hash,
+			Role:         in.Role,
+			FullName:     in.FullName,
+			Phone:        in.Phone,
+			Language:     language,
+		})
+		if err != nil {
+			return err
+		}
+		created, err = tx.Users().Get(ctx, id)
+		return err
+	})
+	if err != nil {
+		return models.UserWithProfile{}, fail("Registration failed", err)
+	}
+	return created, nil
+}
+
+// Login checks the user's password and returns their account.
+func (s *AuthService) Login(ctx context.Context, email, password string) (Profile, error) {
+	email = strings.TrimSpace(email)
+	if !emailPattern.MatchString(email) {
+		return Profile{}, invalid("Invalid email format")
+	}
+	if password == "" {
+		return Profile{}, invalid("Password is required")
+	}
+
+	rejected := unauthorized("Invalid email or password")
+	u, err := s.store.Users().GetByEmail(ctx, email)
+	if err != nil {
+		return Profile{}, rejected
+	}
+	hash, err := s.store.Users().PasswordHash(ctx, u.ID)
+	if err != nil || !libs.VerifyPassword(hash, password) {
+		return Profile{}, rejected
+	}
+
+	// The account is usable without a language, so a failed read only
+	// loses the preference.
+	language, err := s.store.Users().Language(ctx, u.ID)
+	if err != nil {
+		log.Printf("Language of user %d not read: %v", u.ID, err)
+	}
+	return Profile{UserWithProfile: u, Language: language}, nil
+}
+
+// SendPasswordReset mails an OTP to email when it belongs to a user, in
+// their saved language or else locale. Unknown emails succeed too, so the
+// response does not reveal which emails are registered.
+func (s *AuthService) SendPasswordReset(ctx context.Context, email, locale string) error {
+	email = strings.TrimSpace(email)
+	u, err := s.store.Users().GetByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+
+	otp, err := generateOTP(6)
+	if err != nil {
+		return fail("Failed to generate OTP", err)
+	}
+	if err := s.otps.Save(ctx, email, otp, otpTTL); err != nil {
+		if errors.Is(err, ErrOTPUnavailable) {
+			return fail("OTP service unavailable", err)
+		}
+		return fail("Failed to store OTP", err)
+	}
+
+	emailService, err := models.NewEmailService()
+	if err != nil {
+		log.Printf("SMTP not configured; OTP for %s is %s, valid for %s", email, otp, otpTTL)
+		return nil
+	}
+	if language, _ := s.store.Users().Language(ctx, u.ID); language != "" {
+		locale = language
+	}
+	if err := emailService.SendOTPEmail(email, otp, locale); err != nil {
+		log.Printf("OTP email to %s not sent: %v", email, err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password for email once otp matches the one sent
+// to it. The OTP can only be used once.
+func (s *AuthService) ResetPassword(ctx context.Context, email, otp, password string) error {
+	email = strings.TrimSpace(email)
+	otp = strings.TrimSpace(otp)
+
+	stored, err := s.otps.Get(ctx, email)
+	if errors.Is(err, ErrOTPUnavailable) {
+		return fail("OTP service unavailable", err)
+	}
+	if err != nil {
+		return fail("Failed to verify OTP", err)
+	}
+	if stored == "" || stored != otp {
+		return invalid("OTP is invalid or expired")
+	}
+	if len(password) < 6 {
+		return invalid("Password must be at least 6 characters")
+	}
+
+	u, err := s.store.Users().GetByEmail(ctx, email)
+	if err != nil {
+		return invalid("OTP is invalid or expired")
+	}
+	hash, err := libs.HashPassword(password)
+	if err != nil {
+		return fail("Failed to reset password", err)
+	}
+	if err := s.store.Users().SetPassword(ctx, u.ID, hash); err != nil {
+		return fail("Failed to reset password", err)
+	}
+
+	if err := s.otps.Delete(ctx, email); err != nil {
+		log.Printf("Used OTP for %s not deleted: %v", email, err)
+	}
+	return nil
+}
+
+func generateOTP(length int) (string, error) {
+	const digits = "0123456789"
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = digits[int(b[i])%len(digits)]
+	}
+	return string(b), nil
+}
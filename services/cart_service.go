@@ -0,0 +1,189 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"time"
+)
+
+type CartService struct {
+	store repositories.Store
+}
+
+func NewCartService(store repositories.Store) *CartService {
+	return &CartService{store: store}
+}
+
+func (s *CartService) Get(ctx context.Context, userID int, locale string) (models.CartV2, error) {
+	items, err := s.store.Carts().Items(ctx, userID, locale)
+	if err != nil {
+		return models.CartV2{}, fail("Failed to retrieve cart: %v", err)
+	}
+
+	productIDs := make([]int, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	options, err := productOptions(ctx, s.store, productIDs...)
+	if err != nil {
+		return models.CartV2{}, fail("Failed to retrieve cart: %v", err)
+	}
+
+	prices, err := flashSalePrices(ctx, s.store, time.Now())
+	if err != nil {
+		return models.CartV2{}, fail("Failed to retrieve cart: %v", err)
+	}
+
+	rules, err := s.store.Promotions().Running(ctx, time.Now())
+	if err != nil {
+		return models.CartV2{}, fail("Failed to retrieve cart: %v", err)
+	}
+
+	cart := models.CartV2{Items: items, Adjustments: []models.CartAdjustmentV2{}}
+	lines := make([]promotionLine, len(cart.Items))
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		item.RegularPrice = item.BasePrice
+		if price, ok := prices[item.ProductID]; ok {
+			item.BasePrice = price.Price
+			item.FlashSale = models.NewFlashSalePriceV2(&price)
+		}
+		item.OptionPrice, item.Available = optionPrice(options[item.ProductID], item.SizeID, item.TemperatureID, item.VariantID)
+		lines[i] = promotionLine{ProductID: item.ProductID, CategoryID: item.CategoryID, IsBuy1Get1: item.IsBuy1Get1,
+			UnitPrice: item.BasePrice + item.OptionPrice, Quantity: item.Quantity,
+			Excluded: !item.Available || item.FlashSale != nil}
+		if !item.Available {
+			continue
+		}
+		item.Subtotal = (item.BasePrice + item.OptionPrice) * item.Quantity
+		cart.Subtotal += item.Subtotal
+	}
+	for i, adj := range applyPromotions(lines, rules) {
+		if adj == nil {
+			continue
+		}
+		cart.Adjustments = append(cart.Adjustments, models.CartAdjustmentV2{CartItemID: cart.Items[i].ID,
+			PromotionRuleID: adj.PromotionRuleID, Name: adj.Name, Quantity: adj.Quantity, Amount: adj.Amount})
+		cart.Discount += adj.Amount
+	}
+	cart.Total = cart.Subtotal - cart.Discount
+	return cart, nil
+}
+
+// Add puts quantity units of the product into the cart, merging with a line
+// that has the same options. created reports whether a new line was added.
+func (s *CartService) Add(ctx context.Context, key repositories.CartItemKey, quantity int) (ref models.CartItemRefV2, created bool, err error) {
+	if key.ProductID <= 0 {
+		return ref, false, invalid("Invalid product ID")
+	}
+	if quantity <= 0 {
+		return ref, false, invalid("Invalid quantity")
+	}
+
+	product, err := s.store.Products().Get(ctx, key.ProductID)
+	if err != nil || !product.IsActive {
+		return ref, false, invalid("Product not found or inactive")
+	}
+	if product.Stock < quantity {
+		return ref, false, invalid("Insufficient stock. Available: %d", product.Stock)
+	}
+	options, err := productOptions(ctx, s.store, key.ProductID)
+	if err != nil {
+		return ref, false, fail("Failed to add to cart: %v", err)
+	}
+	if _, ok := optionPrice(options[key.ProductID], key.SizeID, key.TemperatureID, key.VariantID); !ok {
+		return ref, false, invalid("This product is not available with the chosen options")
+	}
+
+	if err := s.checkFlashSaleLimit(ctx, key, product.Name, quantity); err != nil {
+		return ref, false, err
+	}
+
+	carts := s.store.Carts()
+	existingID, existingQty, err := carts.FindItem(ctx, key)
+	switch {
+	case err == nil:
+		newQty := existingQty + quantity
+		if product.Stock < newQty {
+			return ref, false, invalid("Insufficient stock. Available: %d, Current cart: %d", product.Stock, existingQty)
+		}
+		if err := carts.SetQuantity(ctx, existingID, newQty); err != nil {
+			return ref, false, fail("Failed to update cart: %v", err)
+		}
+		return models.CartItemRefV2{CartItemID: existingID, Quantity: newQty}, false, nil
+
+	case errors.Is(err, repositories.ErrNotFound):
+		id, err := carts.AddItem(ctx, key, quantity)
+		if err != nil {
+			return ref, false, fail("Failed to add to cart: %v", err)
+		}
+		return models.CartItemRefV2{CartItemID: id, Quantity: quantity}, true, nil
+
+	default:
+		return ref, false, fail("Failed to update cart: %v", err)
+	}
+}
+
+// checkFlashSaleLimit refuses the units when the product is on a flash sale
+// and they take the user past its limit, counting every line of the product
+// already in the cart.
+func (s *CartService) checkFlashSaleLimit(ctx context.Context, key repositories.CartItemKey, name string, quantity int) error {
+	prices, err := flashSalePrices(ctx, s.store, time.Now())
+	if err != nil {
+		return fail("Failed to add to cart: %v", err)
+	}
+	price, ok := prices[key.ProductID]
+	if !ok || price.MaxPerCustomer == nil {
+		return nil
+	}
+	items, err := s.store.Carts().Items(ctx, key.UserID, "")
+	if err != nil {
+		return fail("Failed to add to cart: %v", err)
+	}
+	for _, item := range items {
+		if item.ProductID == key.ProductID {
+			quantity += item.Quantity
+		}
+	}
+	return checkFlashSaleLimit(ctx, s.store, key.UserID, price, key.ProductID, name, quantity)
+}
+
+// productOptions loads the option matrix of the products, keyed by product.
+func productOptions(ctx context.Context, store repositories.Store, productIDs ...int) (map[int][]models.ProductOption, error) {
+	byProduct := map[int][]models.ProductOption{}
+	if len(productIDs) == 0 {
+		return byProduct, nil
+	}
+	options, err := store.Products().Options(ctx, productIDs...)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range options {
+		byProduct[o.ProductID] = append(byProduct[o.ProductID], o)
+	}
+	return byProduct, nil
+}
+
+// optionPrice returns the surcharge of a product in the chosen combination
+// and whether the product is sold that way at all: the combination must be
+// an active option, and a product without options is only sold plain.
+func optionPrice(options []models.ProductOption, sizeID, temperatureID, variantID *int) (int, bool) {
+	if len(options) == 0 {
+		return 0, sizeID == nil && temperatureID == nil && variantID == nil
+	}
+	for _, o := range options {
+		if o.IsActive && sameOption(o.SizeID, sizeID) && sameOption(o.TemperatureID, temperatureID) && sameOption(o.VariantID, variantID) {
+			return o.PriceAdjustment, true
+		}
+	}
+	return 0, false
+}
+
+func sameOption(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
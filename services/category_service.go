@@ -0,0 +1,152 @@
+package services
+
+import (
+	"coffee-shop/cache"
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"strings"
+)
+
+// CategoryCacheNamespace holds the cached category responses.
+const CategoryCacheNamespace = "categories"
+
+type CategoryService struct {
+	store repositories.Store
+	cache cache.Store
+}
+
+func NewCategoryService(store repositories.Store, cache cache.Store) *CategoryService {
+	return &CategoryService{store: store, cache: cache}
+}
+
+func (s *CategoryService) List(ctx context.Context, locale string) ([]models.Category, error) {
+	categories, err := s.store.Categories().List(ctx, locale)
+	if err != nil {
+		return nil, fail("Failed to retrieve categories", err)
+	}
+	return categories, nil
+}
+
+func (s *CategoryService) Get(ctx context.Context, id int, locale string) (models.Category, error) {
+	category, err := s.store.Categories().Get(ctx, id, locale)
+	if err != nil {
+		return models.Category{}, notFound("Category not found")
+	}
+	return category, nil
+}
+
+func (s *CategoryService) Create(ctx context.Context, actor Actor, name string) (models.Category, error) {
+	name = strings.TrimSpace(name)
+	if err := s.validateName(ctx, name, 0); err != nil {
+		return models.Category{}, err
+	}
+
+	category := models.Category{Name: name}
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Categories().Create(ctx, &category); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionCreate, models.AuditEntityCategory, category.ID,
+			nil, newCategoryAuditSnapshot(category)))
+	})
+	if err != nil {
+		return models.Category{}, fail("Failed to create category", err)
+	}
+	s.invalidate(ctx)
+	return category, nil
+}
+
+func (s *CategoryService) Update(ctx context.Context, actor Actor, id int, name string) error {
+	name = strings.TrimSpace(name)
+	if err := validateCategoryName(name); err != nil {
+		return err
+	}
+
+	before, err := s.store.Categories().Get(ctx, id, "")
+	if err != nil {
+		return notFound("Category not found")
+	}
+	if err := s.validateName(ctx, name, id); err != nil {
+		return err
+	}
+
+	after := before
+	after.Name = name
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Categories().UpdateName(ctx, id, name); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionUpdate, models.AuditEntityCategory, id,
+			newCategoryAuditSnapshot(before), newCategoryAuditSnapshot(after)))
+	})
+	if err != nil {
+		return fail("Failed to update category", err)
+	}
+	s.invalidate(ctx)
+	return nil
+}
+
+func (s *CategoryService) Delete(ctx context.Context, actor Actor, id int) error {
+	before, err := s.store.Categories().Get(ctx, id, "")
+	if err != nil {
+		return notFound("Category not found")
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Categories().Delete(ctx, id); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionDelete, models.AuditEntityCategory, id,
+			newCategoryAuditSnapshot(before), nil))
+	})
+	if errors.Is(err, repositories.ErrNotFound) {
+		return notFound("Category not found")
+	}
+	if err != nil {
+		return fail("Failed to delete category", err)
+	}
+	s.invalidate(ctx)
+	return nil
+}
+
+// invalidate drops the cached categories, and the cached products too:
+// product searches and suggestions match category names.
+func (s *CategoryService) invalidate(ctx context.Context) {
+	cache.NewNamespace(s.cache, CategoryCacheNamespace).Invalidate(ctx)
+	cache.NewNamespace(s.cache, ProductCacheNamespace).Invalidate(ctx)
+}
+
+func (s *CategoryService) validateName(ctx context.Context, name string, excludeID int) error {
+	if err := validateCategoryName(name); err != nil {
+		return err
+	}
+	exists, err := s.store.Categories().NameExists(ctx, name, excludeID)
+	if err != nil {
+		return fail("Failed to create category", err)
+	}
+	if exists {
+		return invalid("Category name already exists")
+	}
+	return nil
+}
+
+func validateCategoryName(name string) error {
+	if name == "" {
+		return invalid("Name is required")
+	}
+	if len(name) < 3 {
+		return invalid("Category name must be at least 3 characters")
+	}
+	return nil
+}
+
+type categoryAuditSnapshot struct {
+	Name     string `json:"name"`
+	IsActive bool   `json:"is_active"`
+}
+
+func newCategoryAuditSnapshot(c models.Category) categoryAuditSnapshot {
+	return categoryAuditSnapshot{Name: c.Name, IsActive: c.IsActive}
+}
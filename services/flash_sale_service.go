@@ -0,0 +1,310 @@
+package services
+
+import (
+	"coffee-shop/cache"
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+type FlashSaleService struct {
+	store repositories.Store
+	cache cache.Store
+}
+
+func NewFlashSaleService(store repositories.Store, cache cache.Store) *FlashSaleService {
+	return &FlashSaleService{store: store, cache: cache}
+}
+
+// FlashSaleInput holds the writable flash sale fields. Only ProductID,
+// SalePrice, DiscountPercent and MaxPerCustomer of each item are read.
+type FlashSaleInput struct {
+	Name     string
+	StartsAt time.Time
+	EndsAt   time.Time
+	IsActive bool
+	Items    []models.FlashSaleItem
+}
+
+func (s *FlashSaleService) List(ctx context.Context) ([]models.FlashSale, error) {
+	sales, err := s.store.FlashSales().List(ctx)
+	if err != nil {
+		return nil, fail("Failed to retrieve flash sales", err)
+	}
+	return sales, nil
+}
+
+func (s *FlashSaleService) Get(ctx context.Context, id int) (models.FlashSale, error) {
+	if id <= 0 {
+		return models.FlashSale{}, invalid("Invalid flash sale ID")
+	}
+	sale, err := s.store.FlashSales().Get(ctx, id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.FlashSale{}, notFound("Flash sale not found")
+	}
+	if err != nil {
+		return models.FlashSale{}, fail("Failed to retrieve flash sales", err)
+	}
+	return sale, nil
+}
+
+// Active returns the flash sales running at the server time, which it also
+// returns so clients can sync their countdowns.
+func (s *FlashSaleService) Active(ctx context.Context, locale string) ([]models.ActiveFlashSale, time.Time, error) {
+	now := time.Now()
+	sales, err := s.store.FlashSales().Current(ctx, now, locale)
+	if err != nil {
+		return nil, now, fail("Failed to retrieve flash sales", err)
+	}
+	active := []models.ActiveFlashSale{}
+	for _, sale := range sales {
+		if sale.RunningAt(now) && len(sale.Items) > 0 {
+			active = append(active, models.ActiveFlashSale{
+				FlashSale:     sale,
+				EndsInSeconds: int64(sale.EndsAt.Sub(now) / time.Second),
+			})
+		}
+	}
+	return active, now, nil
+}
+
+func (s *FlashSaleService) Create(ctx context.Context, actor Actor, in FlashSaleInput) (models.FlashSale, error) {
+	sale, err := s.validate(ctx, in, 0)
+	if err != nil {
+		return models.FlashSale{}, err
+	}
+	if !sale.EndsAt.After(time.Now()) {
+		return models.FlashSale{}, invalid("End time must be in the future")
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.FlashSales().Create(ctx, &sale); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionCreate, models.AuditEntityFlashSale, sale.ID,
+			nil, newFlashSaleAuditSnapshot(sale)))
+	})
+	if err != nil {
+		return models.FlashSale{}, fail("Failed to create flash sale", err)
+	}
+
+	s.invalidate(ctx)
+	return s.Get(ctx, sale.ID)
+}
+
+func (s *FlashSaleService) Update(ctx context.Context, actor Actor, id int, in FlashSaleInput) (models.FlashSale, error) {
+	existing, err := s.Get(ctx, id)
+	if err != nil {
+		return models.FlashSale{}, err
+	}
+	sale, err := s.validate(ctx, in, id)
+	if err != nil {
+		return models.FlashSale{}, err
+	}
+	sale.ID = id
+	sale.CreatedAt = existing.CreatedAt
+	sale.UpdatedAt = time.Now()
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.FlashSales().Update(ctx, sale); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionUpdate, models.AuditEntityFlashSale, id,
+			newFlashSaleAuditSnapshot(existing), newFlashSaleAuditSnapshot(sale)))
+	})
+	if err != nil {
+		return models.FlashSale{}, fail("Failed to update flash sale", err)
+	}
+
+	s.invalidate(ctx)
+	return s.Get(ctx, id)
+}
+
+// Delete removes the flash sale. Orders placed during it keep their prices
+// but lose the link to it.
+func (s *FlashSaleService) Delete(ctx context.Context, actor Actor, id int) error {
+	existing, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.FlashSales().Delete(ctx, id); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionDelete, models.AuditEntityFlashSale, id,
+			newFlashSaleAuditSnapshot(existing), nil))
+	})
+	if err != nil {
+		return fail("Failed to delete flash sale", err)
+	}
+
+	s.invalidate(ctx)
+	return nil
+}
+
+// validate checks in and returns it as a sale. A product may only be in one
+// active flash sale at a time, so overlapping active sales are refused.
+func (s *FlashSaleService) validate(ctx context.Context, in FlashSaleInput, id int) (models.FlashSale, error) {
+	sale := models.FlashSale{
+		Name:     strings.TrimSpace(in.Name),
+		StartsAt: in.StartsAt,
+		EndsAt:   in.EndsAt,
+		IsActive: in.IsActive,
+		Items:    []models.FlashSaleItem{},
+	}
+	if len(sale.Name) < 3 {
+		return sale, invalid("Flash sale name must be at least 3 characters")
+	}
+	if len(sale.Name) > 100 {
+		return sale, invalid("Flash sale name must be at most 100 characters")
+	}
+	if sale.StartsAt.IsZero() || sale.EndsAt.IsZero() {
+		return sale, invalid("Start and end time are required")
+	}
+	if !sale.EndsAt.After(sale.StartsAt) {
+		return sale, invalid("End time must be after the start time")
+	}
+	if len(in.Items) == 0 {
+		return sale, invalid("Add at least one product to the flash sale")
+	}
+
+	seen := map[int]bool{}
+	for _, item := range in.Items {
+		if seen[item.ProductID] {
+			return sale, invalid("Product %d is listed more than once", item.ProductID)
+		}
+		seen[item.ProductID] = true
+
+		p, err := s.store.Products().Get(ctx, item.ProductID)
+		if errors.Is(err, repositories.ErrNotFound) || (err == nil && p.DeletedAt != nil) {
+			return sale, invalid("Product %d not found", item.ProductID)
+		}
+		if err != nil {
+			return sale, fail("Failed to save flash sale", err)
+		}
+		if (item.SalePrice == nil) == (item.DiscountPercent == nil) {
+			return sale, invalid("Set either a sale price or a discount percent for %s", p.Name)
+		}
+		if item.SalePrice != nil && (*item.SalePrice <= 0 || *item.SalePrice >= p.Price) {
+			return sale, invalid("Sale price of %s must be above 0 and below its price of %d", p.Name, p.Price)
+		}
+		if item.DiscountPercent != nil && (*item.DiscountPercent < 1 || *item.DiscountPercent > 99) {
+			return sale, invalid("Discount percent must be between 1 and 99")
+		}
+		if item.MaxPerCustomer != nil && *item.MaxPerCustomer <= 0 {
+			return sale, invalid("Limit per customer must be greater than 0")
+		}
+		sale.Items = append(sale.Items, models.FlashSaleItem{
+			ProductID:       item.ProductID,
+			SalePrice:       item.SalePrice,
+			DiscountPercent: item.DiscountPercent,
+			MaxPerCustomer:  item.MaxPerCustomer,
+		})
+	}
+
+	if !sale.IsActive {
+		return sale, nil
+	}
+	others, err := s.store.FlashSales().List(ctx)
+	if err != nil {
+		return sale, fail("Failed to save flash sale", err)
+	}
+	for _, other := range others {
+		if other.ID == id || !other.IsActive ||
+			!other.StartsAt.Before(sale.EndsAt) || !sale.StartsAt.Before(other.EndsAt) {
+			continue
+		}
+		for _, item := range other.Items {
+			if seen[item.ProductID] {
+				return sale, conflict("%s is already in the flash sale %s at that time", item.Name, other.Name)
+			}
+		}
+	}
+	return sale, nil
+}
+
+func (s *FlashSaleService) invalidate(ctx context.Context) {
+	cache.NewNamespace(s.cache, ProductCacheNamespace).Invalidate(ctx)
+}
+
+// flashSalePrices returns the flash sale price of every product on sale at
+// now, keyed by product ID. Should two sales overlap the lower price wins.
+func flashSalePrices(ctx context.Context, store repositories.Store, now time.Time) (map[int]models.FlashSalePrice, error) {
+	sales, err := store.FlashSales().Current(ctx, now, "")
+	if err != nil {
+		return nil, err
+	}
+	prices := map[int]models.FlashSalePrice{}
+	for _, sale := range sales {
+		if !sale.RunningAt(now) {
+			continue
+		}
+		for _, item := range sale.Items {
+			price := models.NewFlashSalePrice(sale, item)
+			if current, ok := prices[item.ProductID]; !ok || price.Price < current.Price {
+				prices[item.ProductID] = price
+			}
+		}
+	}
+	return prices, nil
+}
+
+// checkFlashSaleLimit refuses quantity units of the named product on sale at
+// price when that takes the user past the sale's per-customer limit, counting
+// what they already bought in the sale.
+func checkFlashSaleLimit(ctx context.Context, store repositories.Store, userID int, price models.FlashSalePrice,
+	productID int, name string, quantity int) error {
+	if price.MaxPerCustomer == nil {
+		return nil
+	}
+	bought, err := store.FlashSales().Purchased(ctx, price.FlashSaleID, productID, userID)
+	if err != nil {
+		return fail("Failed to check the flash sale limit: %v", err)
+	}
+	if bought+quantity > *price.MaxPerCustomer {
+		return invalid("%s is limited to %d per customer in the flash sale", name, *price.MaxPerCustomer)
+	}
+	return nil
+}
+
+// applyFlashSalePrices marks the products that are on sale with their price.
+func applyFlashSalePrices(products []models.Product, prices map[int]models.FlashSalePrice) {
+	for i := range products {
+		if price, ok := prices[products[i].ID]; ok {
+			products[i].FlashSale = &price
+			products[i].IsFlashSale = true
+		}
+	}
+}
+
+type flashSaleAuditSnapshot struct {
+	Name     string                       `json:"name"`
+	StartsAt time.Time                    `json:"starts_at"`
+	EndsAt   time.Time                    `json:"ends_at"`
+	IsActive bool                         `json:"is_active"`
+	Items    []flashSaleItemAuditSnapshot `json:"items"`
+}
+
+type flashSaleItemAuditSnapshot struct {
+	ProductID       int  `json:"product_id"`
+	SalePrice       *int `json:"sale_price"`
+	DiscountPercent *int `json:"discount_percent"`
+	MaxPerCustomer  *int `json:"max_per_customer"`
+}
+
+func newFlashSaleAuditSnapshot(s models.FlashSale) flashSaleAuditSnapshot {
+	items := make([]flashSaleItemAuditSnapshot, 0, len(s.Items))
+	for _, item := range s.Items {
+		items = append(items, flashSaleItemAuditSnapshot{
+			ProductID:       item.ProductID,
+			SalePrice:       item.SalePrice,
+			DiscountPercent: item.DiscountPercent,
+			MaxPerCustomer:  item.MaxPerCustomer,
+		})
+	}
+	return flashSaleAuditSnapshot{Name: s.Name, StartsAt: s.StartsAt, EndsAt: s.EndsAt, IsActive: s.IsActive, Items: items}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"context"
+	"errors"
+	"mime/multipart"
+)
+
+// ErrImagesUnavailable is returned by an ImageStore that is not configured.
+var ErrImagesUnavailable = errors.New("image upload service not available")
+
+// ImageStore keeps uploaded product images. The returned ID is what Delete
+// expects.
+type ImageStore interface {
+	Validate(header *multipart.FileHeader) error
+	Upload(ctx context.Context, file multipart.File, filename, folder string) (url, id string, err error)
+	// UploadMany uploads every file in order. When one fails, the images
+	// already uploaded are deleted again and nothing is returned.
+	UploadMany(ctx context.Context, headers []*multipart.FileHeader, folder string) ([]UploadedImage, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// UploadedImage is the URL and ID of one image stored by UploadMany.
+type UploadedImage struct {
+	URL string
+	ID  string
+}
+
+// Upload is an image sent with a create or update request.
+type Upload struct {
+	File   multipart.File
+	Header *multipart.FileHeader
+}
+
+// NewCloudinaryImages returns an ImageStore backed by Cloudinary. The client
+// is created on first use so the API starts without Cloudinary credentials.
+func NewCloudinaryImages() ImageStore {
+	return cloudinaryImages{}
+}
+
+type cloudinaryImages struct{}
+
+func (cloudinaryImages) service() (*models.CloudinaryService, error) {
+	svc, err := models.NewCloudinaryService()
+	if err != nil {
+		return nil, errors.Join(ErrImagesUnavailable, err)
+	}
+	return svc, nil
+}
+
+func (s cloudinaryImages) Validate(header *multipart.FileHeader) error {
+	svc, err := s.service()
+	if err != nil {
+		return err
+	}
+	return svc.ValidateImageFile(header)
+}
+
+func (s cloudinaryImages) Upload(ctx context.Context, file multipart.File, filename, folder string) (string, string, error) {
+	svc, err := s.service()
+	if err != nil {
+		return "", "", err
+	}
+	return svc.UploadImage(ctx, file, filename, folder)
+}
+
+func (s cloudinaryImages) UploadMany(ctx context.Context, headers []*multipart.FileHeader, folder string) ([]UploadedImage, error) {
+	svc, err := s.service()
+	if err != nil {
+		return nil, err
+	}
+	results, err := svc.UploadMultipleImages(ctx, headers, folder)
+	if err != nil {
+		return nil, err
+	}
+	uploaded := make([]UploadedImage, 0, len(results))
+	for _, r := range results {
+		uploaded = append(uploaded, UploadedImage{URL: r["url"], ID: r["public_id"]})
+	}
+	return uploaded, nil
+}
+
+func (s cloudinaryImages) Delete(ctx context.Context, id string) error {
+	svc, err := s.service()
+	if err != nil {
+		return err
+	}
+	return svc.DeleteImage(ctx, id)
+}
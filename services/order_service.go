@@ -0,0 +1,418 @@
+package services
+
+import (
+	"coffee-shop/cache"
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// doorDeliveryFee is charged on top of the subtotal for door delivery.
+const doorDeliveryFee = 10000
+
+var deliveryMethods = map[string]bool{"dine_in": true, "door_delivery": true, "pick_up": true}
+
+type OrderService struct {
+	store  repositories.Store
+	alerts StockAlerter
+	cache  cache.Store
+}
+
+// NewOrderService returns an OrderService that tells alerts about products a
+// checkout takes below their low-stock threshold; alerts may be nil. The
+// cached product responses are cleared whenever an order changes the stock.
+func NewOrderService(store repositories.Store, alerts StockAlerter, cache cache.Store) *OrderService {
+	return &OrderService{store: store, alerts: alerts, cache: cache}
+}
+
+// HistoryQuery is a customer's order history request. Month is in
+// "January 2006" form; an unparseable month is ignored.
+type HistoryQuery struct {
+	UserID    int
+	Status    string
+	StartDate string
+	EndDate   string
+	Month     string
+	Page      pagination.Params
+}
+
+// CheckoutInput holds the checkout form. Empty contact fields are filled from
+// the customer's profile.
+type CheckoutInput struct {
+	Email          string
+	FullName       string
+	Address        string
+	DeliveryMethod string
+	PaymentMethod  string
+}
+
+func (s *OrderService) List(ctx context.Context, filter repositories.OrderFilter) ([]models.OrderSummaryV2, int, error) {
+	orders, total, err := s.store.Orders().List(ctx, filter)
+	if err != nil {
+		return nil, 0, fail("Failed to count orders", err)
+	}
+	return orders, total, nil
+}
+
+func (s *OrderService) Get(ctx context.Context, id int) (models.OrderSummaryV2, error) {
+	if id <= 0 {
+		return models.OrderSummaryV2{}, invalid("Invalid order ID")
+	}
+	order, err := s.store.Orders().Get(ctx, id)
+	if err != nil {
+		return models.OrderSummaryV2{}, notFound("Order not found")
+	}
+	return order, nil
+}
+
+func (s *OrderService) UpdateStatus(ctx context.Context, actor Actor, id int, status string) error {
+	status = strings.TrimSpace(status)
+	if id <= 0 {
+		return invalid("Invalid order ID")
+	}
+	if status == "" {
+		return invalid("Status is required")
+	}
+
+	statusID, err := s.store.Orders().StatusID(ctx, status)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return invalid("Invalid status")
+	}
+	if err != nil {
+		return fail("Failed to update order status", err)
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		previous, err := tx.Orders().StatusName(ctx, id)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return notFound("Order not found")
+		}
+		if err != nil {
+			return err
+		}
+		if previous == "cancelled" && status != "cancelled" {
+			return conflict("Cancelled orders cannot be reopened")
+		}
+		if status == "cancelled" && previous != "cancelled" {
+			if err := returnStock(ctx, tx, actor, id); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Orders().UpdateStatus(ctx, id, statusID); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionUpdateStatus, models.AuditEntityOrder, id,
+			map[string]string{"status": previous}, map[string]string{"status": status}))
+	})
+	if err != nil {
+		var serviceErr *Error
+		if errors.As(err, &serviceErr) {
+			return serviceErr
+		}
+		return fail("Failed to update order status", err)
+	}
+	if status == "cancelled" {
+		cache.NewNamespace(s.cache, ProductCacheNamespace).Invalidate(ctx)
+	}
+	return nil
+}
+
+// returnStock puts the items of a cancelled order back in stock.
+func returnStock(ctx context.Context, tx repositories.Store, actor Actor, orderID int) error {
+	items, err := tx.Orders().Items(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		m := models.StockMovement{ProductID: item.ProductID, Type: models.StockMovementCancellation,
+			Quantity: item.Quantity, Reason: "Order cancelled", OrderID: &orderID, ActorID: actorID(actor)}
+		if err := moveStock(ctx, tx, &m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *OrderService) Delete(ctx context.Context, id int) error {
+	if id <= 0 {
+		return invalid("Invalid order ID")
+	}
+
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		return tx.Orders().Delete(ctx, id)
+	})
+	if errors.Is(err, repositories.ErrNotFound) {
+		return notFound("Order not found")
+	}
+	if err != nil {
+		return fail("Failed to delete order", err)
+	}
+	return nil
+}
+
+func (s *OrderService) History(ctx context.Context, q HistoryQuery) ([]models.HistoryOrderV2, int, error) {
+	filter := repositories.HistoryFilter{
+		UserID: q.UserID,
+		Status: strings.TrimSpace(q.Status),
+		From:   strings.TrimSpace(q.StartDate),
+		To:     strings.TrimSpace(q.EndDate),
+		Params: q.Page,
+	}
+	if month, err := time.Parse("January 2006", strings.TrimSpace(q.Month)); err == nil {
+		filter.Month = month
+	}
+
+	orders, total, err := s.store.Orders().History(ctx, filter)
+	if err != nil {
+		return nil, 0, fail("Query error: %v", err)
+	}
+	for i := range orders {
+		if len(orders[i].ProductImages) == 0 {
+			orders[i].ProductImages = []string{"https://via.placeholder.com/150"}
+		}
+	}
+	return orders, total, nil
+}
+
+func (s *OrderService) Detail(ctx context.Context, orderID, userID int) (models.OrderDetailV2, error) {
+	if orderID <= 0 {
+		return models.OrderDetailV2{}, invalid("Invalid order ID")
+	}
+	detail, err := s.store.Orders().Detail(ctx, orderID, userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.OrderDetailV2{}, notFound("Order not found")
+	}
+	if err != nil {
+		return models.OrderDetailV2{}, fail("Failed to get order items", err)
+	}
+	return detail, nil
+}
+
+// Checkout turns the customer's cart into an order: it prices every line at
+// its flash sale price, if any, plus its option surcharge, checks the flash
+// sales' per-customer limits, applies the running promotion rules to the
+// other lines, writes the order and its items, takes
+// the stock and empties the cart, all in one transaction. Products the order
+// takes below their low-stock threshold are then reported to the alerter.
+func (s *OrderService) Checkout(ctx context.Context, userID int, in CheckoutInput) (models.CheckoutResultV2, error) {
+	var result models.CheckoutResultV2
+	var low []models.Product
+
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		lines, err := tx.Carts().LockForCheckout(ctx, userID)
+		if err != nil {
+			return fail("Query error: %v", err)
+		}
+		// Lines of one product with different options share its stock.
+		wanted := map[int]int{}
+		for _, line := range lines {
+			wanted[line.ProductID] += line.Quantity
+			if line.Stock < wanted[line.ProductID] {
+				return invalid("Insufficient stock for %s", line.Name)
+			}
+		}
+		if len(lines) == 0 {
+			return invalid("Cart is empty")
+		}
+
+		productIDs := make([]int, 0, len(lines))
+		for _, line := range lines {
+			productIDs = append(productIDs, line.ProductID)
+		}
+		options, err := productOptions(ctx, tx, productIDs...)
+		if err != nil {
+			return fail("Query error: %v", err)
+		}
+		prices, err := flashSalePrices(ctx, tx, time.Now())
+		if err != nil {
+			return fail("Query error: %v", err)
+		}
+		rules, err := tx.Promotions().Running(ctx, time.Now())
+		if err != nil {
+			return fail("Query error: %v", err)
+		}
+		onSale := map[int]int{}
+		promoted := make([]promotionLine, len(lines))
+		for i := range lines {
+			extra, ok := optionPrice(options[lines[i].ProductID], lines[i].SizeID, lines[i].TemperatureID, lines[i].VariantID)
+			if !ok {
+				return invalid("%s is no longer available with the chosen options", lines[i].Name)
+			}
+			if price, ok := prices[lines[i].ProductID]; ok {
+				lines[i].Price = price.Price
+				onSale[lines[i].ProductID] += lines[i].Quantity
+			}
+			lines[i].Price += extra
+			promoted[i] = promotionLine{ProductID: lines[i].ProductID, CategoryID: lines[i].CategoryID,
+				IsBuy1Get1: lines[i].IsBuy1Get1, UnitPrice: lines[i].Price, Quantity: lines[i].Quantity,
+				Excluded: onSale[lines[i].ProductID] > 0}
+		}
+		adjustments := applyPromotions(promoted, rules)
+		for _, line := range lines {
+			quantity, ok := onSale[line.ProductID]
+			if !ok {
+				continue
+			}
+			delete(onSale, line.ProductID)
+			if err := checkFlashSaleLimit(ctx, tx, userID, prices[line.ProductID], line.ProductID, line.Name, quantity); err != nil {
+				return err
+			}
+		}
+
+		in.Email = strings.TrimSpace(in.Email)
+		in.FullName = strings.TrimSpace(in.FullName)
+		in.Address = strings.TrimSpace(in.Address)
+		in.DeliveryMethod = strings.ToLower(in.DeliveryMethod)
+		if in.DeliveryMethod == "" {
+			in.DeliveryMethod = "dine_in"
+		}
+		in.PaymentMethod = strings.TrimSpace(in.PaymentMethod)
+		if in.PaymentMethod == "" {
+			in.PaymentMethod = "1"
+		}
+		paymentMethodID, err := strconv.Atoi(in.PaymentMethod)
+		if err != nil || paymentMethodID <= 0 {
+			paymentMethodID = 1
+		}
+
+		if in.Email == "" || in.FullName == "" || in.Address == "" {
+			if profile, err := tx.Users().Get(ctx, userID); err == nil {
+				if in.Email == "" {
+					in.Email = profile.Email
+				}
+				if in.FullName == "" {
+					in.FullName = profile.FullName
+				}
+				if in.Address == "" {
+					in.Address = profile.Address
+				}
+			}
+		}
+		if in.Email == "" || in.FullName == "" || in.Address == "" {
+			return invalid("Email, full name, and address are required")
+		}
+		if !deliveryMethods[in.DeliveryMethod] {
+			return invalid("Invalid delivery method")
+		}
+
+		deliveryFee := 0
+		if in.DeliveryMethod == "door_delivery" {
+			deliveryFee = doorDeliveryFee
+		}
+		subtotal, discount := 0, 0
+		for i, line := range lines {
+			subtotal += line.Price * line.Quantity
+			if adjustments[i] != nil {
+				discount += adjustments[i].Amount
+			}
+		}
+
+		statusID, err := tx.Orders().StatusID(ctx, "pending")
+		if err != nil {
+			return fail("Order status pending not found: %v", err)
+		}
+
+		now := time.Now()
+		order := repositories.NewOrder{
+			OrderNumber:     fmt.Sprintf("ORD-%d", now.Unix()),
+			UserID:          userID,
+			StatusID:        statusID,
+			CustomerName:    in.FullName,
+			DeliveryAddress: in.Address,
+			Subtotal:        subtotal,
+			DeliveryFee:     deliveryFee,
+			Total:           subtotal - discount + deliveryFee,
+			PaymentMethodID: paymentMethodID,
+			OrderDate:       now,
+		}
+		orderID, err := tx.Orders().Create(ctx, order)
+		if err != nil {
+			return fail("Failed to create order: %v", err)
+		}
+
+		stockAfter := map[int]int{}
+		for i, line := range lines {
+			item := repositories.NewOrderItem{
+				ProductID:     line.ProductID,
+				Quantity:      line.Quantity,
+				SizeID:        line.SizeID,
+				TemperatureID: line.TemperatureID,
+				UnitPrice:     line.Price,
+			}
+			if price, ok := prices[line.ProductID]; ok {
+				item.IsFlashSale = true
+				item.FlashSaleID = &price.FlashSaleID
+			}
+			if adj := adjustments[i]; adj != nil {
+				item.DiscountAmount = adj.Amount
+				item.PromotionRuleID = adj.PromotionRuleID
+				item.PromotionName = adj.Name
+			}
+			if err := tx.Orders().AddItem(ctx, orderID, item); err != nil {
+				return fail("Failed to create order items: %v", err)
+			}
+			sale := models.StockMovement{ProductID: line.ProductID, Type: models.StockMovementSale,
+				Quantity: -line.Quantity, Reason: order.OrderNumber, OrderID: &orderID, ActorID: &userID}
+			if err := moveStock(ctx, tx, &sale); err != nil {
+				if errors.Is(err, repositories.ErrInsufficientStock) {
+					return conflict("Insufficient stock for %s", line.Name)
+				}
+				return fail("Failed to update stock: %v", err)
+			}
+			stockAfter[line.ProductID] = sale.StockAfter
+		}
+		// Lines of the same product share the stock read when the cart was
+		// locked, so each product is checked once against its final level.
+		for _, line := range lines {
+			after, ok := stockAfter[line.ProductID]
+			if !ok {
+				continue
+			}
+			delete(stockAfter, line.ProductID)
+			if line.Stock >= line.LowStockThreshold && after < line.LowStockThreshold {
+				low = append(low, models.Product{ID: line.ProductID, Name: line.Name, Stock: after,
+					LowStockThreshold: line.LowStockThreshold})
+			}
+		}
+
+		if err := tx.Carts().Clear(ctx, userID); err != nil {
+			return fail("Failed to clear cart: %v", err)
+		}
+
+		result = models.CheckoutResultV2{
+			ID:             orderID,
+			OrderNumber:    order.OrderNumber,
+			Status:         "pending",
+			Subtotal:       order.Subtotal,
+			Discount:       discount,
+			DeliveryFee:    order.DeliveryFee,
+			Total:          order.Total,
+			Email:          in.Email,
+			FullName:       in.FullName,
+			Address:        in.Address,
+			DeliveryMethod: in.DeliveryMethod,
+			PaymentMethod:  in.PaymentMethod,
+		}
+		return nil
+	})
+	if err != nil {
+		var serviceErr *Error
+		if errors.As(err, &serviceErr) {
+			return models.CheckoutResultV2{}, serviceErr
+		}
+		return models.CheckoutResultV2{}, fail("Failed to commit: %v", err)
+	}
+	cache.NewNamespace(s.cache, ProductCacheNamespace).Invalidate(ctx)
+
+	if len(low) > 0 && s.alerts != nil {
+		s.alerts.LowStock(ctx, low)
+	}
+	return result, nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+)
+
+// AddFavorite puts an active product in the user's favourites and reports
+// whether it was not there yet.
+func (s *ProductService) AddFavorite(ctx context.Context, userID, productID int) (bool, error) {
+	if productID <= 0 {
+		return false, invalid("Invalid product ID")
+	}
+	_, err := s.store.Products().GetActive(ctx, productID, "")
+	if errors.Is(err, repositories.ErrNotFound) {
+		return false, notFound("Product not found")
+	}
+	if err != nil {
+		return false, fail("Failed to update favorites", err)
+	}
+	added, err := s.store.Favorites().Add(ctx, userID, productID)
+	if err != nil {
+		return false, fail("Failed to update favorites", err)
+	}
+	return added, nil
+}
+
+func (s *ProductService) RemoveFavorite(ctx context.Context, userID, productID int) error {
+	if productID <= 0 {
+		return invalid("Invalid product ID")
+	}
+	err := s.store.Favorites().Remove(ctx, userID, productID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return notFound("Product is not in your favorites")
+	}
+	if err != nil {
+		return fail("Failed to update favorites", err)
+	}
+	return nil
+}
+
+// FavoriteProducts returns one page of the user's favourite products, the
+// latest favourite first. Archived and inactive products are left out.
+func (s *ProductService) FavoriteProducts(ctx context.Context, userID int, locale string, page pagination.Params) ([]models.Product, int, error) {
+	products, total, err := s.store.Favorites().List(ctx, repositories.FavoriteFilter{
+		Params: page,
+		UserID: userID,
+		Locale: locale,
+	})
+	if err != nil {
+		return nil, 0, fail("Failed to retrieve favorites", err)
+	}
+	if err := s.priceFlashSales(ctx, products); err != nil {
+		return nil, 0, fail("Failed to retrieve favorites", err)
+	}
+	favorited := true
+	for i := range products {
+		products[i].IsFavorited = &favorited
+	}
+	return products, total, nil
+}
+
+// MarkFavorited sets IsFavorited on each of the products for the user.
+func (s *ProductService) MarkFavorited(ctx context.Context, userID int, products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+	favorited, err := s.store.Favorites().Favorited(ctx, userID, ids)
+	if err != nil {
+		return fail("Failed to retrieve favorites", err)
+	}
+	for i := range products {
+		is := favorited[products[i].ID]
+		products[i].IsFavorited = &is
+	}
+	return nil
+}
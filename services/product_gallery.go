@@ -0,0 +1,309 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"log"
+	"mime/multipart"
+	"time"
+)
+
+// MaxProductImages is the size of a product gallery. Galleries that held
+// more before the limit was enforced can still be reordered and trimmed.
+const MaxProductImages = 4
+
+// Images returns the gallery of a product, archived or not, in display order.
+func (s *ProductService) Images(ctx context.Context, productID int) ([]models.ProductImage, error) {
+	if _, err := s.storedProduct(ctx, productID, "Failed to retrieve product images"); err != nil {
+		return nil, err
+	}
+	images, err := s.store.Products().Images(ctx, productID)
+	if err != nil {
+		return nil, fail("Failed to retrieve product images", err)
+	}
+	return images, nil
+}
+
+// AddImages uploads files to the end of the gallery. The first image of an
+// empty gallery becomes the primary image.
+func (s *ProductService) AddImages(ctx context.Context, actor Actor, productID int, files []*multipart.FileHeader) ([]models.ProductImage, error) {
+	if _, err := s.storedProduct(ctx, productID, "Failed to upload product images"); err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, invalid("No images uploaded")
+	}
+	current, err := s.store.Products().Images(ctx, productID)
+	if err != nil {
+		return nil, fail("Failed to upload product images", err)
+	}
+	if len(current)+len(files) > MaxProductImages {
+		return nil, invalid("A product can have at most %d images", MaxProductImages)
+	}
+	for _, f := range files {
+		if err := s.images.Validate(f); err != nil {
+			if errors.Is(err, ErrImagesUnavailable) {
+				log.Printf("Cloudinary not configured: %v", err)
+				return nil, &Error{Status: 500, Message: "Image upload service not available", Err: err}
+			}
+			return nil, invalid(err.Error())
+		}
+	}
+
+	uploaded, err := s.images.UploadMany(ctx, files, "products")
+	if err != nil {
+		return nil, fail("Failed to upload image", err)
+	}
+
+	gallery, _, err := s.changeGallery(ctx, actor, productID, models.AuditActionCreate, "Failed to upload product images",
+		func(images []models.ProductImage) ([]models.ProductImage, error) {
+			if len(images)+len(uploaded) > MaxProductImages {
+				return nil, invalid("A product can have at most %d images", MaxProductImages)
+			}
+			for _, u := range uploaded {
+				images = append(images, models.ProductImage{ProductID: productID, URL: u.URL, CloudinaryID: u.ID})
+			}
+			return images, nil
+		})
+	if err != nil {
+		for _, u := range uploaded {
+			s.deleteImage(ctx, u.ID)
+		}
+		return nil, err
+	}
+	return gallery, nil
+}
+
+// ReorderImages puts the gallery in the order of imageIDs, which must list
+// every image of the product exactly once.
+func (s *ProductService) ReorderImages(ctx context.Context, actor Actor, productID int, imageIDs []int) ([]models.ProductImage, error) {
+	if _, err := s.storedProduct(ctx, productID, "Failed to reorder product images"); err != nil {
+		return nil, err
+	}
+
+	gallery, _, err := s.changeGallery(ctx, actor, productID, models.AuditActionUpdate, "Failed to reorder product images",
+		func(images []models.ProductImage) ([]models.ProductImage, error) {
+			byID := map[int]models.ProductImage{}
+			for _, img := range images {
+				byID[img.ID] = img
+			}
+			if len(imageIDs) != len(images) {
+				return nil, invalid("image_ids must list every image of the product exactly once")
+			}
+			ordered := make([]models.ProductImage, 0, len(images))
+			for _, id := range imageIDs {
+				img, ok := byID[id]
+				if !ok {
+					return nil, invalid("image_ids must list every image of the product exactly once")
+				}
+				delete(byID, id)
+				ordered = append(ordered, img)
+			}
+			return ordered, nil
+		})
+	return gallery, err
+}
+
+// SetPrimaryImage makes imageID the primary image, which the product also
+// shows as its image_url.
+func (s *ProductService) SetPrimaryImage(ctx context.Context, actor Actor, productID, imageID int) ([]models.ProductImage, error) {
+	if _, err := s.storedProduct(ctx, productID, "Failed to update product images"); err != nil {
+		return nil, err
+	}
+
+	gallery, _, err := s.changeGallery(ctx, actor, productID, models.AuditActionUpdate, "Failed to update product images",
+		func(images []models.ProductImage) ([]models.ProductImage, error) {
+			found := false
+			for i := range images {
+				images[i].IsPrimary = images[i].ID == imageID
+				found = found || images[i].IsPrimary
+			}
+			if !found {
+				return nil, notFound("Image not found")
+			}
+			return images, nil
+		})
+	return gallery, err
+}
+
+// DeleteImage removes one image from the gallery and from Cloudinary. When it
+// was the primary image, the next image in order takes its place.
+func (s *ProductService) DeleteImage(ctx context.Context, actor Actor, productID, imageID int) ([]models.ProductImage, error) {
+	if _, err := s.storedProduct(ctx, productID, "Failed to delete product image"); err != nil {
+		return nil, err
+	}
+
+	gallery, removed, err := s.changeGallery(ctx, actor, productID, models.AuditActionDelete, "Failed to delete product image",
+		func(images []models.ProductImage) ([]models.ProductImage, error) {
+			for i, img := range images {
+				if img.ID == imageID {
+					return append(images[:i:i], images[i+1:]...), nil
+				}
+			}
+			return nil, notFound("Image not found")
+		})
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range removed {
+		s.deleteImage(ctx, img.CloudinaryID)
+	}
+	return gallery, nil
+}
+
+// gallerySnapshot is the audit state of a product gallery.
+type gallerySnapshot struct {
+	Images []models.ProductImage `json:"images"`
+}
+
+func (s *ProductService) storedProduct(ctx context.Context, productID int, message string) (models.Product, error) {
+	if productID <= 0 {
+		return models.Product{}, invalid("Invalid product ID")
+	}
+	p, err := s.store.Products().Get(ctx, productID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.Product{}, notFound("Product not found")
+	}
+	if err != nil {
+		return models.Product{}, fail(message, err)
+	}
+	return p, nil
+}
+
+// changeGallery loads the gallery in a transaction, lets change return the
+// new list of images in display order and saves it with an audit entry. It
+// returns the saved gallery and the images change dropped.
+func (s *ProductService) changeGallery(ctx context.Context, actor Actor, productID int, action, message string,
+	change func(images []models.ProductImage) ([]models.ProductImage, error)) ([]models.ProductImage, []models.ProductImage, error) {
+	var gallery, removed []models.ProductImage
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		p, err := tx.Products().Get(ctx, productID)
+		if err != nil {
+			return err
+		}
+		before, err := tx.Products().Images(ctx, productID)
+		if err != nil {
+			return err
+		}
+		after, err := change(append([]models.ProductImage(nil), before...))
+		if err != nil {
+			return err
+		}
+		if gallery, removed, err = saveGallery(ctx, tx, p, before, after); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(action, models.AuditEntityProductGallery, productID,
+			gallerySnapshot{Images: before}, gallerySnapshot{Images: gallery}))
+	})
+	if err != nil {
+		var serviceErr *Error
+		if errors.As(err, &serviceErr) {
+			return nil, nil, serviceErr
+		}
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, nil, notFound("Product not found")
+		}
+		return nil, nil, fail(message, err)
+	}
+
+	s.invalidate(ctx)
+	return gallery, removed, nil
+}
+
+// saveGallery stores after, the whole gallery of p in display order, over
+// before. Images without an ID are inserted and images of before missing
+// from after are deleted. Positions are renumbered from 1, the first image
+// becomes primary when none is, and p's image_url follows the primary image.
+func saveGallery(ctx context.Context, tx repositories.Store, p models.Product, before, after []models.ProductImage) ([]models.ProductImage, []models.ProductImage, error) {
+	if len(after) > MaxProductImages && len(after) > len(before) {
+		return nil, nil, invalid("A product can have at most %d images", MaxProductImages)
+	}
+
+	primary := -1
+	for i := range after {
+		after[i].ProductID = p.ID
+		after[i].Order = i + 1
+		if after[i].IsPrimary {
+			primary = i
+		}
+	}
+	if primary < 0 && len(after) > 0 {
+		primary = 0
+		after[0].IsPrimary = true
+	}
+
+	kept := map[int]bool{}
+	for _, img := range after {
+		kept[img.ID] = true
+	}
+	stored := map[int]models.ProductImage{}
+	removed := []models.ProductImage{}
+	for _, img := range before {
+		if !kept[img.ID] {
+			removed = append(removed, img)
+			if err := tx.Products().DeleteImage(ctx, img.ID); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		stored[img.ID] = img
+	}
+
+	// Only one image may be primary at any time, so the old primary is
+	// cleared before the others are saved.
+	for _, demote := range []bool{true, false} {
+		for _, img := range after {
+			old, ok := stored[img.ID]
+			if !ok || old == img || (old.IsPrimary && !img.IsPrimary) != demote {
+				continue
+			}
+			if err := tx.Products().UpdateImage(ctx, img); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	for i := range after {
+		if after[i].ID == 0 {
+			if err := tx.Products().AddImage(ctx, &after[i]); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	url, cloudinaryID := "", ""
+	if primary >= 0 {
+		url, cloudinaryID = after[primary].URL, after[primary].CloudinaryID
+	}
+	if p.ImageURL != url || p.CloudinaryID != cloudinaryID {
+		p.ImageURL, p.CloudinaryID, p.UpdatedAt = url, cloudinaryID, time.Now()
+		if err := tx.Products().Update(ctx, p); err != nil {
+			return nil, nil, err
+		}
+	}
+	return after, removed, nil
+}
+
+// replacePrimaryImage points the primary gallery image of p at p's new
+// image_url, adding it in front of the gallery when there is no primary
+// image yet.
+func replacePrimaryImage(ctx context.Context, tx repositories.Store, p models.Product) error {
+	before, err := tx.Products().Images(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	after := append([]models.ProductImage(nil), before...)
+	replaced := false
+	for i := range after {
+		if after[i].IsPrimary {
+			after[i].URL, after[i].CloudinaryID = p.ImageURL, p.CloudinaryID
+			replaced = true
+		}
+	}
+	if !replaced {
+		after = append([]models.ProductImage{{URL: p.ImageURL, CloudinaryID: p.CloudinaryID, IsPrimary: true}}, after...)
+	}
+	_, _, err = saveGallery(ctx, tx, p, before, after)
+	return err
+}
@@ -0,0 +1,476 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProductSheetColumns are the columns of the product export, in order. The
+// import reads the same columns in any order and ignores the others.
+var ProductSheetColumns = []string{
+	"sku", "name", "description", "category", "price", "stock", "low_stock_threshold",
+	"is_flash_sale", "is_featured", "is_buy1get1", "is_active", "image_url",
+}
+
+// importColumnAliases maps the old names of columns, found in sheets exported
+// before the rename, to their current ones.
+var importColumnAliases = map[string]string{"is_favorite": "is_featured"}
+
+// MaxImportRows caps the product rows of one import.
+const MaxImportRows = 1000
+
+// ImportRow is what a product import does with one row of the sheet. Errors
+// are untranslated, for the handler to localize.
+type ImportRow struct {
+	Row       int
+	Action    string
+	ProductID int
+	SKU       string
+	Name      string
+	Changes   []models.ProductImportChange
+	Errors    []*Error
+}
+
+// ImportReport is the row-by-row diff of a product import.
+type ImportReport struct {
+	Applied bool
+	Rows    []ImportRow
+}
+
+// importPlan is the product a row leaves behind, next to the stored one it
+// replaces, which is zero for a new product.
+type importPlan struct {
+	before  models.Product
+	product models.Product
+}
+
+// Export returns the unarchived products as a sheet: a header row of
+// ProductSheetColumns, then one row per product in ID order.
+func (s *ProductService) Export(ctx context.Context) ([][]string, error) {
+	products, err := s.store.Products().All(ctx)
+	if err != nil {
+		return nil, fail("Failed to export products", err)
+	}
+	categories, err := s.store.Categories().List(ctx, "")
+	if err != nil {
+		return nil, fail("Failed to export products", err)
+	}
+	names := make(map[int]string, len(categories))
+	for _, c := range categories {
+		names[c.ID] = c.Name
+	}
+
+	rows := [][]string{ProductSheetColumns}
+	for _, p := range products {
+		if p.DeletedAt != nil {
+			continue
+		}
+		rows = append(rows, productSheetRow(p, names[p.CategoryID]))
+	}
+	return rows, nil
+}
+
+// Import reads a product sheet in the export's layout and diffs every row
+// against the catalogue. A row updates the product with its SKU, or failing
+// that the product with its name, and creates one otherwise; empty cells
+// keep the stored value. Rows are checked with the rules of Create.
+//
+// Unless apply is set nothing is written. When it is, and no row has an
+// error, every row is written in one transaction.
+func (s *ProductService) Import(ctx context.Context, actor Actor, sheet [][]string, apply bool) (ImportReport, error) {
+	header, body, err := importHeader(sheet)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	products, err := s.store.Products().All(ctx)
+	if err != nil {
+		return ImportReport{}, fail("Failed to import products", err)
+	}
+	categoryList, err := s.store.Categories().List(ctx, "")
+	if err != nil {
+		return ImportReport{}, fail("Failed to import products", err)
+	}
+	categories := make(map[string]int, len(categoryList))
+	categoryNames := make(map[int]string, len(categoryList))
+	for _, c := range categoryList {
+		categories[strings.ToLower(c.Name)] = c.ID
+		categoryNames[c.ID] = c.Name
+	}
+
+	report := ImportReport{Rows: make([]ImportRow, 0, len(body))}
+	plans := make([]importPlan, 0, len(body))
+	claimed := map[string]int{}
+	for i, cells := range body {
+		if blankRow(cells) {
+			continue
+		}
+		row := ImportRow{Row: i + 2}
+		plan := planImportRow(&row, header, cells, products, categories, claimed)
+		if len(row.Errors) == 0 {
+			row.Changes = productChanges(plan.before, plan.product, categoryNames)
+			switch {
+			case plan.before.ID == 0:
+				row.Action = models.ImportActionCreate
+			case len(row.Changes) == 0:
+				row.Action = models.ImportActionUnchanged
+			default:
+				row.Action = models.ImportActionUpdate
+			}
+		} else {
+			row.Action = models.ImportActionError
+		}
+		report.Rows = append(report.Rows, row)
+		plans = append(plans, plan)
+	}
+	if len(report.Rows) == 0 {
+		return ImportReport{}, invalid("The file has no product rows")
+	}
+
+	if !apply {
+		return report, nil
+	}
+	for _, row := range report.Rows {
+		if row.Action == models.ImportActionError {
+			return report, nil
+		}
+	}
+
+	var replacedImages []string
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		replacedImages = nil
+		for i := range plans {
+			replaced, err := applyImportPlan(ctx, tx, actor, &report.Rows[i], plans[i])
+			if err != nil {
+				return err
+			}
+			if replaced != "" {
+				replacedImages = append(replacedImages, replaced)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ImportReport{}, fail("Failed to import products", err)
+	}
+
+	for _, id := range replacedImages {
+		s.deleteImage(ctx, id)
+	}
+	s.invalidate(ctx)
+	report.Applied = true
+	return report, nil
+}
+
+// importHeader maps each known column of the sheet's first row to its index
+// and returns the rows below it.
+func importHeader(sheet [][]string) (map[string]int, [][]string, error) {
+	if len(sheet) == 0 {
+		return nil, nil, invalid("The file is empty")
+	}
+	header := map[string]int{}
+	for i, cell := range sheet[0] {
+		name := strings.ToLower(strings.TrimSpace(cell))
+		if alias, ok := importColumnAliases[name]; ok {
+			name = alias
+		}
+		if !slices.Contains(ProductSheetColumns, name) {
+			continue
+		}
+		if _, ok := header[name]; ok {
+			return nil, nil, invalid("Column %s appears more than once", name)
+		}
+		header[name] = i
+	}
+	if _, ok := header["name"]; !ok {
+		return nil, nil, invalid("The file has no name column")
+	}
+	body := sheet[1:]
+	if len(body) > MaxImportRows {
+		return nil, nil, invalid("An import can have at most %d rows", MaxImportRows)
+	}
+	return header, body, nil
+}
+
+// planImportRow matches the row to a product and works out what it becomes,
+// adding to row.Errors whatever stops the row from being imported. claimed
+// remembers the products earlier rows write, keyed by ID, SKU and name.
+func planImportRow(row *ImportRow, header map[string]int, cells []string, products []models.Product,
+	categories map[string]int, claimed map[string]int) importPlan {
+	var plan importPlan
+	cell := func(column string) (string, bool) {
+		i, ok := header[column]
+		if !ok || i >= len(cells) {
+			return "", false
+		}
+		value := strings.TrimSpace(cells[i])
+		return value, value != ""
+	}
+	rowErr := func(message string, args ...any) {
+		row.Errors = append(row.Errors, invalid(message, args...))
+	}
+
+	sku, hasSKU := cell("sku")
+	name, _ := cell("name")
+	row.SKU, row.Name = sku, name
+
+	match, err := matchProduct(products, sku, name)
+	if err != nil {
+		row.Errors = append(row.Errors, err)
+		return plan
+	}
+	if match.ID != 0 && match.DeletedAt != nil {
+		rowErr("Product %s is archived, restore it before importing", match.Name)
+		return plan
+	}
+
+	p := models.Product{Stock: 0, LowStockThreshold: DefaultLowStockThreshold, IsActive: true}
+	if match.ID != 0 {
+		p = match
+		row.ProductID = match.ID
+	}
+	plan.before = match
+
+	if hasSKU {
+		p.SKU = sku
+	}
+	if name != "" {
+		p.Name = name
+	}
+	if v, ok := cell("description"); ok {
+		p.Description = v
+	}
+	if v, ok := cell("category"); ok {
+		id, found := categories[strings.ToLower(v)]
+		if !found {
+			rowErr("Category %s does not exist", v)
+		}
+		p.CategoryID = id
+	}
+	for _, field := range []struct {
+		column string
+		value  *int
+	}{{"price", &p.Price}, {"stock", &p.Stock}, {"low_stock_threshold", &p.LowStockThreshold}} {
+		if v, ok := cell(field.column); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				rowErr("Column %s must be a whole number", field.column)
+			}
+			*field.value = n
+		}
+	}
+	for _, field := range []struct {
+		column string
+		value  *bool
+	}{{"is_flash_sale", &p.IsFlashSale}, {"is_featured", &p.IsFeatured}, {"is_buy1get1", &p.IsBuy1Get1}, {"is_active", &p.IsActive}} {
+		if v, ok := cell(field.column); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				rowErr("Column %s must be true or false", field.column)
+			}
+			*field.value = b
+		}
+	}
+	if v, ok := cell("image_url"); ok && v != p.ImageURL {
+		if u, err := url.ParseRequestURI(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			rowErr("Column image_url must be an http or https URL")
+		}
+		p.ImageURL, p.CloudinaryID = v, ""
+	}
+
+	// Cells that did not parse leave zero values behind, which would only
+	// add misleading errors.
+	if len(row.Errors) == 0 {
+		var serviceErr *Error
+		if err := validateProduct(productInputOf(p)); errors.As(err, &serviceErr) {
+			row.Errors = append(row.Errors, serviceErr)
+		}
+	}
+	if p.SKU != "" {
+		for _, other := range products {
+			if other.ID != p.ID && strings.EqualFold(other.SKU, p.SKU) {
+				rowErr("SKU already exists")
+				break
+			}
+		}
+	}
+
+	keys := []string{"name:" + strings.ToLower(p.Name)}
+	if p.ID != 0 {
+		keys = append(keys, "id:"+strconv.Itoa(p.ID))
+	}
+	if p.SKU != "" {
+		keys = append(keys, "sku:"+strings.ToLower(p.SKU))
+	}
+	for _, key := range keys {
+		if earlier, ok := claimed[key]; ok {
+			rowErr("Row %d already imports this product", earlier)
+			break
+		}
+	}
+	for _, key := range keys {
+		if _, ok := claimed[key]; !ok {
+			claimed[key] = row.Row
+		}
+	}
+
+	plan.product = p
+	return plan
+}
+
+// matchProduct finds the product a row imports: the one with its SKU, or
+// else the one with its name. A product found by name keeps its SKU unless it
+// has none. It returns a zero product when the row is a new one.
+func matchProduct(products []models.Product, sku, name string) (models.Product, *Error) {
+	if sku != "" {
+		for _, p := range products {
+			if strings.EqualFold(p.SKU, sku) {
+				return p, nil
+			}
+		}
+	}
+	if name == "" {
+		return models.Product{}, nil
+	}
+
+	var found []models.Product
+	for _, p := range products {
+		if strings.EqualFold(p.Name, name) && (sku == "" || p.SKU == "") {
+			found = append(found, p)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return models.Product{}, nil
+	case 1:
+		return found[0], nil
+	}
+	return models.Product{}, invalid("Several products are named %s, add a SKU to pick one", name)
+}
+
+// applyImportPlan writes one checked row. It returns the Cloudinary ID of an
+// image the row replaced, to delete once the import is committed.
+func applyImportPlan(ctx context.Context, tx repositories.Store, actor Actor, row *ImportRow, plan importPlan) (string, error) {
+	p := plan.product
+	switch row.Action {
+	case models.ImportActionCreate:
+		if err := tx.Products().Create(ctx, &p); err != nil {
+			return "", err
+		}
+		row.ProductID = p.ID
+		if err := recordPrice(ctx, tx, actor, p.ID, p.Price, nil, "Opening price"); err != nil {
+			return "", err
+		}
+		if p.Stock != 0 {
+			opening := models.StockMovement{ProductID: p.ID, Type: models.StockMovementAdjustment, Quantity: p.Stock,
+				StockAfter: p.Stock, Reason: "Opening balance", ActorID: actorID(actor)}
+			if err := tx.Inventory().Record(ctx, &opening); err != nil {
+				return "", err
+			}
+		}
+		if p.ImageURL != "" {
+			primary := []models.ProductImage{{URL: p.ImageURL, IsPrimary: true}}
+			if _, _, err := saveGallery(ctx, tx, p, nil, primary); err != nil {
+				return "", err
+			}
+		}
+		return "", tx.Audit().Record(ctx, actor.audit(models.AuditActionCreate, models.AuditEntityProduct, p.ID, nil, newProductAuditSnapshot(p)))
+
+	case models.ImportActionUpdate:
+		before := plan.before
+		p.UpdatedAt = time.Now()
+		if err := tx.Products().Update(ctx, p); err != nil {
+			return "", err
+		}
+		if p.Price != before.Price {
+			if err := recordPrice(ctx, tx, actor, p.ID, p.Price, &before.Price, "Product import"); err != nil {
+				return "", err
+			}
+		}
+		if p.Stock != before.Stock {
+			stock, err := setStock(ctx, tx, actor, p.ID, p.Stock, "Product import")
+			if err != nil {
+				return "", err
+			}
+			before.Stock = stock
+		}
+		replaced := ""
+		if p.ImageURL != before.ImageURL {
+			if err := replacePrimaryImage(ctx, tx, p); err != nil {
+				return "", err
+			}
+			replaced = before.CloudinaryID
+		}
+		return replaced, tx.Audit().Record(ctx, actor.audit(models.AuditActionUpdate, models.AuditEntityProduct, p.ID,
+			newProductAuditSnapshot(before), newProductAuditSnapshot(p)))
+	}
+	return "", nil
+}
+
+// productChanges lists the sheet columns that differ between before and
+// after, in column order. Every filled column of a new product is a change.
+func productChanges(before, after models.Product, categoryNames map[int]string) []models.ProductImportChange {
+	from := productSheetRow(before, categoryNames[before.CategoryID])
+	to := productSheetRow(after, categoryNames[after.CategoryID])
+	if before.ID == 0 {
+		from = make([]string, len(ProductSheetColumns))
+	}
+
+	var changes []models.ProductImportChange
+	for i, column := range ProductSheetColumns {
+		if from[i] != to[i] {
+			changes = append(changes, models.ProductImportChange{Field: column, From: from[i], To: to[i]})
+		}
+	}
+	return changes
+}
+
+// productSheetRow is p's row of the export, in ProductSheetColumns order.
+func productSheetRow(p models.Product, category string) []string {
+	return []string{
+		p.SKU,
+		p.Name,
+		p.Description,
+		category,
+		strconv.Itoa(p.Price),
+		strconv.Itoa(p.Stock),
+		strconv.Itoa(p.LowStockThreshold),
+		strconv.FormatBool(p.IsFlashSale),
+		strconv.FormatBool(p.IsFeatured),
+		strconv.FormatBool(p.IsBuy1Get1),
+		strconv.FormatBool(p.IsActive),
+		p.ImageURL,
+	}
+}
+
+func productInputOf(p models.Product) ProductInput {
+	return ProductInput{
+		SKU:               p.SKU,
+		Name:              p.Name,
+		Description:       p.Description,
+		CategoryID:        p.CategoryID,
+		Price:             p.Price,
+		Stock:             p.Stock,
+		LowStockThreshold: p.LowStockThreshold,
+		IsFlashSale:       p.IsFlashSale,
+		IsFeatured:        p.IsFeatured,
+		IsBuy1Get1:        p.IsBuy1Get1,
+		IsActive:          p.IsActive,
+	}
+}
+
+func blankRow(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"strings"
+)
+
+// StockMovements returns one page of a product's stock ledger, newest first.
+func (s *ProductService) StockMovements(ctx context.Context, productID int, page pagination.Params) ([]models.StockMovement, int, error) {
+	if _, err := s.storedProduct(ctx, productID, "Failed to retrieve stock movements"); err != nil {
+		return nil, 0, err
+	}
+	movements, total, err := s.store.Inventory().Movements(ctx, repositories.MovementFilter{ProductID: productID, Params: page})
+	if err != nil {
+		return nil, 0, fail("Failed to retrieve stock movements", err)
+	}
+	return movements, total, nil
+}
+
+// Restock books quantity units delivered to the shop.
+func (s *ProductService) Restock(ctx context.Context, actor Actor, productID, quantity int, reason string) (models.StockMovement, error) {
+	if quantity <= 0 {
+		return models.StockMovement{}, invalid("Quantity must be greater than 0")
+	}
+	return s.changeStock(ctx, actor, productID, func(int) (models.StockMovement, error) {
+		return models.StockMovement{Type: models.StockMovementRestock, Quantity: quantity, Reason: reason}, nil
+	})
+}
+
+// Waste writes off quantity spoiled or damaged units. A reason is required.
+func (s *ProductService) Waste(ctx context.Context, actor Actor, productID, quantity int, reason string) (models.StockMovement, error) {
+	if quantity <= 0 {
+		return models.StockMovement{}, invalid("Quantity must be greater than 0")
+	}
+	if strings.TrimSpace(reason) == "" {
+		return models.StockMovement{}, invalid("Reason is required")
+	}
+	return s.changeStock(ctx, actor, productID, func(stock int) (models.StockMovement, error) {
+		if quantity > stock {
+			return models.StockMovement{}, invalid("Cannot write off more than the %d in stock", stock)
+		}
+		return models.StockMovement{Type: models.StockMovementWaste, Quantity: -quantity, Reason: reason}, nil
+	})
+}
+
+// Stocktake sets the stock to the counted level and records the difference
+// as an adjustment. When the count matches, nothing is recorded and the
+// returned movement has no ID.
+func (s *ProductService) Stocktake(ctx context.Context, actor Actor, productID, counted int, reason string) (models.StockMovement, error) {
+	if counted < 0 {
+		return models.StockMovement{}, invalid("Counted stock cannot be negative")
+	}
+	if strings.TrimSpace(reason) == "" {
+		reason = "Stocktake"
+	}
+	return s.changeStock(ctx, actor, productID, func(stock int) (models.StockMovement, error) {
+		return models.StockMovement{Type: models.StockMovementAdjustment, Quantity: counted - stock, Reason: reason}, nil
+	})
+}
+
+// LowStock lists the unarchived products below their low-stock threshold,
+// lowest stock first.
+func (s *ProductService) LowStock(ctx context.Context) ([]models.Product, error) {
+	products, err := s.store.Inventory().LowStock(ctx)
+	if err != nil {
+		return nil, fail("Failed to retrieve low stock products", err)
+	}
+	return products, nil
+}
+
+// changeStock locks the product's stock, lets change describe the movement
+// from the current level and books it. A movement of zero is not recorded.
+func (s *ProductService) changeStock(ctx context.Context, actor Actor, productID int,
+	change func(stock int) (models.StockMovement, error)) (models.StockMovement, error) {
+	if _, err := s.storedProduct(ctx, productID, "Failed to update stock"); err != nil {
+		return models.StockMovement{}, err
+	}
+
+	var m models.StockMovement
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		stock, err := tx.Products().LockStock(ctx, productID)
+		if err != nil {
+			return err
+		}
+		if m, err = change(stock); err != nil {
+			return err
+		}
+		m.ProductID = productID
+		m.Reason = strings.TrimSpace(m.Reason)
+		m.ActorID = actorID(actor)
+		if m.Quantity == 0 {
+			m.StockAfter = stock
+			return nil
+		}
+		return moveStock(ctx, tx, &m)
+	})
+	if err != nil {
+		var serviceErr *Error
+		if errors.As(err, &serviceErr) {
+			return models.StockMovement{}, serviceErr
+		}
+		if errors.Is(err, repositories.ErrNotFound) {
+			return models.StockMovement{}, notFound("Product not found")
+		}
+		if errors.Is(err, repositories.ErrInsufficientStock) {
+			return models.StockMovement{}, conflict("Stock cannot drop below zero")
+		}
+		return models.StockMovement{}, fail("Failed to update stock", err)
+	}
+
+	if m.ID != 0 {
+		s.invalidate(ctx)
+	}
+	return m, nil
+}
+
+// moveStock applies m's quantity to the product's stock and records m in the
+// ledger with the level it left.
+func moveStock(ctx context.Context, tx repositories.Store, m *models.StockMovement) error {
+	stock, err := tx.Products().AdjustStock(ctx, m.ProductID, m.Quantity)
+	if err != nil {
+		return err
+	}
+	m.StockAfter = stock
+	return tx.Inventory().Record(ctx, m)
+}
+
+// setStock sets the product's stock to stock, recording the difference from
+// the locked level as an adjustment, so sales made since the caller read the
+// product are not lost from the ledger. It returns the level it replaced.
+func setStock(ctx context.Context, tx repositories.Store, actor Actor, productID, stock int, reason string) (int, error) {
+	current, err := tx.Products().LockStock(ctx, productID)
+	if err != nil {
+		return 0, err
+	}
+	if stock != current {
+		m := models.StockMovement{ProductID: productID, Type: models.StockMovementAdjustment,
+			Quantity: stock - current, Reason: reason, ActorID: actorID(actor)}
+		if err := moveStock(ctx, tx, &m); err != nil {
+			return 0, err
+		}
+	}
+	return current, nil
+}
+
+// actorID is the ledger's actor for a, or nil for changes nobody signed in
+// made.
+func actorID(a Actor) *int {
+	if a.ID <= 0 {
+		return nil
+	}
+	id := a.ID
+	return &id
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+)
+
+// OptionInput is a new entry of a product's option matrix. Nil option IDs
+// leave that kind of choice out of the combination.
+type OptionInput struct {
+	SizeID          *int
+	TemperatureID   *int
+	VariantID       *int
+	PriceAdjustment int
+	IsActive        *bool
+}
+
+// OptionUpdate holds the option fields to change; nil fields are left as
+// they are. The combination itself cannot change: delete the option and
+// add the new one instead.
+type OptionUpdate struct {
+	PriceAdjustment *int
+	IsActive        *bool
+}
+
+// Options returns the whole option matrix of a product, inactive options
+// included, cheapest first.
+func (s *ProductService) Options(ctx context.Context, productID int) ([]models.ProductOption, error) {
+	if _, err := s.storedProduct(ctx, productID, "Failed to retrieve product options"); err != nil {
+		return nil, err
+	}
+	options, err := s.store.Products().Options(ctx, productID)
+	if err != nil {
+		return nil, fail("Failed to retrieve product options", err)
+	}
+	return options, nil
+}
+
+// CreateOption adds a size, temperature and variant combination to the
+// product's matrix. Each combination can only be listed once.
+func (s *ProductService) CreateOption(ctx context.Context, actor Actor, productID int, in OptionInput) (models.ProductOption, error) {
+	if _, err := s.storedProduct(ctx, productID, "Failed to create product option"); err != nil {
+		return models.ProductOption{}, err
+	}
+	if in.PriceAdjustment < 0 {
+		return models.ProductOption{}, invalid("Price adjustment cannot be negative")
+	}
+	if err := s.checkChoices(ctx, in); err != nil {
+		return models.ProductOption{}, err
+	}
+
+	o := models.ProductOption{
+		ProductID:       productID,
+		SizeID:          in.SizeID,
+		TemperatureID:   in.TemperatureID,
+		VariantID:       in.VariantID,
+		PriceAdjustment: in.PriceAdjustment,
+		IsActive:        in.IsActive == nil || *in.IsActive,
+	}
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		existing, err := tx.Products().Options(ctx, productID)
+		if err != nil {
+			return err
+		}
+		for _, e := range existing {
+			if sameOption(e.SizeID, o.SizeID) && sameOption(e.TemperatureID, o.TemperatureID) && sameOption(e.VariantID, o.VariantID) {
+				return conflict("Product already has this option combination")
+			}
+		}
+		if err := tx.Products().CreateOption(ctx, &o); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionCreate, models.AuditEntityProductOption, o.ID, nil, o))
+	})
+	if err != nil {
+		var serviceErr *Error
+		if errors.As(err, &serviceErr) {
+			return models.ProductOption{}, serviceErr
+		}
+		return models.ProductOption{}, fail("Failed to create product option", err)
+	}
+
+	s.invalidate(ctx)
+	return s.option(ctx, productID, o.ID)
+}
+
+// UpdateOption changes the price adjustment or active flag of an option.
+// Carts holding a deactivated combination can no longer check out.
+func (s *ProductService) UpdateOption(ctx context.Context, actor Actor, productID, optionID int, in OptionUpdate) (models.ProductOption, error) {
+	before, err := s.option(ctx, productID, optionID)
+	if err != nil {
+		return models.ProductOption{}, err
+	}
+	after := before
+	if in.PriceAdjustment != nil {
+		if *in.PriceAdjustment < 0 {
+			return models.ProductOption{}, invalid("Price adjustment cannot be negative")
+		}
+		after.PriceAdjustment = *in.PriceAdjustment
+	}
+	if in.IsActive != nil {
+		after.IsActive = *in.IsActive
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Products().UpdateOption(ctx, after); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionUpdate, models.AuditEntityProductOption, optionID, before, after))
+	})
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.ProductOption{}, notFound("Option not found")
+	}
+	if err != nil {
+		return models.ProductOption{}, fail("Failed to update product option", err)
+	}
+
+	s.invalidate(ctx)
+	return s.option(ctx, productID, optionID)
+}
+
+// DeleteOption removes a combination from the product's matrix.
+func (s *ProductService) DeleteOption(ctx context.Context, actor Actor, productID, optionID int) error {
+	before, err := s.option(ctx, productID, optionID)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Products().DeleteOption(ctx, optionID); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionDelete, models.AuditEntityProductOption, optionID, before, nil))
+	})
+	if errors.Is(err, repositories.ErrNotFound) {
+		return notFound("Option not found")
+	}
+	if err != nil {
+		return fail("Failed to delete product option", err)
+	}
+
+	s.invalidate(ctx)
+	return nil
+}
+
+// option returns one option of the product, or a 404 when the product does
+// not exist or has no such option.
+func (s *ProductService) option(ctx context.Context, productID, optionID int) (models.ProductOption, error) {
+	options, err := s.Options(ctx, productID)
+	if err != nil {
+		return models.ProductOption{}, err
+	}
+	for _, o := range options {
+		if o.ID == optionID {
+			return o, nil
+		}
+	}
+	return models.ProductOption{}, notFound("Option not found")
+}
+
+// checkChoices rejects option IDs that are not an active size, temperature
+// or variant.
+func (s *ProductService) checkChoices(ctx context.Context, in OptionInput) error {
+	products := s.store.Products()
+	if in.SizeID != nil {
+		sizes, err := products.Sizes(ctx)
+		if err != nil {
+			return fail("Failed to create product option", err)
+		}
+		if !hasChoice(sizes, *in.SizeID, func(size models.ProductSizeV2) int { return size.ID }) {
+			return invalid("Invalid size ID")
+		}
+	}
+	if in.TemperatureID != nil {
+		temperatures, err := products.Temperatures(ctx)
+		if err != nil {
+			return fail("Failed to create product option", err)
+		}
+		if !hasChoice(temperatures, *in.TemperatureID, func(t models.ProductTemperatureV2) int { return t.ID }) {
+			return invalid("Invalid temperature ID")
+		}
+	}
+	if in.VariantID != nil {
+		variants, err := products.Variants(ctx)
+		if err != nil {
+			return fail("Failed to create product option", err)
+		}
+		if !hasChoice(variants, *in.VariantID, func(v models.ProductVariantV2) int { return v.ID }) {
+			return invalid("Invalid variant ID")
+		}
+	}
+	return nil
+}
+
+func hasChoice[T any](choices []T, id int, idOf func(T) int) bool {
+	for _, c := range choices {
+		if idOf(c) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// offeredChoices lists the sizes and temperatures a product's active options
+// offer, in the options' order. A size's price adjustment is that of its
+// cheapest combination.
+func offeredChoices(options []models.ProductOption) ([]models.ProductSizeV2, []models.ProductTemperatureV2) {
+	sizes := []models.ProductSizeV2{}
+	temperatures := []models.ProductTemperatureV2{}
+	seenSize, seenTemperature := map[int]bool{}, map[int]bool{}
+	for _, o := range options {
+		if !o.IsActive {
+			continue
+		}
+		if o.SizeID != nil && !seenSize[*o.SizeID] {
+			seenSize[*o.SizeID] = true
+			sizes = append(sizes, models.ProductSizeV2{ID: *o.SizeID, Name: o.Size, PriceAdjustment: o.PriceAdjustment})
+		}
+		if o.TemperatureID != nil && !seenTemperature[*o.TemperatureID] {
+			seenTemperature[*o.TemperatureID] = true
+			temperatures = append(temperatures, models.ProductTemperatureV2{ID: *o.TemperatureID, Name: o.Temperature})
+		}
+	}
+	return sizes, temperatures
+}
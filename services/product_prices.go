@@ -0,0 +1,229 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// schedulerActor is recorded in the audit log for the price changes the
+// scheduler applies.
+var schedulerActor = Actor{Email: "scheduler"}
+
+// PriceHistory returns one page of a product's applied prices, latest first.
+func (s *ProductService) PriceHistory(ctx context.Context, productID int, page pagination.Params) ([]models.ProductPrice, int, error) {
+	if _, err := s.storedProduct(ctx, productID, "Failed to retrieve price history"); err != nil {
+		return nil, 0, err
+	}
+	prices, total, err := s.store.Prices().History(ctx, repositories.PriceFilter{ProductID: productID, Params: page})
+	if err != nil {
+		return nil, 0, fail("Failed to retrieve price history", err)
+	}
+	return prices, total, nil
+}
+
+// PriceAt returns the price the product had at t.
+func (s *ProductService) PriceAt(ctx context.Context, productID int, t time.Time) (models.ProductPrice, error) {
+	if _, err := s.storedProduct(ctx, productID, "Failed to retrieve price history"); err != nil {
+		return models.ProductPrice{}, err
+	}
+	price, err := s.store.Prices().At(ctx, productID, t)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.ProductPrice{}, notFound("No price recorded for the product at that time")
+	}
+	if err != nil {
+		return models.ProductPrice{}, fail("Failed to retrieve price history", err)
+	}
+	return price, nil
+}
+
+// ScheduledPrices lists the pending price changes of a product, or of every
+// product when productID is 0, soonest first.
+func (s *ProductService) ScheduledPrices(ctx context.Context, productID int) ([]models.ProductPrice, error) {
+	if productID != 0 {
+		if _, err := s.storedProduct(ctx, productID, "Failed to retrieve scheduled prices"); err != nil {
+			return nil, err
+		}
+	}
+	prices, err := s.store.Prices().Scheduled(ctx, productID)
+	if err != nil {
+		return nil, fail("Failed to retrieve scheduled prices", err)
+	}
+	return prices, nil
+}
+
+// SchedulePrice plans a change of the product's price to price at
+// effectiveAt. The scheduler applies it once that time has passed.
+func (s *ProductService) SchedulePrice(ctx context.Context, actor Actor, productID, price int, effectiveAt time.Time,
+	reason string) (models.ProductPrice, error) {
+	p, err := s.storedProduct(ctx, productID, "Failed to schedule price change")
+	if err != nil {
+		return models.ProductPrice{}, err
+	}
+	if p.DeletedAt != nil {
+		return models.ProductPrice{}, conflict("Product is archived")
+	}
+	if price < 1000 {
+		return models.ProductPrice{}, invalid("Price must be at least 1000")
+	}
+	if !effectiveAt.After(time.Now()) {
+		return models.ProductPrice{}, invalid("Effective time must be in the future")
+	}
+
+	scheduled, err := s.store.Prices().Scheduled(ctx, productID)
+	if err != nil {
+		return models.ProductPrice{}, fail("Failed to schedule price change", err)
+	}
+	for _, other := range scheduled {
+		if other.EffectiveAt.Equal(effectiveAt) {
+			return models.ProductPrice{}, conflict("A price change for %s is already scheduled at that time", p.Name)
+		}
+	}
+
+	change := models.ProductPrice{
+		ProductID:   productID,
+		Price:       price,
+		EffectiveAt: effectiveAt,
+		Reason:      strings.TrimSpace(reason),
+		ActorID:     actorID(actor),
+	}
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Prices().Record(ctx, &change); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionCreate, models.AuditEntityProductPrice, change.ID,
+			nil, newPriceAuditSnapshot(change)))
+	})
+	if err != nil {
+		return models.ProductPrice{}, fail("Failed to schedule price change", err)
+	}
+	change.ActorEmail = actor.Email
+	return change, nil
+}
+
+// CancelScheduledPrice drops a pending price change of the product.
+func (s *ProductService) CancelScheduledPrice(ctx context.Context, actor Actor, productID, changeID int) error {
+	if _, err := s.storedProduct(ctx, productID, "Failed to cancel price change"); err != nil {
+		return err
+	}
+	change, err := s.store.Prices().Get(ctx, changeID)
+	if errors.Is(err, repositories.ErrNotFound) || (err == nil && (change.ProductID != productID || change.AppliedAt != nil)) {
+		return notFound("Scheduled price change not found")
+	}
+	if err != nil {
+		return fail("Failed to cancel price change", err)
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Prices().Delete(ctx, changeID); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionDelete, models.AuditEntityProductPrice, changeID,
+			newPriceAuditSnapshot(change), nil))
+	})
+	if errors.Is(err, repositories.ErrNotFound) {
+		return notFound("Scheduled price change not found")
+	}
+	if err != nil {
+		return fail("Failed to cancel price change", err)
+	}
+	return nil
+}
+
+// ApplyScheduledPrices applies every pending price change whose effective
+// time is not after now, in order, and returns how many it applied. The
+// product caches are cleared when any was.
+func (s *ProductService) ApplyScheduledPrices(ctx context.Context, now time.Time) (int, error) {
+	applied := 0
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		due, err := tx.Prices().Due(ctx, now)
+		if err != nil {
+			return err
+		}
+		for _, change := range due {
+			product, err := tx.Products().Get(ctx, change.ProductID)
+			if err != nil {
+				return err
+			}
+			previous, err := tx.Products().SetPrice(ctx, change.ProductID, change.Price)
+			if err != nil {
+				return err
+			}
+			change.PreviousPrice = &previous
+			change.AppliedAt = &now
+			if err := tx.Prices().MarkApplied(ctx, change); err != nil {
+				return err
+			}
+
+			before := product
+			before.Price = previous
+			product.Price = change.Price
+			if err := tx.Audit().Record(ctx, schedulerActor.audit(models.AuditActionUpdate, models.AuditEntityProduct,
+				product.ID, newProductAuditSnapshot(before), newProductAuditSnapshot(product))); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fail("Failed to apply scheduled prices", err)
+	}
+
+	if applied > 0 {
+		s.invalidate(ctx)
+	}
+	return applied, nil
+}
+
+// RunPriceScheduler applies scheduled price changes every interval until ctx
+// is done. Failures are logged and retried on the next tick.
+func (s *ProductService) RunPriceScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.ApplyScheduledPrices(ctx, time.Now()); err != nil {
+			log.Printf("Scheduled prices not applied: %v", err)
+		} else if n > 0 {
+			log.Printf("Applied %d scheduled price changes", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordPrice adds a price set directly, rather than scheduled, to the
+// product's history. previous is nil for the product's first price.
+func recordPrice(ctx context.Context, tx repositories.Store, actor Actor, productID, price int, previous *int, reason string) error {
+	now := time.Now()
+	return tx.Prices().Record(ctx, &models.ProductPrice{
+		ProductID:     productID,
+		Price:         price,
+		PreviousPrice: previous,
+		EffectiveAt:   now,
+		AppliedAt:     &now,
+		Reason:        reason,
+		ActorID:       actorID(actor),
+	})
+}
+
+type priceAuditSnapshot struct {
+	ProductID   int       `json:"product_id"`
+	Price       int       `json:"price"`
+	EffectiveAt time.Time `json:"effective_at"`
+	Reason      string    `json:"reason"`
+}
+
+func newPriceAuditSnapshot(p models.ProductPrice) priceAuditSnapshot {
+	return priceAuditSnapshot{ProductID: p.ProductID, Price: p.Price, EffectiveAt: p.EffectiveAt, Reason: p.Reason}
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRecommendationLimit and MaxRecommendationLimit bound how many
+	// recommendations one request returns.
+	DefaultRecommendationLimit = 6
+	MaxRecommendationLimit     = 20
+
+	// detailRecommendations is how many recommendations the product page
+	// shows.
+	detailRecommendations = 3
+	// minBoughtTogetherOrders is how many orders must hold two products
+	// before one is suggested with the other.
+	minBoughtTogetherOrders = 2
+)
+
+// cartRecommendationTypes leaves upsells out of the cart: they suggest
+// something instead of a product rather than something to go with it.
+var cartRecommendationTypes = []string{models.RecommendationCrossSell, models.RecommendationPairing}
+
+// RecommendationInput is a new curated recommendation. IsActive defaults to
+// true.
+type RecommendationInput struct {
+	RecommendedProductID int
+	Type                 string
+	Priority             int
+	IsActive             *bool
+}
+
+// RecommendationUpdate holds the recommendation fields to change; nil fields
+// are left as they are. To recommend another product, delete the
+// recommendation and add a new one.
+type RecommendationUpdate struct {
+	Type     *string
+	Priority *int
+	IsActive *bool
+}
+
+// Recommendations suggests up to limit products to go with an active
+// product. The shop's curated picks come first, by priority, then the
+// products most often ordered with it, then others from its category and
+// finally the best sellers, so the list is only short when the menu is.
+func (s *ProductService) Recommendations(ctx context.Context, productID, limit int, locale string) ([]models.Product, error) {
+	if productID <= 0 {
+		return nil, invalid("Invalid product ID")
+	}
+	p, err := s.store.Products().GetActive(ctx, productID, locale)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, notFound("Product not found")
+	}
+	if err != nil {
+		return nil, fail("Failed to retrieve recommendations", err)
+	}
+
+	products, err := s.recommend(ctx, []int{p.ID}, models.RecommendationTypes, []int{p.CategoryID},
+		recommendationLimit(limit), locale)
+	if err != nil {
+		return nil, fail("Failed to retrieve recommendations", err)
+	}
+	return products, nil
+}
+
+// CartRecommendations suggests up to limit products to add to the user's
+// cart: cross-sells and pairings of what is in it, then what is often
+// ordered with it, then products from the same categories and the best
+// sellers. An empty cart gets the best sellers.
+func (s *ProductService) CartRecommendations(ctx context.Context, userID, limit int, locale string) ([]models.Product, error) {
+	items, err := s.store.Carts().Items(ctx, userID, locale)
+	if err != nil {
+		return nil, fail("Failed to retrieve recommendations", err)
+	}
+
+	productIDs, categoryIDs := []int{}, []int{}
+	for _, item := range items {
+		if !slices.Contains(productIDs, item.ProductID) {
+			productIDs = append(productIDs, item.ProductID)
+		}
+		if !slices.Contains(categoryIDs, item.CategoryID) {
+			categoryIDs = append(categoryIDs, item.CategoryID)
+		}
+	}
+
+	products, err := s.recommend(ctx, productIDs, cartRecommendationTypes, categoryIDs, recommendationLimit(limit), locale)
+	if err != nil {
+		return nil, fail("Failed to retrieve recommendations", err)
+	}
+	return products, nil
+}
+
+// recommend fills up to limit places for productIDs from each source in
+// turn, skipping productIDs themselves and products already picked, and
+// prices the picks for running flash sales.
+func (s *ProductService) recommend(ctx context.Context, productIDs []int, types []string, categoryIDs []int, limit int,
+	locale string) ([]models.Product, error) {
+	picks := []models.Product{}
+	seen := map[int]bool{}
+	for _, id := range productIDs {
+		seen[id] = true
+	}
+	add := func(products []models.Product, recommendationType string) {
+		for _, p := range products {
+			if len(picks) == limit {
+				return
+			}
+			if seen[p.ID] {
+				continue
+			}
+			seen[p.ID] = true
+			if p.RecommendationType == "" {
+				p.RecommendationType = recommendationType
+			}
+			picks = append(picks, p)
+		}
+	}
+
+	recommendations := s.store.Recommendations()
+	if len(productIDs) > 0 {
+		curated, err := recommendations.Curated(ctx, productIDs, types, locale)
+		if err != nil {
+			return nil, err
+		}
+		add(curated, "")
+
+		if len(picks) < limit {
+			together, err := recommendations.BoughtTogether(ctx, productIDs, limit, locale)
+			if err != nil {
+				return nil, err
+			}
+			add(together, models.RecommendationBoughtTogether)
+		}
+	}
+	for _, categoryID := range categoryIDs {
+		if len(picks) == limit {
+			break
+		}
+		related, err := s.store.Products().Related(ctx, categoryID, 0, limit+len(seen), locale)
+		if err != nil {
+			return nil, err
+		}
+		add(related, models.RecommendationSameCategory)
+	}
+	if len(picks) < limit {
+		bestSellers, _, err := s.store.Products().List(ctx, repositories.ProductFilter{
+			Params: pagination.Params{Limit: limit + len(seen)},
+			Sort:   repositories.SortBestSelling,
+			Locale: locale,
+		})
+		if err != nil {
+			return nil, err
+		}
+		add(bestSellers, models.RecommendationBestSelling)
+	}
+
+	if err := s.priceFlashSales(ctx, picks); err != nil {
+		return nil, err
+	}
+	return picks, nil
+}
+
+// recommendationLimit brings a requested number of recommendations within
+// bounds, using the default when none is given.
+func recommendationLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultRecommendationLimit
+	}
+	return min(limit, MaxRecommendationLimit)
+}
+
+// ProductRecommendations returns the shop's curated recommendations for a
+// product, inactive ones included, highest priority first.
+func (s *ProductService) ProductRecommendations(ctx context.Context, productID int) ([]models.ProductRecommendation, error) {
+	if _, err := s.storedProduct(ctx, productID, "Failed to retrieve recommendations"); err != nil {
+		return nil, err
+	}
+	recommendations, err := s.store.Recommendations().List(ctx, productID)
+	if err != nil {
+		return nil, fail("Failed to retrieve recommendations", err)
+	}
+	return recommendations, nil
+}
+
+// CreateRecommendation curates another product to show with the product.
+// Each product can only be recommended once per product.
+func (s *ProductService) CreateRecommendation(ctx context.Context, actor Actor, productID int,
+	in RecommendationInput) (models.ProductRecommendation, error) {
+	if _, err := s.storedProduct(ctx, productID, "Failed to create recommendation"); err != nil {
+		return models.ProductRecommendation{}, err
+	}
+	in.Type = strings.TrimSpace(in.Type)
+	if err := validateRecommendationType(in.Type); err != nil {
+		return models.ProductRecommendation{}, err
+	}
+	if in.RecommendedProductID == productID {
+		return models.ProductRecommendation{}, invalid("A product cannot recommend itself")
+	}
+	if in.RecommendedProductID <= 0 {
+		return models.ProductRecommendation{}, invalid("Invalid recommended product ID")
+	}
+	_, err := s.store.Products().Get(ctx, in.RecommendedProductID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.ProductRecommendation{}, invalid("Invalid recommended product ID")
+	}
+	if err != nil {
+		return models.ProductRecommendation{}, fail("Failed to create recommendation", err)
+	}
+
+	rec := models.ProductRecommendation{
+		ProductID:            productID,
+		RecommendedProductID: in.RecommendedProductID,
+		Type:                 in.Type,
+		Priority:             in.Priority,
+		IsActive:             in.IsActive == nil || *in.IsActive,
+	}
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		existing, err := tx.Recommendations().List(ctx, productID)
+		if err != nil {
+			return err
+		}
+		for _, e := range existing {
+			if e.RecommendedProductID == rec.RecommendedProductID {
+				return conflict("Product already recommends this product")
+			}
+		}
+		if err := tx.Recommendations().Create(ctx, &rec); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionCreate, models.AuditEntityProductRecommendation,
+			rec.ID, nil, rec))
+	})
+	if err != nil {
+		var serviceErr *Error
+		if errors.As(err, &serviceErr) {
+			return models.ProductRecommendation{}, serviceErr
+		}
+		return models.ProductRecommendation{}, fail("Failed to create recommendation", err)
+	}
+	s.invalidate(ctx)
+	return s.recommendation(ctx, productID, rec.ID)
+}
+
+// UpdateRecommendation changes the type, priority or active flag of a
+// curated recommendation.
+func (s *ProductService) UpdateRecommendation(ctx context.Context, actor Actor, productID, recommendationID int,
+	in RecommendationUpdate) (models.ProductRecommendation, error) {
+	before, err := s.recommendation(ctx, productID, recommendationID)
+	if err != nil {
+		return models.ProductRecommendation{}, err
+	}
+	after := before
+	if in.Type != nil {
+		after.Type = strings.TrimSpace(*in.Type)
+		if err := validateRecommendationType(after.Type); err != nil {
+			return models.ProductRecommendation{}, err
+		}
+	}
+	if in.Priority != nil {
+		after.Priority = *in.Priority
+	}
+	if in.IsActive != nil {
+		after.IsActive = *in.IsActive
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Recommendations().Update(ctx, after); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionUpdate, models.AuditEntityProductRecommendation,
+			recommendationID, before, after))
+	})
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.ProductRecommendation{}, notFound("Recommendation not found")
+	}
+	if err != nil {
+		return models.ProductRecommendation{}, fail("Failed to update recommendation", err)
+	}
+	s.invalidate(ctx)
+	return s.recommendation(ctx, productID, recommendationID)
+}
+
+// DeleteRecommendation stops recommending a product with the product.
+func (s *ProductService) DeleteRecommendation(ctx context.Context, actor Actor, productID, recommendationID int) error {
+	before, err := s.recommendation(ctx, productID, recommendationID)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Recommendations().Delete(ctx, recommendationID); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionDelete, models.AuditEntityProductRecommendation,
+			recommendationID, before, nil))
+	})
+	if errors.Is(err, repositories.ErrNotFound) {
+		return notFound("Recommendation not found")
+	}
+	if err != nil {
+		return fail("Failed to delete recommendation", err)
+	}
+	s.invalidate(ctx)
+	return nil
+}
+
+// recommendation returns one curated recommendation of the product, or a
+// 404 when the product does not exist or has no such recommendation.
+func (s *ProductService) recommendation(ctx context.Context, productID, recommendationID int) (models.ProductRecommendation, error) {
+	recommendations, err := s.ProductRecommendations(ctx, productID)
+	if err != nil {
+		return models.ProductRecommendation{}, err
+	}
+	for _, rec := range recommendations {
+		if rec.ID == recommendationID {
+			return rec, nil
+		}
+	}
+	return models.ProductRecommendation{}, notFound("Recommendation not found")
+}
+
+func validateRecommendationType(recommendationType string) error {
+	if !slices.Contains(models.RecommendationTypes, recommendationType) {
+		return invalid("Recommendation type must be one of: %s", strings.Join(models.RecommendationTypes, ", "))
+	}
+	return nil
+}
+
+// RefreshBoughtTogether recounts which products are ordered together from
+// the order history and returns the number of pairs now suggested together.
+func (s *ProductService) RefreshBoughtTogether(ctx context.Context, now time.Time) (int, error) {
+	var pairs int
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		var err error
+		pairs, err = tx.Recommendations().RefreshBoughtTogether(ctx, minBoughtTogetherOrders, now)
+		return err
+	})
+	if err != nil {
+		return 0, fail("Failed to refresh recommendations", err)
+	}
+	s.invalidate(ctx)
+	return pairs, nil
+}
+
+// RunRecommendationRefresher refreshes the bought-together pairs and the
+// product popularity every interval until ctx is done. Failures are logged
+// and retried on the next tick.
+func (s *ProductService) RunRecommendationRefresher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.RefreshBoughtTogether(ctx, time.Now()); err != nil {
+			log.Printf("Recommendations not refreshed: %v", err)
+		} else {
+			log.Printf("Refreshed bought-together recommendations: %d product pairs", n)
+		}
+		if n, err := s.RefreshPopularity(ctx, time.Now()); err != nil {
+			log.Printf("Popularity not refreshed: %v", err)
+		} else {
+			log.Printf("Refreshed product popularity: %d old views deleted", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxReviewLength is the longest review text, and reply, in characters.
+const maxReviewLength = 2000
+
+// Reviews returns one page of an active product's approved reviews, newest
+// first, and the summary of its ratings.
+func (s *ProductService) Reviews(ctx context.Context, productID int, page pagination.Params) ([]models.ProductReview, int, models.RatingSummary, error) {
+	if _, err := s.store.Products().GetActive(ctx, productID, ""); err != nil {
+		return nil, 0, models.RatingSummary{}, notFound("Product not found")
+	}
+
+	reviews, total, err := s.store.Reviews().List(ctx, repositories.ReviewFilter{
+		Params:    page,
+		ProductID: productID,
+		Status:    models.ReviewApproved,
+	})
+	if err != nil {
+		return nil, 0, models.RatingSummary{}, fail("Failed to retrieve reviews", err)
+	}
+	counts, err := s.store.Reviews().Ratings(ctx, productID)
+	if err != nil {
+		return nil, 0, models.RatingSummary{}, fail("Failed to retrieve reviews", err)
+	}
+	return reviews, total, models.NewRatingSummary(counts), nil
+}
+
+// CreateReview records the user's review of an active product they received
+// in a completed order. It waits for moderation before it is shown.
+func (s *ProductService) CreateReview(ctx context.Context, userID, productID int, in models.ReviewRequest) (models.ProductReview, error) {
+	if productID <= 0 {
+		return models.ProductReview{}, invalid("Invalid product ID")
+	}
+	if in.Rating == nil {
+		return models.ProductReview{}, invalid("Rating is required")
+	}
+	review := models.ProductReview{ProductID: productID, UserID: userID, Status: models.ReviewPending}
+	if err := applyReviewRequest(&review, in); err != nil {
+		return models.ProductReview{}, err
+	}
+
+	_, err := s.store.Products().GetActive(ctx, productID, "")
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.ProductReview{}, notFound("Product not found")
+	}
+	if err != nil {
+		return models.ProductReview{}, fail("Failed to create review", err)
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		purchased, err := tx.Orders().Purchased(ctx, userID, productID)
+		if err != nil {
+			return err
+		}
+		if !purchased {
+			return forbidden("Only customers who received the product in a completed order can review it")
+		}
+		if _, err := tx.Reviews().Find(ctx, productID, userID); err == nil {
+			return conflict("You have already reviewed this product")
+		} else if !errors.Is(err, repositories.ErrNotFound) {
+			return err
+		}
+		return tx.Reviews().Create(ctx, &review)
+	})
+	var serviceErr *Error
+	if errors.As(err, &serviceErr) {
+		return models.ProductReview{}, serviceErr
+	}
+	if err != nil {
+		return models.ProductReview{}, fail("Failed to create review", err)
+	}
+	return s.review(ctx, review.ID, "Failed to create review")
+}
+
+// UpdateReview edits the user's own review, which goes back to the
+// moderation queue.
+func (s *ProductService) UpdateReview(ctx context.Context, userID, productID, reviewID int, in models.ReviewRequest) (models.ProductReview, error) {
+	review, err := s.ownReview(ctx, userID, productID, reviewID, "Failed to update review")
+	if err != nil {
+		return models.ProductReview{}, err
+	}
+	if err := applyReviewRequest(&review, in); err != nil {
+		return models.ProductReview{}, err
+	}
+	wasApproved := review.Status == models.ReviewApproved
+	review.Status = models.ReviewPending
+	review.UpdatedAt = time.Now()
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Reviews().Update(ctx, review); err != nil {
+			return err
+		}
+		return tx.Reviews().Recompute(ctx, productID)
+	})
+	if err != nil {
+		return models.ProductReview{}, fail("Failed to update review", err)
+	}
+	if wasApproved {
+		s.invalidate(ctx)
+	}
+	return s.review(ctx, review.ID, "Failed to update review")
+}
+
+// DeleteReview removes the user's own review.
+func (s *ProductService) DeleteReview(ctx context.Context, userID, productID, reviewID int) error {
+	review, err := s.ownReview(ctx, userID, productID, reviewID, "Failed to delete review")
+	if err != nil {
+		return err
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Reviews().Delete(ctx, review.ID); err != nil {
+			return err
+		}
+		return tx.Reviews().Recompute(ctx, productID)
+	})
+	if err != nil {
+		return fail("Failed to delete review", err)
+	}
+	if review.Status == models.ReviewApproved {
+		s.invalidate(ctx)
+	}
+	return nil
+}
+
+// ReviewQueue returns one page of the reviews in the status, newest first;
+// an empty status lists them all.
+func (s *ProductService) ReviewQueue(ctx context.Context, status string, page pagination.Params) ([]models.ProductReview, int, error) {
+	if status != "" && !slices.Contains(models.ReviewStatuses, status) {
+		return nil, 0, invalid("Invalid review status")
+	}
+	reviews, total, err := s.store.Reviews().List(ctx, repositories.ReviewFilter{Params: page, Status: status})
+	if err != nil {
+		return nil, 0, fail("Failed to retrieve reviews", err)
+	}
+	return reviews, total, nil
+}
+
+// ModerateReview approves or hides a review and sets or removes the shop's
+// reply to it.
+func (s *ProductService) ModerateReview(ctx context.Context, actor Actor, id int, in models.ReviewModerationRequest) (models.ProductReview, error) {
+	if in.Status == nil && in.Reply == nil {
+		return models.ProductReview{}, invalid("Status or reply is required")
+	}
+	if in.Status != nil && *in.Status != models.ReviewApproved && *in.Status != models.ReviewHidden {
+		return models.ProductReview{}, invalid("Status must be approved or hidden")
+	}
+	existing, err := s.review(ctx, id, "Failed to moderate review")
+	if err != nil {
+		return models.ProductReview{}, err
+	}
+
+	review := existing
+	now := time.Now()
+	if in.Status != nil {
+		review.Status = *in.Status
+	}
+	if in.Reply != nil {
+		reply := strings.TrimSpace(*in.Reply)
+		if utf8.RuneCountInString(reply) > maxReviewLength {
+			return models.ProductReview{}, invalid("Reply must be at most %d characters", maxReviewLength)
+		}
+		if reply != review.Reply {
+			review.Reply, review.RepliedAt = reply, &now
+			if reply == "" {
+				review.RepliedAt = nil
+			}
+		}
+	}
+	review.UpdatedAt = now
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Reviews().Update(ctx, review); err != nil {
+			return err
+		}
+		if err := tx.Reviews().Recompute(ctx, review.ProductID); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionUpdate, models.AuditEntityProductReview, id,
+			newReviewAuditSnapshot(existing), newReviewAuditSnapshot(review)))
+	})
+	if err != nil {
+		return models.ProductReview{}, fail("Failed to moderate review", err)
+	}
+	s.invalidate(ctx)
+	return s.review(ctx, id, "Failed to moderate review")
+}
+
+func (s *ProductService) review(ctx context.Context, id int, message string) (models.ProductReview, error) {
+	if id <= 0 {
+		return models.ProductReview{}, invalid("Invalid review ID")
+	}
+	review, err := s.store.Reviews().Get(ctx, id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.ProductReview{}, notFound("Review not found")
+	}
+	if err != nil {
+		return models.ProductReview{}, fail(message, err)
+	}
+	return review, nil
+}
+
+// ownReview returns the review when the user wrote it about the product, and
+// a 404 otherwise.
+func (s *ProductService) ownReview(ctx context.Context, userID, productID, reviewID int, message string) (models.ProductReview, error) {
+	review, err := s.review(ctx, reviewID, message)
+	if err != nil {
+		return models.ProductReview{}, err
+	}
+	if review.ProductID != productID || review.UserID != userID {
+		return models.ProductReview{}, notFound("Review not found")
+	}
+	return review, nil
+}
+
+// applyReviewRequest validates the fields set in the request and copies them
+// onto the review.
+func applyReviewRequest(review *models.ProductReview, in models.ReviewRequest) error {
+	if in.Rating != nil {
+		if *in.Rating < 1 || *in.Rating > 5 {
+			return invalid("Rating must be between 1 and 5")
+		}
+		review.Rating = *in.Rating
+	}
+	if in.Text != nil {
+		text := strings.TrimSpace(*in.Text)
+		if utf8.RuneCountInString(text) > maxReviewLength {
+			return invalid("Review must be at most %d characters", maxReviewLength)
+		}
+		review.Text = text
+	}
+	return nil
+}
+
+// reviewAuditSnapshot is the part of a review the audit log records.
+type reviewAuditSnapshot struct {
+	ProductID int    `json:"product_id"`
+	UserID    int    `json:"user_id"`
+	Rating    int    `json:"rating"`
+	Status    string `json:"status"`
+	Reply     string `json:"reply"`
+}
+
+func newReviewAuditSnapshot(r models.ProductReview) reviewAuditSnapshot {
+	return reviewAuditSnapshot{ProductID: r.ProductID, UserID: r.UserID, Rating: r.Rating, Status: r.Status, Reply: r.Reply}
+}
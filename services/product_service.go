@@ -0,0 +1,691 @@
+package services
+
+import (
+	"coffee-shop/cache"
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"coffee-shop/search"
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ProductCacheNamespace holds every cached product response: lists,
+// details and search suggestions, in all locales and API versions. Any
+// change to what they show invalidates it as a whole.
+const ProductCacheNamespace = "products"
+
+// DefaultLowStockThreshold is the low-stock threshold of a new product when
+// none is given, matching the column default.
+const DefaultLowStockThreshold = 10
+
+type ProductService struct {
+	store  repositories.Store
+	images ImageStore
+	cache  cache.Store
+}
+
+func NewProductService(store repositories.Store, images ImageStore, cache cache.Store) *ProductService {
+	return &ProductService{store: store, images: images, cache: cache}
+}
+
+// ProductInput holds the writable product fields.
+type ProductInput struct {
+	SKU               string
+	Name              string
+	Description       string
+	CategoryID        int
+	Price             int
+	Stock             int
+	LowStockThreshold int
+	IsFlashSale       bool
+	IsFeatured        bool
+	IsBuy1Get1        bool
+	IsActive          bool
+}
+
+// ProductPatch is a partial update; nil fields keep the stored value.
+type ProductPatch struct {
+	SKU               *string
+	Name              *string
+	Description       *string
+	CategoryID        *int
+	Price             *int
+	Stock             *int
+	LowStockThreshold *int
+	IsFlashSale       *bool
+	IsFeatured        *bool
+	IsBuy1Get1        *bool
+	IsActive          *bool
+}
+
+// ProductDetail is everything the product page shows.
+type ProductDetail struct {
+	Product models.Product
+	Images  []models.ProductImageV2
+	// Sizes and Temperatures are the choices the product's options offer.
+	Sizes           []models.ProductSizeV2
+	Temperatures    []models.ProductTemperatureV2
+	Options         []models.ProductOptionV2
+	TotalReviews    int
+	AverageRating   float64
+	Reviews         []models.ProductReviewV2
+	Recommendations []models.ProductSummaryV2
+}
+
+func (s *ProductService) List(ctx context.Context, filter repositories.ProductFilter) ([]models.Product, int, error) {
+	filter, err := normalizeProductFilter(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	products, total, err := s.store.Products().List(ctx, filter)
+	if err != nil {
+		return nil, 0, fail("Failed to retrieve products", err)
+	}
+	if err := s.priceFlashSales(ctx, products); err != nil {
+		return nil, 0, fail("Failed to retrieve products", err)
+	}
+	if terms := search.Terms(filter.Search); len(terms) > 0 {
+		for i := range products {
+			name, _ := search.Highlight(products[i].Name, terms)
+			description, _ := search.Highlight(products[i].Description, terms)
+			products[i].Highlight = &models.SearchHighlight{Name: name, Description: description}
+		}
+	}
+	return products, total, nil
+}
+
+// Facets counts the products matching filter per category, price range and
+// flag; the filter's sort and page are ignored.
+func (s *ProductService) Facets(ctx context.Context, filter repositories.ProductFilter) (models.ProductFacets, error) {
+	filter, err := normalizeProductFilter(filter)
+	if err != nil {
+		return models.ProductFacets{}, err
+	}
+	facets, err := s.store.Products().Facets(ctx, filter)
+	if err != nil {
+		return models.ProductFacets{}, fail("Failed to retrieve products", err)
+	}
+	return facets, nil
+}
+
+// normalizeProductFilter trims the search and rejects an unknown sort, or a
+// cursor on an order other than newest first.
+func normalizeProductFilter(filter repositories.ProductFilter) (repositories.ProductFilter, error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+	filter.Sort = strings.TrimSpace(filter.Sort)
+	if filter.Sort != "" && !slices.Contains(repositories.ProductSorts, filter.Sort) {
+		return filter, invalid("Invalid sort, use one of: %s", strings.Join(repositories.ProductSorts, ", "))
+	}
+	if filter.MinRating < 0 || filter.MinRating > 5 {
+		return filter, invalid("Minimum rating must be between 0 and 5")
+	}
+	if filter.After != nil && !filter.NewestFirst() {
+		return filter, invalid("Cursor pagination is only available for the newest-first order; use page")
+	}
+	return filter, nil
+}
+
+// Featured lists the products the shop features.
+func (s *ProductService) Featured(ctx context.Context, locale string) ([]models.Product, error) {
+	products, _, err := s.store.Products().List(ctx, repositories.ProductFilter{Featured: true, Locale: locale})
+	if err != nil {
+		return nil, fail("Failed to retrieve featured products", err)
+	}
+	if err := s.priceFlashSales(ctx, products); err != nil {
+		return nil, fail("Failed to retrieve featured products", err)
+	}
+	return products, nil
+}
+
+func (s *ProductService) Get(ctx context.Context, id int, locale string) (models.Product, error) {
+	if id <= 0 {
+		return models.Product{}, invalid("Invalid product ID")
+	}
+	p, err := s.store.Products().GetActive(ctx, id, locale)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.Product{}, notFound("Product not found")
+	}
+	if err != nil {
+		return models.Product{}, fail("Failed to retrieve products", err)
+	}
+	products := []models.Product{p}
+	if err := s.priceFlashSales(ctx, products); err != nil {
+		return models.Product{}, fail("Failed to retrieve products", err)
+	}
+	return products[0], nil
+}
+
+func (s *ProductService) Detail(ctx context.Context, id int, locale string) (ProductDetail, error) {
+	p, err := s.store.Products().GetActive(ctx, id, locale)
+	if err != nil {
+		return ProductDetail{}, notFound("Product not found")
+	}
+
+	priced := []models.Product{p}
+	if err := s.priceFlashSales(ctx, priced); err != nil {
+		return ProductDetail{}, fail("Failed to retrieve products", err)
+	}
+	p = priced[0]
+	recommended, err := s.recommend(ctx, []int{id}, models.RecommendationTypes, []int{p.CategoryID}, detailRecommendations, locale)
+	if err != nil {
+		return ProductDetail{}, fail("Failed to retrieve products", err)
+	}
+
+	products := s.store.Products()
+	d := ProductDetail{Product: p, Recommendations: []models.ProductSummaryV2{}}
+	images, err := products.Images(ctx, id)
+	if err != nil {
+		return ProductDetail{}, fail("Failed to retrieve products", err)
+	}
+	d.Images = models.NewProductImageListV2(images)
+	options, err := products.Options(ctx, id)
+	if err != nil {
+		return ProductDetail{}, fail("Failed to retrieve products", err)
+	}
+	active := []models.ProductOption{}
+	for _, o := range options {
+		if o.IsActive {
+			active = append(active, o)
+		}
+	}
+	d.Options = models.NewProductOptionListV2(active)
+	d.Sizes, d.Temperatures = offeredChoices(active)
+	d.TotalReviews, d.AverageRating = p.ReviewCount, p.AverageRating
+	reviews, _, err := s.store.Reviews().List(ctx, repositories.ReviewFilter{
+		ProductID: id,
+		Status:    models.ReviewApproved,
+		Params:    pagination.Params{Limit: 5},
+	})
+	if err != nil {
+		return ProductDetail{}, fail("Failed to retrieve products", err)
+	}
+	d.Reviews = models.NewProductReviewListV2(reviews)
+
+	for _, r := range recommended {
+		d.Recommendations = append(d.Recommendations, models.ProductSummaryV2{
+			ID:                 r.ID,
+			Name:               r.Name,
+			Price:              r.Price,
+			ImageURL:           r.ImageURL,
+			IsFlashSale:        r.IsFlashSale,
+			RecommendationType: r.RecommendationType,
+		})
+	}
+	return d, nil
+}
+
+func (s *ProductService) Create(ctx context.Context, actor Actor, in ProductInput, image *Upload) (models.Product, error) {
+	in.SKU = strings.TrimSpace(in.SKU)
+	in.Name = strings.TrimSpace(in.Name)
+	in.Description = strings.TrimSpace(in.Description)
+	in.IsActive = true
+	if err := validateProduct(in); err != nil {
+		return models.Product{}, err
+	}
+	if err := s.checkSKU(ctx, in.SKU, 0, "Failed to create product"); err != nil {
+		return models.Product{}, err
+	}
+
+	p := models.Product{
+		SKU:               in.SKU,
+		Name:              in.Name,
+		Description:       in.Description,
+		CategoryID:        in.CategoryID,
+		Price:             in.Price,
+		Stock:             in.Stock,
+		LowStockThreshold: in.LowStockThreshold,
+		IsFlashSale:       in.IsFlashSale,
+		IsFeatured:        in.IsFeatured,
+		IsBuy1Get1:        in.IsBuy1Get1,
+		IsActive:          in.IsActive,
+	}
+
+	if image != nil {
+		var err error
+		if p.ImageURL, p.CloudinaryID, err = s.upload(ctx, image); err != nil {
+			return models.Product{}, err
+		}
+	}
+
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Products().Create(ctx, &p); err != nil {
+			return err
+		}
+		if err := recordPrice(ctx, tx, actor, p.ID, p.Price, nil, "Opening price"); err != nil {
+			return err
+		}
+		if p.Stock != 0 {
+			opening := models.StockMovement{ProductID: p.ID, Type: models.StockMovementAdjustment, Quantity: p.Stock,
+				StockAfter: p.Stock, Reason: "Opening balance", ActorID: actorID(actor)}
+			if err := tx.Inventory().Record(ctx, &opening); err != nil {
+				return err
+			}
+		}
+		if p.ImageURL != "" {
+			primary := []models.ProductImage{{URL: p.ImageURL, CloudinaryID: p.CloudinaryID, IsPrimary: true}}
+			if _, _, err := saveGallery(ctx, tx, p, nil, primary); err != nil {
+				return err
+			}
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionCreate, models.AuditEntityProduct, p.ID, nil, newProductAuditSnapshot(p)))
+	})
+	if err != nil {
+		s.deleteImage(ctx, p.CloudinaryID)
+		return models.Product{}, fail("Failed to create product", err)
+	}
+
+	s.invalidate(ctx)
+	return p, nil
+}
+
+func (s *ProductService) Update(ctx context.Context, actor Actor, id int, patch ProductPatch, image *Upload) (models.Product, error) {
+	if id <= 0 {
+		return models.Product{}, invalid("Invalid product ID")
+	}
+
+	existing, err := s.store.Products().Get(ctx, id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.Product{}, notFound("Product not found")
+	}
+	if err != nil {
+		return models.Product{}, fail("Failed to update product", err)
+	}
+
+	in := ProductInput{
+		SKU:               strings.TrimSpace(stringOr(patch.SKU, existing.SKU)),
+		Name:              strings.TrimSpace(stringOr(patch.Name, existing.Name)),
+		Description:       strings.TrimSpace(stringOr(patch.Description, existing.Description)),
+		CategoryID:        intOr(patch.CategoryID, existing.CategoryID),
+		Price:             intOr(patch.Price, existing.Price),
+		Stock:             intOr(patch.Stock, existing.Stock),
+		LowStockThreshold: intOr(patch.LowStockThreshold, existing.LowStockThreshold),
+		IsFlashSale:       boolOr(patch.IsFlashSale, existing.IsFlashSale),
+		IsFeatured:        boolOr(patch.IsFeatured, existing.IsFeatured),
+		IsBuy1Get1:        boolOr(patch.IsBuy1Get1, existing.IsBuy1Get1),
+		IsActive:          boolOr(patch.IsActive, existing.IsActive),
+	}
+	if err := validateProduct(in); err != nil {
+		return models.Product{}, err
+	}
+	if !strings.EqualFold(in.SKU, existing.SKU) {
+		if err := s.checkSKU(ctx, in.SKU, id, "Failed to update product"); err != nil {
+			return models.Product{}, err
+		}
+	}
+
+	updated := existing
+	updated.SKU = in.SKU
+	updated.Name = in.Name
+	updated.Description = in.Description
+	updated.CategoryID = in.CategoryID
+	updated.Price = in.Price
+	updated.Stock = in.Stock
+	updated.LowStockThreshold = in.LowStockThreshold
+	updated.IsFlashSale = in.IsFlashSale
+	updated.IsFeatured = in.IsFeatured
+	updated.IsBuy1Get1 = in.IsBuy1Get1
+	updated.IsActive = in.IsActive
+	updated.UpdatedAt = time.Now()
+
+	if image != nil {
+		if updated.ImageURL, updated.CloudinaryID, err = s.upload(ctx, image); err != nil {
+			return models.Product{}, err
+		}
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Products().Update(ctx, updated); err != nil {
+			return err
+		}
+		if updated.Price != existing.Price {
+			if err := recordPrice(ctx, tx, actor, id, updated.Price, &existing.Price, "Product update"); err != nil {
+				return err
+			}
+		}
+		if patch.Stock != nil {
+			stock, err := setStock(ctx, tx, actor, id, updated.Stock, "Product update")
+			if err != nil {
+				return err
+			}
+			existing.Stock = stock
+		}
+		if updated.CloudinaryID != existing.CloudinaryID {
+			if err := replacePrimaryImage(ctx, tx, updated); err != nil {
+				return err
+			}
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionUpdate, models.AuditEntityProduct, id,
+			newProductAuditSnapshot(existing), newProductAuditSnapshot(updated)))
+	})
+	if err != nil {
+		if updated.CloudinaryID != existing.CloudinaryID {
+			s.deleteImage(ctx, updated.CloudinaryID)
+		}
+		var serviceErr *Error
+		if errors.As(err, &serviceErr) {
+			return models.Product{}, serviceErr
+		}
+		return models.Product{}, fail("Failed to update product", err)
+	}
+
+	if updated.CloudinaryID != existing.CloudinaryID {
+		s.deleteImage(ctx, existing.CloudinaryID)
+	}
+
+	s.invalidate(ctx)
+	return updated, nil
+}
+
+// Delete archives the product: it disappears from the storefront and carts
+// but stays in order history, and its image is kept so it can be restored.
+func (s *ProductService) Delete(ctx context.Context, actor Actor, id int) error {
+	if id <= 0 {
+		return invalid("Invalid product ID")
+	}
+
+	existing, err := s.store.Products().Get(ctx, id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return notFound("Product not found")
+	}
+	if err != nil {
+		return fail("Failed to archive product", err)
+	}
+	if existing.DeletedAt != nil {
+		return conflict("Product is already archived")
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Products().Archive(ctx, id); err != nil {
+			return err
+		}
+		archived := newProductAuditSnapshot(existing)
+		archived.Archived = true
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionArchive, models.AuditEntityProduct, id,
+			newProductAuditSnapshot(existing), archived))
+	})
+	if err != nil {
+		return fail("Failed to archive product", err)
+	}
+
+	s.invalidate(ctx)
+	return nil
+}
+
+// Archived lists archived products, most recently archived first.
+func (s *ProductService) Archived(ctx context.Context, page pagination.Params) ([]models.Product, int, error) {
+	products, total, err := s.store.Products().Archived(ctx, page)
+	if err != nil {
+		return nil, 0, fail("Failed to retrieve archived products", err)
+	}
+	return products, total, nil
+}
+
+// Restore puts an archived product back on the storefront.
+func (s *ProductService) Restore(ctx context.Context, actor Actor, id int) (models.Product, error) {
+	if id <= 0 {
+		return models.Product{}, invalid("Invalid product ID")
+	}
+
+	existing, err := s.store.Products().Get(ctx, id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.Product{}, notFound("Product not found")
+	}
+	if err != nil {
+		return models.Product{}, fail("Failed to restore product", err)
+	}
+	if existing.DeletedAt == nil {
+		return models.Product{}, conflict("Product is not archived")
+	}
+
+	var restored models.Product
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Products().Restore(ctx, id); err != nil {
+			return err
+		}
+		if restored, err = tx.Products().Get(ctx, id); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionRestore, models.AuditEntityProduct, id,
+			newProductAuditSnapshot(existing), newProductAuditSnapshot(restored)))
+	})
+	if err != nil {
+		return models.Product{}, fail("Failed to restore product", err)
+	}
+
+	s.invalidate(ctx)
+	return restored, nil
+}
+
+// Purge permanently deletes an archived product and its images. Products that
+// appear on any order are refused, since order history must keep them.
+func (s *ProductService) Purge(ctx context.Context, actor Actor, id int) error {
+	if id <= 0 {
+		return invalid("Invalid product ID")
+	}
+
+	existing, err := s.store.Products().Get(ctx, id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return notFound("Product not found")
+	}
+	if err != nil {
+		return fail("Failed to delete product", err)
+	}
+	if existing.DeletedAt == nil {
+		return conflict("Archive the product before deleting it permanently")
+	}
+
+	images, err := s.store.Products().Images(ctx, id)
+	if err != nil {
+		return fail("Failed to delete product", err)
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		orders, err := tx.Products().OrderCount(ctx, id)
+		if err != nil {
+			return err
+		}
+		if orders > 0 {
+			return conflict("Product appears in past orders and cannot be deleted permanently")
+		}
+		if err := tx.Products().Delete(ctx, id); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionDelete, models.AuditEntityProduct, id, newProductAuditSnapshot(existing), nil))
+	})
+	if err != nil {
+		var serviceErr *Error
+		if errors.As(err, &serviceErr) {
+			return serviceErr
+		}
+		return fail("Failed to delete product", err)
+	}
+
+	s.deleteImage(ctx, existing.CloudinaryID)
+	for _, img := range images {
+		if img.CloudinaryID != existing.CloudinaryID {
+			s.deleteImage(ctx, img.CloudinaryID)
+		}
+	}
+	s.invalidate(ctx)
+	return nil
+}
+
+// priceFlashSales sets the flash sale price of the products on sale now.
+func (s *ProductService) priceFlashSales(ctx context.Context, products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	prices, err := flashSalePrices(ctx, s.store, time.Now())
+	if err != nil {
+		return err
+	}
+	applyFlashSalePrices(products, prices)
+	return nil
+}
+
+// CacheTTL is how long a product list may be cached: at most limit, and no
+// later than the next flash sale starts or ends, so prices flip on time.
+func (s *ProductService) CacheTTL(ctx context.Context, limit time.Duration) time.Duration {
+	now := time.Now()
+	sales, err := s.store.FlashSales().Current(ctx, now, "")
+	if err != nil {
+		log.Printf("Failed to retrieve flash sales: %v", err)
+		return limit
+	}
+	ttl := limit
+	for _, sale := range sales {
+		next := sale.EndsAt
+		if sale.StartsAt.After(now) {
+			next = sale.StartsAt
+		}
+		if until := next.Sub(now); until < ttl {
+			ttl = max(until, time.Second)
+		}
+	}
+	return ttl
+}
+
+// PricesChangedAt returns when a flash sale last started or ended, changing
+// product prices without writing the products. Scheduled prices need no such
+// help: applying one writes the product and clears the cache.
+func (s *ProductService) PricesChangedAt(ctx context.Context) time.Time {
+	changed, err := s.store.FlashSales().LastBoundary(ctx, time.Now())
+	if err != nil {
+		log.Printf("Failed to retrieve flash sales: %v", err)
+		return time.Now()
+	}
+	return changed
+}
+
+// InvalidateCache drops every cached product response.
+func (s *ProductService) InvalidateCache(ctx context.Context) {
+	s.invalidate(ctx)
+}
+
+func (s *ProductService) invalidate(ctx context.Context) {
+	cache.NewNamespace(s.cache, ProductCacheNamespace).Invalidate(ctx)
+}
+
+func (s *ProductService) upload(ctx context.Context, image *Upload) (string, string, error) {
+	if err := s.images.Validate(image.Header); err != nil {
+		if errors.Is(err, ErrImagesUnavailable) {
+			log.Printf("Cloudinary not configured: %v", err)
+			return "", "", &Error{Status: 500, Message: "Image upload service not available", Err: err}
+		}
+		return "", "", invalid(err.Error())
+	}
+
+	url, id, err := s.images.Upload(ctx, image.File, image.Header.Filename, "products")
+	if err != nil {
+		return "", "", fail("Failed to upload image", err)
+	}
+	return url, id, nil
+}
+
+func (s *ProductService) deleteImage(ctx context.Context, id string) {
+	if id == "" {
+		return
+	}
+	if err := s.images.Delete(ctx, id); err != nil {
+		log.Printf("Failed to delete image %s: %v", id, err)
+	}
+}
+
+// checkSKU rejects sku when another product than excludeID already uses it.
+func (s *ProductService) checkSKU(ctx context.Context, sku string, excludeID int, failMessage string) error {
+	if sku == "" {
+		return nil
+	}
+	exists, err := s.store.Products().SKUExists(ctx, sku, excludeID)
+	if err != nil {
+		return fail(failMessage, err)
+	}
+	if exists {
+		return invalid("SKU already exists")
+	}
+	return nil
+}
+
+func validateProduct(in ProductInput) error {
+	if len(in.SKU) > 64 {
+		return invalid("SKU must be at most 64 characters")
+	}
+	if len(in.Name) < 3 {
+		return invalid("Product name must be at least 3 characters")
+	}
+	if in.CategoryID <= 0 {
+		return invalid("Invalid category_id")
+	}
+	if in.Price < 1000 {
+		return invalid("Price must be at least 1000")
+	}
+	if in.Stock < 0 {
+		return invalid("Invalid stock")
+	}
+	if in.LowStockThreshold < 0 {
+		return invalid("Low stock threshold cannot be negative")
+	}
+	return nil
+}
+
+type productAuditSnapshot struct {
+	SKU               string `json:"sku,omitempty"`
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	CategoryID        int    `json:"category_id"`
+	Price             int    `json:"price"`
+	Stock             int    `json:"stock"`
+	LowStockThreshold int    `json:"low_stock_threshold"`
+	ImageURL          string `json:"image_url"`
+	CloudinaryID      string `json:"cloudinary_id"`
+	IsFlashSale       bool   `json:"is_flash_sale"`
+	IsFeatured        bool   `json:"is_featured"`
+	IsBuy1Get1        bool   `json:"is_buy1get1"`
+	IsActive          bool   `json:"is_active"`
+	Archived          bool   `json:"archived"`
+}
+
+func newProductAuditSnapshot(p models.Product) productAuditSnapshot {
+	return productAuditSnapshot{
+		SKU:               p.SKU,
+		Name:              p.Name,
+		Description:       p.Description,
+		CategoryID:        p.CategoryID,
+		Price:             p.Price,
+		Stock:             p.Stock,
+		LowStockThreshold: p.LowStockThreshold,
+		ImageURL:          p.ImageURL,
+		CloudinaryID:      p.CloudinaryID,
+		IsFlashSale:       p.IsFlashSale,
+		IsFeatured:        p.IsFeatured,
+		IsBuy1Get1:        p.IsBuy1Get1,
+		IsActive:          p.IsActive,
+		Archived:          p.DeletedAt != nil,
+	}
+}
+
+func stringOr(v *string, fallback string) string {
+	if v != nil {
+		return *v
+	}
+	return fallback
+}
+
+func intOr(v *int, fallback int) int {
+	if v != nil {
+		return *v
+	}
+	return fallback
+}
+
+func boolOr(v *bool, fallback bool) bool {
+	if v != nil {
+		return *v
+	}
+	return fallback
+}
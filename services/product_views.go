@@ -0,0 +1,91 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+const (
+	// RecentlyViewedLimit is how many products a recently viewed list keeps.
+	RecentlyViewedLimit = 20
+	recentlyViewedTTL   = 30 * 24 * time.Hour
+	// ProductViewRetention is how long product views are kept. It outlasts
+	// both the popularity window and the cached recently viewed lists.
+	ProductViewRetention = 90 * 24 * time.Hour
+)
+
+// recentlyViewedKey is the cache key of the user's recently viewed product
+// IDs, latest first.
+func recentlyViewedKey(userID int) string {
+	return fmt.Sprintf("recently_viewed:%d", userID)
+}
+
+// RecordView stores that the user opened an active product's page. A view
+// that cannot be stored is only logged: it must not fail the page.
+func (s *ProductService) RecordView(ctx context.Context, userID, productID int) {
+	if err := s.store.Views().Record(ctx, userID, productID, time.Now()); err != nil {
+		log.Printf("Failed to record view of product %d by user %d: %v", productID, userID, err)
+		return
+	}
+	s.cache.PushList(ctx, recentlyViewedKey(userID), strconv.Itoa(productID), RecentlyViewedLimit, recentlyViewedTTL)
+}
+
+// RecentlyViewed returns the products the user viewed most recently, latest
+// first. Archived and inactive products are left out. The list is read from
+// the cache, and rebuilt there from the stored views when missing.
+func (s *ProductService) RecentlyViewed(ctx context.Context, userID int, locale string) ([]models.Product, error) {
+	key := recentlyViewedKey(userID)
+	ids := []int{}
+	if cached, ok := s.cache.GetList(ctx, key); ok {
+		for _, value := range cached {
+			if id, err := strconv.Atoi(value); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	} else {
+		stored, err := s.store.Views().Recent(ctx, userID, RecentlyViewedLimit)
+		if err != nil {
+			return nil, fail("Failed to retrieve recently viewed products", err)
+		}
+		ids = stored
+		values := make([]string, 0, len(ids))
+		for _, id := range ids {
+			values = append(values, strconv.Itoa(id))
+		}
+		s.cache.SetList(ctx, key, values, recentlyViewedTTL)
+	}
+
+	products, err := s.store.Views().Products(ctx, ids, locale)
+	if err != nil {
+		return nil, fail("Failed to retrieve recently viewed products", err)
+	}
+	if err := s.priceFlashSales(ctx, products); err != nil {
+		return nil, fail("Failed to retrieve recently viewed products", err)
+	}
+	return products, nil
+}
+
+// RefreshPopularity counts the daily viewers of each product that the
+// popular sort reads, then deletes the views older than ProductViewRetention.
+// It returns how many views it deleted.
+func (s *ProductService) RefreshPopularity(ctx context.Context, now time.Time) (int, error) {
+	var pruned int
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Views().CountDaily(ctx, now.Add(-repositories.PopularityWindow)); err != nil {
+			return err
+		}
+		var err error
+		pruned, err = tx.Views().Prune(ctx, now.Add(-ProductViewRetention))
+		return err
+	})
+	if err != nil {
+		return 0, fail("Failed to refresh popularity", err)
+	}
+	s.invalidate(ctx)
+	return pruned, nil
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+)
+
+// PromoService lists the promo banners of the storefront.
+type PromoService struct {
+	store repositories.Store
+}
+
+func NewPromoService(store repositories.Store) *PromoService {
+	return &PromoService{store: store}
+}
+
+// Active returns the active promos, newest first.
+func (s *PromoService) Active(ctx context.Context) ([]models.Promo, error) {
+	promos, err := s.store.Promos().Active(ctx)
+	if err != nil {
+		return nil, fail("Failed to get promos", err)
+	}
+	return promos, nil
+}
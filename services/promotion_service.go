@@ -0,0 +1,208 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+type PromotionService struct {
+	store repositories.Store
+}
+
+func NewPromotionService(store repositories.Store) *PromotionService {
+	return &PromotionService{store: store}
+}
+
+// PromotionInput holds the writable promotion rule fields.
+type PromotionInput struct {
+	Name            string
+	Type            string
+	ProductID       *int
+	CategoryID      *int
+	BuyQuantity     int
+	GetQuantity     int
+	DiscountPercent int
+	StartsAt        *time.Time
+	EndsAt          *time.Time
+	IsActive        bool
+}
+
+func (s *PromotionService) List(ctx context.Context) ([]models.PromotionRule, error) {
+	rules, err := s.store.Promotions().List(ctx)
+	if err != nil {
+		return nil, fail("Failed to retrieve promotion rules", err)
+	}
+	return rules, nil
+}
+
+func (s *PromotionService) Get(ctx context.Context, id int) (models.PromotionRule, error) {
+	if id <= 0 {
+		return models.PromotionRule{}, invalid("Invalid promotion rule ID")
+	}
+	rule, err := s.store.Promotions().Get(ctx, id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.PromotionRule{}, notFound("Promotion rule not found")
+	}
+	if err != nil {
+		return models.PromotionRule{}, fail("Failed to retrieve promotion rules", err)
+	}
+	return rule, nil
+}
+
+func (s *PromotionService) Create(ctx context.Context, actor Actor, in PromotionInput) (models.PromotionRule, error) {
+	rule, err := s.validate(ctx, in)
+	if err != nil {
+		return models.PromotionRule{}, err
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Promotions().Create(ctx, &rule); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionCreate, models.AuditEntityPromotionRule, rule.ID,
+			nil, newPromotionAuditSnapshot(rule)))
+	})
+	if err != nil {
+		return models.PromotionRule{}, fail("Failed to create promotion rule", err)
+	}
+	return rule, nil
+}
+
+func (s *PromotionService) Update(ctx context.Context, actor Actor, id int, in PromotionInput) (models.PromotionRule, error) {
+	existing, err := s.Get(ctx, id)
+	if err != nil {
+		return models.PromotionRule{}, err
+	}
+	rule, err := s.validate(ctx, in)
+	if err != nil {
+		return models.PromotionRule{}, err
+	}
+	rule.ID = id
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now()
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Promotions().Update(ctx, rule); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionUpdate, models.AuditEntityPromotionRule, id,
+			newPromotionAuditSnapshot(existing), newPromotionAuditSnapshot(rule)))
+	})
+	if err != nil {
+		return models.PromotionRule{}, fail("Failed to update promotion rule", err)
+	}
+	return rule, nil
+}
+
+// Delete removes the rule. Orders it discounted keep their discount and
+// promotion name.
+func (s *PromotionService) Delete(ctx context.Context, actor Actor, id int) error {
+	existing, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Promotions().Delete(ctx, id); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionDelete, models.AuditEntityPromotionRule, id,
+			newPromotionAuditSnapshot(existing), nil))
+	})
+	if err != nil {
+		return fail("Failed to delete promotion rule", err)
+	}
+	return nil
+}
+
+func (s *PromotionService) validate(ctx context.Context, in PromotionInput) (models.PromotionRule, error) {
+	rule := models.PromotionRule{
+		Name:            strings.TrimSpace(in.Name),
+		Type:            strings.TrimSpace(in.Type),
+		ProductID:       in.ProductID,
+		CategoryID:      in.CategoryID,
+		BuyQuantity:     in.BuyQuantity,
+		GetQuantity:     in.GetQuantity,
+		DiscountPercent: in.DiscountPercent,
+		StartsAt:        in.StartsAt,
+		EndsAt:          in.EndsAt,
+		IsActive:        in.IsActive,
+	}
+	if len(rule.Name) < 3 {
+		return rule, invalid("Promotion name must be at least 3 characters")
+	}
+	if len(rule.Name) > 100 {
+		return rule, invalid("Promotion name must be at most 100 characters")
+	}
+	if !slices.Contains(models.PromotionTypes, rule.Type) {
+		return rule, invalid("Invalid promotion type, use one of: %s", strings.Join(models.PromotionTypes, ", "))
+	}
+	if rule.BuyQuantity <= 0 || rule.GetQuantity <= 0 {
+		return rule, invalid("Buy and get quantities must be greater than 0")
+	}
+	if rule.DiscountPercent < 1 || rule.DiscountPercent > 100 {
+		return rule, invalid("Discount percent must be between 1 and 100")
+	}
+	if rule.StartsAt != nil && rule.EndsAt != nil && !rule.EndsAt.After(*rule.StartsAt) {
+		return rule, invalid("End time must be after the start time")
+	}
+
+	switch rule.Type {
+	case models.PromotionBuyXGetY:
+		if rule.ProductID == nil || rule.CategoryID != nil {
+			return rule, invalid("A buy_x_get_y promotion needs a product_id and no category_id")
+		}
+		p, err := s.store.Products().Get(ctx, *rule.ProductID)
+		if errors.Is(err, repositories.ErrNotFound) || (err == nil && p.DeletedAt != nil) {
+			return rule, invalid("Product not found")
+		}
+		if err != nil {
+			return rule, fail("Failed to save promotion rule", err)
+		}
+	case models.PromotionMixAndMatch:
+		if rule.CategoryID == nil || rule.ProductID != nil {
+			return rule, invalid("A mix_and_match promotion needs a category_id and no product_id")
+		}
+		_, err := s.store.Categories().Get(ctx, *rule.CategoryID, "")
+		if errors.Is(err, repositories.ErrNotFound) {
+			return rule, invalid("Category not found")
+		}
+		if err != nil {
+			return rule, fail("Failed to save promotion rule", err)
+		}
+	}
+	return rule, nil
+}
+
+type promotionAuditSnapshot struct {
+	Name            string     `json:"name"`
+	Type            string     `json:"type"`
+	ProductID       *int       `json:"product_id"`
+	CategoryID      *int       `json:"category_id"`
+	BuyQuantity     int        `json:"buy_quantity"`
+	GetQuantity     int        `json:"get_quantity"`
+	DiscountPercent int        `json:"discount_percent"`
+	StartsAt        *time.Time `json:"starts_at"`
+	EndsAt          *time.Time `json:"ends_at"`
+	IsActive        bool       `json:"is_active"`
+}
+
+func newPromotionAuditSnapshot(r models.PromotionRule) promotionAuditSnapshot {
+	return promotionAuditSnapshot{
+		Name:            r.Name,
+		Type:            r.Type,
+		ProductID:       r.ProductID,
+		CategoryID:      r.CategoryID,
+		BuyQuantity:     r.BuyQuantity,
+		GetQuantity:     r.GetQuantity,
+		DiscountPercent: r.DiscountPercent,
+		StartsAt:        r.StartsAt,
+		EndsAt:          r.EndsAt,
+		IsActive:        r.IsActive,
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"sort"
+)
+
+// buyOneGetOneName names the discount of a product flagged is_buy1get1 that
+// has no promotion rule of its own.
+const buyOneGetOneName = "Buy 1 Get 1"
+
+// promotionLine is a cart line as the promotion engine sees it. UnitPrice
+// includes the option surcharge. Excluded lines, such as lines on a flash
+// sale or no longer available, take no part in promotions.
+type promotionLine struct {
+	ProductID  int
+	CategoryID int
+	IsBuy1Get1 bool
+	UnitPrice  int
+	Quantity   int
+	Excluded   bool
+}
+
+// applyPromotions returns the promotion adjustment of each line, nil where
+// none applies. Rules are tried in order: buy_x_get_y rules, then the buy 1
+// get 1 flag of products without such a rule, then mix_and_match rules,
+// oldest first within each. A line takes part in at most one promotion: once
+// a rule uses any of its units, later rules skip it.
+//
+// A rule sorts the units of its lines by price, most expensive first, and
+// cuts them into groups of BuyQuantity+GetQuantity; the GetQuantity cheapest
+// units of every full group are discounted by DiscountPercent.
+func applyPromotions(lines []promotionLine, rules []models.PromotionRule) []*models.PromotionAdjustment {
+	adjustments := make([]*models.PromotionAdjustment, len(lines))
+	claimed := make([]bool, len(lines))
+
+	type unit struct{ line, price int }
+	for _, rule := range orderPromotions(lines, rules) {
+		units := []unit{}
+		for i, l := range lines {
+			if claimed[i] || l.Excluded || !promotionMatches(rule, l) {
+				continue
+			}
+			for q := 0; q < l.Quantity; q++ {
+				units = append(units, unit{line: i, price: l.UnitPrice})
+			}
+		}
+		group := rule.BuyQuantity + rule.GetQuantity
+		full := len(units) / group * group
+		if full == 0 {
+			continue
+		}
+		sort.SliceStable(units, func(a, b int) bool { return units[a].price > units[b].price })
+
+		for k, u := range units[:full] {
+			claimed[u.line] = true
+			if k%group < rule.BuyQuantity {
+				continue
+			}
+			adj := adjustments[u.line]
+			if adj == nil {
+				adj = &models.PromotionAdjustment{Name: rule.Name}
+				if rule.ID != 0 {
+					id := rule.ID
+					adj.PromotionRuleID = &id
+				}
+				adjustments[u.line] = adj
+			}
+			adj.Quantity++
+			adj.Amount += (u.price*rule.DiscountPercent + 50) / 100
+		}
+	}
+	return adjustments
+}
+
+// orderPromotions lists rules in the order applyPromotions tries them, with a
+// buy 1 get 1 rule for every flagged product of lines that has no
+// buy_x_get_y rule.
+func orderPromotions(lines []promotionLine, rules []models.PromotionRule) []models.PromotionRule {
+	ordered := []models.PromotionRule{}
+	hasRule := map[int]bool{}
+	for _, rule := range rules {
+		if rule.Type == models.PromotionBuyXGetY && rule.ProductID != nil {
+			ordered = append(ordered, rule)
+			hasRule[*rule.ProductID] = true
+		}
+	}
+	for _, l := range lines {
+		if l.IsBuy1Get1 && !hasRule[l.ProductID] {
+			productID := l.ProductID
+			ordered = append(ordered, models.PromotionRule{Name: buyOneGetOneName, Type: models.PromotionBuyXGetY,
+				ProductID: &productID, BuyQuantity: 1, GetQuantity: 1, DiscountPercent: 100})
+			hasRule[productID] = true
+		}
+	}
+	for _, rule := range rules {
+		if rule.Type == models.PromotionMixAndMatch && rule.CategoryID != nil {
+			ordered = append(ordered, rule)
+		}
+	}
+	return ordered
+}
+
+func promotionMatches(rule models.PromotionRule, l promotionLine) bool {
+	switch rule.Type {
+	case models.PromotionBuyXGetY:
+		return rule.ProductID != nil && *rule.ProductID == l.ProductID
+	case models.PromotionMixAndMatch:
+		return rule.CategoryID != nil && *rule.CategoryID == l.CategoryID
+	}
+	return false
+}
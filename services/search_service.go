@@ -0,0 +1,144 @@
+package services
+
+import (
+	"coffee-shop/cache"
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"coffee-shop/search"
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"time"
+)
+
+const (
+	// DefaultSuggestions and MaxSuggestions bound the items per group in a
+	// suggestion response.
+	DefaultSuggestions = 5
+	MaxSuggestions     = 10
+
+	// minQueryHits is how often a query must have been searched before it is
+	// suggested to other shoppers, which keeps one-off typos out.
+	minQueryHits = 3
+	// minRecordedQuery is the shortest query worth remembering.
+	minRecordedQuery = 2
+	// queryCountWindow is how long a search counts once per searcher, so
+	// one shopper repeating it cannot make it popular.
+	queryCountWindow = 24 * time.Hour
+)
+
+// SearchService backs the storefront search box.
+type SearchService struct {
+	store repositories.Store
+	cache cache.Store
+}
+
+func NewSearchService(store repositories.Store, cache cache.Store) *SearchService {
+	return &SearchService{store: store, cache: cache}
+}
+
+// Suggest returns product names, categories and popular past searches
+// matching what the user has typed so far. An empty query suggests nothing.
+func (s *SearchService) Suggest(ctx context.Context, q, locale string, limit int) (models.Suggestions, error) {
+	q = search.Normalize(q)
+	if q == "" {
+		return models.Suggestions{
+			Products:   []models.SuggestedProduct{},
+			Categories: []models.SuggestedCategory{},
+			Queries:    []string{},
+		}, nil
+	}
+	if limit <= 0 {
+		limit = DefaultSuggestions
+	}
+	if limit > MaxSuggestions {
+		limit = MaxSuggestions
+	}
+
+	suggestions, err := s.store.Search().Suggest(ctx, repositories.SuggestFilter{
+		Query:   q,
+		Locale:  locale,
+		Limit:   limit,
+		MinHits: minQueryHits,
+	})
+	if err != nil {
+		return models.Suggestions{}, fail("Failed to retrieve suggestions", err)
+	}
+	return suggestions, nil
+}
+
+// RecordQuery counts a storefront search that found products so it can be
+// suggested later, once an admin allows it. searcher identifies who ran it,
+// such as a user ID or else an IP address; each searcher counts once per
+// queryCountWindow. A failure is only logged; it must not fail the search
+// itself.
+func (s *SearchService) RecordQuery(ctx context.Context, q, searcher string, found int) {
+	q = search.Normalize(q)
+	if found == 0 || len([]rune(q)) < minRecordedQuery {
+		return
+	}
+	key := "search_seen:" + searcher + ":" + q
+	if _, seen := s.cache.Get(ctx, key); seen {
+		return
+	}
+	s.cache.Set(ctx, key, "1", queryCountWindow)
+	if err := s.store.Search().RecordQuery(ctx, q); err != nil {
+		log.Printf("Failed to record search query: %v", err)
+	}
+}
+
+// Queries returns one page of the recorded searches in status, most searched
+// first; an empty status lists them all.
+func (s *SearchService) Queries(ctx context.Context, status string, page pagination.Params) ([]models.SearchQuery, int, error) {
+	if status != "" && !slices.Contains(models.SearchQueryStatuses, status) {
+		return nil, 0, invalid("Invalid search query status")
+	}
+	if page.After != nil {
+		return nil, 0, invalid("Cursor pagination is not available for search queries; use page")
+	}
+	queries, total, err := s.store.Search().Queries(ctx, repositories.SearchQueryFilter{Params: page, Status: status})
+	if err != nil {
+		return nil, 0, fail("Failed to retrieve search queries", err)
+	}
+	return queries, total, nil
+}
+
+type searchQueryAuditSnapshot struct {
+	Query  string `json:"query"`
+	Status string `json:"status"`
+}
+
+// ModerateQuery allows a recorded search to be suggested to shoppers, or
+// blocks it from ever being suggested.
+func (s *SearchService) ModerateQuery(ctx context.Context, actor Actor, id int, status string) (models.SearchQuery, error) {
+	if status != models.SearchQueryAllowed && status != models.SearchQueryBlocked {
+		return models.SearchQuery{}, invalid("Status must be allowed or blocked")
+	}
+
+	var moderated models.SearchQuery
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		existing, err := tx.Search().Query(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Search().SetQueryStatus(ctx, id, status); err != nil {
+			return err
+		}
+		moderated = existing
+		moderated.Status = status
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionUpdate, models.AuditEntitySearchQuery, id,
+			searchQueryAuditSnapshot{Query: existing.Query, Status: existing.Status},
+			searchQueryAuditSnapshot{Query: existing.Query, Status: status}))
+	})
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.SearchQuery{}, notFound("Search query not found")
+	}
+	if err != nil {
+		return models.SearchQuery{}, fail("Failed to moderate search query", err)
+	}
+	// Suggestions are cached with the products.
+	cache.NewNamespace(s.cache, ProductCacheNamespace).Invalidate(ctx)
+	return moderated, nil
+}
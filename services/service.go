@@ -0,0 +1,83 @@
+// Package services holds the business rules behind the HTTP handlers. Services
+// talk to the database only through a repositories.Store, so they can be
+// exercised against the in-memory store in tests.
+package services
+
+import (
+	"coffee-shop/models"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error is a failure that maps directly onto an HTTP response. Message is the
+// untranslated message ID (a fmt format when Args is set), so the handler can
+// localize it; Err keeps the underlying cause for logging.
+type Error struct {
+	Status  int
+	Message string
+	Args    []any
+	Err     error
+}
+
+func (e *Error) Error() string {
+	text := e.Message
+	if len(e.Args) > 0 {
+		text = fmt.Sprintf(e.Message, e.Args...)
+	}
+	if e.Err != nil {
+		return text + ": " + e.Err.Error()
+	}
+	return text
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func invalid(message string, args ...any) *Error {
+	return &Error{Status: http.StatusBadRequest, Message: message, Args: args}
+}
+
+func unauthorized(message string) *Error {
+	return &Error{Status: http.StatusUnauthorized, Message: message}
+}
+
+func forbidden(message string) *Error {
+	return &Error{Status: http.StatusForbidden, Message: message}
+}
+
+func notFound(message string) *Error {
+	return &Error{Status: http.StatusNotFound, Message: message}
+}
+
+func conflict(message string, args ...any) *Error {
+	return &Error{Status: http.StatusConflict, Message: message, Args: args}
+}
+
+// fail wraps an unexpected error. Message may contain a %v verb for err.
+func fail(message string, err error) *Error {
+	e := &Error{Status: http.StatusInternalServerError, Message: message, Err: err}
+	if strings.Contains(message, "%v") {
+		e.Args = []any{err}
+	}
+	return e
+}
+
+// Actor identifies who made a change, for the audit log.
+type Actor struct {
+	ID    int
+	Email string
+	IP    string
+}
+
+func (a Actor) audit(action, entityType string, entityID int, before, after interface{}) models.AuditEntry {
+	return models.AuditEntry{
+		ActorID:    a.ID,
+		ActorEmail: a.Email,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Before:     before,
+		After:      after,
+		IPAddress:  a.IP,
+	}
+}
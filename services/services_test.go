@@ -0,0 +1,1776 @@
+package services
+
+import (
+	"coffee-shop/cache"
+	"coffee-shop/libs"
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"coffee-shop/repositories/memory"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type fakeImages struct {
+	deleted []string
+}
+
+func (f *fakeImages) Validate(*multipart.FileHeader) error { return nil }
+
+func (f *fakeImages) Upload(context.Context, multipart.File, string, string) (string, string, error) {
+	return "https://img.example/new.png", "new", nil
+}
+
+func (f *fakeImages) UploadMany(_ context.Context, headers []*multipart.FileHeader, _ string) ([]UploadedImage, error) {
+	uploaded := []UploadedImage{}
+	for _, h := range headers {
+		uploaded = append(uploaded, UploadedImage{URL: "https://img.example/" + h.Filename, ID: h.Filename})
+	}
+	return uploaded, nil
+}
+
+func (f *fakeImages) Delete(_ context.Context, id string) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+var admin = Actor{ID: 1, Email: "admin@example.com", IP: "127.0.0.1"}
+
+func assertStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var serviceErr *Error
+	if !errors.As(err, &serviceErr) {
+		t.Fatalf("expected *services.Error with status %d, got %v", status, err)
+	}
+	if serviceErr.Status != status {
+		t.Fatalf("status = %d (%s), want %d", serviceErr.Status, serviceErr.Message, status)
+	}
+}
+
+func seedProduct(t *testing.T, store repositories.Store, p models.Product) models.Product {
+	t.Helper()
+	if err := store.Products().Create(context.Background(), &p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestProductServiceCreateValidatesAndAudits(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	responses := cache.NewMemory()
+	productCache := cache.NewNamespace(responses, ProductCacheNamespace)
+	productCache.Set(ctx, "list_en_p1_l10", "{}", 0)
+	svc := NewProductService(store, &fakeImages{}, responses)
+
+	_, err := svc.Create(ctx, admin, ProductInput{Name: "Latte", CategoryID: 1, Price: 500}, nil)
+	assertStatus(t, err, http.StatusBadRequest)
+	if _, ok := productCache.Get(ctx, "list_en_p1_l10"); !ok {
+		t.Fatal("a rejected create must not invalidate the cache")
+	}
+
+	p, err := svc.Create(ctx, admin, ProductInput{Name: "  Latte  ", CategoryID: 1, Price: 25000, Stock: 3}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID == 0 || p.Name != "Latte" || !p.IsActive {
+		t.Fatalf("unexpected product %+v", p)
+	}
+	if _, ok := productCache.Get(ctx, "list_en_p1_l10"); ok {
+		t.Fatal("expected product cache to be cleared")
+	}
+
+	entries := store.AuditEntries()
+	if len(entries) != 1 || entries[0].Action != models.AuditActionCreate || entries[0].EntityID != p.ID {
+		t.Fatalf("unexpected audit entries %+v", entries)
+	}
+}
+
+func TestProductServiceUpdateKeepsUnsetFields(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	images := &fakeImages{}
+	svc := NewProductService(store, images, cache.Noop{})
+	existing := seedProduct(t, store, models.Product{
+		Name: "Mocha", CategoryID: 2, Price: 30000, Stock: 5, CloudinaryID: "old", IsActive: true,
+	})
+
+	price := 32000
+	updated, err := svc.Update(ctx, admin, existing.ID, ProductPatch{Price: &price}, &Upload{Header: &multipart.FileHeader{Filename: "a.png"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "Mocha" || updated.Stock != 5 || updated.Price != 32000 {
+		t.Fatalf("unexpected product %+v", updated)
+	}
+	if updated.CloudinaryID != "new" || len(images.deleted) != 1 || images.deleted[0] != "old" {
+		t.Fatalf("expected old image to be replaced, deleted=%v", images.deleted)
+	}
+
+	_, err = svc.Update(ctx, admin, 999, ProductPatch{}, nil)
+	assertStatus(t, err, http.StatusNotFound)
+}
+
+func TestProductServiceArchiveRestoreAndPurge(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	images := &fakeImages{}
+	svc := NewProductService(store, images, cache.Noop{})
+	latte := seedProduct(t, store, models.Product{Name: "Latte", CategoryID: 1, Price: 25000, Stock: 5, CloudinaryID: "latte", IsActive: true})
+	mocha := seedProduct(t, store, models.Product{Name: "Mocha", CategoryID: 1, Price: 30000, Stock: 5, CloudinaryID: "mocha", IsActive: true})
+
+	orderID, err := store.Orders().Create(ctx, repositories.NewOrder{UserID: 7, StatusID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Orders().AddItem(ctx, orderID, repositories.NewOrderItem{ProductID: latte.ID, Quantity: 1, UnitPrice: 25000}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = svc.Purge(ctx, admin, latte.ID)
+	assertStatus(t, err, http.StatusConflict)
+
+	for _, id := range []int{latte.ID, mocha.ID} {
+		if err := svc.Delete(ctx, admin, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertStatus(t, svc.Delete(ctx, admin, latte.ID), http.StatusConflict)
+	if len(images.deleted) != 0 {
+		t.Fatalf("archiving deleted images %v", images.deleted)
+	}
+
+	listed, total, err := svc.List(ctx, repositories.ProductFilter{})
+	if err != nil || total != 0 || len(listed) != 0 {
+		t.Fatalf("storefront lists %d archived products (err %v)", total, err)
+	}
+	_, err = svc.Get(ctx, latte.ID, "")
+	assertStatus(t, err, http.StatusNotFound)
+
+	archived, total, err := svc.Archived(ctx, pagination.Params{Limit: 10})
+	if err != nil || total != 2 || len(archived) != 2 || archived[0].DeletedAt == nil {
+		t.Fatalf("archived = %+v, total %d, err %v", archived, total, err)
+	}
+
+	restored, err := svc.Restore(ctx, admin, latte.ID)
+	if err != nil || restored.DeletedAt != nil {
+		t.Fatalf("restored = %+v, err %v", restored, err)
+	}
+	if _, err := svc.Get(ctx, latte.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.Restore(ctx, admin, latte.ID)
+	assertStatus(t, err, http.StatusConflict)
+
+	assertStatus(t, svc.Purge(ctx, admin, latte.ID), http.StatusConflict)
+	if err := svc.Purge(ctx, admin, mocha.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Products().Get(ctx, mocha.ID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Fatalf("purged product still stored: %v", err)
+	}
+	if !slices.Equal(images.deleted, []string{"mocha"}) {
+		t.Fatalf("deleted images = %v", images.deleted)
+	}
+
+	actions := []string{}
+	for _, e := range store.AuditEntries() {
+		actions = append(actions, e.Action)
+	}
+	want := []string{models.AuditActionArchive, models.AuditActionArchive, models.AuditActionRestore, models.AuditActionDelete}
+	if !slices.Equal(actions, want) {
+		t.Fatalf("audit actions = %v, want %v", actions, want)
+	}
+}
+
+func TestProductServiceGalleryKeepsPrimaryInSync(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	images := &fakeImages{}
+	svc := NewProductService(store, images, cache.Noop{})
+
+	p, err := svc.Create(ctx, admin, ProductInput{Name: "Latte", CategoryID: 1, Price: 25000, Stock: 5},
+		&Upload{Header: &multipart.FileHeader{Filename: "a.png"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := func(names ...string) []*multipart.FileHeader {
+		headers := []*multipart.FileHeader{}
+		for _, n := range names {
+			headers = append(headers, &multipart.FileHeader{Filename: n})
+		}
+		return headers
+	}
+	gallery, err := svc.AddImages(ctx, admin, p.ID, files("b.png", "c.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gallery) != 3 || !gallery[0].IsPrimary || gallery[0].CloudinaryID != "new" || gallery[2].Order != 3 {
+		t.Fatalf("gallery = %+v", gallery)
+	}
+	_, err = svc.AddImages(ctx, admin, p.ID, files("d.png", "e.png"))
+	assertStatus(t, err, http.StatusBadRequest)
+
+	first, second, third := gallery[0].ID, gallery[1].ID, gallery[2].ID
+	_, err = svc.ReorderImages(ctx, admin, p.ID, []int{third, first})
+	assertStatus(t, err, http.StatusBadRequest)
+	gallery, err = svc.ReorderImages(ctx, admin, p.ID, []int{third, first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gallery[0].ID != third || gallery[0].Order != 1 || !gallery[1].IsPrimary {
+		t.Fatalf("reordered gallery = %+v", gallery)
+	}
+
+	if _, err := svc.SetPrimaryImage(ctx, admin, p.ID, third); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := store.Products().Get(ctx, p.ID)
+	if stored.ImageURL != "https://img.example/c.png" || stored.CloudinaryID != "c.png" {
+		t.Fatalf("product image = %q (%q), want the primary image", stored.ImageURL, stored.CloudinaryID)
+	}
+	_, err = svc.SetPrimaryImage(ctx, admin, p.ID, 999)
+	assertStatus(t, err, http.StatusNotFound)
+
+	gallery, err = svc.DeleteImage(ctx, admin, p.ID, third)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gallery) != 2 || gallery[0].ID != first || !gallery[0].IsPrimary || gallery[1].Order != 2 {
+		t.Fatalf("gallery after delete = %+v", gallery)
+	}
+	stored, _ = store.Products().Get(ctx, p.ID)
+	if stored.CloudinaryID != "new" || !slices.Equal(images.deleted, []string{"c.png"}) {
+		t.Fatalf("product image %q, deleted %v", stored.CloudinaryID, images.deleted)
+	}
+
+	if err := svc.Delete(ctx, admin, p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Purge(ctx, admin, p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(images.deleted, []string{"c.png", "new", "b.png"}) {
+		t.Fatalf("deleted images = %v", images.deleted)
+	}
+}
+
+func TestProductServiceGalleryOverLimitCanShrink(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	svc := NewProductService(store, &fakeImages{}, cache.Noop{})
+	p := seedProduct(t, store, models.Product{Name: "Matcha", CategoryID: 1, Price: 27000, IsActive: true})
+
+	// A gallery that grew past the limit before it was enforced.
+	ids := []int{}
+	for i := 1; i <= MaxProductImages+1; i++ {
+		img := models.ProductImage{ProductID: p.ID, URL: "m" + strconv.Itoa(i) + ".jpg", IsPrimary: i == 1, Order: i}
+		if err := store.Products().AddImage(ctx, &img); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, img.ID)
+	}
+
+	_, err := svc.AddImages(ctx, admin, p.ID, []*multipart.FileHeader{{Filename: "n.png"}})
+	assertStatus(t, err, http.StatusBadRequest)
+	slices.Reverse(ids)
+	if _, err := svc.ReorderImages(ctx, admin, p.ID, ids); err != nil {
+		t.Fatal(err)
+	}
+	gallery, err := svc.DeleteImage(ctx, admin, p.ID, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gallery) != MaxProductImages {
+		t.Fatalf("gallery after delete = %+v", gallery)
+	}
+}
+
+func TestProductServiceListAppliesTranslation(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	p := seedProduct(t, store, models.Product{Name: "Iced Tea", CategoryID: 1, Price: 15000, IsActive: true})
+	store.SetProductTranslation(models.ProductTranslation{ProductID: p.ID, Locale: "id", Name: "Es Teh"})
+	svc := NewProductService(store, &fakeImages{}, cache.Noop{})
+
+	products, total, err := svc.List(ctx, repositories.ProductFilter{Search: "teh", Locale: "id", Params: pagination.Params{Limit: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || products[0].Name != "Es Teh" {
+		t.Fatalf("got %d products: %+v", total, products)
+	}
+}
+
+func TestProductServiceSearchRanksAndHighlights(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	latte := seedProduct(t, store, models.Product{Name: "Iced Latte", Description: "Espresso with cold milk", CategoryID: 1, Price: 25000, IsActive: true})
+	espresso := seedProduct(t, store, models.Product{Name: "Espresso", Description: "A bold single shot", CategoryID: 1, Price: 18000, IsActive: true})
+	seedProduct(t, store, models.Product{Name: "Green Tea", CategoryID: 1, Price: 15000, IsActive: true})
+	svc := NewProductService(store, &fakeImages{}, cache.Noop{})
+
+	products, total, err := svc.List(ctx, repositories.ProductFilter{Search: "espresso", Params: pagination.Params{Limit: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || products[0].ID != espresso.ID || products[1].ID != latte.ID {
+		t.Fatalf("got %d products: %+v", total, products)
+	}
+	if products[0].Relevance <= products[1].Relevance {
+		t.Fatalf("relevance not descending: %v, %v", products[0].Relevance, products[1].Relevance)
+	}
+	if h := products[1].Highlight; h == nil || h.Name != "Iced Latte" || h.Description != "<mark>Espresso</mark> with cold milk" {
+		t.Fatalf("highlight = %+v", h)
+	}
+
+	_, _, err = svc.List(ctx, repositories.ProductFilter{
+		Search: "latte",
+		Params: pagination.Params{Limit: 10, After: &pagination.Cursor{ID: latte.ID}},
+	})
+	assertStatus(t, err, http.StatusBadRequest)
+}
+
+func TestProductServiceSortsAndCountsFacets(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	coffee := models.Category{Name: "Coffee"}
+	tea := models.Category{Name: "Tea"}
+	for _, c := range []*models.Category{&coffee, &tea} {
+		if err := store.Categories().Create(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	latte := seedProduct(t, store, models.Product{Name: "Latte", CategoryID: coffee.ID, Price: 25000, Stock: 5, IsFeatured: true, IsActive: true})
+	mocha := seedProduct(t, store, models.Product{Name: "Mocha", CategoryID: coffee.ID, Price: 28000, Stock: 0, IsBuy1Get1: true, IsActive: true})
+	matcha := seedProduct(t, store, models.Product{Name: "Matcha", CategoryID: tea.ID, Price: 12000, Stock: 3, IsFlashSale: true, IsActive: true})
+	store.AddReview(mocha.ID, 5)
+	store.AddReview(latte.ID, 4)
+	store.AddReview(latte.ID, 5)
+	svc := NewProductService(store, &fakeImages{}, cache.Noop{})
+
+	ids := func(filter repositories.ProductFilter) []int {
+		t.Helper()
+		products, _, err := svc.List(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := []int{}
+		for _, p := range products {
+			out = append(out, p.ID)
+		}
+		return out
+	}
+	if got := ids(repositories.ProductFilter{Sort: repositories.SortPriceAsc}); !slices.Equal(got, []int{matcha.ID, latte.ID, mocha.ID}) {
+		t.Fatalf("price_asc = %v", got)
+	}
+	if got := ids(repositories.ProductFilter{Sort: repositories.SortTopRated}); !slices.Equal(got, []int{mocha.ID, latte.ID, matcha.ID}) {
+		t.Fatalf("top_rated = %v", got)
+	}
+	if got := ids(repositories.ProductFilter{InStock: true, Sort: repositories.SortPriceDesc}); !slices.Equal(got, []int{latte.ID, matcha.ID}) {
+		t.Fatalf("in_stock = %v", got)
+	}
+
+	facets, err := svc.Facets(ctx, repositories.ProductFilter{InStock: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(facets.Categories) != 2 || facets.Categories[0] != (models.CategoryFacet{ID: coffee.ID, Name: "Coffee", Count: 1}) {
+		t.Fatalf("categories = %+v", facets.Categories)
+	}
+	wantPrices := []models.PriceFacet{{Min: 0, Max: 15000, Count: 1}, {Min: 15000, Max: 25000}, {Min: 25000, Max: 35000, Count: 1}, {Min: 35000}}
+	if !slices.Equal(facets.PriceRanges, wantPrices) {
+		t.Fatalf("price ranges = %+v", facets.PriceRanges)
+	}
+	if facets.FlashSale != 1 || facets.Featured != 1 || facets.Buy1Get1 != 0 || facets.InStock != 2 {
+		t.Fatalf("flags = %+v", facets)
+	}
+
+	_, _, err = svc.List(ctx, repositories.ProductFilter{Sort: "cheapest"})
+	assertStatus(t, err, http.StatusBadRequest)
+	_, _, err = svc.List(ctx, repositories.ProductFilter{
+		Sort:   repositories.SortPriceAsc,
+		Params: pagination.Params{Limit: 10, After: &pagination.Cursor{ID: latte.ID}},
+	})
+	assertStatus(t, err, http.StatusBadRequest)
+}
+
+func TestProductServiceFavorites(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	svc := NewProductService(store, &fakeImages{}, cache.Noop{})
+	latte := seedProduct(t, store, models.Product{Name: "Latte", CategoryID: 1, Price: 25000, IsActive: true})
+	mocha := seedProduct(t, store, models.Product{Name: "Mocha", CategoryID: 1, Price: 30000, IsActive: true})
+	tea := seedProduct(t, store, models.Product{Name: "Tea", CategoryID: 1, Price: 15000})
+
+	_, err := svc.AddFavorite(ctx, 7, tea.ID)
+	assertStatus(t, err, http.StatusNotFound)
+	for _, id := range []int{latte.ID, mocha.ID, latte.ID} {
+		if _, err := svc.AddFavorite(ctx, 7, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if added, _ := svc.AddFavorite(ctx, 7, mocha.ID); added {
+		t.Fatal("adding a favourite twice reported it as new")
+	}
+
+	first, total, err := svc.FavoriteProducts(ctx, 7, "", pagination.Params{Limit: 1})
+	if err != nil || total != 2 || len(first) != 1 || first[0].ID != mocha.ID || !*first[0].IsFavorited || first[0].FavoritedAt == nil {
+		t.Fatalf("first page = %+v, total %d, err %v", first, total, err)
+	}
+	after := pagination.Cursor{CreatedAt: *first[0].FavoritedAt, ID: first[0].ID}
+	second, _, err := svc.FavoriteProducts(ctx, 7, "", pagination.Params{Limit: 1, After: &after})
+	if err != nil || len(second) != 1 || second[0].ID != latte.ID {
+		t.Fatalf("second page = %+v, err %v", second, err)
+	}
+
+	listed, _, err := svc.List(ctx, repositories.ProductFilter{Params: pagination.Params{Limit: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listed[0].IsFavorited != nil {
+		t.Fatal("listed products are marked before MarkFavorited")
+	}
+	if err := svc.MarkFavorited(ctx, 8, listed); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range listed {
+		if *p.IsFavorited {
+			t.Fatalf("another user's favourite marked on %+v", p)
+		}
+	}
+
+	if err := svc.RemoveFavorite(ctx, 7, latte.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, svc.RemoveFavorite(ctx, 7, latte.ID), http.StatusNotFound)
+	if err := svc.Delete(ctx, admin, mocha.ID); err != nil {
+		t.Fatal(err)
+	}
+	if favorites, total, _ := svc.FavoriteProducts(ctx, 7, "", pagination.Params{Limit: 10}); total != 0 || len(favorites) != 0 {
+		t.Fatalf("favourites after removing latte and archiving mocha = %+v", favorites)
+	}
+}
+
+func TestProductServiceRecentlyViewedAndPopularity(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	responses := cache.NewMemory()
+	svc := NewProductService(store, &fakeImages{}, responses)
+	latte := seedProduct(t, store, models.Product{Name: "Latte", CategoryID: 1, Price: 25000, IsActive: true})
+	mocha := seedProduct(t, store, models.Product{Name: "Mocha", CategoryID: 1, Price: 30000, IsActive: true})
+	matcha := seedProduct(t, store, models.Product{Name: "Matcha", CategoryID: 1, Price: 20000, IsActive: true})
+
+	names := func(products []models.Product) []string {
+		out := []string{}
+		for _, p := range products {
+			out = append(out, p.Name)
+		}
+		return out
+	}
+	recent := func(userID int) []string {
+		t.Helper()
+		products, err := svc.RecentlyViewed(ctx, userID, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return names(products)
+	}
+
+	for _, id := range []int{latte.ID, mocha.ID, latte.ID, latte.ID} {
+		svc.RecordView(ctx, 7, id)
+	}
+	if got := recent(7); !slices.Equal(got, []string{"Latte", "Mocha"}) {
+		t.Fatalf("recently viewed = %v", got)
+	}
+	// Once cached, the list is kept up to date by each view.
+	svc.RecordView(ctx, 7, mocha.ID)
+	if cached, _ := responses.GetList(ctx, recentlyViewedKey(7)); !slices.Equal(cached, []string{strconv.Itoa(mocha.ID), strconv.Itoa(latte.ID)}) {
+		t.Fatalf("cached list = %v", cached)
+	}
+	if err := svc.Delete(ctx, admin, mocha.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := recent(7); !slices.Equal(got, []string{"Latte"}) {
+		t.Fatalf("recently viewed after archiving mocha = %v", got)
+	}
+	if got := recent(8); len(got) != 0 {
+		t.Fatalf("another user's recently viewed = %v", got)
+	}
+
+	// Latte's three views by one user count once; matcha's two viewers count
+	// twice, and a view older than the window not at all. Views only count
+	// once popularity is refreshed, which also drops those past retention.
+	svc.RecordView(ctx, 8, matcha.ID)
+	svc.RecordView(ctx, 9, matcha.ID)
+	if err := store.Views().Record(ctx, 8, latte.ID, time.Now().Add(-repositories.PopularityWindow-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Views().Record(ctx, 9, mocha.ID, time.Now().Add(-ProductViewRetention-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := svc.RefreshPopularity(ctx, time.Now()); err != nil || n != 1 {
+		t.Fatalf("refresh = %d, %v", n, err)
+	}
+	products, _, err := svc.List(ctx, repositories.ProductFilter{Sort: repositories.SortPopular})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(products); !slices.Equal(got, []string{"Matcha", "Latte"}) {
+		t.Fatalf("popular = %v", got)
+	}
+}
+
+func TestProductReviewsNeedPurchaseAndModeration(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	svc := NewProductService(store, &fakeImages{}, cache.Noop{})
+	latte := seedProduct(t, store, models.Product{Name: "Latte", CategoryID: 1, Price: 25000, IsActive: true})
+	rating := func(n int) *int { return &n }
+	completed := func(userID int) {
+		t.Helper()
+		orderID, err := store.Orders().Create(ctx, repositories.NewOrder{UserID: userID, StatusID: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Orders().AddItem(ctx, orderID, repositories.NewOrderItem{ProductID: latte.ID, Quantity: 1, UnitPrice: 25000}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stored := func() models.Product {
+		t.Helper()
+		p, err := store.Products().Get(ctx, latte.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	_, err := svc.CreateReview(ctx, 7, latte.ID, models.ReviewRequest{Rating: rating(4)})
+	assertStatus(t, err, http.StatusForbidden)
+	completed(7)
+	completed(8)
+	_, err = svc.CreateReview(ctx, 7, latte.ID, models.ReviewRequest{})
+	assertStatus(t, err, http.StatusBadRequest)
+	_, err = svc.CreateReview(ctx, 7, latte.ID, models.ReviewRequest{Rating: rating(0)})
+	assertStatus(t, err, http.StatusBadRequest)
+
+	mine, err := svc.CreateReview(ctx, 7, latte.ID, models.ReviewRequest{Rating: rating(4)})
+	if err != nil || mine.Status != models.ReviewPending {
+		t.Fatalf("review = %+v, err %v", mine, err)
+	}
+	_, err = svc.CreateReview(ctx, 7, latte.ID, models.ReviewRequest{Rating: rating(5)})
+	assertStatus(t, err, http.StatusConflict)
+	theirs, err := svc.CreateReview(ctx, 8, latte.ID, models.ReviewRequest{Rating: rating(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _, _ := svc.Reviews(ctx, latte.ID, pagination.Params{Limit: 10}); total != 0 {
+		t.Fatalf("%d pending reviews are public", total)
+	}
+
+	approved := models.ReviewApproved
+	for _, id := range []int{mine.ID, theirs.ID} {
+		if _, err := svc.ModerateReview(ctx, admin, id, models.ReviewModerationRequest{Status: &approved}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p := stored(); p.AverageRating != 2.5 || p.ReviewCount != 2 {
+		t.Fatalf("rating after approval = %v from %d reviews", p.AverageRating, p.ReviewCount)
+	}
+	_, total, summary, err := svc.Reviews(ctx, latte.ID, pagination.Params{Limit: 10})
+	if err != nil || total != 2 || summary.Average != 2.5 || summary.Distribution[1] != (models.RatingCount{Rating: 4, Count: 1}) {
+		t.Fatalf("reviews = %d, summary %+v, err %v", total, summary, err)
+	}
+
+	reply := " Thanks! "
+	replied, err := svc.ModerateReview(ctx, admin, mine.ID, models.ReviewModerationRequest{Reply: &reply})
+	if err != nil || replied.Reply != "Thanks!" || replied.RepliedAt == nil || replied.Status != models.ReviewApproved {
+		t.Fatalf("replied = %+v, err %v", replied, err)
+	}
+	pending := models.ReviewPending
+	_, err = svc.ModerateReview(ctx, admin, mine.ID, models.ReviewModerationRequest{Status: &pending})
+	assertStatus(t, err, http.StatusBadRequest)
+
+	_, err = svc.UpdateReview(ctx, 8, latte.ID, mine.ID, models.ReviewRequest{Rating: rating(5)})
+	assertStatus(t, err, http.StatusNotFound)
+	edited, err := svc.UpdateReview(ctx, 7, latte.ID, mine.ID, models.ReviewRequest{Rating: rating(5)})
+	if err != nil || edited.Status != models.ReviewPending || edited.Rating != 5 {
+		t.Fatalf("edited = %+v, err %v", edited, err)
+	}
+	if p := stored(); p.AverageRating != 1 || p.ReviewCount != 1 {
+		t.Fatalf("rating after edit = %v from %d reviews", p.AverageRating, p.ReviewCount)
+	}
+
+	queue, _, err := svc.ReviewQueue(ctx, models.ReviewPending, pagination.Params{Limit: 10})
+	if err != nil || len(queue) != 1 || queue[0].ID != mine.ID {
+		t.Fatalf("queue = %+v, err %v", queue, err)
+	}
+	if err := svc.DeleteReview(ctx, 8, latte.ID, theirs.ID); err != nil {
+		t.Fatal(err)
+	}
+	if p := stored(); p.AverageRating != 0 || p.ReviewCount != 0 {
+		t.Fatalf("rating after delete = %v from %d reviews", p.AverageRating, p.ReviewCount)
+	}
+
+	entries := store.AuditEntries()
+	if len(entries) != 3 || entries[0].EntityType != models.AuditEntityProductReview {
+		t.Fatalf("audit = %+v", entries)
+	}
+}
+
+func TestProductRecommendationsBlendSources(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	svc := NewProductService(store, &fakeImages{}, cache.Noop{})
+	latte := seedProduct(t, store, models.Product{Name: "Latte", CategoryID: 1, Price: 25000, Stock: 9, IsActive: true})
+	mocha := seedProduct(t, store, models.Product{Name: "Mocha", CategoryID: 1, Price: 30000, IsActive: true})
+	seedProduct(t, store, models.Product{Name: "Americano", CategoryID: 1, Price: 20000, IsActive: true})
+	cookie := seedProduct(t, store, models.Product{Name: "Cookie", CategoryID: 4, Price: 15000, IsActive: true})
+	pie := seedProduct(t, store, models.Product{Name: "Hand Pie", CategoryID: 4, Price: 18000, IsActive: true})
+	croissant := seedProduct(t, store, models.Product{Name: "Croissant", CategoryID: 4, Price: 20000, IsActive: true})
+	order := func(statusID int, productIDs ...int) {
+		t.Helper()
+		orderID, err := store.Orders().Create(ctx, repositories.NewOrder{UserID: 7, StatusID: statusID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range productIDs {
+			if err := store.Orders().AddItem(ctx, orderID, repositories.NewOrderItem{ProductID: id, Quantity: 1, UnitPrice: 10000}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	types := func(products []models.Product) []string {
+		out := []string{}
+		for _, p := range products {
+			out = append(out, p.Name+":"+p.RecommendationType)
+		}
+		return out
+	}
+
+	curate := func(recommended models.Product, recommendationType string, priority int) models.ProductRecommendation {
+		t.Helper()
+		rec, err := svc.CreateRecommendation(ctx, admin, latte.ID, RecommendationInput{
+			RecommendedProductID: recommended.ID, Type: recommendationType, Priority: priority})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+	curate(cookie, models.RecommendationPairing, 5)
+	curate(mocha, models.RecommendationUpsell, 10)
+	hidden := curate(croissant, models.RecommendationCrossSell, 20)
+	off := false
+	if _, err := svc.UpdateRecommendation(ctx, admin, latte.ID, hidden.ID, RecommendationUpdate{IsActive: &off}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := svc.CreateRecommendation(ctx, admin, latte.ID, RecommendationInput{RecommendedProductID: latte.ID, Type: models.RecommendationPairing})
+	assertStatus(t, err, http.StatusBadRequest)
+	_, err = svc.CreateRecommendation(ctx, admin, latte.ID, RecommendationInput{RecommendedProductID: pie.ID, Type: "bought_together"})
+	assertStatus(t, err, http.StatusBadRequest)
+	_, err = svc.CreateRecommendation(ctx, admin, latte.ID, RecommendationInput{RecommendedProductID: 999, Type: models.RecommendationPairing})
+	assertStatus(t, err, http.StatusBadRequest)
+	_, err = svc.CreateRecommendation(ctx, admin, latte.ID, RecommendationInput{RecommendedProductID: cookie.ID, Type: models.RecommendationCrossSell})
+	assertStatus(t, err, http.StatusConflict)
+	_, err = svc.UpdateRecommendation(ctx, admin, mocha.ID, hidden.ID, RecommendationUpdate{IsActive: &off})
+	assertStatus(t, err, http.StatusNotFound)
+
+	order(2, latte.ID, pie.ID)
+	order(2, latte.ID, pie.ID)
+	order(2, latte.ID, croissant.ID)
+	order(3, latte.ID, croissant.ID)
+	order(3, latte.ID, croissant.ID)
+	if pairs, err := svc.RefreshBoughtTogether(ctx, time.Now()); err != nil || pairs != 1 {
+		t.Fatalf("pairs = %d, err %v", pairs, err)
+	}
+
+	got, err := svc.Recommendations(ctx, latte.ID, 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Mocha:upsell", "Cookie:pairing", "Hand Pie:bought_together", "Americano:same_category", "Croissant:best_selling"}
+	if !slices.Equal(types(got), want) {
+		t.Fatalf("recommendations = %v, want %v", types(got), want)
+	}
+	d, err := svc.Detail(ctx, latte.ID, "")
+	if err != nil || len(d.Recommendations) != 3 || d.Recommendations[0].RecommendationType != models.RecommendationUpsell {
+		t.Fatalf("detail recommendations = %+v, err %v", d.Recommendations, err)
+	}
+
+	if _, _, err := NewCartService(store).Add(ctx, repositories.CartItemKey{UserID: 9, ProductID: latte.ID}, 1); err != nil {
+		t.Fatal(err)
+	}
+	cart, err := svc.CartRecommendations(ctx, 9, 3, "")
+	if want := []string{"Cookie:pairing", "Hand Pie:bought_together", "Mocha:same_category"}; err != nil || !slices.Equal(types(cart), want) {
+		t.Fatalf("cart recommendations = %v, want %v (err %v)", types(cart), want, err)
+	}
+	empty, err := svc.CartRecommendations(ctx, 10, 2, "")
+	if want := []string{"Latte:best_selling", "Hand Pie:best_selling"}; err != nil || !slices.Equal(types(empty), want) {
+		t.Fatalf("empty cart recommendations = %v, want %v (err %v)", types(empty), want, err)
+	}
+
+	if err := svc.DeleteRecommendation(ctx, admin, latte.ID, hidden.ID); err != nil {
+		t.Fatal(err)
+	}
+	curated, err := svc.ProductRecommendations(ctx, latte.ID)
+	if err != nil || len(curated) != 2 || curated[0].RecommendedProductName != "Mocha" {
+		t.Fatalf("curated = %+v, err %v", curated, err)
+	}
+	if entries := store.AuditEntries(); len(entries) != 5 || entries[4].EntityType != models.AuditEntityProductRecommendation {
+		t.Fatalf("audit = %+v", entries)
+	}
+}
+
+func TestCategoryServiceRejectsDuplicateName(t *testing.T) {
+	ctx := context.Background()
+	responses := cache.NewMemory()
+	svc := NewCategoryService(memory.NewStore(), responses)
+	productCache := cache.NewNamespace(responses, ProductCacheNamespace)
+	categoryCache := cache.NewNamespace(responses, CategoryCacheNamespace)
+	productCache.Set(ctx, "suggest_la", "{}", 0)
+	categoryCache.Set(ctx, "list_en", "[]", 0)
+
+	if _, err := svc.Create(ctx, admin, "Coffee"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := categoryCache.Get(ctx, "list_en"); ok {
+		t.Fatal("creating a category must invalidate the category cache")
+	}
+	if _, ok := productCache.Get(ctx, "suggest_la"); ok {
+		t.Fatal("creating a category must invalidate the product cache")
+	}
+
+	categoryCache.Set(ctx, "list_en", "[]", 0)
+	_, err := svc.Create(ctx, admin, "Coffee")
+	assertStatus(t, err, http.StatusBadRequest)
+	if _, ok := categoryCache.Get(ctx, "list_en"); !ok {
+		t.Fatal("a rejected create must not invalidate the cache")
+	}
+
+	err = svc.Delete(ctx, admin, 42)
+	assertStatus(t, err, http.StatusNotFound)
+}
+
+func TestUserServiceCreateHashesPassword(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	svc := NewUserService(store, cache.NewMemory())
+
+	_, err := svc.Create(ctx, admin, NewUserInput{Email: "not-an-email", Password: "secret1", Role: "customer"})
+	assertStatus(t, err, http.StatusBadRequest)
+
+	u, err := svc.Create(ctx, admin, NewUserInput{Email: "ani@example.com", Password: "secret1", Role: "customer", FullName: "Ani"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = svc.Create(ctx, admin, NewUserInput{Email: "ani@example.com", Password: "secret1", Role: "customer"})
+	assertStatus(t, err, http.StatusBadRequest)
+
+	if _, err := svc.Delete(ctx, admin, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.AuditEntries()) != 2 {
+		t.Fatalf("expected create and delete to be audited, got %+v", store.AuditEntries())
+	}
+}
+
+func TestUserServiceResetPassword(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	svc := NewUserService(store, cache.NewMemory())
+
+	u, err := svc.Create(ctx, admin, NewUserInput{Email: "ani@example.com", Password: "secret1", Role: "customer", FullName: "Ani"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertStatus(t, svc.ResetPassword(ctx, admin, "nobody@example.com", "secret2"), http.StatusNotFound)
+	assertStatus(t, svc.ResetPassword(ctx, admin, u.Email, "short"), http.StatusBadRequest)
+
+	if err := svc.ResetPassword(ctx, admin, u.Email, "secret2"); err != nil {
+		t.Fatal(err)
+	}
+	if !libs.VerifyPassword(store.PasswordHash(u.ID), "secret2") {
+		t.Fatal("password was not updated")
+	}
+	entries := store.AuditEntries()
+	if last := entries[len(entries)-1]; last.Action != models.AuditActionResetPassword || last.EntityID != u.ID {
+		t.Fatalf("unexpected audit entry %+v", last)
+	}
+}
+
+func TestUserServiceLanguage(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	svc := NewUserService(store, cache.NewMemory())
+
+	u, err := svc.Create(ctx, admin, NewUserInput{Email: "ani@example.com", Password: "secret1", Role: "customer", FullName: "Ani"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetLanguage(u.ID, "en")
+	if got := svc.Language(ctx, u.ID); got != "en" {
+		t.Fatalf("language = %q, want en", got)
+	}
+
+	// The cached value is served until a profile update replaces it.
+	store.SetLanguage(u.ID, "id")
+	if got := svc.Language(ctx, u.ID); got != "en" {
+		t.Fatalf("cached language = %q, want en", got)
+	}
+	assertStatus(t, svc.UpdateProfile(ctx, u.ID, repositories.ProfileUpdate{Language: "fr"}), http.StatusBadRequest)
+	if err := svc.UpdateProfile(ctx, u.ID, repositories.ProfileUpdate{Language: "ID"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := svc.Language(ctx, u.ID); got != "id" {
+		t.Fatalf("language after update = %q, want id", got)
+	}
+
+	if got := svc.Language(ctx, 999); got != "" {
+		t.Fatalf("unknown user language = %q", got)
+	}
+}
+
+// memoryOTPs is an OTPStore for tests.
+type memoryOTPs map[string]string
+
+func (m memoryOTPs) Save(_ context.Context, email, otp string, _ time.Duration) error {
+	m[email] = otp
+	return nil
+}
+
+func (m memoryOTPs) Get(_ context.Context, email string) (string, error) { return m[email], nil }
+
+func (m memoryOTPs) Delete(_ context.Context, email string) error {
+	delete(m, email)
+	return nil
+}
+
+func TestAuthServiceRegisterLoginAndReset(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	otps := memoryOTPs{}
+	svc := NewAuthService(store, otps)
+
+	_, err := svc.Register(ctx, RegisterInput{Email: "ani@example.com", Password: "secret1", FullName: "Ani", Language: "fr"})
+	assertStatus(t, err, http.StatusBadRequest)
+	u, err := svc.Register(ctx, RegisterInput{Email: "ani@example.com", Password: "secret1", FullName: "Ani", Language: "ID"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Role != "customer" {
+		t.Fatalf("role = %q, want customer", u.Role)
+	}
+	_, err = svc.Register(ctx, RegisterInput{Email: "ani@example.com", Password: "secret1", FullName: "Ani"})
+	assertStatus(t, err, http.StatusBadRequest)
+
+	_, err = svc.Login(ctx, "ani@example.com", "wrong-password")
+	assertStatus(t, err, http.StatusUnauthorized)
+	profile, err := svc.Login(ctx, "ani@example.com", "secret1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.ID != u.ID || profile.Language != "id" {
+		t.Fatalf("login = %+v", profile)
+	}
+
+	if err := svc.SendPasswordReset(ctx, "nobody@example.com", "en"); err != nil || len(otps) != 0 {
+		t.Fatalf("unknown email: err %v, otps %v", err, otps)
+	}
+	if err := svc.SendPasswordReset(ctx, "ani@example.com", "en"); err != nil {
+		t.Fatal(err)
+	}
+	otp := otps["ani@example.com"]
+	assertStatus(t, svc.ResetPassword(ctx, "ani@example.com", "000000x", "secret2"), http.StatusBadRequest)
+	if err := svc.ResetPassword(ctx, "ani@example.com", otp, "secret2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Login(ctx, "ani@example.com", "secret2"); err != nil {
+		t.Fatal(err)
+	}
+	// The OTP is spent.
+	assertStatus(t, svc.ResetPassword(ctx, "ani@example.com", otp, "secret3"), http.StatusBadRequest)
+
+	unavailable := NewAuthService(store, NewRedisOTPs(nil))
+	assertStatus(t, unavailable.SendPasswordReset(ctx, "ani@example.com", "en"), http.StatusInternalServerError)
+}
+
+func TestUserServiceChangePassword(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	svc := NewUserService(store, cache.NewMemory())
+	u, err := svc.Create(ctx, admin, NewUserInput{Email: "ani@example.com", Password: "secret1", Role: "customer", FullName: "Ani"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertStatus(t, svc.ChangePassword(ctx, u.ID, "secret1", "secret2", "secret3"), http.StatusBadRequest)
+	assertStatus(t, svc.ChangePassword(ctx, u.ID, "wrong1", "secret2", "secret2"), http.StatusBadRequest)
+	if err := svc.ChangePassword(ctx, u.ID, "secret1", "secret2", "secret2"); err != nil {
+		t.Fatal(err)
+	}
+	if !libs.VerifyPassword(store.PasswordHash(u.ID), "secret2") {
+		t.Fatal("password was not changed")
+	}
+}
+
+func TestTranslationServiceAuditsAndInvalidates(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	responses := cache.NewMemory()
+	svc := NewTranslationService(store, responses)
+	latte := seedProduct(t, store, models.Product{Name: "Latte", CategoryID: 1, Price: 25000, Stock: 5, IsActive: true})
+
+	products := cache.NewNamespace(responses, ProductCacheNamespace)
+	products.Set(ctx, "list", "cached", 0)
+
+	assertStatus(t, svc.SaveProductTranslation(ctx, admin, models.ProductTranslation{ProductID: latte.ID, Locale: "id"}), http.StatusBadRequest)
+	assertStatus(t, svc.SaveProductTranslation(ctx, admin, models.ProductTranslation{ProductID: 999, Locale: "id", Name: "Kopi"}), http.StatusNotFound)
+	for _, name := range []string{"Kopi Susu", "Kopi Latte"} {
+		if err := svc.SaveProductTranslation(ctx, admin, models.ProductTranslation{ProductID: latte.ID, Locale: "id", Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := products.Get(ctx, "list"); ok {
+		t.Fatal("saving a translation must invalidate the cached products")
+	}
+
+	translations, err := svc.ProductTranslations(ctx, latte.ID)
+	if err != nil || len(translations) != 1 || translations[0].Name != "Kopi Latte" {
+		t.Fatalf("translations = %+v, %v", translations, err)
+	}
+
+	if err := svc.DeleteProductTranslation(ctx, admin, latte.ID, "id"); err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, svc.DeleteProductTranslation(ctx, admin, latte.ID, "id"), http.StatusNotFound)
+
+	entries := store.AuditEntries()
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	if want := []string{models.AuditActionCreate, models.AuditActionUpdate, models.AuditActionDelete}; !slices.Equal(actions, want) {
+		t.Fatalf("audit actions = %v, want %v", actions, want)
+	}
+}
+
+func TestSearchServiceRecordsAndModeratesQueries(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	svc := NewSearchService(store, cache.NewMemory())
+
+	svc.RecordQuery(ctx, "Latte", "ip:192.0.2.1", 2)
+	svc.RecordQuery(ctx, "latte ", "ip:192.0.2.1", 2)
+	svc.RecordQuery(ctx, "latte", "user:7", 2)
+	svc.RecordQuery(ctx, "mocha", "user:7", 0)
+	if hits := store.SearchHits("latte"); hits != 2 {
+		t.Fatalf("latte hits = %d, want one per searcher", hits)
+	}
+	if hits := store.SearchHits("mocha"); hits != 0 {
+		t.Fatalf("mocha hits = %d, want none without results", hits)
+	}
+
+	pending, total, err := svc.Queries(ctx, models.SearchQueryPending, pagination.Params{Limit: 10})
+	if err != nil || total != 1 || pending[0].Query != "latte" {
+		t.Fatalf("pending = %+v, %v", pending, err)
+	}
+	_, err = svc.ModerateQuery(ctx, admin, pending[0].ID, models.SearchQueryPending)
+	assertStatus(t, err, http.StatusBadRequest)
+	_, err = svc.ModerateQuery(ctx, admin, pending[0].ID+100, models.SearchQueryAllowed)
+	assertStatus(t, err, http.StatusNotFound)
+
+	moderated, err := svc.ModerateQuery(ctx, admin, pending[0].ID, models.SearchQueryBlocked)
+	if err != nil || moderated.Status != models.SearchQueryBlocked {
+		t.Fatalf("moderated = %+v, %v", moderated, err)
+	}
+	entries := store.AuditEntries()
+	if len(entries) != 1 || entries[0].EntityType != models.AuditEntitySearchQuery {
+		t.Fatalf("audit = %+v", entries)
+	}
+
+	for _, searcher := range []string{"ip:192.0.2.2", "ip:192.0.2.3"} {
+		svc.RecordQuery(ctx, "latte", searcher, 2)
+	}
+	suggestions, err := svc.Suggest(ctx, "la", "", 5)
+	if err != nil || len(suggestions.Queries) != 0 {
+		t.Fatalf("blocked query suggested: %+v, %v", suggestions.Queries, err)
+	}
+}
+
+func TestAuditServiceListFilters(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	users := NewUserService(store, cache.NewMemory())
+	for _, email := range []string{"ani@example.com", "budi@example.com"} {
+		if _, err := users.Create(ctx, admin, NewUserInput{Email: email, Password: "secret1", Role: "customer", FullName: "Someone"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	latte := seedProduct(t, store, models.Product{Name: "Latte", CategoryID: 1, Price: 25000, IsActive: true})
+	if err := NewTranslationService(store, cache.NewMemory()).SaveProductTranslation(ctx, admin, models.ProductTranslation{ProductID: latte.ID, Locale: "id", Name: "Kopi Latte"}); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewAuditService(store)
+	page := pagination.Params{Page: 1, Limit: 1}
+	entries, total, err := svc.List(ctx, repositories.AuditFilter{EntityType: " USER "}, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(entries) != 1 || entries[0].EntityType != models.AuditEntityUser {
+		t.Fatalf("entries = %+v, total %d", entries, total)
+	}
+
+	next := pagination.Cursor{CreatedAt: entries[0].CreatedAt, ID: int(entries[0].ID)}
+	rest, _, err := svc.List(ctx, repositories.AuditFilter{EntityType: "user"}, pagination.Params{Limit: 10, After: &next})
+	if err != nil || len(rest) != 1 || rest[0].ID >= entries[0].ID {
+		t.Fatalf("after cursor = %+v, %v", rest, err)
+	}
+
+	entries, total, _ = svc.List(ctx, repositories.AuditFilter{Until: time.Now().Add(-time.Hour)}, page)
+	if total != 0 || len(entries) != 0 {
+		t.Fatalf("entries before an hour ago = %+v", entries)
+	}
+}
+
+func TestCartServiceMergesMatchingLines(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	p := seedProduct(t, store, models.Product{Name: "Americano", CategoryID: 1, Price: 20000, Stock: 3, IsActive: true})
+	svc := NewCartService(store)
+	key := repositories.CartItemKey{UserID: 7, ProductID: p.ID}
+
+	ref, created, err := svc.Add(ctx, key, 2)
+	if err != nil || !created {
+		t.Fatalf("first add: created=%v err=%v", created, err)
+	}
+
+	_, _, err = svc.Add(ctx, key, 2)
+	assertStatus(t, err, http.StatusBadRequest)
+
+	merged, created, err := svc.Add(ctx, key, 1)
+	if err != nil || created || merged.CartItemID != ref.CartItemID || merged.Quantity != 3 {
+		t.Fatalf("merge: ref=%+v created=%v err=%v", merged, created, err)
+	}
+}
+
+func TestProductOptionsGateCartAndCheckout(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	svc := NewProductService(store, &fakeImages{}, cache.Noop{})
+	carts := NewCartService(store)
+	latte := seedProduct(t, store, models.Product{Name: "Latte", CategoryID: 1, Price: 25000, Stock: 9, IsActive: true})
+	pastry := seedProduct(t, store, models.Product{Name: "Croissant", CategoryID: 3, Price: 20000, Stock: 9, IsActive: true})
+
+	regular, large, hot, iced := 1, 2, 1, 2
+	store.AddSize(models.ProductSizeV2{ID: regular, Name: "Regular"})
+	store.AddSize(models.ProductSizeV2{ID: large, Name: "Large"})
+	store.AddTemperature(models.ProductTemperatureV2{ID: hot, Name: "Hot"})
+	store.AddTemperature(models.ProductTemperatureV2{ID: iced, Name: "Iced"})
+
+	_, err := svc.CreateOption(ctx, admin, latte.ID, OptionInput{SizeID: &regular, TemperatureID: &hot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	icedLarge, err := svc.CreateOption(ctx, admin, latte.ID, OptionInput{SizeID: &large, TemperatureID: &iced, PriceAdjustment: 7000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.CreateOption(ctx, admin, latte.ID, OptionInput{SizeID: &large, TemperatureID: &iced})
+	assertStatus(t, err, http.StatusConflict)
+	unknown := 99
+	_, err = svc.CreateOption(ctx, admin, latte.ID, OptionInput{SizeID: &unknown})
+	assertStatus(t, err, http.StatusBadRequest)
+
+	d, err := svc.Detail(ctx, latte.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Options) != 2 || len(d.Sizes) != 2 || d.Sizes[1].PriceAdjustment != 7000 || len(d.Temperatures) != 2 {
+		t.Fatalf("detail options %+v sizes %+v temperatures %+v", d.Options, d.Sizes, d.Temperatures)
+	}
+	if d, _ := svc.Detail(ctx, pastry.ID, ""); len(d.Options) != 0 || len(d.Sizes) != 0 {
+		t.Fatalf("pastry offers %+v", d.Options)
+	}
+
+	_, _, err = carts.Add(ctx, repositories.CartItemKey{UserID: 5, ProductID: latte.ID, SizeID: &large, TemperatureID: &hot}, 1)
+	assertStatus(t, err, http.StatusBadRequest)
+	_, _, err = carts.Add(ctx, repositories.CartItemKey{UserID: 5, ProductID: latte.ID}, 1)
+	assertStatus(t, err, http.StatusBadRequest)
+	_, _, err = carts.Add(ctx, repositories.CartItemKey{UserID: 5, ProductID: pastry.ID, SizeID: &large}, 1)
+	assertStatus(t, err, http.StatusBadRequest)
+	if _, _, err := carts.Add(ctx, repositories.CartItemKey{UserID: 5, ProductID: latte.ID, SizeID: &large, TemperatureID: &iced}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := carts.Add(ctx, repositories.CartItemKey{UserID: 5, ProductID: pastry.ID}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	cart, err := carts.Get(ctx, 5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cart.Subtotal != (25000+7000)*2+20000 {
+		t.Fatalf("cart subtotal = %d", cart.Subtotal)
+	}
+
+	off := false
+	if _, err := svc.UpdateOption(ctx, admin, latte.ID, icedLarge.ID, OptionUpdate{IsActive: &off}); err != nil {
+		t.Fatal(err)
+	}
+	cart, _ = carts.Get(ctx, 5, "")
+	if cart.Subtotal != 20000 || cart.Items[0].Available == cart.Items[1].Available {
+		t.Fatalf("cart after deactivating = %+v", cart)
+	}
+	_, err = NewOrderService(store, nil, cache.Noop{}).Checkout(ctx, 5, CheckoutInput{
+		Email: "e@example.com", FullName: "Eka", Address: "Jl. Kopi 5", DeliveryMethod: "pick_up",
+	})
+	assertStatus(t, err, http.StatusBadRequest)
+
+	assertStatus(t, svc.DeleteOption(ctx, admin, pastry.ID, icedLarge.ID), http.StatusNotFound)
+	if err := svc.DeleteOption(ctx, admin, latte.ID, icedLarge.ID); err != nil {
+		t.Fatal(err)
+	}
+	options, _ := svc.Options(ctx, latte.ID)
+	if len(options) != 1 {
+		t.Fatalf("options after delete = %+v", options)
+	}
+}
+
+func TestOrderServiceCheckout(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	p := seedProduct(t, store, models.Product{Name: "Cappuccino", CategoryID: 1, Price: 25000, Stock: 4, IsActive: true})
+	userID, err := store.Users().Create(ctx, repositories.NewUser{Email: "budi@example.com", Role: "customer", FullName: "Budi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users().Update(ctx, userID, repositories.UserUpdate{FullName: "Budi", Address: "Jl. Kopi 1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	size := 100
+	store.AddSize(models.ProductSizeV2{ID: size, Name: "Large"})
+	if err := store.Products().CreateOption(ctx, &models.ProductOption{ProductID: p.ID, SizeID: &size, PriceAdjustment: 5000, IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := NewCartService(store).Add(ctx, repositories.CartItemKey{UserID: userID, ProductID: p.ID, SizeID: &size}, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewOrderService(store, nil, cache.Noop{})
+	result, err := svc.Checkout(ctx, userID, CheckoutInput{DeliveryMethod: "door_delivery"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Subtotal != 60000 || result.DeliveryFee != doorDeliveryFee || result.Total != 70000 {
+		t.Fatalf("unexpected totals %+v", result)
+	}
+	if result.Email != "budi@example.com" || result.Address != "Jl. Kopi 1" {
+		t.Fatalf("contact not filled from profile: %+v", result)
+	}
+	if order, err := svc.Get(ctx, result.ID); err != nil || order.CustomerName != "Budi" || order.DeliveryAddress != "Jl. Kopi 1" ||
+		order.OrderNumber != result.OrderNumber {
+		t.Fatalf("stored order = %+v, %v", order, err)
+	}
+
+	stored, _ := store.Products().Get(ctx, p.ID)
+	if stored.Stock != 2 {
+		t.Fatalf("stock = %d, want 2", stored.Stock)
+	}
+
+	_, err = svc.Checkout(ctx, userID, CheckoutInput{})
+	assertStatus(t, err, http.StatusBadRequest)
+}
+
+func TestOrderServiceCheckoutSumsOptionLines(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	p := seedProduct(t, store, models.Product{Name: "Latte", CategoryID: 1, Price: 28000, Stock: 3, IsActive: true})
+	hot, iced := 200, 201
+	store.AddTemperature(models.ProductTemperatureV2{ID: hot, Name: "Hot"})
+	store.AddTemperature(models.ProductTemperatureV2{ID: iced, Name: "Iced"})
+	for _, temperature := range []*int{&hot, &iced} {
+		if err := store.Products().CreateOption(ctx, &models.ProductOption{ProductID: p.ID, TemperatureID: temperature, IsActive: true}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Carts().AddItem(ctx, repositories.CartItemKey{UserID: 6, ProductID: p.ID, TemperatureID: temperature}, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := NewOrderService(store, nil, cache.Noop{}).Checkout(ctx, 6, CheckoutInput{
+		Email: "e@example.com", FullName: "Eka", Address: "Jl. Gula 4", DeliveryMethod: "pick_up",
+	})
+	assertStatus(t, err, http.StatusBadRequest)
+
+	stored, _ := store.Products().Get(ctx, p.ID)
+	if stored.Stock != 3 {
+		t.Fatalf("stock = %d, want 3", stored.Stock)
+	}
+	if _, err := store.Products().AdjustStock(ctx, p.ID, -4); !errors.Is(err, repositories.ErrInsufficientStock) {
+		t.Fatalf("AdjustStock below zero = %v, want ErrInsufficientStock", err)
+	}
+}
+
+func TestOrderServiceCheckoutRollsBack(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	p := seedProduct(t, store, models.Product{Name: "Espresso", CategoryID: 1, Price: 18000, Stock: 2, IsActive: true})
+	if _, err := store.Carts().AddItem(ctx, repositories.CartItemKey{UserID: 3, ProductID: p.ID}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewOrderService(store, nil, cache.Noop{}).Checkout(ctx, 3, CheckoutInput{
+		Email: "c@example.com", FullName: "Citra", Address: "Jl. Teh 2", DeliveryMethod: "teleport",
+	})
+	assertStatus(t, err, http.StatusBadRequest)
+
+	items, _ := store.Carts().Items(ctx, 3, "")
+	if len(items) != 1 {
+		t.Fatal("a failed checkout must leave the cart intact")
+	}
+}
+
+func TestOrderServiceUpdateStatus(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	p := seedProduct(t, store, models.Product{Name: "Mocha", CategoryID: 1, Price: 30000, Stock: 3, IsActive: true})
+	if _, err := store.Carts().AddItem(ctx, repositories.CartItemKey{UserID: 4, ProductID: p.ID}, 1); err != nil {
+		t.Fatal(err)
+	}
+	svc := NewOrderService(store, nil, cache.Noop{})
+	result, err := svc.Checkout(ctx, 4, CheckoutInput{
+		Email: "d@example.com", FullName: "Dewi", Address: "Jl. Susu 3", DeliveryMethod: "pick_up",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertStatus(t, svc.UpdateStatus(ctx, admin, result.ID, "shipped"), http.StatusBadRequest)
+
+	if err := svc.UpdateStatus(ctx, admin, result.ID, "completed"); err != nil {
+		t.Fatal(err)
+	}
+	order, err := svc.Get(ctx, result.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "completed" {
+		t.Fatalf("status = %q, want completed", order.Status)
+	}
+}
+
+type fakeAlerts struct {
+	low []models.Product
+}
+
+func (f *fakeAlerts) LowStock(_ context.Context, products []models.Product) {
+	f.low = append(f.low, products...)
+}
+
+func TestInventoryLedgerTracksEveryStockChange(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	svc := NewProductService(store, &fakeImages{}, cache.Noop{})
+	alerts := &fakeAlerts{}
+	orders := NewOrderService(store, alerts, cache.Noop{})
+
+	p, err := svc.Create(ctx, admin, ProductInput{Name: "Flat White", CategoryID: 1, Price: 28000, Stock: 5, LowStockThreshold: 4}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.Restock(ctx, admin, p.ID, 0, "")
+	assertStatus(t, err, http.StatusBadRequest)
+	if m, err := svc.Restock(ctx, admin, p.ID, 3, "Supplier delivery"); err != nil || m.StockAfter != 8 {
+		t.Fatalf("restock = %+v, %v", m, err)
+	}
+	_, err = svc.Waste(ctx, admin, p.ID, 1, " ")
+	assertStatus(t, err, http.StatusBadRequest)
+	_, err = svc.Waste(ctx, admin, p.ID, 9, "Spilled")
+	assertStatus(t, err, http.StatusBadRequest)
+	if m, err := svc.Waste(ctx, admin, p.ID, 1, "Spilled"); err != nil || m.Quantity != -1 || m.StockAfter != 7 {
+		t.Fatalf("waste = %+v, %v", m, err)
+	}
+	if m, err := svc.Stocktake(ctx, admin, p.ID, 7, ""); err != nil || m.ID != 0 {
+		t.Fatalf("matching stocktake = %+v, %v", m, err)
+	}
+	if m, err := svc.Stocktake(ctx, admin, p.ID, 6, ""); err != nil || m.Quantity != -1 || m.Reason != "Stocktake" {
+		t.Fatalf("stocktake = %+v, %v", m, err)
+	}
+
+	if _, err := store.Carts().AddItem(ctx, repositories.CartItemKey{UserID: 7, ProductID: p.ID}, 3); err != nil {
+		t.Fatal(err)
+	}
+	result, err := orders.Checkout(ctx, 7, CheckoutInput{
+		Email: "g@example.com", FullName: "Gita", Address: "Jl. Gula 7", DeliveryMethod: "pick_up",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts.low) != 1 || alerts.low[0].ID != p.ID || alerts.low[0].Stock != 3 {
+		t.Fatalf("low stock alerts = %+v", alerts.low)
+	}
+	if low, _ := svc.LowStock(ctx); len(low) != 1 || low[0].ID != p.ID {
+		t.Fatalf("low stock report = %+v", low)
+	}
+
+	if err := orders.UpdateStatus(ctx, admin, result.ID, "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, orders.UpdateStatus(ctx, admin, result.ID, "pending"), http.StatusConflict)
+	if stored, _ := store.Products().Get(ctx, p.ID); stored.Stock != 6 {
+		t.Fatalf("stock after cancellation = %d, want 6", stored.Stock)
+	}
+
+	stock := 10
+	if _, err := svc.Update(ctx, admin, p.ID, ProductPatch{Stock: &stock}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	movements, total, err := svc.StockMovements(ctx, p.ID, pagination.Params{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := []string{}
+	for _, m := range movements {
+		types = append(types, m.Type)
+	}
+	want := []string{models.StockMovementAdjustment, models.StockMovementCancellation, models.StockMovementSale,
+		models.StockMovementAdjustment, models.StockMovementWaste, models.StockMovementRestock, models.StockMovementAdjustment}
+	if total != len(want) || !slices.Equal(types, want) {
+		t.Fatalf("ledger = %v (total %d), want %v", types, total, want)
+	}
+	if sale := movements[2]; sale.OrderID == nil || *sale.OrderID != result.ID || sale.StockAfter != 3 {
+		t.Fatalf("sale movement = %+v", sale)
+	}
+	if movements[0].Quantity != 4 || movements[0].StockAfter != 10 {
+		t.Fatalf("update movement = %+v", movements[0])
+	}
+}
+
+func TestPriceHistoryAndScheduledChanges(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	responses := cache.NewMemory()
+	svc := NewProductService(store, &fakeImages{}, responses)
+
+	p, err := svc.Create(ctx, admin, ProductInput{Name: "Latte", CategoryID: 1, Price: 25000}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	price := 27000
+	if _, err := svc.Update(ctx, admin, p.ID, ProductPatch{Price: &price}, nil); err != nil {
+		t.Fatal(err)
+	}
+	beforeIncrease := time.Now()
+
+	effective := time.Now().Add(time.Hour)
+	_, err = svc.SchedulePrice(ctx, admin, p.ID, 500, effective, "")
+	assertStatus(t, err, http.StatusBadRequest)
+	_, err = svc.SchedulePrice(ctx, admin, p.ID, 30000, time.Now().Add(-time.Minute), "")
+	assertStatus(t, err, http.StatusBadRequest)
+	change, err := svc.SchedulePrice(ctx, admin, p.ID, 30000, effective, " Supplier increase ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.AppliedAt != nil || change.Reason != "Supplier increase" {
+		t.Fatalf("scheduled change = %+v", change)
+	}
+	_, err = svc.SchedulePrice(ctx, admin, p.ID, 31000, effective, "")
+	assertStatus(t, err, http.StatusConflict)
+	later, err := svc.SchedulePrice(ctx, admin, p.ID, 32000, effective.Add(time.Hour), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scheduled, _ := svc.ScheduledPrices(ctx, 0); len(scheduled) != 2 || scheduled[0].ID != change.ID {
+		t.Fatalf("scheduled = %+v", scheduled)
+	}
+	if err := svc.CancelScheduledPrice(ctx, admin, p.ID, later.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, svc.CancelScheduledPrice(ctx, admin, p.ID, later.ID), http.StatusNotFound)
+
+	if n, err := svc.ApplyScheduledPrices(ctx, time.Now()); err != nil || n != 0 {
+		t.Fatalf("early apply = %d, %v", n, err)
+	}
+	productCache := cache.NewNamespace(responses, ProductCacheNamespace)
+	productCache.Set(ctx, "list_en_p1_l10", "{}", 0)
+	if n, err := svc.ApplyScheduledPrices(ctx, effective.Add(time.Minute)); err != nil || n != 1 {
+		t.Fatalf("apply = %d, %v", n, err)
+	}
+	if _, ok := productCache.Get(ctx, "list_en_p1_l10"); ok {
+		t.Fatal("applying a price change must invalidate the product cache")
+	}
+	if stored, _ := store.Products().Get(ctx, p.ID); stored.Price != 30000 {
+		t.Fatalf("price after apply = %d, want 30000", stored.Price)
+	}
+	if scheduled, _ := svc.ScheduledPrices(ctx, p.ID); len(scheduled) != 0 {
+		t.Fatalf("scheduled after apply = %+v", scheduled)
+	}
+	assertStatus(t, svc.CancelScheduledPrice(ctx, admin, p.ID, change.ID), http.StatusNotFound)
+
+	history, total, err := svc.PriceHistory(ctx, p.ID, pagination.Params{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prices := []int{}
+	for _, h := range history {
+		prices = append(prices, h.Price)
+	}
+	if total != 3 || !slices.Equal(prices, []int{30000, 27000, 25000}) {
+		t.Fatalf("history = %v (total %d)", prices, total)
+	}
+	if history[0].PreviousPrice == nil || *history[0].PreviousPrice != 27000 || history[2].PreviousPrice != nil {
+		t.Fatalf("previous prices = %+v", history)
+	}
+	if at, err := svc.PriceAt(ctx, p.ID, beforeIncrease); err != nil || at.Price != 27000 {
+		t.Fatalf("price at = %+v, %v", at, err)
+	}
+	_, err = svc.PriceAt(ctx, p.ID, beforeIncrease.AddDate(-1, 0, 0))
+	assertStatus(t, err, http.StatusNotFound)
+
+	entries := store.AuditEntries()
+	if last := entries[len(entries)-1]; last.EntityType != models.AuditEntityProduct || last.ActorEmail != "scheduler" {
+		t.Fatalf("last audit entry = %+v", last)
+	}
+}
+
+func TestScheduledPricesSkipArchivedProducts(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	svc := NewProductService(store, &fakeImages{}, cache.Noop{})
+
+	p, err := svc.Create(ctx, admin, ProductInput{Name: "Mocha", CategoryID: 1, Price: 30000}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	effective := time.Now().Add(time.Hour)
+	if _, err := svc.SchedulePrice(ctx, admin, p.ID, 32000, effective, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Delete(ctx, admin, p.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := svc.ApplyScheduledPrices(ctx, effective.Add(time.Minute)); err != nil || n != 0 {
+		t.Fatalf("apply while archived = %d, %v", n, err)
+	}
+	if _, err := svc.Restore(ctx, admin, p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := svc.ApplyScheduledPrices(ctx, effective.Add(time.Minute)); err != nil || n != 1 {
+		t.Fatalf("apply after restore = %d, %v", n, err)
+	}
+}
+
+func TestProductImportPreviewsThenApplies(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	svc := NewProductService(store, &fakeImages{}, cache.Noop{})
+	coffee := models.Category{Name: "Coffee"}
+	if err := store.Categories().Create(ctx, &coffee); err != nil {
+		t.Fatal(err)
+	}
+	latte, err := svc.Create(ctx, admin, ProductInput{SKU: "LAT-1", Name: "Latte", CategoryID: coffee.ID, Price: 28000, Stock: 5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mocha, err := svc.Create(ctx, admin, ProductInput{Name: "Mocha", CategoryID: coffee.ID, Price: 30000, Stock: 5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.Create(ctx, admin, ProductInput{SKU: "lat-1", Name: "Latte Copy", CategoryID: coffee.ID, Price: 28000}, nil)
+	assertStatus(t, err, http.StatusBadRequest)
+
+	sheet := [][]string{
+		{"Name", "SKU", "price", "stock", "category", "is_favorite", "notes"},
+		{"Latte", "LAT-1", "32000", "", "", "", "ignored"},
+		{"mocha", "MOC-1", "", "2", "", "true"},
+		{"Cold Brew", "", "25000", "", "coffee"},
+		{"Tea", "", "500", "", "Tea"},
+		{"", "", "", ""},
+		{"Latte", "", "33000"},
+	}
+
+	report, err := svc.Import(ctx, admin, sheet, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := []string{}
+	for _, row := range report.Rows {
+		actions = append(actions, row.Action)
+	}
+	want := []string{models.ImportActionUpdate, models.ImportActionUpdate, models.ImportActionCreate,
+		models.ImportActionError, models.ImportActionError}
+	if !slices.Equal(actions, want) || report.Applied {
+		t.Fatalf("dry run actions = %v (applied %v), want %v", actions, report.Applied, want)
+	}
+	if row := report.Rows[0]; row.ProductID != latte.ID || len(row.Changes) != 1 || row.Changes[0] != (models.ProductImportChange{Field: "price", From: "28000", To: "32000"}) {
+		t.Fatalf("latte row = %+v", row)
+	}
+	if row := report.Rows[4]; row.Row != 7 || len(row.Errors) != 1 || row.Errors[0].Message != "Row %d already imports this product" {
+		t.Fatalf("duplicate row = %+v", row)
+	}
+
+	report, err = svc.Import(ctx, admin, sheet, true)
+	if err != nil || report.Applied {
+		t.Fatalf("apply with errors = %v, %v", report.Applied, err)
+	}
+	if stored, _ := store.Products().Get(ctx, latte.ID); stored.Price != 28000 {
+		t.Fatalf("import with errors wrote price %d", stored.Price)
+	}
+
+	report, err = svc.Import(ctx, admin, sheet[:4], true)
+	if err != nil || !report.Applied {
+		t.Fatalf("apply = %v, %v", report.Applied, err)
+	}
+	stored, _ := store.Products().Get(ctx, mocha.ID)
+	if stored.SKU != "MOC-1" || stored.Stock != 2 || !stored.IsFeatured || stored.Price != 30000 {
+		t.Fatalf("mocha after import = %+v", stored)
+	}
+	movements, _, _ := svc.StockMovements(ctx, mocha.ID, pagination.Params{Limit: 10})
+	if len(movements) != 2 || movements[0].Quantity != -3 || movements[0].Reason != "Product import" {
+		t.Fatalf("mocha ledger = %+v", movements)
+	}
+	created := report.Rows[2]
+	if brew, err := store.Products().Get(ctx, created.ProductID); err != nil || brew.Name != "Cold Brew" || brew.LowStockThreshold != DefaultLowStockThreshold {
+		t.Fatalf("created product = %+v, %v", brew, err)
+	}
+
+	rows, err := svc.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || !slices.Equal(rows[0], ProductSheetColumns) || rows[3][1] != "Cold Brew" || rows[3][3] != "Coffee" {
+		t.Fatalf("export = %v", rows)
+	}
+	report, err = svc.Import(ctx, admin, rows, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range report.Rows {
+		if row.Action != models.ImportActionUnchanged {
+			t.Fatalf("re-importing the export changes row %+v", row)
+		}
+	}
+}
+
+func TestFlashSalePricesCartAndCheckout(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	latte := seedProduct(t, store, models.Product{Name: "Latte", CategoryID: 1, Price: 20000, Stock: 10, IsActive: true})
+	mocha := seedProduct(t, store, models.Product{Name: "Mocha", CategoryID: 1, Price: 30000, Stock: 10, IsActive: true})
+	userID, err := store.Users().Create(ctx, repositories.NewUser{Email: "sari@example.com", Role: "customer", FullName: "Sari"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewFlashSaleService(store, cache.NewMemory())
+	now := time.Now()
+	price, percent, limit := 15000, 10, 2
+	in := FlashSaleInput{Name: "Payday", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), IsActive: true,
+		Items: []models.FlashSaleItem{{ProductID: latte.ID, SalePrice: &price, DiscountPercent: &percent}}}
+	_, err = svc.Create(ctx, admin, in)
+	assertStatus(t, err, http.StatusBadRequest)
+
+	in.Items = []models.FlashSaleItem{
+		{ProductID: latte.ID, SalePrice: &price, MaxPerCustomer: &limit},
+		{ProductID: mocha.ID, DiscountPercent: &percent},
+	}
+	sale, err := svc.Create(ctx, admin, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlapping := in
+	overlapping.Items = in.Items[:1]
+	_, err = svc.Create(ctx, admin, overlapping)
+	assertStatus(t, err, http.StatusConflict)
+
+	products, _, err := NewProductService(store, &fakeImages{}, cache.NewMemory()).List(ctx, repositories.ProductFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range products {
+		want := map[int]int{latte.ID: 15000, mocha.ID: 27000}[p.ID]
+		if p.FlashSale == nil || p.FlashSale.Price != want || p.Price == want || !p.IsFlashSale {
+			t.Fatalf("product %d not priced at %d: %+v", p.ID, want, p.FlashSale)
+		}
+	}
+
+	carts := NewCartService(store)
+	_, _, err = carts.Add(ctx, repositories.CartItemKey{UserID: userID, ProductID: latte.ID}, 3)
+	assertStatus(t, err, http.StatusBadRequest)
+	if _, _, err := carts.Add(ctx, repositories.CartItemKey{UserID: userID, ProductID: latte.ID}, 2); err != nil {
+		t.Fatal(err)
+	}
+	cart, err := carts.Get(ctx, userID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item := cart.Items[0]; item.BasePrice != 15000 || item.RegularPrice != 20000 || cart.Subtotal != 30000 {
+		t.Fatalf("cart not at the sale price: %+v", cart)
+	}
+
+	result, err := NewOrderService(store, nil, cache.Noop{}).Checkout(ctx, userID, CheckoutInput{Address: "Jl. Kopi 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Subtotal != 30000 {
+		t.Fatalf("subtotal = %d, want 30000", result.Subtotal)
+	}
+	items, _ := store.Orders().Items(ctx, result.ID)
+	if len(items) != 1 || !items[0].IsFlashSale || items[0].FlashSaleID == nil || *items[0].FlashSaleID != sale.ID {
+		t.Fatalf("order item not linked to the flash sale: %+v", items)
+	}
+
+	_, _, err = carts.Add(ctx, repositories.CartItemKey{UserID: userID, ProductID: latte.ID}, 1)
+	assertStatus(t, err, http.StatusBadRequest)
+
+	active, _, err := svc.Active(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 1 || active[0].EndsInSeconds <= 0 || len(active[0].Items) != 2 {
+		t.Fatalf("unexpected active flash sales %+v", active)
+	}
+}
+
+func TestApplyPromotions(t *testing.T) {
+	latte, mocha, cookie := 1, 2, 3
+	drinks := 10
+	bogo := models.PromotionRule{ID: 7, Name: "Latte B1G1", Type: models.PromotionBuyXGetY, ProductID: &latte,
+		BuyQuantity: 1, GetQuantity: 1, DiscountPercent: 100}
+	buyTwoHalf := models.PromotionRule{ID: 8, Name: "Mocha 2+1", Type: models.PromotionBuyXGetY, ProductID: &mocha,
+		BuyQuantity: 2, GetQuantity: 1, DiscountPercent: 50}
+	mix := models.PromotionRule{ID: 9, Name: "Any 3 drinks", Type: models.PromotionMixAndMatch, CategoryID: &drinks,
+		BuyQuantity: 2, GetQuantity: 1, DiscountPercent: 100}
+
+	tests := []struct {
+		name  string
+		lines []promotionLine
+		rules []models.PromotionRule
+		want  []int
+	}{
+		{"bogo pairs units", []promotionLine{{ProductID: latte, UnitPrice: 20000, Quantity: 3}},
+			[]models.PromotionRule{bogo}, []int{20000}},
+		{"cheapest unit of each group is discounted", []promotionLine{
+			{ProductID: mocha, UnitPrice: 30000, Quantity: 2}, {ProductID: mocha, UnitPrice: 24000, Quantity: 1}},
+			[]models.PromotionRule{buyTwoHalf}, []int{0, 12000}},
+		{"mix and match across a category", []promotionLine{
+			{ProductID: latte, CategoryID: drinks, UnitPrice: 20000, Quantity: 1},
+			{ProductID: mocha, CategoryID: drinks, UnitPrice: 30000, Quantity: 1},
+			{ProductID: cookie, CategoryID: drinks, UnitPrice: 15000, Quantity: 1}},
+			[]models.PromotionRule{mix}, []int{0, 0, 15000}},
+		{"product rules claim their lines first", []promotionLine{
+			{ProductID: latte, CategoryID: drinks, UnitPrice: 20000, Quantity: 2},
+			{ProductID: mocha, CategoryID: drinks, UnitPrice: 30000, Quantity: 3}},
+			[]models.PromotionRule{mix, bogo}, []int{20000, 30000}},
+		{"flag acts as buy 1 get 1", []promotionLine{{ProductID: cookie, IsBuy1Get1: true, UnitPrice: 15000, Quantity: 2}},
+			nil, []int{15000}},
+		{"excluded lines take no promotion", []promotionLine{{ProductID: latte, UnitPrice: 20000, Quantity: 2, Excluded: true}},
+			[]models.PromotionRule{bogo}, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyPromotions(tt.lines, tt.rules)
+			for i, want := range tt.want {
+				amount := 0
+				if got[i] != nil {
+					amount = got[i].Amount
+				}
+				if amount != want {
+					t.Fatalf("line %d discount = %d, want %d", i, amount, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPromotionsDiscountCartAndCheckout(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	drinks := models.Category{Name: "Drinks"}
+	if err := store.Categories().Create(ctx, &drinks); err != nil {
+		t.Fatal(err)
+	}
+	latte := seedProduct(t, store, models.Product{Name: "Latte", CategoryID: drinks.ID, Price: 20000, Stock: 10, IsActive: true})
+	mocha := seedProduct(t, store, models.Product{Name: "Mocha", CategoryID: drinks.ID, Price: 30000, Stock: 10, IsActive: true})
+	userID, err := store.Users().Create(ctx, repositories.NewUser{Email: "dewi@example.com", Role: "customer", FullName: "Dewi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewPromotionService(store)
+	_, err = svc.Create(ctx, admin, PromotionInput{Name: "Any 3", Type: models.PromotionMixAndMatch, ProductID: &latte.ID,
+		CategoryID: &drinks.ID, BuyQuantity: 2, GetQuantity: 1, DiscountPercent: 100, IsActive: true})
+	assertStatus(t, err, http.StatusBadRequest)
+	rule, err := svc.Create(ctx, admin, PromotionInput{Name: "Any 3", Type: models.PromotionMixAndMatch,
+		CategoryID: &drinks.ID, BuyQuantity: 2, GetQuantity: 1, DiscountPercent: 100, IsActive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	carts := NewCartService(store)
+	for productID, quantity := range map[int]int{latte.ID: 1, mocha.ID: 2} {
+		if _, _, err := carts.Add(ctx, repositories.CartItemKey{UserID: userID, ProductID: productID}, quantity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cart, err := carts.Get(ctx, userID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cart.Subtotal != 80000 || cart.Discount != 20000 || cart.Total != 60000 || len(cart.Adjustments) != 1 {
+		t.Fatalf("unexpected cart %+v", cart)
+	}
+	if adj := cart.Adjustments[0]; adj.PromotionRuleID == nil || *adj.PromotionRuleID != rule.ID || adj.Quantity != 1 {
+		t.Fatalf("unexpected adjustment %+v", adj)
+	}
+
+	result, err := NewOrderService(store, nil, cache.Noop{}).Checkout(ctx, userID, CheckoutInput{Address: "Jl. Kopi 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Subtotal != 80000 || result.Discount != 20000 || result.Total != 60000 {
+		t.Fatalf("unexpected totals %+v", result)
+	}
+	items, _ := store.Orders().Items(ctx, result.ID)
+	discounted := 0
+	for _, item := range items {
+		if item.DiscountAmount > 0 {
+			discounted++
+			if item.ProductID != latte.ID || item.PromotionName != "Any 3" {
+				t.Fatalf("discount on the wrong line %+v", item)
+			}
+		}
+	}
+	if discounted != 1 {
+		t.Fatalf("%d discounted order items, want 1", discounted)
+	}
+}
+
+func TestPromoServiceListsNewestFirst(t *testing.T) {
+	store := memory.NewStore()
+	store.AddPromo(&models.Promo{Title: "Happy hour", Code: "HAPPY", BgColor: "#F5F5DC", TextColor: "#000000"})
+	store.AddPromo(&models.Promo{Title: "Weekend", Code: "WKND", BgColor: "#C7A27C", TextColor: "#FFFFFF"})
+
+	promos, err := NewPromoService(store).Active(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(promos) != 2 || promos[0].Code != "WKND" || promos[1].BgColor != "#F5F5DC" {
+		t.Fatalf("unexpected promos %+v", promos)
+	}
+}
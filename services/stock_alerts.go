@@ -0,0 +1,45 @@
+package services
+
+import (
+	"coffee-shop/models"
+	"context"
+	"log"
+)
+
+// StockAlerter is told about the products a checkout took below their
+// low-stock threshold, once the order is committed. It must not block the
+// checkout.
+type StockAlerter interface {
+	LowStock(ctx context.Context, products []models.Product)
+}
+
+// NewEmailStockAlerter returns a StockAlerter that logs every alert and, when
+// to is set, mails it there in the background. Mail is skipped with a log
+// line while SMTP is not configured.
+func NewEmailStockAlerter(to string) StockAlerter {
+	return emailStockAlerter{to: to}
+}
+
+type emailStockAlerter struct {
+	to string
+}
+
+func (a emailStockAlerter) LowStock(_ context.Context, products []models.Product) {
+	for _, p := range products {
+		log.Printf("Low stock: %s (#%d) has %d left, threshold %d", p.Name, p.ID, p.Stock, p.LowStockThreshold)
+	}
+	if a.to == "" {
+		return
+	}
+
+	go func() {
+		emailService, err := models.NewEmailService()
+		if err != nil {
+			log.Printf("Low stock email not sent: %v", err)
+			return
+		}
+		if err := emailService.SendLowStockEmail(a.to, products); err != nil {
+			log.Printf("Low stock email not sent: %v", err)
+		}
+	}()
+}
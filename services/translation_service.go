@@ -0,0 +1,163 @@
+package services
+
+import (
+	"coffee-shop/cache"
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"strings"
+)
+
+// TranslationService manages the per-locale product and category names
+// admins enter.
+type TranslationService struct {
+	store repositories.Store
+	cache cache.Store
+}
+
+func NewTranslationService(store repositories.Store, cache cache.Store) *TranslationService {
+	return &TranslationService{store: store, cache: cache}
+}
+
+type translationAuditSnapshot struct {
+	Locale      string `json:"locale"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+func (s *TranslationService) ProductTranslations(ctx context.Context, productID int) ([]models.ProductTranslation, error) {
+	translations, err := s.store.Translations().ProductTranslations(ctx, productID)
+	if err != nil {
+		return nil, fail("Failed to retrieve translations", err)
+	}
+	return translations, nil
+}
+
+// SaveProductTranslation creates or replaces t. Either field may be empty to
+// fall back to the product's own.
+func (s *TranslationService) SaveProductTranslation(ctx context.Context, actor Actor, t models.ProductTranslation) error {
+	t.Name = strings.TrimSpace(t.Name)
+	t.Description = strings.TrimSpace(t.Description)
+	if t.Name == "" && t.Description == "" {
+		return invalid("Name or description is required")
+	}
+	if _, err := s.store.Products().Get(ctx, t.ProductID); err != nil {
+		return notFound("Product not found")
+	}
+
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		action, before := models.AuditActionCreate, interface{}(nil)
+		existing, err := tx.Translations().ProductTranslation(ctx, t.ProductID, t.Locale)
+		switch {
+		case err == nil:
+			action = models.AuditActionUpdate
+			before = translationAuditSnapshot{Locale: t.Locale, Name: existing.Name, Description: existing.Description}
+		case !errors.Is(err, repositories.ErrNotFound):
+			return err
+		}
+
+		if err := tx.Translations().SaveProductTranslation(ctx, t); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(action, models.AuditEntityProductTranslation, t.ProductID,
+			before, translationAuditSnapshot{Locale: t.Locale, Name: t.Name, Description: t.Description}))
+	})
+	if err != nil {
+		return fail("Failed to save translation", err)
+	}
+	cache.NewNamespace(s.cache, ProductCacheNamespace).Invalidate(ctx)
+	return nil
+}
+
+func (s *TranslationService) DeleteProductTranslation(ctx context.Context, actor Actor, productID int, locale string) error {
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		existing, err := tx.Translations().ProductTranslation(ctx, productID, locale)
+		if err != nil {
+			return err
+		}
+		if err := tx.Translations().DeleteProductTranslation(ctx, productID, locale); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionDelete, models.AuditEntityProductTranslation, productID,
+			translationAuditSnapshot{Locale: locale, Name: existing.Name, Description: existing.Description}, nil))
+	})
+	if errors.Is(err, repositories.ErrNotFound) {
+		return notFound("Translation not found")
+	}
+	if err != nil {
+		return fail("Failed to delete translation", err)
+	}
+	cache.NewNamespace(s.cache, ProductCacheNamespace).Invalidate(ctx)
+	return nil
+}
+
+func (s *TranslationService) CategoryTranslations(ctx context.Context, categoryID int) ([]models.CategoryTranslation, error) {
+	translations, err := s.store.Translations().CategoryTranslations(ctx, categoryID)
+	if err != nil {
+		return nil, fail("Failed to retrieve translations", err)
+	}
+	return translations, nil
+}
+
+func (s *TranslationService) SaveCategoryTranslation(ctx context.Context, actor Actor, t models.CategoryTranslation) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return invalid("Name is required")
+	}
+	if _, err := s.store.Categories().Get(ctx, t.CategoryID, ""); err != nil {
+		return notFound("Category not found")
+	}
+
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		action, before := models.AuditActionCreate, interface{}(nil)
+		existing, err := tx.Translations().CategoryTranslation(ctx, t.CategoryID, t.Locale)
+		switch {
+		case err == nil:
+			action = models.AuditActionUpdate
+			before = translationAuditSnapshot{Locale: t.Locale, Name: existing.Name}
+		case !errors.Is(err, repositories.ErrNotFound):
+			return err
+		}
+
+		if err := tx.Translations().SaveCategoryTranslation(ctx, t); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(action, models.AuditEntityCategoryTranslation, t.CategoryID,
+			before, translationAuditSnapshot{Locale: t.Locale, Name: t.Name}))
+	})
+	if err != nil {
+		return fail("Failed to save translation", err)
+	}
+	s.invalidateCategories(ctx)
+	return nil
+}
+
+func (s *TranslationService) DeleteCategoryTranslation(ctx context.Context, actor Actor, categoryID int, locale string) error {
+	err := s.store.WithTx(ctx, func(tx repositories.Store) error {
+		existing, err := tx.Translations().CategoryTranslation(ctx, categoryID, locale)
+		if err != nil {
+			return err
+		}
+		if err := tx.Translations().DeleteCategoryTranslation(ctx, categoryID, locale); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionDelete, models.AuditEntityCategoryTranslation, categoryID,
+			translationAuditSnapshot{Locale: locale, Name: existing.Name}, nil))
+	})
+	if errors.Is(err, repositories.ErrNotFound) {
+		return notFound("Translation not found")
+	}
+	if err != nil {
+		return fail("Failed to delete translation", err)
+	}
+	s.invalidateCategories(ctx)
+	return nil
+}
+
+// invalidateCategories drops the cached categories and products: product
+// responses embed category names.
+func (s *TranslationService) invalidateCategories(ctx context.Context) {
+	cache.NewNamespace(s.cache, CategoryCacheNamespace).Invalidate(ctx)
+	cache.NewNamespace(s.cache, ProductCacheNamespace).Invalidate(ctx)
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"coffee-shop/i18n"
+	"coffee-shop/libs"
+	"coffee-shop/models"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// profilePhonePattern is the phone format users may give themselves, when
+// registering or editing their profile.
+var profilePhonePattern = regexp.MustCompile(`^[0-9+\-]{8,20}$`)
+
+// languageCacheTTL bounds how long a user's saved language is cached between
+// profile reads. UpdateProfile replaces it as soon as the profile changes.
+const languageCacheTTL = 5 * time.Minute
+
+func languageCacheKey(userID int) string {
+	return fmt.Sprintf("user_language:%d", userID)
+}
+
+// Profile is a user's account as they see it themselves.
+type Profile struct {
+	models.UserWithProfile
+	Language string
+}
+
+// Profile returns the signed-in user's own account.
+func (s *UserService) Profile(ctx context.Context, userID int) (Profile, error) {
+	u, err := s.store.Users().Get(ctx, userID)
+	if err != nil {
+		return Profile{}, notFound("Profile not found")
+	}
+	language, err := s.store.Users().Language(ctx, userID)
+	if err != nil {
+		return Profile{}, fail("Failed to retrieve profile", err)
+	}
+	return Profile{UserWithProfile: u, Language: language}, nil
+}
+
+// NormalizeProfile trims u and maps its language to a supported locale, or
+// explains what is wrong with it. Callers check an edit with it before
+// uploading the photo that goes with it.
+func NormalizeProfile(u repositories.ProfileUpdate) (repositories.ProfileUpdate, error) {
+	u.FullName = strings.TrimSpace(u.FullName)
+	u.Phone = strings.TrimSpace(u.Phone)
+	u.Address = strings.TrimSpace(u.Address)
+
+	if u.FullName != "" && len(u.FullName) < 3 {
+		return u, invalid("Full name must be at least 3 characters")
+	}
+	if u.Phone != "" && !profilePhonePattern.MatchString(u.Phone) {
+		return u, invalid("Invalid phone number format")
+	}
+	if u.Language != "" {
+		language := i18n.Normalize(u.Language)
+		if language == "" {
+			return u, invalid("Unsupported language")
+		}
+		u.Language = language
+	}
+	return u, nil
+}
+
+// UpdateProfile saves the user's edit of their own profile. A new language
+// applies to their next request, whichever token it carries.
+func (s *UserService) UpdateProfile(ctx context.Context, userID int, u repositories.ProfileUpdate) error {
+	u, err := NormalizeProfile(u)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Users().UpdateProfile(ctx, userID, u); err != nil {
+		return fail("Failed to update profile", err)
+	}
+	if u.Language != "" {
+		s.cache.Set(ctx, languageCacheKey(userID), u.Language, languageCacheTTL)
+	}
+	return nil
+}
+
+// ChangePassword replaces the user's password after checking the current one.
+func (s *UserService) ChangePassword(ctx context.Context, userID int, oldPassword, newPassword, confirmPassword string) error {
+	if oldPassword == "" || newPassword == "" || confirmPassword == "" {
+		return invalid("All password fields are required for password change")
+	}
+	if len(newPassword) < 6 {
+		return invalid("New password must be at least 6 characters")
+	}
+	if newPassword != confirmPassword {
+		return invalid("New password and confirm password do not match")
+	}
+	if oldPassword == newPassword {
+		return invalid("New password must be different from old password")
+	}
+
+	current, err := s.store.Users().PasswordHash(ctx, userID)
+	if err != nil {
+		return fail("Failed to verify password", err)
+	}
+	if !libs.VerifyPassword(current, oldPassword) {
+		return invalid("Invalid old password")
+	}
+
+	hash, err := libs.HashPassword(newPassword)
+	if err != nil {
+		return fail("Failed to hash password", err)
+	}
+	if err := s.store.Users().SetPassword(ctx, userID, hash); err != nil {
+		return fail("Failed to update password", err)
+	}
+	return nil
+}
+
+// PhotoID returns the image host's ID of the user's current profile photo,
+// or "" when they have none.
+func (s *UserService) PhotoID(ctx context.Context, userID int) string {
+	id, err := s.store.Users().PhotoID(ctx, userID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		log.Printf("Profile photo of user %d not read: %v", userID, err)
+	}
+	return id
+}
+
+// Language returns the language userID saved in their profile, or "" when
+// they have not chosen one or it cannot be read. The auth middleware uses it
+// instead of the token's language claim, which is only as new as the token.
+func (s *UserService) Language(ctx context.Context, userID int) string {
+	key := languageCacheKey(userID)
+	if language, ok := s.cache.Get(ctx, key); ok {
+		return language
+	}
+
+	language, err := s.store.Users().Language(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrNotFound) {
+			log.Printf("Language of user %d not read: %v", userID, err)
+		}
+		return ""
+	}
+	s.cache.Set(ctx, key, language, languageCacheTTL)
+	return language
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"coffee-shop/cache"
+	"coffee-shop/libs"
+	"coffee-shop/models"
+	"coffee-shop/pagination"
+	"coffee-shop/repositories"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+	phonePattern = regexp.MustCompile(`^[\d+\-\s()]+$`)
+)
+
+type UserService struct {
+	store repositories.Store
+	// cache keeps each user's saved language for the auth middleware.
+	cache cache.Store
+}
+
+func NewUserService(store repositories.Store, cache cache.Store) *UserService {
+	return &UserService{store: store, cache: cache}
+}
+
+// NewUserInput is an admin-created account.
+type NewUserInput struct {
+	Email    string
+	Password string
+	Role     string
+	FullName string
+	Phone    string
+}
+
+func (s *UserService) List(ctx context.Context, page pagination.Params) ([]models.UserWithProfile, int, error) {
+	users, total, err := s.store.Users().List(ctx, page)
+	if err != nil {
+		return nil, 0, fail("Failed to retrieve users", err)
+	}
+	return users, total, nil
+}
+
+func (s *UserService) Get(ctx context.Context, id int) (models.UserWithProfile, error) {
+	if id <= 0 {
+		return models.UserWithProfile{}, invalid("Invalid user ID")
+	}
+	u, err := s.store.Users().Get(ctx, id)
+	if err != nil {
+		return models.UserWithProfile{}, notFound("User not found")
+	}
+	return u, nil
+}
+
+func (s *UserService) Create(ctx context.Context, actor Actor, in NewUserInput) (models.UserWithProfile, error) {
+	in.Email = strings.TrimSpace(in.Email)
+	in.Role = strings.TrimSpace(in.Role)
+	in.FullName = strings.TrimSpace(in.FullName)
+	in.Phone = strings.TrimSpace(in.Phone)
+
+	if in.Email == "" || in.Password == "" || in.Role == "" {
+		return models.UserWithProfile{}, invalid("Email, password, and role are required")
+	}
+	if !emailPattern.MatchString(in.Email) {
+		return models.UserWithProfile{}, invalid("Invalid email format")
+	}
+	if len(in.Password) < 6 {
+		return models.UserWithProfile{}, invalid("Password must be at least 6 characters")
+	}
+	if err := validateUserFields(in.Role, in.FullName, in.Phone); err != nil {
+		return models.UserWithProfile{}, err
+	}
+
+	exists, err := s.store.Users().EmailExists(ctx, in.Email, 0)
+	if err != nil {
+		return models.UserWithProfile{}, fail("Failed to create user", err)
+	}
+	if exists {
+		return models.UserWithProfile{}, invalid("Email already exists")
+	}
+
+	hash, err := libs.HashPassword(in.Password)
+	if err != nil {
+		return models.UserWithProfile{}, fail("Failed to create user", err)
+	}
+
+	var created models.UserWithProfile
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		id, err := tx.Users().Create(ctx, repositories.NewUser{
+			Email:        in.Email,
+			PasswordHash: hash,
+			Role:         in.Role,
+			FullName:     in.FullName,
+			Phone:        in.Phone,
+		})
+		if err != nil {
+			return err
+		}
+		if created, err = tx.Users().Get(ctx, id); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionCreate, models.AuditEntityUser, id,
+			nil, newUserAuditSnapshot(created)))
+	})
+	if err != nil {
+		return models.UserWithProfile{}, fail("Failed to create user", err)
+	}
+	return created, nil
+}
+
+func (s *UserService) Update(ctx context.Context, actor Actor, id int, in repositories.UserUpdate) error {
+	if id <= 0 {
+		return invalid("Invalid user ID")
+	}
+
+	before, err := s.store.Users().Get(ctx, id)
+	if err != nil {
+		return notFound("User not found")
+	}
+
+	in.Email = strings.TrimSpace(in.Email)
+	in.Role = strings.TrimSpace(in.Role)
+	in.FullName = strings.TrimSpace(in.FullName)
+	in.Phone = strings.TrimSpace(in.Phone)
+	in.Address = strings.TrimSpace(in.Address)
+
+	if in.Email != "" {
+		if !emailPattern.MatchString(in.Email) {
+			return invalid("Invalid email format")
+		}
+		exists, err := s.store.Users().EmailExists(ctx, in.Email, id)
+		if err != nil {
+			return fail("Failed to update user", err)
+		}
+		if exists {
+			return invalid("Email already exists")
+		}
+	}
+	if err := validateUserFields(in.Role, in.FullName, in.Phone); err != nil {
+		return err
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Users().Update(ctx, id, in); err != nil {
+			return err
+		}
+		after, err := tx.Users().Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionUpdate, models.AuditEntityUser, id,
+			newUserAuditSnapshot(before), newUserAuditSnapshot(after)))
+	})
+	if err != nil {
+		return fail("Failed to update user", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password for the user with the given email.
+func (s *UserService) ResetPassword(ctx context.Context, actor Actor, email, password string) error {
+	email = strings.TrimSpace(email)
+	if len(password) < 6 {
+		return invalid("Password must be at least 6 characters")
+	}
+
+	u, err := s.store.Users().GetByEmail(ctx, email)
+	if err != nil {
+		return notFound("User not found")
+	}
+
+	hash, err := libs.HashPassword(password)
+	if err != nil {
+		return fail("Failed to update password", err)
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Users().SetPassword(ctx, u.ID, hash); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionResetPassword, models.AuditEntityUser, u.ID, nil, nil))
+	})
+	if err != nil {
+		return fail("Failed to update password", err)
+	}
+	return nil
+}
+
+// Delete removes the user and returns the profile photo path so the caller
+// can remove the file once the transaction has committed.
+func (s *UserService) Delete(ctx context.Context, actor Actor, id int) (string, error) {
+	if id <= 0 {
+		return "", invalid("Invalid user ID")
+	}
+
+	before, err := s.store.Users().Get(ctx, id)
+	if err != nil {
+		return "", notFound("User not found")
+	}
+
+	err = s.store.WithTx(ctx, func(tx repositories.Store) error {
+		if err := tx.Users().Delete(ctx, id); err != nil {
+			return err
+		}
+		return tx.Audit().Record(ctx, actor.audit(models.AuditActionDelete, models.AuditEntityUser, id,
+			newUserAuditSnapshot(before), nil))
+	})
+	if errors.Is(err, repositories.ErrNotFound) {
+		return "", notFound("User not found")
+	}
+	if err != nil {
+		return "", fail("Failed to delete user", err)
+	}
+	return before.PhotoURL, nil
+}
+
+// validateUserFields checks the optional fields shared by create and update.
+// An empty role is only valid on update, where it keeps the stored role.
+func validateUserFields(role, fullName, phone string) error {
+	if role != "" && role != "admin" && role != "customer" {
+		return invalid("Role must be 'admin' or 'customer'")
+	}
+	if fullName != "" && len(fullName) < 3 {
+		return invalid("Full name must be at least 3 characters")
+	}
+	if phone != "" && (!phonePattern.MatchString(phone) || len(phone) < 10) {
+		return invalid("Invalid phone number")
+	}
+	return nil
+}
+
+// userAuditSnapshot is the user state recorded in the audit log. The password
+// hash is deliberately left out.
+type userAuditSnapshot struct {
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	FullName string `json:"full_name"`
+	Phone    string `json:"phone"`
+	Address  string `json:"address"`
+}
+
+func newUserAuditSnapshot(u models.UserWithProfile) userAuditSnapshot {
+	return userAuditSnapshot{Email: u.Email, Role: u.Role, FullName: u.FullName, Phone: u.Phone, Address: u.Address}
+}
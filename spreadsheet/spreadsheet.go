@@ -0,0 +1,85 @@
+// Package spreadsheet reads and writes a single table of text cells as CSV
+// or as an XLSX workbook, for the product import and export.
+//
+// Only what a product sheet needs is supported: one worksheet, text and
+// number cells. Formulas are read as their cached value and styles are
+// ignored, so a file saved by Excel, LibreOffice or Google Sheets reads back
+// as the grid the user sees.
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Supported formats.
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+)
+
+// ErrUnsupportedFormat is returned for a format other than CSV or XLSX.
+var ErrUnsupportedFormat = errors.New("unsupported spreadsheet format")
+
+// ContentTypes maps each format to the MIME type it is served as.
+var ContentTypes = map[string]string{
+	CSV:  "text/csv; charset=utf-8",
+	XLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// FormatOf returns the format named by filename's extension, or "" when it is
+// neither .csv nor .xlsx.
+func FormatOf(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return CSV
+	case ".xlsx":
+		return XLSX
+	}
+	return ""
+}
+
+// Read returns the rows of the file. XLSX needs the whole file, so size is
+// the length of r.
+func Read(r io.ReaderAt, size int64, format string) ([][]string, error) {
+	switch format {
+	case CSV:
+		return readCSV(io.NewSectionReader(r, 0, size))
+	case XLSX:
+		return readXLSX(r, size)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// Write writes rows to w in format.
+func Write(w io.Writer, format string, rows [][]string) error {
+	switch format {
+	case CSV:
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	case XLSX:
+		return writeXLSX(w, rows)
+	}
+	return ErrUnsupportedFormat
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	// Excel prefixes UTF-8 CSV files with a byte order mark.
+	if len(rows) > 0 && len(rows[0]) > 0 {
+		rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+	}
+	return rows, nil
+}
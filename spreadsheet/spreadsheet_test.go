@@ -0,0 +1,83 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+var sample = [][]string{
+	{"sku", "name", "price", "note"},
+	{"007", "Caffè Latte", "25000", `<b>"quoted" & escaped</b>`},
+	{"", "Croissant", "-5", ""},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{CSV, XLSX} {
+		var buf bytes.Buffer
+		if err := Write(&buf, format, sample); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		rows, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()), format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !reflect.DeepEqual(rows, sample) {
+			t.Fatalf("%s round trip = %q", format, rows)
+		}
+	}
+}
+
+func TestReadCSVStripsByteOrderMark(t *testing.T) {
+	data := []byte("\ufeffname,price\nLatte,25000\n")
+	rows, err := Read(bytes.NewReader(data), int64(len(data)), CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0][0] != "name" {
+		t.Fatalf("header = %q", rows[0])
+	}
+}
+
+func TestReadXLSXSharedStringsAndGaps(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"xl/workbook.xml":            `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Menu" sheetId="1" r:id="rId7"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId7" Target="worksheets/menu.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":       `<sst><si><t>name</t></si><si><r><t>Iced </t></r><r><t>Tea</t></r></si></sst>`,
+		"xl/worksheets/menu.xml": `<worksheet><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="b"><v>1</v></c></row>
+			<row r="3"><c r="B3" t="s"><v>1</v></c><c r="C3"><f>1+1</f><v>2</v></c></row>
+		</sheetData></worksheet>`,
+	}
+	for name, body := range parts {
+		f, _ := zw.Create(name)
+		f.Write([]byte(body))
+	}
+	zw.Close()
+
+	rows, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()), XLSX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"name", "", "true"}, {}, {"", "Iced Tea", "2"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("rows = %q", rows)
+	}
+}
+
+func TestColumnNames(t *testing.T) {
+	for col, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(col); got != name {
+			t.Fatalf("columnName(%d) = %s, want %s", col, got, name)
+		}
+		if got, err := column(name + "12"); err != nil || got != col {
+			t.Fatalf("column(%s12) = %d, %v", name, got, err)
+		}
+	}
+	if FormatOf("menu.XLSX") != XLSX || FormatOf("menu.csv") != CSV || FormatOf("menu.xls") != "" {
+		t.Fatal("FormatOf")
+	}
+}
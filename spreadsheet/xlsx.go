@@ -0,0 +1,257 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxXLSXPart caps how much of one workbook part is decompressed, so a zip
+// bomb cannot exhaust memory.
+const maxXLSXPart = 32 << 20
+
+var errNoWorksheet = errors.New("xlsx: workbook has no worksheet")
+
+func readXLSX(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("xlsx: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheet(files)
+	if err != nil {
+		return nil, err
+	}
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []stringItem `xml:"si"`
+		}
+		if err := decodePart(f, &sst); err != nil {
+			return nil, err
+		}
+		for _, si := range sst.Items {
+			shared = append(shared, si.text())
+		}
+	}
+
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, errNoWorksheet
+	}
+	var ws struct {
+		Rows []struct {
+			Index int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string     `xml:"r,attr"`
+				Type   string     `xml:"t,attr"`
+				Value  string     `xml:"v"`
+				Inline stringItem `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodePart(f, &ws); err != nil {
+		return nil, err
+	}
+
+	rows := [][]string{}
+	for _, row := range ws.Rows {
+		// Empty rows are left out of the file; keep the row numbers.
+		for row.Index > len(rows)+1 {
+			rows = append(rows, []string{})
+		}
+		cells := []string{}
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				if col, err = column(c.Ref); err != nil {
+					return nil, err
+				}
+			}
+			for len(cells) < col {
+				cells = append(cells, "")
+			}
+			value := c.Value
+			switch c.Type {
+			case "s":
+				n, err := strconv.Atoi(c.Value)
+				if err != nil || n < 0 || n >= len(shared) {
+					return nil, fmt.Errorf("xlsx: cell %s refers to a missing shared string", c.Ref)
+				}
+				value = shared[n]
+			case "inlineStr":
+				value = c.Inline.text()
+			case "b":
+				value = strconv.FormatBool(c.Value == "1")
+			}
+			cells = append(cells, value)
+		}
+		rows = append(rows, cells)
+	}
+	return rows, nil
+}
+
+// stringItem is a shared or inline string: plain text, or rich text runs.
+type stringItem struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (si stringItem) text() string {
+	if len(si.Runs) == 0 {
+		return si.Text
+	}
+	var b strings.Builder
+	for _, r := range si.Runs {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
+
+// firstSheet resolves the path of the workbook's first worksheet.
+func firstSheet(files map[string]*zip.File) (string, error) {
+	workbook, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", errNoWorksheet
+	}
+	var wb struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(workbook, &wb); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", errNoWorksheet
+	}
+	rels, ok := files["xl/_rels/workbook.xml.rels"]
+	if !ok {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	var rs struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodePart(rels, &rs); err != nil {
+		return "", err
+	}
+	for _, rel := range rs.Relationships {
+		if rel.ID == wb.Sheets[0].RelID {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+	}
+	return "", errNoWorksheet
+}
+
+func decodePart(f *zip.File, v any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("xlsx: %w", err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(io.LimitReader(rc, maxXLSXPart)).Decode(v); err != nil {
+		return fmt.Errorf("xlsx: %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// column returns the zero-based column of a cell reference such as "AB12".
+func column(ref string) (int, error) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 || col > 16384 {
+		return 0, fmt.Errorf("xlsx: invalid cell reference %q", ref)
+	}
+	return col - 1, nil
+}
+
+// columnName is the inverse of column.
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+// writeXLSX writes rows as the only worksheet of a workbook. Integers are
+// stored as numbers and everything else as inline strings.
+func writeXLSX(w io.Writer, rows [][]string) error {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		for j, value := range row {
+			ref := columnName(j) + strconv.Itoa(i+1)
+			if _, err := strconv.Atoi(value); err == nil && value == strings.TrimSpace(value) && !leadingZero(value) {
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, value)
+				continue
+			}
+			fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			if err := xml.EscapeText(&b, []byte(value)); err != nil {
+				return err
+			}
+			b.WriteString(`</t></is></c>`)
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	if _, err := io.WriteString(f, b.String()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// leadingZero reports whether value is a number a spreadsheet would change,
+// such as the SKU "007" or "+62", and so must stay text.
+func leadingZero(value string) bool {
+	value = strings.TrimPrefix(value, "-")
+	return len(value) > 1 && value[0] == '0' || strings.HasPrefix(value, "+")
+}